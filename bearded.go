@@ -8,6 +8,7 @@ import (
 	"github.com/m0sth8/cli" // use fork until subcommands will be fixed
 
 	"github.com/bearded-web/bearded/cmd/agent"
+	apiCli "github.com/bearded-web/bearded/cmd/cli"
 	"github.com/bearded-web/bearded/cmd/dispatcher"
 	"github.com/bearded-web/bearded/cmd/utils"
 )
@@ -50,7 +51,9 @@ func main() {
 		dispatcher.New(),
 		utils.Plugins,
 		utils.Plans,
+		utils.Integrity,
 		agent.New(),
+		apiCli.New(),
 	}
 
 	app.Flags = append(app.Flags, []cli.Flag{
@@ -0,0 +1,139 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/m0sth8/cli" // use fork until subcommands will be fixed
+	"golang.org/x/net/context"
+
+	"github.com/bearded-web/bearded/cmd"
+	"github.com/bearded-web/bearded/pkg/client"
+	"github.com/bearded-web/bearded/pkg/utils"
+	"github.com/bearded-web/bearded/vendor/homedir"
+)
+
+const (
+	EnvPrefix = "BEARDED"
+
+	FormatTable = "table"
+	FormatJson  = "json"
+)
+
+// Credentials saved after login
+type Credentials struct {
+	Addr  string `json:"addr"`
+	Token string `json:"token"`
+}
+
+func New() cli.Command {
+	return cli.Command{
+		Name:  "cli",
+		Usage: "Command line client for bearded api",
+		Subcommands: []cli.Command{
+			loginCommand(),
+			issuesCommand(),
+			scansCommand(),
+		},
+	}
+}
+
+func clientFlags() []cli.Flag {
+	return append(cmd.ApiFlags(EnvPrefix),
+		cli.StringFlag{
+			Name:   "format",
+			Value:  FormatTable,
+			EnvVar: fmt.Sprintf("%s_CLI_FORMAT", EnvPrefix),
+			Usage:  "output format [table|json]",
+		},
+	)
+}
+
+// takeApi works like cmd.TakeApi, but falls back to the credentials saved by login command
+func takeApi(fn func(*cli.Context, *client.Client, cmd.Timeout)) func(*cli.Context) {
+	return func(ctx *cli.Context) {
+		timeout := func() context.Context {
+			return utils.JustTimeout(context.Background(), time.Duration(ctx.Int("api-timeout"))*time.Second)
+		}
+		addr, token := ctx.String("api-addr"), ctx.String("api-token")
+		if token == "" {
+			creds, err := loadCredentials()
+			if err != nil || creds.Token == "" {
+				fatalf("Please login or set up api-token[$%s_API_TOKEN] flag\n", EnvPrefix)
+			}
+			token = creds.Token
+			if !ctx.IsSet("api-addr") && creds.Addr != "" {
+				addr = creds.Addr
+			}
+		}
+		api := client.NewTokenClient(addr, token, nil)
+		if ctx.GlobalBool("debug") {
+			api.Debug = true
+		}
+		fn(ctx, api, timeout)
+	}
+}
+
+func credentialsPath() (string, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".bearded", "credentials.json"), nil
+}
+
+func loadCredentials() (*Credentials, error) {
+	path, err := credentialsPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	creds := &Credentials{}
+	return creds, json.Unmarshal(data, creds)
+}
+
+func saveCredentials(creds *Credentials) error {
+	path, err := credentialsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(creds, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// print obj as json or call table to write rows
+func output(ctx *cli.Context, obj interface{}, table func(w *tabwriter.Writer)) {
+	switch ctx.String("format") {
+	case FormatJson:
+		data, err := json.MarshalIndent(obj, "", "    ")
+		if err != nil {
+			fatalf("%s\n", err)
+		}
+		fmt.Println(string(data))
+	case FormatTable:
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		table(w)
+		w.Flush()
+	default:
+		fatalf("Unknown format %s\n", ctx.String("format"))
+	}
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, format, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,165 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/m0sth8/cli" // use fork until subcommands will be fixed
+
+	"github.com/bearded-web/bearded/cmd"
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/pkg/client"
+	"github.com/bearded-web/bearded/pkg/utils"
+)
+
+func issuesCommand() cli.Command {
+	filterFlags := []cli.Flag{
+		cli.StringFlag{Name: "project", Usage: "project id"},
+		cli.StringFlag{Name: "target", Usage: "target id"},
+		cli.StringFlag{Name: "severity", Usage: "one of [info|low|medium|high|error]"},
+		cli.StringFlag{Name: "search", Usage: "full text search"},
+		cli.BoolFlag{Name: "resolved", Usage: "show only resolved issues"},
+		cli.BoolFlag{Name: "unresolved", Usage: "show only unresolved issues"},
+		cli.StringFlag{Name: "sort", Usage: "sort field, f.e -created"},
+	}
+	return cli.Command{
+		Name:  "issues",
+		Usage: "Work with issues",
+		Subcommands: []cli.Command{
+			cli.Command{
+				Name:   "list",
+				Usage:  "Show issues",
+				Action: takeApi(issuesListAction),
+				Flags: append(append(clientFlags(), filterFlags...),
+					cli.IntFlag{Name: "limit", Value: 20, Usage: "max number of issues, 0 shows all"},
+				),
+			},
+			cli.Command{
+				Name:   "show",
+				Usage:  "Show issue by id",
+				Action: takeApi(issuesShowAction),
+				Flags:  clientFlags(),
+			},
+			cli.Command{
+				Name:   "export",
+				Usage:  "Export all filtered issues as json",
+				Action: takeApi(issuesExportAction),
+				Flags: append(append(cmd.ApiFlags(EnvPrefix), filterFlags...),
+					cli.StringFlag{Name: "output, o", Usage: "file to write, stdout by default"},
+				),
+			},
+		},
+	}
+}
+
+func issuesListOpts(ctx *cli.Context) *client.IssuesListOpts {
+	opt := &client.IssuesListOpts{
+		Project:  ctx.String("project"),
+		Target:   ctx.String("target"),
+		Severity: issue.Severity(ctx.String("severity")),
+		Search:   ctx.String("search"),
+		Sort:     ctx.String("sort"),
+	}
+	if ctx.Bool("resolved") {
+		opt.Resolved = utils.BoolP(true)
+	} else if ctx.Bool("unresolved") {
+		opt.Resolved = utils.BoolP(false)
+	}
+	return opt
+}
+
+func issuesListAction(ctx *cli.Context, api *client.Client, timeout cmd.Timeout) {
+	opt := issuesListOpts(ctx)
+	limit := ctx.Int("limit")
+	if limit > 0 && limit < 100 {
+		opt.Limit = limit
+	}
+	issues := []*issue.TargetIssue{}
+	errStop := fmt.Errorf("stop")
+	err := api.Issues.Iter(timeout(), opt, func(obj *issue.TargetIssue) error {
+		issues = append(issues, obj)
+		if limit > 0 && len(issues) >= limit {
+			return errStop
+		}
+		return nil
+	})
+	if err != nil && err != errStop {
+		fatalf("%s\n", err)
+	}
+	output(ctx, issues, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "ID\tSEVERITY\tSTATUS\tSUMMARY")
+		for _, obj := range issues {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", client.FromId(obj.Id), obj.Severity, issueStatus(obj), obj.Summary)
+		}
+	})
+}
+
+func issuesShowAction(ctx *cli.Context, api *client.Client, timeout cmd.Timeout) {
+	if len(ctx.Args()) == 0 {
+		fatalf("You should set issue id argument: issues show [id]\n")
+	}
+	obj, err := api.Issues.Get(timeout(), ctx.Args()[0])
+	if err != nil {
+		if client.IsNotFound(err) {
+			fatalf("Issue not found\n")
+		}
+		fatalf("%s\n", err)
+	}
+	output(ctx, obj, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "Id:\t%s\n", client.FromId(obj.Id))
+		fmt.Fprintf(w, "Summary:\t%s\n", obj.Summary)
+		fmt.Fprintf(w, "Severity:\t%s\n", obj.Severity)
+		fmt.Fprintf(w, "Status:\t%s\n", issueStatus(obj))
+		fmt.Fprintf(w, "Target:\t%s\n", client.FromId(obj.Target))
+		fmt.Fprintf(w, "Created:\t%s\n", obj.Created)
+		if obj.Vector != nil && obj.Vector.Url != "" {
+			fmt.Fprintf(w, "Url:\t%s\n", obj.Vector.Url)
+		}
+		if obj.Desc != "" {
+			fmt.Fprintf(w, "\n%s\n", obj.Desc)
+		}
+	})
+}
+
+func issuesExportAction(ctx *cli.Context, api *client.Client, timeout cmd.Timeout) {
+	out := os.Stdout
+	if filename := ctx.String("output"); filename != "" {
+		f, err := os.Create(filename)
+		if err != nil {
+			fatalf("%s\n", err)
+		}
+		defer f.Close()
+		out = f
+	}
+	opt := issuesListOpts(ctx)
+	opt.Limit = 100
+	issues := []*issue.TargetIssue{}
+	err := api.Issues.Iter(timeout(), opt, func(obj *issue.TargetIssue) error {
+		issues = append(issues, obj)
+		return nil
+	})
+	if err != nil {
+		fatalf("%s\n", err)
+	}
+	enc := json.NewEncoder(out)
+	if err := enc.Encode(issues); err != nil {
+		fatalf("%s\n", err)
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d issues\n", len(issues))
+}
+
+func issueStatus(obj *issue.TargetIssue) string {
+	switch {
+	case obj.Resolved:
+		return "resolved"
+	case obj.False:
+		return "false"
+	case obj.Muted:
+		return "muted"
+	case obj.Confirmed:
+		return "confirmed"
+	}
+	return "new"
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"time"
+
+	"github.com/m0sth8/cli" // use fork until subcommands will be fixed
+	"golang.org/x/net/context"
+
+	"github.com/bearded-web/bearded/cmd"
+	"github.com/bearded-web/bearded/models/token"
+	"github.com/bearded-web/bearded/pkg/client"
+	"github.com/bearded-web/bearded/pkg/utils"
+)
+
+const cliTokenName = "bearded-cli"
+
+func loginCommand() cli.Command {
+	return cli.Command{
+		Name:   "login",
+		Usage:  "Login with email and password and save api token",
+		Action: loginAction,
+		Flags: append(cmd.ApiFlags(EnvPrefix),
+			cli.StringFlag{
+				Name:   "email",
+				EnvVar: fmt.Sprintf("%s_EMAIL", EnvPrefix),
+				Usage:  "user email",
+			},
+			cli.StringFlag{
+				Name:   "password",
+				EnvVar: fmt.Sprintf("%s_PASSWORD", EnvPrefix),
+				Usage:  "user password",
+			},
+		),
+	}
+}
+
+func loginAction(ctx *cli.Context) {
+	email, password := ctx.String("email"), ctx.String("password")
+	if email == "" || password == "" {
+		fatalf("Email and password are required\n")
+	}
+	timeout := func() context.Context {
+		return utils.JustTimeout(context.Background(), time.Duration(ctx.Int("api-timeout"))*time.Second)
+	}
+	// session is kept in cookies until the token is created
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		fatalf("%s\n", err)
+	}
+	addr := ctx.String("api-addr")
+	api := client.NewClient(addr, &http.Client{Jar: jar})
+	api.Debug = ctx.GlobalBool("debug")
+
+	if err := api.Auth.Login(timeout(), email, password); err != nil {
+		fatalf("Login failed: %s\n", err)
+	}
+	tkn, err := api.Tokens.Create(timeout(), &token.Token{Name: cliTokenName})
+	if err != nil {
+		fatalf("Can't create token: %s\n", err)
+	}
+	if err := saveCredentials(&Credentials{Addr: addr, Token: tkn.HashValue}); err != nil {
+		fatalf("Can't save credentials: %s\n", err)
+	}
+	api.Auth.Logout(timeout())
+	fmt.Println("Successful")
+}
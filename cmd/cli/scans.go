@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/m0sth8/cli" // use fork until subcommands will be fixed
+	"golang.org/x/net/context"
+
+	"github.com/bearded-web/bearded/cmd"
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/pkg/client"
+	"github.com/bearded-web/bearded/pkg/utils"
+)
+
+func scansCommand() cli.Command {
+	return cli.Command{
+		Name:  "scans",
+		Usage: "Work with scans",
+		Subcommands: []cli.Command{
+			cli.Command{
+				Name:   "list",
+				Usage:  "Show scans",
+				Action: takeApi(scansListAction),
+				Flags: append(clientFlags(),
+					cli.StringFlag{Name: "project", Usage: "project id"},
+					cli.StringFlag{Name: "target", Usage: "target id"},
+					cli.StringFlag{Name: "status", Usage: "one of [created|queued|working|paused|finished|failed]"},
+				),
+			},
+			cli.Command{
+				Name:   "show",
+				Usage:  "Show scan by id",
+				Action: takeApi(scansShowAction),
+				Flags:  clientFlags(),
+			},
+			cli.Command{
+				Name:   "start",
+				Usage:  "Start a new scan for the target with the plan",
+				Action: takeApi(scansStartAction),
+				Flags: append(clientFlags(),
+					cli.StringFlag{Name: "project", Usage: "project id"},
+					cli.StringFlag{Name: "target", Usage: "target id"},
+					cli.StringFlag{Name: "plan", Usage: "plan id"},
+					cli.BoolFlag{Name: "wait", Usage: "wait until the scan is finished"},
+					cli.IntFlag{Name: "wait-interval", Value: 5, Usage: "polling interval in seconds"},
+					cli.IntFlag{Name: "wait-timeout", Value: 3600, Usage: "max waiting time in seconds, 0 is unlimited"},
+				),
+			},
+		},
+	}
+}
+
+func scansListAction(ctx *cli.Context, api *client.Client, timeout cmd.Timeout) {
+	opt := &client.ScansListOpts{
+		Project: ctx.String("project"),
+		Target:  ctx.String("target"),
+		Status:  scan.ScanStatus(ctx.String("status")),
+	}
+	list, err := api.Scans.List(timeout(), opt)
+	if err != nil {
+		fatalf("%s\n", err)
+	}
+	output(ctx, list, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "ID\tSTATUS\tTARGET\tCREATED")
+		for _, obj := range list.Results {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", client.FromId(obj.Id), obj.Status, obj.Conf.Target, obj.Created)
+		}
+	})
+}
+
+func scansShowAction(ctx *cli.Context, api *client.Client, timeout cmd.Timeout) {
+	if len(ctx.Args()) == 0 {
+		fatalf("You should set scan id argument: scans show [id]\n")
+	}
+	obj, err := api.Scans.Get(timeout(), ctx.Args()[0])
+	if err != nil {
+		if client.IsNotFound(err) {
+			fatalf("Scan not found\n")
+		}
+		fatalf("%s\n", err)
+	}
+	printScan(ctx, obj)
+}
+
+func scansStartAction(ctx *cli.Context, api *client.Client, timeout cmd.Timeout) {
+	for _, name := range []string{"project", "target", "plan"} {
+		if ctx.String(name) == "" {
+			fatalf("Flag %s is required\n", name)
+		}
+	}
+	src := &scan.Scan{
+		Project: client.ToId(ctx.String("project")),
+		Target:  client.ToId(ctx.String("target")),
+		Plan:    client.ToId(ctx.String("plan")),
+	}
+	obj, err := api.Scans.Create(timeout(), src)
+	if err != nil {
+		fatalf("Scan wasn't created because: %s\n", err)
+	}
+	if ctx.Bool("wait") {
+		waitCtx := context.Background()
+		if t := ctx.Int("wait-timeout"); t > 0 {
+			waitCtx = utils.JustTimeout(waitCtx, time.Duration(t)*time.Second)
+		}
+		lastStatus := obj.Status
+		opt := &client.WaitOpts{
+			Interval: time.Duration(ctx.Int("wait-interval")) * time.Second,
+			Progress: func(sc *scan.Scan) {
+				if sc.Status != lastStatus {
+					fmt.Fprintf(os.Stderr, "Scan is %s\n", sc.Status)
+					lastStatus = sc.Status
+				}
+			},
+		}
+		if obj, err = api.Scans.WaitFor(waitCtx, client.FromId(obj.Id), opt); err != nil {
+			fatalf("Waiting failed: %s\n", err)
+		}
+	}
+	printScan(ctx, obj)
+	if obj.Status == scan.StatusFailed {
+		fatalf("Scan failed\n")
+	}
+}
+
+func printScan(ctx *cli.Context, obj *scan.Scan) {
+	output(ctx, obj, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "Id:\t%s\n", client.FromId(obj.Id))
+		fmt.Fprintf(w, "Status:\t%s\n", obj.Status)
+		fmt.Fprintf(w, "Target:\t%s\n", obj.Conf.Target)
+		fmt.Fprintf(w, "Plan:\t%s\n", client.FromId(obj.Plan))
+		fmt.Fprintf(w, "Sessions:\t%d\n", len(obj.Sessions))
+	})
+}
@@ -41,14 +41,15 @@ func New() cli.Command {
 	return cmd
 }
 
-func dispatcherAction(cliCtx *cli.Context) {
+// Load config in order: defaults, config file, environment variables and flags.
+// Every next source overrides values from the previous one.
+func loadConfig(cliCtx *cli.Context) (*config.Dispatcher, error) {
 	cfg := config.NewDispatcher()
 	if cfgPath := cliCtx.String("config"); cfgPath != "" {
 		logrus.Infof("Load config from %s", cfgPath)
-		err := load.FromFile(cfgPath, cfg)
+		err := load.FromFile(cfgPath, cfg, load.Opts{Format: load.Format(cliCtx.String("config-format"))})
 		if err != nil {
-			logrus.Fatalf("Couldn't load config: %s", err)
-			return
+			return nil, fmt.Errorf("Couldn't load config: %s", err)
 		}
 	}
 
@@ -56,16 +57,37 @@ func dispatcherAction(cliCtx *cli.Context) {
 	err := flags.ParseFlags(cfg, cliCtx, flags.Opts{
 		EnvPrefix: "BEARDED",
 	})
+	if err != nil {
+		return nil, err
+	}
+	cfg.Debug = cliCtx.GlobalBool("debug")
+	return cfg, nil
+}
+
+func dispatcherAction(cliCtx *cli.Context) {
+	cfg, err := loadConfig(cliCtx)
 	if err != nil {
 		logrus.Fatal(err)
 	}
-	if cfg.Debug = cliCtx.GlobalBool("debug"); cfg.Debug {
+	if cfg.Debug {
 		logrus.Info("Debug mode is enabled")
 	}
+	reload := make(chan *config.Dispatcher, 1)
+	go func() {
+		for range utils.NotifyHangup() {
+			newCfg, err := loadConfig(cliCtx)
+			if err != nil {
+				logrus.Errorf("Config wasn't reloaded: %s", err)
+				continue
+			}
+			reload <- newCfg
+		}
+	}()
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	dErr := async.Promise(func() error { return dispatcher.Serve(ctx, cfg) })
+	dErr := async.Promise(func() error { return dispatcher.Serve(ctx, cfg, reload) })
 
 	select {
 	case <-utils.NotifyInterrupt():
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/m0sth8/cli" // use fork until subcommands will be fixed
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/cmd"
+	"github.com/bearded-web/bearded/models/integrity"
+	"github.com/bearded-web/bearded/pkg/client"
+)
+
+var Integrity = cli.Command{
+	Name:  "integrity",
+	Usage: "Find and repair dangling references, admin token is required",
+	Flags: cmd.ApiFlags("BEARDED"),
+	Subcommands: []cli.Command{
+		cli.Command{
+			Name:   "check",
+			Usage:  "Show objects which reference missing ones",
+			Action: cmd.TakeApi(integrityCheckAction),
+			Flags: []cli.Flag{
+				cli.IntFlag{
+					Name:  "limit",
+					Value: 100,
+					Usage: "Max problems of every kind to show",
+				},
+			},
+		},
+		cli.Command{
+			Name:   "repair",
+			Usage:  "Delete or reattach all objects of the kind: integrity repair [kind]",
+			Action: cmd.TakeApi(integrityRepairAction),
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:  "reattach",
+					Usage: "Move issues or scans to the target with this id instead of deletion",
+				},
+			},
+		},
+	},
+}
+
+// ========= Actions
+
+func integrityCheckAction(ctx *cli.Context, api *client.Client, timeout cmd.Timeout) {
+	report, err := api.Admin.Integrity(timeout(), ctx.Int("limit"))
+	if err != nil {
+		fmt.Printf("%s", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Found %d problems:\n", report.Total())
+	for _, kind := range integrity.Kinds() {
+		fmt.Printf("%s: %d\n", kind, report.Counts[kind])
+	}
+	for _, p := range report.Problems {
+		fmt.Printf("%s %s references missing %s\n", p.Kind, p.Id.Hex(), p.Ref.Hex())
+	}
+}
+
+func integrityRepairAction(ctx *cli.Context, api *client.Client, timeout cmd.Timeout) {
+	if len(ctx.Args()) == 0 {
+		fmt.Printf("You should set kind argument: integrity repair [%s]\n", integrity.KindIssueTarget)
+		os.Exit(1)
+	}
+	raw := &integrity.Repair{Kind: integrity.Kind(ctx.Args()[0]), Action: integrity.ActionDelete}
+	if target := ctx.String("reattach"); target != "" {
+		if !bson.IsObjectIdHex(target) {
+			fmt.Println("Target should be bson id in hex form")
+			os.Exit(1)
+		}
+		raw.Action = integrity.ActionReattach
+		raw.Target = bson.ObjectIdHex(target)
+	}
+	report, err := api.Admin.Repair(timeout(), raw)
+	if err != nil {
+		fmt.Printf("%s", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Repaired %d of %d %s\n", report.Repaired, report.Counts[raw.Kind], raw.Kind)
+}
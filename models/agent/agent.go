@@ -16,6 +16,11 @@ type Agent struct {
 
 	Created time.Time `json:"created,omitempty" description:"when plan is created"`
 	Updated time.Time `json:"updated,omitempty" description:"when plan is updated"`
+
+	// images are sent to the agent with the next jobs request
+	Pull           []string   `json:"pull,omitempty" bson:"pull,omitempty" description:"images which the agent should pull"`
+	Images         []*Image   `json:"images,omitempty" bson:"images,omitempty" description:"image cache reported by the agent"`
+	ImagesReported *time.Time `json:"imagesReported,omitempty" bson:"imagesReported,omitempty"`
 	// tags is useful for filtering by clouds, server types etc.. f.e {"cloud": ["north"], "memory": ["high"], "cpu": ["low"]}
 	//	Tags map[string][]string
 }
@@ -24,6 +29,26 @@ func New() *Agent {
 	return &Agent{}
 }
 
+// Load is reported by agents when they ask for jobs, the scheduler gives sessions to the least loaded agent
+type Load struct {
+	Running  int `json:"running" url:"running" description:"sessions which are handled by the agent"`
+	Queued   int `json:"queued" url:"queued" description:"sessions which wait for a free slot on the agent"`
+	Capacity int `json:"capacity,omitempty" url:"capacity,omitempty" description:"max parallel sessions, 0 is unlimited"`
+}
+
+// Busy is the load in percents of capacity, or the number of sessions if capacity is unlimited
+func (l Load) Busy() int {
+	if l.Capacity <= 0 {
+		return l.Running + l.Queued
+	}
+	return (l.Running + l.Queued) * 100 / l.Capacity
+}
+
+// Full reports whether the agent can't take one more session
+func (l Load) Full() bool {
+	return l.Capacity > 0 && l.Running+l.Queued >= l.Capacity
+}
+
 type AgentList struct {
 	pagination.Meta `json:",inline"`
 	Results         []*Agent `json:"results"`
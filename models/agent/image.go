@@ -0,0 +1,63 @@
+package agent
+
+import (
+	"sort"
+	"time"
+)
+
+// Image is the state of the plugin image in the docker cache of the agent
+type Image struct {
+	Name   string `json:"name" description:"image name, e.g. barbudo/wpscan"`
+	Cached bool   `json:"cached" description:"image is pulled and could be run without downloading"`
+	Size   int64  `json:"size,omitempty" description:"virtual size in bytes"`
+	Error  string `json:"error,omitempty" description:"why the image isn't pulled"`
+}
+
+// ImageCache is the image cache reported by the agent and images which the agent is asked to pull
+type ImageCache struct {
+	Images   []*Image   `json:"images"`
+	Pull     []string   `json:"pull" description:"images which are sent to the agent with the next jobs request"`
+	Reported *time.Time `json:"reported,omitempty" description:"when the agent reported its cache"`
+}
+
+// PrePull is a request for agents to download images of plugins used by upcoming scans
+type PrePull struct {
+	Hours  int        `json:"hours" description:"images of scans scheduled during these hours are pulled, 24 if empty, max 168"`
+	Agents []string   `json:"agents,omitempty" description:"agent ids, all approved agents if empty"`
+	Until  *time.Time `json:"until,omitempty" description:"set by server"`
+	Images []string   `json:"images,omitempty" description:"set by server, images which agents are asked to pull"`
+}
+
+// NewImageCache returns the image cache of the agent
+func NewImageCache(a *Agent) *ImageCache {
+	c := &ImageCache{Images: a.Images, Pull: a.Pull, Reported: a.ImagesReported}
+	if c.Images == nil {
+		c.Images = []*Image{}
+	}
+	if c.Pull == nil {
+		c.Pull = []string{}
+	}
+	return c
+}
+
+// MergeImages replaces images of the agent cache with the same names and adds new ones, images are sorted by name
+func (a *Agent) MergeImages(images []*Image) {
+	byName := map[string]*Image{}
+	for _, img := range a.Images {
+		byName[img.Name] = img
+	}
+	for _, img := range images {
+		byName[img.Name] = img
+	}
+	a.Images = make([]*Image, 0, len(byName))
+	for _, img := range byName {
+		a.Images = append(a.Images, img)
+	}
+	sort.Sort(imagesByName(a.Images))
+}
+
+type imagesByName []*Image
+
+func (s imagesByName) Len() int           { return len(s) }
+func (s imagesByName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s imagesByName) Less(i, j int) bool { return s[i].Name < s[j].Name }
@@ -0,0 +1,20 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgentMergeImages(t *testing.T) {
+	a := &Agent{}
+	assert.Equal(t, []*Image{}, NewImageCache(a).Images)
+	assert.Equal(t, []string{}, NewImageCache(a).Pull)
+
+	a.MergeImages([]*Image{{Name: "barbudo/wpscan", Error: "timeout"}, {Name: "barbudo/nmap", Cached: true}})
+	a.MergeImages([]*Image{{Name: "barbudo/wpscan", Cached: true, Size: 100}})
+	assert.Equal(t, []*Image{
+		{Name: "barbudo/nmap", Cached: true},
+		{Name: "barbudo/wpscan", Cached: true, Size: 100},
+	}, a.Images)
+}
@@ -11,12 +11,16 @@ type JobCmd string
 const (
 	CmdRepeat JobCmd = "repeat" // just repeat request
 	CmdScan   JobCmd = "scan"
+	CmdPull   JobCmd = "pull" // download images before scans
 )
 
 type Job struct {
-	Cmd JobCmd `json:"cmd" description:"one of [repeat|scan]"`
+	Cmd JobCmd `json:"cmd" description:"one of [repeat|scan|pull]"`
 
-	Scan *scan.Session
+	Scan   *scan.Session
+	Images []string `json:"images,omitempty" description:"images to pull for the pull command"`
+	// plugin callbacks are authenticated by the session token, it's valid until the session is queued to another agent
+	Token string `json:"token,omitempty" description:"secret for session callbacks"`
 }
 
 func (j *Job) String() string {
@@ -0,0 +1,69 @@
+// Package apiusage contains api call counters of projects, they show which integrations load the api.
+package apiusage
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Bucket counts calls of one route by one token to the project during the hour.
+// Calls authenticated by the session cookie have an empty token.
+type Bucket struct {
+	Project    bson.ObjectId `json:"project"`
+	Token      bson.ObjectId `json:"token,omitempty" bson:"token,omitempty"`
+	Route      string        `json:"route" description:"method and path template, like GET /api/v1/targets/{target-id}"`
+	Hour       time.Time     `json:"hour"`
+	Calls      int           `json:"calls"`
+	Errors     int           `json:"errors" description:"calls answered with 5xx status"`
+	Latency    int           `json:"latency" description:"total latency of calls in milliseconds"`
+	MaxLatency int           `json:"maxLatency" description:"milliseconds"`
+}
+
+// Key identifies the bucket
+type Key struct {
+	Project bson.ObjectId
+	Token   bson.ObjectId
+	Route   string
+	Hour    time.Time
+}
+
+func (b *Bucket) Key() Key {
+	return Key{Project: b.Project, Token: b.Token, Route: b.Route, Hour: b.Hour}
+}
+
+// Add counts the call in the bucket
+func (b *Bucket) Add(latency time.Duration, failed bool) {
+	ms := int(latency / time.Millisecond)
+	b.Calls++
+	b.Latency += ms
+	if ms > b.MaxLatency {
+		b.MaxLatency = ms
+	}
+	if failed {
+		b.Errors++
+	}
+}
+
+// Stat sums buckets grouped by token, route or hour
+type Stat struct {
+	Token      bson.ObjectId `json:"token,omitempty" bson:"token,omitempty"`
+	TokenName  string        `json:"tokenName,omitempty" bson:"-"`
+	Route      string        `json:"route,omitempty" bson:"route,omitempty"`
+	Hour       *time.Time    `json:"hour,omitempty" bson:"hour,omitempty"`
+	Calls      int           `json:"calls"`
+	Errors     int           `json:"errors"`
+	Latency    int           `json:"-"`
+	AvgLatency int           `json:"avgLatency" bson:"-" description:"milliseconds"`
+	MaxLatency int           `json:"maxLatency" description:"milliseconds"`
+}
+
+type Report struct {
+	Project bson.ObjectId `json:"project"`
+	From    time.Time     `json:"from"`
+	To      time.Time     `json:"to,omitempty"`
+	Calls   int           `json:"calls"`
+	Tokens  []*Stat       `json:"tokens" description:"calls by token, the most active first"`
+	Routes  []*Stat       `json:"routes" description:"calls by route, the most active first"`
+	Hours   []*Stat       `json:"hours" description:"calls by hour in time order"`
+}
@@ -0,0 +1,276 @@
+// Package apply describes bundles of declarative resources which are applied
+// with create-or-update semantics, so scanning programs could be managed from git.
+package apply
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/bearded-web/bearded/models/notification"
+	"github.com/bearded-web/bearded/models/plan"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/pkg/utils/load"
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+const BundleVersion = 1
+
+// Bundle is a set of resources matched by names: projects of the user by name,
+// targets by address inside the project, plans by name, schedules by target and plan
+// and webhooks by project and type.
+// Resources missed in the bundle are left as is.
+type Bundle struct {
+	Version   int              `json:"version"`
+	Name      string           `json:"name,omitempty" description:"applied state of named bundles is kept to detect drift"`
+	Plans     []*plan.Document `json:"plans,omitempty" description:"plan documents, version could be omitted"`
+	Projects  []*Project       `json:"projects,omitempty"`
+	Targets   []*Target        `json:"targets,omitempty"`
+	Schedules []*Schedule      `json:"schedules,omitempty" description:"periodic discoveries of web targets"`
+	Webhooks  []*Webhook       `json:"webhooks,omitempty" description:"channels for project notifications"`
+}
+
+type Project struct {
+	Name          string            `json:"name"`
+	RequireReview bool              `json:"requireReview,omitempty"`
+	RateLimit     *target.RateLimit `json:"rateLimit,omitempty"`
+}
+
+// Target is a web or repo target, other types need uploaded files and aren't supported
+type Target struct {
+	Project     string             `json:"project" description:"project name"`
+	Type        target.TargetType  `json:"type" description:"one of [web|repo]"`
+	Web         *target.WebTarget  `json:"web,omitempty"`
+	Repo        *target.RepoTarget `json:"repo,omitempty"`
+	Criticality target.Criticality `json:"criticality,omitempty"`
+	Environment target.Environment `json:"environment,omitempty"`
+	RateLimit   *target.RateLimit  `json:"rateLimit,omitempty"`
+}
+
+// Schedule runs the discovery plan on the web target every interval hours
+type Schedule struct {
+	Project  string `json:"project" description:"project name"`
+	Target   string `json:"target" description:"web target address"`
+	Plan     string `json:"plan" description:"plan name"`
+	Interval int    `json:"interval" description:"hours between runs"`
+	At       string `json:"at,omitempty" description:"time of day in format 15:04 when runs are started, interval should be whole days then"`
+	Timezone string `json:"timezone,omitempty" description:"IANA time zone of at like Europe/Moscow, UTC if empty"`
+	Enabled  bool   `json:"enabled"`
+}
+
+type WebhookType string
+
+const WebhookMsTeams = WebhookType("msteams")
+
+// Webhook is a notification channel of the project, microsoft teams is the only supported one
+type Webhook struct {
+	Project      string               `json:"project" description:"project name"`
+	Type         WebhookType          `json:"type" description:"one of [msteams]"`
+	Enabled      bool                 `json:"enabled"`
+	Url          string               `json:"url" description:"incoming webhook url of the channel"`
+	Events       []notification.Event `json:"events,omitempty" description:"posted events, all if empty"`
+	Environments []target.Environment `json:"environments,omitempty" description:"posted environments of targets, all if empty"`
+}
+
+// MsTeams returns the project config of the microsoft teams webhook
+func (w *Webhook) MsTeams() *project.MsTeams {
+	return &project.MsTeams{
+		Enabled:      w.Enabled,
+		Url:          w.Url,
+		Events:       w.Events,
+		Environments: w.Environments,
+	}
+}
+
+func (w *Webhook) Validate() error {
+	errs := validate.Errors{}
+	if w.Project == "" {
+		errs.Add("project", validate.CodeRequired, "project is required")
+	}
+	switch w.Type {
+	case WebhookMsTeams:
+		errs.Extend("", w.MsTeams().Validate())
+	default:
+		errs.Add("type", validate.CodeInvalid, "should be one of [msteams]")
+	}
+	return errs.Err()
+}
+
+// Addr returns the address which identifies the target inside the project
+func (t *Target) Addr() string {
+	switch t.Type {
+	case target.TypeWeb:
+		if t.Web != nil {
+			return t.Web.Domain
+		}
+	case target.TypeRepo:
+		if t.Repo != nil {
+			return t.Repo.Url
+		}
+	}
+	return ""
+}
+
+func (t *Target) Validate() error {
+	errs := validate.Errors{}
+	if t.Project == "" {
+		errs.Add("project", validate.CodeRequired, "project is required")
+	}
+	switch t.Type {
+	case target.TypeWeb:
+		if t.Web == nil || t.Web.Domain == "" {
+			errs.Add("web.domain", validate.CodeRequired, "domain is required")
+			break
+		}
+		addr, err := url.Parse(t.Web.Domain)
+		if err != nil || !(addr.Scheme == "http" || addr.Scheme == "https") {
+			errs.Add("web.domain", validate.CodeInvalid, "scheme must be http or https")
+		}
+	case target.TypeRepo:
+		if t.Repo == nil {
+			errs.Add("repo", validate.CodeRequired, "repo is required")
+			break
+		}
+		if t.Repo.Name == "" {
+			errs.Add("repo.name", validate.CodeRequired, "name is required")
+		}
+		errs.Extend("repo", t.Repo.Validate())
+	default:
+		errs.Add("type", validate.CodeInvalid, "should be one of [web|repo]")
+	}
+	if t.Criticality != "" && !t.Criticality.IsValid() {
+		errs.Add("criticality", validate.CodeInvalid, "should be one of [low|medium|high|critical]")
+	}
+	if t.Environment != "" && !t.Environment.IsValid() {
+		errs.Add("environment", validate.CodeInvalid, "should be one of [prod|staging|dev]")
+	}
+	if t.RateLimit != nil {
+		errs.Extend("rateLimit", t.RateLimit.Validate())
+	}
+	return errs.Err()
+}
+
+func (s *Schedule) Validate() error {
+	errs := validate.Errors{}
+	if s.Project == "" {
+		errs.Add("project", validate.CodeRequired, "project is required")
+	}
+	if s.Target == "" {
+		errs.Add("target", validate.CodeRequired, "target is required")
+	}
+	if s.Plan == "" {
+		errs.Add("plan", validate.CodeRequired, "plan is required")
+	}
+	if s.Interval <= 0 {
+		errs.Add("interval", validate.CodeMin, "should be positive")
+	}
+	if s.At != "" {
+		if _, err := time.Parse("15:04", s.At); err != nil {
+			errs.Add("at", validate.CodeInvalid, "should be in format 15:04")
+		}
+		if s.Interval%24 != 0 {
+			errs.Add("interval", validate.CodeInvalid, "should be whole days if at is set")
+		}
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		errs.Add("timezone", validate.CodeInvalid, err.Error())
+	}
+	return errs.Err()
+}
+
+// Validate checks resources and that names are unique in the bundle
+func (b *Bundle) Validate() error {
+	errs := validate.Errors{}
+	if b.Version != BundleVersion {
+		errs.Add("version", validate.CodeInvalid, "unsupported version %d, should be %d", b.Version, BundleVersion)
+	}
+	plans := map[string]bool{}
+	for i, d := range b.Plans {
+		field := fmt.Sprintf("plans.%d", i)
+		if d == nil {
+			errs.Add(field, validate.CodeRequired, "plan is null")
+			continue
+		}
+		if d.Version == 0 {
+			d.Version = plan.DocumentVersion
+		}
+		errs.Extend(field, d.Validate())
+		if plans[d.Name] {
+			errs.Add(field+".name", validate.CodeInvalid, "plan %s is duplicated", d.Name)
+		}
+		plans[d.Name] = true
+	}
+	projects := map[string]bool{}
+	for i, p := range b.Projects {
+		field := fmt.Sprintf("projects.%d", i)
+		switch {
+		case p == nil:
+			errs.Add(field, validate.CodeRequired, "project is null")
+			continue
+		case p.Name == "":
+			errs.Add(field+".name", validate.CodeRequired, "name is required")
+		case projects[p.Name]:
+			errs.Add(field+".name", validate.CodeInvalid, "project %s is duplicated", p.Name)
+		}
+		if p.RateLimit != nil {
+			errs.Extend(field+".rateLimit", p.RateLimit.Validate())
+		}
+		projects[p.Name] = true
+	}
+	targets := map[string]bool{}
+	for i, t := range b.Targets {
+		field := fmt.Sprintf("targets.%d", i)
+		if t == nil {
+			errs.Add(field, validate.CodeRequired, "target is null")
+			continue
+		}
+		errs.Extend(field, t.Validate())
+		key := t.Project + " " + t.Addr()
+		if targets[key] {
+			errs.Add(field, validate.CodeInvalid, "target %s is duplicated", t.Addr())
+		}
+		targets[key] = true
+	}
+	schedules := map[Schedule]bool{}
+	for i, s := range b.Schedules {
+		field := fmt.Sprintf("schedules.%d", i)
+		if s == nil {
+			errs.Add(field, validate.CodeRequired, "schedule is null")
+			continue
+		}
+		errs.Extend(field, s.Validate())
+		key := Schedule{Project: s.Project, Target: s.Target, Plan: s.Plan}
+		if schedules[key] {
+			errs.Add(field, validate.CodeInvalid, "schedule of %s with plan %s is duplicated", s.Target, s.Plan)
+		}
+		schedules[key] = true
+	}
+	webhooks := map[string]bool{}
+	for i, w := range b.Webhooks {
+		field := fmt.Sprintf("webhooks.%d", i)
+		if w == nil {
+			errs.Add(field, validate.CodeRequired, "webhook is null")
+			continue
+		}
+		errs.Extend(field, w.Validate())
+		key := w.Project + " " + string(w.Type)
+		if webhooks[key] {
+			errs.Add(field, validate.CodeInvalid, "%s webhook of project %s is duplicated", w.Type, w.Project)
+		}
+		webhooks[key] = true
+	}
+	return errs.Err()
+}
+
+// ParseBundle decodes and validates yaml bundle, unknown fields are rejected
+func ParseBundle(data []byte) (*Bundle, error) {
+	b := &Bundle{}
+	if err := load.UnmarshalYamlStrict(data, b); err != nil {
+		return nil, err
+	}
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,88 @@
+package apply
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+func TestParseBundle(t *testing.T) {
+	data := `
+version: 1
+plans:
+  - name: Subdomains
+    targetType: web
+    workflow:
+      - plugin: barbudo/subfinder
+        name: Subfinder
+projects:
+  - name: Shop
+    requireReview: true
+targets:
+  - project: Shop
+    type: web
+    web: {domain: "https://shop.example.com"}
+    criticality: high
+    environment: prod
+  - project: Shop
+    type: repo
+    repo: {name: shop, url: "git@github.com:example/shop.git"}
+schedules:
+  - project: Shop
+    target: https://shop.example.com
+    plan: Subdomains
+    interval: 24
+    enabled: true
+webhooks:
+  - project: Shop
+    type: msteams
+    enabled: true
+    url: https://example.webhook.office.com/webhookb2/1
+    environments: [prod]
+`
+	b, err := ParseBundle([]byte(data))
+	require.NoError(t, err)
+	require.Len(t, b.Targets, 2)
+	assert.Equal(t, 1, b.Plans[0].Version)
+	assert.Equal(t, target.CriticalityHigh, b.Targets[0].Criticality)
+	assert.Equal(t, "git@github.com:example/shop.git", b.Targets[1].Addr())
+	assert.Equal(t, 24, b.Schedules[0].Interval)
+	require.Len(t, b.Webhooks, 1)
+	assert.Equal(t, []target.Environment{target.EnvironmentProd}, b.Webhooks[0].MsTeams().Environments)
+
+	_, err = ParseBundle([]byte("version: 1\nwebhooks:\n  - {project: Shop, type: msteams, url: https://example.com}"))
+	assert.Error(t, err)
+	_, err = ParseBundle([]byte("version: 1\nhooks:\n  - url: https://example.com"))
+	assert.Error(t, err)
+}
+
+func TestBundleValidate(t *testing.T) {
+	web := func(domain string) *Target {
+		return &Target{Project: "Shop", Type: target.TypeWeb, Web: &target.WebTarget{Domain: domain}}
+	}
+	assert.NoError(t, (&Bundle{Version: 1, Targets: []*Target{web("https://a.com"), web("https://b.com")}}).Validate())
+
+	for _, b := range []*Bundle{
+		{},
+		{Version: 1, Projects: []*Project{{Name: "Shop"}, {Name: "Shop"}}},
+		{Version: 1, Projects: []*Project{{Name: ""}}},
+		{Version: 1, Targets: []*Target{web("https://a.com"), web("https://a.com")}},
+		{Version: 1, Targets: []*Target{web("ftp://a.com")}},
+		{Version: 1, Targets: []*Target{{Project: "Shop", Type: target.TypeAndroid}}},
+		{Version: 1, Targets: []*Target{{Project: "Shop", Type: target.TypeRepo, Repo: &target.RepoTarget{Url: "https://a.com/r.git"}}}},
+		{Version: 1, Schedules: []*Schedule{{Project: "Shop", Target: "https://a.com", Plan: "Subdomains"}}},
+		{Version: 1, Webhooks: []*Webhook{{Project: "Shop", Type: "slack", Url: "https://hooks.slack.com/1"}}},
+		{Version: 1, Webhooks: []*Webhook{
+			{Project: "Shop", Type: WebhookMsTeams, Url: "https://example.webhook.office.com/1"},
+			{Project: "Shop", Type: WebhookMsTeams, Url: "https://example.webhook.office.com/2"},
+		}},
+	} {
+		err := b.Validate()
+		assert.Error(t, err)
+		assert.IsType(t, validate.Errors{}, err)
+	}
+}
@@ -0,0 +1,56 @@
+package apply
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+type Kind string
+
+const (
+	KindPlan     = Kind("plan")
+	KindProject  = Kind("project")
+	KindTarget   = Kind("target")
+	KindSchedule = Kind("schedule")
+	KindWebhook  = Kind("webhook")
+)
+
+type Status string
+
+const (
+	StatusInSync  = Status("in-sync")
+	StatusDrifted = Status("drifted") // changed out of band
+	StatusMissing = Status("missing") // removed out of band
+)
+
+// Resource is a resource managed by the bundle
+type Resource struct {
+	Kind Kind          `json:"kind" description:"one of [plan|project|target|schedule|webhook]"`
+	Name string        `json:"name"`
+	Id   bson.ObjectId `json:"id"`
+}
+
+// State is the last applied named bundle of the user
+type State struct {
+	Id        bson.ObjectId `json:"id,omitempty" bson:"_id"`
+	Name      string        `json:"name"`
+	Owner     bson.ObjectId `json:"owner"`
+	Bundle    *Bundle       `json:"bundle"`
+	Resources []*Resource   `json:"resources"`
+	Applied   time.Time     `json:"applied"`
+}
+
+type ResourceState struct {
+	Resource `json:",inline" bson:",inline"`
+	Status   Status   `json:"status" description:"one of [in-sync|drifted|missing]"`
+	Fields   []string `json:"fields,omitempty" description:"fields which differ from the bundle"`
+}
+
+// StateReport compares managed resources with the applied bundle
+type StateReport struct {
+	Name      string           `json:"name"`
+	Applied   time.Time        `json:"applied"`
+	Drifted   bool             `json:"drifted" description:"some resources are changed or removed out of band"`
+	Resources []*ResourceState `json:"resources"`
+}
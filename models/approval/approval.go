@@ -0,0 +1,101 @@
+package approval
+
+import (
+	"encoding/json"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/pkg/pagination"
+)
+
+type Action string
+
+const (
+	ActionProjectDelete = Action("project.delete")
+	ActionTargetDelete  = Action("target.delete")
+	ActionIssuesDelete  = Action("issues.delete")
+)
+
+var actions = []interface{}{
+	ActionProjectDelete,
+	ActionTargetDelete,
+	ActionIssuesDelete,
+}
+
+// It's a hack to show custom type as string in swagger
+func (t Action) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+func (t Action) Enum() []interface{} {
+	return actions
+}
+
+func (t Action) Convert(text string) (interface{}, error) {
+	return Action(text), nil
+}
+
+type Status string
+
+const (
+	StatusPending  = Status("pending")
+	StatusApproved = Status("approved")
+	StatusRejected = Status("rejected")
+	StatusExpired  = Status("expired")
+)
+
+var statuses = []interface{}{
+	StatusPending,
+	StatusApproved,
+	StatusRejected,
+	StatusExpired,
+}
+
+// It's a hack to show custom type as string in swagger
+func (t Status) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+func (t Status) Enum() []interface{} {
+	return statuses
+}
+
+func (t Status) Convert(text string) (interface{}, error) {
+	return Status(text), nil
+}
+
+// Approval is a destructive action waiting for confirmation by a second admin
+type Approval struct {
+	Id      bson.ObjectId   `json:"id,omitempty" bson:"_id"`
+	Action  Action          `json:"action" description:"one of [project.delete|target.delete|issues.delete]"`
+	Status  Status          `json:"status" description:"one of [pending|approved|rejected|expired]"`
+	Project bson.ObjectId   `json:"project"`
+	Target  bson.ObjectId   `json:"target,omitempty" bson:",omitempty" description:"target to delete"`
+	Issues  []bson.ObjectId `json:"issues,omitempty" bson:",omitempty" description:"issues to delete"`
+
+	Requester bson.ObjectId `json:"requester"`
+	Reviewer  bson.ObjectId `json:"reviewer,omitempty" bson:",omitempty" description:"admin who approved or rejected the action"`
+	Reason    string        `json:"reason,omitempty" bson:",omitempty" description:"rejection reason"`
+
+	Created  time.Time  `json:"created"`
+	Expires  time.Time  `json:"expires" description:"the action isn't executed if it's not approved till this time"`
+	Resolved *time.Time `json:"resolved,omitempty" bson:",omitempty"`
+}
+
+type ApprovalList struct {
+	pagination.Meta `json:",inline"`
+	Results         []*Approval `json:"results"`
+}
+
+// IsPending returns true if the action could still be approved
+func (a *Approval) IsPending(now time.Time) bool {
+	return a.Status == StatusPending && now.Before(a.Expires)
+}
+
+// Resolve sets the final status of the approval
+func (a *Approval) Resolve(status Status, reviewer bson.ObjectId, now time.Time) {
+	a.Status = status
+	a.Reviewer = reviewer
+	a.Resolved = &now
+}
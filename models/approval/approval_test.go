@@ -0,0 +1,22 @@
+package approval
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestApproval(t *testing.T) {
+	now := time.Date(2015, 6, 1, 10, 0, 0, 0, time.UTC)
+	a := &Approval{Status: StatusPending, Expires: now.Add(time.Hour)}
+	assert.True(t, a.IsPending(now))
+	assert.False(t, a.IsPending(now.Add(time.Hour)))
+
+	reviewer := bson.NewObjectId()
+	a.Resolve(StatusApproved, reviewer, now)
+	assert.False(t, a.IsPending(now))
+	assert.Equal(t, reviewer, a.Reviewer)
+	assert.Equal(t, now, *a.Resolved)
+}
@@ -0,0 +1,117 @@
+package cascade
+
+import (
+	"encoding/json"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/pkg/pagination"
+)
+
+// Policy decides what happens with issues and scans of a removed target or project
+type Policy string
+
+const (
+	// deletion is forbidden while the entity has issues or scans
+	PolicyBlock = Policy("block")
+	// children are removed by a background job
+	PolicyCascade = Policy("cascade")
+)
+
+type Kind string
+
+const (
+	KindTarget  = Kind("target")
+	KindProject = Kind("project")
+)
+
+var kinds = []interface{}{
+	KindTarget,
+	KindProject,
+}
+
+// It's a hack to show custom type as string in swagger
+func (t Kind) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+func (t Kind) Enum() []interface{} {
+	return kinds
+}
+
+func (t Kind) Convert(text string) (interface{}, error) {
+	return Kind(text), nil
+}
+
+type Status string
+
+const (
+	StatusPending  = Status("pending")
+	StatusWorking  = Status("working")
+	StatusFinished = Status("finished")
+	StatusFailed   = Status("failed")
+)
+
+var statuses = []interface{}{
+	StatusPending,
+	StatusWorking,
+	StatusFinished,
+	StatusFailed,
+}
+
+// It's a hack to show custom type as string in swagger
+func (t Status) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+func (t Status) Enum() []interface{} {
+	return statuses
+}
+
+func (t Status) Convert(text string) (interface{}, error) {
+	return Status(text), nil
+}
+
+// Job removes children of a removed target or project
+type Job struct {
+	Id      bson.ObjectId `json:"id,omitempty" bson:"_id"`
+	Kind    Kind          `json:"kind" description:"one of [target|project]"`
+	Entity  bson.ObjectId `json:"entity" description:"id of the removed target or project"`
+	Project bson.ObjectId `json:"project"`
+	User    bson.ObjectId `json:"user" description:"who requested the deletion"`
+	Status  Status        `json:"status" description:"one of [pending|working|finished|failed]"`
+
+	Total int    `json:"total" description:"targets, issues and scans to remove"`
+	Done  int    `json:"done" description:"already removed targets, issues and scans"`
+	Error string `json:"error,omitempty" bson:",omitempty"`
+
+	Created  time.Time  `json:"created"`
+	Updated  time.Time  `json:"updated"`
+	Finished *time.Time `json:"finished,omitempty" bson:",omitempty"`
+}
+
+type JobList struct {
+	pagination.Meta `json:",inline"`
+	Results         []*Job `json:"results"`
+}
+
+// Advance counts removed children, total grows if children are added while the job works
+func (j *Job) Advance(n int, now time.Time) {
+	j.Done += n
+	if j.Done > j.Total {
+		j.Total = j.Done
+	}
+	j.Updated = now
+}
+
+// Finish sets the final status of the job, it's failed if err isn't nil
+func (j *Job) Finish(err error, now time.Time) {
+	j.Status = StatusFinished
+	if err != nil {
+		j.Status = StatusFailed
+		j.Error = err.Error()
+	}
+	j.Updated = now
+	j.Finished = &now
+}
@@ -0,0 +1,28 @@
+package cascade
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJob(t *testing.T) {
+	now := time.Date(2015, 6, 1, 10, 0, 0, 0, time.UTC)
+	j := &Job{Status: StatusWorking, Total: 3}
+	j.Advance(2, now)
+	assert.Equal(t, 2, j.Done)
+	assert.Equal(t, 3, j.Total)
+	j.Advance(2, now)
+	assert.Equal(t, 4, j.Done)
+	assert.Equal(t, 4, j.Total)
+
+	j.Finish(nil, now)
+	assert.Equal(t, StatusFinished, j.Status)
+	assert.Equal(t, now, *j.Finished)
+
+	j.Finish(errors.New("no reachable servers"), now)
+	assert.Equal(t, StatusFailed, j.Status)
+	assert.Equal(t, "no reachable servers", j.Error)
+}
@@ -0,0 +1,159 @@
+package discovery
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+type HostStatus string
+
+const (
+	HostPending  = HostStatus("pending")  // waiting for decision
+	HostApproved = HostStatus("approved") // target is created for the host
+	HostIgnored  = HostStatus("ignored")  // host is out of scope
+)
+
+var hostStatuses = []interface{}{
+	HostPending,
+	HostApproved,
+	HostIgnored,
+}
+
+// It's a hack to show custom type as string in swagger
+func (t HostStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+func (t HostStatus) Enum() []interface{} {
+	return hostStatuses
+}
+
+func (t HostStatus) Convert(text string) (interface{}, error) {
+	return HostStatus(text), nil
+}
+
+// Discovery periodically runs enumeration plan on the root domain of the target
+type Discovery struct {
+	Id       bson.ObjectId `json:"id,omitempty" bson:"_id"`
+	Project  bson.ObjectId `json:"project"`
+	Target   bson.ObjectId `json:"target" description:"web target with the root domain"`
+	Plan     bson.ObjectId `json:"plan" description:"plan with enumeration plugins, it should report hosts"`
+	Owner    bson.ObjectId `json:"owner,omitempty" description:"discovery scans are created on behalf of this user"`
+	Interval int           `json:"interval" description:"hours between runs"`
+	At       string        `json:"at,omitempty" bson:",omitempty" description:"time of day in format 15:04 when runs are started, interval should be whole days then"`
+	Timezone string        `json:"timezone,omitempty" bson:",omitempty" description:"IANA time zone of at like Europe/Moscow, UTC if empty"`
+	Enabled  bool          `json:"enabled"`
+	Created  time.Time     `json:"created,omitempty"`
+	Updated  time.Time     `json:"updated,omitempty"`
+
+	LastRun  *time.Time    `json:"lastRun,omitempty" bson:",omitempty" description:"when the last scan is created"`
+	LastScan bson.ObjectId `json:"lastScan,omitempty" bson:",omitempty"`
+}
+
+type DiscoveryList struct {
+	pagination.Meta `json:",inline"`
+	Results         []*Discovery `json:"results"`
+}
+
+func (d *Discovery) Validate() error {
+	errs := validate.Errors{}
+	if d.Interval <= 0 {
+		errs.Add("interval", validate.CodeMin, "should be positive")
+	}
+	if d.At != "" {
+		if _, err := time.Parse("15:04", d.At); err != nil {
+			errs.Add("at", validate.CodeInvalid, "should be in format 15:04")
+		}
+		if d.Interval%24 != 0 {
+			errs.Add("interval", validate.CodeInvalid, "should be whole days if at is set")
+		}
+	}
+	if _, err := time.LoadLocation(d.Timezone); err != nil {
+		errs.Add("timezone", validate.CodeInvalid, err.Error())
+	}
+	return errs.Err()
+}
+
+// Next returns when the discovery should be run, zero time if it should be run at once.
+// Runs at the time of day keep the wall clock of the time zone, so daylight saving changes don't shift them.
+func (d *Discovery) Next() time.Time {
+	if d.At == "" {
+		if d.LastRun == nil {
+			return time.Time{}
+		}
+		return d.LastRun.Add(time.Duration(d.Interval) * time.Hour)
+	}
+	clock, err := time.Parse("15:04", d.At)
+	if err != nil {
+		return time.Time{}
+	}
+	loc, err := time.LoadLocation(d.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	if d.LastRun == nil {
+		// the first run is at the nearest time of day after creation
+		created := d.Created.In(loc)
+		next := time.Date(created.Year(), created.Month(), created.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+		if next.Before(d.Created) {
+			next = time.Date(created.Year(), created.Month(), created.Day()+1, clock.Hour(), clock.Minute(), 0, 0, loc)
+		}
+		return next.UTC()
+	}
+	last := d.LastRun.In(loc)
+	return time.Date(last.Year(), last.Month(), last.Day()+d.Interval/24, clock.Hour(), clock.Minute(), 0, 0, loc).UTC()
+}
+
+// Due reports whether the discovery should be run at now
+func (d *Discovery) Due(now time.Time) bool {
+	if !d.Enabled {
+		return false
+	}
+	return !now.Before(d.Next())
+}
+
+// Host is a host name found by discovery which is proposed as a new target
+type Host struct {
+	Id        bson.ObjectId `json:"id,omitempty" bson:"_id"`
+	Project   bson.ObjectId `json:"project"`
+	Discovery bson.ObjectId `json:"discovery"`
+	Name      string        `json:"name" description:"host name, like api.example.com"`
+	Status    HostStatus    `json:"status" description:"one of [pending|approved|ignored]"`
+	Scan      bson.ObjectId `json:"scan" description:"scan where the host was found first"`
+	Seen      time.Time     `json:"seen" description:"when the host was reported last time"`
+	Created   time.Time     `json:"created,omitempty"`
+	Updated   time.Time     `json:"updated,omitempty"`
+
+	Target bson.ObjectId `json:"target,omitempty" bson:",omitempty" description:"created target for approved host"`
+	User   bson.ObjectId `json:"user,omitempty" bson:",omitempty" description:"who approved or ignored the host"`
+}
+
+type HostList struct {
+	pagination.Meta `json:",inline"`
+	Results         []*Host `json:"results"`
+}
+
+// NormalizeHost returns lower cased host name without trailing dot, wildcard and port
+func NormalizeHost(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if i := strings.Index(name, "://"); i >= 0 {
+		name = name[i+3:]
+	}
+	if i := strings.IndexAny(name, "/:?#"); i >= 0 {
+		name = name[:i]
+	}
+	name = strings.TrimPrefix(name, "*.")
+	return strings.TrimSuffix(name, ".")
+}
+
+// InScope reports whether the host is a subdomain of the root
+func InScope(host, root string) bool {
+	host, root = NormalizeHost(host), NormalizeHost(root)
+	return root != "" && host != root && strings.HasSuffix(host, "."+root)
+}
@@ -0,0 +1,72 @@
+package discovery
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDue(t *testing.T) {
+	now := time.Date(2015, 6, 1, 12, 0, 0, 0, time.UTC)
+	d := &Discovery{Interval: 24}
+	assert.False(t, d.Due(now))
+
+	d.Enabled = true
+	assert.True(t, d.Due(now))
+
+	last := now.Add(-23 * time.Hour)
+	d.LastRun = &last
+	assert.False(t, d.Due(now))
+	assert.True(t, d.Due(now.Add(time.Hour)))
+}
+
+func TestDueAt(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	// created before 03:00 at the eve of daylight saving change
+	created := time.Date(2015, 3, 7, 1, 0, 0, 0, loc)
+	d := &Discovery{Interval: 24, At: "03:00", Timezone: "America/New_York", Enabled: true, Created: created.UTC()}
+	require.NoError(t, d.Validate())
+	assert.Equal(t, time.Date(2015, 3, 7, 3, 0, 0, 0, loc), d.Next().In(loc))
+	assert.False(t, d.Due(time.Date(2015, 3, 7, 2, 59, 0, 0, loc)))
+
+	last := time.Date(2015, 3, 7, 3, 0, 30, 0, loc).UTC()
+	d.LastRun = &last
+	// the day is 23 hours long, but the run is still at 03:00
+	next := d.Next()
+	assert.Equal(t, time.Date(2015, 3, 8, 3, 0, 0, 0, loc), next.In(loc))
+	assert.Equal(t, 23*time.Hour, next.Sub(time.Date(2015, 3, 7, 3, 0, 0, 0, loc)))
+	assert.False(t, d.Due(next.Add(-time.Minute)))
+	assert.True(t, d.Due(next))
+
+	// weekly
+	d.Interval = 24 * 7
+	assert.Equal(t, time.Date(2015, 3, 14, 3, 0, 0, 0, loc), d.Next().In(loc))
+
+	assert.Error(t, (&Discovery{Interval: 12, At: "03:00"}).Validate())
+	assert.Error(t, (&Discovery{Interval: 24, At: "3am"}).Validate())
+	assert.Error(t, (&Discovery{Interval: 24, Timezone: "Mars/Olympus"}).Validate())
+}
+
+func TestNormalizeHost(t *testing.T) {
+	data := map[string]string{
+		"Api.Example.com.":             "api.example.com",
+		"*.example.com":                "example.com",
+		"https://www.example.com:443/": "www.example.com",
+		" mail.example.com ":           "mail.example.com",
+	}
+	for in, out := range data {
+		assert.Equal(t, out, NormalizeHost(in), in)
+	}
+}
+
+func TestInScope(t *testing.T) {
+	assert.True(t, InScope("api.example.com", "http://example.com/"))
+	assert.True(t, InScope("a.b.example.com.", "example.com"))
+	assert.False(t, InScope("example.com", "example.com"))
+	assert.False(t, InScope("badexample.com", "example.com"))
+	assert.False(t, InScope("example.org", "example.com"))
+	assert.False(t, InScope("api.example.com", ""))
+}
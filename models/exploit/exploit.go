@@ -0,0 +1,175 @@
+// Package exploit describes known exploits of CVEs from public exploit databases.
+package exploit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Source string
+
+const (
+	SourceExploitDb = Source("exploit-db")
+	SourceKev       = Source("cisa-kev")
+)
+
+var sources = []interface{}{
+	SourceExploitDb,
+	SourceKev,
+}
+
+// It's a hack to show custom type as string in swagger
+func (t Source) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+func (t Source) Enum() []interface{} {
+	return sources
+}
+
+func (t Source) Convert(text string) (interface{}, error) {
+	return Source(text), nil
+}
+
+const exploitDbUrl = "https://www.exploit-db.com/exploits/%s"
+
+var cveRe = regexp.MustCompile(`^CVE-\d{4}-\d{4,}$`)
+
+// Ref is a public exploit of the cve or a report of its exploitation in the wild
+type Ref struct {
+	Source Source `json:"source" description:"one of [exploit-db|cisa-kev]"`
+	Id     string `json:"id" description:"id in the source database, cve for cisa-kev"`
+	Title  string `json:"title,omitempty" bson:",omitempty"`
+	Url    string `json:"url,omitempty" bson:",omitempty"`
+}
+
+// Exploit is an entry of the stored catalog
+type Exploit struct {
+	Cve     string    `json:"cve" bson:"_id"`
+	Refs    []*Ref    `json:"refs"`
+	Updated time.Time `json:"updated"`
+}
+
+// Catalog is known exploits by cve ids in upper case
+type Catalog map[string][]*Ref
+
+// NormalizeCve returns the cve id in upper case, ok is false if it isn't a cve id
+func NormalizeCve(cve string) (string, bool) {
+	cve = strings.ToUpper(strings.TrimSpace(cve))
+	return cve, cveRe.MatchString(cve)
+}
+
+// Add puts the exploit to the catalog, refs which aren't for cves are skipped
+func (c Catalog) Add(cve string, ref *Ref) {
+	cve, ok := NormalizeCve(cve)
+	if !ok {
+		return
+	}
+	for _, r := range c[cve] {
+		if r.Source == ref.Source && r.Id == ref.Id {
+			return
+		}
+	}
+	c[cve] = append(c[cve], ref)
+}
+
+// Refs returns exploits of cves ordered by source and id
+func (c Catalog) Refs(cves []string) []*Ref {
+	var res []*Ref
+	seen := map[string]bool{}
+	for _, cve := range cves {
+		cve, _ = NormalizeCve(cve)
+		if seen[cve] {
+			continue
+		}
+		seen[cve] = true
+		res = append(res, c[cve]...)
+	}
+	sort.Stable(refsBySource(res))
+	return res
+}
+
+type refsBySource []*Ref
+
+func (s refsBySource) Len() int      { return len(s) }
+func (s refsBySource) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s refsBySource) Less(i, j int) bool {
+	if s[i].Source != s[j].Source {
+		return s[i].Source < s[j].Source
+	}
+	return s[i].Id < s[j].Id
+}
+
+// ReadKev adds vulnerabilities from CISA known exploited vulnerabilities catalog in json
+func (c Catalog) ReadKev(r io.Reader) error {
+	data := struct {
+		Vulnerabilities []struct {
+			CveID             string `json:"cveID"`
+			VulnerabilityName string `json:"vulnerabilityName"`
+		} `json:"vulnerabilities"`
+	}{}
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return fmt.Errorf("broken kev catalog: %v", err)
+	}
+	for _, v := range data.Vulnerabilities {
+		cve, _ := NormalizeCve(v.CveID)
+		c.Add(cve, &Ref{
+			Source: SourceKev,
+			Id:     cve,
+			Title:  v.VulnerabilityName,
+			Url:    fmt.Sprintf("https://nvd.nist.gov/vuln/detail/%s", cve),
+		})
+	}
+	return nil
+}
+
+// ReadExploitDb adds exploits from files_exploits.csv of exploit-db,
+// cves are taken from the codes column separated by semicolon
+func (c Catalog) ReadExploitDb(r io.Reader) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("broken exploit-db csv: %v", err)
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		cols[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"id", "description", "codes"} {
+		if _, ok := cols[name]; !ok {
+			return fmt.Errorf("broken exploit-db csv: column %s isn't found", name)
+		}
+	}
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("broken exploit-db csv: %v", err)
+		}
+		field := func(name string) string {
+			if i := cols[name]; i < len(rec) {
+				return rec[i]
+			}
+			return ""
+		}
+		id := field("id")
+		for _, code := range strings.Split(field("codes"), ";") {
+			c.Add(code, &Ref{
+				Source: SourceExploitDb,
+				Id:     id,
+				Title:  field("description"),
+				Url:    fmt.Sprintf(exploitDbUrl, id),
+			})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package exploit
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadKev(t *testing.T) {
+	c := Catalog{}
+	err := c.ReadKev(strings.NewReader(`{"vulnerabilities": [
+		{"cveID": "CVE-2014-0160", "vulnerabilityName": "OpenSSL Heartbleed"},
+		{"cveID": "not-a-cve"}
+	]}`))
+	require.NoError(t, err)
+	require.Len(t, c, 1)
+	assert.Equal(t, []*Ref{{
+		Source: SourceKev,
+		Id:     "CVE-2014-0160",
+		Title:  "OpenSSL Heartbleed",
+		Url:    "https://nvd.nist.gov/vuln/detail/CVE-2014-0160",
+	}}, c["CVE-2014-0160"])
+
+	assert.Error(t, c.ReadKev(strings.NewReader("{")))
+}
+
+func TestReadExploitDb(t *testing.T) {
+	c := Catalog{}
+	err := c.ReadExploitDb(strings.NewReader(`id,file,description,codes
+32745,exploits/multiple/remote/32745.py,"OpenSSL TLS Heartbeat - Information Leak (1)",CVE-2014-0160;OSVDB-105465
+32764,exploits/multiple/remote/32764.py,"OpenSSL 1.0.1f - Memory Leak",cve-2014-0160
+1,exploits/windows/dos/1.c,"No cve",
+`))
+	require.NoError(t, err)
+	require.Len(t, c, 1)
+	refs := c["CVE-2014-0160"]
+	require.Len(t, refs, 2)
+	assert.Equal(t, "32745", refs[0].Id)
+	assert.Equal(t, "https://www.exploit-db.com/exploits/32745", refs[0].Url)
+
+	err = c.ReadExploitDb(strings.NewReader("id,file\n1,a\n"))
+	assert.Error(t, err)
+}
+
+func TestCatalogRefs(t *testing.T) {
+	c := Catalog{}
+	c.Add("CVE-2014-0160", &Ref{Source: SourceKev, Id: "CVE-2014-0160"})
+	c.Add("CVE-2014-0160", &Ref{Source: SourceExploitDb, Id: "2"})
+	c.Add("CVE-2014-0160", &Ref{Source: SourceExploitDb, Id: "2"})
+	c.Add("CVE-2021-44228", &Ref{Source: SourceExploitDb, Id: "1"})
+
+	refs := c.Refs([]string{"cve-2014-0160", "CVE-2014-0160", "CVE-2021-44228", "CVE-2000-0001"})
+	require.Len(t, refs, 3)
+	assert.Equal(t, SourceKev, refs[0].Source)
+	assert.Equal(t, "1", refs[1].Id)
+	assert.Equal(t, "2", refs[2].Id)
+	assert.Len(t, c.Refs(nil), 0)
+}
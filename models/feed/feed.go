@@ -18,15 +18,20 @@ type ItemType string
 const (
 	TypeComment ItemType = "comment"
 	TypeScan    ItemType = "scan"
+	TypeIssue   ItemType = "issue"
+	TypeMove    ItemType = "move"
 )
 
+// MaxAggregated limits ids kept in an aggregated feed item, the count isn't limited
+const MaxAggregated = 1000
+
 // It's a hack to show custom type as string in swagger
 func (t ItemType) MarshalJSON() ([]byte, error) {
 	return json.Marshal(string(t))
 }
 
 func (t ItemType) Enum() []interface{} {
-	return []interface{}{TypeScan, TypeComment}
+	return []interface{}{TypeScan, TypeComment, TypeIssue, TypeMove}
 }
 
 func (t ItemType) Convert(text string) (interface{}, error) {
@@ -39,7 +44,7 @@ type FeedItem struct {
 	Created time.Time     `json:"created,omitempty" description:"when feed item is created"`
 	Updated time.Time     `json:"updated,omitempty" description:"when feed item is updated"`
 
-	Owner   bson.ObjectId `json:"owner" bson:"owner" description:""`
+	Owner   bson.ObjectId `json:"owner" bson:"owner,omitempty" description:"empty for system events"`
 	Target  bson.ObjectId `json:"target" bson:"target" description:"target for this feed item"`
 	Project bson.ObjectId `json:"project" bson:"project" description:"project for this feed item"`
 
@@ -48,6 +53,14 @@ type FeedItem struct {
 	Scan          *scan.Scan            `json:"scan,omitempty" description:"scan shows only for type: scan"`
 	SummaryReport *target.SummaryReport `json:"summaryReport,omitempty" bson:"summaryReport" description:"shows only for type: scan"`
 	Techs         []*tech.Tech          `json:"techs,omitempty" bson:"techs" description:"shows only for type: scan"`
+
+	// data for aggregated types, similar events in a burst are collapsed into one item
+	Count  int             `json:"count,omitempty" description:"count of collapsed created issues, shows for types: issue, scan; count of rewritten issues for type: move"`
+	Issues []bson.ObjectId `json:"issues,omitempty" bson:"issues,omitempty" description:"first created issues, shows for types: issue, scan"`
+
+	// data for move type, the count is the number of rewritten issues
+	From string `json:"from,omitempty" bson:"from,omitempty" description:"old target address, shows only for type: move"`
+	To   string `json:"to,omitempty" bson:"to,omitempty" description:"new target address, shows only for type: move"`
 }
 
 type Feed struct {
@@ -1,8 +1,11 @@
 package file
 
 import (
+	"fmt"
 	"io"
 
+	"gopkg.in/mgo.v2/bson"
+
 	"github.com/bearded-web/bearded/pkg/utils"
 )
 
@@ -12,6 +15,9 @@ type Meta struct {
 	Size        int    `json:"size,omitempty"`
 	ContentType string `json:"contentType"`
 	MD5         string `json:"md5,omitempty"`
+	Thumbnails  []int  `json:"thumbnails,omitempty" bson:",omitempty" description:"available thumbnail sizes for images"`
+
+	Project bson.ObjectId `json:"project,omitempty" bson:",omitempty" description:"the file counts toward the project storage quota"`
 }
 
 type File struct {
@@ -22,3 +28,20 @@ type File struct {
 func UniqueFileId() string {
 	return utils.UuidV4String()
 }
+
+// id of the thumbnail file with size for the original file
+func ThumbnailId(id string, size int) string {
+	return fmt.Sprintf("%s_%d", id, size)
+}
+
+// Get the smallest available thumbnail which is not less than size.
+// Returns 0 if there is no such thumbnail.
+func (m *Meta) ThumbnailSize(size int) int {
+	found := 0
+	for _, s := range m.Thumbnails {
+		if s >= size && (found == 0 || s < found) {
+			found = s
+		}
+	}
+	return found
+}
@@ -0,0 +1,31 @@
+package inbox
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/notification"
+	"github.com/bearded-web/bearded/pkg/pagination"
+)
+
+// Item is a personal in-app notification
+type Item struct {
+	Id      bson.ObjectId      `json:"id,omitempty" bson:"_id"`
+	User    bson.ObjectId      `json:"user"`
+	Event   notification.Event `json:"event"`
+	Subject string             `json:"subject"`
+	Text    string             `json:"text,omitempty"`
+	Link    string             `json:"link,omitempty"`
+	Read    bool               `json:"read"`
+	Created time.Time          `json:"created,omitempty"`
+}
+
+type ItemList struct {
+	pagination.Meta `json:",inline"`
+	Results         []*Item `json:"results"`
+}
+
+type Unread struct {
+	Count int `json:"count"`
+}
@@ -0,0 +1,141 @@
+package integrity
+
+import (
+	"encoding/json"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Kind is a type of dangling reference
+type Kind string
+
+const (
+	KindIssueTarget   = Kind("issue.target")   // issue of a missing target
+	KindScanTarget    = Kind("scan.target")    // scan of a missing target
+	KindCommentIssue  = Kind("comment.issue")  // issue comment of a missing issue
+	KindCommentTarget = Kind("comment.target") // scan comment of a missing target
+	KindReportSession = Kind("report.session") // report of a missing scan or scan session
+)
+
+var kinds = []interface{}{
+	KindIssueTarget,
+	KindScanTarget,
+	KindCommentIssue,
+	KindCommentTarget,
+	KindReportSession,
+}
+
+// Kinds returns all kinds in the order they are checked
+func Kinds() []Kind {
+	result := make([]Kind, len(kinds))
+	for i, k := range kinds {
+		result[i] = k.(Kind)
+	}
+	return result
+}
+
+// It's a hack to show custom type as string in swagger
+func (t Kind) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+func (t Kind) Enum() []interface{} {
+	return kinds
+}
+
+func (t Kind) Convert(text string) (interface{}, error) {
+	return Kind(text), nil
+}
+
+// Valid returns true if the kind is known
+func (t Kind) Valid() bool {
+	for _, k := range kinds {
+		if k == t {
+			return true
+		}
+	}
+	return false
+}
+
+// CanReattach returns true if objects of the kind could be moved to another target
+func (t Kind) CanReattach() bool {
+	return t == KindIssueTarget || t == KindScanTarget
+}
+
+type Action string
+
+const (
+	ActionDelete   = Action("delete")
+	ActionReattach = Action("reattach")
+)
+
+var actions = []interface{}{
+	ActionDelete,
+	ActionReattach,
+}
+
+// It's a hack to show custom type as string in swagger
+func (t Action) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+func (t Action) Enum() []interface{} {
+	return actions
+}
+
+func (t Action) Convert(text string) (interface{}, error) {
+	return Action(text), nil
+}
+
+// Problem is an object which references a missing one
+type Problem struct {
+	Kind    Kind          `json:"kind"`
+	Id      bson.ObjectId `json:"id" description:"object with the dangling reference"`
+	Ref     bson.ObjectId `json:"ref" description:"missing object"`
+	Project bson.ObjectId `json:"project,omitempty"`
+}
+
+// Report is a result of the integrity check or repair
+type Report struct {
+	Checked  time.Time    `json:"checked"`
+	Counts   map[Kind]int `json:"counts" description:"count of problems by kinds"`
+	Problems []*Problem   `json:"problems" description:"first problems of every kind"`
+	Repaired int          `json:"repaired,omitempty" description:"count of deleted or reattached objects"`
+}
+
+func NewReport(now time.Time) *Report {
+	r := &Report{
+		Checked:  now,
+		Counts:   map[Kind]int{},
+		Problems: []*Problem{},
+	}
+	for _, k := range Kinds() {
+		r.Counts[k] = 0
+	}
+	return r
+}
+
+// Add counts the problem, it's kept in the report only if there are less than limit problems of the kind
+func (r *Report) Add(p *Problem, limit int) {
+	r.Counts[p.Kind]++
+	if r.Counts[p.Kind] <= limit {
+		r.Problems = append(r.Problems, p)
+	}
+}
+
+// Total returns count of all problems
+func (r *Report) Total() int {
+	total := 0
+	for _, c := range r.Counts {
+		total += c
+	}
+	return total
+}
+
+// Repair is a request to fix all problems of the kind
+type Repair struct {
+	Kind   Kind          `json:"kind"`
+	Action Action        `json:"action" description:"one of [delete|reattach]"`
+	Target bson.ObjectId `json:"target,omitempty" description:"existing target for reattach action"`
+}
@@ -0,0 +1,28 @@
+package integrity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestReport(t *testing.T) {
+	r := NewReport(time.Now())
+	assert.Len(t, r.Counts, len(Kinds()))
+	for i := 0; i < 3; i++ {
+		r.Add(&Problem{Kind: KindIssueTarget, Id: bson.NewObjectId()}, 2)
+	}
+	r.Add(&Problem{Kind: KindReportSession, Id: bson.NewObjectId()}, 2)
+	assert.Equal(t, 3, r.Counts[KindIssueTarget])
+	assert.Equal(t, 4, r.Total())
+	assert.Len(t, r.Problems, 3)
+}
+
+func TestKind(t *testing.T) {
+	assert.True(t, KindCommentIssue.Valid())
+	assert.False(t, Kind("issue.scan").Valid())
+	assert.True(t, KindScanTarget.CanReattach())
+	assert.False(t, KindCommentTarget.CanReattach())
+}
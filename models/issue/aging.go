@@ -0,0 +1,68 @@
+package issue
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// AgeLimits are max ages in days of aging buckets, issues older than the last limit are put to the last bucket
+var AgeLimits = []int{7, 30, 90}
+
+type AgeBucket struct {
+	Name   string           `json:"name" description:"like 8-30d"`
+	From   int              `json:"from" description:"min age in days"`
+	To     int              `json:"to,omitempty" description:"max age in days, the last bucket isn't limited"`
+	Total  int              `json:"total"`
+	Issues map[Severity]int `json:"issues" description:"open issues by severity"`
+}
+
+// Aging is the report of open issues by age, issues of unreviewed scans, muted and false ones aren't counted
+type Aging struct {
+	Project bson.ObjectId `json:"project"`
+	Date    time.Time     `json:"date" description:"ages are counted at this time"`
+	Total   int           `json:"total"`
+	Buckets []*AgeBucket  `json:"buckets"`
+}
+
+// NewAging returns the report with empty buckets for AgeLimits
+func NewAging(project bson.ObjectId, now time.Time) *Aging {
+	a := &Aging{Project: project, Date: now, Buckets: []*AgeBucket{}}
+	from := 0
+	for _, to := range AgeLimits {
+		a.Buckets = append(a.Buckets, &AgeBucket{
+			Name:   fmt.Sprintf("%d-%dd", from, to),
+			From:   from,
+			To:     to,
+			Issues: map[Severity]int{},
+		})
+		from = to + 1
+	}
+	a.Buckets = append(a.Buckets, &AgeBucket{
+		Name:   fmt.Sprintf(">%dd", from-1),
+		From:   from,
+		Issues: map[Severity]int{},
+	})
+	return a
+}
+
+// AgeBucketStart returns the time since which issues are put to the bucket, zero for the last bucket
+func AgeBucketStart(now time.Time, bucket int) time.Time {
+	if bucket >= len(AgeLimits) {
+		return time.Time{}
+	}
+	// issue is N days old until N+1 days pass
+	return now.Add(-time.Duration(AgeLimits[bucket]+1) * 24 * time.Hour)
+}
+
+// Add counts issues of the bucket with the severity
+func (a *Aging) Add(bucket int, sev Severity, count int) {
+	if bucket < 0 || bucket >= len(a.Buckets) {
+		return
+	}
+	b := a.Buckets[bucket]
+	b.Issues[sev] += count
+	b.Total += count
+	a.Total += count
+}
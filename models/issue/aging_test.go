@@ -0,0 +1,36 @@
+package issue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAging(t *testing.T) {
+	a := NewAging("", time.Now())
+	require.Len(t, a.Buckets, 4)
+	names := []string{}
+	for _, b := range a.Buckets {
+		names = append(names, b.Name)
+	}
+	assert.Equal(t, []string{"0-7d", "8-30d", "31-90d", ">90d"}, names)
+	assert.Equal(t, 91, a.Buckets[3].From)
+	assert.Equal(t, 0, a.Buckets[3].To)
+
+	a.Add(1, SeverityHigh, 2)
+	a.Add(1, SeverityLow, 1)
+	a.Add(3, SeverityHigh, 1)
+	a.Add(4, SeverityHigh, 1)
+	assert.Equal(t, 4, a.Total)
+	assert.Equal(t, 3, a.Buckets[1].Total)
+	assert.Equal(t, 2, a.Buckets[1].Issues[SeverityHigh])
+}
+
+func TestAgeBucketStart(t *testing.T) {
+	now := time.Date(2015, 6, 30, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2015, 6, 22, 12, 0, 0, 0, time.UTC), AgeBucketStart(now, 0))
+	assert.Equal(t, time.Date(2015, 5, 30, 12, 0, 0, 0, time.UTC), AgeBucketStart(now, 1))
+	assert.True(t, AgeBucketStart(now, 3).IsZero())
+}
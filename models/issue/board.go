@@ -0,0 +1,96 @@
+package issue
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Column is a board column, every issue is in one column by its status
+type Column string
+
+const (
+	ColumnOpen      Column = "open"
+	ColumnConfirmed Column = "confirmed"
+	ColumnMuted     Column = "muted"
+	ColumnFalse     Column = "false"
+	ColumnResolved  Column = "resolved"
+)
+
+// Columns are ordered by status rank
+var Columns = []Column{ColumnOpen, ColumnConfirmed, ColumnMuted, ColumnFalse, ColumnResolved}
+
+// allowed moves between columns, false positives and resolved issues should be reopened first
+var transitions = map[Column][]Column{
+	ColumnOpen:      {ColumnConfirmed, ColumnMuted, ColumnFalse, ColumnResolved},
+	ColumnConfirmed: {ColumnOpen, ColumnMuted, ColumnResolved},
+	ColumnMuted:     {ColumnOpen, ColumnConfirmed},
+	ColumnFalse:     {ColumnOpen},
+	ColumnResolved:  {ColumnOpen, ColumnConfirmed},
+}
+
+func (c Column) IsValid() bool {
+	_, ok := transitions[c]
+	return ok
+}
+
+// Rank is the status rank of issues in the column
+func (c Column) Rank() int {
+	for i, col := range Columns {
+		if col == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// CanMove reports whether issues could be moved from the column to another one
+func (c Column) CanMove(to Column) bool {
+	for _, col := range transitions[c] {
+		if col == to {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Status) Column() Column {
+	return Columns[s.Rank()]
+}
+
+// Move sets the status for the column and adds the activity, transition rules aren't checked here
+func (i *TargetIssue) Move(to Column, user bson.ObjectId) {
+	confirmed := i.Confirmed
+	now := time.Now().UTC()
+	if i.Resolved && to != ColumnResolved {
+		i.reopen(now)
+	}
+	i.Status = Status{}
+	activity := ActivityReopened
+	switch to {
+	case ColumnConfirmed:
+		i.Confirmed = true
+		activity = ActivityConfirmed
+	case ColumnMuted:
+		i.Confirmed = confirmed
+		i.Muted = true
+		activity = ActivityMuted
+	case ColumnFalse:
+		i.False = true
+		activity = ActivityFalse
+	case ColumnResolved:
+		i.Confirmed = confirmed
+		i.Resolved = true
+		activity = ActivityResolved
+	}
+	if i.Resolved {
+		i.ResolvedAt = now
+	} else {
+		i.ResolvedAt = time.Time{}
+	}
+	i.Activities = append(i.Activities, &Activity{
+		Created: now,
+		Type:    activity,
+		User:    user,
+	})
+}
@@ -0,0 +1,40 @@
+package issue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestColumns(t *testing.T) {
+	for i, col := range Columns {
+		assert.Equal(t, i, col.Rank())
+		obj := &TargetIssue{}
+		obj.Move(col, "")
+		assert.Equal(t, col, obj.Column())
+	}
+	assert.Equal(t, ColumnMuted, Status{Confirmed: true, Muted: true}.Column())
+	assert.True(t, ColumnOpen.CanMove(ColumnFalse))
+	assert.False(t, ColumnConfirmed.CanMove(ColumnFalse))
+	assert.False(t, ColumnResolved.CanMove(ColumnMuted))
+	assert.False(t, Column("done").IsValid())
+}
+
+func TestMove(t *testing.T) {
+	user := bson.NewObjectId()
+	obj := &TargetIssue{Status: Status{Confirmed: true}}
+
+	obj.Move(ColumnResolved, user)
+	assert.Equal(t, ColumnResolved, obj.Column())
+	assert.True(t, obj.Confirmed)
+	assert.False(t, obj.ResolvedAt.IsZero())
+
+	obj.Move(ColumnOpen, user)
+	assert.Equal(t, ColumnOpen, obj.Column())
+	assert.Equal(t, Status{}, obj.Status)
+	assert.True(t, obj.ResolvedAt.IsZero())
+	assert.Len(t, obj.Activities, 2)
+	assert.Equal(t, ActivityReopened, obj.Activities[1].Type)
+	assert.Equal(t, user, obj.Activities[1].User)
+}
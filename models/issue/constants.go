@@ -20,6 +20,26 @@ var severities = []interface{}{
 	SeverityError,
 }
 
+// Rank is used for sorting issues by severity, error is the lowest
+func (t Severity) Rank() int {
+	switch t {
+	case SeverityInfo:
+		return 1
+	case SeverityLow:
+		return 2
+	case SeverityMedium:
+		return 3
+	case SeverityHigh:
+		return 4
+	}
+	return 0
+}
+
+// IsValid reports whether the severity could be set for issue, error isn't allowed
+func (t Severity) IsValid() bool {
+	return t == SeverityInfo || t == SeverityLow || t == SeverityMedium || t == SeverityHigh
+}
+
 // It's a hack to show custom type as string in swagger
 func (t Severity) MarshalJSON() ([]byte, error) {
 	return json.Marshal(string(t))
@@ -33,6 +53,60 @@ func (t Severity) Convert(text string) (interface{}, error) {
 	return Severity(text), nil
 }
 
+// Confidence shows how sure the plugin is that the issue is real
+type Confidence string
+
+const (
+	ConfidenceCertain   = Confidence("certain")   // confirmed by exploitation
+	ConfidenceFirm      = Confidence("firm")      // reliable detection
+	ConfidenceTentative = Confidence("tentative") // heuristic, often false positive
+)
+
+var confidences = []interface{}{
+	ConfidenceCertain,
+	ConfidenceFirm,
+	ConfidenceTentative,
+}
+
+// Rank is used for comparing confidences, issues without confidence are firm
+func (c Confidence) Rank() int {
+	switch c {
+	case ConfidenceTentative:
+		return 1
+	case ConfidenceCertain:
+		return 3
+	}
+	return 2
+}
+
+func (c Confidence) IsValid() bool {
+	return c == ConfidenceCertain || c == ConfidenceFirm || c == ConfidenceTentative
+}
+
+// AtLeast returns confidences not lower than c, empty one is included with firm
+func (c Confidence) AtLeast() []Confidence {
+	results := []Confidence{}
+	for _, v := range []Confidence{ConfidenceCertain, ConfidenceFirm, "", ConfidenceTentative} {
+		if v.Rank() >= c.Rank() {
+			results = append(results, v)
+		}
+	}
+	return results
+}
+
+// It's a hack to show custom type as string in swagger
+func (c Confidence) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(c))
+}
+
+func (c Confidence) Enum() []interface{} {
+	return confidences
+}
+
+func (c Confidence) Convert(text string) (interface{}, error) {
+	return Confidence(text), nil
+}
+
 //
 //type Affect string
 //
@@ -44,14 +118,17 @@ func (t Severity) Convert(text string) (interface{}, error) {
 type ActivityType string
 
 const (
-	ActivityReported  = ActivityType("reported")  // the issue was reported by plugin or user
-	ActivityConfirmed = ActivityType("confirmed") // the issue was confirmed by someone
-	ActivityMuted     = ActivityType("muted")     // go away! I'll fix you later
-	ActivityUnmuted   = ActivityType("unmuted")
-	ActivityFalse     = ActivityType("false") // set to false
-	ActivityTrue      = ActivityType("true")  // set to true
-	ActivityResolved  = ActivityType("resolved")
-	ActivityReopened  = ActivityType("reopened")
+	ActivityReported     = ActivityType("reported")  // the issue was reported by plugin or user
+	ActivityConfirmed    = ActivityType("confirmed") // the issue was confirmed by someone
+	ActivityMuted        = ActivityType("muted")     // go away! I'll fix you later
+	ActivityUnmuted      = ActivityType("unmuted")
+	ActivityFalse        = ActivityType("false") // set to false
+	ActivityTrue         = ActivityType("true")  // set to true
+	ActivityResolved     = ActivityType("resolved")
+	ActivityReopened     = ActivityType("reopened")
+	ActivityRetested     = ActivityType("retested")     // retest scan is finished
+	ActivityAcknowledged = ActivityType("acknowledged") // someone is working on the issue, stops escalation
+	ActivityMoved        = ActivityType("moved")        // the target is moved to another host, urls are rewritten
 )
 
 var activities = []interface{}{
@@ -61,6 +138,9 @@ var activities = []interface{}{
 	ActivityUnmuted,
 	ActivityFalse,
 	ActivityTrue,
+	ActivityRetested,
+	ActivityAcknowledged,
+	ActivityMoved,
 }
 
 // It's a hack to show custom type as string in swagger
@@ -75,3 +155,32 @@ func (t ActivityType) Enum() []interface{} {
 func (t ActivityType) Convert(text string) (interface{}, error) {
 	return ActivityType(text), nil
 }
+
+type RetestStatus string
+
+const (
+	RetestPending       = RetestStatus("pending")        // retest scan is in progress
+	RetestVulnerable    = RetestStatus("vulnerable")     // the issue was reported again
+	RetestNotReproduced = RetestStatus("not-reproduced") // scan is finished without the issue
+	RetestFailed        = RetestStatus("failed")         // scan is failed, nothing is known
+)
+
+var retestStatuses = []interface{}{
+	RetestPending,
+	RetestVulnerable,
+	RetestNotReproduced,
+	RetestFailed,
+}
+
+// It's a hack to show custom type as string in swagger
+func (t RetestStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+func (t RetestStatus) Enum() []interface{} {
+	return retestStatuses
+}
+
+func (t RetestStatus) Convert(text string) (interface{}, error) {
+	return RetestStatus(text), nil
+}
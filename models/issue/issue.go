@@ -6,7 +6,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bearded-web/bearded/models/exploit"
 	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/pkg/urlnorm"
 	"gopkg.in/mgo.v2/bson"
 )
 
@@ -20,41 +22,135 @@ type Reference struct {
 	Title string `json:"title,omitempty"`
 }
 
+// Location is a place in source code reported by static analysis plugins
+type Location struct {
+	Path    string `json:"path" description:"file path relative to the repository root"`
+	Line    int    `json:"line,omitempty"`
+	EndLine int    `json:"endLine,omitempty" bson:"endLine,omitempty"`
+	Column  int    `json:"column,omitempty" bson:",omitempty"`
+	Snippet string `json:"snippet,omitempty" bson:",omitempty" description:"code fragment"`
+	Url     string `json:"url,omitempty" bson:",omitempty" description:"link to the line on repository hosting, set by server"`
+}
+
+// String returns location like path/to/file.go:12
+func (l *Location) String() string {
+	if l.Line > 0 {
+		return fmt.Sprintf("%s:%d", l.Path, l.Line)
+	}
+	return l.Path
+}
+
 type Issue struct {
 	UniqId     string       `json:"uniqId,omitempty" bson:"uniqId" description:"id for merging similar issues"`
 	Summary    string       `json:"summary"`
 	VulnType   int          `json:"vulnType,omitempty" bson:"vulnType" description:"vulnerability type from vulndb"`
 	Severity   Severity     `json:"severity"`
+	Confidence Confidence   `json:"confidence,omitempty" bson:",omitempty" description:"one of [certain|firm|tentative], firm if empty"`
 	References []*Reference `json:"references,omitempty" bson:"references" description:"information about vulnerability"`
 	Extras     []*Extra     `json:"extras,omitempty" bson:"extras" description:"information about vulnerability, deprecated"`
 	Desc       string       `json:"desc,omitempty"`
 	Vector     *Vector      `json:"vector,omitempty"`
+	Operation  string       `json:"operation,omitempty" bson:",omitempty" description:"api operation for api targets, like GET /pets/{id}"`
+	Location   *Location    `json:"location,omitempty" bson:",omitempty" description:"source code location for repository targets"`
+
+	Cve         []string `json:"cve,omitempty" description:"related CVE identifiers, like CVE-2014-0160"`
+	Remediation string   `json:"remediation,omitempty" description:"how to fix the issue"`
+	Enriched    []string `json:"enriched,omitempty" description:"fields populated from vulndb, one of [desc|remediation|references]"`
 	//	Affect   Affect   `json:"affect,omitempty" description:"who is affected by the issue?"`
 }
 
+// Request returns method and url where the issue is happened, method is empty if unknown
+func (i *Issue) Request() (string, string) {
+	if i.Vector == nil {
+		return "", ""
+	}
+	for _, tr := range i.Vector.HttpTransactions {
+		if tr != nil && tr.Url != "" {
+			return tr.Method, tr.Url
+		}
+	}
+	return "", i.Vector.Url
+}
+
+// FingerprintVersion is the version of GenerateUniqId,
+// stored issues with older fingerprints are upgraded on start of the api server
+const FingerprintVersion = 1
+
+// GenerateUniqId returns the fingerprint of the issue,
+// urls are normalized so trivially different urls give the same fingerprint
 func (i *Issue) GenerateUniqId() string {
+	return i.fingerprint(urlnorm.Normalize)
+}
+
+// legacyUniqId returns the fingerprint of version 0, when urls weren't normalized
+func (i *Issue) legacyUniqId() string {
+	return i.fingerprint(func(u string) string { return u })
+}
+
+// GeneratedUniqId reports whether the uniq id is generated by the server with any version of fingerprints,
+// uniq ids set by plugins aren't generated
+func (i *Issue) GeneratedUniqId() bool {
+	return i.UniqId != "" && (i.UniqId == i.GenerateUniqId() || i.UniqId == i.legacyUniqId())
+}
+
+// UpgradeUniqId replaces the generated uniq id of the old version with the current one,
+// returns true if the uniq id is changed
+func (i *Issue) UpgradeUniqId() bool {
+	if i.UniqId == "" || i.UniqId != i.legacyUniqId() {
+		return false
+	}
+	val := i.GenerateUniqId()
+	if val == i.UniqId {
+		return false
+	}
+	i.UniqId = val
+	return true
+}
+
+func (i *Issue) fingerprint(norm func(string) string) string {
 	fields := []string{}
 	fields = append(fields, i.Summary)
 	fields = append(fields, fmt.Sprintf("%d", i.VulnType))
 	fields = append(fields, i.Desc)
 
 	if i.Vector != nil {
-		fields = append(fields, i.Vector.Url)
+		fields = append(fields, norm(i.Vector.Url))
 		for _, transaction := range i.Vector.HttpTransactions {
 			fields = append(
 				fields,
 				transaction.Method,
-				transaction.Url,
+				norm(transaction.Url),
 				fmt.Sprintf("%#v", transaction.Params),
 				fmt.Sprintf("%#v", transaction.Request),
 			)
 		}
 	}
+	if i.Location != nil {
+		fields = append(fields, i.Location.String())
+	}
 	hash := md5.New()
 	hash.Write([]byte(strings.Join(fields, ":")))
 	return fmt.Sprintf("%x", hash.Sum(nil))
 }
 
+// Same reports whether other is the same vulnerability, e.g. found by retest
+func (i *Issue) Same(other *Issue) bool {
+	if i.UniqId != "" && i.UniqId == other.UniqId {
+		return true
+	}
+	return i.Summary == other.Summary && i.VulnType == other.VulnType
+}
+
+// HasReference reports whether the issue has a reference with url
+func (i *Issue) HasReference(url string) bool {
+	for _, ref := range i.References {
+		if ref.Url == url {
+			return true
+		}
+	}
+	return false
+}
+
 type Status struct {
 	Confirmed bool `json:"confirmed" description:"the issue was confirmed by someone"`
 	False     bool `json:"false"`
@@ -62,6 +158,27 @@ type Status struct {
 	Resolved  bool `json:"resolved"`
 }
 
+// Rank is used for sorting issues by status: open, confirmed, muted, false, resolved
+func (s Status) Rank() int {
+	switch {
+	case s.Resolved:
+		return 4
+	case s.False:
+		return 3
+	case s.Muted:
+		return 2
+	case s.Confirmed:
+		return 1
+	}
+	return 0
+}
+
+// Exposure describes how easy is to reach the issue
+type Exposure struct {
+	InternetFacing bool `json:"internetFacing" bson:"internetFacing" description:"the issue is reachable from the internet"`
+	AuthRequired   bool `json:"authRequired" bson:"authRequired" description:"attacker should be authenticated"`
+}
+
 type Report struct {
 	Report      bson.ObjectId `json:"report" description:"report id"`
 	Scan        bson.ObjectId `json:"scan,omitempty" description:"scan id"`
@@ -74,6 +191,23 @@ type Activity struct {
 
 	User   bson.ObjectId `json:"user,omitempty" bson:",omitempty" description:"who did the activity"`
 	Report *Report       `json:"report,omitempty" description:"link to report for reported activity"`
+	Retest RetestStatus  `json:"retest,omitempty" bson:",omitempty" description:"result for retested activity"`
+	From   string        `json:"from,omitempty" bson:",omitempty" description:"old host for moved activity"`
+	To     string        `json:"to,omitempty" bson:",omitempty" description:"new host for moved activity"`
+}
+
+type Retest struct {
+	Status      RetestStatus  `json:"status" description:"one of [pending|vulnerable|not-reproduced|failed]"`
+	User        bson.ObjectId `json:"user,omitempty" bson:",omitempty" description:"who requested the retest"`
+	Scan        bson.ObjectId `json:"scan" description:"retest scan id"`
+	ScanSession bson.ObjectId `json:"scanSession" bson:"scanSession" description:"retest scan session id"`
+	Created     time.Time     `json:"created"`
+	Finished    *time.Time    `json:"finished,omitempty" bson:",omitempty"`
+}
+
+type Acknowledgement struct {
+	User    bson.ObjectId `json:"user" description:"who acknowledged the issue"`
+	Created time.Time     `json:"created"`
 }
 
 type TargetIssue struct {
@@ -84,6 +218,43 @@ type TargetIssue struct {
 	Updated    time.Time     `json:"updated,omitempty" description:"when issue is updated"`
 	ResolvedAt time.Time     `json:"resolvedAt,omitempty" bson:"resolvedAt" description:"resolved time"`
 	Activities []*Activity   `json:"activities,omitempty"`
+	Retest     *Retest       `json:"retest,omitempty" bson:",omitempty" description:"the last retest"`
+	Exposure   Exposure      `json:"exposure"`
+	Risk       int           `json:"risk" description:"composite risk score from 0 to 100, computed by server"`
+	Assignee   bson.ObjectId `json:"assignee,omitempty" bson:",omitempty" description:"user responsible for the issue"`
+	Team       bson.ObjectId `json:"team,omitempty" bson:",omitempty" description:"project team responsible for the issue"`
+	Labels     []string      `json:"labels,omitempty" bson:",omitempty"`
+	// it's target.Environment, copied from the target for filtering
+	Environment string `json:"environment,omitempty" bson:"environment,omitempty" description:"environment of the target, one of [prod|staging|dev]"`
+	// it's target.Criticality, copied from the target for scoring
+	Criticality string `json:"criticality,omitempty" bson:"criticality,omitempty" description:"criticality of the target, one of [low|medium|high|critical]"`
+
+	Fields map[string]interface{} `json:"fields,omitempty" bson:",omitempty" description:"values of custom project fields"`
+	Links  []*Link                `json:"links,omitempty" bson:",omitempty" description:"relationships with other issues"`
+
+	PendingScan bson.ObjectId `json:"pendingScan,omitempty" bson:"pendingScan,omitempty" description:"the issue is found by a scan waiting for review, it isn't counted in target summary till then"`
+
+	Reopened   int       `json:"reopened" bson:"reopened" description:"how many times the resolved issue was reopened"`
+	ReopenedAt time.Time `json:"reopenedAt,omitempty" bson:"reopenedAt,omitempty" description:"when the issue was reopened last time, escalation starts again from it"`
+
+	Voters []bson.ObjectId `json:"voters,omitempty" bson:",omitempty" description:"project members who upvoted the issue as important for business"`
+	Votes  int             `json:"votes" bson:"votes" description:"number of voters, set by server"`
+
+	Ticket *Ticket `json:"ticket,omitempty" bson:"ticket,omitempty" description:"linked record in an external tracker, set by server"`
+
+	Exploitable bool           `json:"exploitable" bson:"exploitable" description:"there are known exploits of issue cves, set by server"`
+	Exploits    []*exploit.Ref `json:"exploits,omitempty" bson:"exploits,omitempty" description:"public exploits and exploitation in the wild of issue cves, set by server"`
+
+	Acknowledged    *Acknowledgement `json:"acknowledged,omitempty" bson:",omitempty"`
+	EscalationLevel int              `json:"escalationLevel" bson:"escalationLevel" description:"number of escalation steps passed"`
+
+	// version of the fingerprint, see FingerprintVersion
+	Fingerprint int `json:"-" bson:"fingerprint,omitempty"`
+
+	// denormalized fields for sorting
+	SeverityRank int       `json:"-" bson:"severityRank"`
+	StatusRank   int       `json:"-" bson:"statusRank"`
+	LastActivity time.Time `json:"lastActivity,omitempty" bson:"lastActivity,omitempty" description:"time of the last activity"`
 
 	// usually this field is taken from the last report
 	Issue  `json:",inline" bson:",inline"`
@@ -110,6 +281,127 @@ func (i *TargetIssue) AddReportActivity(reportId, scanId, sessionId bson.ObjectI
 	})
 }
 
+// Reopen marks the resolved issue as unresolved and counts the reopening, it returns false if the issue isn't resolved
+func (i *TargetIssue) Reopen() bool {
+	if !i.Resolved {
+		return false
+	}
+	i.Resolved = false
+	i.ResolvedAt = time.Time{}
+	i.reopen(time.Now().UTC())
+	return true
+}
+
+// reopen counts the reopening and restarts escalation, the old acknowledgement was for the fixed issue
+func (i *TargetIssue) reopen(now time.Time) {
+	i.Reopened++
+	i.ReopenedAt = now
+	i.Acknowledged = nil
+	i.EscalationLevel = 0
+}
+
+// EscalationAge returns how long the issue is open since it was created or reopened last time
+func (i *TargetIssue) EscalationAge(now time.Time) time.Duration {
+	if i.ReopenedAt.After(i.Created) {
+		return now.Sub(i.ReopenedAt)
+	}
+	return now.Sub(i.Created)
+}
+
+// IsFlapping reports whether the fix of the issue keeps regressing
+func (i *TargetIssue) IsFlapping() bool {
+	return i.Reopened >= FlappingReopens
+}
+
+// Acknowledge stops escalation of the issue
+func (i *TargetIssue) Acknowledge(userId bson.ObjectId) {
+	now := time.Now().UTC()
+	i.Acknowledged = &Acknowledgement{User: userId, Created: now}
+	i.Activities = append(i.Activities, &Activity{
+		Created: now,
+		Type:    ActivityAcknowledged,
+		User:    userId,
+	})
+}
+
+// HasVoter reports whether the user upvoted the issue
+func (i *TargetIssue) HasVoter(userId bson.ObjectId) bool {
+	for _, id := range i.Voters {
+		if id == userId {
+			return true
+		}
+	}
+	return false
+}
+
+// AddLabel adds label if the issue doesn't have it yet
+func (i *TargetIssue) AddLabel(label string) {
+	for _, l := range i.Labels {
+		if l == label {
+			return
+		}
+	}
+	i.Labels = append(i.Labels, label)
+}
+
+// UpdateRanks updates denormalized fields used for sorting,
+// the last activity of issues without activities is their creation
+func (i *TargetIssue) UpdateRanks() {
+	i.SeverityRank = i.Severity.Rank()
+	i.StatusRank = i.Status.Rank()
+	if i.LastActivity.IsZero() {
+		i.LastActivity = i.Created
+	}
+	for _, act := range i.Activities {
+		if act.Created.After(i.LastActivity) {
+			i.LastActivity = act.Created
+		}
+	}
+}
+
+// LastScanReport returns the report link of the last activity reported by plugin
+func (i *TargetIssue) LastScanReport() *Report {
+	for n := len(i.Activities) - 1; n >= 0; n-- {
+		act := i.Activities[n]
+		if act.Type == ActivityReported && act.Report != nil && act.Report.ScanSession != "" {
+			return act.Report
+		}
+	}
+	return nil
+}
+
+// FinishRetest sets the result of the current retest and adds retested activity
+func (i *TargetIssue) FinishRetest(status RetestStatus) {
+	if i.Retest == nil {
+		return
+	}
+	now := time.Now().UTC()
+	i.Retest.Status = status
+	i.Retest.Finished = &now
+	i.Activities = append(i.Activities, &Activity{
+		Created: now,
+		Type:    ActivityRetested,
+		Retest:  status,
+		Report: &Report{
+			Scan:        i.Retest.Scan,
+			ScanSession: i.Retest.ScanSession,
+		},
+	})
+}
+
+// issues reopened this number of times are flapping
+const FlappingReopens = 2
+
+// Flappiness is the project metric of fixes which don't stick
+type Flappiness struct {
+	Project  bson.ObjectId `json:"project"`
+	Issues   int           `json:"issues" description:"issues which were resolved at least once"`
+	Reopened int           `json:"reopened" description:"issues which were reopened at least once"`
+	Flapping int           `json:"flapping" description:"issues which were reopened twice or more"`
+	Reopens  int           `json:"reopens" description:"total number of reopenings"`
+	Rate     float64       `json:"rate" description:"reopened issues to resolved ones, from 0 to 1"`
+}
+
 type TargetIssueList struct {
 	pagination.Meta `json:",inline"`
 	Results         []*TargetIssue `json:"results"`
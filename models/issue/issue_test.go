@@ -0,0 +1,150 @@
+package issue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestIssueSame(t *testing.T) {
+	a := &Issue{UniqId: "1", Summary: "xss", VulnType: 1}
+	assert.True(t, a.Same(&Issue{UniqId: "1", Summary: "other"}))
+	assert.True(t, a.Same(&Issue{Summary: "xss", VulnType: 1}))
+	assert.False(t, a.Same(&Issue{UniqId: "2", Summary: "xss"}))
+}
+
+func TestIssueGenerateUniqId(t *testing.T) {
+	a := &Issue{Summary: "XSS", Vector: &Vector{Url: "http://Example.com:80/search/?q=1&page=2"}}
+	b := &Issue{Summary: "XSS", Vector: &Vector{Url: "http://example.com/search?page=2&q=1"}}
+	c := &Issue{Summary: "XSS", Vector: &Vector{Url: "http://example.com/search?page=3&q=1"}}
+	assert.Equal(t, a.GenerateUniqId(), b.GenerateUniqId())
+	assert.NotEqual(t, a.GenerateUniqId(), c.GenerateUniqId())
+}
+
+func TestIssueUpgradeUniqId(t *testing.T) {
+	a := &Issue{Summary: "XSS", Vector: &Vector{Url: "http://Example.com:80/search/?q=1&page=2"}}
+	a.UniqId = a.legacyUniqId()
+	assert.True(t, a.GeneratedUniqId())
+	assert.True(t, a.UpgradeUniqId())
+	assert.Equal(t, a.GenerateUniqId(), a.UniqId)
+	assert.True(t, a.GeneratedUniqId())
+	assert.False(t, a.UpgradeUniqId())
+
+	// uniq ids of plugins are kept
+	b := &Issue{UniqId: "plugin-id", Summary: "XSS", Vector: &Vector{Url: "http://Example.com/"}}
+	assert.False(t, b.GeneratedUniqId())
+	assert.False(t, b.UpgradeUniqId())
+	assert.Equal(t, "plugin-id", b.UniqId)
+}
+
+func TestTargetIssueRetest(t *testing.T) {
+	scanId, sessId := bson.NewObjectId(), bson.NewObjectId()
+	obj := &TargetIssue{}
+	assert.Nil(t, obj.LastScanReport())
+
+	obj.AddReportActivity(bson.NewObjectId(), bson.NewObjectId(), bson.NewObjectId())
+	obj.AddReportActivity(bson.NewObjectId(), scanId, sessId)
+	obj.AddUserReportActivity(bson.NewObjectId())
+	rep := obj.LastScanReport()
+	require.NotNil(t, rep)
+	assert.Equal(t, sessId, rep.ScanSession)
+
+	// nothing to finish
+	obj.FinishRetest(RetestVulnerable)
+	assert.Len(t, obj.Activities, 3)
+
+	obj.Retest = &Retest{Status: RetestPending, Scan: scanId, ScanSession: sessId}
+	obj.FinishRetest(RetestNotReproduced)
+	assert.Equal(t, RetestNotReproduced, obj.Retest.Status)
+	assert.NotNil(t, obj.Retest.Finished)
+	require.Len(t, obj.Activities, 4)
+	assert.Equal(t, ActivityRetested, obj.Activities[3].Type)
+	assert.Equal(t, RetestNotReproduced, obj.Activities[3].Retest)
+}
+
+func TestTargetIssueUpdateRanks(t *testing.T) {
+	obj := &TargetIssue{
+		Issue:  Issue{Severity: SeverityHigh},
+		Status: Status{Confirmed: true, Muted: true},
+	}
+	obj.AddUserReportActivity(bson.NewObjectId())
+	obj.UpdateRanks()
+	assert.Equal(t, 4, obj.SeverityRank)
+	assert.Equal(t, 2, obj.StatusRank)
+	assert.Equal(t, obj.Activities[0].Created, obj.LastActivity)
+
+	created := time.Now().UTC()
+	obj = &TargetIssue{Created: created}
+	obj.UpdateRanks()
+	assert.Equal(t, created, obj.LastActivity)
+
+	assert.True(t, SeverityLow.Rank() < SeverityMedium.Rank())
+	assert.Equal(t, 0, SeverityError.Rank())
+	assert.Equal(t, 0, Status{}.Rank())
+	assert.Equal(t, 4, Status{Resolved: true, False: true}.Rank())
+}
+
+func TestTargetIssueHasVoter(t *testing.T) {
+	u := bson.NewObjectId()
+	obj := &TargetIssue{}
+	assert.False(t, obj.HasVoter(u))
+	obj.Voters = append(obj.Voters, bson.NewObjectId(), u)
+	assert.True(t, obj.HasVoter(u))
+}
+
+func TestConfidenceAtLeast(t *testing.T) {
+	assert.Equal(t, []Confidence{ConfidenceCertain}, ConfidenceCertain.AtLeast())
+	assert.Equal(t, []Confidence{ConfidenceCertain, ConfidenceFirm, ""}, ConfidenceFirm.AtLeast())
+	assert.Len(t, ConfidenceTentative.AtLeast(), 4)
+}
+
+func TestLinkType(t *testing.T) {
+	for _, typ := range []LinkType{LinkDuplicates, LinkCausedBy, LinkBlocks, LinkRelatedTo} {
+		assert.True(t, typ.IsValid())
+		assert.Equal(t, typ, typ.Inverse().Inverse())
+	}
+	assert.Equal(t, LinkCauses, LinkCausedBy.Inverse())
+	assert.False(t, LinkType("parent").IsValid())
+}
+
+func TestTargetIssueReopen(t *testing.T) {
+	obj := &TargetIssue{}
+	assert.False(t, obj.Reopen())
+	assert.Equal(t, 0, obj.Reopened)
+
+	for i := 1; i <= FlappingReopens; i++ {
+		obj.Resolved = true
+		obj.ResolvedAt = time.Now()
+		assert.True(t, obj.Reopen())
+		assert.False(t, obj.Resolved)
+		assert.True(t, obj.ResolvedAt.IsZero())
+		assert.Equal(t, i, obj.Reopened)
+	}
+	assert.True(t, obj.IsFlapping())
+
+	// moving from resolved column is reopening too
+	obj.Move(ColumnResolved, bson.NewObjectId())
+	obj.Move(ColumnConfirmed, bson.NewObjectId())
+	assert.Equal(t, FlappingReopens+1, obj.Reopened)
+}
+
+func TestTargetIssueReopenEscalation(t *testing.T) {
+	now := time.Now().UTC()
+	obj := &TargetIssue{Created: now.Add(-48 * time.Hour), EscalationLevel: 2}
+	obj.Acknowledge(bson.NewObjectId())
+	assert.Equal(t, 48*time.Hour, obj.EscalationAge(now))
+
+	obj.Resolved = true
+	require.True(t, obj.Reopen())
+	assert.Nil(t, obj.Acknowledged)
+	assert.Equal(t, 0, obj.EscalationLevel)
+	assert.True(t, obj.EscalationAge(time.Now().UTC()) < time.Minute)
+
+	obj.Acknowledge(bson.NewObjectId())
+	obj.Move(ColumnResolved, bson.NewObjectId())
+	obj.Move(ColumnOpen, bson.NewObjectId())
+	assert.Nil(t, obj.Acknowledged)
+}
@@ -0,0 +1,66 @@
+package issue
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/pkg/pagination"
+)
+
+type LinkType string
+
+const (
+	LinkDuplicates   LinkType = "duplicates"
+	LinkDuplicatedBy LinkType = "duplicated-by"
+	LinkCausedBy     LinkType = "caused-by"
+	LinkCauses       LinkType = "causes"
+	LinkBlocks       LinkType = "blocks"
+	LinkBlockedBy    LinkType = "blocked-by"
+	LinkRelatedTo    LinkType = "related-to"
+)
+
+var linkInverse = map[LinkType]LinkType{
+	LinkDuplicates:   LinkDuplicatedBy,
+	LinkDuplicatedBy: LinkDuplicates,
+	LinkCausedBy:     LinkCauses,
+	LinkCauses:       LinkCausedBy,
+	LinkBlocks:       LinkBlockedBy,
+	LinkBlockedBy:    LinkBlocks,
+	LinkRelatedTo:    LinkRelatedTo,
+}
+
+func (t LinkType) IsValid() bool {
+	_, ok := linkInverse[t]
+	return ok
+}
+
+// Inverse returns the type of the link from the other side, like caused-by for causes
+func (t LinkType) Inverse() LinkType {
+	return linkInverse[t]
+}
+
+// Link is a typed relationship with another issue, both issues keep the link
+type Link struct {
+	Type    LinkType      `json:"type" description:"one of [duplicates duplicated-by caused-by causes blocks blocked-by related-to]"`
+	Issue   bson.ObjectId `json:"issue" description:"linked issue"`
+	Target  bson.ObjectId `json:"target" description:"target of the linked issue"`
+	User    bson.ObjectId `json:"user,omitempty" bson:",omitempty" description:"who linked issues"`
+	Created time.Time     `json:"created"`
+}
+
+type LinkList struct {
+	pagination.Meta `json:",inline"`
+	Results         []*Link `json:"results"`
+}
+
+// GetLinks returns links with the issue
+func (i *TargetIssue) GetLinks(id bson.ObjectId) []*Link {
+	links := []*Link{}
+	for _, l := range i.Links {
+		if l.Issue == id {
+			links = append(links, l)
+		}
+	}
+	return links
+}
@@ -0,0 +1,104 @@
+package issue
+
+import (
+	"sort"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// MttrStat is the mean time to remediate resolved issues
+type MttrStat struct {
+	Resolved int     `json:"resolved" description:"resolved issues"`
+	Hours    float64 `json:"hours" description:"mean hours from creation to resolution"`
+
+	total float64
+}
+
+func (s *MttrStat) add(count int, hours float64) {
+	s.Resolved += count
+	s.total += hours
+	s.Hours = s.total / float64(s.Resolved)
+}
+
+type AssigneeMttr struct {
+	Assignee bson.ObjectId `json:"assignee,omitempty" description:"empty for unassigned issues"`
+	MttrStat `json:",inline"`
+}
+
+type MonthMttr struct {
+	Month      string `json:"month" description:"like 2015-06"`
+	MttrStat   `json:",inline"`
+	Severities map[Severity]*MttrStat `json:"severities"`
+}
+
+// Mttr is the report of remediation times of the project issues resolved in the period.
+// False issues aren't counted, reopened ones are counted till the last resolution.
+type Mttr struct {
+	Project    bson.ObjectId `json:"project"`
+	From       time.Time     `json:"from"`
+	To         time.Time     `json:"to"`
+	MttrStat   `json:",inline"`
+	Severities map[Severity]*MttrStat `json:"severities"`
+	Assignees  []*AssigneeMttr        `json:"assignees"`
+	Months     []*MonthMttr           `json:"months" description:"by month of resolution, oldest first"`
+}
+
+func NewMttr(project bson.ObjectId, from, to time.Time) *Mttr {
+	return &Mttr{
+		Project:    project,
+		From:       from,
+		To:         to,
+		Severities: map[Severity]*MttrStat{},
+		Assignees:  []*AssigneeMttr{},
+		Months:     []*MonthMttr{},
+	}
+}
+
+// Add counts issues resolved in the month with the total of their remediation hours
+func (m *Mttr) Add(sev Severity, assignee bson.ObjectId, month string, count int, hours float64) {
+	m.MttrStat.add(count, hours)
+	m.severity(m.Severities, sev).add(count, hours)
+	m.assignee(assignee).add(count, hours)
+	mon := m.month(month)
+	mon.add(count, hours)
+	m.severity(mon.Severities, sev).add(count, hours)
+}
+
+func (m *Mttr) severity(stats map[Severity]*MttrStat, sev Severity) *MttrStat {
+	s, ok := stats[sev]
+	if !ok {
+		s = &MttrStat{}
+		stats[sev] = s
+	}
+	return s
+}
+
+func (m *Mttr) assignee(id bson.ObjectId) *MttrStat {
+	for _, a := range m.Assignees {
+		if a.Assignee == id {
+			return &a.MttrStat
+		}
+	}
+	a := &AssigneeMttr{Assignee: id}
+	m.Assignees = append(m.Assignees, a)
+	return &a.MttrStat
+}
+
+func (m *Mttr) month(month string) *MonthMttr {
+	for _, mon := range m.Months {
+		if mon.Month == month {
+			return mon
+		}
+	}
+	mon := &MonthMttr{Month: month, Severities: map[Severity]*MttrStat{}}
+	m.Months = append(m.Months, mon)
+	sort.Sort(monthMttrs(m.Months))
+	return mon
+}
+
+type monthMttrs []*MonthMttr
+
+func (m monthMttrs) Len() int           { return len(m) }
+func (m monthMttrs) Less(i, j int) bool { return m[i].Month < m[j].Month }
+func (m monthMttrs) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
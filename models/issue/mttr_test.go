@@ -0,0 +1,34 @@
+package issue
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestMttrAdd(t *testing.T) {
+	u := bson.NewObjectId()
+	m := NewMttr("", time.Time{}, time.Now())
+	m.Add(SeverityHigh, u, "2015-07", 2, 20)
+	m.Add(SeverityLow, "", "2015-06", 1, 100)
+	m.Add(SeverityHigh, u, "2015-06", 1, 40)
+
+	assert.Equal(t, 4, m.Resolved)
+	assert.Equal(t, 40.0, m.Hours)
+	assert.Equal(t, 3, m.Severities[SeverityHigh].Resolved)
+	assert.Equal(t, 20.0, m.Severities[SeverityHigh].Hours)
+
+	require.Len(t, m.Assignees, 2)
+	assert.Equal(t, u, m.Assignees[0].Assignee)
+	assert.Equal(t, 20.0, m.Assignees[0].Hours)
+	assert.Equal(t, 100.0, m.Assignees[1].Hours)
+
+	require.Len(t, m.Months, 2)
+	assert.Equal(t, "2015-06", m.Months[0].Month)
+	assert.Equal(t, 70.0, m.Months[0].Hours)
+	assert.Equal(t, 40.0, m.Months[0].Severities[SeverityHigh].Hours)
+	assert.Equal(t, "2015-07", m.Months[1].Month)
+}
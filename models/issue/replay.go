@@ -0,0 +1,90 @@
+package issue
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// headers which are set by curl itself
+var curlSkipHeaders = map[string]bool{
+	"Content-Length": true,
+	"Host":           true,
+}
+
+// Curl returns a shell command which replays the request
+func (t *HttpTransaction) Curl() (string, error) {
+	parts := []string{"curl", "-i"}
+	method := t.Method
+	if method == "" {
+		method = "GET"
+	}
+	if method != "GET" {
+		parts = append(parts, "-X", shellQuote(method))
+	}
+	var body []byte
+	if t.Request != nil {
+		for _, key := range sortedKeys(t.Request.Header) {
+			if curlSkipHeaders[key] {
+				continue
+			}
+			for _, val := range t.Request.Header[key] {
+				parts = append(parts, "-H", shellQuote(fmt.Sprintf("%s: %s", key, val)))
+			}
+		}
+		var err error
+		if body, err = t.Request.Body.Bytes(); err != nil {
+			return "", err
+		}
+	}
+	if len(body) > 0 {
+		parts = append(parts, "--data-binary", shellQuote(string(body)))
+	}
+	parts = append(parts, shellQuote(t.Url))
+	return strings.Join(parts, " "), nil
+}
+
+// HttpFile returns the request in .http file format, which is supported by editors rest clients
+func (t *HttpTransaction) HttpFile() (string, error) {
+	buf := &bytes.Buffer{}
+	method := t.Method
+	if method == "" {
+		method = "GET"
+	}
+	fmt.Fprintf(buf, "%s %s\n", method, t.Url)
+	if t.Request != nil {
+		for _, key := range sortedKeys(t.Request.Header) {
+			if key == "Content-Length" {
+				continue
+			}
+			for _, val := range t.Request.Header[key] {
+				fmt.Fprintf(buf, "%s: %s\n", key, val)
+			}
+		}
+		body, err := t.Request.Body.Bytes()
+		if err != nil {
+			return "", err
+		}
+		if len(body) > 0 {
+			buf.WriteString("\n")
+			buf.Write(body)
+			buf.WriteString("\n")
+		}
+	}
+	return buf.String(), nil
+}
+
+func sortedKeys(header map[string][]string) []string {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// quote string for posix shells
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,41 @@
+package issue
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplay(t *testing.T) {
+	tr := &HttpTransaction{
+		Method: "POST",
+		Url:    "http://example.com/login",
+		Request: &HttpEntity{
+			Header: http.Header{
+				"Host":           []string{"example.com"},
+				"Content-Length": []string{"15"},
+				"Content-Type":   []string{"application/x-www-form-urlencoded"},
+				"Cookie":         []string{"a=b"},
+			},
+			Body: &HttpBody{Content: "user=it's me"},
+		},
+	}
+	curl, err := tr.Curl()
+	require.NoError(t, err)
+	assert.Equal(t, `curl -i -X 'POST' -H 'Content-Type: application/x-www-form-urlencoded' -H 'Cookie: a=b' `+
+		`--data-binary 'user=it'\''s me' 'http://example.com/login'`, curl)
+
+	file, err := tr.HttpFile()
+	require.NoError(t, err)
+	assert.Equal(t, "POST http://example.com/login\n"+
+		"Content-Type: application/x-www-form-urlencoded\n"+
+		"Cookie: a=b\n"+
+		"Host: example.com\n"+
+		"\nuser=it's me\n", file)
+
+	curl, err = (&HttpTransaction{Url: "http://example.com/"}).Curl()
+	require.NoError(t, err)
+	assert.Equal(t, "curl -i 'http://example.com/'", curl)
+}
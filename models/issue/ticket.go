@@ -0,0 +1,20 @@
+package issue
+
+import "time"
+
+const TicketServiceNow = "servicenow"
+
+// Ticket is a record in an external tracker which is synced with the issue
+type Ticket struct {
+	System string `json:"system" description:"tracker, one of [servicenow]"`
+	Id     string `json:"id" description:"record id in the tracker, sys_id for servicenow"`
+	Number string `json:"number,omitempty" bson:",omitempty" description:"human readable number, like INC0010001"`
+	Url    string `json:"url"`
+
+	// remote fields after the last sync, they show what is changed in the tracker since then
+	State  string `json:"state" description:"record state in the tracker"`
+	Impact string `json:"impact,omitempty" bson:",omitempty" description:"record impact in the tracker"`
+	Closed bool   `json:"closed" description:"the record is resolved, closed or canceled"`
+
+	Synced time.Time `json:"synced"`
+}
@@ -0,0 +1,23 @@
+package issue
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/pkg/pagination"
+)
+
+// TrashedIssue is a removed issue, it could be restored till it expires
+type TrashedIssue struct {
+	TargetIssue `json:",inline" bson:",inline"`
+
+	Removed   time.Time     `json:"removed"`
+	RemovedBy bson.ObjectId `json:"removedBy,omitempty" bson:"removedBy,omitempty"`
+	Expires   time.Time     `json:"expires" bson:"-" description:"the issue is removed permanently after this time"`
+}
+
+type TrashedIssueList struct {
+	pagination.Meta `json:",inline"`
+	Results         []*TrashedIssue `json:"results"`
+}
@@ -0,0 +1,50 @@
+package issue
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+)
+
+// Vars are values of variables like {{target.host}} in issue description and remediation
+type Vars map[string]string
+
+var varRe = regexp.MustCompile(`\{\{\s*([a-zA-Z][a-zA-Z0-9_.]*)\s*\}\}`)
+
+// RenderVars substitutes variables in the text, unknown variables are left as is.
+// Values are html escaped, because the text is already sanitized.
+func RenderVars(text string, vars Vars) string {
+	if len(vars) == 0 {
+		return text
+	}
+	return varRe.ReplaceAllStringFunc(text, func(match string) string {
+		if val, ok := vars[varRe.FindStringSubmatch(match)[1]]; ok {
+			return html.EscapeString(val)
+		}
+		return match
+	})
+}
+
+// Vars returns vector.url, vector.host and vector.path of the issue
+func (i *Issue) Vars() Vars {
+	vars := Vars{}
+	if i.Vector == nil || i.Vector.Url == "" {
+		return vars
+	}
+	vars["vector.url"] = i.Vector.Url
+	if u, err := url.Parse(i.Vector.Url); err == nil {
+		vars["vector.host"] = u.Hostname()
+		vars["vector.path"] = u.Path
+	}
+	return vars
+}
+
+// RenderVars substitutes variables of the issue and the given ones in description and remediation
+func (t *TargetIssue) RenderVars(vars Vars) {
+	all := t.Vars()
+	for name, val := range vars {
+		all[name] = val
+	}
+	t.Desc = RenderVars(t.Desc, all)
+	t.Remediation = RenderVars(t.Remediation, all)
+}
@@ -0,0 +1,24 @@
+package issue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderVars(t *testing.T) {
+	vars := Vars{"target.host": "example.com", "vector.url": "http://example.com/?a=1&b=<2>"}
+	assert.Equal(t, "Upgrade example.com", RenderVars("Upgrade {{target.host}}", vars))
+	assert.Equal(t, "Open example.com and {{ unknown }}", RenderVars("Open {{ target.host }} and {{ unknown }}", vars))
+	assert.Equal(t, "http://example.com/?a=1&amp;b=&lt;2&gt;", RenderVars("{{vector.url}}", vars))
+	assert.Equal(t, "{{target.host}}", RenderVars("{{target.host}}", nil))
+
+	obj := &TargetIssue{Issue: Issue{
+		Desc:        "Found at {{vector.path}} of {{target.host}}",
+		Remediation: "Fix {{vector.host}}",
+		Vector:      &Vector{Url: "https://example.com:8443/admin"},
+	}}
+	obj.RenderVars(Vars{"target.host": "example.com"})
+	assert.Equal(t, "Found at /admin of example.com", obj.Desc)
+	assert.Equal(t, "Fix example.com", obj.Remediation)
+}
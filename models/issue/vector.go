@@ -1,14 +1,50 @@
 package issue
 
-import "net/http"
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const EncodingBase64 = "base64"
 
 type HttpBody struct {
-	ContentEncoding string `json:"contentEncoding"`
+	ContentEncoding string `json:"contentEncoding" description:"empty for plain text or base64"`
 	Content         string `json:"content"`
 }
 
+// Bytes returns decoded body content
+func (b *HttpBody) Bytes() ([]byte, error) {
+	if b == nil {
+		return nil, nil
+	}
+	if b.ContentEncoding == EncodingBase64 {
+		return base64.StdEncoding.DecodeString(b.Content)
+	}
+	return []byte(b.Content), nil
+}
+
+// NewHttpBody keeps text body as is and encodes binary body to base64
+func NewHttpBody(data []byte) *HttpBody {
+	if len(data) == 0 {
+		return nil
+	}
+	if utf8.Valid(data) {
+		return &HttpBody{Content: string(data)}
+	}
+	return &HttpBody{ContentEncoding: EncodingBase64, Content: base64.StdEncoding.EncodeToString(data)}
+}
+
 type HttpEntity struct {
-	Status string      `json:"status"`
+	Status string      `json:"status" description:"response status, f.e 200 OK"`
+	Proto  string      `json:"proto,omitempty" description:"protocol version, f.e HTTP/1.1"`
 	Header http.Header `json:"header"`
 	Body   *HttpBody   `json:"body,omitempty"`
 }
@@ -20,9 +56,151 @@ type HttpTransaction struct {
 	Method   string      `json:"method"`
 	Request  *HttpEntity `json:"request,omitempty"`
 	Response *HttpEntity `json:"response,omitempty"`
+
+	// Plugins could send raw http messages instead of the structured ones,
+	// they are parsed by Normalize and kept only if parsing is failed.
+	RawRequest  string `json:"rawRequest,omitempty" bson:"rawRequest,omitempty"`
+	RawResponse string `json:"rawResponse,omitempty" bson:"rawResponse,omitempty"`
+}
+
+// Normalize parses raw request and response to the structured fields
+func (t *HttpTransaction) Normalize() error {
+	if t.RawRequest != "" {
+		req, err := http.ReadRequest(bufio.NewReader(strings.NewReader(t.RawRequest)))
+		if err != nil {
+			return fmt.Errorf("couldn't parse raw request: %s", err)
+		}
+		body, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return fmt.Errorf("couldn't read raw request body: %s", err)
+		}
+		if t.Url == "" {
+			u := *req.URL
+			if u.Host == "" {
+				u.Host = req.Host
+			}
+			if u.Scheme == "" {
+				u.Scheme = "http"
+			}
+			t.Url = u.String()
+		}
+		t.Method = req.Method
+		header := req.Header
+		if req.Host != "" {
+			// go moves host header to request field
+			header.Set("Host", req.Host)
+		}
+		t.Request = &HttpEntity{
+			Proto:  req.Proto,
+			Header: header,
+			Body:   NewHttpBody(body),
+		}
+		t.RawRequest = ""
+	}
+	if t.RawResponse != "" {
+		resp, err := http.ReadResponse(bufio.NewReader(strings.NewReader(t.RawResponse)), nil)
+		if err != nil {
+			return fmt.Errorf("couldn't parse raw response: %s", err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("couldn't read raw response body: %s", err)
+		}
+		t.Response = &HttpEntity{
+			Status: resp.Status,
+			Proto:  resp.Proto,
+			Header: resp.Header,
+			Body:   NewHttpBody(body),
+		}
+		t.RawResponse = ""
+	}
+	return nil
+}
+
+// DumpRequest returns request in http wire format
+func (t *HttpTransaction) DumpRequest() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	uri := t.Url
+	proto := "HTTP/1.1"
+	if u, err := url.Parse(t.Url); err == nil && u.Host != "" {
+		uri = u.RequestURI()
+	}
+	var entity *HttpEntity
+	if t.Request != nil {
+		entity = t.Request
+		if entity.Proto != "" {
+			proto = entity.Proto
+		}
+	}
+	method := t.Method
+	if method == "" {
+		method = "GET"
+	}
+	fmt.Fprintf(buf, "%s %s %s\r\n", method, uri, proto)
+	if err := dumpEntity(buf, entity); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DumpResponse returns response in http wire format, nil if there is no response
+func (t *HttpTransaction) DumpResponse() ([]byte, error) {
+	if t.Response == nil {
+		return nil, nil
+	}
+	buf := &bytes.Buffer{}
+	proto := t.Response.Proto
+	if proto == "" {
+		proto = "HTTP/1.1"
+	}
+	fmt.Fprintf(buf, "%s %s\r\n", proto, t.Response.Status)
+	if err := dumpEntity(buf, t.Response); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func dumpEntity(buf *bytes.Buffer, entity *HttpEntity) error {
+	if entity == nil {
+		buf.WriteString("\r\n")
+		return nil
+	}
+	// sort headers to get the same dump every time
+	keys := make([]string, 0, len(entity.Header))
+	for key := range entity.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, val := range entity.Header[key] {
+			fmt.Fprintf(buf, "%s: %s\r\n", key, val)
+		}
+	}
+	buf.WriteString("\r\n")
+	body, err := entity.Body.Bytes()
+	if err != nil {
+		return err
+	}
+	buf.Write(body)
+	return nil
 }
 
 type Vector struct {
 	Url              string             `json:"url,omitempty" description:"where this issue is happened"`
 	HttpTransactions []*HttpTransaction `json:"httpTransactions,omitempty" bson:"httpTransactions"`
 }
+
+// Normalize parses raw messages in all transactions, it returns the first error,
+// but tries to normalize every transaction.
+func (v *Vector) Normalize() error {
+	if v == nil {
+		return nil
+	}
+	var first error
+	for _, t := range v.HttpTransactions {
+		if err := t.Normalize(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
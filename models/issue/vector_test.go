@@ -0,0 +1,52 @@
+package issue
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHttpTransactionNormalize(t *testing.T) {
+	tr := &HttpTransaction{
+		RawRequest:  "POST /login?next=%2F HTTP/1.1\r\nHost: example.com\r\nContent-Type: application/x-www-form-urlencoded\r\nContent-Length: 9\r\n\r\nuser=test",
+		RawResponse: "HTTP/1.1 302 Found\r\nLocation: /\r\nContent-Length: 0\r\n\r\n",
+	}
+	require.NoError(t, tr.Normalize())
+	assert.Equal(t, "POST", tr.Method)
+	assert.Equal(t, "http://example.com/login?next=%2F", tr.Url)
+	assert.Equal(t, "HTTP/1.1", tr.Request.Proto)
+	assert.Equal(t, "example.com", tr.Request.Header.Get("Host"))
+	assert.Equal(t, "user=test", tr.Request.Body.Content)
+	assert.Equal(t, "302 Found", tr.Response.Status)
+	assert.Equal(t, "/", tr.Response.Header.Get("Location"))
+	assert.Nil(t, tr.Response.Body)
+	assert.Empty(t, tr.RawRequest)
+	assert.Empty(t, tr.RawResponse)
+
+	dump, err := tr.DumpRequest()
+	require.NoError(t, err)
+	assert.Equal(t, "POST /login?next=%2F HTTP/1.1\r\n"+
+		"Content-Length: 9\r\n"+
+		"Content-Type: application/x-www-form-urlencoded\r\n"+
+		"Host: example.com\r\n\r\n"+
+		"user=test", string(dump))
+
+	dump, err = tr.DumpResponse()
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1 302 Found\r\nContent-Length: 0\r\nLocation: /\r\n\r\n", string(dump))
+
+	broken := &HttpTransaction{RawRequest: "not a request"}
+	assert.Error(t, broken.Normalize())
+	assert.Equal(t, "not a request", broken.RawRequest)
+}
+
+func TestHttpBody(t *testing.T) {
+	assert.Nil(t, NewHttpBody(nil))
+
+	body := NewHttpBody([]byte{0xff, 0x00})
+	assert.Equal(t, EncodingBase64, body.ContentEncoding)
+	data, err := body.Bytes()
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xff, 0x00}, data)
+}
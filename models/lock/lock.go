@@ -0,0 +1,13 @@
+package lock
+
+import (
+	"time"
+)
+
+// Lock is held by one api instance, so background jobs run once across all instances
+type Lock struct {
+	Name    string    `json:"name" bson:"_id" description:"name of the job, e.g. escalation"`
+	Owner   string    `json:"owner" description:"instance which holds the lock, hostname with pid"`
+	Expires time.Time `json:"expires" description:"other instances could take the lock after this time"`
+	Updated time.Time `json:"updated,omitempty"`
+}
@@ -0,0 +1,224 @@
+// Package migration describes moves of applications to another host.
+// Targets and issues are rewritten in place, so their history is kept.
+package migration
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+// Kind is a type of the rewritten object
+type Kind string
+
+const (
+	KindTarget = Kind("target")
+	KindIssue  = Kind("issue")
+)
+
+var kinds = []interface{}{
+	KindTarget,
+	KindIssue,
+}
+
+// It's a hack to show custom type as string in swagger
+func (t Kind) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+func (t Kind) Enum() []interface{} {
+	return kinds
+}
+
+func (t Kind) Convert(text string) (interface{}, error) {
+	return Kind(text), nil
+}
+
+// HostMove rewrites addresses of web and api targets and urls of their issues from one host to another.
+// The port is kept if hosts are set without ports.
+type HostMove struct {
+	From    string        `json:"from" description:"old host with optional port, e.g. staging.example.com"`
+	To      string        `json:"to" description:"new host with optional port, e.g. app.example.com"`
+	Project bson.ObjectId `json:"project,omitempty" description:"move targets of the project only, all projects if empty"`
+}
+
+// Change is a rewritten field of the object
+type Change struct {
+	Kind  Kind          `json:"kind" description:"one of [target|issue]"`
+	Id    bson.ObjectId `json:"id"`
+	Field string        `json:"field"`
+	Old   string        `json:"old"`
+	New   string        `json:"new"`
+}
+
+// Result of the move, changes aren't stored if it's a dry run
+type Result struct {
+	DryRun  bool      `json:"dryRun"`
+	Targets int       `json:"targets" description:"count of moved targets"`
+	Issues  int       `json:"issues" description:"count of rewritten issues"`
+	Changes []*Change `json:"changes" description:"first changed fields"`
+}
+
+func NewResult(dryRun bool) *Result {
+	return &Result{
+		DryRun:  dryRun,
+		Changes: []*Change{},
+	}
+}
+
+// Add counts the changed object, its changes are kept in the result only if there are less than limit changes
+func (r *Result) Add(changes []*Change, limit int) {
+	if len(changes) == 0 {
+		return
+	}
+	switch changes[0].Kind {
+	case KindTarget:
+		r.Targets++
+	case KindIssue:
+		r.Issues++
+	}
+	for _, c := range changes {
+		if len(r.Changes) >= limit {
+			return
+		}
+		r.Changes = append(r.Changes, c)
+	}
+}
+
+func (m *HostMove) Validate() error {
+	errs := validate.Errors{}
+	if !validHost(m.From) {
+		errs.Add("from", validate.CodeInvalid, "should be a host with optional port")
+	}
+	if !validHost(m.To) {
+		errs.Add("to", validate.CodeInvalid, "should be a host with optional port")
+	}
+	if strings.EqualFold(m.From, m.To) {
+		errs.Add("to", validate.CodeInvalid, "should differ from the old host")
+	}
+	if m.Project != "" && !m.Project.Valid() {
+		errs.Add("project", validate.CodeBsonId, "should be bson uuid in hex form")
+	}
+	return errs.Err()
+}
+
+func validHost(host string) bool {
+	if host == "" {
+		return false
+	}
+	u, err := url.Parse("//" + host)
+	return err == nil && u.Host == host && u.Hostname() != "" && u.User == nil
+}
+
+func splitHost(host string) (string, string) {
+	h, port, err := net.SplitHostPort(host)
+	if err != nil {
+		return strings.Trim(host, "[]"), ""
+	}
+	return h, port
+}
+
+// RewriteUrl returns the url with the new host, ok is false if the url isn't on the old host
+func (m *HostMove) RewriteUrl(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw, false
+	}
+	host, ok := m.rewriteHost(u.Host)
+	if !ok {
+		return raw, false
+	}
+	u.Host = host
+	return u.String(), true
+}
+
+func (m *HostMove) rewriteHost(host string) (string, bool) {
+	fromHost, fromPort := splitHost(m.From)
+	h, port := splitHost(host)
+	if !strings.EqualFold(h, fromHost) || (fromPort != "" && port != fromPort) {
+		return host, false
+	}
+	toHost, toPort := splitHost(m.To)
+	switch {
+	case toPort != "":
+		port = toPort
+	case fromPort != "":
+		port = ""
+	}
+	if port == "" {
+		if strings.Contains(toHost, ":") {
+			return "[" + toHost + "]", true
+		}
+		return toHost, true
+	}
+	return net.JoinHostPort(toHost, port), true
+}
+
+// RewriteTarget moves the address of web or api target, returns changed fields
+func (m *HostMove) RewriteTarget(t *target.Target) []*Change {
+	var field string
+	var addr *string
+	switch {
+	case t.Type == target.TypeWeb && t.Web != nil:
+		field, addr = "web.domain", &t.Web.Domain
+	case t.Type == target.TypeApi && t.Api != nil:
+		field, addr = "api.baseUrl", &t.Api.BaseUrl
+	default:
+		return nil
+	}
+	val, ok := m.RewriteUrl(*addr)
+	if !ok {
+		return nil
+	}
+	c := &Change{Kind: KindTarget, Id: t.Id, Field: field, Old: *addr, New: val}
+	*addr = val
+	return []*Change{c}
+}
+
+// RewriteIssue moves urls of the issue vector and host headers of its requests, returns changed fields.
+// Generated uniq id is recalculated, so new reports on the new host are merged to the issue,
+// uniq ids set by plugins are kept.
+func (m *HostMove) RewriteIssue(obj *issue.TargetIssue) []*Change {
+	if obj.Vector == nil {
+		return nil
+	}
+	generated := obj.GeneratedUniqId()
+	changes := []*Change{}
+	rewrite := func(field string, val *string, rw func(string) (string, bool)) {
+		if newVal, ok := rw(*val); ok {
+			changes = append(changes, &Change{Kind: KindIssue, Id: obj.Id, Field: field, Old: *val, New: newVal})
+			*val = newVal
+		}
+	}
+	rewrite("vector.url", &obj.Vector.Url, m.RewriteUrl)
+	for i, tr := range obj.Vector.HttpTransactions {
+		if tr == nil {
+			continue
+		}
+		field := fmt.Sprintf("vector.httpTransactions.%d", i)
+		rewrite(field+".url", &tr.Url, m.RewriteUrl)
+		if tr.Request != nil && tr.Request.Header.Get("Host") != "" {
+			host := tr.Request.Header.Get("Host")
+			rewrite(field+".request.header.Host", &host, m.rewriteHost)
+			tr.Request.Header.Set("Host", host)
+		}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	if generated {
+		rewrite("uniqId", &obj.UniqId, func(old string) (string, bool) {
+			val := obj.GenerateUniqId()
+			return val, val != old
+		})
+	}
+	return changes
+}
@@ -0,0 +1,96 @@
+package migration
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/target"
+)
+
+func TestHostMoveValidate(t *testing.T) {
+	assert.NoError(t, (&HostMove{From: "staging.example.com", To: "app.example.com:8443"}).Validate())
+	for _, mv := range []*HostMove{
+		{From: "", To: "app.example.com"},
+		{From: "https://staging.example.com", To: "app.example.com"},
+		{From: "staging.example.com/path", To: "app.example.com"},
+		{From: "Example.com", To: "example.com"},
+		{From: "staging.example.com", To: "app.example.com", Project: "bad"},
+	} {
+		assert.Error(t, mv.Validate(), mv.From)
+	}
+}
+
+func TestHostMoveRewriteUrl(t *testing.T) {
+	mv := &HostMove{From: "staging.example.com", To: "app.example.com"}
+	for raw, expected := range map[string]string{
+		"https://Staging.example.com/login?next=/": "https://app.example.com/login?next=/",
+		"http://staging.example.com:8080/":         "http://app.example.com:8080/",
+	} {
+		val, ok := mv.RewriteUrl(raw)
+		assert.True(t, ok, raw)
+		assert.Equal(t, expected, val)
+	}
+	for _, raw := range []string{"https://example.com/", "https://old.staging.example.com/", "/login"} {
+		val, ok := mv.RewriteUrl(raw)
+		assert.False(t, ok, raw)
+		assert.Equal(t, raw, val)
+	}
+
+	mv = &HostMove{From: "staging.example.com:8080", To: "app.example.com"}
+	val, ok := mv.RewriteUrl("http://staging.example.com:8080/a")
+	assert.True(t, ok)
+	assert.Equal(t, "http://app.example.com/a", val)
+	_, ok = mv.RewriteUrl("http://staging.example.com/a")
+	assert.False(t, ok)
+}
+
+func TestHostMoveRewrite(t *testing.T) {
+	mv := &HostMove{From: "staging.example.com", To: "app.example.com"}
+
+	tgt := &target.Target{Type: target.TypeWeb, Web: &target.WebTarget{Domain: "https://staging.example.com"}}
+	changes := mv.RewriteTarget(tgt)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "web.domain", changes[0].Field)
+	assert.Equal(t, "https://app.example.com", tgt.Web.Domain)
+	assert.Nil(t, mv.RewriteTarget(tgt))
+
+	obj := &issue.TargetIssue{Issue: issue.Issue{
+		Summary: "XSS",
+		Vector: &issue.Vector{
+			Url: "https://staging.example.com/search",
+			HttpTransactions: []*issue.HttpTransaction{{
+				Method:  "GET",
+				Url:     "https://staging.example.com/search?q=1",
+				Request: &issue.HttpEntity{Header: http.Header{"Host": {"staging.example.com"}}},
+			}},
+		},
+	}}
+	obj.UniqId = obj.GenerateUniqId()
+	changes = mv.RewriteIssue(obj)
+	require.Len(t, changes, 4)
+	assert.Equal(t, "https://app.example.com/search?q=1", obj.Vector.HttpTransactions[0].Url)
+	assert.Equal(t, "app.example.com", obj.Vector.HttpTransactions[0].Request.Header.Get("Host"))
+	assert.Equal(t, "uniqId", changes[3].Field)
+	assert.Equal(t, obj.GenerateUniqId(), obj.UniqId)
+
+	// uniq id from the plugin is kept
+	obj.Vector.Url = "https://staging.example.com/search"
+	obj.UniqId = "plugin:xss"
+	changes = mv.RewriteIssue(obj)
+	require.Len(t, changes, 1)
+	assert.Equal(t, "plugin:xss", obj.UniqId)
+}
+
+func TestResultAdd(t *testing.T) {
+	r := NewResult(true)
+	r.Add([]*Change{{Kind: KindTarget}}, 2)
+	r.Add([]*Change{{Kind: KindIssue}, {Kind: KindIssue}}, 2)
+	r.Add(nil, 2)
+	assert.Equal(t, 1, r.Targets)
+	assert.Equal(t, 1, r.Issues)
+	assert.Len(t, r.Changes, 2)
+}
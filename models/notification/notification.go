@@ -0,0 +1,132 @@
+package notification
+
+import (
+	"encoding/json"
+
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+type Event string
+
+const (
+	EventIssueAssigned  Event = "issue-assigned"
+	EventScanFailed     Event = "scan-failed"
+	EventIssueEscalated Event = "issue-escalated"
+)
+
+var events = []interface{}{
+	EventIssueAssigned,
+	EventScanFailed,
+	EventIssueEscalated,
+}
+
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	ChannelInApp Channel = "inapp"
+)
+
+var channels = []interface{}{
+	ChannelEmail,
+	ChannelInApp,
+}
+
+// channels enabled for events which aren't set by user
+var defaultChannels = []Channel{ChannelEmail, ChannelInApp}
+
+// Preferences maps event type to the channels where user wants to get notifications
+type Preferences map[Event][]Channel
+
+// Full returns preferences with all events, unset events have default channels.
+// Channels which aren't supported anymore are skipped, so full preferences could be saved back.
+func (p Preferences) Full() Preferences {
+	full := Preferences{}
+	for _, e := range events {
+		event := e.(Event)
+		chs := []Channel{}
+		for _, ch := range p.Channels(event) {
+			if ch.IsValid() {
+				chs = append(chs, ch)
+			}
+		}
+		full[event] = chs
+	}
+	return full
+}
+
+// Channels returns enabled channels for the event
+func (p Preferences) Channels(event Event) []Channel {
+	if chs, ok := p[event]; ok {
+		return chs
+	}
+	return defaultChannels
+}
+
+func (t Event) IsValid() bool {
+	return contains(events, t)
+}
+
+func (t Channel) IsValid() bool {
+	return contains(channels, t)
+}
+
+func (p Preferences) Enabled(event Event, channel Channel) bool {
+	for _, ch := range p.Channels(event) {
+		if ch == channel {
+			return true
+		}
+	}
+	return false
+}
+
+func (p Preferences) Validate() error {
+	errs := validate.Errors{}
+	for event, chs := range p {
+		if !contains(events, event) {
+			errs.Add(string(event), validate.CodeInvalid, "unknown event %s", event)
+			continue
+		}
+		for _, ch := range chs {
+			if !contains(channels, ch) {
+				errs.Add(string(event), validate.CodeInvalid, "unknown channel %s", ch)
+			}
+		}
+	}
+	return errs.Err()
+}
+
+func contains(list []interface{}, val interface{}) bool {
+	for _, v := range list {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
+
+// It's a hack to show custom type as string in swagger
+func (t Event) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+func (t Event) Enum() []interface{} {
+	return events
+}
+
+func (t Event) Convert(text string) (interface{}, error) {
+	return Event(text), nil
+}
+
+// It's a hack to show custom type as string in swagger
+func (t Channel) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+func (t Channel) Enum() []interface{} {
+	return channels
+}
+
+func (t Channel) Convert(text string) (interface{}, error) {
+	return Channel(text), nil
+}
@@ -0,0 +1,31 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreferences(t *testing.T) {
+	var p Preferences
+	assert.True(t, p.Enabled(EventScanFailed, ChannelEmail))
+	assert.True(t, p.Enabled(EventScanFailed, ChannelInApp))
+
+	p = Preferences{EventScanFailed: []Channel{ChannelInApp}, EventIssueAssigned: []Channel{}}
+	assert.True(t, p.Enabled(EventScanFailed, ChannelInApp))
+	assert.False(t, p.Enabled(EventScanFailed, ChannelEmail))
+	assert.False(t, p.Enabled(EventIssueAssigned, ChannelInApp))
+	assert.NoError(t, p.Validate())
+
+	full := p.Full()
+	assert.Len(t, full, 3)
+	assert.Equal(t, defaultChannels, full[EventIssueEscalated])
+
+	assert.Error(t, Preferences{"unknown": nil}.Validate())
+	assert.Error(t, Preferences{EventIssueAssigned: []Channel{"pigeon"}}.Validate())
+
+	// channels saved before they were removed
+	p = Preferences{EventScanFailed: []Channel{"slack", ChannelEmail}}
+	assert.Equal(t, []Channel{ChannelEmail}, p.Full()[EventScanFailed])
+	assert.NoError(t, p.Full().Validate())
+}
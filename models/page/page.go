@@ -0,0 +1,57 @@
+// Package page describes markdown pages of projects, like scoping notes and rules of engagement.
+package page
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/pkg/pagination"
+)
+
+type Page struct {
+	Id       bson.ObjectId `json:"id,omitempty" bson:"_id"`
+	Project  bson.ObjectId `json:"project"`
+	Title    string        `json:"title"`
+	Text     string        `json:"text" description:"markdown text"`
+	Revision int           `json:"revision" description:"number of the current revision, starts from 1"`
+	Author   bson.ObjectId `json:"author" description:"user who made the current revision"`
+	Created  time.Time     `json:"created,omitempty"`
+	Updated  time.Time     `json:"updated,omitempty"`
+}
+
+// Revision is a snapshot of the page made by every change
+type Revision struct {
+	Id      bson.ObjectId `json:"-" bson:"_id"`
+	Page    bson.ObjectId `json:"page"`
+	Project bson.ObjectId `json:"-"`
+	Number  int           `json:"number"`
+	Title   string        `json:"title"`
+	Text    string        `json:"text"`
+	Author  bson.ObjectId `json:"author"`
+	Created time.Time     `json:"created"`
+}
+
+// NewRevision returns the snapshot of the current page state
+func (p *Page) NewRevision() *Revision {
+	return &Revision{
+		Id:      bson.NewObjectId(),
+		Page:    p.Id,
+		Project: p.Project,
+		Number:  p.Revision,
+		Title:   p.Title,
+		Text:    p.Text,
+		Author:  p.Author,
+		Created: p.Updated,
+	}
+}
+
+type PageList struct {
+	pagination.Meta `json:",inline"`
+	Results         []*Page `json:"results"`
+}
+
+type RevisionList struct {
+	pagination.Meta `json:",inline"`
+	Results         []*Revision `json:"results"`
+}
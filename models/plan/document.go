@@ -0,0 +1,101 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/pkg/utils/load"
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+// DocumentVersion is the version of plan document schema
+const DocumentVersion = 1
+
+// Document is a plan exported as yaml, it could be kept in git and imported to another instance.
+// Keys are the same as in the api.
+type Document struct {
+	Version    int               `json:"version"`
+	Name       string            `json:"name"`
+	Desc       string            `json:"desc,omitempty"`
+	TargetType target.TargetType `json:"targetType"`
+	Workflow   []*WorkflowStep   `json:"workflow"`
+}
+
+// NewDocument returns the document of the plan without id and dates
+func NewDocument(p *Plan) *Document {
+	return &Document{
+		Version:    DocumentVersion,
+		Name:       p.Name,
+		Desc:       p.Desc,
+		TargetType: p.TargetType,
+		Workflow:   p.Workflow,
+	}
+}
+
+// Plan returns the new plan from the document
+func (d *Document) Plan() *Plan {
+	return &Plan{
+		Name:       d.Name,
+		Desc:       d.Desc,
+		TargetType: d.TargetType,
+		Workflow:   d.Workflow,
+	}
+}
+
+func (d *Document) Validate() error {
+	errs := validate.Errors{}
+	if d.Version != DocumentVersion {
+		errs.Add("version", validate.CodeInvalid, "unsupported version %d, should be %d", d.Version, DocumentVersion)
+	}
+	if d.Name == "" {
+		errs.Add("name", validate.CodeRequired, "name is required")
+	}
+	if !d.TargetType.IsValid() {
+		errs.Add("targetType", validate.CodeInvalid, "should be one of [web|android|api|repo]")
+	}
+	if len(d.Workflow) == 0 {
+		errs.Add("workflow", validate.CodeRequired, "workflow is required")
+	}
+	for i, step := range d.Workflow {
+		field := fmt.Sprintf("workflow.%d", i)
+		if step == nil {
+			errs.Add(field, validate.CodeRequired, "step is null")
+			continue
+		}
+		if step.Plugin == "" {
+			errs.Add(field+".plugin", validate.CodeRequired, "plugin is required")
+		}
+		if step.Name == "" {
+			errs.Add(field+".name", validate.CodeRequired, "name is required")
+		}
+	}
+	return errs.Err()
+}
+
+// MarshalYaml encodes the document to yaml using json names of fields
+func (d *Document) MarshalYaml() ([]byte, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(v)
+}
+
+// ParseDocument decodes and validates yaml document, unknown fields are rejected
+func ParseDocument(data []byte) (*Document, error) {
+	d := &Document{}
+	if err := load.UnmarshalYamlStrict(data, d); err != nil {
+		return nil, err
+	}
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
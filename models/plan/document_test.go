@@ -0,0 +1,58 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bearded-web/bearded/models/target"
+)
+
+func TestDocumentRoundTrip(t *testing.T) {
+	p := &Plan{
+		Name:       "Wappalyzer",
+		Desc:       "detect web technologies",
+		TargetType: target.TypeWeb,
+		Workflow: []*WorkflowStep{{
+			Plugin: "barbudo/wappalyzer:0.0.2",
+			Name:   "Wappalyzer",
+			Conf:   &Conf{CommandArgs: "--verbose", RateLimit: &target.RateLimit{RequestsPerSecond: 5}},
+		}},
+	}
+	data, err := NewDocument(p).MarshalYaml()
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "targetType: web")
+	assert.Contains(t, string(data), "commandArgs: --verbose")
+
+	d, err := ParseDocument(data)
+	require.NoError(t, err)
+	assert.Equal(t, p, d.Plan())
+}
+
+func TestParseDocument(t *testing.T) {
+	valid := `
+version: 1
+name: Nmap
+targetType: web
+workflow:
+  - plugin: barbudo/nmap
+    name: Nmap
+`
+	_, err := ParseDocument([]byte(valid))
+	assert.NoError(t, err)
+
+	for _, data := range []string{
+		"version: 1\nname: [",
+		"version: 2\nname: Nmap\ntargetType: web\nworkflow:\n  - {plugin: a, name: b}",
+		"version: 1\ntargetType: web\nworkflow:\n  - {plugin: a, name: b}",
+		"version: 1\nname: Nmap\ntargetType: ftp\nworkflow:\n  - {plugin: a, name: b}",
+		"version: 1\nname: Nmap\ntargetType: web\nworkflow: []",
+		"version: 1\nname: Nmap\ntargetType: web\nworkflow:\n  - {name: b}",
+		"version: 1\nname: Nmap\ntargetType: web\nowner: me\nworkflow:\n  - {plugin: a, name: b}",
+		"version: 1\nname: Nmap\ntargetType: web\nworkflow:\n  - {plugin: a, name: b, conf: {args: x}}",
+	} {
+		_, err := ParseDocument([]byte(data))
+		assert.Error(t, err, data)
+	}
+}
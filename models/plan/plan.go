@@ -25,6 +25,12 @@ type Conf struct {
 	Target      string `json:"target,omitempty" description:"used in script, taken from scan conf directly"`
 	FormData    string `json:"formData,omitempty" description:"data from form is saved as json string here"`
 
+	RateLimit *target.RateLimit  `json:"rateLimit,omitempty" bson:"rateLimit,omitempty" description:"passed to container as BEARDED_RATE_LIMIT_* environment variables"`
+	Endpoints []*target.Endpoint `json:"endpoints,omitempty" bson:"endpoints,omitempty" description:"scope for api targets, also shared to container as /share/endpoints.json"`
+	Repo      *target.RepoTarget `json:"repo,omitempty" bson:"repo,omitempty" description:"repository for static analysis, passed to container as BEARDED_REPO_* environment variables"`
+	Proxy     *target.Proxy      `json:"proxy,omitempty" bson:"proxy,omitempty" description:"passed to container as HTTP_PROXY, HTTPS_PROXY, ALL_PROXY and BEARDED_PROXY environment variables, tools which ignore them connect directly"`
+	Record    bool               `json:"record,omitempty" bson:"record,omitempty" description:"agent proxies container http traffic and uploads it as traffic.har session artifact, https tunnels are recorded as opaque CONNECT entries"`
+
 	// this fields helps to communicate with container through files
 	TakeFiles   []*File       `json:"takeFiles,omitempty" description:"copy this files from container when it's done"`
 	SharedFiles []*SharedFile `json:"sharedFiles,omitempty" description:"share file to container"`
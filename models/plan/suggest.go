@@ -0,0 +1,74 @@
+package plan
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bearded-web/bearded/models/plugin"
+	"github.com/bearded-web/bearded/models/target"
+)
+
+// SuggestedCategories is the order of steps in suggested plans, discovery and passive checks go before attacks
+var SuggestedCategories = []plugin.Category{
+	plugin.CategoryDiscovery,
+	plugin.CategoryWebPassive,
+	plugin.CategorySsl,
+	plugin.CategoryCms,
+	plugin.CategoryWebActive,
+}
+
+// Suggest composes the unsaved plan for the target type from enabled plugins.
+// Steps are ordered by SuggestedCategories, only the given categories are used if they aren't empty.
+// Plugins which could harm the target (dos, bruteforce) are never suggested, each plugin is added once.
+func Suggest(targetType target.TargetType, plugins []*plugin.Plugin, categories []plugin.Category) *Plan {
+	if len(categories) == 0 {
+		categories = SuggestedCategories
+	}
+	p := &Plan{
+		TargetType: targetType,
+		Workflow:   []*WorkflowStep{},
+	}
+	added := map[string]bool{}
+	used := []string{}
+	for _, c := range SuggestedCategories {
+		if !hasCategory(categories, c) {
+			continue
+		}
+		count := len(p.Workflow)
+		for _, pl := range plugins {
+			if added[pl.Name] || !pl.Enabled || pl.TargetType != targetType || !pl.HasCategory(c) {
+				continue
+			}
+			if pl.HasCategory(plugin.CategoryDos) || pl.HasCategory(plugin.CategoryBruteforce) {
+				continue
+			}
+			added[pl.Name] = true
+			step := &WorkflowStep{Plugin: pl.Name, Name: pl.Name}
+			if pl.Desc != nil {
+				if pl.Desc.Title != "" {
+					step.Name = pl.Desc.Title
+				}
+				step.Desc = pl.Desc.Info
+			}
+			p.Workflow = append(p.Workflow, step)
+		}
+		if len(p.Workflow) > count {
+			used = append(used, string(c))
+		}
+	}
+	p.Name = fmt.Sprintf("%s scan", targetType)
+	p.Desc = "There are no enabled plugins of these categories"
+	if len(used) > 0 {
+		p.Desc = fmt.Sprintf("Suggested steps: %s", strings.Join(used, ", "))
+	}
+	return p
+}
+
+func hasCategory(categories []plugin.Category, c plugin.Category) bool {
+	for _, cat := range categories {
+		if cat == c {
+			return true
+		}
+	}
+	return false
+}
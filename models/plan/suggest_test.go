@@ -0,0 +1,50 @@
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bearded-web/bearded/models/plugin"
+	"github.com/bearded-web/bearded/models/target"
+)
+
+func TestSuggest(t *testing.T) {
+	web := func(name string, categories ...plugin.Category) *plugin.Plugin {
+		return &plugin.Plugin{Name: name, Enabled: true, TargetType: target.TypeWeb, Categories: categories}
+	}
+	wpscan := web("barbudo/wpscan", plugin.CategoryCms, plugin.CategoryWebActive)
+	wpscan.Desc = &plugin.Desc{Title: "WPScan", Info: "wordpress scanner"}
+	disabled := web("barbudo/nikto", plugin.CategoryWebActive)
+	disabled.Enabled = false
+	plugins := []*plugin.Plugin{
+		web("barbudo/zap", plugin.CategoryWebActive),
+		wpscan,
+		web("barbudo/sslyze", plugin.CategorySsl),
+		web("barbudo/wappalyzer", plugin.CategoryDiscovery),
+		web("barbudo/slowloris", plugin.CategoryWebActive, plugin.CategoryDos),
+		web("barbudo/hydra", plugin.CategoryBruteforce),
+		disabled,
+		{Name: "barbudo/androbugs", Enabled: true, TargetType: target.TypeAndroid, Categories: []plugin.Category{plugin.CategoryDiscovery}},
+	}
+
+	p := Suggest(target.TypeWeb, plugins, nil)
+	assert.Equal(t, target.TypeWeb, p.TargetType)
+	names := []string{}
+	for _, step := range p.Workflow {
+		names = append(names, step.Plugin)
+	}
+	assert.Equal(t, []string{"barbudo/wappalyzer", "barbudo/sslyze", "barbudo/wpscan", "barbudo/zap"}, names)
+	assert.Equal(t, "WPScan", p.Workflow[2].Name)
+	assert.Equal(t, "wordpress scanner", p.Workflow[2].Desc)
+	assert.Equal(t, "Suggested steps: discovery, ssl, cms, web-active", p.Desc)
+
+	p = Suggest(target.TypeWeb, plugins, []plugin.Category{plugin.CategoryWebActive})
+	require.Len(t, p.Workflow, 2)
+	assert.Equal(t, "barbudo/zap", p.Workflow[0].Plugin)
+
+	p = Suggest(target.TypeRepo, plugins, nil)
+	assert.Empty(t, p.Workflow)
+	assert.Equal(t, "There are no enabled plugins of these categories", p.Desc)
+}
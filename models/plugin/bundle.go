@@ -0,0 +1,133 @@
+package plugin
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+const (
+	// BundleVersion is the version of the bundle manifest schema
+	BundleVersion = 1
+	// BundleManifest is the path of the manifest in the bundle
+	BundleManifest = "bundle.json"
+	// max size of the manifest, images aren't limited
+	bundleManifestMaxSize = 1 << 20
+)
+
+// Bundle is a tarball with plugin images for deployments without access to docker registries.
+// The tarball contains bundle.json with this manifest and image archives made by `docker save`,
+// it could be gzipped.
+type Bundle struct {
+	Version int             `json:"version"`
+	Plugins []*BundlePlugin `json:"plugins"`
+}
+
+type BundlePlugin struct {
+	Plugin *Plugin `json:"plugin" description:"plugin metadata, container.image is the name of the saved image"`
+	Image  string  `json:"image" description:"path of the image archive in the bundle, e.g. images/wpscan.tar"`
+}
+
+// ImagePath returns the clean path of the image archive in the bundle
+func (bp *BundlePlugin) ImagePath() string {
+	return cleanBundlePath(bp.Image)
+}
+
+// BundleResult is the result of the bundle import
+type BundleResult struct {
+	Created []*Plugin `json:"created"`
+	Updated []*Plugin `json:"updated"`
+}
+
+func (b *Bundle) Validate(images map[string]string) error {
+	errs := validate.Errors{}
+	if b.Version != BundleVersion {
+		errs.Add("version", validate.CodeInvalid, "unsupported version %d, should be %d", b.Version, BundleVersion)
+	}
+	if len(b.Plugins) == 0 {
+		errs.Add("plugins", validate.CodeRequired, "plugins are required")
+	}
+	for i, bp := range b.Plugins {
+		field := fmt.Sprintf("plugins.%d", i)
+		if bp == nil || bp.Plugin == nil {
+			errs.Add(field+".plugin", validate.CodeRequired, "plugin is required")
+			continue
+		}
+		p := bp.Plugin
+		if p.Name == "" {
+			errs.Add(field+".plugin.name", validate.CodeRequired, "name is required")
+		}
+		if p.Version == "" {
+			errs.Add(field+".plugin.version", validate.CodeRequired, "version is required")
+		}
+		if p.Container == nil || p.Container.Image == "" {
+			errs.Add(field+".plugin.container.image", validate.CodeRequired, "image name is required")
+		}
+		if _, ok := images[bp.ImagePath()]; !ok {
+			errs.Add(field+".image", validate.CodeInvalid, "archive %s isn't found in the bundle", bp.Image)
+		}
+	}
+	return errs.Err()
+}
+
+// ReadBundle reads the bundle tarball, image archives are passed to store which returns ids of stored files.
+// Returned images are ids of stored archives by their paths.
+func ReadBundle(r io.Reader, store func(name string, r io.Reader) (string, error)) (*Bundle, map[string]string, error) {
+	br := bufio.NewReader(r)
+	var src io.Reader = br
+	// gzip magic bytes
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer gz.Close()
+		src = gz
+	}
+	var bundle *Bundle
+	images := map[string]string{}
+	tr := tar.NewReader(src)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("broken tarball: %v", err)
+		}
+		if h.Typeflag != tar.TypeReg && h.Typeflag != tar.TypeRegA {
+			continue
+		}
+		name := cleanBundlePath(h.Name)
+		if name == BundleManifest {
+			bundle = &Bundle{}
+			if err := json.NewDecoder(io.LimitReader(tr, bundleManifestMaxSize)).Decode(bundle); err != nil {
+				return nil, nil, fmt.Errorf("broken %s: %v", BundleManifest, err)
+			}
+			continue
+		}
+		if !strings.HasSuffix(name, ".tar") {
+			continue
+		}
+		id, err := store(name, tr)
+		if err != nil {
+			return nil, nil, err
+		}
+		images[name] = id
+	}
+	if bundle == nil {
+		return nil, nil, fmt.Errorf("%s isn't found in the bundle", BundleManifest)
+	}
+	return bundle, images, nil
+}
+
+func cleanBundlePath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
@@ -0,0 +1,74 @@
+package plugin
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func makeTar(t *testing.T, files map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for name, data := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data))}))
+		_, err := tw.Write([]byte(data))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func TestReadBundle(t *testing.T) {
+	manifest := `{"version": 1, "plugins": [{"plugin": {"name": "barbudo/wpscan", "version": "0.1.0",
+		"container": {"image": "barbudo/wpscan:0.1.0"}}, "image": "./images/wpscan.tar"}]}`
+	data := makeTar(t, map[string]string{
+		"bundle.json":       manifest,
+		"images/wpscan.tar": "image data",
+		"README":            "skipped",
+	})
+	stored := map[string]string{}
+	store := func(name string, r io.Reader) (string, error) {
+		data, err := ioutil.ReadAll(r)
+		stored[name] = string(data)
+		return "file-" + name, err
+	}
+
+	b, images, err := ReadBundle(bytes.NewReader(data), store)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"images/wpscan.tar": "image data"}, stored)
+	assert.Equal(t, map[string]string{"images/wpscan.tar": "file-images/wpscan.tar"}, images)
+	require.Len(t, b.Plugins, 1)
+	assert.Equal(t, "barbudo/wpscan", b.Plugins[0].Plugin.Name)
+	assert.NoError(t, b.Validate(images))
+	assert.Error(t, b.Validate(map[string]string{}), "archive is missed")
+
+	// gzipped bundles are supported too
+	gzBuf := &bytes.Buffer{}
+	gz := gzip.NewWriter(gzBuf)
+	gz.Write(data)
+	gz.Close()
+	_, images, err = ReadBundle(gzBuf, store)
+	require.NoError(t, err)
+	assert.Len(t, images, 1)
+
+	_, _, err = ReadBundle(bytes.NewReader(makeTar(t, map[string]string{"images/a.tar": "a"})), store)
+	assert.Error(t, err, "manifest is required")
+	_, _, err = ReadBundle(bytes.NewReader([]byte("not a tarball")), store)
+	assert.Error(t, err)
+}
+
+func TestBundleValidate(t *testing.T) {
+	b := &Bundle{Version: 2, Plugins: []*BundlePlugin{nil, {Plugin: &Plugin{}, Image: "a.tar"}}}
+	err := b.Validate(map[string]string{"a.tar": "id"})
+	require.Error(t, err)
+	for _, field := range []string{"version", "plugins.0.plugin", "plugins.1.plugin.name",
+		"plugins.1.plugin.version", "plugins.1.plugin.container.image"} {
+		assert.Contains(t, err.Error(), field)
+	}
+}
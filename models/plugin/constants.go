@@ -62,6 +62,54 @@ func (t PluginWeight) Convert(text string) (interface{}, error) {
 
 // =========
 
+// Category describes what the plugin does to the target, plugins could have several categories.
+// Plans are composed by categories and projects could forbid some of them
+type Category string
+
+const (
+	CategoryDiscovery  Category = "discovery"   // hosts, ports and technologies
+	CategoryWebPassive Category = "web-passive" // checks of responses without attacks
+	CategoryWebActive  Category = "web-active"  // sends attack payloads
+	CategorySsl        Category = "ssl"         // tls configuration and certificates
+	CategoryCms        Category = "cms"         // checks of wordpress, joomla and others
+	CategoryDos        Category = "dos"         // checks which could make the target unavailable
+	CategoryBruteforce Category = "bruteforce"  // password guessing, could lock accounts
+)
+
+var pluginCategories = []interface{}{
+	CategoryDiscovery,
+	CategoryWebPassive,
+	CategoryWebActive,
+	CategorySsl,
+	CategoryCms,
+	CategoryDos,
+	CategoryBruteforce,
+}
+
+// It's a hack to show custom type as string in swagger
+func (t Category) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+func (t Category) Enum() []interface{} {
+	return pluginCategories
+}
+
+func (t Category) Convert(text string) (interface{}, error) {
+	return Category(text), nil
+}
+
+func (t Category) IsValid() bool {
+	for _, c := range pluginCategories {
+		if c == t {
+			return true
+		}
+	}
+	return false
+}
+
+// =========
+
 type Dependence string
 
 const (
@@ -12,6 +12,8 @@ import (
 type Container struct {
 	Registry string `json:"registry"` // use public if empty
 	Image    string `json:"image"`
+	// offline deployments can't pull images, so agents load them from the server file storage
+	Archive string `json:"archive,omitempty" bson:"archive,omitempty" description:"file id of the image archive imported with the bundle"`
 }
 
 type Desc struct {
@@ -39,6 +41,7 @@ type Plugin struct {
 	FormSchema string        `json:"formSchema" bson:"formSchema" description:"json schema form description"`
 
 	TargetType target.TargetType `json:"targetType" bson:"targetType" description:"available only for target with this type"`
+	Categories []Category        `json:"categories,omitempty" bson:"categories,omitempty" description:"what plugin does to the target, one of [discovery|web-passive|web-active|ssl|cms|dos|bruteforce]"`
 
 	//	Requirements []*Required   `json:"requirements,omitempty" description:"other plugins required for running"`
 	Enabled bool `json:"enabled" description:"is plugin enabled for running"`
@@ -61,6 +64,16 @@ func (p *Plugin) String() string {
 	return str
 }
 
+// HasCategory reports whether the plugin is in the category
+func (p *Plugin) HasCategory(c Category) bool {
+	for _, pc := range p.Categories {
+		if pc == c {
+			return true
+		}
+	}
+	return false
+}
+
 //type Link struct {
 //	Type     LinkType `json:"type"`
 //	Info     string   `json:"info"`
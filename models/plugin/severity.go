@@ -0,0 +1,28 @@
+package plugin
+
+import (
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+)
+
+// SeverityMap translates severities reported by the plugin to bearded ones,
+// it's shared between all versions of the plugin
+type SeverityMap struct {
+	Id      bson.ObjectId             `json:"id,omitempty" bson:"_id"`
+	Plugin  string                    `json:"plugin" description:"plugin name, ex: barbudo/wpscan"`
+	Mapping map[string]issue.Severity `json:"mapping" description:"plugin severity -> one of [info|low|medium|high]"`
+	Updated time.Time                 `json:"updated,omitempty"`
+}
+
+// Get returns mapped severity, case of the plugin severity is ignored
+func (m *SeverityMap) Get(sev string) (issue.Severity, bool) {
+	if m == nil {
+		return "", false
+	}
+	mapped, ok := m.Mapping[strings.ToLower(strings.TrimSpace(sev))]
+	return mapped, ok
+}
@@ -0,0 +1,182 @@
+package project
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+// Blackout is a weekly window when scans for project targets mustn't be started,
+// like business hours for production
+type Blackout struct {
+	Id       bson.ObjectId   `json:"id"`
+	Name     string          `json:"name" description:"blackout name, 80 symbols max" validate:"nonzero,max=80"`
+	Targets  []bson.ObjectId `json:"targets,omitempty" bson:",omitempty" description:"blocked targets, all project targets if empty"`
+	Days     []time.Weekday  `json:"days,omitempty" bson:",omitempty" description:"days of week from 0 (sunday) to 6, every day if empty"`
+	Start    string          `json:"start" description:"start time in format 15:04"`
+	End      string          `json:"end" description:"end time in format 15:04, the window lasts to the next day if end is before start"`
+	Timezone string          `json:"timezone,omitempty" description:"IANA time zone like Europe/Moscow, UTC if empty"`
+}
+
+type BlackoutList struct {
+	pagination.Meta `json:",inline"`
+	Results         []*Blackout `json:"results"`
+}
+
+// Period is a time range [Start, End)
+type Period struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+func (b *Blackout) Validate() error {
+	errs := validate.Errors{}
+	if _, err := parseClock(b.Start); err != nil {
+		errs.Add("start", validate.CodeInvalid, "should be in format 15:04")
+	}
+	if _, err := parseClock(b.End); err != nil {
+		errs.Add("end", validate.CodeInvalid, "should be in format 15:04")
+	}
+	if b.Start == b.End {
+		errs.Add("end", validate.CodeInvalid, "start and end shouldn't be equal")
+	}
+	for _, d := range b.Days {
+		if d < time.Sunday || d > time.Saturday {
+			errs.Add("days", validate.CodeInvalid, "day should be from 0 to 6")
+			break
+		}
+	}
+	if _, err := time.LoadLocation(b.Timezone); err != nil {
+		errs.Add("timezone", validate.CodeInvalid, err.Error())
+	}
+	return errs.Err()
+}
+
+// HasTarget reports whether the blackout blocks scans for the target
+func (b *Blackout) HasTarget(target bson.ObjectId) bool {
+	if len(b.Targets) == 0 {
+		return true
+	}
+	for _, t := range b.Targets {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+// Periods returns blocked periods which intersect with [from, to).
+func (b *Blackout) Periods(from, to time.Time) []Period {
+	loc, err := time.LoadLocation(b.Timezone)
+	if err != nil {
+		return nil
+	}
+	start, err := parseClock(b.Start)
+	if err != nil {
+		return nil
+	}
+	end, err := parseClock(b.End)
+	if err != nil {
+		return nil
+	}
+	if end <= start {
+		end += 24 * time.Hour
+	}
+	periods := []Period{}
+	local := from.In(loc)
+	// the window started yesterday could still be active
+	day := time.Date(local.Year(), local.Month(), local.Day()-1, 0, 0, 0, 0, loc)
+	for !day.After(to) {
+		if b.hasDay(day.Weekday()) {
+			p := Period{Start: onDay(day, start).UTC(), End: onDay(day, end).UTC()}
+			if p.End.After(from) && p.Start.Before(to) {
+				periods = append(periods, p)
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return periods
+}
+
+// Active returns the blocked period which contains t
+func (b *Blackout) Active(t time.Time) *Period {
+	for _, p := range b.Periods(t, t.Add(time.Nanosecond)) {
+		if !p.Start.After(t) && p.End.After(t) {
+			return &p
+		}
+	}
+	return nil
+}
+
+func (b *Blackout) hasDay(day time.Weekday) bool {
+	if len(b.Days) == 0 {
+		return true
+	}
+	for _, d := range b.Days {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Project) GetBlackout(id bson.ObjectId) *Blackout {
+	for _, b := range p.Blackouts {
+		if b.Id == id {
+			return b
+		}
+	}
+	return nil
+}
+
+// windows which cover whole days could block scanning forever, so the search of the end is limited
+const MaxBlocked = 7 * 24 * time.Hour
+
+// Blocked returns the end of the blackout which forbids scanning the target at t,
+// zero time if scanning is allowed. If windows block the whole MaxBlocked after t,
+// the target is blocked indefinitely and t plus MaxBlocked is returned.
+func (p *Project) Blocked(target bson.ObjectId, t time.Time) time.Time {
+	var until time.Time
+	limit := t.Add(MaxBlocked)
+	// windows could overlap, so check again from the end of the found one
+	for changed := true; changed; {
+		changed = false
+		at := t
+		if !until.IsZero() {
+			at = until
+		}
+		for _, b := range p.Blackouts {
+			if !b.HasTarget(target) {
+				continue
+			}
+			if period := b.Active(at); period != nil && period.End.After(until) {
+				until = period.End
+				changed = true
+			}
+		}
+		if !until.Before(limit) {
+			return limit
+		}
+	}
+	return until
+}
+
+// onDay returns the wall clock time of the day in its location. Days with daylight saving changes
+// are shorter or longer than 24 hours, so the clock isn't added as a duration to midnight.
+// The clock in the skipped hour is moved forward, like 02:30 to 03:30.
+func onDay(day time.Time, clock time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(),
+		int(clock/time.Hour), int(clock%time.Hour/time.Minute), 0, 0, day.Location())
+}
+
+// parse time of day in format 15:04
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
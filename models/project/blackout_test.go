@@ -0,0 +1,83 @@
+package project
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestBlackoutValidate(t *testing.T) {
+	assert.NoError(t, (&Blackout{Start: "09:00", End: "18:00"}).Validate())
+	assert.NoError(t, (&Blackout{Start: "22:00", End: "02:00", Timezone: "Europe/Moscow"}).Validate())
+	assert.Error(t, (&Blackout{Start: "9", End: "18:00"}).Validate())
+	assert.Error(t, (&Blackout{Start: "09:00", End: "09:00"}).Validate())
+	assert.Error(t, (&Blackout{Start: "09:00", End: "18:00", Days: []time.Weekday{7}}).Validate())
+	assert.Error(t, (&Blackout{Start: "09:00", End: "18:00", Timezone: "Mars/Olympus"}).Validate())
+}
+
+func TestBlackoutPeriods(t *testing.T) {
+	// business hours on weekdays
+	b := &Blackout{Start: "09:00", End: "18:00", Days: []time.Weekday{1, 2, 3, 4, 5}}
+	// 2015-06-05 is friday
+	from := time.Date(2015, 6, 5, 12, 0, 0, 0, time.UTC)
+	periods := b.Periods(from, from.AddDate(0, 0, 4))
+	require.Len(t, periods, 3)
+	assert.Equal(t, time.Date(2015, 6, 5, 9, 0, 0, 0, time.UTC), periods[0].Start)
+	assert.Equal(t, time.Date(2015, 6, 8, 9, 0, 0, 0, time.UTC), periods[1].Start)
+	assert.Equal(t, time.Date(2015, 6, 9, 18, 0, 0, 0, time.UTC), periods[2].End)
+
+	assert.NotNil(t, b.Active(from))
+	assert.Nil(t, b.Active(time.Date(2015, 6, 6, 12, 0, 0, 0, time.UTC)))
+
+	// overnight window started yesterday
+	night := &Blackout{Start: "22:00", End: "02:00", Timezone: "Europe/Moscow"}
+	active := night.Active(time.Date(2015, 6, 5, 21, 30, 0, 0, time.UTC)) // 00:30 in Moscow
+	require.NotNil(t, active)
+	assert.Equal(t, time.Date(2015, 6, 5, 23, 0, 0, 0, time.UTC), active.End)
+}
+
+func TestBlackoutPeriodsDst(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	b := &Blackout{Start: "09:00", End: "18:00", Timezone: "America/New_York"}
+	// clocks are moved forward at 2015-03-08 and back at 2015-11-01
+	for _, day := range []time.Time{
+		time.Date(2015, 3, 7, 0, 0, 0, 0, time.UTC),
+		time.Date(2015, 3, 8, 0, 0, 0, 0, time.UTC),
+		time.Date(2015, 10, 31, 0, 0, 0, 0, time.UTC),
+		time.Date(2015, 11, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		active := b.Active(day.Add(17 * time.Hour))
+		require.NotNil(t, active, day.String())
+		assert.Equal(t, "09:00", active.Start.In(loc).Format("15:04"), day.String())
+		assert.Equal(t, "18:00", active.End.In(loc).Format("15:04"), day.String())
+	}
+
+	// overnight window over the change lasts an hour less
+	night := &Blackout{Start: "22:00", End: "06:00", Timezone: "America/New_York"}
+	active := night.Active(time.Date(2015, 3, 8, 8, 0, 0, 0, time.UTC)) // 04:00 EDT
+	require.NotNil(t, active)
+	assert.Equal(t, 7*time.Hour, active.End.Sub(active.Start))
+}
+
+func TestProjectBlocked(t *testing.T) {
+	tgt := bson.NewObjectId()
+	p := &Project{Blackouts: []*Blackout{
+		{Start: "09:00", End: "12:00"},
+		{Start: "11:00", End: "14:00", Targets: []bson.ObjectId{tgt}},
+	}}
+	at := time.Date(2015, 6, 5, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2015, 6, 5, 14, 0, 0, 0, time.UTC), p.Blocked(tgt, at))
+	assert.Equal(t, time.Date(2015, 6, 5, 12, 0, 0, 0, time.UTC), p.Blocked(bson.NewObjectId(), at))
+	assert.True(t, p.Blocked(tgt, time.Date(2015, 6, 5, 15, 0, 0, 0, time.UTC)).IsZero())
+
+	// windows cover the whole day
+	p = &Project{Blackouts: []*Blackout{
+		{Start: "00:00", End: "12:00"},
+		{Start: "12:00", End: "00:00"},
+	}}
+	assert.Equal(t, at.Add(MaxBlocked), p.Blocked(tgt, at))
+}
@@ -0,0 +1,121 @@
+package project
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+const (
+	MaxRecipients = 20
+
+	// deliveries which are missed longer, e.g. while the server is down, are skipped till the next week
+	deliveryWindow = 24 * time.Hour
+)
+
+type DeliveryFormat string
+
+const (
+	DeliveryCsv = DeliveryFormat("csv")
+)
+
+var deliveryFormats = []interface{}{
+	DeliveryCsv,
+}
+
+// It's a hack to show custom type as string in swagger
+func (t DeliveryFormat) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+func (t DeliveryFormat) Enum() []interface{} {
+	return deliveryFormats
+}
+
+func (t DeliveryFormat) Convert(text string) (interface{}, error) {
+	return DeliveryFormat(text), nil
+}
+
+func (t DeliveryFormat) IsValid() bool {
+	for _, f := range deliveryFormats {
+		if f == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery sends the report of open project issues to recipients every week
+type Delivery struct {
+	Enabled    bool           `json:"enabled"`
+	Format     DeliveryFormat `json:"format,omitempty" description:"report format, csv if empty"`
+	Weekday    time.Weekday   `json:"weekday" description:"day of the week in utc, 0 is sunday"`
+	Hour       int            `json:"hour" description:"hour in utc from 0 to 23"`
+	Recipients []string       `json:"recipients" description:"emails, max 20"`
+	Severity   issue.Severity `json:"severity,omitempty" bson:",omitempty" description:"minimal severity of reported issues, all if empty"`
+
+	LastSent *time.Time `json:"lastSent,omitempty" bson:"lastSent,omitempty" description:"set by server"`
+}
+
+func (d *Delivery) GetFormat() DeliveryFormat {
+	if d.Format == "" {
+		return DeliveryCsv
+	}
+	return d.Format
+}
+
+// Slot returns the last scheduled time of the delivery before now
+func (d *Delivery) Slot(now time.Time) time.Time {
+	now = now.UTC()
+	slot := time.Date(now.Year(), now.Month(), now.Day(), d.Hour, 0, 0, 0, time.UTC)
+	slot = slot.AddDate(0, 0, -(int(now.Weekday())-int(d.Weekday)+7)%7)
+	if slot.After(now) {
+		slot = slot.AddDate(0, 0, -7)
+	}
+	return slot
+}
+
+// Due reports whether the report of the last slot should be sent now
+func (d *Delivery) Due(now time.Time) bool {
+	if !d.Enabled || len(d.Recipients) == 0 {
+		return false
+	}
+	slot := d.Slot(now)
+	if now.Sub(slot) >= deliveryWindow {
+		return false
+	}
+	return d.LastSent == nil || d.LastSent.Before(slot)
+}
+
+func (d *Delivery) Validate() error {
+	errs := validate.Errors{}
+	if d.Format != "" && !d.Format.IsValid() {
+		errs.Add("format", validate.CodeInvalid, "should be one of [csv]")
+	}
+	if d.Weekday < time.Sunday || d.Weekday > time.Saturday {
+		errs.Add("weekday", validate.CodeInvalid, "should be from 0 to 6")
+	}
+	if d.Hour < 0 || d.Hour > 23 {
+		errs.Add("hour", validate.CodeInvalid, "should be from 0 to 23")
+	}
+	if d.Enabled && len(d.Recipients) == 0 {
+		errs.Add("recipients", validate.CodeRequired, "recipients are required")
+	}
+	if len(d.Recipients) > MaxRecipients {
+		errs.Add("recipients", validate.CodeMax, "max %d recipients", MaxRecipients)
+	}
+	for i, email := range d.Recipients {
+		if !govalidator.IsEmail(email) {
+			errs.Add(fmt.Sprintf("recipients.%d", i), validate.CodeEmail, "should be an email")
+		}
+	}
+	if d.Severity != "" && !d.Severity.IsValid() {
+		errs.Add("severity", validate.CodeInvalid, "should be one of [high|medium|low|info]")
+	}
+	return errs.Err()
+}
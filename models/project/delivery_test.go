@@ -0,0 +1,51 @@
+package project
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeliverySlot(t *testing.T) {
+	d := &Delivery{Weekday: time.Monday, Hour: 9}
+	// 2015-06-10 is wednesday
+	now := time.Date(2015, 6, 10, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2015, 6, 8, 9, 0, 0, 0, time.UTC), d.Slot(now))
+
+	d.Weekday = time.Wednesday
+	assert.Equal(t, time.Date(2015, 6, 10, 9, 0, 0, 0, time.UTC), d.Slot(now))
+	d.Hour = 13
+	assert.Equal(t, time.Date(2015, 6, 3, 13, 0, 0, 0, time.UTC), d.Slot(now))
+}
+
+func TestDeliveryDue(t *testing.T) {
+	now := time.Date(2015, 6, 10, 12, 0, 0, 0, time.UTC)
+	d := &Delivery{Enabled: true, Weekday: time.Wednesday, Hour: 9, Recipients: []string{"a@example.com"}}
+	assert.True(t, d.Due(now))
+
+	sent := now.Add(-time.Hour)
+	d.LastSent = &sent
+	assert.False(t, d.Due(now))
+	assert.True(t, d.Due(now.AddDate(0, 0, 7)))
+	// missed for more than a day
+	assert.False(t, d.Due(now.AddDate(0, 0, 8)))
+
+	d.Enabled = false
+	assert.False(t, d.Due(now.AddDate(0, 0, 7)))
+}
+
+func TestDeliveryValidate(t *testing.T) {
+	assert.NoError(t, (&Delivery{Enabled: true, Weekday: time.Friday, Hour: 23, Recipients: []string{"a@example.com"}}).Validate())
+	assert.NoError(t, (&Delivery{}).Validate())
+	for _, d := range []*Delivery{
+		{Enabled: true},
+		{Format: "pdf", Recipients: []string{"a@example.com"}},
+		{Weekday: 7},
+		{Hour: 24},
+		{Recipients: []string{"not an email"}},
+		{Severity: "bad"},
+	} {
+		assert.Error(t, d.Validate())
+	}
+}
@@ -0,0 +1,151 @@
+package project
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bearded-web/bearded/models/plugin"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+// Engagement is rules of engagement agreed with owners of project targets,
+// scans which break them aren't created and aren't started
+type Engagement struct {
+	Windows              []*Window         `json:"windows,omitempty" bson:"windows,omitempty" description:"weekly windows when scans are allowed, any time if empty"`
+	ForbiddenCategories  []plugin.Category `json:"forbiddenCategories,omitempty" bson:"forbiddenCategories,omitempty" description:"plugins of these categories aren't allowed, one of [discovery|web-passive|web-active|ssl|cms|dos|bruteforce]"`
+	MaxRequestsPerSecond int               `json:"maxRequestsPerSecond,omitempty" bson:"maxRequestsPerSecond,omitempty" description:"max rate limit of scans, it's used for targets without rate limit"`
+}
+
+// Window is a weekly window when scans are allowed, like night hours for production
+type Window struct {
+	Days     []time.Weekday `json:"days,omitempty" bson:",omitempty" description:"days of week from 0 (sunday) to 6, every day if empty"`
+	Start    string         `json:"start" description:"start time in format 15:04"`
+	End      string         `json:"end" description:"end time in format 15:04, the window lasts to the next day if end is before start"`
+	Timezone string         `json:"timezone,omitempty" description:"IANA time zone like Europe/Moscow, UTC if empty"`
+}
+
+// windows are weekly periods the same as blackouts, so their calculation is shared
+func (w *Window) blackout() *Blackout {
+	return &Blackout{Days: w.Days, Start: w.Start, End: w.End, Timezone: w.Timezone}
+}
+
+func (w *Window) String() string {
+	days := "every day"
+	if len(w.Days) > 0 {
+		names := make([]string, len(w.Days))
+		for i, d := range w.Days {
+			names[i] = d.String()[:3]
+		}
+		days = strings.Join(names, ",")
+	}
+	tz := w.Timezone
+	if tz == "" {
+		tz = "UTC"
+	}
+	return fmt.Sprintf("%s %s-%s %s", days, w.Start, w.End, tz)
+}
+
+func (e *Engagement) Validate() error {
+	errs := validate.Errors{}
+	for i, w := range e.Windows {
+		if w == nil {
+			errs.Add(fmt.Sprintf("windows.%d", i), validate.CodeRequired, "window is required")
+			continue
+		}
+		errs.Extend(fmt.Sprintf("windows.%d", i), w.blackout().Validate())
+	}
+	for i, c := range e.ForbiddenCategories {
+		if !c.IsValid() {
+			errs.Add(fmt.Sprintf("forbiddenCategories.%d", i), validate.CodeInvalid, "should be one of [discovery|web-passive|web-active|ssl|cms|dos|bruteforce]")
+		}
+	}
+	if e.MaxRequestsPerSecond < 0 {
+		errs.Add("maxRequestsPerSecond", validate.CodeMin, "shouldn't be negative")
+	}
+	return errs.Err()
+}
+
+// Window returns the allowed period which contains t or the next one during the week,
+// nil if there are no windows
+func (e *Engagement) Window(t time.Time) *Period {
+	var next *Period
+	for _, w := range e.Windows {
+		for _, p := range w.blackout().Periods(t, t.AddDate(0, 0, 8)) {
+			if !p.End.After(t) {
+				continue
+			}
+			if next == nil || p.Start.Before(next.Start) {
+				p := p
+				next = &p
+			}
+		}
+	}
+	if next != nil && next.Start.Before(t) {
+		next.Start = t
+	}
+	return next
+}
+
+// Allowed reports whether scans could be started at t
+func (e *Engagement) Allowed(t time.Time) bool {
+	if e == nil || len(e.Windows) == 0 {
+		return true
+	}
+	w := e.Window(t)
+	return w != nil && !w.Start.After(t)
+}
+
+// RateLimit returns the scan rate limit with the max rate of the engagement if the rate isn't set
+func (e *Engagement) RateLimit(r *target.RateLimit) *target.RateLimit {
+	if e == nil || e.MaxRequestsPerSecond == 0 {
+		return r
+	}
+	return r.WithDefaults(&target.RateLimit{RequestsPerSecond: e.MaxRequestsPerSecond})
+}
+
+// Violations explains why the scan with plugins and the rate limit breaks the rules at t, nil if it doesn't
+func (e *Engagement) Violations(plugins []*plugin.Plugin, r *target.RateLimit, t time.Time) []string {
+	if e == nil {
+		return nil
+	}
+	var res []string
+	if !e.Allowed(t) {
+		windows := make([]string, len(e.Windows))
+		for i, w := range e.Windows {
+			windows[i] = w.String()
+		}
+		msg := fmt.Sprintf("scans are allowed only in windows [%s]", strings.Join(windows, "; "))
+		if next := e.Window(t); next != nil {
+			msg = fmt.Sprintf("%s, the next one starts at %s", msg, next.Start.Format(time.RFC3339))
+		}
+		res = append(res, msg)
+	}
+	res = append(res, e.PluginViolations(plugins)...)
+	if e.MaxRequestsPerSecond > 0 {
+		if r == nil || r.RequestsPerSecond == 0 {
+			res = append(res, fmt.Sprintf("rate limit should be set, max %d requests per second", e.MaxRequestsPerSecond))
+		} else if r.RequestsPerSecond > e.MaxRequestsPerSecond {
+			res = append(res, fmt.Sprintf("rate limit %d requests per second is more than max %d",
+				r.RequestsPerSecond, e.MaxRequestsPerSecond))
+		}
+	}
+	return res
+}
+
+// PluginViolations explains which plugins are in forbidden categories, nil if there are no such plugins
+func (e *Engagement) PluginViolations(plugins []*plugin.Plugin) []string {
+	if e == nil {
+		return nil
+	}
+	var res []string
+	for _, p := range plugins {
+		for _, c := range e.ForbiddenCategories {
+			if p.HasCategory(c) {
+				res = append(res, fmt.Sprintf("plugin %s is in the forbidden category %s", p.Name, c))
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,78 @@
+package project
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bearded-web/bearded/models/plugin"
+	"github.com/bearded-web/bearded/models/target"
+)
+
+func TestEngagementValidate(t *testing.T) {
+	e := &Engagement{
+		Windows:              []*Window{{Start: "22:00", End: "06:00", Timezone: "Europe/Moscow"}},
+		ForbiddenCategories:  []plugin.Category{plugin.CategoryDos},
+		MaxRequestsPerSecond: 10,
+	}
+	assert.NoError(t, e.Validate())
+	assert.NoError(t, (&Engagement{}).Validate())
+	assert.Error(t, (&Engagement{Windows: []*Window{{Start: "22:00", End: "22:00"}}}).Validate())
+	assert.Error(t, (&Engagement{Windows: []*Window{nil}}).Validate())
+	assert.Error(t, (&Engagement{ForbiddenCategories: []plugin.Category{"unknown"}}).Validate())
+	assert.Error(t, (&Engagement{MaxRequestsPerSecond: -1}).Validate())
+}
+
+func TestEngagementWindow(t *testing.T) {
+	// weekend only, 2015-06-05 is friday
+	e := &Engagement{Windows: []*Window{{Start: "00:00", End: "23:59", Days: []time.Weekday{0, 6}}}}
+	friday := time.Date(2015, 6, 5, 12, 0, 0, 0, time.UTC)
+	assert.False(t, e.Allowed(friday))
+	next := e.Window(friday)
+	require.NotNil(t, next)
+	assert.Equal(t, time.Date(2015, 6, 6, 0, 0, 0, 0, time.UTC), next.Start)
+
+	saturday := time.Date(2015, 6, 6, 12, 0, 0, 0, time.UTC)
+	assert.True(t, e.Allowed(saturday))
+	assert.Equal(t, saturday, e.Window(saturday).Start)
+
+	var empty *Engagement
+	assert.True(t, empty.Allowed(friday))
+	assert.True(t, (&Engagement{}).Allowed(friday))
+}
+
+func TestEngagementViolations(t *testing.T) {
+	now := time.Date(2015, 6, 5, 12, 0, 0, 0, time.UTC)
+	var empty *Engagement
+	assert.Nil(t, empty.Violations(nil, nil, now))
+	assert.Nil(t, empty.RateLimit(nil))
+
+	e := &Engagement{
+		Windows:              []*Window{{Start: "22:00", End: "06:00"}},
+		ForbiddenCategories:  []plugin.Category{plugin.CategoryDos},
+		MaxRequestsPerSecond: 10,
+	}
+	plugins := []*plugin.Plugin{
+		{Name: "barbudo/wpscan"},
+		{Name: "barbudo/slowloris", Categories: []plugin.Category{plugin.CategoryDos}},
+	}
+	v := e.Violations(plugins, &target.RateLimit{RequestsPerSecond: 50}, now)
+	require.Len(t, v, 3)
+	assert.Contains(t, v[0], "every day 22:00-06:00 UTC")
+	assert.Contains(t, v[0], "2015-06-05T22:00:00Z")
+	assert.Contains(t, v[1], "barbudo/slowloris")
+	assert.Contains(t, v[2], "more than max 10")
+
+	// children spawned by running scans are checked only against categories
+	assert.Empty(t, e.PluginViolations(plugins[:1]))
+	assert.Len(t, e.PluginViolations(plugins), 1)
+	assert.Nil(t, empty.PluginViolations(plugins))
+
+	night := time.Date(2015, 6, 5, 23, 0, 0, 0, time.UTC)
+	assert.Len(t, e.Violations(plugins[:1], nil, night), 1, "rate limit is required")
+	assert.Empty(t, e.Violations(plugins[:1], e.RateLimit(nil), night))
+	assert.Equal(t, &target.RateLimit{RequestsPerSecond: 5, Connections: 2},
+		e.RateLimit(&target.RateLimit{RequestsPerSecond: 5, Connections: 2}))
+}
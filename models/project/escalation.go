@@ -0,0 +1,91 @@
+package project
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/notification"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+// Escalation notifies the chain of people while severe issue stays unacknowledged
+type Escalation struct {
+	Enabled  bool              `json:"enabled"`
+	Severity issue.Severity    `json:"severity,omitempty" description:"minimal severity of escalated issues, high if empty"`
+	Steps    []*EscalationStep `json:"steps"`
+
+	Environments []target.Environment `json:"environments,omitempty" bson:",omitempty" description:"escalate only issues of targets in these environments, all if empty"`
+}
+
+type EscalationStep struct {
+	After    int                    `json:"after" description:"hours since the issue is created or reopened"`
+	User     bson.ObjectId          `json:"user,omitempty" bson:",omitempty" description:"project member to notify"`
+	Team     bson.ObjectId          `json:"team,omitempty" bson:",omitempty" description:"project team to notify, all its members get the notification"`
+	Channels []notification.Channel `json:"channels,omitempty" bson:",omitempty" description:"channels to notify, user preferences are used if empty"`
+}
+
+func (e *Escalation) MinSeverity() issue.Severity {
+	if e.Severity == "" {
+		return issue.SeverityHigh
+	}
+	return e.Severity
+}
+
+// Level returns how many steps should be notified for the issue of this age
+func (e *Escalation) Level(age time.Duration) int {
+	level := 0
+	for _, step := range e.Steps {
+		if age < time.Duration(step.After)*time.Hour {
+			break
+		}
+		level++
+	}
+	return level
+}
+
+// Validate checks that steps go one by one and notify project members or teams
+func (e *Escalation) Validate(p *Project) error {
+	errs := validate.Errors{}
+	if e.Severity != "" && !e.Severity.IsValid() {
+		errs.Add("severity", validate.CodeInvalid, "should be one of [high|medium|low|info]")
+	}
+	if err := validEnvironments(e.Environments); err != nil {
+		errs.Add("environments", validate.CodeInvalid, "%s", err)
+	}
+	if e.Enabled && len(e.Steps) == 0 {
+		errs.Add("steps", validate.CodeRequired, "steps are required")
+	}
+	prev := 0
+	for i, step := range e.Steps {
+		field := fmt.Sprintf("steps.%d", i)
+		if step == nil {
+			errs.Add(field, validate.CodeRequired, "step is null")
+			continue
+		}
+		if step.After <= prev {
+			errs.Add(field+".after", validate.CodeMin, "should be bigger than %d", prev)
+		}
+		prev = step.After
+		switch {
+		case step.User == "" && step.Team == "":
+			errs.Add(field+".user", validate.CodeRequired, "user or team is required")
+		case step.User != "" && step.Team != "":
+			errs.Add(field+".team", validate.CodeInvalid, "only one of user or team could be set")
+		case step.User != "" && p.GetMember(step.User) == nil:
+			errs.Add(field+".user", validate.CodeInvalid, "should be a project member")
+		case step.Team != "" && p.GetTeam(step.Team) == nil:
+			errs.Add(field+".team", validate.CodeInvalid, "should be a project team")
+		}
+		for _, ch := range step.Channels {
+			if !ch.IsValid() {
+				errs.Add(field+".channels", validate.CodeInvalid, "unknown channel %s", ch)
+				break
+			}
+		}
+	}
+	return errs.Err()
+}
@@ -0,0 +1,35 @@
+package project
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/notification"
+)
+
+func TestEscalation(t *testing.T) {
+	u1, u2 := bson.NewObjectId(), bson.NewObjectId()
+	p := &Project{Members: []*Member{{User: u1}, {User: u2}}}
+	e := &Escalation{
+		Enabled: true,
+		Steps: []*EscalationStep{
+			{After: 4, User: u1},
+			{After: 24, User: u2, Channels: []notification.Channel{notification.ChannelEmail}},
+		},
+	}
+	assert.NoError(t, e.Validate(p))
+	assert.Equal(t, issue.SeverityHigh, e.MinSeverity())
+	assert.Equal(t, 0, e.Level(time.Hour))
+	assert.Equal(t, 1, e.Level(4*time.Hour))
+	assert.Equal(t, 2, e.Level(48*time.Hour))
+
+	assert.Error(t, (&Escalation{Enabled: true}).Validate(p))
+	assert.Error(t, (&Escalation{Steps: []*EscalationStep{{After: 4, User: u1}, {After: 2, User: u2}}}).Validate(p))
+	assert.Error(t, (&Escalation{Steps: []*EscalationStep{{After: 4, User: bson.NewObjectId()}}}).Validate(p))
+	assert.Error(t, (&Escalation{Steps: []*EscalationStep{{After: 4, User: u1, Channels: []notification.Channel{"pigeon"}}}}).Validate(p))
+	assert.Error(t, (&Escalation{Severity: "bla"}).Validate(p))
+}
@@ -0,0 +1,201 @@
+package project
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+type FieldType string
+
+const (
+	FieldText   FieldType = "text"
+	FieldEnum   FieldType = "enum"
+	FieldNumber FieldType = "number"
+	FieldDate   FieldType = "date"
+)
+
+// DateLayout is used for date fields without time
+const DateLayout = "2006-01-02"
+
+var fieldNameRe = regexp.MustCompile(`^[a-z][a-zA-Z0-9]{0,39}$`)
+
+// Field is a custom issue field defined by the project, like internal ticket id or business unit.
+// Values are stored in issue fields by the name.
+type Field struct {
+	Id       bson.ObjectId `json:"id"`
+	Name     string        `json:"name" description:"key in issue fields, camelCase, 40 symbols max"`
+	Title    string        `json:"title" description:"title for humans, 80 symbols max" validate:"nonzero,max=80"`
+	Type     FieldType     `json:"type" description:"one of [text enum number date]"`
+	Required bool          `json:"required"`
+
+	// validation
+	Values    []string `json:"values,omitempty" bson:",omitempty" description:"allowed values for enum"`
+	Min       *float64 `json:"min,omitempty" bson:",omitempty" description:"min number"`
+	Max       *float64 `json:"max,omitempty" bson:",omitempty" description:"max number"`
+	MaxLength int      `json:"maxLength,omitempty" bson:",omitempty" description:"max text length"`
+	Pattern   string   `json:"pattern,omitempty" bson:",omitempty" description:"regular expression for text, like ^JIRA-[0-9]+$"`
+}
+
+type FieldList struct {
+	pagination.Meta `json:",inline"`
+	Results         []*Field `json:"results"`
+}
+
+func (t FieldType) IsValid() bool {
+	switch t {
+	case FieldText, FieldEnum, FieldNumber, FieldDate:
+		return true
+	}
+	return false
+}
+
+// Validate checks the field definition
+func (f *Field) Validate() error {
+	errs := validate.Errors{}
+	if !fieldNameRe.MatchString(f.Name) {
+		errs.Add("name", validate.CodeInvalid, "should be camelCase letters and digits")
+	}
+	if !f.Type.IsValid() {
+		errs.Add("type", validate.CodeInvalid, "should be one of [text enum number date]")
+	}
+	if f.Type == FieldEnum && len(f.Values) == 0 {
+		errs.Add("values", validate.CodeRequired, "values are required for enum")
+	}
+	if f.Type != FieldEnum && len(f.Values) > 0 {
+		errs.Add("values", validate.CodeInvalid, "values are allowed only for enum")
+	}
+	if (f.Min != nil || f.Max != nil) && f.Type != FieldNumber {
+		errs.Add("min", validate.CodeInvalid, "min and max are allowed only for number")
+	}
+	if f.Min != nil && f.Max != nil && *f.Min > *f.Max {
+		errs.Add("min", validate.CodeInvalid, "min should be less than max")
+	}
+	if (f.MaxLength != 0 || f.Pattern != "") && f.Type != FieldText {
+		errs.Add("maxLength", validate.CodeInvalid, "maxLength and pattern are allowed only for text")
+	}
+	if f.MaxLength < 0 {
+		errs.Add("maxLength", validate.CodeMin, "should be positive")
+	}
+	if f.Pattern != "" {
+		if _, err := regexp.Compile(f.Pattern); err != nil {
+			errs.Add("pattern", validate.CodeInvalid, "wrong regular expression: %s", err)
+		}
+	}
+	return errs.Err()
+}
+
+// Value checks the raw value from json or query and returns the value to store:
+// string for text and enum, float64 for number and time for date
+func (f *Field) Value(raw interface{}) (interface{}, error) {
+	switch f.Type {
+	case FieldNumber:
+		var val float64
+		switch v := raw.(type) {
+		case float64:
+			val = v
+		case int:
+			val = float64(v)
+		case string:
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s should be a number", f.Name)
+			}
+			val = parsed
+		default:
+			return nil, fmt.Errorf("%s should be a number", f.Name)
+		}
+		if f.Min != nil && val < *f.Min {
+			return nil, fmt.Errorf("%s should be %v or more", f.Name, *f.Min)
+		}
+		if f.Max != nil && val > *f.Max {
+			return nil, fmt.Errorf("%s should be %v or less", f.Name, *f.Max)
+		}
+		return val, nil
+	case FieldDate:
+		switch v := raw.(type) {
+		case time.Time:
+			return v.UTC(), nil
+		case string:
+			if t, err := time.Parse(time.RFC3339, v); err == nil {
+				return t.UTC(), nil
+			}
+			if t, err := time.Parse(DateLayout, v); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("%s should be a date in format %s or RFC3339", f.Name, DateLayout)
+	}
+
+	val, ok := raw.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s should be a string", f.Name)
+	}
+	if f.Type == FieldEnum {
+		for _, v := range f.Values {
+			if v == val {
+				return val, nil
+			}
+		}
+		return nil, fmt.Errorf("%s should be one of %v", f.Name, f.Values)
+	}
+	if f.MaxLength > 0 && len([]rune(val)) > f.MaxLength {
+		return nil, fmt.Errorf("%s should be %d symbols max", f.Name, f.MaxLength)
+	}
+	if f.Pattern != "" {
+		if re, err := regexp.Compile(f.Pattern); err == nil && !re.MatchString(val) {
+			return nil, fmt.Errorf("%s should match %s", f.Name, f.Pattern)
+		}
+	}
+	return val, nil
+}
+
+func (p *Project) GetField(id bson.ObjectId) *Field {
+	for _, f := range p.Fields {
+		if f.Id == id {
+			return f
+		}
+	}
+	return nil
+}
+
+func (p *Project) GetFieldByName(name string) *Field {
+	for _, f := range p.Fields {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// CheckFields validates issue field values against project fields and returns values to store.
+// Nil values remove fields, but required fields can't be removed.
+func (p *Project) CheckFields(values map[string]interface{}) (map[string]interface{}, error) {
+	result := map[string]interface{}{}
+	for name, raw := range values {
+		f := p.GetFieldByName(name)
+		if f == nil {
+			return nil, fmt.Errorf("unknown field %s", name)
+		}
+		if raw == nil {
+			continue
+		}
+		val, err := f.Value(raw)
+		if err != nil {
+			return nil, err
+		}
+		result[name] = val
+	}
+	for _, f := range p.Fields {
+		if _, ok := result[f.Name]; f.Required && !ok {
+			return nil, fmt.Errorf("%s is required", f.Name)
+		}
+	}
+	return result, nil
+}
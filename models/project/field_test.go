@@ -0,0 +1,64 @@
+package project
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFieldValidate(t *testing.T) {
+	min, max := 10.0, 1.0
+	assert.Error(t, (&Field{Name: "Ticket", Type: FieldText}).Validate())
+	assert.Error(t, (&Field{Name: "ticket.id", Type: FieldText}).Validate())
+	assert.Error(t, (&Field{Name: "ticket", Type: "bool"}).Validate())
+	assert.Error(t, (&Field{Name: "unit", Type: FieldEnum}).Validate())
+	assert.Error(t, (&Field{Name: "unit", Type: FieldText, Values: []string{"a"}}).Validate())
+	assert.Error(t, (&Field{Name: "score", Type: FieldNumber, Min: &min, Max: &max}).Validate())
+	assert.Error(t, (&Field{Name: "ticket", Type: FieldText, Pattern: "("}).Validate())
+	assert.Error(t, (&Field{Name: "due", Type: FieldDate, MaxLength: 10}).Validate())
+	assert.NoError(t, (&Field{Name: "ticketId", Type: FieldText, Pattern: "^JIRA-[0-9]+$"}).Validate())
+	assert.NoError(t, (&Field{Name: "unit", Type: FieldEnum, Values: []string{"retail", "bank"}}).Validate())
+}
+
+func TestCheckFields(t *testing.T) {
+	min := 0.0
+	p := &Project{Fields: []*Field{
+		{Name: "ticket", Type: FieldText, Pattern: "^JIRA-[0-9]+$", Required: true},
+		{Name: "unit", Type: FieldEnum, Values: []string{"retail", "bank"}},
+		{Name: "score", Type: FieldNumber, Min: &min},
+		{Name: "due", Type: FieldDate},
+	}}
+
+	values, err := p.CheckFields(map[string]interface{}{
+		"ticket": "JIRA-12",
+		"unit":   "bank",
+		"score":  5.5,
+		"due":    "2015-06-01",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "JIRA-12", values["ticket"])
+	assert.Equal(t, 5.5, values["score"])
+	assert.Equal(t, time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC), values["due"])
+
+	// nil removes the value
+	values, err = p.CheckFields(map[string]interface{}{"ticket": "JIRA-1", "unit": nil})
+	require.NoError(t, err)
+	assert.Len(t, values, 1)
+
+	for _, wrong := range []map[string]interface{}{
+		{},
+		{"ticket": nil},
+		{"ticket": "TASK-1"},
+		{"ticket": 1.0},
+		{"ticket": "JIRA-1", "unit": "other"},
+		{"ticket": "JIRA-1", "score": -1.0},
+		{"ticket": "JIRA-1", "score": "many"},
+		{"ticket": "JIRA-1", "due": "tomorrow"},
+		{"ticket": "JIRA-1", "unknown": "x"},
+	} {
+		_, err := p.CheckFields(wrong)
+		assert.Error(t, err, "%v", wrong)
+	}
+}
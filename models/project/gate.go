@@ -0,0 +1,74 @@
+package project
+
+import (
+	"fmt"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+// Gate is the deploy policy checked by CI, open issues matched by the gate block deploys
+type Gate struct {
+	Severity     issue.Severity       `json:"severity,omitempty" description:"minimal severity of blocking issues, high if empty"`
+	Environments []target.Environment `json:"environments,omitempty" bson:",omitempty" description:"only issues of targets in these environments block, all if empty"`
+	Confidence   issue.Confidence     `json:"confidence,omitempty" bson:",omitempty" description:"minimal confidence of blocking issues, one of [certain|firm|tentative], all if empty"`
+}
+
+// GateResult is returned to CI, the deploy should be stopped if it isn't passed
+type GateResult struct {
+	Passed   bool            `json:"passed"`
+	Blocking int             `json:"blocking" description:"number of blocking issues"`
+	Issues   []bson.ObjectId `json:"issues" description:"ids of the most risky blocking issues, max 100"`
+	Gate     *Gate           `json:"gate" description:"policy which was checked"`
+}
+
+// DefaultGate blocks deploys by high issues of any environment
+func DefaultGate() *Gate {
+	return &Gate{Severity: issue.SeverityHigh}
+}
+
+func (g *Gate) MinSeverity() issue.Severity {
+	if g.Severity == "" {
+		return issue.SeverityHigh
+	}
+	return g.Severity
+}
+
+// Blocks reports whether the environment is checked by the gate
+func (g *Gate) Blocks(env target.Environment) bool {
+	if len(g.Environments) == 0 {
+		return true
+	}
+	for _, e := range g.Environments {
+		if e == env {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Gate) Validate() error {
+	errs := validate.Errors{}
+	if g.Severity != "" && !g.Severity.IsValid() {
+		errs.Add("severity", validate.CodeInvalid, "should be one of [high|medium|low|info]")
+	}
+	if err := validEnvironments(g.Environments); err != nil {
+		errs.Add("environments", validate.CodeInvalid, "%s", err)
+	}
+	if g.Confidence != "" && !g.Confidence.IsValid() {
+		errs.Add("confidence", validate.CodeInvalid, "should be one of [certain|firm|tentative]")
+	}
+	return errs.Err()
+}
+
+func validEnvironments(envs []target.Environment) error {
+	for _, env := range envs {
+		if !env.IsValid() {
+			return fmt.Errorf("unknown environment %s, should be one of [prod|staging|dev]", env)
+		}
+	}
+	return nil
+}
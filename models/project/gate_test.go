@@ -0,0 +1,30 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/target"
+)
+
+func TestGate(t *testing.T) {
+	g := &Gate{}
+	assert.Equal(t, issue.SeverityHigh, g.MinSeverity())
+	assert.True(t, g.Blocks(target.EnvironmentDev))
+	assert.NoError(t, g.Validate())
+
+	g = &Gate{Severity: issue.SeverityMedium, Environments: []target.Environment{target.EnvironmentProd}}
+	assert.Equal(t, issue.SeverityMedium, g.MinSeverity())
+	assert.True(t, g.Blocks(target.EnvironmentProd))
+	assert.False(t, g.Blocks(target.EnvironmentStaging))
+	assert.False(t, g.Blocks(""))
+	assert.NoError(t, g.Validate())
+
+	g = &Gate{Severity: "critical", Environments: []target.Environment{"qa"}}
+	assert.Error(t, g.Validate())
+
+	assert.NoError(t, (&Gate{Confidence: issue.ConfidenceFirm}).Validate())
+	assert.Error(t, (&Gate{Confidence: "sure"}).Validate())
+}
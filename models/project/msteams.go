@@ -0,0 +1,87 @@
+package project
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/bearded-web/bearded/models/notification"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+// hosts of microsoft teams incoming webhooks and workflows
+var msTeamsHosts = []string{".webhook.office.com", ".office.com", ".logic.azure.com"}
+
+// MsTeams posts project notifications as connector cards to a Microsoft Teams channel
+type MsTeams struct {
+	Enabled bool                 `json:"enabled"`
+	Url     string               `json:"url" description:"incoming webhook url of the channel"`
+	Events  []notification.Event `json:"events,omitempty" bson:",omitempty" description:"posted events, all if empty"`
+
+	// notifications which aren't related to a target, like escalations of issues without environment, are always posted
+	Environments []target.Environment `json:"environments,omitempty" bson:",omitempty" description:"posted environments of targets, all if empty"`
+}
+
+func (t *MsTeams) Validate() error {
+	errs := validate.Errors{}
+	u, err := url.Parse(t.Url)
+	switch {
+	case err != nil:
+		errs.Add("url", validate.CodeInvalid, err.Error())
+	case u.Scheme != "https":
+		errs.Add("url", validate.CodeInvalid, "should be https url")
+	case !hasHostSuffix(u.Hostname(), msTeamsHosts):
+		errs.Add("url", validate.CodeInvalid, "should be a webhook url of microsoft teams, like https://example.webhook.office.com/...")
+	}
+	for i, e := range t.Events {
+		if !e.IsValid() {
+			errs.Add(fmt.Sprintf("events.%d", i), validate.CodeInvalid, "unknown event %s", e)
+		}
+	}
+	for i, env := range t.Environments {
+		if !env.IsValid() {
+			errs.Add(fmt.Sprintf("environments.%d", i), validate.CodeInvalid, "unknown environment %s", env)
+		}
+	}
+	return errs.Err()
+}
+
+// Allows reports whether the event of the target environment is posted to the channel,
+// env is empty if the event isn't related to a target with environment
+func (t *MsTeams) Allows(event notification.Event, env target.Environment) bool {
+	if t == nil || !t.Enabled {
+		return false
+	}
+	if env != "" && len(t.Environments) > 0 && !hasEnvironment(t.Environments, env) {
+		return false
+	}
+	if len(t.Events) == 0 {
+		return true
+	}
+	for _, e := range t.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func hasEnvironment(envs []target.Environment, env target.Environment) bool {
+	for _, e := range envs {
+		if e == env {
+			return true
+		}
+	}
+	return false
+}
+
+func hasHostSuffix(host string, suffixes []string) bool {
+	host = strings.ToLower(host)
+	for _, s := range suffixes {
+		if strings.HasSuffix(host, s) {
+			return true
+		}
+	}
+	return false
+}
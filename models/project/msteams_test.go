@@ -0,0 +1,48 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bearded-web/bearded/models/notification"
+	"github.com/bearded-web/bearded/models/target"
+)
+
+func TestMsTeamsValidate(t *testing.T) {
+	teams := &MsTeams{Url: "https://example.webhook.office.com/webhookb2/abc"}
+	assert.NoError(t, teams.Validate())
+
+	teams.Url = "http://example.webhook.office.com/webhookb2/abc"
+	assert.Error(t, teams.Validate())
+	teams.Url = "https://example.com/webhook"
+	assert.Error(t, teams.Validate())
+	teams.Url = "https://prod-01.westus.logic.azure.com/workflows/abc"
+	assert.NoError(t, teams.Validate())
+
+	teams.Events = []notification.Event{notification.EventScanFailed, "unknown"}
+	assert.Error(t, teams.Validate())
+
+	teams.Events = nil
+	teams.Environments = []target.Environment{target.EnvironmentProd, "qa"}
+	assert.Error(t, teams.Validate())
+}
+
+func TestMsTeamsAllows(t *testing.T) {
+	var teams *MsTeams
+	assert.False(t, teams.Allows(notification.EventScanFailed, ""))
+
+	teams = &MsTeams{Url: "https://example.webhook.office.com/webhookb2/abc"}
+	assert.False(t, teams.Allows(notification.EventScanFailed, ""))
+	teams.Enabled = true
+	assert.True(t, teams.Allows(notification.EventScanFailed, ""))
+
+	teams.Events = []notification.Event{notification.EventIssueEscalated}
+	assert.False(t, teams.Allows(notification.EventScanFailed, ""))
+	assert.True(t, teams.Allows(notification.EventIssueEscalated, ""))
+
+	teams.Environments = []target.Environment{target.EnvironmentProd}
+	assert.True(t, teams.Allows(notification.EventIssueEscalated, target.EnvironmentProd))
+	assert.False(t, teams.Allows(notification.EventIssueEscalated, target.EnvironmentDev))
+	assert.True(t, teams.Allows(notification.EventIssueEscalated, ""))
+}
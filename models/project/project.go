@@ -5,6 +5,7 @@ import (
 
 	"gopkg.in/mgo.v2/bson"
 
+	"github.com/bearded-web/bearded/models/target"
 	"github.com/bearded-web/bearded/pkg/pagination"
 )
 
@@ -19,7 +20,33 @@ type Project struct {
 	Created time.Time     `json:"created,omitempty"`
 	Updated time.Time     `json:"updated,omitempty"`
 
-	Members []*Member `json:"members" bson:"members"`
+	Members   []*Member        `json:"members" bson:"members"`
+	Templates []*IssueTemplate `json:"templates,omitempty" bson:"templates,omitempty" description:"templates for manually reported issues"`
+	IssueSort string           `json:"issueSort,omitempty" bson:"issueSort,omitempty" description:"default sort for project issues, like -severity,created"`
+	Rules     []*Rule          `json:"rules,omitempty" bson:"rules,omitempty" description:"rules for issues created from scans"`
+	Blackouts []*Blackout      `json:"blackouts,omitempty" bson:"blackouts,omitempty" description:"windows when scans aren't started"`
+	Fields    []*Field         `json:"fields,omitempty" bson:"fields,omitempty" description:"custom issue fields"`
+	Teams     []*Team          `json:"teams,omitempty" bson:"teams,omitempty" description:"groups of members, issues could be assigned to them"`
+
+	Escalation *Escalation       `json:"escalation,omitempty" bson:"escalation,omitempty" description:"notify people while severe issues stay unacknowledged"`
+	Gate       *Gate             `json:"gate,omitempty" bson:"gate,omitempty" description:"deploy policy checked by CI, high issues block deploys if empty"`
+	RateLimit  *target.RateLimit `json:"rateLimit,omitempty" bson:"rateLimit,omitempty" description:"default scan politeness for project targets"`
+	Proxy      *target.Proxy     `json:"proxy,omitempty" bson:"proxy,omitempty" description:"default outbound proxy for plugin traffic of project targets"`
+	Quota      *Quota            `json:"quota,omitempty" bson:"quota,omitempty" description:"scan limits set by admins, server defaults are used if empty"`
+	Delivery   *Delivery         `json:"delivery,omitempty" bson:"delivery,omitempty" description:"weekly report of open issues sent by email"`
+	Engagement *Engagement       `json:"engagement,omitempty" bson:"engagement,omitempty" description:"rules of engagement, scans which break them are rejected"`
+	MsTeams    *MsTeams          `json:"msTeams,omitempty" bson:"msTeams,omitempty" description:"microsoft teams channel for project notifications"`
+	ServiceNow *ServiceNow       `json:"serviceNow,omitempty" bson:"serviceNow,omitempty" description:"records in servicenow for severe issues, synced both ways"`
+
+	RequireReview bool `json:"requireReview" bson:"requireReview,omitempty" description:"issues found by scans are counted in target summaries only after the scan review"`
+}
+
+// GetGate returns the project gate or the default one
+func (p *Project) GetGate() *Gate {
+	if p.Gate == nil {
+		return DefaultGate()
+	}
+	return p.Gate
 }
 
 func (p *Project) String() string {
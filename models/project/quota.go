@@ -0,0 +1,41 @@
+package project
+
+import "github.com/bearded-web/bearded/pkg/validate"
+
+// Quota limits resources of the project, zero values are unlimited
+type Quota struct {
+	MaxScans     int `json:"maxScans" bson:"maxScans" description:"max scans running at the same time"`
+	AgentMinutes int `json:"agentMinutes" bson:"agentMinutes" description:"agent minutes per calendar month"`
+	Targets      int `json:"targets" bson:"targets" description:"max targets in the project"`
+	ScansPerDay  int `json:"scansPerDay" bson:"scansPerDay" description:"scans created per UTC day"`
+	Storage      int `json:"storage" bson:"storage" description:"megabytes of files uploaded to the project"`
+}
+
+// Validate checks that quota values aren't negative
+func (q *Quota) Validate() error {
+	errs := validate.Errors{}
+	values := []struct {
+		field string
+		value int
+	}{
+		{"maxScans", q.MaxScans},
+		{"agentMinutes", q.AgentMinutes},
+		{"targets", q.Targets},
+		{"scansPerDay", q.ScansPerDay},
+		{"storage", q.Storage},
+	}
+	for _, v := range values {
+		if v.value < 0 {
+			errs.Add(v.field, validate.CodeMin, "shouldn't be negative")
+		}
+	}
+	return errs.Err()
+}
+
+// GetQuota returns the project quota or the default one
+func (p *Project) GetQuota(def Quota) Quota {
+	if p.Quota != nil {
+		return *p.Quota
+	}
+	return def
+}
@@ -0,0 +1,19 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQuota(t *testing.T) {
+	def := Quota{MaxScans: 2, Storage: 100}
+	p := &Project{}
+	assert.Equal(t, def, p.GetQuota(def))
+	p.Quota = &Quota{Targets: 5}
+	assert.Equal(t, Quota{Targets: 5}, p.GetQuota(def))
+
+	assert.NoError(t, p.Quota.Validate())
+	p.Quota.ScansPerDay = -1
+	assert.Error(t, p.Quota.Validate())
+}
@@ -0,0 +1,148 @@
+package project
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+// Rule assigns issues created from scans and labels them.
+// All conditions which are set must match.
+type Rule struct {
+	Id      bson.ObjectId `json:"id"`
+	Name    string        `json:"name" description:"rule name, 80 symbols max" validate:"nonzero,max=80"`
+	Enabled bool          `json:"enabled"`
+
+	// conditions
+	Plugin string `json:"plugin,omitempty" description:"plugin name without version, ex: barbudo/wpscan"`
+	Path   string `json:"path,omitempty" description:"regular expression for the path of the issue url"`
+
+	Confidence issue.Confidence `json:"confidence,omitempty" bson:",omitempty" description:"minimal confidence of the issue, one of [certain|firm|tentative]"`
+
+	// actions
+	Assignee bson.ObjectId `json:"assignee,omitempty" bson:",omitempty" description:"project member to assign the issue, if the issue isn't assigned yet"`
+	Team     bson.ObjectId `json:"team,omitempty" bson:",omitempty" description:"project team to assign the issue, if the issue isn't assigned to a team yet"`
+	Labels   []string      `json:"labels,omitempty" bson:",omitempty"`
+}
+
+type RuleList struct {
+	pagination.Meta `json:",inline"`
+	Results         []*Rule `json:"results"`
+}
+
+// Validate checks that the rule has conditions and actions and the path is a valid regexp
+func (r *Rule) Validate() error {
+	errs := validate.Errors{}
+	if r.Plugin == "" && r.Path == "" && r.Confidence == "" {
+		errs.Add("plugin", validate.CodeRequired, "plugin, path or confidence condition is required")
+	}
+	if r.Confidence != "" && !r.Confidence.IsValid() {
+		errs.Add("confidence", validate.CodeInvalid, "should be one of [certain|firm|tentative]")
+	}
+	if r.Assignee == "" && r.Team == "" && len(r.Labels) == 0 {
+		errs.Add("assignee", validate.CodeRequired, "assignee, team or labels are required")
+	}
+	if r.Path != "" {
+		if _, err := regexp.Compile(r.Path); err != nil {
+			errs.Add("path", validate.CodeInvalid, "wrong regular expression: %s", err)
+		}
+	}
+	for _, label := range r.Labels {
+		if strings.TrimSpace(label) == "" {
+			errs.Add("labels", validate.CodeInvalid, "label shouldn't be empty")
+			break
+		}
+	}
+	return errs.Err()
+}
+
+// Match reports whether the rule matches the issue found by the plugin.
+// Plugin could be in format "name:version".
+func (r *Rule) Match(plugin string, obj *issue.TargetIssue) bool {
+	if !r.Enabled {
+		return false
+	}
+	if r.Plugin != "" && r.Plugin != strings.Split(plugin, ":")[0] {
+		return false
+	}
+	if r.Confidence != "" && obj.Confidence.Rank() < r.Confidence.Rank() {
+		return false
+	}
+	if r.Path != "" {
+		re, err := regexp.Compile(r.Path)
+		if err != nil || obj.Vector == nil || obj.Vector.Url == "" {
+			return false
+		}
+		path := obj.Vector.Url
+		if u, err := url.Parse(obj.Vector.Url); err == nil {
+			path = u.Path
+		}
+		if !re.MatchString(path) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply sets the assignee and the team if they aren't set and adds labels
+func (r *Rule) Apply(obj *issue.TargetIssue) {
+	if r.Assignee != "" && obj.Assignee == "" {
+		obj.Assignee = r.Assignee
+	}
+	if r.Team != "" && obj.Team == "" {
+		obj.Team = r.Team
+	}
+	for _, label := range r.Labels {
+		obj.AddLabel(label)
+	}
+}
+
+func (p *Project) GetRule(id bson.ObjectId) *Rule {
+	for _, r := range p.Rules {
+		if r.Id == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// ApplyRules applies matched rules in order to the issue and returns them
+func ApplyRules(rules []*Rule, plugin string, obj *issue.TargetIssue) []*Rule {
+	matched := []*Rule{}
+	for _, r := range rules {
+		if r.Match(plugin, obj) {
+			r.Apply(obj)
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+// RuleAssignees returns users who are assigned by project rules
+func (p *Project) RuleAssignees() []bson.ObjectId {
+	ids := []bson.ObjectId{}
+	for _, r := range p.Rules {
+		if r.Assignee != "" {
+			ids = append(ids, r.Assignee)
+		}
+	}
+	return ids
+}
+
+// SkipAway moves the issue from the assignee who is out of office to the assignee team,
+// so it doesn't wait in the queue of the absent person. The issue stays unassigned if the user has no team.
+func (p *Project) SkipAway(obj *issue.TargetIssue, away map[bson.ObjectId]bool) {
+	if obj.Assignee == "" || !away[obj.Assignee] {
+		return
+	}
+	if t := p.MemberTeam(obj.Assignee); t != nil && obj.Team == "" {
+		obj.Team = t.Id
+	}
+	obj.Assignee = ""
+}
@@ -0,0 +1,75 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+)
+
+func TestRuleValidate(t *testing.T) {
+	assert.Error(t, (&Rule{Labels: []string{"web"}}).Validate())
+	assert.Error(t, (&Rule{Plugin: "barbudo/wpscan"}).Validate())
+	assert.Error(t, (&Rule{Path: "(", Labels: []string{"web"}}).Validate())
+	assert.Error(t, (&Rule{Path: "^/admin", Labels: []string{" "}}).Validate())
+	assert.NoError(t, (&Rule{Path: "^/admin", Labels: []string{"admin"}}).Validate())
+	assert.NoError(t, (&Rule{Confidence: issue.ConfidenceCertain, Labels: []string{"confirmed"}}).Validate())
+	assert.Error(t, (&Rule{Confidence: "sure", Labels: []string{"confirmed"}}).Validate())
+}
+
+func TestRuleMatchConfidence(t *testing.T) {
+	r := &Rule{Enabled: true, Confidence: issue.ConfidenceFirm}
+	assert.True(t, r.Match("", &issue.TargetIssue{Issue: issue.Issue{Confidence: issue.ConfidenceCertain}}))
+	assert.True(t, r.Match("", &issue.TargetIssue{}))
+	assert.False(t, r.Match("", &issue.TargetIssue{Issue: issue.Issue{Confidence: issue.ConfidenceTentative}}))
+}
+
+func TestApplyRules(t *testing.T) {
+	user1, user2 := bson.NewObjectId(), bson.NewObjectId()
+	rules := []*Rule{
+		{Enabled: true, Plugin: "barbudo/wpscan", Assignee: user1, Labels: []string{"wordpress"}},
+		{Enabled: true, Path: "^/admin", Assignee: user2, Labels: []string{"admin", "wordpress"}},
+		{Enabled: false, Path: ".*", Labels: []string{"disabled"}},
+	}
+
+	obj := &issue.TargetIssue{Issue: issue.Issue{Vector: &issue.Vector{Url: "http://example.com/admin/login?next=/"}}}
+	matched := ApplyRules(rules, "barbudo/wpscan:0.2.0", obj)
+	require.Len(t, matched, 2)
+	assert.Equal(t, user1, obj.Assignee)
+	assert.Equal(t, []string{"wordpress", "admin"}, obj.Labels)
+
+	obj = &issue.TargetIssue{Issue: issue.Issue{Vector: &issue.Vector{Url: "http://example.com/"}}}
+	assert.Empty(t, ApplyRules(rules, "barbudo/nikto", obj))
+	assert.Equal(t, bson.ObjectId(""), obj.Assignee)
+	assert.Empty(t, obj.Labels)
+
+	obj = &issue.TargetIssue{}
+	assert.Len(t, ApplyRules(rules, "barbudo/wpscan", obj), 1)
+}
+
+func TestSkipAway(t *testing.T) {
+	user1, user2 := bson.NewObjectId(), bson.NewObjectId()
+	team := &Team{Id: bson.NewObjectId(), Name: "AppSec", Members: []bson.ObjectId{user1}}
+	p := &Project{
+		Teams: []*Team{team},
+		Rules: []*Rule{{Enabled: true, Plugin: "barbudo/wpscan", Assignee: user1}},
+	}
+	assert.Equal(t, []bson.ObjectId{user1}, p.RuleAssignees())
+
+	obj := &issue.TargetIssue{Assignee: user1}
+	p.SkipAway(obj, map[bson.ObjectId]bool{})
+	assert.Equal(t, user1, obj.Assignee)
+
+	p.SkipAway(obj, map[bson.ObjectId]bool{user1: true})
+	assert.Equal(t, bson.ObjectId(""), obj.Assignee)
+	assert.Equal(t, team.Id, obj.Team)
+
+	// users without team leave the issue unassigned
+	obj = &issue.TargetIssue{Assignee: user2}
+	p.SkipAway(obj, map[bson.ObjectId]bool{user2: true})
+	assert.Equal(t, bson.ObjectId(""), obj.Assignee)
+	assert.Equal(t, bson.ObjectId(""), obj.Team)
+}
@@ -0,0 +1,163 @@
+package project
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+// ServiceNowTable is a table where records are created
+type ServiceNowTable string
+
+const (
+	ServiceNowIncident = ServiceNowTable("incident")
+	ServiceNowSecOps   = ServiceNowTable("sn_si_incident") // security incident response
+)
+
+var serviceNowTables = []interface{}{
+	ServiceNowIncident,
+	ServiceNowSecOps,
+}
+
+// It's a hack to show custom type as string in swagger
+func (t ServiceNowTable) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+func (t ServiceNowTable) Enum() []interface{} {
+	return serviceNowTables
+}
+
+func (t ServiceNowTable) Convert(text string) (interface{}, error) {
+	return ServiceNowTable(text), nil
+}
+
+func (t ServiceNowTable) IsValid() bool {
+	for _, v := range serviceNowTables {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// record states of the tables
+var serviceNowStates = map[ServiceNowTable]struct {
+	open, resolved string
+	closed         []string
+	canceled       []string
+}{
+	ServiceNowIncident: {open: "2", resolved: "6", closed: []string{"6", "7"}, canceled: []string{"8"}},
+	ServiceNowSecOps:   {open: "16", resolved: "3", closed: []string{"3"}, canceled: []string{"7"}},
+}
+
+// OpenState is the state which reopened records get
+func (t ServiceNowTable) OpenState() string {
+	return serviceNowStates[t].open
+}
+
+// ResolvedState is the state which resolved records get
+func (t ServiceNowTable) ResolvedState() string {
+	return serviceNowStates[t].resolved
+}
+
+// CanceledState is the state which records of false positives get
+func (t ServiceNowTable) CanceledState() string {
+	return serviceNowStates[t].canceled[0]
+}
+
+// IsResolved reports whether the record in the state is resolved or closed
+func (t ServiceNowTable) IsResolved(state string) bool {
+	return containsString(serviceNowStates[t].closed, state)
+}
+
+// IsCanceled reports whether the record in the state is canceled, e.g. it isn't a real incident
+func (t ServiceNowTable) IsCanceled(state string) bool {
+	return containsString(serviceNowStates[t].canceled, state)
+}
+
+var credentialsRef = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// ServiceNow creates records in ServiceNow for severe issues and syncs their state and impact both ways
+type ServiceNow struct {
+	Enabled         bool            `json:"enabled"`
+	Instance        string          `json:"instance" description:"instance url, like https://example.service-now.com"`
+	Table           ServiceNowTable `json:"table,omitempty" description:"one of [incident|sn_si_incident], incident if empty"`
+	Credentials     string          `json:"credentials" description:"name of credentials configured on api servers as user:password in BEARDED_CREDENTIALS_<NAME>, they are used only for the instance hosts in BEARDED_CREDENTIALS_<NAME>_HOSTS and projects in BEARDED_CREDENTIALS_<NAME>_PROJECTS, the secret itself isn't stored"`
+	Severity        issue.Severity  `json:"severity,omitempty" description:"records are created for issues with this severity or higher, high if empty"`
+	AssignmentGroup string          `json:"assignmentGroup,omitempty" bson:"assignmentGroup,omitempty" description:"sys_id or name of the assignment group of created records"`
+}
+
+func (s *ServiceNow) GetTable() ServiceNowTable {
+	if s.Table == "" {
+		return ServiceNowIncident
+	}
+	return s.Table
+}
+
+func (s *ServiceNow) MinSeverity() issue.Severity {
+	if s.Severity == "" {
+		return issue.SeverityHigh
+	}
+	return s.Severity
+}
+
+func (s *ServiceNow) Validate() error {
+	errs := validate.Errors{}
+	u, err := url.Parse(s.Instance)
+	switch {
+	case err != nil:
+		errs.Add("instance", validate.CodeInvalid, err.Error())
+	case u.Scheme != "https" || u.Host == "":
+		errs.Add("instance", validate.CodeInvalid, "should be https url")
+	case u.User != nil:
+		errs.Add("instance", validate.CodeInvalid, "credentials shouldn't be in the url")
+	}
+	if s.Table != "" && !s.Table.IsValid() {
+		errs.Add("table", validate.CodeInvalid, "should be one of [incident|sn_si_incident]")
+	}
+	if !credentialsRef.MatchString(s.Credentials) {
+		errs.Add("credentials", validate.CodeInvalid, "should contain only latin letters, digits and underscore")
+	}
+	if s.Severity != "" && !s.Severity.IsValid() {
+		errs.Add("severity", validate.CodeInvalid, "should be one of [high|medium|low|info]")
+	}
+	return errs.Err()
+}
+
+// ServiceNowImpact maps the issue severity to impact and urgency of the record
+func ServiceNowImpact(sev issue.Severity) string {
+	switch sev {
+	case issue.SeverityHigh:
+		return "1"
+	case issue.SeverityMedium:
+		return "2"
+	}
+	return "3"
+}
+
+// ServiceNowSeverity maps the record impact to the issue severity, ok is false for unknown impacts.
+// Low impact is mapped to low severity, so info issues keep their severity while impact isn't changed.
+func ServiceNowSeverity(impact string) (issue.Severity, bool) {
+	switch impact {
+	case "1":
+		return issue.SeverityHigh, true
+	case "2":
+		return issue.SeverityMedium, true
+	case "3":
+		return issue.SeverityLow, true
+	}
+	return "", false
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
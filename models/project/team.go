@@ -0,0 +1,118 @@
+package project
+
+import (
+	"strings"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+// Team is a group of project members, issues are assigned and notifications are sent to the whole team
+type Team struct {
+	Id      bson.ObjectId   `json:"id"`
+	Name    string          `json:"name" description:"team name, 80 symbols max" validate:"nonzero,max=80"`
+	Members []bson.ObjectId `json:"members" bson:"members" description:"project members in the team"`
+}
+
+type TeamList struct {
+	pagination.Meta `json:",inline"`
+	Results         []*Team `json:"results"`
+}
+
+func (t *Team) HasMember(userId bson.ObjectId) bool {
+	for _, m := range t.Members {
+		if m == userId {
+			return true
+		}
+	}
+	return false
+}
+
+// RemoveMember returns true if the user was in the team
+func (t *Team) RemoveMember(userId bson.ObjectId) bool {
+	members := make([]bson.ObjectId, 0, len(t.Members))
+	for _, m := range t.Members {
+		if m != userId {
+			members = append(members, m)
+		}
+	}
+	removed := len(members) != len(t.Members)
+	t.Members = members
+	return removed
+}
+
+// Validate checks that team members are project members and the name isn't used by other teams
+func (t *Team) Validate(p *Project) error {
+	errs := validate.Errors{}
+	for _, other := range p.Teams {
+		if other.Id != t.Id && strings.EqualFold(other.Name, t.Name) {
+			errs.Add("name", validate.CodeInvalid, "team %s already exists", t.Name)
+			break
+		}
+	}
+	seen := map[bson.ObjectId]bool{}
+	for _, m := range t.Members {
+		if p.GetMember(m) == nil {
+			errs.Add("members", validate.CodeInvalid, "%s isn't a project member", m.Hex())
+			break
+		}
+		if seen[m] {
+			errs.Add("members", validate.CodeInvalid, "%s is duplicated", m.Hex())
+			break
+		}
+		seen[m] = true
+	}
+	return errs.Err()
+}
+
+func (p *Project) GetTeam(id bson.ObjectId) *Team {
+	for _, t := range p.Teams {
+		if t.Id == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// MemberTeam returns the first team of the user or nil
+func (p *Project) MemberTeam(userId bson.ObjectId) *Team {
+	for _, t := range p.Teams {
+		if t.HasMember(userId) {
+			return t
+		}
+	}
+	return nil
+}
+
+// TeamUsed reports whether rules or escalation steps refer to the team
+func (p *Project) TeamUsed(id bson.ObjectId) bool {
+	for _, r := range p.Rules {
+		if r.Team == id {
+			return true
+		}
+	}
+	if p.Escalation != nil {
+		for _, step := range p.Escalation.Steps {
+			if step != nil && step.Team == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RemoveMember removes the user from project members and teams
+func (p *Project) RemoveMember(userId bson.ObjectId) {
+	members := make([]*Member, 0, len(p.Members))
+	for _, m := range p.Members {
+		if m.User != userId {
+			members = append(members, m)
+		}
+	}
+	p.Members = members
+	for _, t := range p.Teams {
+		t.RemoveMember(userId)
+	}
+}
@@ -0,0 +1,46 @@
+package project
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestTeamValidate(t *testing.T) {
+	u1, u2 := bson.NewObjectId(), bson.NewObjectId()
+	team := &Team{Id: bson.NewObjectId(), Name: "AppSec", Members: []bson.ObjectId{u1}}
+	p := &Project{Members: []*Member{{User: u1}, {User: u2}}, Teams: []*Team{team}}
+
+	assert.NoError(t, team.Validate(p))
+	assert.NoError(t, (&Team{Name: "Ops", Members: []bson.ObjectId{u1, u2}}).Validate(p))
+	assert.Error(t, (&Team{Name: "appsec"}).Validate(p))
+	assert.Error(t, (&Team{Name: "Ops", Members: []bson.ObjectId{bson.NewObjectId()}}).Validate(p))
+	assert.Error(t, (&Team{Name: "Ops", Members: []bson.ObjectId{u1, u1}}).Validate(p))
+}
+
+func TestProjectRemoveMember(t *testing.T) {
+	u1, u2 := bson.NewObjectId(), bson.NewObjectId()
+	team := &Team{Id: bson.NewObjectId(), Name: "AppSec", Members: []bson.ObjectId{u1, u2}}
+	p := &Project{Members: []*Member{{User: u1}, {User: u2}}, Teams: []*Team{team}}
+
+	p.RemoveMember(u1)
+	assert.Nil(t, p.GetMember(u1))
+	assert.False(t, team.HasMember(u1))
+	assert.True(t, team.HasMember(u2))
+	assert.False(t, team.RemoveMember(u1))
+}
+
+func TestProjectTeamUsed(t *testing.T) {
+	team := &Team{Id: bson.NewObjectId(), Name: "AppSec"}
+	p := &Project{Teams: []*Team{team}}
+	assert.False(t, p.TeamUsed(team.Id))
+
+	p.Rules = []*Rule{{Name: "wp", Plugin: "barbudo/wpscan", Team: team.Id}}
+	assert.True(t, p.TeamUsed(team.Id))
+
+	p.Rules = nil
+	p.Escalation = &Escalation{Steps: []*EscalationStep{{After: 4, Team: team.Id}}}
+	assert.True(t, p.TeamUsed(team.Id))
+	assert.NoError(t, p.Escalation.Validate(p))
+}
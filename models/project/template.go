@@ -0,0 +1,34 @@
+package project
+
+import (
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/pkg/pagination"
+)
+
+// IssueTemplate is a predefined structure for manually reported issues
+type IssueTemplate struct {
+	Id          bson.ObjectId      `json:"id"`
+	Name        string             `json:"name" description:"template name, 80 symbols max" validate:"nonzero,max=80"`
+	Summary     string             `json:"summary,omitempty"`
+	VulnType    int                `json:"vulnType,omitempty" bson:"vulnType" description:"vulnerability type from vulndb"`
+	Severity    issue.Severity     `json:"severity,omitempty"`
+	Desc        string             `json:"desc,omitempty" description:"markdown skeleton for description"`
+	Remediation string             `json:"remediation,omitempty"`
+	References  []*issue.Reference `json:"references,omitempty"`
+}
+
+type IssueTemplateList struct {
+	pagination.Meta `json:",inline"`
+	Results         []*IssueTemplate `json:"results"`
+}
+
+func (p *Project) GetTemplate(id bson.ObjectId) *IssueTemplate {
+	for _, t := range p.Templates {
+		if t.Id == id {
+			return t
+		}
+	}
+	return nil
+}
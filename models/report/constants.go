@@ -10,6 +10,7 @@ const (
 	TypeMulti  = ReportType("multi")
 	TypeIssues = ReportType("issues")
 	TypeTechs  = ReportType("techs")
+	TypeHosts  = ReportType("hosts")
 )
 
 var reportTypes = []interface{}{
@@ -18,6 +19,7 @@ var reportTypes = []interface{}{
 	TypeMulti,
 	TypeIssues,
 	TypeTechs,
+	TypeHosts,
 }
 
 // It's a hack to show custom type as string in swagger
@@ -0,0 +1,57 @@
+package report
+
+import (
+	"encoding/json"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/pkg/pagination"
+)
+
+type QuarantineStatus string
+
+const (
+	QuarantinePending   = QuarantineStatus("pending")   // waiting for review
+	QuarantineReleased  = QuarantineStatus("released")  // report is fixed and ingested
+	QuarantineDiscarded = QuarantineStatus("discarded") // report is dropped by reviewer
+)
+
+var quarantineStatuses = []interface{}{
+	QuarantinePending,
+	QuarantineReleased,
+	QuarantineDiscarded,
+}
+
+// It's a hack to show custom type as string in swagger
+func (t QuarantineStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+func (t QuarantineStatus) Enum() []interface{} {
+	return quarantineStatuses
+}
+
+func (t QuarantineStatus) Convert(text string) (interface{}, error) {
+	return QuarantineStatus(text), nil
+}
+
+// Quarantine keeps a plugin report which couldn't be ingested
+type Quarantine struct {
+	Id          bson.ObjectId    `json:"id,omitempty" bson:"_id"`
+	Status      QuarantineStatus `json:"status" description:"one of [pending|released|discarded]"`
+	Scan        bson.ObjectId    `json:"scan" description:"scan id"`
+	ScanSession bson.ObjectId    `json:"scanSession" bson:"scanSession" description:"scan session id"`
+	Errors      []string         `json:"errors" description:"validation errors"`
+	Data        string           `json:"data,omitempty" description:"report as it was sent by plugin, it isn't returned in lists"`
+	Created     time.Time        `json:"created,omitempty"`
+	Updated     time.Time        `json:"updated,omitempty"`
+
+	Report   bson.ObjectId `json:"report,omitempty" bson:",omitempty" description:"report id for released report"`
+	Reviewer bson.ObjectId `json:"reviewer,omitempty" bson:",omitempty" description:"who released or discarded the report"`
+}
+
+type QuarantineList struct {
+	pagination.Meta `json:",inline"`
+	Results         []*Quarantine `json:"results"`
+}
@@ -14,21 +14,26 @@ import (
 type Raw struct {
 	Raw   string       `json:"raw"`
 	Files []*file.Meta `json:"files,omitempty" bson:"files,omitempty"`
+	// big raw data is moved out of the report document
+	Archive *file.Meta `json:"archive,omitempty" bson:"archive,omitempty" description:"raw data is stored in file storage, get it from report/raw"`
+	Size    int        `json:"size,omitempty" bson:"size,omitempty" description:"length of raw data"`
 }
 
 type Report struct {
 	Id          bson.ObjectId `json:"id,omitempty" bson:"_id"`
-	Type        ReportType    `json:"type" description:"one of [raw,issues,techs,multi,empty]"`
+	Type        ReportType    `json:"type" description:"one of [raw,issues,techs,hosts,multi,empty]"`
 	Created     time.Time     `json:"created,omitempty" description:"when report is created"`
 	Updated     time.Time     `json:"updated,omitempty" description:"when report is updated"`
 	Scan        bson.ObjectId `json:"scan,omitempty" description:"scan id"`
 	ScanSession bson.ObjectId `json:"scanSession,omitempty" bson:"scanSession" description:"scan session id"`
+	Version     int           `json:"version,omitempty" bson:"version,omitempty" description:"schema version of the report document, reports are migrated on read"`
 
 	Raw `json:",inline,omitempty" bson:"raw,inline"`
 
 	Multi  []*Report      `json:"multi,omitempty" bson:"multi,omitempty"`
 	Issues []*issue.Issue `json:"issues,omitempty" bson:"issues,omitempty"`
 	Techs  []*tech.Tech   `json:"techs,omitempty"`
+	Hosts  []string       `json:"hosts,omitempty" bson:"hosts,omitempty" description:"host names found by enumeration plugins"`
 }
 
 type ReportList struct {
@@ -69,6 +74,28 @@ func (r *Report) GetAllIssues() []*issue.Issue {
 	return issues
 }
 
+// get all raw reports from the report and underlying multi reports
+func (r *Report) GetAllRaws() []*Report {
+	var raws []*Report
+	switch r.Type {
+	case TypeMulti:
+		for _, subReport := range r.Multi {
+			raws = append(raws, subReport.GetAllRaws()...)
+		}
+	case TypeRaw:
+		raws = append(raws, r)
+	}
+	return raws
+}
+
+// Summarize drops raw data from the report and underlying multi reports,
+// so only sizes and archives are left for listings
+func (r *Report) Summarize() {
+	for _, raw := range r.GetAllRaws() {
+		raw.Raw.Raw = ""
+	}
+}
+
 // get all techs from the report and underlying multi reports
 func (r *Report) GetAllTechs() []*tech.Tech {
 	var techs []*tech.Tech
@@ -82,3 +109,17 @@ func (r *Report) GetAllTechs() []*tech.Tech {
 	}
 	return techs
 }
+
+// get all hosts from the report and underlying multi reports
+func (r *Report) GetAllHosts() []string {
+	var hosts []string
+	switch r.Type {
+	case TypeMulti:
+		for _, subReport := range r.Multi {
+			hosts = append(hosts, subReport.GetAllHosts()...)
+		}
+	case TypeHosts:
+		hosts = append(hosts, r.Hosts...)
+	}
+	return hosts
+}
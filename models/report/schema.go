@@ -0,0 +1,65 @@
+package report
+
+// SchemaVersion is the version of report documents written by this code.
+// Increase it and add a migration when the report structure is changed,
+// so reports of historical scans are still readable.
+const SchemaVersion = 1
+
+// migrations[i] upgrades the report from version i to i+1
+var migrations = []func(*Report){
+	migrateV1,
+}
+
+// Migrate upgrades the report and its multi reports to SchemaVersion.
+// Reports of newer versions are left as is. Returns true if the report is changed.
+func Migrate(r *Report) bool {
+	if r == nil || r.Version >= SchemaVersion {
+		return false
+	}
+	for v := r.Version; v < SchemaVersion; v++ {
+		migrations[v](r)
+	}
+	setVersion(r, SchemaVersion)
+	return true
+}
+
+func setVersion(r *Report, v int) {
+	r.Version = v
+	for _, rep := range r.Multi {
+		setVersion(rep, v)
+	}
+}
+
+// reports before versioning could be saved without type, and multi reports without scan links
+func migrateV1(r *Report) {
+	if r.Type == "" {
+		switch {
+		case len(r.Multi) > 0:
+			r.Type = TypeMulti
+		case len(r.Issues) > 0:
+			r.Type = TypeIssues
+		case len(r.Techs) > 0:
+			r.Type = TypeTechs
+		case len(r.Hosts) > 0:
+			r.Type = TypeHosts
+		case r.Raw.Raw != "" || r.Archive != nil:
+			r.Type = TypeRaw
+		default:
+			r.Type = TypeEmpty
+		}
+	}
+	for _, rep := range r.Multi {
+		if rep.Scan == "" {
+			rep.Scan = r.Scan
+		}
+		if rep.ScanSession == "" {
+			rep.ScanSession = r.ScanSession
+		}
+		migrateV1(rep)
+	}
+	for _, obj := range r.Issues {
+		if obj.UniqId == "" {
+			obj.UniqId = obj.GenerateUniqId()
+		}
+	}
+}
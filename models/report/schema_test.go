@@ -0,0 +1,38 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+)
+
+func TestMigrate(t *testing.T) {
+	scanId, sessId := bson.NewObjectId(), bson.NewObjectId()
+	rep := &Report{
+		Scan:        scanId,
+		ScanSession: sessId,
+		Multi: []*Report{
+			{Raw: Raw{Raw: "log"}},
+			{Type: TypeIssues, Issues: []*issue.Issue{{Summary: "xss"}}},
+		},
+	}
+	assert.True(t, Migrate(rep))
+	assert.Equal(t, SchemaVersion, rep.Version)
+	assert.Equal(t, TypeMulti, rep.Type)
+
+	raw := rep.Multi[0]
+	assert.Equal(t, TypeRaw, raw.Type)
+	assert.Equal(t, scanId, raw.Scan)
+	assert.Equal(t, sessId, raw.ScanSession)
+	assert.Equal(t, SchemaVersion, raw.Version)
+	assert.NotEmpty(t, rep.Multi[1].Issues[0].UniqId)
+
+	// current and newer reports aren't touched
+	assert.False(t, Migrate(rep))
+	newer := &Report{Version: SchemaVersion + 1}
+	assert.False(t, Migrate(newer))
+	assert.Equal(t, ReportType(""), newer.Type)
+}
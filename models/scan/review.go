@@ -0,0 +1,24 @@
+package scan
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Review is a sign-off of scan results by a project member
+type Review struct {
+	User    bson.ObjectId `json:"user"`
+	Notes   string        `json:"notes,omitempty" bson:",omitempty"`
+	Created time.Time     `json:"created"`
+}
+
+// IsReviewed returns true if somebody signed off the scan
+func (p *Scan) IsReviewed() bool {
+	return p.Review != nil
+}
+
+// CanReview returns true if the scan is over and its results could be reviewed
+func (p *Scan) CanReview() bool {
+	return p.Status == StatusFinished || p.Status == StatusFailed
+}
@@ -0,0 +1,20 @@
+package scan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReview(t *testing.T) {
+	sc := &Scan{Status: StatusWorking}
+	assert.False(t, sc.CanReview())
+	assert.False(t, sc.IsReviewed())
+
+	for _, status := range []ScanStatus{StatusFinished, StatusFailed} {
+		sc.Status = status
+		assert.True(t, sc.CanReview(), string(status))
+	}
+	sc.Review = &Review{Notes: "checked"}
+	assert.True(t, sc.IsReviewed())
+}
@@ -6,7 +6,9 @@ import (
 
 	"gopkg.in/mgo.v2/bson"
 
+	"github.com/bearded-web/bearded/models/file"
 	"github.com/bearded-web/bearded/models/plan"
+	"github.com/bearded-web/bearded/models/target"
 	"github.com/bearded-web/bearded/pkg/pagination"
 )
 
@@ -25,6 +27,12 @@ type Session struct {
 	Step   *plan.WorkflowStep `json:"step"`
 	Plugin bson.ObjectId      `json:"plugin,omitempty" description:"plugin id"`
 	Scan   bson.ObjectId      `json:"scan" description:"scan id"`
+	Agent  bson.ObjectId      `json:"agent,omitempty" description:"agent which the session is queued to" bson:"agent,omitempty"`
+	// secret for plugin callbacks, it's given to the agent with the job
+	Token    string    `json:"-" bson:"token,omitempty"`
+	Progress *Progress `json:"progress,omitempty" bson:"progress,omitempty" description:"reported by plugin while it's working"`
+	// artifacts are kept apart from the report, they are downloaded by /artifacts/{id}
+	Artifacts []*file.Meta `json:"artifacts,omitempty" bson:"artifacts,omitempty" description:"files uploaded by plugin, like crawled sitemap or pcap"`
 	// dates
 	Dates `json:",inline"`
 
@@ -33,6 +41,13 @@ type Session struct {
 	Parent   bson.ObjectId `json:"parent,omitempty" description:"parent session for this one" bson:"parent,omitempty"`
 }
 
+// Progress of the working session, plugins report it through the agent
+type Progress struct {
+	Percent int        `json:"percent" description:"from 0 to 100"`
+	Message string     `json:"message,omitempty" description:"what plugin is doing now"`
+	Updated *time.Time `json:"updated,omitempty"`
+}
+
 func (p *Session) GetChild(id bson.ObjectId) *Session {
 	for _, sess := range p.Children {
 		if sess.Id == id {
@@ -58,13 +73,28 @@ func (p *Session) GetAllChildren() []*Session {
 	return result
 }
 
+// GetArtifact returns the artifact meta by file id or nil
+func (p *Session) GetArtifact(id string) *file.Meta {
+	for _, meta := range p.Artifacts {
+		if meta.Id == id {
+			return meta
+		}
+	}
+	return nil
+}
+
 func (p *Session) HasParent() bool {
 	return p.Parent != ""
 }
 
 type ScanConf struct {
-	Target string                 `json:"target"`
-	Params map[string]interface{} `json:"params"`
+	Target    string                 `json:"target"`
+	Params    map[string]interface{} `json:"params"`
+	RateLimit *target.RateLimit      `json:"rateLimit,omitempty" bson:"rateLimit,omitempty" description:"taken from target and project on scan creation"`
+	Endpoints []*target.Endpoint     `json:"endpoints,omitempty" bson:"endpoints,omitempty" description:"api operations of api target"`
+	Repo      *target.RepoTarget     `json:"repo,omitempty" bson:"repo,omitempty" description:"repository of repo target"`
+	Proxy     *target.Proxy          `json:"proxy,omitempty" bson:"proxy,omitempty" description:"taken from target or project on scan creation"`
+	Record    bool                   `json:"record,omitempty" bson:"record,omitempty" description:"taken from target on scan creation"`
 }
 
 type Scan struct {
@@ -79,6 +109,17 @@ type Scan struct {
 	Owner   bson.ObjectId `json:"owner,omitempty"`
 	Target  bson.ObjectId `json:"target"`
 	Project bson.ObjectId `json:"project"`
+	Retest  bson.ObjectId `json:"retest,omitempty" bson:",omitempty" description:"issue id, if the scan is created to retest it"`
+
+	Discovery bson.ObjectId `json:"discovery,omitempty" bson:",omitempty" description:"discovery id, if the scan is created by discovery"`
+
+	Scheduled *time.Time `json:"scheduled,omitempty" bson:",omitempty" description:"the scan isn't started before this time"`
+
+	Waiting *Waiting `json:"waiting,omitempty" bson:",omitempty" description:"why the created scan isn't started yet"`
+
+	Usage *Usage `json:"usage,omitempty" bson:",omitempty" description:"consumed resources, calculated by server"`
+
+	Review *Review `json:"review,omitempty" bson:",omitempty" description:"sign-off by a reviewer, required for projects with requireReview"`
 
 	// dates
 	Dates `json:",inline"`
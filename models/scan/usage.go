@@ -0,0 +1,79 @@
+package scan
+
+import (
+	"sort"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Usage is resources consumed by the scan, it's recalculated on every scan update
+type Usage struct {
+	AgentSeconds int            `json:"agentSeconds" bson:"agentSeconds" description:"time of root sessions on agents"`
+	Plugins      []*PluginUsage `json:"plugins,omitempty" bson:",omitempty"`
+}
+
+type PluginUsage struct {
+	Plugin   string `json:"plugin" description:"plugin name with version"`
+	Seconds  int    `json:"seconds" description:"runtime of plugin sessions"`
+	Sessions int    `json:"sessions"`
+}
+
+// CalcUsage measures session runtimes, working sessions are measured till now.
+// Child sessions run inside of their parents, so they are counted only for plugins.
+func (p *Scan) CalcUsage(now time.Time) *Usage {
+	u := &Usage{}
+	for _, sess := range p.Sessions {
+		u.AgentSeconds += sess.runtime(now)
+	}
+	plugins := map[string]*PluginUsage{}
+	for _, sess := range p.GetAllSessions() {
+		if sess.Started == nil || sess.Step == nil {
+			continue
+		}
+		pu, ok := plugins[sess.Step.Plugin]
+		if !ok {
+			pu = &PluginUsage{Plugin: sess.Step.Plugin}
+			plugins[sess.Step.Plugin] = pu
+			u.Plugins = append(u.Plugins, pu)
+		}
+		pu.Seconds += sess.runtime(now)
+		pu.Sessions++
+	}
+	sort.Sort(pluginUsages(u.Plugins))
+	return u
+}
+
+// runtime returns seconds between start and finish of the session
+func (p *Session) runtime(now time.Time) int {
+	if p.Started == nil {
+		return 0
+	}
+	end := now
+	if p.Finished != nil {
+		end = *p.Finished
+	} else if p.Status != StatusWorking && p.Status != StatusQueued {
+		// paused or broken sessions aren't running
+		return 0
+	}
+	if end.Before(*p.Started) {
+		return 0
+	}
+	return int(end.Sub(*p.Started) / time.Second)
+}
+
+type pluginUsages []*PluginUsage
+
+func (p pluginUsages) Len() int           { return len(p) }
+func (p pluginUsages) Swap(i, j int)      { p[i], p[j] = p[j], p[i] }
+func (p pluginUsages) Less(i, j int) bool { return p[i].Plugin < p[j].Plugin }
+
+// UsageReport is resources consumed by project scans created in the period
+type UsageReport struct {
+	Project      bson.ObjectId  `json:"project"`
+	From         time.Time      `json:"from,omitempty"`
+	To           time.Time      `json:"to,omitempty"`
+	Scans        int            `json:"scans"`
+	AgentSeconds int            `json:"agentSeconds"`
+	Plugins      []*PluginUsage `json:"plugins"`
+}
@@ -0,0 +1,41 @@
+package scan
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bearded-web/bearded/models/plan"
+)
+
+func TestCalcUsage(t *testing.T) {
+	start := time.Date(2015, 6, 1, 10, 0, 0, 0, time.UTC)
+	at := func(min int) *time.Time {
+		t := start.Add(time.Duration(min) * time.Minute)
+		return &t
+	}
+	sc := &Scan{Sessions: []*Session{
+		{
+			Status: StatusFinished,
+			Step:   &plan.WorkflowStep{Plugin: "barbudo/script:0.1"},
+			Dates:  Dates{Started: at(0), Finished: at(10)},
+			Children: []*Session{
+				{Status: StatusFinished, Step: &plan.WorkflowStep{Plugin: "barbudo/wpscan:0.2"}, Dates: Dates{Started: at(1), Finished: at(5)}},
+			},
+		},
+		{Status: StatusWorking, Step: &plan.WorkflowStep{Plugin: "barbudo/wpscan:0.2"}, Dates: Dates{Started: at(10)}},
+		{Status: StatusCreated, Step: &plan.WorkflowStep{Plugin: "barbudo/nikto:0.1"}},
+	}}
+
+	u := sc.CalcUsage(*at(12))
+	assert.Equal(t, 12*60, u.AgentSeconds)
+	require.Len(t, u.Plugins, 2)
+	assert.Equal(t, &PluginUsage{Plugin: "barbudo/script:0.1", Seconds: 600, Sessions: 1}, u.Plugins[0])
+	assert.Equal(t, &PluginUsage{Plugin: "barbudo/wpscan:0.2", Seconds: 360, Sessions: 2}, u.Plugins[1])
+
+	// paused sessions aren't counted after the pause
+	sc.Sessions[1].Status = StatusPaused
+	assert.Equal(t, 10*60, sc.CalcUsage(*at(20)).AgentSeconds)
+}
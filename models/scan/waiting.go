@@ -0,0 +1,61 @@
+package scan
+
+import (
+	"encoding/json"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+type WaitReason string
+
+const (
+	WaitScheduled  = WaitReason("scheduled")  // the scheduled time isn't come
+	WaitBlackout   = WaitReason("blackout")   // project blackout window
+	WaitQuota      = WaitReason("quota")      // project quota of running scans or agent minutes is exceeded
+	WaitTarget     = WaitReason("target")     // another scan is running against the target
+	WaitEngagement = WaitReason("engagement") // the scan breaks project rules of engagement
+)
+
+var waitReasons = []interface{}{
+	WaitScheduled,
+	WaitBlackout,
+	WaitQuota,
+	WaitTarget,
+	WaitEngagement,
+}
+
+// It's a hack to show custom type as string in swagger
+func (t WaitReason) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+func (t WaitReason) Enum() []interface{} {
+	return waitReasons
+}
+
+func (t WaitReason) Convert(text string) (interface{}, error) {
+	return WaitReason(text), nil
+}
+
+// Waiting explains why the created scan isn't started yet
+type Waiting struct {
+	Reason  WaitReason    `json:"reason" description:"one of [scheduled|blackout|quota|target|engagement]"`
+	Scan    bson.ObjectId `json:"scan,omitempty" bson:",omitempty" description:"scan running against the same target"`
+	Message string        `json:"message,omitempty" bson:",omitempty" description:"broken rules of engagement"`
+}
+
+// IsActive reports whether the scan is taken by agents and isn't finished, paused scans are active too
+func (p *Scan) IsActive() bool {
+	if p.Status == StatusQueued || p.Status == StatusWorking || p.Status == StatusPaused {
+		return true
+	}
+	if p.Status != StatusCreated {
+		return false
+	}
+	for _, sess := range p.Sessions {
+		if sess.Status != StatusCreated {
+			return true
+		}
+	}
+	return false
+}
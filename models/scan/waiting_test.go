@@ -0,0 +1,26 @@
+package scan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanIsActive(t *testing.T) {
+	sc := &Scan{Status: StatusCreated, Sessions: []*Session{{Status: StatusCreated}}}
+	assert.False(t, sc.IsActive())
+
+	sc.Sessions[0].Status = StatusQueued
+	assert.True(t, sc.IsActive())
+
+	for st, active := range map[ScanStatus]bool{
+		StatusQueued:   true,
+		StatusWorking:  true,
+		StatusPaused:   true,
+		StatusFinished: false,
+		StatusFailed:   false,
+	} {
+		sc := &Scan{Status: st}
+		assert.Equal(t, active, sc.IsActive(), string(st))
+	}
+}
@@ -0,0 +1,93 @@
+package stats
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+)
+
+// StatusNames are names of issue statuses by their rank
+var StatusNames = []string{"open", "confirmed", "muted", "false", "resolved"}
+
+// Snapshot is counts of project issues at the end of the UTC day.
+// Snapshots aren't removed with issues and projects, so trends of the past stay the same.
+type Snapshot struct {
+	Id       bson.ObjectId          `json:"-" bson:"_id"`
+	Project  bson.ObjectId          `json:"project,omitempty" bson:"project" description:"empty for the sum of all projects"`
+	Date     string                 `json:"date" description:"UTC date in 2006-01-02 format"`
+	Total    int                    `json:"total" description:"all issues including resolved ones"`
+	Active   int                    `json:"active" description:"open and confirmed issues"`
+	Status   map[string]int         `json:"status" description:"issues by status [open|confirmed|muted|false|resolved]"`
+	Severity map[issue.Severity]int `json:"severity" description:"active issues by severity"`
+	Created  time.Time              `json:"created,omitempty" description:"when the snapshot was taken"`
+}
+
+// Trend is daily snapshots of the period, days without snapshots are skipped
+type Trend struct {
+	Project bson.ObjectId `json:"project,omitempty" description:"empty for the sum of all projects"`
+	From    string        `json:"from" description:"UTC date in 2006-01-02 format"`
+	To      string        `json:"to" description:"UTC date in 2006-01-02 format"`
+	Results []*Snapshot   `json:"results"`
+}
+
+// NewTrend returns the trend for the last days which are finished at now
+func NewTrend(project bson.ObjectId, now time.Time, days int) *Trend {
+	to := now.UTC().AddDate(0, 0, -1)
+	return &Trend{
+		Project: project,
+		From:    to.AddDate(0, 0, 1-days).Format(DateLayout),
+		To:      to.Format(DateLayout),
+		Results: []*Snapshot{},
+	}
+}
+
+func NewSnapshot(project bson.ObjectId, date string) *Snapshot {
+	return &Snapshot{
+		Project:  project,
+		Date:     date,
+		Status:   map[string]int{},
+		Severity: map[issue.Severity]int{},
+	}
+}
+
+// Add counts issues with the status rank and severity
+func (s *Snapshot) Add(rank int, sev issue.Severity, count int) {
+	if rank < 0 || rank >= len(StatusNames) {
+		return
+	}
+	s.Total += count
+	s.Status[StatusNames[rank]] += count
+	// open and confirmed issues have the lowest ranks
+	if rank <= (issue.Status{Confirmed: true}).Rank() {
+		s.Active += count
+		s.Severity[sev] += count
+	}
+}
+
+// Merge adds counts of the other snapshot
+func (s *Snapshot) Merge(other *Snapshot) {
+	s.Total += other.Total
+	s.Active += other.Active
+	for status, count := range other.Status {
+		s.Status[status] += count
+	}
+	for sev, count := range other.Severity {
+		s.Severity[sev] += count
+	}
+}
+
+// Sum returns snapshots of all projects summed by date, snapshots should be sorted by date
+func Sum(snapshots []*Snapshot) []*Snapshot {
+	results := []*Snapshot{}
+	var last *Snapshot
+	for _, s := range snapshots {
+		if last == nil || last.Date != s.Date {
+			last = NewSnapshot("", s.Date)
+			results = append(results, last)
+		}
+		last.Merge(s)
+	}
+	return results
+}
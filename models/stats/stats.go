@@ -0,0 +1,100 @@
+package stats
+
+import (
+	"time"
+
+	"github.com/bearded-web/bearded/models/issue"
+)
+
+// DateLayout is used for days of stats, days are in UTC
+const DateLayout = "2006-01-02"
+
+// Stats are instance-wide counters for capacity planning and health dashboards
+type Stats struct {
+	Users    int    `json:"users"`
+	Projects int    `json:"projects"`
+	Targets  int    `json:"targets"`
+	Issues   Issues `json:"issues"`
+	Scans    Scans  `json:"scans"`
+	Agents   Agents `json:"agents"`
+
+	Generated time.Time `json:"generated" description:"stats are cached, it's when they were calculated"`
+}
+
+type Issues struct {
+	Total    int                    `json:"total"`
+	Active   int                    `json:"active" description:"open and confirmed issues"`
+	Severity map[issue.Severity]int `json:"severity" description:"active issues by severity"`
+}
+
+type Scans struct {
+	Total   int     `json:"total"`
+	Queued  int     `json:"queued"`
+	Working int     `json:"working"`
+	PerDay  float64 `json:"perDay" description:"average count of scans created per day"`
+	Days    []*Day  `json:"days" description:"scans created per day, the last one is today"`
+}
+
+type Day struct {
+	Date  string `json:"date" description:"UTC date in 2006-01-02 format"`
+	Count int    `json:"count"`
+}
+
+type Agents struct {
+	Total        int     `json:"total"`
+	Approved     int     `json:"approved"`
+	AgentSeconds int     `json:"agentSeconds" description:"agent time of scans created in the last 24 hours"`
+	Utilization  float64 `json:"utilization" description:"agent seconds to the day of all approved agents"`
+}
+
+// NewDays returns n days till the day of now inclusive, counts are taken by date
+func NewDays(now time.Time, n int, counts map[string]int) []*Day {
+	days := make([]*Day, 0, n)
+	now = now.UTC()
+	for i := n - 1; i >= 0; i-- {
+		date := now.AddDate(0, 0, -i).Format(DateLayout)
+		days = append(days, &Day{Date: date, Count: counts[date]})
+	}
+	return days
+}
+
+// SetDays sets scans created per day and their average
+func (s *Scans) SetDays(days []*Day) {
+	s.Days = days
+	s.PerDay = 0
+	if len(days) == 0 {
+		return
+	}
+	total := 0
+	for _, d := range days {
+		total += d.Count
+	}
+	s.PerDay = float64(total) / float64(len(days))
+}
+
+// SetUtilization calculates utilization of approved agents from agent time of the last day
+func (a *Agents) SetUtilization(seconds int) {
+	a.AgentSeconds = seconds
+	a.Utilization = 0
+	if a.Approved > 0 {
+		a.Utilization = float64(seconds) / float64(a.Approved*24*60*60)
+	}
+}
+
+// Pool is a state of mongo connections, driver counters are collected since the dispatcher start
+type Pool struct {
+	Limit      int `json:"limit" description:"max sockets in use per server, 0 is the driver default"`
+	OpenCopies int `json:"openCopies" description:"copied sessions which aren't closed, one per active request"`
+
+	Clusters     int `json:"clusters"`
+	MasterConns  int `json:"masterConns"`
+	SlaveConns   int `json:"slaveConns"`
+	SentOps      int `json:"sentOps"`
+	ReceivedOps  int `json:"receivedOps"`
+	ReceivedDocs int `json:"receivedDocs"`
+	SocketsAlive int `json:"socketsAlive"`
+	SocketsInUse int `json:"socketsInUse"`
+	SocketRefs   int `json:"socketRefs"`
+
+	Generated time.Time `json:"generated"`
+}
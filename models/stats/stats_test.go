@@ -0,0 +1,75 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+)
+
+func TestNewDays(t *testing.T) {
+	now := time.Date(2015, 7, 2, 10, 0, 0, 0, time.UTC)
+	days := NewDays(now, 3, map[string]int{"2015-07-01": 4, "2015-07-02": 2, "2015-06-01": 10})
+	require.Len(t, days, 3)
+	assert.Equal(t, &Day{Date: "2015-06-30"}, days[0])
+	assert.Equal(t, &Day{Date: "2015-07-01", Count: 4}, days[1])
+	assert.Equal(t, &Day{Date: "2015-07-02", Count: 2}, days[2])
+
+	s := &Scans{}
+	s.SetDays(days)
+	assert.Equal(t, 2.0, s.PerDay)
+	s.SetDays(nil)
+	assert.Equal(t, 0.0, s.PerDay)
+}
+
+func TestSetUtilization(t *testing.T) {
+	a := &Agents{}
+	a.SetUtilization(3600)
+	assert.Equal(t, 0.0, a.Utilization)
+
+	a.Approved = 2
+	a.SetUtilization(24 * 60 * 60)
+	assert.Equal(t, 3600*24, a.AgentSeconds)
+	assert.Equal(t, 0.5, a.Utilization)
+}
+
+func TestSnapshot(t *testing.T) {
+	p := bson.NewObjectId()
+	s := NewSnapshot(p, "2015-07-01")
+	s.Add(issue.Status{}.Rank(), issue.SeverityHigh, 2)
+	s.Add(issue.Status{Confirmed: true}.Rank(), issue.SeverityHigh, 1)
+	s.Add(issue.Status{Confirmed: true}.Rank(), issue.SeverityLow, 3)
+	s.Add(issue.Status{Resolved: true}.Rank(), issue.SeverityHigh, 4)
+	s.Add(10, issue.SeverityHigh, 4)
+	assert.Equal(t, 10, s.Total)
+	assert.Equal(t, 6, s.Active)
+	assert.Equal(t, map[string]int{"open": 2, "confirmed": 4, "resolved": 4}, s.Status)
+	assert.Equal(t, map[issue.Severity]int{issue.SeverityHigh: 3, issue.SeverityLow: 3}, s.Severity)
+
+	other := NewSnapshot(bson.NewObjectId(), "2015-07-01")
+	other.Add(issue.Status{Muted: true}.Rank(), issue.SeverityMedium, 1)
+	next := NewSnapshot(p, "2015-07-02")
+	next.Add(issue.Status{}.Rank(), issue.SeverityMedium, 5)
+
+	sum := Sum([]*Snapshot{s, other, next})
+	require.Len(t, sum, 2)
+	assert.Equal(t, bson.ObjectId(""), sum[0].Project)
+	assert.Equal(t, "2015-07-01", sum[0].Date)
+	assert.Equal(t, 11, sum[0].Total)
+	assert.Equal(t, 6, sum[0].Active)
+	assert.Equal(t, 1, sum[0].Status["muted"])
+	assert.Equal(t, 5, sum[1].Severity[issue.SeverityMedium])
+	// snapshots of projects aren't changed
+	assert.Equal(t, 10, s.Total)
+}
+
+func TestNewTrend(t *testing.T) {
+	trend := NewTrend("", time.Date(2015, 7, 2, 10, 0, 0, 0, time.UTC), 3)
+	assert.Equal(t, "2015-06-29", trend.From)
+	assert.Equal(t, "2015-07-01", trend.To)
+	assert.Empty(t, trend.Results)
+}
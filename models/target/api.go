@@ -0,0 +1,99 @@
+package target
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bearded-web/bearded/models/file"
+)
+
+type ApiTarget struct {
+	Name    string     `json:"name" description:"target name, 80 symbols max"`
+	SpecUrl string     `json:"specUrl,omitempty" bson:"specUrl,omitempty" description:"where the spec is downloaded from"`
+	File    *file.Meta `json:"file,omitempty" bson:",omitempty" description:"uploaded spec file metadata"`
+
+	// parsed from the spec
+	Title     string      `json:"title,omitempty" bson:",omitempty"`
+	Version   string      `json:"version,omitempty" bson:",omitempty" description:"api version from the spec"`
+	BaseUrl   string      `json:"baseUrl" bson:"baseUrl" description:"api server url, scans are run against it"`
+	Endpoints []*Endpoint `json:"endpoints" description:"api operations, they are passed to plugins as scope"`
+	Parsed    time.Time   `json:"parsed" description:"when the spec was parsed last time"`
+}
+
+// Endpoint is a single api operation
+type Endpoint struct {
+	Method      string `json:"method" description:"upper case http method"`
+	Path        string `json:"path" description:"path template relative to base url, like /pets/{id}"`
+	OperationId string `json:"operationId,omitempty" bson:"operationId,omitempty"`
+	Summary     string `json:"summary,omitempty" bson:",omitempty"`
+}
+
+// Operation returns method and path, like "GET /pets/{id}"
+func (e *Endpoint) Operation() string {
+	return fmt.Sprintf("%s %s", e.Method, e.Path)
+}
+
+// Match returns endpoint for the request url, method is optional.
+// Static path segments win over template parameters.
+func (a *ApiTarget) Match(method, rawUrl string) *Endpoint {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil
+	}
+	p := u.Path
+	if base, err := url.Parse(a.BaseUrl); err == nil {
+		basePath := strings.TrimSuffix(base.Path, "/")
+		if basePath != "" {
+			if p != basePath && !strings.HasPrefix(p, basePath+"/") {
+				return nil
+			}
+			p = p[len(basePath):]
+		}
+	}
+	segments := splitPath(p)
+	method = strings.ToUpper(method)
+
+	var found *Endpoint
+	bestScore := -1
+	for _, e := range a.Endpoints {
+		if method != "" && e.Method != method {
+			continue
+		}
+		score, ok := matchSegments(splitPath(e.Path), segments)
+		if ok && score > bestScore {
+			found, bestScore = e, score
+		}
+	}
+	return found
+}
+
+func splitPath(p string) []string {
+	p = strings.Trim(p, "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+// returns the number of static segments matched
+func matchSegments(tmpl, segments []string) (int, bool) {
+	if len(tmpl) != len(segments) {
+		return 0, false
+	}
+	score := 0
+	for i, t := range tmpl {
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+			if segments[i] == "" {
+				return 0, false
+			}
+			continue
+		}
+		if t != segments[i] {
+			return 0, false
+		}
+		score++
+	}
+	return score, true
+}
@@ -0,0 +1,40 @@
+package target
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApiMatch(t *testing.T) {
+	a := &ApiTarget{
+		BaseUrl: "https://api.example.com/v1",
+		Endpoints: []*Endpoint{
+			{Method: "GET", Path: "/pets"},
+			{Method: "GET", Path: "/pets/{id}"},
+			{Method: "DELETE", Path: "/pets/{id}"},
+			{Method: "GET", Path: "/pets/mine"},
+		},
+	}
+	e := a.Match("get", "https://api.example.com/v1/pets/12?full=1")
+	require.NotNil(t, e)
+	assert.Equal(t, "GET /pets/{id}", e.Operation())
+
+	e = a.Match("DELETE", "https://api.example.com/v1/pets/12")
+	require.NotNil(t, e)
+	assert.Equal(t, "DELETE /pets/{id}", e.Operation())
+
+	// static segments are preferred
+	e = a.Match("GET", "https://api.example.com/v1/pets/mine")
+	require.NotNil(t, e)
+	assert.Equal(t, "/pets/mine", e.Path)
+
+	e = a.Match("", "https://api.example.com/v1/pets")
+	require.NotNil(t, e)
+	assert.Equal(t, "/pets", e.Path)
+
+	assert.Nil(t, a.Match("POST", "https://api.example.com/v1/pets"))
+	assert.Nil(t, a.Match("GET", "https://api.example.com/v2/pets"))
+	assert.Nil(t, a.Match("GET", "https://api.example.com/v1/pets/1/photos"))
+}
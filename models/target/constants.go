@@ -7,9 +7,11 @@ type TargetType string
 const (
 	TypeWeb     TargetType = "web"
 	TypeAndroid TargetType = "android"
+	TypeApi     TargetType = "api"
+	TypeRepo    TargetType = "repo"
 )
 
-var targetTypes = []interface{}{TypeWeb, TypeAndroid}
+var targetTypes = []interface{}{TypeWeb, TypeAndroid, TypeApi, TypeRepo}
 
 // It's a hack to show custom type as string in swagger
 func (t TargetType) MarshalJSON() ([]byte, error) {
@@ -23,3 +25,88 @@ func (t TargetType) Enum() []interface{} {
 func (t TargetType) Convert(text string) (interface{}, error) {
 	return TargetType(text), nil
 }
+
+func (t TargetType) IsValid() bool {
+	for _, v := range targetTypes {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Criticality shows how important the target is for business, it's used in risk scoring
+type Criticality string
+
+const (
+	CriticalityLow      Criticality = "low"
+	CriticalityMedium   Criticality = "medium"
+	CriticalityHigh     Criticality = "high"
+	CriticalityCritical Criticality = "critical"
+)
+
+var criticalities = []interface{}{
+	CriticalityLow,
+	CriticalityMedium,
+	CriticalityHigh,
+	CriticalityCritical,
+}
+
+func (c Criticality) IsValid() bool {
+	for _, v := range criticalities {
+		if v == c {
+			return true
+		}
+	}
+	return false
+}
+
+// It's a hack to show custom type as string in swagger
+func (c Criticality) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(c))
+}
+
+func (c Criticality) Enum() []interface{} {
+	return criticalities
+}
+
+func (c Criticality) Convert(text string) (interface{}, error) {
+	return Criticality(text), nil
+}
+
+// Environment is where the target is deployed, it's used in escalation and deploy gates
+type Environment string
+
+const (
+	EnvironmentProd    Environment = "prod"
+	EnvironmentStaging Environment = "staging"
+	EnvironmentDev     Environment = "dev"
+)
+
+var environments = []interface{}{
+	EnvironmentProd,
+	EnvironmentStaging,
+	EnvironmentDev,
+}
+
+func (e Environment) IsValid() bool {
+	for _, v := range environments {
+		if v == e {
+			return true
+		}
+	}
+	return false
+}
+
+// It's a hack to show custom type as string in swagger
+func (e Environment) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(e))
+}
+
+func (e Environment) Enum() []interface{} {
+	return environments
+}
+
+func (e Environment) Convert(text string) (interface{}, error) {
+	return Environment(text), nil
+}
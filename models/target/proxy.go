@@ -0,0 +1,96 @@
+package target
+
+import (
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+// Proxy is an outbound proxy which plugins route traffic through,
+// e.g. a jump proxy for assets which aren't reachable from agents directly.
+// The proxy is passed to plugins by environment variables, which are advisory:
+// http tools respect them, but tools like nmap connect directly, so traffic isn't forced through the proxy.
+type Proxy struct {
+	Url         string `json:"url" description:"http, https or socks5 proxy url without credentials, e.g. socks5://jump.example.com:1080. It's passed to plugins by proxy environment variables, tools which ignore them, like nmap, connect directly"`
+	Credentials string `json:"credentials,omitempty" bson:",omitempty" description:"name of credentials configured on agents as user:password, they are given only for the proxy hosts in BEARDED_CREDENTIALS_<NAME>_HOSTS, the secret itself isn't stored"`
+}
+
+func (p *Proxy) Validate() error {
+	errs := validate.Errors{}
+	u, err := url.Parse(p.Url)
+	switch {
+	case err != nil:
+		errs.Add("url", validate.CodeInvalid, err.Error())
+	case u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5" && u.Scheme != "socks5h":
+		errs.Add("url", validate.CodeInvalid, "scheme must be one of [http|https|socks5|socks5h]")
+	case u.Host == "":
+		errs.Add("url", validate.CodeInvalid, "host is empty")
+	case u.User != nil:
+		errs.Add("url", validate.CodeInvalid, "credentials shouldn't be in the url, use credentials configured on agents")
+	}
+	if p.Credentials != "" && !credentialsRef.MatchString(p.Credentials) {
+		errs.Add("credentials", validate.CodeInvalid, "should contain only latin letters, digits and underscore")
+	}
+	return errs.Err()
+}
+
+// IsSocks reports whether the proxy is socks5, the recording proxy of agents can't chain to it
+func (p *Proxy) IsSocks() bool {
+	u, err := url.Parse(p.Url)
+	return err == nil && (u.Scheme == "socks5" || u.Scheme == "socks5h")
+}
+
+// ValidateRecord returns an error if traffic is recorded through a socks proxy,
+// proxy is the target proxy or the project proxy if the target doesn't have one
+func ValidateRecord(record bool, proxy *Proxy) error {
+	if record && proxy != nil && proxy.IsSocks() {
+		return validate.NewError("record", validate.CodeUnsupported, "traffic can't be recorded through socks5 proxies")
+	}
+	return nil
+}
+
+// Host returns the host of the proxy url without port, empty string if the url is wrong
+func (p *Proxy) Host() string {
+	u, err := url.Parse(p.Url)
+	if err != nil {
+		return ""
+	}
+	host := u.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// Addr returns the proxy url with credentials, the secret is resolved by agent, it's user:password
+func (p *Proxy) Addr(secret string) string {
+	if secret == "" {
+		return p.Url
+	}
+	u, err := url.Parse(p.Url)
+	if err != nil {
+		return p.Url
+	}
+	parts := strings.SplitN(secret, ":", 2)
+	if len(parts) == 2 {
+		u.User = url.UserPassword(parts[0], parts[1])
+	} else {
+		u.User = url.User(parts[0])
+	}
+	return u.String()
+}
+
+// Env returns environment variables for plugin containers, most http tools respect them
+func (p *Proxy) Env(secret string) []string {
+	if p == nil {
+		return nil
+	}
+	addr := p.Addr(secret)
+	env := []string{"BEARDED_PROXY=" + addr}
+	for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "ALL_PROXY"} {
+		env = append(env, name+"="+addr, strings.ToLower(name)+"="+addr)
+	}
+	return env
+}
@@ -0,0 +1,60 @@
+package target
+
+import (
+	"strconv"
+
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+// RateLimit is a politeness control for scanning fragile targets, zero value means no limit
+type RateLimit struct {
+	RequestsPerSecond int `json:"requestsPerSecond,omitempty" bson:"requestsPerSecond,omitempty" description:"max requests per second sent by plugins"`
+	Connections       int `json:"connections,omitempty" bson:"connections,omitempty" description:"max parallel connections, the agent doesn't run more plugins against the target"`
+}
+
+func (r *RateLimit) Validate() error {
+	errs := validate.Errors{}
+	if r.RequestsPerSecond < 0 {
+		errs.Add("requestsPerSecond", validate.CodeMin, "shouldn't be negative")
+	}
+	if r.Connections < 0 {
+		errs.Add("connections", validate.CodeMin, "shouldn't be negative")
+	}
+	return errs.Err()
+}
+
+// WithDefaults returns a copy of the limit where unset fields are taken from def.
+// Returns nil if both limits are empty.
+func (r *RateLimit) WithDefaults(def *RateLimit) *RateLimit {
+	res := &RateLimit{}
+	if r != nil {
+		*res = *r
+	}
+	if def != nil {
+		if res.RequestsPerSecond == 0 {
+			res.RequestsPerSecond = def.RequestsPerSecond
+		}
+		if res.Connections == 0 {
+			res.Connections = def.Connections
+		}
+	}
+	if res.RequestsPerSecond == 0 && res.Connections == 0 {
+		return nil
+	}
+	return res
+}
+
+// Env returns environment variables for plugin containers
+func (r *RateLimit) Env() []string {
+	if r == nil {
+		return nil
+	}
+	env := []string{}
+	if r.RequestsPerSecond > 0 {
+		env = append(env, "BEARDED_RATE_LIMIT_RPS="+strconv.Itoa(r.RequestsPerSecond))
+	}
+	if r.Connections > 0 {
+		env = append(env, "BEARDED_RATE_LIMIT_CONNECTIONS="+strconv.Itoa(r.Connections))
+	}
+	return env
+}
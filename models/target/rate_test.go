@@ -0,0 +1,27 @@
+package target
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	var empty *RateLimit
+	assert.Nil(t, empty.WithDefaults(nil))
+	assert.Nil(t, empty.WithDefaults(&RateLimit{}))
+	assert.Nil(t, empty.Env())
+
+	def := &RateLimit{RequestsPerSecond: 10, Connections: 4}
+	assert.Equal(t, def, empty.WithDefaults(def))
+
+	r := &RateLimit{Connections: 1}
+	merged := r.WithDefaults(def)
+	assert.Equal(t, &RateLimit{RequestsPerSecond: 10, Connections: 1}, merged)
+	assert.Equal(t, &RateLimit{Connections: 1}, r, "original limit shouldn't be changed")
+	assert.Equal(t, []string{"BEARDED_RATE_LIMIT_RPS=10", "BEARDED_RATE_LIMIT_CONNECTIONS=1"}, merged.Env())
+
+	assert.NoError(t, merged.Validate())
+	assert.Error(t, (&RateLimit{RequestsPerSecond: -1}).Validate())
+	assert.Error(t, (&RateLimit{Connections: -1}).Validate())
+}
@@ -0,0 +1,132 @@
+package target
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+// scp like git address, ex: git@github.com:org/repo.git
+var scpAddr = regexp.MustCompile(`^[\w.-]+@([\w.-]+):(.+)$`)
+
+var credentialsRef = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+type RepoTarget struct {
+	Name        string `json:"name" description:"target name, 80 symbols max"`
+	Url         string `json:"url" description:"clone url, https, ssh or git@host:path"`
+	Branch      string `json:"branch,omitempty" bson:",omitempty" description:"default branch is used if empty"`
+	Credentials string `json:"credentials,omitempty" bson:",omitempty" description:"name of credentials configured on agents, they are given only for the repository hosts in BEARDED_CREDENTIALS_<NAME>_HOSTS, the secret itself isn't stored"`
+}
+
+func (r *RepoTarget) Validate() error {
+	errs := validate.Errors{}
+	if !scpAddr.MatchString(r.Url) {
+		u, err := url.Parse(r.Url)
+		switch {
+		case err != nil:
+			errs.Add("url", validate.CodeInvalid, err.Error())
+		case u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "ssh" && u.Scheme != "git":
+			errs.Add("url", validate.CodeInvalid, "scheme must be one of [http|https|ssh|git]")
+		case u.Host == "":
+			errs.Add("url", validate.CodeInvalid, "host is empty")
+		}
+	}
+	if strings.HasPrefix(r.Branch, "-") || strings.ContainsAny(r.Branch, " ~^:?*[\\") {
+		errs.Add("branch", validate.CodeInvalid, "wrong branch name")
+	}
+	if r.Credentials != "" && !credentialsRef.MatchString(r.Credentials) {
+		errs.Add("credentials", validate.CodeInvalid, "should contain only latin letters, digits and underscore")
+	}
+	return errs.Err()
+}
+
+// Env returns environment variables for plugin containers.
+// Credentials are resolved by agent.
+func (r *RepoTarget) Env() []string {
+	if r == nil {
+		return nil
+	}
+	env := []string{"BEARDED_REPO_URL=" + r.Url}
+	if r.Branch != "" {
+		env = append(env, "BEARDED_REPO_BRANCH="+r.Branch)
+	}
+	return env
+}
+
+// Host returns the host of the clone url without user and port, empty string if the url is wrong
+func (r *RepoTarget) Host() string {
+	if m := scpAddr.FindStringSubmatch(r.Url); m != nil {
+		return strings.ToLower(m[1])
+	}
+	u, err := url.Parse(r.Url)
+	if err != nil {
+		return ""
+	}
+	host := u.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(host)
+}
+
+// WebUrl returns repository page for known hostings or empty string
+func (r *RepoTarget) WebUrl() string {
+	host, path := "", ""
+	if m := scpAddr.FindStringSubmatch(r.Url); m != nil {
+		host, path = m[1], m[2]
+	} else if u, err := url.Parse(r.Url); err == nil {
+		host, path = u.Host, u.Path
+		if i := strings.Index(host, "@"); i >= 0 {
+			host = host[i+1:]
+		}
+	}
+	// ssh port isn't used by web
+	if i := strings.Index(host, ":"); i >= 0 {
+		host = host[:i]
+	}
+	switch host {
+	case "github.com", "gitlab.com", "bitbucket.org":
+	default:
+		return ""
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	if path == "" {
+		return ""
+	}
+	return fmt.Sprintf("https://%s/%s", host, path)
+}
+
+// FileUrl returns link to the file line on the repository hosting or empty string
+func (r *RepoTarget) FileUrl(path string, line int) string {
+	web := r.WebUrl()
+	if web == "" || path == "" {
+		return ""
+	}
+	branch := r.Branch
+	if branch == "" {
+		branch = "HEAD"
+	}
+	path = strings.TrimPrefix(path, "/")
+	switch {
+	case strings.HasPrefix(web, "https://github.com/"):
+		web = fmt.Sprintf("%s/blob/%s/%s", web, branch, path)
+		if line > 0 {
+			web = fmt.Sprintf("%s#L%d", web, line)
+		}
+	case strings.HasPrefix(web, "https://gitlab.com/"):
+		web = fmt.Sprintf("%s/-/blob/%s/%s", web, branch, path)
+		if line > 0 {
+			web = fmt.Sprintf("%s#L%d", web, line)
+		}
+	case strings.HasPrefix(web, "https://bitbucket.org/"):
+		web = fmt.Sprintf("%s/src/%s/%s", web, branch, path)
+		if line > 0 {
+			web = fmt.Sprintf("%s#lines-%d", web, line)
+		}
+	}
+	return web
+}
@@ -0,0 +1,50 @@
+package target
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepoValidate(t *testing.T) {
+	valid := []*RepoTarget{
+		{Url: "https://github.com/bearded-web/bearded.git"},
+		{Url: "git@github.com:bearded-web/bearded.git", Branch: "release/1.0"},
+		{Url: "ssh://git@example.com:2222/repo.git", Credentials: "DEPLOY_KEY"},
+	}
+	for _, r := range valid {
+		assert.NoError(t, r.Validate(), r.Url)
+	}
+	invalid := []*RepoTarget{
+		{Url: "ftp://example.com/repo.git"},
+		{Url: "/local/path"},
+		{Url: "https://github.com/repo.git", Branch: "--upload-pack=sh"},
+		{Url: "https://github.com/repo.git", Credentials: "my key"},
+	}
+	for _, r := range invalid {
+		assert.Error(t, r.Validate(), r.Url)
+	}
+}
+
+func TestRepoFileUrl(t *testing.T) {
+	r := &RepoTarget{Url: "git@github.com:bearded-web/bearded.git", Branch: "dev"}
+	assert.Equal(t, "https://github.com/bearded-web/bearded", r.WebUrl())
+	assert.Equal(t, "https://github.com/bearded-web/bearded/blob/dev/pkg/main.go#L12", r.FileUrl("/pkg/main.go", 12))
+
+	r = &RepoTarget{Url: "https://user@gitlab.com/group/project.git"}
+	assert.Equal(t, "https://gitlab.com/group/project/-/blob/HEAD/app.py", r.FileUrl("app.py", 0))
+
+	r = &RepoTarget{Url: "https://bitbucket.org/team/repo"}
+	assert.Equal(t, "https://bitbucket.org/team/repo/src/HEAD/a.js#lines-3", r.FileUrl("a.js", 3))
+
+	r = &RepoTarget{Url: "https://git.example.com/repo.git"}
+	assert.Equal(t, "", r.FileUrl("a.js", 3))
+	assert.Equal(t, []string{"BEARDED_REPO_URL=https://git.example.com/repo.git"}, r.Env())
+}
+
+func TestRepoHost(t *testing.T) {
+	assert.Equal(t, "github.com", (&RepoTarget{Url: "git@GitHub.com:org/app.git"}).Host())
+	assert.Equal(t, "git.example.com", (&RepoTarget{Url: "ssh://git@git.example.com:2222/app.git"}).Host())
+	assert.Equal(t, "gitlab.com", (&RepoTarget{Url: "https://user@gitlab.com/group/project.git"}).Host())
+	assert.Equal(t, "", (&RepoTarget{Url: "%%"}).Host())
+}
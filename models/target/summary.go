@@ -0,0 +1,29 @@
+package target
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+)
+
+// Summary is the materialized summary report of the target. Summaries are kept apart from targets
+// and rebuilt in background after issues of the target are changed, so issue handlers only mark them stale.
+type Summary struct {
+	Target        bson.ObjectId `json:"target" bson:"_id"`
+	SummaryReport `bson:",inline"`
+	Stale         bool      `json:"stale" description:"issues are changed after the last build"`
+	Version       int       `json:"-" description:"incremented by every change of issues"`
+	Taken         time.Time `json:"-" bson:"taken,omitempty" description:"when the build is started, stale builds are taken again"`
+	Updated       time.Time `json:"updated,omitempty" description:"when the summary is built"`
+}
+
+// Report returns the summary report, it's never nil
+func (s *Summary) Report() *SummaryReport {
+	report := s.SummaryReport
+	if report.Issues == nil {
+		report.Issues = map[issue.Severity]int{}
+	}
+	return &report
+}
@@ -0,0 +1,23 @@
+package target
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bearded-web/bearded/models/issue"
+)
+
+func TestSummaryReport(t *testing.T) {
+	s := &Summary{}
+	assert.Equal(t, &SummaryReport{Issues: map[issue.Severity]int{}}, s.Report())
+
+	s.Issues = map[issue.Severity]int{issue.SeverityHigh: 2}
+	s.Risk = 80
+	report := s.Report()
+	assert.Equal(t, 2, report.Issues[issue.SeverityHigh])
+	assert.Equal(t, 80, report.Risk)
+	// the report could be changed without changing the summary
+	report.Risk = 0
+	assert.Equal(t, 80, s.Risk)
+}
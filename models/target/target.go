@@ -1,6 +1,7 @@
 package target
 
 import (
+	"net/url"
 	"time"
 
 	"gopkg.in/mgo.v2/bson"
@@ -12,18 +13,34 @@ import (
 
 type SummaryReport struct {
 	Issues map[issue.Severity]int `json:"issues" bson:"issues"`
+	Risk   int                    `json:"risk" bson:"risk" description:"max risk score of open issues"`
 }
 
 type Target struct {
 	Id      bson.ObjectId  `json:"id,omitempty" bson:"_id"`
-	Type    TargetType     `json:"type" description:"one of [web|android]"`
+	Type    TargetType     `json:"type" description:"one of [web|android|api|repo]"`
 	Web     *WebTarget     `json:"web,omitempty" description:"information about web target"`
 	Android *AndroidTarget `json:"android,omitempty" description:"information about android target"`
+	Api     *ApiTarget     `json:"api,omitempty" description:"information about api target described by openapi spec"`
+	Repo    *RepoTarget    `json:"repo,omitempty" description:"git repository for static analysis"`
 	Project bson.ObjectId  `json:"project"`
 	Created time.Time      `json:"created,omitempty"`
 	Updated time.Time      `json:"updated,omitempty"`
 
+	Criticality Criticality    `json:"criticality,omitempty" description:"one of [low|medium|high|critical], medium if empty"`
+	Environment Environment    `json:"environment,omitempty" bson:"environment,omitempty" description:"one of [prod|staging|dev]"`
+	Exposure    issue.Exposure `json:"exposure" description:"exposure of issues found by scans and monitor, users could change it for each issue"`
+	RateLimit   *RateLimit     `json:"rateLimit,omitempty" bson:"rateLimit,omitempty" description:"scan politeness, project defaults are used for unset fields"`
+	Proxy       *Proxy         `json:"proxy,omitempty" bson:"proxy,omitempty" description:"outbound proxy for plugin traffic, the project proxy is used if empty"`
+	Record      bool           `json:"record,omitempty" bson:"record,omitempty" description:"record plugin http traffic to har artifacts of scan sessions. Https is recorded only as opaque CONNECT entries with host and timings, requests inside tls tunnels aren't visible. Can't be used with socks5 proxies"`
+
 	SummaryReport *SummaryReport `json:"summaryReport,omitempty" bson:"summaryReport"`
+	Monitor       *MonitorState  `json:"monitor,omitempty" bson:"monitor,omitempty" description:"state of the built-in tls and dns monitor"`
+}
+
+type MonitorState struct {
+	Dns     []string  `json:"dns,omitempty" bson:"dns,omitempty" description:"dns records found by the last check"`
+	Checked time.Time `json:"checked"`
 }
 
 type WebTarget struct {
@@ -33,6 +50,44 @@ type WebTarget struct {
 type AndroidTarget struct {
 	Name string     `json:"name" description:"target name, 80 symbols max" mobile:"nonzero,max=80"`
 	File *file.Meta `json:"file" description:"apk file metadata"`
+
+	Version  *ApkVersion   `json:"version,omitempty" bson:",omitempty" description:"current apk version"`
+	Versions []*ApkVersion `json:"versions,omitempty" bson:",omitempty" description:"history of uploaded versions, the current one is the last"`
+	AutoScan bson.ObjectId `json:"autoScan,omitempty" bson:"autoScan,omitempty" description:"plan which is run when a new version is uploaded"`
+}
+
+// ApkVersion is metadata extracted from AndroidManifest.xml of the uploaded apk
+type ApkVersion struct {
+	Package     string        `json:"package"`
+	VersionCode int           `json:"versionCode" bson:"versionCode"`
+	VersionName string        `json:"versionName,omitempty" bson:"versionName,omitempty"`
+	MinSdk      int           `json:"minSdk,omitempty" bson:"minSdk,omitempty"`
+	TargetSdk   int           `json:"targetSdk,omitempty" bson:"targetSdk,omitempty"`
+	File        *file.Meta    `json:"file"`
+	Uploaded    time.Time     `json:"uploaded"`
+	Scan        bson.ObjectId `json:"scan,omitempty" bson:",omitempty" description:"scan triggered for this version"`
+}
+
+// IsNew reports whether the version differs from the current one
+func (a *AndroidTarget) IsNew(v *ApkVersion) bool {
+	cur := a.Version
+	return cur == nil || cur.Package != v.Package || cur.VersionCode != v.VersionCode || cur.VersionName != v.VersionName
+}
+
+// SetVersion makes the version current and puts it to history.
+// Re-uploaded current version replaces the last history entry.
+// Returns true if the version is new.
+func (a *AndroidTarget) SetVersion(v *ApkVersion) bool {
+	isNew := a.IsNew(v)
+	if !isNew && len(a.Versions) > 0 {
+		v.Scan = a.Version.Scan
+		a.Versions[len(a.Versions)-1] = v
+	} else {
+		a.Versions = append(a.Versions, v)
+	}
+	a.Version = v
+	a.File = v.File
+	return isNew
 }
 
 type TargetList struct {
@@ -41,8 +96,30 @@ type TargetList struct {
 }
 
 func (t *Target) Addr() string {
-	if t.Type == "web" {
+	switch {
+	case t.Type == TypeWeb && t.Web != nil:
 		return t.Web.Domain
+	case t.Type == TypeApi && t.Api != nil:
+		return t.Api.BaseUrl
+	case t.Type == TypeRepo && t.Repo != nil:
+		return t.Repo.Url
 	}
 	return ""
 }
+
+// IssueVars returns variables of the target for issue descriptions: target.addr, target.host and target.type
+func (t *Target) IssueVars() issue.Vars {
+	vars := issue.Vars{
+		"target.type": string(t.Type),
+		"target.addr": t.Addr(),
+	}
+	if t.Type == TypeAndroid && t.Android != nil {
+		vars["target.addr"] = t.Android.Name
+	}
+	host := vars["target.addr"]
+	if u, err := url.Parse(host); err == nil && u.Host != "" {
+		host = u.Hostname()
+	}
+	vars["target.host"] = host
+	return vars
+}
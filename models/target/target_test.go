@@ -0,0 +1,42 @@
+package target
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/file"
+)
+
+func TestSetVersion(t *testing.T) {
+	a := &AndroidTarget{}
+	v1 := &ApkVersion{Package: "com.example", VersionCode: 1, File: &file.Meta{Id: "1"}}
+	assert.True(t, a.SetVersion(v1))
+	assert.Equal(t, v1, a.Version)
+	assert.Equal(t, "1", a.File.Id)
+	v1.Scan = bson.NewObjectId()
+
+	// the same version is uploaded again
+	again := &ApkVersion{Package: "com.example", VersionCode: 1, File: &file.Meta{Id: "2"}}
+	assert.False(t, a.SetVersion(again))
+	assert.Len(t, a.Versions, 1)
+	assert.Equal(t, "2", a.File.Id)
+	assert.Equal(t, v1.Scan, a.Version.Scan)
+
+	v2 := &ApkVersion{Package: "com.example", VersionCode: 2, File: &file.Meta{Id: "3"}}
+	assert.True(t, a.SetVersion(v2))
+	assert.Len(t, a.Versions, 2)
+	assert.Equal(t, v2, a.Version)
+}
+
+func TestIssueVars(t *testing.T) {
+	tgt := &Target{Type: TypeWeb, Web: &WebTarget{Domain: "https://example.com:8443/app"}}
+	vars := tgt.IssueVars()
+	assert.Equal(t, "example.com", vars["target.host"])
+	assert.Equal(t, "https://example.com:8443/app", vars["target.addr"])
+	assert.Equal(t, "web", vars["target.type"])
+
+	tgt = &Target{Type: TypeWeb, Web: &WebTarget{Domain: "example.com"}}
+	assert.Equal(t, "example.com", tgt.IssueVars()["target.host"])
+}
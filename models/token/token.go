@@ -10,6 +10,9 @@ import (
 const TokenLength = 32
 const DefaultScope = "all"
 
+// ScopeIngest tokens could only push issues to their project, they don't authenticate the user
+const ScopeIngest = "ingest"
+
 type Token struct {
 	Id        bson.ObjectId `json:"id,omitempty" bson:"_id"`
 	User      bson.ObjectId `json:"user"`
@@ -17,6 +20,7 @@ type Token struct {
 	Hash      string        `json:"-"`
 	HashValue string        `json:"value" bson:"-"`
 	Scopes    []string      `json:"scopes,omitempty"`
+	Project   bson.ObjectId `json:"project,omitempty" bson:"project,omitempty" description:"project of ingest token"`
 	Created   time.Time     `json:"created,omitempty"`
 	Updated   time.Time     `json:"updated,omitempty"`
 	Removed   bool          `json:"-"`
@@ -26,3 +30,13 @@ type TokenList struct {
 	pagination.Meta `json:",inline"`
 	Results         []*Token `json:"results"`
 }
+
+// IsIngest returns true for write-only project tokens used by external tools
+func (t *Token) IsIngest() bool {
+	for _, scope := range t.Scopes {
+		if scope == ScopeIngest {
+			return true
+		}
+	}
+	return false
+}
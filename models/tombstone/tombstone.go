@@ -0,0 +1,26 @@
+package tombstone
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+type Kind string
+
+const (
+	Target  = Kind("target")
+	Issue   = Kind("issue")
+	Scan    = Kind("scan")
+	Comment = Kind("comment")
+)
+
+// Tombstone remembers a removed entity, so sync clients could drop it from their cache.
+// Comments of removed issues and targets don't get own tombstones.
+type Tombstone struct {
+	Id      bson.ObjectId `json:"-" bson:"_id"`
+	Kind    Kind          `json:"kind" description:"one of [target|issue|scan|comment]"`
+	Entity  bson.ObjectId `json:"entity" description:"id of the removed entity"`
+	Project bson.ObjectId `json:"project"`
+	Removed time.Time     `json:"removed"`
+}
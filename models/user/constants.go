@@ -0,0 +1,77 @@
+package user
+
+import "encoding/json"
+
+type Status string
+
+const (
+	StatusActive     Status = "active"
+	StatusUnverified Status = "unverified" // user registered, but email isn't verified yet
+	StatusPending    Status = "pending"    // user is waiting for admin approval
+)
+
+var statuses = []interface{}{
+	StatusActive,
+	StatusUnverified,
+	StatusPending,
+}
+
+type DateFormat string
+
+const (
+	DateIso DateFormat = "iso" // 2006-01-02
+	DateEu  DateFormat = "eu"  // 02.01.2006
+	DateUs  DateFormat = "us"  // 01/02/2006
+)
+
+var dateFormats = []interface{}{
+	DateIso,
+	DateEu,
+	DateUs,
+}
+
+// Layout returns time layout for the format, iso is used for unknown formats
+func (t DateFormat) Layout() string {
+	switch t {
+	case DateEu:
+		return "02.01.2006"
+	case DateUs:
+		return "01/02/2006"
+	}
+	return "2006-01-02"
+}
+
+func (t DateFormat) IsValid() bool {
+	for _, f := range dateFormats {
+		if f == t {
+			return true
+		}
+	}
+	return false
+}
+
+// It's a hack to show custom type as string in swagger
+func (t DateFormat) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+func (t DateFormat) Enum() []interface{} {
+	return dateFormats
+}
+
+func (t DateFormat) Convert(text string) (interface{}, error) {
+	return DateFormat(text), nil
+}
+
+// It's a hack to show custom type as string in swagger
+func (t Status) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(t))
+}
+
+func (t Status) Enum() []interface{} {
+	return statuses
+}
+
+func (t Status) Convert(text string) (interface{}, error) {
+	return Status(text), nil
+}
@@ -0,0 +1,29 @@
+package user
+
+import (
+	"time"
+
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+// OutOfOffice is a vacation or another absence of the user in the period [From, To)
+type OutOfOffice struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	Note string    `json:"note,omitempty" description:"message for colleagues, 200 symbols max" validate:"max=200"`
+}
+
+func (o *OutOfOffice) Contains(t time.Time) bool {
+	return !t.Before(o.From) && t.Before(o.To)
+}
+
+func (o *OutOfOffice) Validate() error {
+	errs := validate.Errors{}
+	if o.From.IsZero() {
+		errs.Add("from", validate.CodeRequired, "from is required")
+	}
+	if !o.To.After(o.From) {
+		errs.Add("to", validate.CodeInvalid, "should be after from")
+	}
+	return errs.Err()
+}
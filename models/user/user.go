@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/bearded-web/bearded/models/notification"
 	"github.com/bearded-web/bearded/pkg/pagination"
 	"gopkg.in/mgo.v2/bson"
 )
@@ -14,6 +15,15 @@ type User struct {
 	Email    string        `json:"email"`
 	Password string        `json:"-"` // password hash in passlib format: $hashAlgo[$values]$hexdigest_hash$
 	Avatar   string        `json:"avatar,omitempty"`
+	Status   Status        `json:"status,omitempty" description:"one of [active|unverified|pending]"`
+
+	AvatarFile string     `json:"-" bson:"avatarFile,omitempty"` // id of the uploaded avatar file
+	Timezone   string     `json:"timezone,omitempty" description:"IANA timezone name, f.e Europe/Moscow, UTC if empty"`
+	DateFormat DateFormat `json:"dateFormat,omitempty" description:"one of [iso|eu|us], iso if empty"`
+	Locale     string     `json:"locale,omitempty" bson:"locale,omitempty" description:"language of messages and emails, like ru, Accept-Language or english is used if empty"`
+
+	Notifications notification.Preferences `json:"-" bson:"notifications,omitempty"`
+	OutOfOffice   *OutOfOffice             `json:"outOfOffice,omitempty" bson:"outOfOffice,omitempty" description:"issues aren't assigned to the user and escalations go to the user team during this period"`
 
 	Created time.Time `json:"created,omitempty"`
 	Updated time.Time `json:"updated,omitempty"`
@@ -30,10 +40,58 @@ func (u *User) IdStr() string {
 	return fmt.Sprintf("%x", string(u.Id))
 }
 
+// Users created before signup statuses have an empty status and they are active
+func (u *User) IsActive() bool {
+	return u.Status == "" || u.Status == StatusActive
+}
+
 func (u *User) IsAdmin() bool {
 	return false
 }
 
+// IsAway reports whether the user is out of office at the moment
+func (u *User) IsAway(now time.Time) bool {
+	return u.OutOfOffice != nil && u.OutOfOffice.Contains(now)
+}
+
+// Location returns the user timezone or UTC if it's empty or unknown
+func (u *User) Location() *time.Location {
+	if u.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// FormatTime formats t in the user timezone and date format, it's used by email renderers
+func (u *User) FormatTime(t time.Time) string {
+	return t.In(u.Location()).Format(u.DateFormat.Layout() + " 15:04 MST")
+}
+
+// FormatDate is like FormatTime, but without the time of day
+func (u *User) FormatDate(t time.Time) string {
+	return t.In(u.Location()).Format(u.DateFormat.Layout())
+}
+
+// Public profile returns a minimal information which is safe to show for everyone
+func (u *User) Profile() *Profile {
+	return &Profile{
+		Id:       u.Id,
+		Nickname: u.Nickname,
+		Avatar:   u.Avatar,
+	}
+}
+
+// Profile is used to render comment authors and other users without private fields
+type Profile struct {
+	Id       bson.ObjectId `json:"id"`
+	Nickname string        `json:"nickname"`
+	Avatar   string        `json:"avatar,omitempty"`
+}
+
 type UserList struct {
 	pagination.Meta `json:",inline"`
 	Results         []*User `json:"results"`
@@ -0,0 +1,36 @@
+package user
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatTime(t *testing.T) {
+	tm := time.Date(2015, 3, 4, 22, 30, 0, 0, time.UTC)
+
+	u := &User{}
+	assert.Equal(t, "2015-03-04 22:30 UTC", u.FormatTime(tm))
+
+	u = &User{Timezone: "Europe/Moscow", DateFormat: DateEu}
+	assert.Equal(t, "05.03.2015", u.FormatDate(tm))
+
+	u = &User{Timezone: "Unknown/Zone", DateFormat: DateUs}
+	assert.Equal(t, "03/04/2015", u.FormatDate(tm))
+}
+
+func TestIsAway(t *testing.T) {
+	now := time.Date(2015, 7, 10, 12, 0, 0, 0, time.UTC)
+	u := &User{}
+	assert.False(t, u.IsAway(now))
+
+	u.OutOfOffice = &OutOfOffice{From: now.AddDate(0, 0, -1), To: now.AddDate(0, 0, 7)}
+	assert.NoError(t, u.OutOfOffice.Validate())
+	assert.True(t, u.IsAway(now))
+	assert.False(t, u.IsAway(now.AddDate(0, 0, 7)))
+	assert.False(t, u.IsAway(now.AddDate(0, 0, -2)))
+
+	assert.Error(t, (&OutOfOffice{From: now, To: now}).Validate())
+	assert.Error(t, (&OutOfOffice{To: now}).Validate())
+}
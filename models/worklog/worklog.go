@@ -0,0 +1,43 @@
+package worklog
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/pkg/pagination"
+)
+
+// Worklog is time spent by the user on the issue
+type Worklog struct {
+	Id       bson.ObjectId `json:"id" bson:"_id"`
+	Issue    bson.ObjectId `json:"issue"`
+	Project  bson.ObjectId `json:"project"`
+	User     bson.ObjectId `json:"user" description:"who did the work"`
+	Duration int           `json:"duration" description:"spent minutes"`
+	Note     string        `json:"note,omitempty" bson:",omitempty"`
+	Date     time.Time     `json:"date" description:"when the work was done"`
+	Created  time.Time     `json:"created,omitempty"`
+	Updated  time.Time     `json:"updated,omitempty"`
+}
+
+type WorklogList struct {
+	pagination.Meta `json:",inline"`
+	Results         []*Worklog `json:"results"`
+}
+
+// Effort is total time spent in the period
+type Effort struct {
+	Project  bson.ObjectId `json:"project"`
+	From     time.Time     `json:"from,omitempty"`
+	To       time.Time     `json:"to,omitempty"`
+	Duration int           `json:"duration" description:"total minutes"`
+	Users    []*Spent      `json:"users" description:"minutes by users"`
+	Issues   []*Spent      `json:"issues" description:"minutes by issues"`
+}
+
+type Spent struct {
+	Id       bson.ObjectId `json:"id" bson:"_id" description:"user or issue id"`
+	Duration int           `json:"duration"`
+	Entries  int           `json:"entries" description:"number of worklogs"`
+}
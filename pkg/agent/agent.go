@@ -2,6 +2,7 @@ package agent
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -18,8 +19,10 @@ import (
 	"github.com/bearded-web/bearded/models/plugin"
 	"github.com/bearded-web/bearded/models/report"
 	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/models/target"
 	"github.com/bearded-web/bearded/pkg/client"
 	"github.com/bearded-web/bearded/pkg/config"
+	"github.com/bearded-web/bearded/pkg/credentials"
 	"github.com/bearded-web/bearded/pkg/docker"
 	"github.com/bearded-web/bearded/pkg/transport/mango"
 	"github.com/bearded-web/bearded/pkg/utils"
@@ -29,19 +32,28 @@ import (
 
 type Agent struct {
 	// client helps to communicate with bearded api
-	api     *client.Client
-	name    string
-	dclient *docker.Docker
+	api      *client.Client
+	name     string
+	capacity int
+	dclient  *docker.Docker
+	// address which recording proxies listen on
+	recordHost string
 
-	jobs *set.Set
+	jobs  *set.Set
+	slots *targetSlots
+	loads *imageLocks
 }
 
-func New(api *client.Client, dclient *docker.Docker, name string) (*Agent, error) {
+// New returns agent which runs up to capacity sessions at once, 0 capacity is unlimited
+func New(api *client.Client, dclient *docker.Docker, name string, capacity int) (*Agent, error) {
 	a := &Agent{
-		api:     api,
-		name:    name,
-		dclient: dclient,
-		jobs:    set.New(),
+		api:      api,
+		name:     name,
+		capacity: capacity,
+		dclient:  dclient,
+		jobs:     set.New(),
+		slots:    newTargetSlots(),
+		loads:    newImageLocks(),
 	}
 	return a, nil
 }
@@ -159,12 +171,16 @@ func (a *Agent) Retrieve(ctx context.Context, agnt *agent.Agent) error {
 
 func (a *Agent) GetJobs(ctx context.Context, agnt *agent.Agent) error {
 	//	logrus.Debug("Request jobs")
-	jobs, err := a.api.Agents.GetJobs(ctx, agnt)
+	jobs, err := a.api.Agents.GetJobs(ctx, agnt, a.Load())
 	if err != nil {
 		return err
 	}
 	//	logrus.Debugf("Got %d jobs", len(jobs))
 	for _, job := range jobs {
+		if job.Cmd == agent.CmdPull {
+			go a.PullImages(ctx, agnt, job.Images)
+			continue
+		}
 		if err := a.HandleJob(ctx, job); err != nil {
 			// TODO (m0sth8): return scan failed status
 			// what should I do if backend server is unavailable?
@@ -174,10 +190,26 @@ func (a *Agent) GetJobs(ctx context.Context, agnt *agent.Agent) error {
 	return nil
 }
 
+// Load is reported to the scheduler with every request for jobs
+func (a *Agent) Load() *agent.Load {
+	queued := a.slots.queued()
+	running := a.jobs.Size() - queued
+	if running < 0 {
+		running = 0
+	}
+	return &agent.Load{
+		Running:  running,
+		Queued:   queued,
+		Capacity: a.capacity,
+	}
+}
+
 func (a *Agent) HandleJob(ctx context.Context, job *agent.Job) error {
 	logrus.Debugf("Job: %s", job)
 	if job.Cmd == agent.CmdScan {
 		go func() {
+			// the token isn't sent back with session updates, it's used only by plugin callbacks
+			job.Scan.Token = job.Token
 			asnc := async.New(ctx, func(ctx context.Context) error {
 				return a.HandleScan(ctx, job.Scan)
 			})
@@ -189,6 +221,53 @@ func (a *Agent) HandleJob(ctx context.Context, job *agent.Job) error {
 	return nil
 }
 
+// loadArchive loads the plugin image from the server file storage if it's imported with the offline bundle,
+// so agents without access to registries don't pull it
+func (a *Agent) loadArchive(ctx context.Context, pl *plugin.Plugin) error {
+	if pl.Container == nil || pl.Container.Archive == "" {
+		return nil
+	}
+	// the image is checked under the lock, sessions which waited for the load use the loaded image
+	defer a.loads.lock(pl.Container.Image)()
+	if ok, err := a.dclient.HasImage(pl.Container.Image); ok || err != nil {
+		return err
+	}
+	logrus.Infof("load image %s from archive %s", pl.Container.Image, pl.Container.Archive)
+	// archives could take gigabytes, so they are streamed to docker instead of being read into memory
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(a.api.Files.DownloadTo(ctx, pl.Container.Archive, w))
+	}()
+	err := a.dclient.LoadImage(r)
+	// stops the download if docker failed before reading the whole archive
+	r.CloseWithError(err)
+	return err
+}
+
+// PullImages downloads images before scans which use them and reports the image cache state
+func (a *Agent) PullImages(ctx context.Context, agnt *agent.Agent, images []string) {
+	states := []*agent.Image{}
+	for _, name := range images {
+		if ctx.Err() != nil {
+			return
+		}
+		state := &agent.Image{Name: name}
+		if err := a.dclient.PullImage(name); err != nil {
+			logrus.Errorf("Pull image %s: %v", name, err)
+			state.Error = err.Error()
+		} else if img, err := a.dclient.Client.InspectImage(name); err != nil {
+			state.Error = err.Error()
+		} else {
+			state.Cached = true
+			state.Size = img.VirtualSize
+		}
+		states = append(states, state)
+	}
+	if _, err := a.api.Agents.ReportImages(ctx, agnt, states); err != nil && !utils.IsCanceled(err) {
+		logrus.Error(err)
+	}
+}
+
 func (a *Agent) HandleScan(ctx context.Context, sess *scan.Session) error {
 	// take a plugin
 	pl, err := a.api.Plugins.Get(ctx, client.FromId(sess.Plugin))
@@ -198,11 +277,18 @@ func (a *Agent) HandleScan(ctx context.Context, sess *scan.Session) error {
 	logrus.Infof("plugin: %s", pl)
 	logrus.Info("set session to working state")
 	sess.Status = scan.StatusWorking
+	token := sess.Token
 	if sess, err = a.api.Scans.SessionUpdate(ctx, sess); err != nil {
 		return err
 	}
+	sess.Token = token
 
+	var rec *trafficRecorder
 	setFailed := func(err error) error {
+		if rec != nil {
+			a.stopRecorder(ctx, sess, rec)
+			rec = nil
+		}
 		if utils.IsCanceled(err) {
 			logrus.Infof("set session to failed state, due to %s", err)
 		} else {
@@ -214,19 +300,53 @@ func (a *Agent) HandleScan(ctx context.Context, sess *scan.Session) error {
 		}
 		return err
 	}
+	if err := a.loadArchive(ctx, pl); err != nil {
+		return setFailed(err)
+	}
 	// we have a couple of hack for boot2docker network
 	isBoot2Docker := utils.IsBoot2Docker()
 
+	env := sess.Step.Conf.RateLimit.Env()
+	if repo := sess.Step.Conf.Repo; repo != nil {
+		repoEnv, err := repoEnv(repo)
+		if err != nil {
+			return setFailed(err)
+		}
+		env = append(env, repoEnv...)
+	}
+	if sess.Step.Conf.Record {
+		// recorder chains to the session proxy itself
+		if rec, err = a.startRecorder(sess.Step.Conf.Proxy); err != nil {
+			return setFailed(err)
+		}
+		env = append(env, rec.Env()...)
+	} else if proxy := sess.Step.Conf.Proxy; proxy != nil {
+		proxyEnv, err := proxyEnv(proxy)
+		if err != nil {
+			return setFailed(err)
+		}
+		env = append(env, proxyEnv...)
+	}
+
 	hostCfg := &dockerclient.HostConfig{}
 	args := sess.Step.Conf.CommandArgs
 	cfg := &dockerclient.Config{
 		Image: pl.Container.Image,
 		Tty:   true,
 		Cmd:   strings.Split(args, " "),
+		Env:   env,
 	}
 
 	switch pl.Type {
 	case plugin.Util:
+		// scripts only orchestrate other plugins, so only utils take target slots
+		if limit := sess.Step.Conf.RateLimit; limit != nil && limit.Connections > 0 {
+			key := slotKey(sess.Step.Conf.Target)
+			if err := a.slots.acquire(ctx, key, limit.Connections); err != nil {
+				return setFailed(err)
+			}
+			defer a.slots.release(key)
+		}
 	case plugin.Script:
 		if hostCfg.PortBindings == nil {
 			hostCfg.PortBindings = map[dockerclient.Port][]dockerclient.PortBinding{}
@@ -414,6 +534,11 @@ func (a *Agent) HandleScan(ctx context.Context, sess *scan.Session) error {
 		}
 	}
 
+	if rec != nil {
+		a.stopRecorder(ctx, sess, rec)
+		rec = nil
+	}
+
 	_, err = a.api.Scans.SessionReportCreate(ctx, sess, rep)
 	if err != nil {
 		return setFailed(stackerr.Wrap(err))
@@ -440,9 +565,42 @@ func ServeAgent(ctx context.Context, cfg *config.Agent, api *client.Client) erro
 		cfg.Name = hostname
 	}
 	logrus.Infof("Agent name: %s", cfg.Name)
-	server, err := New(api, dclient, cfg.Name)
+	server, err := New(api, dclient, cfg.Name, cfg.Capacity)
 	if err != nil {
 		return fmt.Errorf("Initialization error: %s", err.Error())
 	}
+	server.recordHost = cfg.RecordHost
 	return server.Serve(ctx)
 }
+
+// repoEnv returns repository environment for plugin container.
+// Credentials are taken from the agent environment variable BEARDED_CREDENTIALS_<NAME>,
+// so secrets never leave the agent host, and given only for hosts they are bound to.
+func repoEnv(repo *target.RepoTarget) ([]string, error) {
+	env := repo.Env()
+	if repo.Credentials == "" {
+		return env, nil
+	}
+	secret, err := credentials.Lookup(repo.Credentials, repo.Host())
+	if err != nil {
+		return nil, err
+	}
+	return append(env, "BEARDED_REPO_CREDENTIALS="+secret), nil
+}
+
+// proxyEnv returns proxy environment for plugin container, credentials are taken from the agent environment
+func proxyEnv(proxy *target.Proxy) ([]string, error) {
+	secret, err := proxySecret(proxy)
+	if err != nil {
+		return nil, err
+	}
+	return proxy.Env(secret), nil
+}
+
+// proxySecret returns user:password of the proxy, the credentials are given only for hosts they are bound to
+func proxySecret(proxy *target.Proxy) (string, error) {
+	if proxy.Credentials == "" {
+		return "", nil
+	}
+	return credentials.Lookup(proxy.Credentials, proxy.Host())
+}
@@ -2,8 +2,10 @@
 package api
 
 import (
+	"github.com/bearded-web/bearded/models/file"
 	"github.com/bearded-web/bearded/models/plan"
 	"github.com/bearded-web/bearded/models/report"
+	"github.com/bearded-web/bearded/models/scan"
 )
 
 type Method int
@@ -16,8 +18,19 @@ const (
 	RunPlugin
 	SendReport
 	DownloadFile
+	// callbacks, agent proxies them to the server with the session token
+	GetCredentials
+	ReportProgress
+	InScope
+	UploadArtifact
 )
 
+// Artifact is a file which plugin keeps with the session, apart from the report
+type Artifact struct {
+	Name string
+	Data []byte
+}
+
 type RequestV1 struct {
 	Method Method
 
@@ -25,6 +38,10 @@ type RequestV1 struct {
 	RunPlugin         *plan.WorkflowStep
 	SendReport        *report.Report
 	DownloadFile      string
+	GetCredentials    string
+	ReportProgress    *scan.Progress
+	InScope           string
+	UploadArtifact    *Artifact
 }
 
 type ResponseV1 struct {
@@ -33,4 +50,7 @@ type ResponseV1 struct {
 	GetPluginVersions []string
 	RunPlugin         *report.Report
 	DownloadFile      []byte
+	GetCredentials    string
+	InScope           bool
+	UploadArtifact    *file.Meta
 }
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// targetSlots limits the number of plugins which run against the same target at once
+type targetSlots struct {
+	m       sync.Mutex
+	running map[string]int
+	waiting int
+}
+
+func newTargetSlots() *targetSlots {
+	return &targetSlots{running: map[string]int{}}
+}
+
+// tryAcquire takes a slot for the target if less than max plugins are running, max <= 0 means no limit
+func (t *targetSlots) tryAcquire(target string, max int) bool {
+	t.m.Lock()
+	defer t.m.Unlock()
+	if max > 0 && t.running[target] >= max {
+		return false
+	}
+	t.running[target]++
+	return true
+}
+
+// acquire waits for a free slot until the context is done
+func (t *targetSlots) acquire(ctx context.Context, target string, max int) error {
+	if t.tryAcquire(target, max) {
+		return nil
+	}
+	t.m.Lock()
+	t.waiting++
+	t.m.Unlock()
+	defer func() {
+		t.m.Lock()
+		t.waiting--
+		t.m.Unlock()
+	}()
+	for !t.tryAcquire(target, max) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+	return nil
+}
+
+// slotKey groups urls of the same host, plugins could scan different paths of one target
+func slotKey(target string) string {
+	if u, err := url.Parse(target); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return target
+}
+
+// queued returns the number of plugins which wait for a free slot
+func (t *targetSlots) queued() int {
+	t.m.Lock()
+	defer t.m.Unlock()
+	return t.waiting
+}
+
+func (t *targetSlots) release(target string) {
+	t.m.Lock()
+	defer t.m.Unlock()
+	if t.running[target] <= 1 {
+		delete(t.running, target)
+		return
+	}
+	t.running[target]--
+}
+
+// imageLocks serializes loads of the same image, so parallel sessions don't download its archive twice
+type imageLocks struct {
+	m     sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func newImageLocks() *imageLocks {
+	return &imageLocks{locks: map[string]*sync.Mutex{}}
+}
+
+// lock waits until other loads of the image are done and returns the unlock function
+func (l *imageLocks) lock(image string) func() {
+	l.m.Lock()
+	lock, ok := l.locks[image]
+	if !ok {
+		lock = &sync.Mutex{}
+		l.locks[image] = lock
+	}
+	l.m.Unlock()
+	lock.Lock()
+	return lock.Unlock
+}
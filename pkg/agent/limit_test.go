@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestTargetSlots(t *testing.T) {
+	slots := newTargetSlots()
+	assert.True(t, slots.tryAcquire("http://example.com", 2))
+	assert.True(t, slots.tryAcquire("http://example.com", 2))
+	assert.False(t, slots.tryAcquire("http://example.com", 2))
+	assert.True(t, slots.tryAcquire("http://other.com", 1))
+	// no limit
+	assert.True(t, slots.tryAcquire("http://example.com", 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	assert.Error(t, slots.acquire(ctx, "http://other.com", 1))
+
+	slots.release("http://other.com")
+	require.NoError(t, slots.acquire(context.Background(), "http://other.com", 1))
+	slots.release("http://other.com")
+	assert.Empty(t, slots.running["http://other.com"])
+
+	assert.Equal(t, "example.com:8080", slotKey("http://example.com:8080/admin/"))
+	assert.Equal(t, "example.com", slotKey("example.com"))
+}
+
+func TestImageLocks(t *testing.T) {
+	locks := newImageLocks()
+	unlock := locks.lock("barbudo/wpscan")
+	// other images aren't blocked
+	locks.lock("barbudo/nmap")()
+
+	done := make(chan struct{})
+	go func() {
+		locks.lock("barbudo/wpscan")()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("image is loaded twice at once")
+	case <-time.After(time.Millisecond * 10):
+	}
+	unlock()
+	<-done
+}
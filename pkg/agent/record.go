@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/facebookgo/stackerr"
+	"golang.org/x/net/context"
+
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/pkg/har"
+)
+
+const (
+	// address of the host in the default docker bridge network
+	defaultRecordHost = "172.17.0.1"
+	// name of the session artifact with recorded traffic
+	trafficArtifact = "traffic.har"
+)
+
+// trafficRecorder is a proxy for a single session, plugin container is pointed to it by proxy environment
+type trafficRecorder struct {
+	*har.Recorder
+	ln net.Listener
+}
+
+// startRecorder runs the recording proxy which chains to the session proxy if it's set
+func (a *Agent) startRecorder(proxy *target.Proxy) (*trafficRecorder, error) {
+	var upstream *url.URL
+	if proxy != nil {
+		secret, err := proxySecret(proxy)
+		if err != nil {
+			return nil, err
+		}
+		u, err := url.Parse(proxy.Addr(secret))
+		if err != nil {
+			return nil, stackerr.Wrap(err)
+		}
+		upstream = u
+	}
+	host := a.recordHost
+	if host == "" {
+		host = defaultRecordHost
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+	if err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	rec := &trafficRecorder{Recorder: har.NewRecorder(upstream), ln: ln}
+	go http.Serve(ln, rec)
+	logrus.Infof("recording traffic on %s", ln.Addr())
+	return rec, nil
+}
+
+// Env points proxy environment variables of the container to the recorder
+func (r *trafficRecorder) Env() []string {
+	return (&target.Proxy{Url: "http://" + r.ln.Addr().String()}).Env("")
+}
+
+// stopRecorder stops the proxy and uploads recorded traffic to the session artifacts,
+// the session isn't failed if the upload fails, e.g. when the storage quota is exceeded
+func (a *Agent) stopRecorder(ctx context.Context, sess *scan.Session, rec *trafficRecorder) {
+	rec.ln.Close()
+	data, err := json.Marshal(rec.Har())
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		return
+	}
+	if _, err := a.api.Scans.SessionArtifactCreate(ctx, sess, trafficArtifact, bytes.NewReader(data)); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		return
+	}
+	logrus.Infof("recorded traffic is uploaded, %d entries", rec.Len())
+}
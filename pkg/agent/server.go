@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"time"
@@ -8,11 +9,13 @@ import (
 	"github.com/Sirupsen/logrus"
 	"golang.org/x/net/context"
 
+	"github.com/bearded-web/bearded/models/file"
 	"github.com/bearded-web/bearded/models/plan"
 	"github.com/bearded-web/bearded/models/report"
 	"github.com/bearded-web/bearded/models/scan"
 	"github.com/bearded-web/bearded/pkg/agent/api"
 	"github.com/bearded-web/bearded/pkg/client"
+	"github.com/bearded-web/bearded/pkg/credentials"
 	"github.com/bearded-web/bearded/pkg/transport"
 )
 
@@ -73,6 +76,28 @@ func (s *RemoteServer) Handle(ctx context.Context, msg transport.Extractor) (int
 		} else {
 			resp.DownloadFile = data
 		}
+	case api.GetCredentials:
+		if data, err := s.GetCredentials(ctx, req.GetCredentials); err != nil {
+			return nil, err
+		} else {
+			resp.GetCredentials = data
+		}
+	case api.ReportProgress:
+		if err := s.ReportProgress(ctx, req.ReportProgress); err != nil {
+			return nil, err
+		}
+	case api.InScope:
+		if data, err := s.InScope(ctx, req.InScope); err != nil {
+			return nil, err
+		} else {
+			resp.InScope = data
+		}
+	case api.UploadArtifact:
+		if data, err := s.UploadArtifact(ctx, req.UploadArtifact); err != nil {
+			return nil, err
+		} else {
+			resp.UploadArtifact = data
+		}
 	default:
 		return nil, fmt.Errorf("Unknown method requested %s", req.Method)
 	}
@@ -167,3 +192,34 @@ func (s *RemoteServer) DownloadFile(ctx context.Context, fileId string) ([]byte,
 	}
 	return ioutil.ReadAll(buf)
 }
+
+// callbacks
+
+// GetCredentials returns the secret of credentials which are referenced by the scan target.
+// Secrets are kept on the agent host, so the server isn't asked.
+func (s *RemoteServer) GetCredentials(ctx context.Context, name string) (string, error) {
+	repo := s.sess.Step.Conf.Repo
+	if repo == nil || repo.Credentials == "" || repo.Credentials != name {
+		return "", fmt.Errorf("Credentials %s aren't referenced by the target", name)
+	}
+	return credentials.Lookup(name, repo.Host())
+}
+
+func (s *RemoteServer) ReportProgress(ctx context.Context, progress *scan.Progress) error {
+	if progress == nil {
+		return fmt.Errorf("Progress is empty")
+	}
+	_, err := s.api.Scans.SessionProgress(ctx, s.sess, progress)
+	return err
+}
+
+func (s *RemoteServer) InScope(ctx context.Context, url string) (bool, error) {
+	return s.api.Scans.SessionInScope(ctx, s.sess, url)
+}
+
+func (s *RemoteServer) UploadArtifact(ctx context.Context, artifact *api.Artifact) (*file.Meta, error) {
+	if artifact == nil || artifact.Name == "" {
+		return nil, fmt.Errorf("Artifact name is required")
+	}
+	return s.api.Scans.SessionArtifactCreate(ctx, s.sess, artifact.Name, bytes.NewReader(artifact.Data))
+}
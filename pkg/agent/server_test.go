@@ -1,11 +1,13 @@
 package agent
 
 import (
+	"os"
 	"testing"
 	"time"
 
 	"github.com/bearded-web/bearded/models/plan"
 	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/models/target"
 	"github.com/bearded-web/bearded/pkg/agent/api"
 	"github.com/bearded-web/bearded/pkg/transport"
 	"github.com/stretchr/testify/mock"
@@ -52,3 +54,40 @@ func TestServerMethods(t *testing.T) {
 	serv.GetConfig(ctx)
 
 }
+
+func TestServerGetCredentials(t *testing.T) {
+	sess := &scan.Session{
+		Step: &plan.WorkflowStep{
+			Conf: &plan.Conf{
+				Repo: &target.RepoTarget{Url: "https://github.com/org/app.git", Credentials: "github"},
+			},
+		},
+	}
+	serv, err := NewRemoteServer(&MockTransport{}, nil, sess)
+	require.NoError(t, err)
+
+	os.Setenv("BEARDED_CREDENTIALS_GITHUB", "secret")
+	defer os.Unsetenv("BEARDED_CREDENTIALS_GITHUB")
+	os.Setenv("BEARDED_CREDENTIALS_OTHER", "other")
+	defer os.Unsetenv("BEARDED_CREDENTIALS_OTHER")
+
+	// credentials aren't bound to the host
+	_, err = serv.GetCredentials(context.Background(), "github")
+	require.Error(t, err)
+
+	os.Setenv("BEARDED_CREDENTIALS_GITHUB_HOSTS", "github.com")
+	defer os.Unsetenv("BEARDED_CREDENTIALS_GITHUB_HOSTS")
+	secret, err := serv.GetCredentials(context.Background(), "github")
+	require.NoError(t, err)
+	require.Equal(t, "secret", secret)
+
+	// the target is pointed to another host
+	sess.Step.Conf.Repo.Url = "https://evil.example.com/org/app.git"
+	_, err = serv.GetCredentials(context.Background(), "github")
+	require.Error(t, err)
+	sess.Step.Conf.Repo.Url = "https://github.com/org/app.git"
+
+	// only credentials of the target are given to plugin
+	_, err = serv.GetCredentials(context.Background(), "other")
+	require.Error(t, err)
+}
@@ -0,0 +1,259 @@
+// Package apk extracts version metadata from android packages.
+//
+// AndroidManifest.xml inside an apk is stored in the binary xml format,
+// only the parts required to read manifest and uses-sdk attributes are implemented.
+package apk
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"unicode/utf16"
+)
+
+const manifestName = "AndroidManifest.xml"
+
+// manifests are a few kilobytes, bigger files aren't read to memory
+const maxManifestSize = 4 << 20
+
+// chunk types
+const (
+	chunkStringPool   = 0x0001
+	chunkXml          = 0x0003
+	chunkResourceMap  = 0x0180
+	chunkStartElement = 0x0102
+)
+
+// typed value types
+const (
+	typeString = 0x03
+	typeIntDec = 0x10
+	typeIntHex = 0x11
+)
+
+// android attribute resource ids, used when attribute names are stripped
+var attrIds = map[uint32]string{
+	0x0101021b: "versionCode",
+	0x0101021c: "versionName",
+	0x0101020c: "minSdkVersion",
+	0x01010270: "targetSdkVersion",
+}
+
+var ErrNoManifest = errors.New("apk doesn't contain " + manifestName)
+
+type Manifest struct {
+	Package     string
+	VersionCode int
+	VersionName string
+	MinSdk      int
+	TargetSdk   int
+}
+
+// Parse reads the manifest from apk file
+func Parse(r io.ReaderAt, size int64) (*Manifest, error) {
+	z, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range z.File {
+		if f.Name != manifestName {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		data, err := ioutil.ReadAll(io.LimitReader(rc, maxManifestSize+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > maxManifestSize {
+			return nil, fmt.Errorf("manifest is bigger than %d bytes", maxManifestSize)
+		}
+		return ParseManifest(data)
+	}
+	return nil, ErrNoManifest
+}
+
+// ParseManifest decodes binary AndroidManifest.xml
+func ParseManifest(data []byte) (*Manifest, error) {
+	if len(data) < 8 || binary.LittleEndian.Uint16(data) != chunkXml {
+		return nil, fmt.Errorf("wrong binary xml header")
+	}
+	m := &Manifest{}
+	var strs []string
+	var resIds []uint32
+	offset := int(binary.LittleEndian.Uint16(data[2:]))
+	for offset+8 <= len(data) {
+		typ := binary.LittleEndian.Uint16(data[offset:])
+		size := int(binary.LittleEndian.Uint32(data[offset+4:]))
+		if size < 8 || offset+size > len(data) {
+			return nil, fmt.Errorf("wrong chunk size at %d", offset)
+		}
+		chunk := data[offset : offset+size]
+		switch typ {
+		case chunkStringPool:
+			var err error
+			if strs, err = parseStrings(chunk); err != nil {
+				return nil, err
+			}
+		case chunkResourceMap:
+			headerSize := int(binary.LittleEndian.Uint16(chunk[2:]))
+			for i := headerSize; i+4 <= len(chunk); i += 4 {
+				resIds = append(resIds, binary.LittleEndian.Uint32(chunk[i:]))
+			}
+		case chunkStartElement:
+			if err := m.element(chunk, strs, resIds); err != nil {
+				return nil, err
+			}
+		}
+		offset += size
+	}
+	if m.Package == "" {
+		return nil, fmt.Errorf("manifest package is not found")
+	}
+	return m, nil
+}
+
+func (m *Manifest) element(chunk []byte, strs []string, resIds []uint32) error {
+	headerSize := int(binary.LittleEndian.Uint16(chunk[2:]))
+	if headerSize > len(chunk) {
+		return fmt.Errorf("wrong element header size")
+	}
+	ext := chunk[headerSize:]
+	if len(ext) < 20 {
+		return fmt.Errorf("element is too short")
+	}
+	name := str(strs, binary.LittleEndian.Uint32(ext[4:]))
+	if name != "manifest" && name != "uses-sdk" {
+		return nil
+	}
+	attrStart := int(binary.LittleEndian.Uint16(ext[8:]))
+	attrSize := int(binary.LittleEndian.Uint16(ext[10:]))
+	attrCount := int(binary.LittleEndian.Uint16(ext[12:]))
+	if attrSize < 20 || attrStart+attrSize*attrCount > len(ext) {
+		return fmt.Errorf("wrong attributes of %s", name)
+	}
+	for i := 0; i < attrCount; i++ {
+		attr := ext[attrStart+i*attrSize:]
+		nameIdx := binary.LittleEndian.Uint32(attr[4:])
+		attrName := str(strs, nameIdx)
+		if int(nameIdx) < len(resIds) {
+			if known, ok := attrIds[resIds[nameIdx]]; ok {
+				attrName = known
+			}
+		}
+		raw := binary.LittleEndian.Uint32(attr[8:])
+		dataType := attr[15]
+		value := binary.LittleEndian.Uint32(attr[16:])
+
+		text := str(strs, raw)
+		number := 0
+		switch dataType {
+		case typeString:
+			text = str(strs, value)
+		case typeIntDec, typeIntHex:
+			number = int(int32(value))
+		}
+		switch attrName {
+		case "package":
+			m.Package = text
+		case "versionCode":
+			m.VersionCode = number
+		case "versionName":
+			m.VersionName = text
+		case "minSdkVersion":
+			m.MinSdk = number
+		case "targetSdkVersion":
+			m.TargetSdk = number
+		}
+	}
+	return nil
+}
+
+func parseStrings(chunk []byte) ([]string, error) {
+	if len(chunk) < 28 {
+		return nil, fmt.Errorf("string pool is too short")
+	}
+	count := int(binary.LittleEndian.Uint32(chunk[8:]))
+	isUtf8 := binary.LittleEndian.Uint32(chunk[16:])&(1<<8) != 0
+	start := int(binary.LittleEndian.Uint32(chunk[20:]))
+	headerSize := int(binary.LittleEndian.Uint16(chunk[2:]))
+	if headerSize < 28 || headerSize+count*4 > len(chunk) || start > len(chunk) {
+		return nil, fmt.Errorf("wrong string pool")
+	}
+	strs := make([]string, count)
+	for i := range strs {
+		pos := start + int(binary.LittleEndian.Uint32(chunk[headerSize+i*4:]))
+		if pos >= len(chunk) {
+			return nil, fmt.Errorf("wrong string offset")
+		}
+		var err error
+		if isUtf8 {
+			strs[i], err = utf8String(chunk[pos:])
+		} else {
+			strs[i], err = utf16String(chunk[pos:])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return strs, nil
+}
+
+func utf8String(b []byte) (string, error) {
+	// utf16 length goes first, it isn't required
+	_, n := utf8Len(b)
+	b = b[n:]
+	l, n := utf8Len(b)
+	if n+l > len(b) {
+		return "", fmt.Errorf("wrong string length")
+	}
+	return string(b[n : n+l]), nil
+}
+
+func utf8Len(b []byte) (int, int) {
+	if len(b) == 0 {
+		return 0, 0
+	}
+	if b[0]&0x80 != 0 && len(b) > 1 {
+		return int(b[0]&0x7f)<<8 | int(b[1]), 2
+	}
+	return int(b[0]), 1
+}
+
+func utf16String(b []byte) (string, error) {
+	if len(b) < 2 {
+		return "", fmt.Errorf("wrong string length")
+	}
+	l, n := int(binary.LittleEndian.Uint16(b)), 2
+	if l&0x8000 != 0 && len(b) >= 4 {
+		l = (l&0x7fff)<<16 | int(binary.LittleEndian.Uint16(b[2:]))
+		n = 4
+	}
+	if n+l*2 > len(b) {
+		return "", fmt.Errorf("wrong string length")
+	}
+	chars := make([]uint16, l)
+	for i := range chars {
+		chars[i] = binary.LittleEndian.Uint16(b[n+i*2:])
+	}
+	return string(utf16.Decode(chars)), nil
+}
+
+func str(strs []string, idx uint32) string {
+	if int(idx) < len(strs) {
+		return strs[idx]
+	}
+	return ""
+}
+
+// ParseBytes is a shortcut for Parse for apk in memory
+func ParseBytes(data []byte) (*Manifest, error) {
+	return Parse(bytes.NewReader(data), int64(len(data)))
+}
@@ -0,0 +1,132 @@
+package apk
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type attr struct {
+	name     uint32
+	dataType byte
+	value    uint32
+	raw      uint32
+}
+
+// build minimal binary xml with utf16 string pool, resource map and elements
+func buildManifest(strs []string, resIds []uint32, elements map[uint32][]attr) []byte {
+	le := binary.LittleEndian
+	pool := &bytes.Buffer{}
+	offsets := []uint32{}
+	for _, s := range strs {
+		offsets = append(offsets, uint32(pool.Len()))
+		chars := utf16.Encode([]rune(s))
+		binary.Write(pool, le, uint16(len(chars)))
+		binary.Write(pool, le, chars)
+		binary.Write(pool, le, uint16(0))
+	}
+	strChunk := &bytes.Buffer{}
+	start := 28 + 4*len(strs)
+	binary.Write(strChunk, le, []uint16{chunkStringPool, 28})
+	binary.Write(strChunk, le, []uint32{uint32(start + pool.Len()), uint32(len(strs)), 0, 0, uint32(start), 0})
+	binary.Write(strChunk, le, offsets)
+	strChunk.Write(pool.Bytes())
+
+	body := &bytes.Buffer{}
+	body.Write(strChunk.Bytes())
+
+	binary.Write(body, le, []uint16{chunkResourceMap, 8})
+	binary.Write(body, le, uint32(8+4*len(resIds)))
+	binary.Write(body, le, resIds)
+
+	for name, attrs := range elements {
+		binary.Write(body, le, []uint16{chunkStartElement, 16})
+		binary.Write(body, le, uint32(16+20+20*len(attrs)))
+		binary.Write(body, le, []uint32{1, 0xffffffff, 0xffffffff, name})
+		binary.Write(body, le, []uint16{20, 20, uint16(len(attrs)), 0, 0, 0})
+		for _, a := range attrs {
+			binary.Write(body, le, []uint32{0xffffffff, a.name, a.raw})
+			binary.Write(body, le, []byte{8, 0, 0, a.dataType})
+			binary.Write(body, le, a.value)
+		}
+	}
+
+	out := &bytes.Buffer{}
+	binary.Write(out, le, []uint16{chunkXml, 8})
+	binary.Write(out, le, uint32(8+body.Len()))
+	out.Write(body.Bytes())
+	return out.Bytes()
+}
+
+func testManifest() []byte {
+	// names of version attributes are stripped, like obfuscators do
+	strs := []string{"", "", "", "package", "manifest", "uses-sdk", "com.example.app", "1.2.0"}
+	resIds := []uint32{0x0101021b, 0x0101021c, 0x0101020c}
+	none := uint32(0xffffffff)
+	return buildManifest(strs, resIds, map[uint32][]attr{
+		4: {
+			{name: 3, dataType: typeString, value: 6, raw: 6},
+			{name: 0, dataType: typeIntDec, value: 12, raw: none},
+			{name: 1, dataType: typeString, value: 7, raw: 7},
+		},
+		5: {
+			{name: 2, dataType: typeIntDec, value: 16, raw: none},
+		},
+	})
+}
+
+func TestParseManifest(t *testing.T) {
+	m, err := ParseManifest(testManifest())
+	require.NoError(t, err)
+	assert.Equal(t, &Manifest{
+		Package:     "com.example.app",
+		VersionCode: 12,
+		VersionName: "1.2.0",
+		MinSdk:      16,
+	}, m)
+
+	_, err = ParseManifest([]byte("<manifest/>"))
+	assert.Error(t, err)
+
+	data := testManifest()
+	_, err = ParseManifest(data[:len(data)-10])
+	assert.Error(t, err)
+
+	// element header size is bigger than the chunk
+	elem := []byte{0x02, 0x01, 0xff, 0xff, 8, 0, 0, 0}
+	data = append([]byte{chunkXml, 0, 8, 0, byte(8 + len(elem)), 0, 0, 0}, elem...)
+	_, err = ParseManifest(data)
+	assert.Error(t, err)
+}
+
+func TestParse(t *testing.T) {
+	buf := &bytes.Buffer{}
+	z := zip.NewWriter(buf)
+	w, err := z.Create("classes.dex")
+	require.NoError(t, err)
+	w.Write([]byte("dex"))
+	w, err = z.Create(manifestName)
+	require.NoError(t, err)
+	w.Write(testManifest())
+	require.NoError(t, z.Close())
+
+	m, err := ParseBytes(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "com.example.app", m.Package)
+	assert.Equal(t, 12, m.VersionCode)
+
+	buf.Reset()
+	z = zip.NewWriter(buf)
+	z.Create("classes.dex")
+	require.NoError(t, z.Close())
+	_, err = ParseBytes(buf.Bytes())
+	assert.Equal(t, ErrNoManifest, err)
+
+	_, err = ParseBytes([]byte("not a zip"))
+	assert.Error(t, err)
+}
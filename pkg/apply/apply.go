@@ -0,0 +1,485 @@
+// Package apply creates or updates resources of the declarative bundle.
+// All resources are resolved and compared before the first write,
+// so invalid references don't leave the bundle half applied.
+package apply
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+
+	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/apply"
+	"github.com/bearded-web/bearded/models/discovery"
+	"github.com/bearded-web/bearded/models/plan"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+type Action string
+
+const (
+	ActionCreate    = Action("create")
+	ActionUpdate    = Action("update")
+	ActionUnchanged = Action("unchanged")
+)
+
+// Change describes what is done with the bundle resource
+type Change struct {
+	Kind   apply.Kind    `json:"kind" description:"one of [plan|project|target|schedule|webhook]"`
+	Name   string        `json:"name"`
+	Action Action        `json:"action" description:"one of [create|update|unchanged]"`
+	Fields []string      `json:"fields,omitempty" description:"changed fields of updated resource"`
+	Id     bson.ObjectId `json:"id,omitempty" description:"id of the resource, empty for created resources in dry run"`
+
+	save func() (bson.ObjectId, error)
+}
+
+type Result struct {
+	DryRun  bool      `json:"dryRun"`
+	Changes []*Change `json:"changes"`
+}
+
+// Apply creates or updates bundle resources on behalf of the user. In dry run only changes are returned.
+// validate.Errors is returned if the bundle references unknown or foreign resources,
+// manager.QuotaError is returned if the project has max targets already.
+func Apply(mgr *manager.Manager, u *user.User, b *apply.Bundle, dryRun bool) (*Result, error) {
+	a := &applier{
+		mgr:      mgr,
+		user:     u,
+		plans:    map[string]*plan.Plan{},
+		projects: map[string]*project.Project{},
+		targets:  map[string]*target.Target{},
+	}
+	steps := []func(*apply.Bundle) error{a.diffPlans, a.diffProjects, a.diffTargets, a.diffSchedules, a.diffWebhooks}
+	for _, step := range steps {
+		if err := step(b); err != nil {
+			return nil, err
+		}
+	}
+	if err := a.errs.Err(); err != nil {
+		return nil, err
+	}
+	result := &Result{DryRun: dryRun, Changes: a.changes}
+	if dryRun {
+		return result, nil
+	}
+	for _, c := range a.changes {
+		if c.Action == ActionUnchanged {
+			continue
+		}
+		id, err := c.save()
+		if err != nil {
+			return nil, err
+		}
+		c.Id = id
+	}
+	if b.Name != "" {
+		st := &apply.State{Name: b.Name, Owner: u.Id, Bundle: b, Resources: []*apply.Resource{}}
+		for _, c := range a.changes {
+			st.Resources = append(st.Resources, &apply.Resource{Kind: c.Kind, Name: c.Name, Id: c.Id})
+		}
+		if err := mgr.Applied.Save(st); err != nil {
+			return nil, stackerr.Wrap(err)
+		}
+	}
+	return result, nil
+}
+
+// Drift compares resources of the applied bundle with the current ones.
+// Resources which would be updated by applying the bundle again are drifted, which would be created are missing.
+func Drift(mgr *manager.Manager, u *user.User, st *apply.State) (*apply.StateReport, error) {
+	result, err := Apply(mgr, u, st.Bundle, true)
+	if err != nil {
+		return nil, err
+	}
+	ids := map[apply.Resource]bson.ObjectId{}
+	for _, r := range st.Resources {
+		ids[apply.Resource{Kind: r.Kind, Name: r.Name}] = r.Id
+	}
+	report := &apply.StateReport{
+		Name:      st.Name,
+		Applied:   st.Applied,
+		Resources: []*apply.ResourceState{},
+	}
+	for _, c := range result.Changes {
+		rs := &apply.ResourceState{
+			Resource: apply.Resource{Kind: c.Kind, Name: c.Name, Id: c.Id},
+			Status:   apply.StatusInSync,
+		}
+		switch c.Action {
+		case ActionUpdate:
+			rs.Status = apply.StatusDrifted
+			rs.Fields = c.Fields
+		case ActionCreate:
+			rs.Status = apply.StatusMissing
+			rs.Id = ids[apply.Resource{Kind: c.Kind, Name: c.Name}]
+		}
+		if rs.Status != apply.StatusInSync {
+			report.Drifted = true
+		}
+		report.Resources = append(report.Resources, rs)
+	}
+	return report, nil
+}
+
+type applier struct {
+	mgr  *manager.Manager
+	user *user.User
+
+	// resolved resources by names, new ones don't have ids until they are saved
+	plans    map[string]*plan.Plan
+	projects map[string]*project.Project
+	targets  map[string]*target.Target
+
+	changes []*Change
+	errs    validate.Errors
+}
+
+func (a *applier) add(c *Change) {
+	if c.Action == ActionUpdate && len(c.Fields) == 0 {
+		c.Action = ActionUnchanged
+	}
+	a.changes = append(a.changes, c)
+}
+
+// compare adds the field to changed ones if values differ and copies the value to dst
+func (c *Change) compare(field string, dst, value interface{}) {
+	d := reflect.ValueOf(dst).Elem()
+	v := reflect.ValueOf(value)
+	if reflect.DeepEqual(d.Interface(), v.Interface()) {
+		return
+	}
+	c.Fields = append(c.Fields, field)
+	d.Set(v)
+}
+
+func (a *applier) diffPlans(b *apply.Bundle) error {
+	for n, d := range b.Plans {
+		for i, step := range d.Workflow {
+			if _, err := a.mgr.Plugins.GetByName(step.Plugin); err != nil {
+				if !a.mgr.IsNotFound(err) {
+					return stackerr.Wrap(err)
+				}
+				a.errs.Add(fmt.Sprintf("plans.%d.workflow.%d.plugin", n, i), validate.CodeInvalid, "plugin %s is not found", step.Plugin)
+			}
+		}
+		obj, err := a.mgr.Plans.GetByName(d.Name)
+		c := &Change{Kind: apply.KindPlan, Name: d.Name, Action: ActionUpdate}
+		switch {
+		case err == nil:
+			c.Id = obj.Id
+			c.compare("desc", &obj.Desc, d.Desc)
+			c.compare("targetType", &obj.TargetType, d.TargetType)
+			c.compare("workflow", &obj.Workflow, d.Workflow)
+			c.save = func() (bson.ObjectId, error) {
+				return obj.Id, a.mgr.Plans.Update(obj)
+			}
+		case a.mgr.IsNotFound(err):
+			obj = d.Plan()
+			c.Action = ActionCreate
+			c.save = func() (bson.ObjectId, error) {
+				_, err := a.mgr.Plans.Create(obj)
+				return obj.Id, err
+			}
+		default:
+			return stackerr.Wrap(err)
+		}
+		a.plans[d.Name] = obj
+		a.add(c)
+	}
+	return nil
+}
+
+func (a *applier) diffProjects(b *apply.Bundle) error {
+	for _, spec := range b.Projects {
+		obj, err := a.project(spec.Name)
+		if err != nil {
+			return err
+		}
+		c := &Change{Kind: apply.KindProject, Name: spec.Name, Action: ActionUpdate}
+		if obj == nil {
+			obj = &project.Project{Name: spec.Name, Owner: a.user.Id}
+			c.Action = ActionCreate
+			c.save = func() (bson.ObjectId, error) {
+				_, err := a.mgr.Projects.Create(obj)
+				return obj.Id, err
+			}
+		} else {
+			c.Id = obj.Id
+			c.save = func() (bson.ObjectId, error) {
+				return obj.Id, a.mgr.Projects.Update(obj)
+			}
+		}
+		c.compare("requireReview", &obj.RequireReview, spec.RequireReview)
+		c.compare("rateLimit", &obj.RateLimit, spec.RateLimit.WithDefaults(nil))
+		a.projects[spec.Name] = obj
+		a.add(c)
+	}
+	return nil
+}
+
+// project returns the project of the bundle or the existed project owned by the user, nil if it isn't found
+func (a *applier) project(name string) (*project.Project, error) {
+	if p, ok := a.projects[name]; ok {
+		return p, nil
+	}
+	projects, _, err := a.mgr.Projects.FilterByQuery(bson.M{"name": name, "owner": a.user.Id})
+	if err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	if len(projects) == 0 {
+		return nil, nil
+	}
+	a.projects[name] = projects[0]
+	return projects[0], nil
+}
+
+func (a *applier) diffTargets(b *apply.Bundle) error {
+	// quotas are checked before saving, so the bundle isn't applied partly
+	created := map[*project.Project]int{}
+	projects := []*project.Project{}
+	for i, spec := range b.Targets {
+		p, err := a.project(spec.Project)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			a.errs.Add(fmt.Sprintf("targets.%d.project", i), validate.CodeInvalid, "project %s is not found", spec.Project)
+			continue
+		}
+		addr := spec.Addr()
+		if spec.Type == target.TypeWeb {
+			addr = normDomain(addr)
+		}
+		obj, err := a.target(p, addr)
+		if err != nil {
+			return err
+		}
+		if obj != nil && obj.Type != spec.Type {
+			a.errs.Add(fmt.Sprintf("targets.%d.type", i), validate.CodeInvalid, "target %s has type %s", addr, obj.Type)
+			continue
+		}
+		c := &Change{Kind: apply.KindTarget, Name: spec.Project + "/" + addr, Action: ActionUpdate}
+		if obj == nil {
+			obj = &target.Target{Type: spec.Type}
+			switch spec.Type {
+			case target.TypeWeb:
+				obj.Web = &target.WebTarget{Domain: addr}
+			case target.TypeRepo:
+				obj.Repo = &target.RepoTarget{Url: addr}
+			}
+			c.Action = ActionCreate
+			c.save = func() (bson.ObjectId, error) {
+				obj.Project = p.Id
+				_, err := a.mgr.Targets.Create(obj)
+				return obj.Id, err
+			}
+			if created[p] == 0 {
+				projects = append(projects, p)
+			}
+			created[p]++
+		} else {
+			c.Id = obj.Id
+			c.save = func() (bson.ObjectId, error) {
+				return obj.Id, a.updateTarget(obj, c.Fields)
+			}
+		}
+		if spec.Type == target.TypeRepo {
+			c.compare("repo.name", &obj.Repo.Name, spec.Repo.Name)
+			c.compare("repo.branch", &obj.Repo.Branch, spec.Repo.Branch)
+			c.compare("repo.credentials", &obj.Repo.Credentials, spec.Repo.Credentials)
+		}
+		c.compare("criticality", &obj.Criticality, spec.Criticality)
+		c.compare("environment", &obj.Environment, spec.Environment)
+		c.compare("rateLimit", &obj.RateLimit, spec.RateLimit.WithDefaults(nil))
+		a.targets[targetKey(spec.Project, addr)] = obj
+		a.add(c)
+	}
+	for _, p := range projects {
+		if err := a.mgr.Targets.CheckAdd(p, created[p]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateTarget saves the target and updates denormalized fields of its issues like the target service does
+func (a *applier) updateTarget(obj *target.Target, fields []string) error {
+	if err := a.mgr.Targets.Update(obj); err != nil {
+		return err
+	}
+	for _, f := range fields {
+		switch f {
+		case "environment":
+			if err := a.mgr.Issues.UpdateEnvironment(obj); err != nil {
+				return err
+			}
+		case "criticality":
+			if err := a.mgr.Issues.UpdateRisk(obj); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// target returns the target of the bundle or the existed project target with the address, nil if it isn't found
+func (a *applier) target(p *project.Project, addr string) (*target.Target, error) {
+	if t, ok := a.targets[targetKey(p.Name, addr)]; ok {
+		return t, nil
+	}
+	if p.Id == "" {
+		return nil, nil
+	}
+	targets, _, err := a.mgr.Targets.FilterByQuery(bson.M{
+		"project": p.Id,
+		"$or": []bson.M{
+			{"web.domain": addr},
+			{"repo.url": addr},
+		},
+	})
+	if err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	return targets[0], nil
+}
+
+func (a *applier) diffSchedules(b *apply.Bundle) error {
+	for i, spec := range b.Schedules {
+		field := fmt.Sprintf("schedules.%d", i)
+		p, err := a.project(spec.Project)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			a.errs.Add(field+".project", validate.CodeInvalid, "project %s is not found", spec.Project)
+			continue
+		}
+		t, err := a.target(p, normDomain(spec.Target))
+		if err != nil {
+			return err
+		}
+		if t == nil || t.Type != target.TypeWeb {
+			a.errs.Add(field+".target", validate.CodeInvalid, "web target %s is not found in the project", spec.Target)
+			continue
+		}
+		pl, err := a.plan(spec.Plan)
+		if err != nil {
+			return err
+		}
+		if pl == nil || pl.TargetType != target.TypeWeb {
+			a.errs.Add(field+".plan", validate.CodeInvalid, "web plan %s is not found", spec.Plan)
+			continue
+		}
+		obj, err := a.discovery(t, pl)
+		if err != nil {
+			return err
+		}
+		c := &Change{Kind: apply.KindSchedule, Name: fmt.Sprintf("%s/%s/%s", spec.Project, spec.Target, spec.Plan), Action: ActionUpdate}
+		if obj == nil {
+			obj = &discovery.Discovery{Owner: a.user.Id}
+			c.Action = ActionCreate
+			c.save = func() (bson.ObjectId, error) {
+				obj.Project = p.Id
+				obj.Target = t.Id
+				obj.Plan = pl.Id
+				_, err := a.mgr.Discovery.Create(obj)
+				return obj.Id, err
+			}
+		} else {
+			c.Id = obj.Id
+			c.save = func() (bson.ObjectId, error) {
+				return obj.Id, a.mgr.Discovery.Update(obj)
+			}
+		}
+		c.compare("interval", &obj.Interval, spec.Interval)
+		c.compare("at", &obj.At, spec.At)
+		c.compare("timezone", &obj.Timezone, spec.Timezone)
+		c.compare("enabled", &obj.Enabled, spec.Enabled)
+		a.add(c)
+	}
+	return nil
+}
+
+// diffWebhooks sets notification channels of projects, they are saved with the project
+func (a *applier) diffWebhooks(b *apply.Bundle) error {
+	for i, spec := range b.Webhooks {
+		p, err := a.project(spec.Project)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			a.errs.Add(fmt.Sprintf("webhooks.%d.project", i), validate.CodeInvalid, "project %s is not found", spec.Project)
+			continue
+		}
+		c := &Change{Kind: apply.KindWebhook, Name: fmt.Sprintf("%s/%s", spec.Project, spec.Type), Action: ActionUpdate, Id: p.Id}
+		hook := spec.MsTeams()
+		if p.MsTeams == nil {
+			p.MsTeams = hook
+			c.Action = ActionCreate
+		} else {
+			c.compare("enabled", &p.MsTeams.Enabled, hook.Enabled)
+			c.compare("url", &p.MsTeams.Url, hook.Url)
+			c.compare("events", &p.MsTeams.Events, hook.Events)
+			c.compare("environments", &p.MsTeams.Environments, hook.Environments)
+		}
+		// projects created by the bundle are saved by previous changes and have ids here
+		c.save = func() (bson.ObjectId, error) {
+			return p.Id, a.mgr.Projects.Update(p)
+		}
+		a.add(c)
+	}
+	return nil
+}
+
+// plan returns the plan of the bundle or the existed plan, nil if it isn't found
+func (a *applier) plan(name string) (*plan.Plan, error) {
+	if pl, ok := a.plans[name]; ok {
+		return pl, nil
+	}
+	pl, err := a.mgr.Plans.GetByName(name)
+	if err != nil {
+		if a.mgr.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, stackerr.Wrap(err)
+	}
+	a.plans[name] = pl
+	return pl, nil
+}
+
+// discovery returns the existed discovery of the target with the plan, nil if it isn't found
+func (a *applier) discovery(t *target.Target, pl *plan.Plan) (*discovery.Discovery, error) {
+	if t.Id == "" || pl.Id == "" {
+		return nil, nil
+	}
+	results, _, err := a.mgr.Discovery.FilterByQuery(bson.M{"target": t.Id, "plan": pl.Id})
+	if err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	if len(results) == 0 {
+		return nil, nil
+	}
+	return results[0], nil
+}
+
+func targetKey(projectName, addr string) string {
+	return projectName + " " + addr
+}
+
+// normDomain returns the web address in the form which is stored by the target service
+func normDomain(addr string) string {
+	if u, err := url.Parse(addr); err == nil {
+		return u.String()
+	}
+	return addr
+}
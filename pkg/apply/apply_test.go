@@ -0,0 +1,77 @@
+package apply
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bearded-web/bearded/models/apply"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/managertest"
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+func TestChangeCompare(t *testing.T) {
+	obj := &target.Target{Criticality: target.CriticalityHigh, RateLimit: &target.RateLimit{Connections: 2}}
+	c := &Change{Action: ActionUpdate}
+	c.compare("criticality", &obj.Criticality, target.CriticalityHigh)
+	c.compare("rateLimit", &obj.RateLimit, (&target.RateLimit{Connections: 2}).WithDefaults(nil))
+	assert.Empty(t, c.Fields)
+
+	c.compare("environment", &obj.Environment, target.EnvironmentProd)
+	c.compare("rateLimit", &obj.RateLimit, (*target.RateLimit)(nil))
+	assert.Equal(t, []string{"environment", "rateLimit"}, c.Fields)
+	assert.Equal(t, target.EnvironmentProd, obj.Environment)
+	assert.Nil(t, obj.RateLimit)
+}
+
+func TestNormDomain(t *testing.T) {
+	assert.Equal(t, "https://example.com/path", normDomain("https://example.com/path"))
+	assert.Equal(t, "https://example.com/a%20b", normDomain("https://example.com/a b"))
+}
+
+func TestApplyWebhooks(t *testing.T) {
+	env := managertest.Open(t)
+	defer env.Close()
+	u, err := env.Login(&user.User{Email: "owner@example.com"})
+	require.NoError(t, err)
+
+	b := &apply.Bundle{
+		Version:  apply.BundleVersion,
+		Projects: []*apply.Project{{Name: "Shop"}},
+		Webhooks: []*apply.Webhook{{
+			Project: "Shop",
+			Type:    apply.WebhookMsTeams,
+			Enabled: true,
+			Url:     "https://example.webhook.office.com/webhookb2/1",
+		}},
+	}
+	result, err := Apply(env.Mgr, u, b, false)
+	require.NoError(t, err)
+	require.Len(t, result.Changes, 2)
+	assert.Equal(t, ActionCreate, result.Changes[1].Action)
+	assert.Equal(t, result.Changes[0].Id, result.Changes[1].Id)
+
+	p, err := env.Mgr.Projects.GetById(result.Changes[0].Id)
+	require.NoError(t, err)
+	require.NotNil(t, p.MsTeams)
+	assert.Equal(t, b.Webhooks[0].Url, p.MsTeams.Url)
+
+	result, err = Apply(env.Mgr, u, b, true)
+	require.NoError(t, err)
+	assert.Equal(t, ActionUnchanged, result.Changes[1].Action)
+
+	b.Webhooks[0].Enabled = false
+	result, err = Apply(env.Mgr, u, b, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"enabled"}, result.Changes[1].Fields)
+	p, err = env.Mgr.Projects.GetById(p.Id)
+	require.NoError(t, err)
+	assert.False(t, p.MsTeams.Enabled)
+
+	b.Webhooks[0].Project = "Unknown"
+	_, err = Apply(env.Mgr, u, b, true)
+	assert.IsType(t, validate.Errors{}, err)
+}
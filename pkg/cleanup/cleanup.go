@@ -0,0 +1,72 @@
+// Package cleanup runs cascade jobs which remove issues and scans of removed targets and projects,
+// and purges expired issues from the trash.
+package cleanup
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/facebookgo/stackerr"
+	"golang.org/x/net/context"
+
+	"github.com/bearded-web/bearded/pkg/manager"
+)
+
+type Engine struct {
+	mgr *manager.Manager
+}
+
+func New(mgr *manager.Manager) *Engine {
+	return &Engine{
+		mgr: mgr,
+	}
+}
+
+// Run processes new cascade jobs every interval until the context is done.
+// It's safe to run it on every api instance, jobs are taken atomically one by one.
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	logrus.Infof("Cleanup engine is started, check interval %s", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+			if err := e.Check(ctx); err != nil {
+				logrus.Error(err)
+			}
+		}
+	}
+}
+
+// Check purges expired issues from the trash and processes jobs one by one while there are pending ones
+func (e *Engine) Check(ctx context.Context) error {
+	mgr := e.mgr.Copy()
+	defer mgr.Close()
+
+	purged, err := mgr.Issues.PurgeExpired(time.Now().UTC())
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	if purged > 0 {
+		logrus.Infof("%d expired issues are purged from the trash", purged)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		job, err := mgr.Cascades.Take(time.Now().UTC())
+		if err != nil {
+			if mgr.IsNotFound(err) {
+				return nil
+			}
+			return stackerr.Wrap(err)
+		}
+		logrus.Infof("Cascade removal of %s %s, %d children", job.Kind, job.Entity.Hex(), job.Total)
+		if err := mgr.Cascades.Process(job); err != nil {
+			logrus.Errorf("Cascade removal of %s %s is failed: %s", job.Kind, job.Entity.Hex(), err)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package client
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+
+	"github.com/bearded-web/bearded/models/integrity"
+)
+
+const adminUrl = "admin"
+
+type AdminService struct {
+	client *Client
+}
+
+func (s *AdminService) String() string {
+	return Stringify(s)
+}
+
+// Integrity finds objects with dangling references, the report keeps at most limit problems of every kind
+func (s *AdminService) Integrity(ctx context.Context, limit int) (*integrity.Report, error) {
+	report := &integrity.Report{}
+	return report, s.client.Get(ctx, adminUrl, fmt.Sprintf("integrity?limit=%d", limit), report)
+}
+
+// Repair deletes or reattaches all objects with dangling references of the kind
+func (s *AdminService) Repair(ctx context.Context, src *integrity.Repair) (*integrity.Report, error) {
+	report := &integrity.Report{}
+	return report, s.client.Create(ctx, adminUrl+"/integrity/repair", src, report)
+}
@@ -10,6 +10,7 @@ import (
 const (
 	agentsUrl     = "agents"
 	agentsJobsUrl = "jobs"
+	agentsImages  = "images"
 )
 
 type AgentsService struct {
@@ -21,6 +22,7 @@ func (s *AgentsService) String() string {
 }
 
 type AgentsListOpts struct {
+	ListOpts
 	Name string     `url:"name"`
 	Type agent.Type `url:"type"`
 }
@@ -33,6 +35,15 @@ func (s *AgentsService) List(ctx context.Context, opt *AgentsListOpts) (*agent.A
 	return agentList, s.client.List(ctx, agentsUrl, opt, agentList)
 }
 
+// Iter calls fn for every agent from all pages, iteration stops on the first error
+func (s *AgentsService) Iter(ctx context.Context, opt *AgentsListOpts, fn func(*agent.Agent) error) error {
+	o := AgentsListOpts{}
+	if opt != nil {
+		o = *opt
+	}
+	return iterate(&o.ListOpts, func() (interface{}, error) { return s.List(ctx, &o) }, fn)
+}
+
 func (s *AgentsService) Get(ctx context.Context, id string) (*agent.Agent, error) {
 	agent := &agent.Agent{}
 	return agent, s.client.Get(ctx, agentsUrl, id, agent)
@@ -49,8 +60,19 @@ func (s *AgentsService) Update(ctx context.Context, src *agent.Agent) (*agent.Ag
 	return pl, s.client.Update(ctx, agentsUrl, id, src, pl)
 }
 
-func (s *AgentsService) GetJobs(ctx context.Context, src *agent.Agent) ([]*agent.Job, error) {
+func (s *AgentsService) Delete(ctx context.Context, id string) error {
+	return s.client.Delete(ctx, agentsUrl, id)
+}
+
+// GetJobs asks for jobs, the load helps the scheduler to pick the least loaded agent
+func (s *AgentsService) GetJobs(ctx context.Context, src *agent.Agent, load *agent.Load) ([]*agent.Job, error) {
 	jobs := []*agent.Job{}
 	url := fmt.Sprintf("%s/%s/%s", agentsUrl, FromId(src.Id), agentsJobsUrl)
-	return jobs, s.client.List(ctx, url, nil, &jobs)
+	return jobs, s.client.List(ctx, url, load, &jobs)
+}
+
+// ReportImages sends the state of images in the docker cache of the agent
+func (s *AgentsService) ReportImages(ctx context.Context, src *agent.Agent, images []*agent.Image) (*agent.ImageCache, error) {
+	cache := &agent.ImageCache{}
+	return cache, s.client.Update(ctx, agentsUrl, fmt.Sprintf("%s/%s", FromId(src.Id), agentsImages), images, cache)
 }
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"golang.org/x/net/context"
+)
+
+const authUrl = "auth"
+
+// AuthService works with session authentication,
+// http client must have a cookie jar to keep the session.
+type AuthService struct {
+	client *Client
+}
+
+func (s *AuthService) String() string {
+	return Stringify(s)
+}
+
+type authEntity struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// Login authenticates user by email and password and stores session in cookies
+func (s *AuthService) Login(ctx context.Context, email, password string) error {
+	send := &authEntity{Email: email, Password: password}
+	return s.client.Create(ctx, authUrl, send, nil)
+}
+
+// Logout removes the current session
+func (s *AuthService) Logout(ctx context.Context) error {
+	req, err := s.client.NewRequest("DELETE", authUrl, nil)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Do(ctx, req, nil)
+	return err
+}
@@ -12,12 +12,15 @@ import (
 	"net/http"
 	"net/url"
 	"reflect"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/facebookgo/stackerr"
 	"github.com/google/go-querystring/query"
 	"golang.org/x/net/context"
 	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/pkg/validate"
 )
 
 const (
@@ -26,6 +29,9 @@ const (
 	defaultBaseURL = "http://127.0.0.1:3003/api/"
 	mediaTypeV1    = "application/json"
 	apiVersion     = 1
+
+	defaultMaxRetries = 3
+	defaultRetryWait  = time.Millisecond * 500
 )
 
 // A Client manages communication with the Bearded API.
@@ -46,13 +52,25 @@ type Client struct {
 	// Show different debug information
 	Debug bool
 
+	// Number of retries for idempotent requests failed with a network error or 5xx status,
+	// zero disables retrying
+	MaxRetries int
+
+	// Wait before the first retry, it's doubled after every next attempt
+	RetryWait time.Duration
+
 	// Services used for talking to different parts of the Bearded API.
-	Plugins *PluginsService
-	Plans   *PlansService
-	Agents  *AgentsService
-	Scans   *ScansService
-	Files   *FilesService
-	Tokens  *TokensService
+	Plugins  *PluginsService
+	Plans    *PlansService
+	Agents   *AgentsService
+	Scans    *ScansService
+	Files    *FilesService
+	Tokens   *TokensService
+	Issues   *IssuesService
+	Targets  *TargetsService
+	Projects *ProjectsService
+	Auth     *AuthService
+	Admin    *AdminService
 }
 
 // NewClient returns a new Bearded API client. If a nil httpClient is
@@ -68,13 +86,31 @@ func NewClient(baseUrl string, httpClient *http.Client) *Client {
 	}
 	baseURL, _ := url.Parse(baseUrl)
 
-	c := &Client{client: httpClient, BaseURL: baseURL, UserAgent: userAgent}
+	c := &Client{
+		client:     httpClient,
+		BaseURL:    baseURL,
+		UserAgent:  userAgent,
+		MaxRetries: defaultMaxRetries,
+		RetryWait:  defaultRetryWait,
+	}
 	c.Plugins = &PluginsService{client: c}
 	c.Plans = &PlansService{client: c}
 	c.Agents = &AgentsService{client: c}
 	c.Scans = &ScansService{client: c}
 	c.Files = &FilesService{client: c}
 	c.Tokens = &TokensService{client: c}
+	c.Issues = &IssuesService{client: c}
+	c.Targets = &TargetsService{client: c}
+	c.Projects = &ProjectsService{client: c}
+	c.Auth = &AuthService{client: c}
+	c.Admin = &AdminService{client: c}
+	return c
+}
+
+// NewTokenClient returns a new Bearded API client authenticated with the api token
+func NewTokenClient(baseUrl string, token string, httpClient *http.Client) *Client {
+	c := NewClient(baseUrl, httpClient)
+	c.Token = token
 	return c
 }
 
@@ -175,6 +211,62 @@ func (c *Client) getUrl(urlStr string) (string, error) {
 // interface, the raw response body will be written to v, without attempting to
 // first decode it.
 func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) (*http.Response, error) {
+	wait := c.RetryWait
+	attempt := 0
+	var (
+		resp *http.Response
+		err  error
+	)
+	for {
+		resp, err = c.send(ctx, req)
+		if attempt >= c.MaxRetries || ctx.Err() != nil || !shouldRetry(req, resp, err) {
+			break
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		attempt++
+		if req.GetBody != nil {
+			body, bErr := req.GetBody()
+			if bErr != nil {
+				return nil, bErr
+			}
+			req.Body = body
+		}
+		if c.Debug {
+			logrus.Debugf("Retry %s %s in %s", req.Method, req.URL, wait)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, stackerr.Wrap(ctx.Err())
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	err = CheckResponse(resp)
+	if err != nil {
+		// even though there was an error, we still return the response
+		// in case the caller wants to inspect it further
+		return resp, err
+	}
+
+	if v != nil {
+		if w, ok := v.(io.Writer); ok {
+			_, err = io.Copy(w, resp.Body)
+		} else {
+			err = json.NewDecoder(resp.Body).Decode(v)
+		}
+	}
+	return resp, err
+}
+
+// send makes one attempt to send the request
+func (c *Client) send(ctx context.Context, req *http.Request) (*http.Response, error) {
 	var resp *http.Response
 	ret := make(chan error, 1)
 	go func() {
@@ -205,23 +297,24 @@ func (c *Client) Do(ctx context.Context, req *http.Request, v interface{}) (*htt
 			return nil, err
 		}
 	}
-	defer resp.Body.Close()
+	return resp, nil
+}
 
-	err := CheckResponse(resp)
-	if err != nil {
-		// even though there was an error, we still return the response
-		// in case the caller wants to inspect it further
-		return resp, err
+// shouldRetry reports whether the failed request could be safely sent again
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	switch req.Method {
+	case "GET", "HEAD", "PUT", "DELETE":
+	default:
+		return false
 	}
-
-	if v != nil {
-		if w, ok := v.(io.Writer); ok {
-			io.Copy(w, resp.Body)
-		} else {
-			err = json.NewDecoder(resp.Body).Decode(v)
-		}
+	// the body is already consumed and can't be sent twice
+	if req.Body != nil && req.GetBody == nil {
+		return false
 	}
-	return resp, err
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
 }
 
 // Helper method to get a list of payload objects
@@ -282,27 +375,36 @@ func (c *Client) Delete(ctx context.Context, url string, id string) error {
 }
 
 func (c *Client) Upload(ctx context.Context, urlStr string, files []*UploadedFile, payload interface{}) error {
+	req, err := c.NewUploadRequest(urlStr, files)
+	if err != nil {
+		return err
+	}
+	_, err = c.Do(ctx, req, payload)
+	return err
+}
+
+// NewUploadRequest creates a POST request with files in multipart form
+func (c *Client) NewUploadRequest(urlStr string, files []*UploadedFile) (*http.Request, error) {
 	var b bytes.Buffer
 	w := multipart.NewWriter(&b)
 	for _, ufile := range files {
 		dst, err := w.CreateFormFile(ufile.Fieldname, ufile.Filename)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if _, err = io.Copy(dst, ufile.Data); err != nil {
-			return err
+			return nil, err
 		}
 	}
 	if err := w.Close(); err != nil {
-		return err
+		return nil, err
 	}
 	req, err := c.NewRequest("POST", urlStr, &b)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	req.Header.Set("Content-Type", w.FormDataContentType())
-	_, err = c.Do(ctx, req, payload)
-	return err
+	return req, nil
 }
 
 // convert string id to ObjectId
@@ -358,6 +460,7 @@ func (r *ErrorResponse) Error() string {
 type ServiceError struct {
 	Code    int
 	Message string
+	Fields  validate.Errors `json:",omitempty"`
 }
 
 // NewError returns a ServiceError using the code and reason
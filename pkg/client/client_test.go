@@ -0,0 +1,151 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/pkg/utils"
+)
+
+func TestClientRetry(t *testing.T) {
+	bg := context.Background()
+	calls := 0
+	handlerMock := func(res http.ResponseWriter, req *http.Request) {
+		calls++
+		if calls < 3 {
+			http.Error(res, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		assert.Equal(t, "Bearer token", req.Header.Get("Authorization"))
+		res.Write([]byte(`{"id": "5530b4d1cd53d90ce4000001"}`))
+	}
+	s := httptest.NewServer(http.HandlerFunc(handlerMock))
+	defer s.Close()
+
+	client := NewTokenClient(s.URL+"/", "token", nil)
+	client.RetryWait = time.Millisecond
+
+	obj, err := client.Issues.Get(bg, "5530b4d1cd53d90ce4000001")
+	require.NoError(t, err)
+	assert.Equal(t, "5530b4d1cd53d90ce4000001", FromId(obj.Id))
+	assert.Equal(t, 3, calls)
+
+	// post requests aren't retried
+	calls = 0
+	_, err = client.Issues.Create(bg, &issue.TargetIssue{})
+	require.Error(t, err)
+	assert.Equal(t, 1, calls)
+}
+
+func TestIssuesIter(t *testing.T) {
+	bg := context.Background()
+	total := 5
+	handlerMock := func(res http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "/v1/issues", req.URL.Path)
+		var skip, limit int
+		fmt.Sscan(req.URL.Query().Get("skip"), &skip)
+		fmt.Sscan(req.URL.Query().Get("limit"), &limit)
+		assert.Equal(t, "high", req.URL.Query().Get("severity"))
+		results := []string{}
+		for i := skip; i < skip+limit && i < total; i++ {
+			results = append(results, fmt.Sprintf(`{"summary": "issue %d"}`, i))
+		}
+		fmt.Fprintf(res, `{"count": %d, "results": [%s]}`, total, strings.Join(results, ","))
+	}
+	s := httptest.NewServer(http.HandlerFunc(handlerMock))
+	defer s.Close()
+	baseUrl, _ := url.Parse(s.URL)
+
+	client := NewClient(baseUrl.String()+"/", nil)
+	opt := &IssuesListOpts{Severity: issue.SeverityHigh}
+	opt.Limit = 2
+
+	summaries := []string{}
+	err := client.Issues.Iter(bg, opt, func(obj *issue.TargetIssue) error {
+		summaries = append(summaries, obj.Summary)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"issue 0", "issue 1", "issue 2", "issue 3", "issue 4"}, summaries)
+	// original options must stay untouched
+	assert.Equal(t, 0, opt.Skip)
+}
+
+func TestProjectsIter(t *testing.T) {
+	bg := context.Background()
+	handlerMock := func(res http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "/v1/projects", req.URL.Path)
+		assert.Equal(t, "5530b4d1cd53d90ce4000001", req.URL.Query().Get("member"))
+		if req.URL.Query().Get("skip") == "" {
+			fmt.Fprint(res, `{"count": 3, "results": [{"name": "a"}, {"name": "b"}]}`)
+			return
+		}
+		fmt.Fprint(res, `{"count": 3, "results": [{"name": "c"}]}`)
+	}
+	s := httptest.NewServer(http.HandlerFunc(handlerMock))
+	defer s.Close()
+
+	client := NewClient(s.URL+"/", nil)
+	opt := &ProjectsListOpts{Member: "5530b4d1cd53d90ce4000001"}
+
+	names := []string{}
+	err := client.Projects.Iter(bg, opt, func(obj *project.Project) error {
+		names = append(names, obj.Name)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, names)
+
+	// error of fn stops iteration
+	stop := fmt.Errorf("stop")
+	names = []string{}
+	err = client.Projects.Iter(bg, opt, func(obj *project.Project) error {
+		names = append(names, obj.Name)
+		return stop
+	})
+	assert.Equal(t, stop, err)
+	assert.Equal(t, []string{"a"}, names)
+}
+
+func TestScansWaitFor(t *testing.T) {
+	bg := context.Background()
+	statuses := []string{"queued", "working", "working", "finished"}
+	calls := 0
+	handlerMock := func(res http.ResponseWriter, req *http.Request) {
+		require.Equal(t, "/v1/scans/5530b4d1cd53d90ce4000001", req.URL.Path)
+		fmt.Fprintf(res, `{"id": "5530b4d1cd53d90ce4000001", "status": "%s"}`, statuses[calls])
+		calls++
+	}
+	s := httptest.NewServer(http.HandlerFunc(handlerMock))
+	defer s.Close()
+
+	client := NewClient(s.URL+"/", nil)
+	seen := []scan.ScanStatus{}
+	opt := &WaitOpts{
+		Interval: time.Millisecond,
+		Progress: func(obj *scan.Scan) { seen = append(seen, obj.Status) },
+	}
+	obj, err := client.Scans.WaitFor(bg, "5530b4d1cd53d90ce4000001", opt)
+	require.NoError(t, err)
+	assert.Equal(t, scan.StatusFinished, obj.Status)
+	assert.Len(t, seen, 4)
+
+	// timeout
+	calls = 0
+	statuses = []string{"working", "working", "working", "working"}
+	opt.Interval = time.Millisecond * 50
+	_, err = client.Scans.WaitFor(utils.JustTimeout(bg, time.Millisecond*75), "5530b4d1cd53d90ce4000001", opt)
+	require.Error(t, err)
+}
@@ -40,9 +40,14 @@ func (s *FilesService) Create(ctx context.Context, filename string, data io.Read
 
 func (s *FilesService) Download(ctx context.Context, id string) (io.Reader, error) {
 	buf := new(bytes.Buffer)
-	err := s.client.Get(ctx, fmt.Sprintf("%s/%s", filesUrl, id), "download", buf)
+	err := s.DownloadTo(ctx, id, buf)
 	if err != nil {
 		return nil, err
 	}
 	return buf, nil
 }
+
+// DownloadTo writes the file content to w while it's received, large files aren't kept in memory
+func (s *FilesService) DownloadTo(ctx context.Context, id string, w io.Writer) error {
+	return s.client.Get(ctx, fmt.Sprintf("%s/%s", filesUrl, id), "download", w)
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"fmt"
+
+	"golang.org/x/net/context"
+
+	"github.com/bearded-web/bearded/models/comment"
+	"github.com/bearded-web/bearded/models/issue"
+)
+
+const issuesUrl = "issues"
+
+type IssuesService struct {
+	client *Client
+}
+
+func (s *IssuesService) String() string {
+	return Stringify(s)
+}
+
+type IssuesListOpts struct {
+	ListOpts
+	Project   string         `url:"project,omitempty"`
+	Target    string         `url:"target,omitempty"`
+	Severity  issue.Severity `url:"severity,omitempty"`
+	Confirmed *bool          `url:"confirmed,omitempty"`
+	Muted     *bool          `url:"muted,omitempty"`
+	Resolved  *bool          `url:"resolved,omitempty"`
+	False     *bool          `url:"false,omitempty"`
+	Search    string         `url:"search,omitempty"`
+	Sort      string         `url:"sort,omitempty"`
+}
+
+// List issues.
+func (s *IssuesService) List(ctx context.Context, opt *IssuesListOpts) (*issue.TargetIssueList, error) {
+	issueList := &issue.TargetIssueList{}
+	return issueList, s.client.List(ctx, issuesUrl, opt, issueList)
+}
+
+// Iter calls fn for every issue from all pages, iteration stops on the first error
+func (s *IssuesService) Iter(ctx context.Context, opt *IssuesListOpts, fn func(*issue.TargetIssue) error) error {
+	o := IssuesListOpts{}
+	if opt != nil {
+		o = *opt
+	}
+	return iterate(&o.ListOpts, func() (interface{}, error) { return s.List(ctx, &o) }, fn)
+}
+
+func (s *IssuesService) Get(ctx context.Context, id string) (*issue.TargetIssue, error) {
+	obj := &issue.TargetIssue{}
+	return obj, s.client.Get(ctx, issuesUrl, id, obj)
+}
+
+func (s *IssuesService) Create(ctx context.Context, src *issue.TargetIssue) (*issue.TargetIssue, error) {
+	obj := &issue.TargetIssue{}
+	return obj, s.client.Create(ctx, issuesUrl, src, obj)
+}
+
+func (s *IssuesService) Update(ctx context.Context, src *issue.TargetIssue) (*issue.TargetIssue, error) {
+	obj := &issue.TargetIssue{}
+	id := FromId(src.Id)
+	return obj, s.client.Update(ctx, issuesUrl, id, src, obj)
+}
+
+func (s *IssuesService) Delete(ctx context.Context, id string) error {
+	return s.client.Delete(ctx, issuesUrl, id)
+}
+
+func (s *IssuesService) Comments(ctx context.Context, id string) (*comment.CommentList, error) {
+	list := &comment.CommentList{}
+	url := fmt.Sprintf("%s/%s/comments", issuesUrl, id)
+	return list, s.client.List(ctx, url, nil, list)
+}
+
+func (s *IssuesService) CommentsAdd(ctx context.Context, id string, text string) (*comment.Comment, error) {
+	obj := &comment.Comment{}
+	url := fmt.Sprintf("%s/%s/comments", issuesUrl, id)
+	send := map[string]string{"text": text}
+	return obj, s.client.Create(ctx, url, send, obj)
+}
@@ -0,0 +1,36 @@
+package client
+
+import (
+	"reflect"
+)
+
+// Common options for paginated lists
+type ListOpts struct {
+	Skip  int `url:"skip,omitempty"`
+	Limit int `url:"limit,omitempty"`
+}
+
+// iterate fetches pages until all objects are passed to fn, iteration stops on the first error.
+// list must fetch a page with the current opt and return a list with Count and Results fields,
+// fn must be func(T) error where T is the type of Results elements.
+func iterate(opt *ListOpts, list func() (interface{}, error), fn interface{}) error {
+	call := reflect.ValueOf(fn)
+	for {
+		page, err := list()
+		if err != nil {
+			return err
+		}
+		v := reflect.Indirect(reflect.ValueOf(page))
+		results := v.FieldByName("Results")
+		for i := 0; i < results.Len(); i++ {
+			if out := call.Call([]reflect.Value{results.Index(i)}); !out[0].IsNil() {
+				return out[0].Interface().(error)
+			}
+		}
+		n := results.Len()
+		opt.Skip += n
+		if n == 0 || opt.Skip >= int(v.FieldByName("Count").Int()) {
+			return nil
+		}
+	}
+}
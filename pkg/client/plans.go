@@ -18,6 +18,7 @@ func (s *PlansService) String() string {
 }
 
 type PlansListOpts struct {
+	ListOpts
 	Name string `url:"name"`
 }
 
@@ -29,6 +30,15 @@ func (s *PlansService) List(ctx context.Context, opt *PlansListOpts) (*plan.Plan
 	return planList, s.client.List(ctx, plansUrl, opt, planList)
 }
 
+// Iter calls fn for every plan from all pages, iteration stops on the first error
+func (s *PlansService) Iter(ctx context.Context, opt *PlansListOpts, fn func(*plan.Plan) error) error {
+	o := PlansListOpts{}
+	if opt != nil {
+		o = *opt
+	}
+	return iterate(&o.ListOpts, func() (interface{}, error) { return s.List(ctx, &o) }, fn)
+}
+
 func (s *PlansService) Get(ctx context.Context, id string) (*plan.Plan, error) {
 	plan := &plan.Plan{}
 	return plan, s.client.Get(ctx, plansUrl, id, plan)
@@ -44,3 +54,7 @@ func (s *PlansService) Update(ctx context.Context, src *plan.Plan) (*plan.Plan,
 	id := fmt.Sprintf("%x", string(src.Id))
 	return pl, s.client.Update(ctx, plansUrl, id, src, pl)
 }
+
+func (s *PlansService) Delete(ctx context.Context, id string) error {
+	return s.client.Delete(ctx, plansUrl, id)
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/bearded-web/bearded/models/project"
+)
+
+const projectsUrl = "projects"
+
+type ProjectsService struct {
+	client *Client
+}
+
+func (s *ProjectsService) String() string {
+	return Stringify(s)
+}
+
+type ProjectsListOpts struct {
+	ListOpts
+	Owner  string `url:"owner,omitempty"`
+	Member string `url:"member,omitempty"`
+	Sort   string `url:"sort,omitempty"`
+}
+
+// List projects.
+func (s *ProjectsService) List(ctx context.Context, opt *ProjectsListOpts) (*project.ProjectList, error) {
+	projectList := &project.ProjectList{}
+	return projectList, s.client.List(ctx, projectsUrl, opt, projectList)
+}
+
+// Iter calls fn for every project from all pages, iteration stops on the first error
+func (s *ProjectsService) Iter(ctx context.Context, opt *ProjectsListOpts, fn func(*project.Project) error) error {
+	o := ProjectsListOpts{}
+	if opt != nil {
+		o = *opt
+	}
+	return iterate(&o.ListOpts, func() (interface{}, error) { return s.List(ctx, &o) }, fn)
+}
+
+func (s *ProjectsService) Get(ctx context.Context, id string) (*project.Project, error) {
+	obj := &project.Project{}
+	return obj, s.client.Get(ctx, projectsUrl, id, obj)
+}
+
+func (s *ProjectsService) Create(ctx context.Context, src *project.Project) (*project.Project, error) {
+	obj := &project.Project{}
+	return obj, s.client.Create(ctx, projectsUrl, src, obj)
+}
+
+func (s *ProjectsService) Update(ctx context.Context, src *project.Project) (*project.Project, error) {
+	obj := &project.Project{}
+	id := FromId(src.Id)
+	return obj, s.client.Update(ctx, projectsUrl, id, src, obj)
+}
+
+func (s *ProjectsService) Delete(ctx context.Context, id string) error {
+	return s.client.Delete(ctx, projectsUrl, id)
+}
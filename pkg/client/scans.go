@@ -2,9 +2,13 @@ package client
 
 import (
 	"fmt"
+	"io"
+	"net/url"
+	"time"
 
 	"golang.org/x/net/context"
 
+	"github.com/bearded-web/bearded/models/file"
 	"github.com/bearded-web/bearded/models/report"
 	"github.com/bearded-web/bearded/models/scan"
 )
@@ -20,7 +24,11 @@ func (s *ScansService) String() string {
 }
 
 type ScansListOpts struct {
-	Name string `url:"name"`
+	ListOpts
+	Name    string          `url:"name"`
+	Project string          `url:"project,omitempty"`
+	Target  string          `url:"target,omitempty"`
+	Status  scan.ScanStatus `url:"status,omitempty"`
 }
 
 // List scans.
@@ -31,17 +39,79 @@ func (s *ScansService) List(ctx context.Context, opt *ScansListOpts) (*scan.Scan
 	return scanList, s.client.List(ctx, scansUrl, opt, scanList)
 }
 
+// Iter calls fn for every scan from all pages, iteration stops on the first error
+func (s *ScansService) Iter(ctx context.Context, opt *ScansListOpts, fn func(*scan.Scan) error) error {
+	o := ScansListOpts{}
+	if opt != nil {
+		o = *opt
+	}
+	return iterate(&o.ListOpts, func() (interface{}, error) { return s.List(ctx, &o) }, fn)
+}
+
 func (s *ScansService) Get(ctx context.Context, id string) (*scan.Scan, error) {
 	scan := &scan.Scan{}
 	return scan, s.client.Get(ctx, scansUrl, id, scan)
 }
 
+const defaultWaitInterval = time.Second * 5
+
+type WaitOpts struct {
+	// Interval between polls, 5 seconds by default
+	Interval time.Duration
+	// Progress is called with every received scan state
+	Progress func(*scan.Scan)
+}
+
+// WaitFor polls the scan until it's finished or failed.
+// Use context deadline to limit the waiting time.
+func (s *ScansService) WaitFor(ctx context.Context, id string, opt *WaitOpts) (*scan.Scan, error) {
+	interval := defaultWaitInterval
+	var progress func(*scan.Scan)
+	if opt != nil {
+		if opt.Interval > 0 {
+			interval = opt.Interval
+		}
+		progress = opt.Progress
+	}
+	for {
+		obj, err := s.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if progress != nil {
+			progress(obj)
+		}
+		if IsScanFinished(obj.Status) {
+			return obj, nil
+		}
+		select {
+		case <-ctx.Done():
+			return obj, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// return true if scan can't change status anymore
+func IsScanFinished(status scan.ScanStatus) bool {
+	return status == scan.StatusFinished || status == scan.StatusFailed
+}
+
+func (s *ScansService) Create(ctx context.Context, src *scan.Scan) (*scan.Scan, error) {
+	obj := &scan.Scan{}
+	return obj, s.client.Create(ctx, scansUrl, src, obj)
+}
+
 func (s *ScansService) Update(ctx context.Context, src *scan.Scan) (*scan.Scan, error) {
 	pl := &scan.Scan{}
 	id := FromId(src.Id)
 	return pl, s.client.Update(ctx, scansUrl, id, src, pl)
 }
 
+func (s *ScansService) Delete(ctx context.Context, id string) error {
+	return s.client.Delete(ctx, scansUrl, id)
+}
+
 func (s *ScansService) SessionUpdate(ctx context.Context, src *scan.Session) (*scan.Session, error) {
 	obj := &scan.Session{}
 	scanId := FromId(src.Scan)
@@ -82,3 +152,56 @@ func (s *ScansService) SessionReportGet(ctx context.Context, sc *scan.Session) (
 	return obj, s.client.Get(ctx, reportUrl, "report", obj)
 
 }
+
+// header with the session token, see services/scan.SessionTokenHeader
+const sessionTokenHeader = "X-Session-Token"
+
+type SessionScope struct {
+	Url     string `json:"url"`
+	InScope bool   `json:"inScope"`
+}
+
+// SessionProgress reports progress of the working session, the session token is taken from the job
+func (s *ScansService) SessionProgress(ctx context.Context, src *scan.Session, progress *scan.Progress) (*scan.Progress, error) {
+	obj := &scan.Progress{}
+	progressUrl := fmt.Sprintf("%s/%s/sessions/%s/progress", scansUrl, FromId(src.Scan), FromId(src.Id))
+	req, err := s.client.NewRequest("PUT", progressUrl, progress)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(sessionTokenHeader, src.Token)
+	_, err = s.client.Do(ctx, req, obj)
+	return obj, err
+}
+
+// SessionInScope asks whether the url is in scope of the session scan target
+func (s *ScansService) SessionInScope(ctx context.Context, src *scan.Session, u string) (bool, error) {
+	obj := &SessionScope{}
+	scopeUrl := fmt.Sprintf("%s/%s/sessions/%s/scope?url=%s", scansUrl, FromId(src.Scan), FromId(src.Id), url.QueryEscape(u))
+	req, err := s.client.NewRequest("GET", scopeUrl, nil)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set(sessionTokenHeader, src.Token)
+	_, err = s.client.Do(ctx, req, obj)
+	return obj.InScope, err
+}
+
+// SessionArtifactCreate uploads the plugin artifact to the session, the session token is taken from the job
+func (s *ScansService) SessionArtifactCreate(ctx context.Context, src *scan.Session, filename string, data io.Reader) (*file.Meta, error) {
+	meta := &file.Meta{}
+	artifactsUrl := fmt.Sprintf("%s/%s/sessions/%s/artifacts", scansUrl, FromId(src.Scan), FromId(src.Id))
+	req, err := s.client.NewUploadRequest(artifactsUrl, []*UploadedFile{
+		&UploadedFile{
+			Fieldname: filesFieldName,
+			Filename:  filename,
+			Data:      data,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(sessionTokenHeader, src.Token)
+	_, err = s.client.Do(ctx, req, meta)
+	return meta, err
+}
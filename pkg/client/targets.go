@@ -0,0 +1,59 @@
+package client
+
+import (
+	"golang.org/x/net/context"
+
+	"github.com/bearded-web/bearded/models/target"
+)
+
+const targetsUrl = "targets"
+
+type TargetsService struct {
+	client *Client
+}
+
+func (s *TargetsService) String() string {
+	return Stringify(s)
+}
+
+type TargetsListOpts struct {
+	ListOpts
+	Project string            `url:"project,omitempty"`
+	Type    target.TargetType `url:"type,omitempty"`
+	Sort    string            `url:"sort,omitempty"`
+}
+
+// List targets.
+func (s *TargetsService) List(ctx context.Context, opt *TargetsListOpts) (*target.TargetList, error) {
+	targetList := &target.TargetList{}
+	return targetList, s.client.List(ctx, targetsUrl, opt, targetList)
+}
+
+// Iter calls fn for every target from all pages, iteration stops on the first error
+func (s *TargetsService) Iter(ctx context.Context, opt *TargetsListOpts, fn func(*target.Target) error) error {
+	o := TargetsListOpts{}
+	if opt != nil {
+		o = *opt
+	}
+	return iterate(&o.ListOpts, func() (interface{}, error) { return s.List(ctx, &o) }, fn)
+}
+
+func (s *TargetsService) Get(ctx context.Context, id string) (*target.Target, error) {
+	obj := &target.Target{}
+	return obj, s.client.Get(ctx, targetsUrl, id, obj)
+}
+
+func (s *TargetsService) Create(ctx context.Context, src *target.Target) (*target.Target, error) {
+	obj := &target.Target{}
+	return obj, s.client.Create(ctx, targetsUrl, src, obj)
+}
+
+func (s *TargetsService) Update(ctx context.Context, src *target.Target) (*target.Target, error) {
+	obj := &target.Target{}
+	id := FromId(src.Id)
+	return obj, s.client.Update(ctx, targetsUrl, id, src, obj)
+}
+
+func (s *TargetsService) Delete(ctx context.Context, id string) error {
+	return s.client.Delete(ctx, targetsUrl, id)
+}
@@ -5,36 +5,78 @@ import "github.com/bearded-web/bearded/pkg/utils"
 type Dispatcher struct {
 	Debug bool `flag:"-"`
 
-	Frontend Frontend
-	Agent    InternalAgent
-	Worker   InternalWorker
-	Swagger  Swagger
-	Mongo    Mongo
-	Email    Email
-	Api      Api
-	//	Log      Log
-	Template Template
+	Frontend   Frontend
+	Secure     Secure
+	Agent      InternalAgent
+	Worker     InternalWorker
+	Swagger    Swagger
+	Mongo      Mongo
+	Email      Email
+	Api        Api
+	Files      Files
+	Risk       Risk
+	Sanitize   Sanitize
+	Quota      Quota
+	Scheduler  Scheduler
+	Approval   Approval
+	Cascade    Cascade
+	Summary    Summary
+	Escalation Escalation
+	Monitor    Monitor
+	Discovery  Discovery
+	Delivery   Delivery
+	Exploits   Exploits
+	ServiceNow ServiceNow
+	Snapshot   Snapshot
+	ApiUsage   ApiUsage
+	Siem       Siem
+	Log        Log
+	Template   Template
+	I18n       I18n
 }
 
 type Template struct {
 	Path string `desc:"path to template files"`
 }
 
+type I18n struct {
+	Path string `desc:"path to json catalogs of translated messages, like ru.json"`
+}
+
 type Api struct {
 	BindAddr string   `desc:"http address for binding api server"`
 	Host     string   `desc:"host for website, required for building urls"`
 	Admins   []string `desc:"email list of users with admin permissions"`
 
+	TrustedProxies []string `desc:"ips or cidr networks of reverse proxies, client ips are taken from X-Forwarded-For of their requests"`
+
 	ResetPasswordSecret   string `flag:"-" desc:"secret required for reset token generation"`
 	ResetPasswordDuration int    `desc:"lifetime for reset token in seconds"`
 
 	SystemEmail  string `desc:"for sending system emails, like password reseting"`
 	ContactEmail string `desc:"for show in templates, like contact with us"`
+	Reply        Reply
+
+	Raven   string `desc:"sentry addr for frontend logging"`
+	GA      string `desc:"google analytics id"`
+	Signup  Signup
+	Cookie  Cookie
+	Cors    Cors
+	GraphQL GraphQL
+}
 
-	Raven  string `desc:"sentry addr for frontend logging"`
-	GA     string `desc:"google analytics id"`
-	Signup Signup
-	Cookie Cookie
+// Reply accepts email replies to issue notifications as comments,
+// the mail provider posts inbound emails to https://inbound:<Secret>@<host>/api/v1/inbound/email
+type Reply struct {
+	Address     string `desc:"mailbox for replies, like reply@bearded.example, replies are sent to reply+<token>@bearded.example; empty disables replies"`
+	Secret      string `flag:"-" desc:"secret of the inbound webhook which is passed by the mail provider as the basic auth password"`
+	TokenSecret string `flag:"-" desc:"secret for signing of reply addresses, old addresses are invalid after change"`
+}
+
+type GraphQL struct {
+	Enable   bool `desc:"enable graphql endpoint on /api/graphql"`
+	MaxDepth int  `desc:"max nesting of graphql queries, 0 means unlimited"`
+	MaxCost  int  `desc:"max number of objects a graphql query could return, lists are counted by their limit; 0 means unlimited"`
 }
 
 type Cookie struct {
@@ -43,13 +85,157 @@ type Cookie struct {
 	Secure   bool     `desc:"set cookie only for https"`
 }
 
+type Cors struct {
+	Enable           bool     `desc:"enable cross origin requests to api"`
+	AllowedOrigins   []string `desc:"origins allowed to make requests, * to allow any, but not with credentials"`
+	AllowedHeaders   []string `desc:"request headers allowed in cross origin requests"`
+	AllowedMethods   []string `desc:"methods allowed in cross origin requests, all route methods if empty"`
+	ExposeHeaders    []string `desc:"response headers exposed to the browser"`
+	AllowCredentials bool     `desc:"allow cookies in cross origin requests"`
+	MaxAge           int      `desc:"seconds for caching preflight response"`
+}
+
 type Signup struct {
-	Disable bool `desc:"disable signup"`
+	Disable        bool     `desc:"disable signup"`
+	Verify         bool     `desc:"require email verification for new users"`
+	VerifyDuration int      `desc:"lifetime for verification token in seconds"`
+	Approve        bool     `desc:"new users should be approved by admin before login"`
+	Domains        []string `desc:"email domains allowed for signup, any if empty"`
+	RateLimit      int      `desc:"max signup and login requests per minute from one ip, 0 to disable"`
+}
+
+type Risk struct {
+	Severity       []int `desc:"base scores for [info|low|medium|high] severities"`
+	Criticality    []int `desc:"multipliers in percents for [low|medium|high|critical] target criticality"`
+	InternetFacing int   `desc:"multiplier in percents for internet facing issues"`
+	AuthRequired   int   `desc:"multiplier in percents for issues which require authentication"`
+	Exploitable    int   `desc:"multiplier in percents for issues with known exploits"`
+}
+
+type Sanitize struct {
+	Policy string   `desc:"html policy for descriptions, remediations and comments, one of [strict|basic|none]"`
+	Tags   []string `desc:"extra html tags allowed by the policy"`
+}
+
+// Quota is default for projects without their own quota
+type Quota struct {
+	MaxScans     int `desc:"max running scans per project, 0 is unlimited"`
+	AgentMinutes int `desc:"agent minutes per project in a calendar month, 0 is unlimited"`
+	Targets      int `desc:"max targets per project, 0 is unlimited"`
+	ScansPerDay  int `desc:"scans created per project in a UTC day, 0 is unlimited"`
+	Storage      int `desc:"megabytes of uploaded files per project, 0 is unlimited"`
+}
+
+// Scheduler decides when created scans are started
+type Scheduler struct {
+	ConcurrentTargetScans bool `desc:"allow several scans against the same target at once, they produce duplicate issues"`
+}
+
+// Approval is a two-person rule for project, target and bulk issue deletion
+type Approval struct {
+	Enable bool `desc:"require a second admin to confirm destructive actions"`
+	Window int  `desc:"minutes to confirm an action before it expires"`
+}
+
+// Cascade is a policy for issues and scans of removed targets and projects
+type Cascade struct {
+	Policy   string `desc:"block deletion while issues or scans exist or remove them in background [block|cascade]"`
+	Interval int    `desc:"seconds between checks of new cascade jobs and expired issues in the trash"`
+}
+
+// Summary rebuilds summaries of targets in background after their issues are changed
+type Summary struct {
+	Interval int `desc:"seconds between checks of stale target summaries"`
+}
+
+type Escalation struct {
+	Disable  bool `desc:"disable notifications for unacknowledged issues"`
+	Interval int  `desc:"seconds between checks of unacknowledged issues"`
+}
+
+type Monitor struct {
+	Disable        bool `desc:"disable built-in tls and dns monitoring of web targets"`
+	Interval       int  `desc:"seconds between monitor checks"`
+	CertExpiryDays int  `desc:"report certificates which expire in less than this number of days"`
+	Timeout        int  `desc:"connection timeout in seconds"`
+}
+
+type Discovery struct {
+	Disable  bool `desc:"disable scheduled subdomain discovery"`
+	Interval int  `desc:"seconds between checks of due discoveries"`
+}
+
+// Exploits syncs known exploits of cves, issues with them get the exploitable flag and higher risk
+type Exploits struct {
+	Disable      bool   `desc:"disable sync of exploit databases"`
+	Interval     int    `desc:"seconds between syncs"`
+	KevUrl       string `desc:"url of CISA known exploited vulnerabilities catalog in json, empty to skip"`
+	ExploitDbUrl string `desc:"url of exploit-db files_exploits.csv, empty to skip"`
+}
+
+// ServiceNow creates records for severe issues of projects with the integration and syncs them both ways
+type ServiceNow struct {
+	Disable  bool `desc:"disable servicenow sync"`
+	Interval int  `desc:"seconds between syncs"`
+}
+
+// Snapshot takes nightly snapshots of project issue counts for trends
+type Snapshot struct {
+	Disable  bool `desc:"disable snapshots of issue counts"`
+	Interval int  `desc:"seconds between checks of missing snapshots"`
+}
+
+type Delivery struct {
+	Disable  bool `desc:"disable scheduled email reports of projects"`
+	Interval int  `desc:"seconds between checks of due reports"`
+}
+
+// ApiUsage counts api calls of projects by token and route
+type ApiUsage struct {
+	Disable  bool `desc:"disable api usage analytics of projects"`
+	Interval int  `desc:"seconds between writes of counted calls to the db"`
+}
+
+// Siem streams security events for SOC, like high severity issues, login failures and admin actions
+type Siem struct {
+	Enable bool   `desc:"stream security events to siem"`
+	Format string `desc:"event format [cef|leef]"`
+	Output string `desc:"where events are sent [syslog|kafka]"`
+	Syslog Syslog
+	Kafka  Kafka
+}
+
+type Syslog struct {
+	Network string `desc:"syslog network [udp|tcp]"`
+	Addr    string `desc:"syslog server address, e.g. 127.0.0.1:514"`
+}
+
+type Kafka struct {
+	RestProxy string `desc:"url of kafka rest proxy, e.g. http://127.0.0.1:8082"`
+	Topic     string `desc:"topic for events"`
+}
+
+type Files struct {
+	ThumbnailSizes []int `desc:"max side sizes of thumbnails generated for uploaded images"`
+	RawReportLimit int   `desc:"raw plugin reports bigger than this size in bytes are stored as files, 0 to disable"`
 }
 
 type Frontend struct {
-	Disable bool   `desc:"disable serving frontend files"`
-	Path    string `desc:"path to frontend to serve static"`
+	Disable     bool   `desc:"disable serving frontend files"`
+	Path        string `desc:"path to frontend to serve static"`
+	Embed       bool   `desc:"serve frontend embedded to the binary instead of the path"`
+	CacheMaxAge int    `desc:"max age in seconds for the frontend assets cache, 0 to disable"`
+}
+
+type Secure struct {
+	Disable               bool     `desc:"disable security response headers"`
+	HSTSMaxAge            int      `desc:"max age for Strict-Transport-Security header sent over https, 0 to disable"`
+	HSTSIncludeSubdomains bool     `desc:"add includeSubDomains to Strict-Transport-Security header"`
+	FrameOptions          string   `desc:"X-Frame-Options header value, empty to disable"`
+	ContentTypeNosniff    bool     `desc:"set X-Content-Type-Options to nosniff"`
+	ReferrerPolicy        string   `desc:"Referrer-Policy header value, empty to disable"`
+	ContentSecurityPolicy string   `desc:"Content-Security-Policy header value, empty to disable"`
+	SkipPaths             []string `desc:"path prefixes served without security headers"`
 }
 
 type InternalAgent struct {
@@ -58,7 +244,9 @@ type InternalAgent struct {
 }
 
 type Agent struct {
-	Name string `desc:"Unique agent name, set to fqdn if empty"`
+	Name       string `desc:"Unique agent name, set to fqdn if empty"`
+	Capacity   int    `desc:"max parallel scan sessions, 0 is unlimited"`
+	RecordHost string `desc:"agent address in the docker network, plugin traffic is recorded by proxy listening on it, 172.17.0.1 if empty"`
 }
 
 type Worker struct {
@@ -104,11 +292,16 @@ type Mongo struct {
 
 	// to remove text search index in mongodb, you must do it manually
 	TextSearchEnable bool `desc:"enable search with mongo test search index"`
+	CountCacheTtl    int  `desc:"seconds to cache counts for repeated list queries, counts are cached per process, so changes made through other api servers are visible after it, 0 to disable"`
+	PoolLimit        int  `desc:"max sockets in use per mongo server, requests wait for a free one, 0 is the driver default 4096"`
+	SocketTimeout    int  `desc:"seconds to wait for a non-responding mongo server, 0 is the driver default"`
+	SyncTimeout      int  `desc:"seconds to wait for an available mongo server, 0 is the driver default"`
+	QueryTimeout     int  `desc:"seconds to fetch results of a list query, 0 is unlimited"`
 }
 
-//type Log struct {
-//
-//}
+type Log struct {
+	Level string `desc:"logger level [debug|info|warning|error|fatal], overrides global log-level flag"`
+}
 
 func NewDispatcher() *Dispatcher {
 	return &Dispatcher{
@@ -120,10 +313,28 @@ func NewDispatcher() *Dispatcher {
 			ResetPasswordDuration: 86400,
 			SystemEmail:           "admin@localhost",
 			ContactEmail:          "admin@localhost",
+			Signup: Signup{
+				VerifyDuration: 86400 * 3,
+				RateLimit:      20,
+			},
 			Cookie: Cookie{
 				Name:     "bearded-sss",
 				KeyPairs: []string{utils.RandomString(16), utils.RandomString(16)},
 			},
+			GraphQL: GraphQL{
+				MaxDepth: 8,
+				MaxCost:  50000,
+			},
+		},
+		Frontend: Frontend{
+			CacheMaxAge: 86400,
+		},
+		Secure: Secure{
+			HSTSMaxAge:            31536000,
+			FrameOptions:          "DENY",
+			ContentTypeNosniff:    true,
+			ReferrerPolicy:        "same-origin",
+			ContentSecurityPolicy: "default-src 'self'; img-src 'self' data:; style-src 'self' 'unsafe-inline'; frame-ancestors 'none'",
 		},
 		Swagger: Swagger{
 			ApiPath:  "/apidocs.json",
@@ -131,8 +342,9 @@ func NewDispatcher() *Dispatcher {
 			FilePath: "./extra/swagger-ui/dist",
 		},
 		Mongo: Mongo{
-			Addr:     "127.0.0.1",
-			Database: "bearded",
+			Addr:          "127.0.0.1",
+			Database:      "bearded",
+			CountCacheTtl: 30,
 		},
 		Email: Email{
 			Backend: "console",
@@ -144,6 +356,72 @@ func NewDispatcher() *Dispatcher {
 		Template: Template{
 			Path: "./extra/templates",
 		},
+		I18n: I18n{
+			Path: "./extra/locales",
+		},
+		Files: Files{
+			ThumbnailSizes: []int{64, 320},
+			RawReportLimit: 256 * 1024,
+		},
+		Risk: Risk{
+			Severity:       []int{0, 20, 50, 80},
+			Criticality:    []int{50, 80, 100, 125},
+			InternetFacing: 125,
+			AuthRequired:   70,
+			Exploitable:    150,
+		},
+		Sanitize: Sanitize{
+			Policy: "basic",
+		},
+		Approval: Approval{
+			Window: 60,
+		},
+		Cascade: Cascade{
+			Policy:   "cascade",
+			Interval: 5,
+		},
+		Summary: Summary{
+			Interval: 2,
+		},
+		Escalation: Escalation{
+			Interval: 300,
+		},
+		Discovery: Discovery{
+			Interval: 600,
+		},
+		Delivery: Delivery{
+			Interval: 900,
+		},
+		Exploits: Exploits{
+			Interval:     86400,
+			KevUrl:       "https://www.cisa.gov/sites/default/files/feeds/known_exploited_vulnerabilities.json",
+			ExploitDbUrl: "https://gitlab.com/exploit-database/exploitdb/-/raw/main/files_exploits.csv",
+		},
+		ServiceNow: ServiceNow{
+			Interval: 300,
+		},
+		Snapshot: Snapshot{
+			Interval: 3600,
+		},
+		ApiUsage: ApiUsage{
+			Interval: 60,
+		},
+		Siem: Siem{
+			Format: "cef",
+			Output: "syslog",
+			Syslog: Syslog{
+				Network: "udp",
+				Addr:    "127.0.0.1:514",
+			},
+			Kafka: Kafka{
+				Topic: "bearded-events",
+			},
+		},
+		Monitor: Monitor{
+			Interval:       21600,
+			CertExpiryDays: 30,
+			Timeout:        10,
+		},
 	}
 }
 
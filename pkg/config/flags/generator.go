@@ -59,32 +59,7 @@ fields:
 		if prefix != "" {
 			flagName = fmt.Sprintf("%s%s%s", prefix, flagDivider, flagName)
 		}
-		envVar := FlagToEnv(flagName)
-		ignoreEnvPrefix := false
-		if envTags := strings.Split(field.Tag(DefaultEnvTag), ","); len(envTags) > 0 {
-			switch envName := envTags[0]; envName {
-			case "-":
-				// if tag is `env:"-"` then remove env var
-				envVar = ""
-			case "":
-				// if tag is `env:""` then env var is taken from flag name
-			default:
-				// if tag is `env:"NAME"` then env var is envPrefix_flagPrefix_NAME
-				// if tag is `env:"~NAME"` then env var is NAME
-				if strings.HasPrefix(envName, "~") {
-					envVar = envName[1:]
-					ignoreEnvPrefix = true
-				} else {
-					envVar = envName
-					if prefix != "" {
-						envVar = fmt.Sprintf("%s%s%s", FlagToEnv(prefix), envDivider, envVar)
-					}
-				}
-			}
-		}
-		if envVar != "" && !ignoreEnvPrefix && envPrefix != "" {
-			envVar = fmt.Sprintf("%s%s%s", envPrefix, envDivider, envVar)
-		}
+		envVar := envName(field, prefix, flagName, envPrefix)
 		usage := field.Tag(descTag)
 		var f cli.Flag
 		switch field.Kind() {
@@ -150,3 +125,34 @@ fields:
 	}
 	return flags
 }
+
+// get environment variable name for the field, returns empty string if env is disabled
+func envName(field *structs.Field, prefix, flagName, envPrefix string) string {
+	envVar := FlagToEnv(flagName)
+	ignoreEnvPrefix := false
+	if envTags := strings.Split(field.Tag(DefaultEnvTag), ","); len(envTags) > 0 {
+		switch envName := envTags[0]; envName {
+		case "-":
+			// if tag is `env:"-"` then remove env var
+			envVar = ""
+		case "":
+			// if tag is `env:""` then env var is taken from flag name
+		default:
+			// if tag is `env:"NAME"` then env var is envPrefix_flagPrefix_NAME
+			// if tag is `env:"~NAME"` then env var is NAME
+			if strings.HasPrefix(envName, "~") {
+				envVar = envName[1:]
+				ignoreEnvPrefix = true
+			} else {
+				envVar = envName
+				if prefix != "" {
+					envVar = fmt.Sprintf("%s%s%s", FlagToEnv(prefix), envDivider, envVar)
+				}
+			}
+		}
+	}
+	if envVar != "" && !ignoreEnvPrefix && envPrefix != "" {
+		envVar = fmt.Sprintf("%s%s%s", envPrefix, envDivider, envVar)
+	}
+	return envVar
+}
@@ -2,6 +2,7 @@ package flags
 
 import (
 	"fmt"
+	"os"
 	"reflect"
 	"strings"
 
@@ -9,13 +10,23 @@ import (
 	"github.com/m0sth8/cli"
 )
 
+// ParseFlags sets config fields from flags and environment variables.
+// Values which are already in config (f.e loaded from file) are overwritten
+// only if flag or environment variable is set explicitly.
 func ParseFlags(cfg interface{}, ctx *cli.Context, opts ...Opts) error {
 	prefix := ""
+	envPrefix := ""
 	flagTag := DefaultFlagTag
 	for _, opt := range opts {
 		if opt.Prefix != "" {
 			prefix = opt.Prefix
 		}
+		if opt.EnvPrefix != "" {
+			envPrefix = opt.EnvPrefix
+		}
+		if opt.FlagTag != "" {
+			flagTag = opt.FlagTag
+		}
 	}
 	s := structs.New(cfg)
 	srcStruct := reflect.ValueOf(cfg)
@@ -37,8 +48,12 @@ fields:
 		if prefix != "" {
 			flagName = fmt.Sprintf("%s%s%s", prefix, flagDivider, flagName)
 		}
+		// environment doesn't count in IsSet operation, so check it manually
+		envVar := envName(field, prefix, flagName, envPrefix)
+		isSet := ctx.IsSet(flagName) || (envVar != "" && os.Getenv(envVar) != "")
+
 		// if field in config already has non zero value, do not set default value from flags
-		if !field.IsZero() && !ctx.IsSet(flagName) && field.Kind() != reflect.Struct {
+		if !field.IsZero() && !isSet && field.Kind() != reflect.Struct {
 			continue fields
 		}
 
@@ -48,7 +63,7 @@ fields:
 			val := ctx.String(flagName)
 			// environment doesn't count in IsSet operation, don't know why.
 			if val == "" {
-				if !ctx.IsSet(flagName) {
+				if !isSet {
 					continue fields
 				}
 			}
@@ -57,7 +72,7 @@ fields:
 			val := ctx.Bool(flagName)
 			// environment doesn't count in IsSet operation, don't know why.
 			if val == false {
-				if !ctx.IsSet(flagName) {
+				if !isSet {
 					continue fields
 				}
 			}
@@ -66,15 +81,16 @@ fields:
 			val := ctx.Int(flagName)
 			// environment doesn't count in IsSet operation, don't know why.
 			if val == 0 {
-				if !ctx.IsSet(flagName) {
+				if !isSet {
 					continue fields
 				}
 			}
 			err = field.Set(val)
 		case reflect.Struct:
 			opt := Opts{
-				Prefix:  flagName,
-				FlagTag: flagTag,
+				Prefix:    flagName,
+				FlagTag:   flagTag,
+				EnvPrefix: envPrefix,
 			}
 			realField := srcStruct.FieldByName(field.Name())
 			err = ParseFlags(realField.Addr().Interface(), ctx, opt)
@@ -84,7 +100,7 @@ fields:
 			case reflect.String:
 				val := ctx.StringSlice(flagName)
 				if len(val) == 0 {
-					if !ctx.IsSet(flagName) {
+					if !isSet {
 						continue fields
 					}
 				}
@@ -92,7 +108,7 @@ fields:
 			case reflect.Int:
 				val := ctx.IntSlice(flagName)
 				if len(val) == 0 {
-					if !ctx.IsSet(flagName) {
+					if !isSet {
 						continue fields
 					}
 				}
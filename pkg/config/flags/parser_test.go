@@ -96,3 +96,33 @@ func TestParseFlag(t *testing.T) {
 	a.Run([]string{"run", "--name", "bla",
 		"--sub-name", "sub", "--bool-val", "--bool-val4", "--str-slice2", "value22", "--int-slice2", "22"})
 }
+
+func TestParseFlagEnvOverride(t *testing.T) {
+	type Sub struct {
+		Addr string
+		Port int
+	}
+	type Cfg struct {
+		Name string
+		Sub  Sub
+	}
+	os.Setenv("APP_SUB_ADDR", "10.0.0.1")
+	os.Setenv("APP_SUB_PORT", "25")
+	defer os.Unsetenv("APP_SUB_ADDR")
+	defer os.Unsetenv("APP_SUB_PORT")
+	a := cli.NewApp()
+	a.Flags = GenerateFlags(&Cfg{Name: "default", Sub: Sub{Addr: "127.0.0.1", Port: 587}}, Opts{EnvPrefix: "APP"})
+	called := false
+	a.Action = func(ctx *cli.Context) {
+		called = true
+		// config loaded from file
+		cfg := &Cfg{Name: "file", Sub: Sub{Addr: "192.168.0.1", Port: 587}}
+		err := ParseFlags(cfg, ctx, Opts{EnvPrefix: "APP"})
+		require.NoError(t, err)
+		assert.Equal(t, "file", cfg.Name)
+		assert.Equal(t, "10.0.0.1", cfg.Sub.Addr)
+		assert.Equal(t, 25, cfg.Sub.Port)
+	}
+	a.Run([]string{"run"})
+	assert.True(t, called)
+}
@@ -0,0 +1,83 @@
+// Package credentials resolves secrets which targets and integrations reference by name.
+//
+// Secrets aren't stored in the database, they are kept in the environment of api servers and agents:
+//
+//	BEARDED_CREDENTIALS_<NAME>=user:password
+//	BEARDED_CREDENTIALS_<NAME>_HOSTS=git.example.com,*.service-now.com
+//	BEARDED_CREDENTIALS_<NAME>_PROJECTS=<project id>,<project id>
+//
+// Users choose urls and credential names, so a secret is sent only to hosts listed for it
+// and, if projects are listed, used only by these projects. Hosts are required, projects are optional
+// and checked only by api servers, agents don't know projects of sessions.
+package credentials
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+const prefix = "BEARDED_CREDENTIALS_"
+
+// Lookup returns the secret of the credentials if they could be sent to the host
+func Lookup(name, host string) (string, error) {
+	key := prefix + strings.ToUpper(name)
+	secret := os.Getenv(key)
+	if secret == "" {
+		return "", fmt.Errorf("Credentials %s aren't configured, set %s", name, key)
+	}
+	hosts := list(key + "_HOSTS")
+	if len(hosts) == 0 {
+		return "", fmt.Errorf("Credentials %s aren't bound to hosts, set %s_HOSTS", name, key)
+	}
+	if !matchHost(hosts, host) {
+		return "", fmt.Errorf("Credentials %s aren't allowed for host %s", name, host)
+	}
+	return secret, nil
+}
+
+// CheckProject returns an error if the credentials are bound to other projects
+func CheckProject(name, project string) error {
+	projects := list(prefix + strings.ToUpper(name) + "_PROJECTS")
+	if len(projects) == 0 {
+		return nil
+	}
+	for _, p := range projects {
+		if p == project {
+			return nil
+		}
+	}
+	return fmt.Errorf("Credentials %s aren't allowed for project %s", name, project)
+}
+
+// matchHost reports whether the host without port is in the list, *.example.com matches subdomains
+func matchHost(hosts []string, host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" {
+		return false
+	}
+	for _, pattern := range hosts {
+		pattern = strings.ToLower(pattern)
+		if pattern == host {
+			return true
+		}
+		if strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:]) {
+			return true
+		}
+	}
+	return false
+}
+
+func list(key string) []string {
+	results := []string{}
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			results = append(results, v)
+		}
+	}
+	return results
+}
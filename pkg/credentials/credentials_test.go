@@ -0,0 +1,49 @@
+package credentials
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setenv(t *testing.T, key, value string) {
+	require.NoError(t, os.Setenv(key, value))
+}
+
+func TestLookup(t *testing.T) {
+	defer os.Unsetenv("BEARDED_CREDENTIALS_SNOW")
+	defer os.Unsetenv("BEARDED_CREDENTIALS_SNOW_HOSTS")
+
+	_, err := Lookup("snow", "acme.service-now.com")
+	assert.Error(t, err)
+
+	setenv(t, "BEARDED_CREDENTIALS_SNOW", "admin:secret")
+	_, err = Lookup("snow", "acme.service-now.com")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "_HOSTS")
+
+	setenv(t, "BEARDED_CREDENTIALS_SNOW_HOSTS", "git.example.com, *.service-now.com")
+	secret, err := Lookup("snow", "acme.service-now.com:443")
+	require.NoError(t, err)
+	assert.Equal(t, "admin:secret", secret)
+
+	_, err = Lookup("snow", "GIT.example.com")
+	assert.NoError(t, err)
+
+	for _, host := range []string{"evil.com", "service-now.com.evil.com", "example.com", ""} {
+		_, err = Lookup("snow", host)
+		assert.Error(t, err, host)
+	}
+}
+
+func TestCheckProject(t *testing.T) {
+	defer os.Unsetenv("BEARDED_CREDENTIALS_SNOW_PROJECTS")
+
+	assert.NoError(t, CheckProject("snow", "a"))
+
+	setenv(t, "BEARDED_CREDENTIALS_SNOW_PROJECTS", "a,b")
+	assert.NoError(t, CheckProject("snow", "b"))
+	assert.Error(t, CheckProject("snow", "c"))
+}
@@ -0,0 +1,210 @@
+// Package delivery sends weekly reports of open project issues to email recipients.
+package delivery
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/facebookgo/stackerr"
+	"golang.org/x/net/context"
+	"gopkg.in/gomail.v1"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/email"
+	"github.com/bearded-web/bearded/pkg/manager"
+)
+
+// lock in mongo which is held by the instance running checks
+const lockName = "delivery"
+
+// MaxIssues is the max number of issues in the report, the most risky ones are kept
+const MaxIssues = 5000
+
+type Engine struct {
+	mgr    *manager.Manager
+	mailer email.Mailer
+	from   string
+	host   string // used to make absolute links
+}
+
+func New(mgr *manager.Manager, mailer email.Mailer, from, host string) *Engine {
+	return &Engine{
+		mgr:    mgr,
+		mailer: mailer,
+		from:   from,
+		host:   host,
+	}
+}
+
+// Run sends due reports every interval until the context is done
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	logrus.Infof("Delivery engine is started, check interval %s", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			if err := e.mgr.Locks.Release(lockName); err != nil {
+				logrus.Error(err)
+			}
+			return
+		case <-time.After(interval):
+			// only one api instance runs the check, others wait until the lock is expired
+			if !e.mgr.Locks.Lead(lockName, interval) {
+				continue
+			}
+			if err := e.Check(time.Now().UTC()); err != nil {
+				logrus.Error(err)
+			}
+		}
+	}
+}
+
+// Check sends reports of all projects with due deliveries
+func (e *Engine) Check(now time.Time) error {
+	mgr := e.mgr.Copy()
+	defer mgr.Close()
+
+	projects, _, err := mgr.Projects.FilterByQuery(bson.M{"delivery.enabled": true})
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	for _, p := range projects {
+		if !p.Delivery.Due(now) {
+			continue
+		}
+		if err := e.Send(mgr, p, now); err != nil {
+			logrus.Error(err)
+			continue
+		}
+		if err := mgr.Projects.SetDeliverySent(p.Id, now); err != nil {
+			logrus.Error(stackerr.Wrap(err))
+		}
+	}
+	return nil
+}
+
+// Send mails the report of open project issues to all delivery recipients,
+// dates are shown in the timezone and date format of recipients who are users.
+// All recipients are tried, the first error is returned.
+func (e *Engine) Send(mgr *manager.Manager, p *project.Project, now time.Time) error {
+	d := p.Delivery
+	query := bson.M{
+		"project":     p.Id,
+		"resolved":    false,
+		"false":       false,
+		"muted":       false,
+		"pendingScan": bson.M{"$exists": false},
+	}
+	if d.Severity != "" {
+		query["severityRank"] = bson.M{"$gte": d.Severity.Rank()}
+	}
+	issues, count, err := mgr.Issues.FilterByQuery(query, manager.Opts{Sort: []string{"-risk", "-severityRank", "created"}, Limit: MaxIssues})
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	targets, _, err := mgr.Targets.FilterByQuery(bson.M{"project": p.Id})
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	users, _, err := mgr.Users.FilterByQuery(bson.M{"email": bson.M{"$in": d.Recipients}})
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	byEmail := map[string]*user.User{}
+	for _, u := range users {
+		byEmail[u.Email] = u
+	}
+
+	var first error
+	for _, to := range d.Recipients {
+		u := byEmail[to]
+		if u == nil {
+			// utc and iso dates
+			u = &user.User{}
+		}
+		data, err := Csv(issues, targets, e.host, u)
+		if err != nil {
+			return stackerr.Wrap(err)
+		}
+		msg := email.NewMessage()
+		msg.SetHeader("From", msg.FormatAddress(e.from, "Bearded"))
+		msg.SetHeader("To", to)
+		msg.SetHeader("Subject", Subject(p, now, u))
+		msg.SetBody("text/plain", Body(p, len(issues), count, e.host))
+		msg.Attach(gomail.CreateFile(Filename(p, now, u), data))
+		if err := e.mailer.Send(msg); err != nil && first == nil {
+			first = stackerr.Wrap(err)
+		}
+	}
+	return first
+}
+
+// Subject returns the subject of the report email with the date in the recipient date format
+func Subject(p *project.Project, now time.Time, u *user.User) string {
+	return fmt.Sprintf("Open issues of %s on %s", p.Name, u.FormatDate(now))
+}
+
+// Body returns the text of the report email, attached is the number of issues in the report
+func Body(p *project.Project, attached, count int, host string) string {
+	body := fmt.Sprintf("%d open issues of the project %s are attached.", count, p.Name)
+	if count > attached {
+		body = fmt.Sprintf("%d of %d open issues of the project %s with the highest risk are attached.", attached, count, p.Name)
+	}
+	return fmt.Sprintf("%s\n\n%s/#/project/%s", body, host, p.Id.Hex())
+}
+
+// Filename returns the name of the attached report, the date is always iso to keep names sortable
+func Filename(p *project.Project, now time.Time, u *user.User) string {
+	return fmt.Sprintf("issues-%s.%s", now.In(u.Location()).Format("2006-01-02"), p.Delivery.GetFormat())
+}
+
+// Csv returns issues with addresses of their targets in csv format,
+// created dates stay machine readable, but they are in the recipient timezone
+func Csv(issues []*issue.TargetIssue, targets []*target.Target, host string, u *user.User) ([]byte, error) {
+	addrs := map[bson.ObjectId]string{}
+	for _, t := range targets {
+		addrs[t.Id] = t.Addr()
+	}
+	loc := u.Location()
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	w.Write([]string{"id", "severity", "confidence", "summary", "target", "url", "risk", "created", "link"})
+	for _, obj := range issues {
+		confidence := obj.Confidence
+		if confidence == "" {
+			confidence = issue.ConfidenceFirm
+		}
+		_, url := obj.Request()
+		w.Write(csvRow(
+			obj.Id.Hex(),
+			string(obj.Severity),
+			string(confidence),
+			obj.Summary,
+			addrs[obj.Target],
+			url,
+			fmt.Sprintf("%d", obj.Risk),
+			obj.Created.In(loc).Format(time.RFC3339),
+			fmt.Sprintf("%s/#/issue/%s", host, obj.Id.Hex()),
+		))
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// csvRow escapes cells which spreadsheets would evaluate as formulas,
+// summaries and urls come from scanned sites and can't be trusted
+func csvRow(cells ...string) []string {
+	for i, cell := range cells {
+		if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+			cells[i] = "'" + cell
+		}
+	}
+	return cells
+}
@@ -0,0 +1,73 @@
+package delivery
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/models/user"
+)
+
+func TestCsv(t *testing.T) {
+	tgt := &target.Target{Id: bson.NewObjectId(), Type: target.TypeWeb, Web: &target.WebTarget{Domain: "https://example.com"}}
+	obj := &issue.TargetIssue{
+		Id:      bson.NewObjectId(),
+		Target:  tgt.Id,
+		Created: time.Date(2015, 6, 10, 12, 0, 0, 0, time.UTC),
+		Issue: issue.Issue{
+			Summary:  "XSS, reflected",
+			Severity: issue.SeverityHigh,
+			Vector:   &issue.Vector{Url: "https://example.com/search"},
+		},
+	}
+	data, err := Csv([]*issue.TargetIssue{obj}, []*target.Target{tgt}, "https://bearded.example.com", &user.User{})
+	require.NoError(t, err)
+	assert.Equal(t, "id,severity,confidence,summary,target,url,risk,created,link\n"+
+		obj.Id.Hex()+",high,firm,\"XSS, reflected\",https://example.com,https://example.com/search,0,2015-06-10T12:00:00Z,"+
+		"https://bearded.example.com/#/issue/"+obj.Id.Hex()+"\n", string(data))
+}
+
+func TestCsvFormulas(t *testing.T) {
+	obj := &issue.TargetIssue{
+		Id:      bson.NewObjectId(),
+		Created: time.Date(2015, 6, 10, 12, 0, 0, 0, time.UTC),
+		Issue: issue.Issue{
+			Summary: "=HYPERLINK(\"http://evil\")",
+			Vector:  &issue.Vector{Url: "@SUM(1)"},
+		},
+	}
+	data, err := Csv([]*issue.TargetIssue{obj}, nil, "", &user.User{})
+	require.NoError(t, err)
+	assert.Contains(t, string(data), ",\"'=HYPERLINK(\"\"http://evil\"\")\",,'@SUM(1),0,")
+}
+
+func TestBody(t *testing.T) {
+	p := &project.Project{Id: bson.NewObjectId(), Name: "shop", Delivery: &project.Delivery{}}
+	now := time.Date(2016, 3, 7, 10, 0, 0, 0, time.UTC)
+	u := &user.User{}
+	assert.Equal(t, "Open issues of shop on 2016-03-07", Subject(p, now, u))
+	assert.Equal(t, "issues-2016-03-07.csv", Filename(p, now, u))
+	assert.Equal(t, fmt.Sprintf("3 open issues of the project shop are attached.\n\nhttp://bearded/#/project/%s", p.Id.Hex()),
+		Body(p, 3, 3, "http://bearded"))
+	assert.Contains(t, Body(p, 3, 5, "http://bearded"), "3 of 5 open issues")
+}
+
+func TestUserDates(t *testing.T) {
+	p := &project.Project{Id: bson.NewObjectId(), Name: "shop", Delivery: &project.Delivery{}}
+	now := time.Date(2016, 3, 7, 22, 30, 0, 0, time.UTC)
+	u := &user.User{Timezone: "Europe/Moscow", DateFormat: user.DateUs}
+	assert.Equal(t, "Open issues of shop on 03/08/2016", Subject(p, now, u))
+	assert.Equal(t, "issues-2016-03-08.csv", Filename(p, now, u))
+
+	obj := &issue.TargetIssue{Id: bson.NewObjectId(), Created: now}
+	data, err := Csv([]*issue.TargetIssue{obj}, nil, "", u)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), ",2016-03-08T01:30:00+03:00,")
+}
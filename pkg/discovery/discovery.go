@@ -0,0 +1,176 @@
+// Package discovery runs enumeration plans on root domains and proposes found hosts as new targets.
+package discovery
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/facebookgo/stackerr"
+	"golang.org/x/net/context"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/discovery"
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/scheduler"
+)
+
+// lock in mongo which is held by the instance running checks
+const lockName = "discovery"
+
+type Engine struct {
+	mgr *manager.Manager
+	sch scheduler.Scheduler
+}
+
+func New(mgr *manager.Manager, sch scheduler.Scheduler) *Engine {
+	return &Engine{
+		mgr: mgr,
+		sch: sch,
+	}
+}
+
+// Run starts due discoveries every interval until the context is done
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	logrus.Infof("Discovery engine is started, check interval %s", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			if err := e.mgr.Locks.Release(lockName); err != nil {
+				logrus.Error(err)
+			}
+			return
+		case <-time.After(interval):
+			// only one api instance runs the check, others wait until the lock is expired
+			if !e.mgr.Locks.Lead(lockName, interval) {
+				continue
+			}
+			if err := e.Check(time.Now().UTC()); err != nil {
+				logrus.Error(err)
+			}
+		}
+	}
+}
+
+// Check creates scans for all enabled discoveries which are due
+func (e *Engine) Check(now time.Time) error {
+	mgr := e.mgr.Copy()
+	defer mgr.Close()
+
+	discoveries, _, err := mgr.Discovery.FilterByQuery(bson.M{"enabled": true})
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	for _, d := range discoveries {
+		if !d.Due(now) {
+			continue
+		}
+		if _, err := Launch(mgr, e.sch, d); err != nil {
+			logrus.Error(err)
+		}
+	}
+	return nil
+}
+
+// Launch creates and queues a scan of the discovery plan
+func Launch(mgr *manager.Manager, sch scheduler.Scheduler, d *discovery.Discovery) (*scan.Scan, error) {
+	tgt, err := mgr.Targets.GetById(d.Target)
+	if err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	proj, err := mgr.Projects.GetById(d.Project)
+	if err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	planObj, err := mgr.Plans.GetById(d.Plan)
+	if err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	owner := d.Owner
+	if owner == "" {
+		owner = proj.Owner
+	}
+
+	sc, err := mgr.Scans.NewScan(owner, proj, tgt, planObj)
+	if err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	sc.Discovery = d.Id
+	sc, err = mgr.Scans.Create(sc)
+	if err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	sch.AddScan(sc)
+	if _, err := mgr.Feed.AddScan(sc); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+	}
+
+	now := time.Now().UTC()
+	d.LastRun = &now
+	d.LastScan = sc.Id
+	if err := mgr.Discovery.Update(d); err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	return sc, nil
+}
+
+// SaveHosts proposes hosts reported by the discovery scan as pending targets.
+// Hosts out of the root domain and hosts of existing targets are skipped.
+// Returns only new hosts.
+func SaveHosts(mgr *manager.Manager, sc *scan.Scan, names []string) ([]*discovery.Host, error) {
+	if sc.Discovery == "" || len(names) == 0 {
+		return nil, nil
+	}
+	d, err := mgr.Discovery.GetById(sc.Discovery)
+	if err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	root, err := mgr.Targets.GetById(d.Target)
+	if err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	targets, _, err := mgr.Targets.FilterByQuery(bson.M{"project": d.Project})
+	if err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	known := map[string]bool{}
+	for _, t := range targets {
+		known[discovery.NormalizeHost(t.Addr())] = true
+	}
+
+	now := time.Now().UTC()
+	added := []*discovery.Host{}
+	for _, name := range names {
+		name = discovery.NormalizeHost(name)
+		if known[name] || !discovery.InScope(name, root.Addr()) {
+			continue
+		}
+		known[name] = true
+
+		host, err := mgr.Hosts.GetByName(d.Project, name)
+		if err == nil {
+			if err := mgr.Hosts.Seen(host.Id, now); err != nil {
+				logrus.Error(stackerr.Wrap(err))
+			}
+			continue
+		}
+		if !mgr.IsNotFound(err) {
+			return added, stackerr.Wrap(err)
+		}
+		host, err = mgr.Hosts.Create(&discovery.Host{
+			Project:   d.Project,
+			Discovery: d.Id,
+			Name:      name,
+			Scan:      sc.Id,
+			Seen:      now,
+		})
+		if err != nil {
+			if mgr.IsDup(err) {
+				continue
+			}
+			return added, stackerr.Wrap(err)
+		}
+		added = append(added, host)
+	}
+	return added, nil
+}
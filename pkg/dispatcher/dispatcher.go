@@ -2,6 +2,7 @@ package dispatcher
 
 import (
 	"fmt"
+	htmlTemplate "html/template"
 	"log"
 	"net/http"
 	"os"
@@ -13,26 +14,56 @@ import (
 	"golang.org/x/net/context"
 	"gopkg.in/mgo.v2"
 
+	"github.com/bearded-web/bearded/models/cascade"
+	"github.com/bearded-web/bearded/models/notification"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/report"
+	"github.com/bearded-web/bearded/pkg/cleanup"
 	"github.com/bearded-web/bearded/pkg/config"
+	"github.com/bearded-web/bearded/pkg/delivery"
+	"github.com/bearded-web/bearded/pkg/discovery"
 	"github.com/bearded-web/bearded/pkg/email"
+	"github.com/bearded-web/bearded/pkg/escalation"
+	"github.com/bearded-web/bearded/pkg/exploits"
 	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/frontend"
+	"github.com/bearded-web/bearded/pkg/i18n"
 	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/monitor"
+	"github.com/bearded-web/bearded/pkg/notify"
 	"github.com/bearded-web/bearded/pkg/passlib"
+	"github.com/bearded-web/bearded/pkg/reply"
+	"github.com/bearded-web/bearded/pkg/risk"
+	"github.com/bearded-web/bearded/pkg/sanitize"
 	"github.com/bearded-web/bearded/pkg/scheduler"
+	"github.com/bearded-web/bearded/pkg/servicenow"
+	"github.com/bearded-web/bearded/pkg/siem"
+	"github.com/bearded-web/bearded/pkg/snapshot"
+	"github.com/bearded-web/bearded/pkg/summary"
 	"github.com/bearded-web/bearded/pkg/template"
 	"github.com/bearded-web/bearded/pkg/utils/async"
 	"github.com/bearded-web/bearded/services"
+	"github.com/bearded-web/bearded/services/admin"
 	"github.com/bearded-web/bearded/services/agent"
+	"github.com/bearded-web/bearded/services/apply"
+	"github.com/bearded-web/bearded/services/approval"
 	"github.com/bearded-web/bearded/services/auth"
+	cascadeService "github.com/bearded-web/bearded/services/cascade"
 	configService "github.com/bearded-web/bearded/services/config"
+	"github.com/bearded-web/bearded/services/enum"
 	"github.com/bearded-web/bearded/services/feed"
 	"github.com/bearded-web/bearded/services/file"
+	"github.com/bearded-web/bearded/services/graphql"
+	"github.com/bearded-web/bearded/services/inbound"
+	"github.com/bearded-web/bearded/services/ingest"
 	"github.com/bearded-web/bearded/services/issue"
 	"github.com/bearded-web/bearded/services/me"
 	"github.com/bearded-web/bearded/services/plan"
 	"github.com/bearded-web/bearded/services/plugin"
-	"github.com/bearded-web/bearded/services/project"
+	projectService "github.com/bearded-web/bearded/services/project"
+	"github.com/bearded-web/bearded/services/quarantine"
 	"github.com/bearded-web/bearded/services/scan"
+	syncService "github.com/bearded-web/bearded/services/sync"
 	"github.com/bearded-web/bearded/services/target"
 	"github.com/bearded-web/bearded/services/tech"
 	"github.com/bearded-web/bearded/services/token"
@@ -41,12 +72,13 @@ import (
 )
 
 func initServices(wsContainer *restful.Container, cfg *config.Dispatcher,
-	mgr *manager.Manager, mailer email.Mailer, tmpl *template.Template) error {
+	mgr *manager.Manager, mailer email.Mailer, tmpl *template.Template, bundle *i18n.Bundle, notifier *notify.Dispatcher, sch scheduler.Scheduler) error {
 
 	// password manager for generation and verification passwords
 	passCtx := passlib.NewContext()
 
-	sch := scheduler.NewMemoryScheduler(mgr.Copy())
+	// one session copy is shared by all filters and handlers of the request
+	wsContainer.Filter(filters.ManagerFilter(mgr))
 
 	// services
 	base := services.New(mgr, passCtx, sch, mailer, cfg.Api)
@@ -54,12 +86,15 @@ func initServices(wsContainer *restful.Container, cfg *config.Dispatcher,
 		base.Paginator.Host = cfg.Api.Host
 	}
 	base.Template = tmpl
+	base.I18n = bundle
+	base.Notifier = notifier
 	all := []services.ServiceInterface{
 		auth.New(base),
 		plugin.New(base),
 		plan.New(base),
+		apply.New(base),
 		user.New(base),
-		project.New(base),
+		projectService.New(base),
 		target.New(base),
 		scan.New(base),
 		me.New(base),
@@ -69,8 +104,19 @@ func initServices(wsContainer *restful.Container, cfg *config.Dispatcher,
 		issue.New(base),
 		vulndb.New(base),
 		configService.New(base),
+		enum.New(base),
 		token.New(base),
+		ingest.New(base),
 		tech.New(base),
+		quarantine.New(base),
+		syncService.New(base),
+		admin.New(base),
+		approval.New(base),
+		cascadeService.New(base),
+		inbound.New(base),
+	}
+	if cfg.Api.GraphQL.Enable {
+		all = append(all, graphql.New(base))
 	}
 
 	// initialize services
@@ -87,6 +133,23 @@ func initServices(wsContainer *restful.Container, cfg *config.Dispatcher,
 	return nil
 }
 
+func getNotifier(cfg config.Api, mgr *manager.Manager, mailer email.Mailer, bundle *i18n.Bundle) *notify.Dispatcher {
+	notifier := notify.New()
+	notifier.I18n = bundle
+	emailSender := notify.NewEmailSender(mailer, cfg.SystemEmail, cfg.Host)
+	emailSender.I18n = bundle
+	if cfg.Reply.Address != "" {
+		// errors are reported by the inbound service, notifications are sent without reply addresses
+		if addresser, err := reply.New(cfg.Reply.Address, cfg.Reply.TokenSecret); err == nil {
+			emailSender.Reply = addresser
+		}
+	}
+	notifier.Register(notification.ChannelEmail, emailSender)
+	notifier.Register(notification.ChannelInApp, notify.NewInboxSender(mgr))
+	notifier.RegisterMsTeams(notify.NewMsTeamsSender(cfg.Host))
+	return notifier
+}
+
 type MgoLogger struct {
 }
 
@@ -95,7 +158,11 @@ func (m *MgoLogger) Output(calldepth int, s string) error {
 	return nil
 }
 
-func getManager(cfg config.Mongo) (*manager.Manager, error) {
+func getManager(cfg config.Mongo, files config.Files, riskCfg config.Risk, sanitizeCfg config.Sanitize, quota config.Quota, approvalCfg config.Approval, cascadeCfg config.Cascade, events *siem.Stream) (*manager.Manager, error) {
+	policy, err := sanitize.New(sanitizeCfg.Policy, sanitizeCfg.Tags)
+	if err != nil {
+		return nil, err
+	}
 	// initialize mongodb session
 	logrus.Infof("Init mongodb on %s", cfg.Addr)
 	session, err := mgo.Dial(cfg.Addr)
@@ -103,9 +170,38 @@ func getManager(cfg config.Mongo) (*manager.Manager, error) {
 		return nil, fmt.Errorf("Cannot connect to mongodb: %s", err.Error())
 	}
 	logrus.Infof("Successfull")
+	manager.EnableStats()
+	if cfg.PoolLimit > 0 {
+		session.SetPoolLimit(cfg.PoolLimit)
+	}
+	if cfg.SocketTimeout > 0 {
+		session.SetSocketTimeout(time.Duration(cfg.SocketTimeout) * time.Second)
+	}
+	if cfg.SyncTimeout > 0 {
+		session.SetSyncTimeout(time.Duration(cfg.SyncTimeout) * time.Second)
+	}
 	logrus.Infof("Set mongo database %s", cfg.Database)
 	mgrCfg := manager.ManagerConfig{
 		TextSearchEnable: cfg.TextSearchEnable,
+		ThumbnailSizes:   files.ThumbnailSizes,
+		RawReportLimit:   files.RawReportLimit,
+		Risk:             risk.New(riskCfg),
+		Counts:           manager.NewCountCache(time.Duration(cfg.CountCacheTtl) * time.Second),
+		Sanitizer:        policy,
+		CascadePolicy:    cascade.Policy(cascadeCfg.Policy),
+		PoolLimit:        cfg.PoolLimit,
+		QueryTimeout:     time.Duration(cfg.QueryTimeout) * time.Second,
+		Siem:             events,
+		Quota: project.Quota{
+			MaxScans:     quota.MaxScans,
+			AgentMinutes: quota.AgentMinutes,
+			Targets:      quota.Targets,
+			ScansPerDay:  quota.ScansPerDay,
+			Storage:      quota.Storage,
+		},
+	}
+	if approvalCfg.Enable {
+		mgrCfg.ApprovalWindow = time.Duration(approvalCfg.Window) * time.Minute
 	}
 	mgr := manager.New(session.DB(cfg.Database), mgrCfg)
 	// Initialize db indexes
@@ -115,11 +211,25 @@ func getManager(cfg config.Mongo) (*manager.Manager, error) {
 	return mgr, nil
 }
 
-func getRestContainer(cfg config.Api) *restful.Container {
+func getRestContainer(cfg config.Api) (*restful.Container, error) {
 	// Create container and initialize services
 	wsContainer := restful.NewContainer()
 	wsContainer.Router(restful.CurlyRouter{}) // CurlyRouter is the faster routing alternative for restful
 
+	// rate limits and audit events use client ips forwarded by proxies
+	if err := filters.TrustProxies(cfg.TrustedProxies); err != nil {
+		return nil, err
+	}
+
+	// cors filter goes first to answer preflight requests without touching the session
+	if cfg.Cors.Enable {
+		cors, err := filters.CorsFilter(cfg.Cors, wsContainer)
+		if err != nil {
+			return nil, err
+		}
+		wsContainer.Filter(cors)
+	}
+
 	// setup session
 	cookieOpts := &filters.CookieOpts{
 		Path:     "/api/",
@@ -131,11 +241,11 @@ func getRestContainer(cfg config.Api) *restful.Container {
 
 	// Disable recovering in restful cause we recover all panics in negroni
 	wsContainer.DoNotRecover(true)
-	return wsContainer
+	return wsContainer, nil
 
 }
 
-func getNegroniApp(cfg *config.Dispatcher) *negroni.Negroni {
+func getNegroniApp(cfg *config.Dispatcher) (*negroni.Negroni, error) {
 	// Use negroni as middleware framework.
 	app := negroni.New()
 	// TODO (m0sth8): create recovery with ServiceError response
@@ -150,12 +260,34 @@ func getNegroniApp(cfg *config.Dispatcher) *negroni.Negroni {
 	}
 	app.Use(recovery)
 
-	// TODO (m0sth8): add secure middleware
+	if !cfg.Secure.Disable {
+		skip := []string{}
+		if cfg.Swagger.Enable {
+			// swagger ui uses inline scripts
+			skip = append(skip, cfg.Swagger.Path)
+		}
+		app.Use(filters.NewSecureHeaders(cfg.Secure, skip...))
+	}
 	if !cfg.Frontend.Disable {
-		logrus.Infof("Frontend served from %s directory", cfg.Frontend.Path)
-		app.Use(negroni.NewStatic(http.Dir(cfg.Frontend.Path)))
+		var dir http.FileSystem = http.Dir(cfg.Frontend.Path)
+		if cfg.Frontend.Embed {
+			embedded, err := frontend.Embedded()
+			if err != nil {
+				return nil, err
+			}
+			dir = embedded
+			logrus.Info("Frontend served from embedded assets")
+		} else {
+			logrus.Infof("Frontend served from %s directory", cfg.Frontend.Path)
+		}
+		// api and swagger requests shouldn't fall back to the frontend index
+		skip := []string{"/api/", "/config.json"}
+		if cfg.Swagger.Enable {
+			skip = append(skip, cfg.Swagger.Path, cfg.Swagger.ApiPath)
+		}
+		app.Use(frontend.NewStatic(dir, cfg.Frontend.CacheMaxAge, skip...))
 	}
-	return app
+	return app, nil
 }
 
 func runInternalAgent(ctx context.Context, mgr *manager.Manager,
@@ -172,15 +304,57 @@ func runInternalAgent(ctx context.Context, mgr *manager.Manager,
 	}
 }
 
-func Serve(ctx context.Context, cfg *config.Dispatcher) error {
+// Apply settings which could be changed without restart: log level, email and admins
+func reloadConfig(cfg *config.Dispatcher, mgr *manager.Manager, mailer *email.Email) {
+	logrus.Info("Reload config")
+	SetLogLevel(cfg.Log.Level)
+	mgr.Permission.SetAdmins(cfg.Api.Admins)
+	if err := mailer.SetConfig(cfg.Email); err != nil {
+		logrus.Errorf("Cannot reload mailer config: %s", err)
+	}
+}
+
+// Set logger level if it's not empty
+func SetLogLevel(level string) {
+	if level == "" {
+		return
+	}
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		logrus.Error(err)
+		return
+	}
+	logrus.SetLevel(lvl)
+}
+
+// Serve api and frontend until the context is done.
+// Every config from reload channel is applied with reloadConfig.
+func Serve(ctx context.Context, cfg *config.Dispatcher, reload <-chan *config.Dispatcher) error {
 	if cfg.Debug {
 		logrus.Info("Debug mode is enabled")
 	}
+	SetLogLevel(cfg.Log.Level)
 	// TODO (m0sth8): validate config
+	bundle := i18n.New()
+	if err := bundle.Load(cfg.I18n.Path); err != nil {
+		return fmt.Errorf("Cannot load translations: %s", err.Error())
+	}
+	logrus.Infof("Translations are loaded for locales %v", bundle.Locales())
 	logrus.Infof("Template path: %v", cfg.Template.Path)
-	tmpl := template.New(&template.Opts{Directory: cfg.Template.Path})
+	tmpl := template.New(&template.Opts{
+		Directory: cfg.Template.Path,
+		Funcs:     []htmlTemplate.FuncMap{{"t": bundle.Sprintf}},
+	})
+
+	events, err := siem.New(cfg.Siem)
+	if err != nil {
+		return fmt.Errorf("Cannot initialize siem: %s", err.Error())
+	}
+	if events != nil {
+		go events.Run(ctx)
+	}
 
-	mgr, err := getManager(cfg.Mongo)
+	mgr, err := getManager(cfg.Mongo, cfg.Files, cfg.Risk, cfg.Sanitize, cfg.Quota, cfg.Approval, cfg.Cascade, events)
 	if err != nil {
 		return err
 	}
@@ -194,6 +368,17 @@ func Serve(ctx context.Context, cfg *config.Dispatcher) error {
 		return fmt.Errorf("Cannot initialize mailer: %s", err.Error())
 	}
 
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case newCfg := <-reload:
+				reloadConfig(newCfg, mgr, mailer)
+			}
+		}
+	}()
+
 	if cfg.Debug {
 		mgo.SetLogger(&MgoLogger{})
 		mgo.SetDebug(true)
@@ -203,19 +388,89 @@ func Serve(ctx context.Context, cfg *config.Dispatcher) error {
 
 	}
 
-	wsContainer := getRestContainer(cfg.Api)
+	wsContainer, err := getRestContainer(cfg.Api)
+	if err != nil {
+		return fmt.Errorf("Cannot initialize api: %s", err.Error())
+	}
+	wsContainer.Filter(filters.I18nFilter(bundle))
+	if !cfg.ApiUsage.Disable && cfg.ApiUsage.Interval > 0 {
+		usage := filters.NewApiUsage()
+		wsContainer.Filter(filters.ApiUsageFilter(usage))
+		go usage.Run(ctx, mgr, time.Duration(cfg.ApiUsage.Interval)*time.Second)
+	}
 	// Initialize and register services in container
-	err = initServices(wsContainer, cfg, mgr, mailer, tmpl)
+	notifier := getNotifier(cfg.Api, mgr, mailer, bundle)
+	sch := scheduler.NewMemoryScheduler(mgr.Copy())
+	sch.TargetLock = !cfg.Scheduler.ConcurrentTargetScans
+	err = initServices(wsContainer, cfg, mgr, mailer, tmpl, bundle, notifier, sch)
 	if err != nil {
 		return fmt.Errorf("Cannot initialize services: %s", err.Error())
 	}
 
+	// reports are readable without it, but old ones are migrated on every read until they are saved
+	go func(mgr *manager.Manager) {
+		defer mgr.Close()
+		if n, err := mgr.Reports.Upgrade(); err != nil {
+			logrus.Errorf("Reports upgrade: %s", err)
+		} else if n > 0 {
+			logrus.Infof("%d reports are upgraded to schema version %d", n, report.SchemaVersion)
+		}
+	}(mgr.Copy())
+
+	// issues reported before urls were normalized in fingerprints would be duplicated by next scans
+	go func(mgr *manager.Manager) {
+		defer mgr.Close()
+		if n, err := mgr.Issues.UpgradeUniqIds(); err != nil {
+			logrus.Errorf("Issue uniq ids upgrade: %s", err)
+		} else if n > 0 {
+			logrus.Infof("%d issue uniq ids are upgraded to the current fingerprint", n)
+		}
+	}(mgr.Copy())
+
+	if cfg.Cascade.Interval > 0 {
+		go cleanup.New(mgr).Run(ctx, time.Duration(cfg.Cascade.Interval)*time.Second)
+	}
+	if cfg.Summary.Interval > 0 {
+		go summary.New(mgr).Run(ctx, time.Duration(cfg.Summary.Interval)*time.Second)
+	}
+	if !cfg.Escalation.Disable && cfg.Escalation.Interval > 0 {
+		go escalation.New(mgr, notifier).Run(ctx, time.Duration(cfg.Escalation.Interval)*time.Second)
+	}
+	if !cfg.Monitor.Disable && cfg.Monitor.Interval > 0 {
+		mon := monitor.New(mgr)
+		mon.CertExpiry = time.Duration(cfg.Monitor.CertExpiryDays) * 24 * time.Hour
+		mon.Timeout = time.Duration(cfg.Monitor.Timeout) * time.Second
+		go mon.Run(ctx, time.Duration(cfg.Monitor.Interval)*time.Second)
+	}
+	if !cfg.Discovery.Disable && cfg.Discovery.Interval > 0 {
+		go discovery.New(mgr, sch).Run(ctx, time.Duration(cfg.Discovery.Interval)*time.Second)
+	}
+	if !cfg.Delivery.Disable && cfg.Delivery.Interval > 0 {
+		deliveries := delivery.New(mgr, mailer, cfg.Api.SystemEmail, cfg.Api.Host)
+		go deliveries.Run(ctx, time.Duration(cfg.Delivery.Interval)*time.Second)
+	}
+	if !cfg.Exploits.Disable && cfg.Exploits.Interval > 0 {
+		exploitSync := exploits.New(mgr)
+		exploitSync.KevUrl = cfg.Exploits.KevUrl
+		exploitSync.ExploitDbUrl = cfg.Exploits.ExploitDbUrl
+		go exploitSync.Run(ctx, time.Duration(cfg.Exploits.Interval)*time.Second)
+	}
+	if !cfg.ServiceNow.Disable && cfg.ServiceNow.Interval > 0 {
+		go servicenow.New(mgr, cfg.Api.Host).Run(ctx, time.Duration(cfg.ServiceNow.Interval)*time.Second)
+	}
+	if !cfg.Snapshot.Disable && cfg.Snapshot.Interval > 0 {
+		go snapshot.New(mgr).Run(ctx, time.Duration(cfg.Snapshot.Interval)*time.Second)
+	}
+
 	// Swagger should be initialized after services registration
 	if cfg.Swagger.Enable {
 		services.Swagger(wsContainer, cfg.Swagger)
 	}
 
-	app := getNegroniApp(cfg)
+	app, err := getNegroniApp(cfg)
+	if err != nil {
+		return fmt.Errorf("Cannot initialize frontend: %s", err.Error())
+	}
 	app.UseHandler(wsContainer) // set wsContainer as main handler
 
 	agentErr := runInternalAgent(ctx, mgr, app, cfg.Agent)
@@ -55,6 +55,20 @@ func (d *Docker) PullImage(name string) error {
 	return err
 }
 
+// HasImage reports whether the image is in the local cache
+func (d *Docker) HasImage(name string) (bool, error) {
+	_, err := d.Client.InspectImage(name)
+	if err == dockerclient.ErrNoSuchImage {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// LoadImage loads images from the archive made by docker save
+func (d *Docker) LoadImage(r io.Reader) error {
+	return d.Client.LoadImage(dockerclient.LoadImageOptions{InputStream: r})
+}
+
 // RunImage returns 2 response, first with created container object, second with logs.
 // I know it's kind of stupid. But I'll rewrite it later.
 func (d *Docker) RunImage(ctx context.Context, config *dockerclient.Config,
@@ -2,6 +2,7 @@ package email
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/bearded-web/bearded/pkg/config"
 	"gopkg.in/gomail.v1"
@@ -31,6 +32,7 @@ type Mailer interface {
 type Email struct {
 	cfg     *config.Email
 	backend Mailer
+	lock    sync.RWMutex
 }
 
 func New(cfg config.Email) (*Email, error) {
@@ -40,17 +42,24 @@ func New(cfg config.Email) (*Email, error) {
 }
 
 func (e *Email) Send(msg *gomail.Message) error {
+	e.lock.RLock()
+	defer e.lock.RUnlock()
 	return e.backend.Send(msg)
 }
 
 func (e *Email) Close() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
 	e.backend.Close()
 }
 
+// Set new config and recreate the backend, it's safe to call while sending
 func (e *Email) SetConfig(cfg config.Email) error {
 	if err := validateConfig(cfg); err != nil {
 		return err
 	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
 	e.cfg = &cfg
 	if e.backend != nil {
 		e.backend.Close()
@@ -0,0 +1,184 @@
+// Package escalation notifies project escalation chains about severe issues which stay unacknowledged.
+package escalation
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/facebookgo/stackerr"
+	"golang.org/x/net/context"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/notification"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/notify"
+)
+
+// lock in mongo which is held by the instance running checks
+const lockName = "escalation"
+
+type Engine struct {
+	mgr      *manager.Manager
+	notifier *notify.Dispatcher
+}
+
+func New(mgr *manager.Manager, notifier *notify.Dispatcher) *Engine {
+	return &Engine{
+		mgr:      mgr,
+		notifier: notifier,
+	}
+}
+
+// Run checks issues every interval until the context is done
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	logrus.Infof("Escalation engine is started, check interval %s", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			if err := e.mgr.Locks.Release(lockName); err != nil {
+				logrus.Error(err)
+			}
+			return
+		case <-time.After(interval):
+			// only one api instance runs the check, others wait until the lock is expired
+			if !e.mgr.Locks.Lead(lockName, interval) {
+				continue
+			}
+			if err := e.Check(time.Now().UTC()); err != nil {
+				logrus.Error(err)
+			}
+		}
+	}
+}
+
+// Check escalates issues of all projects with enabled escalation
+func (e *Engine) Check(now time.Time) error {
+	mgr := e.mgr.Copy()
+	defer mgr.Close()
+
+	projects, _, err := mgr.Projects.FilterByQuery(bson.M{"escalation.enabled": true})
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	for _, p := range projects {
+		if err := e.checkProject(mgr, p, now); err != nil {
+			logrus.Error(err)
+		}
+	}
+	return nil
+}
+
+func (e *Engine) checkProject(mgr *manager.Manager, p *project.Project, now time.Time) error {
+	policy := p.Escalation
+	query := bson.M{
+		"project":      p.Id,
+		"resolved":     false,
+		"false":        false,
+		"muted":        false,
+		"acknowledged": bson.M{"$exists": false},
+		"severityRank": bson.M{"$gte": policy.MinSeverity().Rank()},
+	}
+	if len(policy.Environments) > 0 {
+		query["environment"] = bson.M{"$in": policy.Environments}
+	}
+	issues, _, err := mgr.Issues.FilterByQuery(query)
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	for _, obj := range issues {
+		level := policy.Level(obj.EscalationAge(now))
+		if level <= obj.EscalationLevel {
+			continue
+		}
+		steps := policy.Steps[obj.EscalationLevel:level]
+		// the level is saved before notifications, so issues acknowledged meanwhile aren't escalated
+		if err := mgr.Issues.Escalate(obj, level); err != nil {
+			if mgr.IsNotFound(err) {
+				continue
+			}
+			return stackerr.Wrap(err)
+		}
+		for _, step := range steps {
+			for _, u := range recipients(mgr, p, step, now) {
+				e.notify(obj, step, u)
+			}
+			// project channels get the step once, errors are logged by notifier
+			e.notifier.NotifyProject(p, newNotification(obj, step))
+		}
+	}
+	return nil
+}
+
+// stepUsers returns the step user or members of the step team
+func stepUsers(p *project.Project, step *project.EscalationStep) []bson.ObjectId {
+	if step.Team == "" {
+		return []bson.ObjectId{step.User}
+	}
+	if t := p.GetTeam(step.Team); t != nil {
+		return t.Members
+	}
+	return nil
+}
+
+// recipients returns users of the step who aren't out of office.
+// If the step user is away, members of the user team are notified instead.
+func recipients(mgr *manager.Manager, p *project.Project, step *project.EscalationStep, now time.Time) []*user.User {
+	ids := stepUsers(p, step)
+	if step.Team == "" {
+		u, err := mgr.Users.GetById(step.User)
+		if err != nil {
+			logrus.Error(stackerr.Wrap(err))
+			return nil
+		}
+		if !u.IsAway(now) {
+			return []*user.User{u}
+		}
+		t := p.MemberTeam(step.User)
+		if t == nil {
+			logrus.Warnf("Escalation user %s is out of office and has no team in project %s", u, p)
+			return nil
+		}
+		ids = t.Members
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	users, _, err := mgr.Users.FilterByQuery(bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		return nil
+	}
+	results := []*user.User{}
+	for _, u := range users {
+		if !u.IsAway(now) {
+			results = append(results, u)
+		}
+	}
+	return results
+}
+
+func newNotification(obj *issue.TargetIssue, step *project.EscalationStep) *notify.Notification {
+	return &notify.Notification{
+		Event:    notification.EventIssueEscalated,
+		Subject:  fmt.Sprintf("Unacknowledged %s issue", obj.Severity),
+		Text:     fmt.Sprintf("Issue %q isn't acknowledged for %d hours", obj.Summary, step.After),
+		Link:     fmt.Sprintf("/#/issue/%s", obj.Id.Hex()),
+		Issue:    obj.Id,
+		Channels: step.Channels,
+
+		Environment: target.Environment(obj.Environment),
+	}
+}
+
+func (e *Engine) notify(obj *issue.TargetIssue, step *project.EscalationStep, u *user.User) {
+	n := newNotification(obj, step)
+	n.User = u
+	if err := e.notifier.Notify(n); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+	}
+}
@@ -0,0 +1,93 @@
+package escalation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/notification"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/notify"
+	"github.com/bearded-web/bearded/pkg/tests"
+)
+
+func TestCheck(t *testing.T) {
+	mongo, dbName, err := tests.RandomTestMongoUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tests.RandomTestMongoDown(mongo, dbName)
+
+	mgr := manager.New(mongo.DB(dbName))
+	u, err := mgr.Users.Create(&user.User{Email: "oncall@example.com"})
+	require.NoError(t, err)
+	p, err := mgr.Projects.Create(&project.Project{
+		Name:  "escalated",
+		Owner: u.Id,
+		Escalation: &project.Escalation{
+			Enabled: true,
+			Steps: []*project.EscalationStep{
+				{After: 1, User: u.Id, Channels: []notification.Channel{notification.ChannelEmail}},
+				{After: 4, User: u.Id, Channels: []notification.Channel{notification.ChannelEmail}},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	sent := []*notify.Notification{}
+	notifier := notify.New()
+	notifier.Register(notification.ChannelEmail, notify.SenderFunc(func(n *notify.Notification) error {
+		sent = append(sent, n)
+		return nil
+	}))
+	e := New(mgr, notifier)
+
+	obj, err := mgr.Issues.Create(&issue.TargetIssue{Project: p.Id, Target: bson.NewObjectId(), Severity: issue.SeverityHigh})
+	require.NoError(t, err)
+	acked, err := mgr.Issues.Create(&issue.TargetIssue{Project: p.Id, Target: bson.NewObjectId(), Severity: issue.SeverityHigh})
+	require.NoError(t, err)
+	acked.Acknowledge(u.Id)
+	require.NoError(t, mgr.Issues.Update(acked))
+
+	now := time.Now().UTC()
+	require.NoError(t, e.Check(now.Add(2*time.Hour)))
+	require.Len(t, sent, 1)
+	assert.Equal(t, obj.Id, sent[0].Issue)
+
+	// the next check doesn't repeat the step
+	require.NoError(t, e.Check(now.Add(3*time.Hour)))
+	assert.Len(t, sent, 1)
+
+	// escalation doesn't overwrite fields changed after the issue is loaded
+	stale, err := mgr.Issues.GetById(obj.Id)
+	require.NoError(t, err)
+	edited, err := mgr.Issues.GetById(obj.Id)
+	require.NoError(t, err)
+	edited.Summary = "edited meanwhile"
+	require.NoError(t, mgr.Issues.Update(edited))
+	other := *stale
+	require.NoError(t, mgr.Issues.Escalate(stale, 2))
+	obj, err = mgr.Issues.GetById(obj.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "edited meanwhile", obj.Summary)
+	assert.Equal(t, 2, obj.EscalationLevel)
+	// the stale level of a concurrent check doesn't match anymore
+	assert.True(t, mgr.IsNotFound(mgr.Issues.Escalate(&other, 2)))
+
+	// reopened issues are escalated again from the reopening
+	obj.Acknowledge(u.Id)
+	obj.Resolved = true
+	require.NoError(t, mgr.Issues.Update(obj))
+	require.True(t, obj.Reopen())
+	require.NoError(t, mgr.Issues.Update(obj))
+	require.NoError(t, e.Check(now.Add(30*time.Minute)))
+	assert.Len(t, sent, 1)
+	require.NoError(t, e.Check(now.Add(90*time.Minute)))
+	assert.Len(t, sent, 2)
+}
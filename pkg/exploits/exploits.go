@@ -0,0 +1,103 @@
+// Package exploits periodically syncs known exploits from exploit-db and CISA KEV,
+// issues with cves from these databases are marked as exploitable and their risk is raised.
+package exploits
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/facebookgo/stackerr"
+	"golang.org/x/net/context"
+
+	"github.com/bearded-web/bearded/models/exploit"
+	"github.com/bearded-web/bearded/pkg/manager"
+)
+
+// lock in mongo which is held by the instance running syncs
+const lockName = "exploits"
+
+type Engine struct {
+	KevUrl       string // skipped if empty
+	ExploitDbUrl string // skipped if empty
+	Client       *http.Client
+
+	mgr *manager.Manager
+}
+
+func New(mgr *manager.Manager) *Engine {
+	return &Engine{
+		Client: &http.Client{Timeout: 5 * time.Minute},
+		mgr:    mgr,
+	}
+}
+
+// Run syncs exploit databases every interval until the context is done, the first sync is right after start
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	logrus.Infof("Exploits sync is started, interval %s", interval)
+	wait := time.Duration(0)
+	for {
+		select {
+		case <-ctx.Done():
+			if err := e.mgr.Locks.Release(lockName); err != nil {
+				logrus.Error(err)
+			}
+			return
+		case <-time.After(wait):
+			wait = interval
+			// only one api instance runs the sync, others wait until the lock is expired
+			if !e.mgr.Locks.Lead(lockName, interval) {
+				continue
+			}
+			if err := e.Sync(time.Now().UTC()); err != nil {
+				logrus.Error(err)
+			}
+		}
+	}
+}
+
+// Sync downloads databases, stores the catalog and updates issues.
+// Nothing is changed if any database couldn't be read, so issues aren't unmarked by a partial catalog.
+func (e *Engine) Sync(now time.Time) error {
+	known := exploit.Catalog{}
+	if e.KevUrl != "" {
+		if err := e.fetch(e.KevUrl, known.ReadKev); err != nil {
+			return err
+		}
+	}
+	if e.ExploitDbUrl != "" {
+		if err := e.fetch(e.ExploitDbUrl, known.ReadExploitDb); err != nil {
+			return err
+		}
+	}
+
+	mgr := e.mgr.Copy()
+	defer mgr.Close()
+
+	if err := mgr.Exploits.Store(known, now); err != nil {
+		return stackerr.Wrap(err)
+	}
+	changed, err := mgr.Issues.UpdateExploits(known)
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	logrus.Infof("Exploits are synced, %d cves with exploits, %d issues are changed", len(known), changed)
+	return nil
+}
+
+func (e *Engine) fetch(url string, read func(io.Reader) error) error {
+	resp, err := e.Client.Get(url)
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return stackerr.Wrap(fmt.Errorf("%s responded %s", url, resp.Status))
+	}
+	if err := read(resp.Body); err != nil {
+		return stackerr.Wrap(fmt.Errorf("%s: %v", url, err))
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package filters
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"golang.org/x/net/context"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/apiusage"
+	"github.com/bearded-web/bearded/models/token"
+	"github.com/bearded-web/bearded/pkg/manager"
+)
+
+// AttrProjectKey keeps the project of the call which is checked by the route
+const AttrProjectKey = "__project"
+
+// ApiUsage counts api calls in memory and periodically adds them to the db,
+// so requests don't wait for usage writes
+type ApiUsage struct {
+	now func() time.Time
+
+	m       sync.Mutex
+	buckets map[apiusage.Key]*apiusage.Bucket
+}
+
+func NewApiUsage() *ApiUsage {
+	return &ApiUsage{
+		now:     func() time.Time { return time.Now().UTC() },
+		buckets: map[apiusage.Key]*apiusage.Bucket{},
+	}
+}
+
+// Record counts the call in the bucket of the current hour
+func (u *ApiUsage) Record(project, token bson.ObjectId, route string, latency time.Duration, failed bool) {
+	key := apiusage.Key{Project: project, Token: token, Route: route, Hour: u.now().Truncate(time.Hour)}
+	u.m.Lock()
+	defer u.m.Unlock()
+	b, ok := u.buckets[key]
+	if !ok {
+		b = &apiusage.Bucket{Project: key.Project, Token: key.Token, Route: key.Route, Hour: key.Hour}
+		u.buckets[key] = b
+	}
+	b.Add(latency, failed)
+}
+
+// Take returns counted buckets and starts counting from zero
+func (u *ApiUsage) Take() []*apiusage.Bucket {
+	u.m.Lock()
+	buckets := u.buckets
+	u.buckets = map[apiusage.Key]*apiusage.Bucket{}
+	u.m.Unlock()
+
+	results := make([]*apiusage.Bucket, 0, len(buckets))
+	for _, b := range buckets {
+		results = append(results, b)
+	}
+	return results
+}
+
+// Flush adds counted calls to the db, calls are lost if the db is unavailable
+func (u *ApiUsage) Flush(mgr *manager.Manager) error {
+	buckets := u.Take()
+	if len(buckets) == 0 {
+		return nil
+	}
+	return mgr.ApiUsage.Add(buckets)
+}
+
+// Run flushes calls every interval until the context is done.
+// Every api instance counts its own calls in memory, so every instance flushes them without the leader lock,
+// buckets are added with $inc, so flushes of instances don't overwrite each other.
+func (u *ApiUsage) Run(ctx context.Context, mgr *manager.Manager, interval time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			if err := u.Flush(mgr); err != nil {
+				logrus.Error(err)
+			}
+			return
+		case <-time.After(interval):
+			if err := u.Flush(mgr); err != nil {
+				logrus.Error(err)
+			}
+		}
+	}
+}
+
+// ApiUsageFilter records calls related to a project: calls of project routes which passed permission checks
+// and calls of project tokens. It should be added to the container, so every route is counted.
+func ApiUsageFilter(usage *ApiUsage) restful.FilterFunction {
+	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		started := time.Now()
+		chain.ProcessFilter(req, resp)
+
+		tok := GetToken(req)
+		project := callProject(req, tok)
+		if project == "" {
+			return
+		}
+		var tokenId bson.ObjectId
+		if tok != nil {
+			tokenId = tok.Id
+		}
+		usage.Record(project, tokenId, RoutePattern(req), time.Since(started), resp.StatusCode() >= 500)
+	}
+}
+
+// SetProject attributes the call to the project, it should be called after the project is loaded
+// and permissions of the user are checked, so users can't spend usage of other projects
+func SetProject(req *restful.Request, project bson.ObjectId) {
+	req.SetAttribute(AttrProjectKey, project)
+}
+
+func callProject(req *restful.Request, tok *token.Token) bson.ObjectId {
+	if project, ok := req.Attribute(AttrProjectKey).(bson.ObjectId); ok {
+		return project
+	}
+	if tok != nil {
+		return tok.Project
+	}
+	return ""
+}
+
+// RoutePattern returns the method and the request path with path parameters replaced by their names,
+// like GET /api/v1/targets/{target-id}
+func RoutePattern(req *restful.Request) string {
+	params := req.PathParameters()
+	parts := strings.Split(req.Request.URL.Path, "/")
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		for name, value := range params {
+			if part == value {
+				parts[i] = "{" + name + "}"
+				break
+			}
+		}
+	}
+	return req.Request.Method + " " + strings.Join(parts, "/")
+}
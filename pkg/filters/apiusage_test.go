@@ -0,0 +1,87 @@
+package filters
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/emicklei/go-restful"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestApiUsage(t *testing.T) {
+	now := time.Date(2015, 5, 1, 10, 20, 0, 0, time.UTC)
+	u := NewApiUsage()
+	u.now = func() time.Time { return now }
+	project, token := bson.NewObjectId(), bson.NewObjectId()
+
+	u.Record(project, token, "GET /api/v1/issues", 10*time.Millisecond, false)
+	u.Record(project, token, "GET /api/v1/issues", 30*time.Millisecond, true)
+	u.Record(project, "", "GET /api/v1/issues", 5*time.Millisecond, false)
+	now = now.Add(time.Hour)
+	u.Record(project, token, "GET /api/v1/issues", 5*time.Millisecond, false)
+
+	buckets := u.Take()
+	require.Len(t, buckets, 3)
+	for _, b := range buckets {
+		if b.Token == token && b.Hour.Hour() == 10 {
+			assert.Equal(t, 2, b.Calls)
+			assert.Equal(t, 1, b.Errors)
+			assert.Equal(t, 40, b.Latency)
+			assert.Equal(t, 30, b.MaxLatency)
+		} else {
+			assert.Equal(t, 1, b.Calls)
+		}
+	}
+	assert.Len(t, u.Take(), 0)
+}
+
+func TestApiUsageFilter(t *testing.T) {
+	project := bson.NewObjectId()
+	u := NewApiUsage()
+	container := restful.NewContainer()
+	container.Router(restful.CurlyRouter{})
+	container.Filter(ApiUsageFilter(u))
+	ws := &restful.WebService{}
+	ws.Path("/api/v1/projects")
+	ws.Route(ws.GET("{project-id}").To(func(req *restful.Request, resp *restful.Response) {
+		if req.PathParameter("project-id") == project.Hex() {
+			SetProject(req, project)
+		}
+	}))
+	container.Add(ws)
+
+	// ids which aren't checked by the route aren't counted
+	for _, url := range []string{"/api/v1/projects/" + bson.NewObjectId().Hex(), "/api/v1/projects/1?project=" + project.Hex()} {
+		req, _ := http.NewRequest("GET", url, nil)
+		container.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	assert.Len(t, u.Take(), 0)
+
+	req, _ := http.NewRequest("GET", "/api/v1/projects/"+project.Hex(), nil)
+	container.ServeHTTP(httptest.NewRecorder(), req)
+	buckets := u.Take()
+	require.Len(t, buckets, 1)
+	assert.Equal(t, project, buckets[0].Project)
+}
+
+func TestRoutePattern(t *testing.T) {
+	var pattern string
+	container := restful.NewContainer()
+	container.Router(restful.CurlyRouter{})
+	container.Filter(func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		pattern = RoutePattern(req)
+		chain.ProcessFilter(req, resp)
+	})
+	ws := &restful.WebService{}
+	ws.Path("/api/v1/projects")
+	ws.Route(ws.GET("{project-id}/members/{user-id}").To(func(*restful.Request, *restful.Response) {}))
+	container.Add(ws)
+
+	req, _ := http.NewRequest("GET", "/api/v1/projects/55/members/66", nil)
+	container.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "GET /api/v1/projects/{project-id}/members/{user-id}", pattern)
+}
@@ -18,6 +18,20 @@ const (
 	AttrUserKey    = "__user"
 )
 
+// AdminRequiredFilter allows requests only for admins, it should go after AuthRequiredFilter
+func AdminRequiredFilter(mgr *manager.Manager) restful.FilterFunction {
+	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		mgrCopy := services.RequestManager(req, mgr)
+		admin := mgrCopy.Permission.IsAdmin(GetUser(req))
+		mgrCopy.Close()
+		if !admin {
+			resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+			return
+		}
+		chain.ProcessFilter(req, resp)
+	}
+}
+
 func AuthRequiredFilter(mgr *manager.Manager) restful.FilterFunction {
 	// TODO (m0sth8): It's not a good solution to make db request on every http request. Fix it.
 	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
@@ -32,9 +46,9 @@ func AuthRequiredFilter(mgr *manager.Manager) restful.FilterFunction {
 			resp.WriteServiceError(http.StatusUnauthorized, services.AuthReqErr)
 			return
 		}
-		mgrCopy := mgr.Copy()
+		mgrCopy := services.RequestManager(req, mgr)
 		defer mgrCopy.Close() // if something goes wrong
-		user, err := mgr.Users.GetById(mgr.ToId(userId))
+		user, err := mgrCopy.Users.GetById(mgrCopy.ToId(userId))
 		mgrCopy.Close() // manually close manager here, because defer will be triggered too late
 
 		if err != nil {
@@ -0,0 +1,42 @@
+package filters
+
+import (
+	"errors"
+
+	"github.com/emicklei/go-restful"
+
+	"github.com/bearded-web/bearded/pkg/config"
+)
+
+var defaultCorsHeaders = []string{"Content-Type", "Accept", "Authorization", "X-Requested-With"}
+
+// CorsFilter returns container filter which handles cross origin requests and preflight OPTIONS requests.
+// Credentials can't be allowed for any origin, every site could make authorized requests with user cookies then.
+func CorsFilter(cfg config.Cors, container *restful.Container) (restful.FilterFunction, error) {
+	headers := cfg.AllowedHeaders
+	if len(headers) == 0 {
+		headers = defaultCorsHeaders
+	}
+	origins := []string{}
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			// restful allows all origins if the list is empty
+			origins = []string{}
+			break
+		}
+		origins = append(origins, origin)
+	}
+	if len(origins) == 0 && cfg.AllowCredentials {
+		return nil, errors.New("cors credentials can't be allowed for any origin, set allowed origins explicitly")
+	}
+	cors := restful.CrossOriginResourceSharing{
+		AllowedDomains: origins,
+		AllowedHeaders: headers,
+		AllowedMethods: cfg.AllowedMethods,
+		ExposeHeaders:  cfg.ExposeHeaders,
+		CookiesAllowed: cfg.AllowCredentials,
+		MaxAge:         cfg.MaxAge,
+		Container:      container,
+	}
+	return cors.Filter, nil
+}
@@ -0,0 +1,71 @@
+package filters
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emicklei/go-restful"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bearded-web/bearded/pkg/config"
+)
+
+func TestCorsFilter(t *testing.T) {
+	container := restful.NewContainer()
+	ws := &restful.WebService{}
+	ws.Path("/api/v1/items")
+	ws.Route(ws.GET("").To(func(_ *restful.Request, resp *restful.Response) {
+		resp.WriteHeader(http.StatusOK)
+	}))
+	ws.Route(ws.POST("").To(func(_ *restful.Request, resp *restful.Response) {
+		resp.WriteHeader(http.StatusCreated)
+	}))
+	container.Add(ws)
+	filter, err := CorsFilter(config.Cors{
+		AllowedOrigins:   []string{"http://front.local"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	}, container)
+	require.NoError(t, err)
+	container.Filter(filter)
+
+	do := func(method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, "/api/v1/items", nil)
+		req.Header.Set("Origin", origin)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		container.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := do("GET", "http://front.local", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "http://front.local", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+
+	rec = do("OPTIONS", "http://front.local", map[string]string{
+		"Access-Control-Request-Method":  "POST",
+		"Access-Control-Request-Headers": "Content-Type, Authorization",
+	})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Access-Control-Allow-Methods"), "POST")
+	assert.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+
+	rec = do("GET", "http://evil.local", nil)
+	assert.Equal(t, "", rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCorsFilterAnyOrigin(t *testing.T) {
+	container := restful.NewContainer()
+	_, err := CorsFilter(config.Cors{AllowedOrigins: []string{"*"}}, container)
+	assert.NoError(t, err)
+
+	_, err = CorsFilter(config.Cors{AllowedOrigins: []string{"http://front.local", "*"}, AllowCredentials: true}, container)
+	assert.Error(t, err)
+	_, err = CorsFilter(config.Cors{AllowCredentials: true}, container)
+	assert.Error(t, err)
+}
@@ -0,0 +1,113 @@
+package filters
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+
+	"github.com/emicklei/go-restful"
+
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/i18n"
+)
+
+// Locale returns the locale of the request, the user preference goes first, then Accept-Language header
+func Locale(req *restful.Request, b *i18n.Bundle) string {
+	if u, ok := req.Attribute(AttrUserKey).(*user.User); ok && u.Locale != "" && b.Has(u.Locale) {
+		return u.Locale
+	}
+	return b.Match(req.HeaderParameter("Accept-Language"))
+}
+
+// I18nFilter translates messages of error responses to the locale of the request.
+// The locale is taken when the error is written, so the user is known for routes with authorization.
+func I18nFilter(b *i18n.Bundle) restful.FilterFunction {
+	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		if len(b.Locales()) == 0 {
+			chain.ProcessFilter(req, resp)
+			return
+		}
+		w := &errorTranslator{ResponseWriter: resp.ResponseWriter, locale: func() string { return Locale(req, b) }, bundle: b}
+		resp.ResponseWriter = w
+		chain.ProcessFilter(req, resp)
+		resp.ResponseWriter = w.ResponseWriter
+		w.flush()
+	}
+}
+
+// errorTranslator buffers responses with error statuses and translates them on flush
+type errorTranslator struct {
+	http.ResponseWriter
+	locale func() string
+	bundle *i18n.Bundle
+	status int
+	buf    *bytes.Buffer // nil if the response isn't an error
+}
+
+func (w *errorTranslator) WriteHeader(status int) {
+	if w.status != 0 {
+		return
+	}
+	w.status = status
+	if status >= http.StatusBadRequest {
+		w.buf = &bytes.Buffer{}
+		return
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *errorTranslator) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.buf != nil {
+		return w.buf.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush passes streamed responses through
+func (w *errorTranslator) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok && w.buf == nil {
+		f.Flush()
+	}
+}
+
+func (w *errorTranslator) flush() {
+	if w.buf == nil {
+		return
+	}
+	data := w.translate(w.buf.Bytes())
+	w.Header().Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+	w.ResponseWriter.Write(data)
+}
+
+// translate changes Message of service and validation errors, other bodies are translated as plain text
+func (w *errorTranslator) translate(data []byte) []byte {
+	locale := w.locale()
+	if locale == i18n.Fallback {
+		return data
+	}
+	body := map[string]interface{}{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return []byte(w.bundle.Translate(locale, string(data)))
+	}
+	if msg, ok := body["Message"].(string); ok {
+		body["Message"] = w.bundle.Translate(locale, msg)
+	}
+	if fields, ok := body["Fields"].([]interface{}); ok {
+		for _, raw := range fields {
+			if field, ok := raw.(map[string]interface{}); ok {
+				if msg, ok := field["message"].(string); ok {
+					field["message"] = w.bundle.Translate(locale, msg)
+				}
+			}
+		}
+	}
+	translated, err := json.MarshalIndent(body, "", " ")
+	if err != nil {
+		return data
+	}
+	return translated
+}
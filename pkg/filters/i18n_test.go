@@ -0,0 +1,100 @@
+package filters
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emicklei/go-restful"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/i18n"
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+func TestI18nFilter(t *testing.T) {
+	b := i18n.New()
+	require.NoError(t, b.Add("ru", map[string]string{
+		"Project not found":   "Проект не найден",
+		"too long":            "слишком длинное",
+		"Not found":           "Не найдено",
+		"unknown timezone %s": "неизвестная временная зона %s",
+	}))
+
+	container := restful.NewContainer()
+	ws := &restful.WebService{}
+	ws.Path("/api/v1/items").Produces(restful.MIME_JSON)
+	ws.Route(ws.GET("error").To(func(_ *restful.Request, resp *restful.Response) {
+		resp.WriteServiceError(http.StatusBadRequest, restful.NewError(5, "unknown timezone Mars"))
+	}))
+	ws.Route(ws.GET("user").Filter(func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		req.SetAttribute(AttrUserKey, &user.User{Locale: "ru"})
+		chain.ProcessFilter(req, resp)
+	}).To(func(_ *restful.Request, resp *restful.Response) {
+		resp.WriteServiceError(http.StatusNotFound, restful.NewError(5, "Project not found"))
+	}))
+	ws.Route(ws.GET("fields").To(func(_ *restful.Request, resp *restful.Response) {
+		resp.WriteHeader(http.StatusBadRequest)
+		resp.WriteEntity(map[string]interface{}{"Code": 5, "Message": "Validation error", "Fields": validate.Errors{
+			{Field: "name", Code: validate.CodeMax, Message: "too long"},
+		}})
+	}))
+	ws.Route(ws.GET("text").To(func(_ *restful.Request, resp *restful.Response) {
+		resp.WriteErrorString(http.StatusNotFound, "Not found")
+	}))
+	ws.Route(ws.GET("ok").To(func(_ *restful.Request, resp *restful.Response) {
+		resp.WriteEntity(map[string]string{"Message": "Project not found"})
+	}))
+	ws.Route(ws.GET("stream").To(func(_ *restful.Request, resp *restful.Response) {
+		resp.ResponseWriter.WriteHeader(http.StatusOK)
+		resp.Write([]byte("{}\n"))
+		resp.ResponseWriter.(http.Flusher).Flush()
+	}))
+	container.Add(ws)
+	container.Filter(I18nFilter(b))
+
+	do := func(path, accept string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", "/api/v1/items/"+path, nil)
+		if accept != "" {
+			req.Header.Set("Accept-Language", accept)
+		}
+		rec := httptest.NewRecorder()
+		container.ServeHTTP(rec, req)
+		return rec
+	}
+	message := func(rec *httptest.ResponseRecorder) string {
+		body := map[string]interface{}{}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		return body["Message"].(string)
+	}
+
+	rec := do("error", "ru-RU,ru;q=0.9")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "неизвестная временная зона Mars", message(rec))
+	assert.Equal(t, "unknown timezone Mars", message(do("error", "")))
+
+	// the user preference is used even without Accept-Language
+	rec = do("user", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "Проект не найден", message(rec))
+
+	rec = do("fields", "ru")
+	assert.Contains(t, rec.Body.String(), "слишком длинное")
+
+	rec = do("text", "ru")
+	assert.Equal(t, "Не найдено", rec.Body.String())
+
+	// successful responses aren't changed
+	rec = do("ok", "ru")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "Project not found", message(rec))
+
+	// streamed responses are flushed through
+	rec = do("stream", "ru")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, "{}\n", rec.Body.String())
+}
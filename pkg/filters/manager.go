@@ -0,0 +1,21 @@
+package filters
+
+import (
+	"github.com/emicklei/go-restful"
+
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/services"
+)
+
+// ManagerFilter copies the manager once per request, filters and handlers get it by services.RequestManager.
+// The copy doesn't take a socket till the first query, so requests without db queries are cheap.
+// List queries of the copy are stopped when the client aborts the request.
+func ManagerFilter(mgr *manager.Manager) restful.FilterFunction {
+	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		reqMgr := mgr.Copy()
+		defer reqMgr.Close()
+		reqMgr.SetContext(req.Request.Context())
+		req.SetAttribute(services.AttrManagerKey, reqMgr.Borrow())
+		chain.ProcessFilter(req, resp)
+	}
+}
@@ -0,0 +1,131 @@
+package filters
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emicklei/go-restful"
+
+	"github.com/bearded-web/bearded/services"
+)
+
+// RateLimiter counts requests by key in fixed time windows
+type RateLimiter struct {
+	limit  int
+	period time.Duration
+	now    func() time.Time
+
+	m       sync.Mutex
+	started time.Time
+	counts  map[string]int
+}
+
+func NewRateLimiter(limit int, period time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:  limit,
+		period: period,
+		now:    time.Now,
+		counts: map[string]int{},
+	}
+}
+
+// Allow registers a request with the key and reports whether the limit isn't exceeded
+func (l *RateLimiter) Allow(key string) bool {
+	if l.limit <= 0 {
+		return true
+	}
+	l.m.Lock()
+	defer l.m.Unlock()
+	now := l.now()
+	if now.Sub(l.started) >= l.period {
+		// new window, forget all previous counters
+		l.started = now
+		l.counts = map[string]int{}
+	}
+	l.counts[key]++
+	return l.counts[key] <= l.limit
+}
+
+// RateLimitFilter rejects requests from the same client ip above the limiter threshold
+func RateLimitFilter(limiter *RateLimiter) restful.FilterFunction {
+	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+		if !limiter.Allow(ClientIp(req.Request)) {
+			resp.WriteServiceError(http.StatusTooManyRequests, services.TooManyReqErr)
+			return
+		}
+		chain.ProcessFilter(req, resp)
+	}
+}
+
+var trustedProxies = []*net.IPNet{}
+
+// TrustProxies sets reverse proxies which X-Forwarded-For header is trusted, ips and networks in cidr notation are accepted.
+// Call it before serving requests.
+func TrustProxies(proxies []string) error {
+	nets := []*net.IPNet{}
+	for _, p := range proxies {
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return fmt.Errorf("wrong proxy address %s", p)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				bits = 8 * net.IPv4len
+			}
+			p = fmt.Sprintf("%s/%d", p, bits)
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return fmt.Errorf("wrong proxy network %s", p)
+		}
+		nets = append(nets, n)
+	}
+	trustedProxies = nets
+	return nil
+}
+
+func isTrustedProxy(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIp returns the ip of the client which sent the request without the port.
+// Requests from trusted proxies are attributed to the last address in X-Forwarded-For which isn't a trusted proxy,
+// the header of other requests is ignored, because clients could set it to anything.
+func ClientIp(r *http.Request) string {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	if !isTrustedProxy(ip) {
+		return ip
+	}
+	forwarded := []string{}
+	for _, header := range r.Header["X-Forwarded-For"] {
+		for _, addr := range strings.Split(header, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				forwarded = append(forwarded, addr)
+			}
+		}
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip = forwarded[i]
+		if !isTrustedProxy(ip) {
+			break
+		}
+	}
+	return ip
+}
@@ -0,0 +1,53 @@
+package filters
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Now()
+	l := NewRateLimiter(2, time.Minute)
+	l.now = func() time.Time { return now }
+
+	assert.True(t, l.Allow("1.1.1.1"))
+	assert.True(t, l.Allow("1.1.1.1"))
+	assert.False(t, l.Allow("1.1.1.1"))
+	assert.True(t, l.Allow("2.2.2.2"))
+
+	// the next window
+	now = now.Add(time.Minute)
+	assert.True(t, l.Allow("1.1.1.1"))
+
+	// disabled limiter
+	assert.True(t, NewRateLimiter(0, time.Minute).Allow("1.1.1.1"))
+}
+
+func TestClientIp(t *testing.T) {
+	defer TrustProxies(nil)
+	req := &http.Request{RemoteAddr: "10.0.0.1:1234", Header: http.Header{}}
+	req.Header.Add("X-Forwarded-For", "1.1.1.1, 2.2.2.2")
+	req.Header.Add("X-Forwarded-For", "10.0.1.1")
+
+	// forwarded header isn't trusted by default
+	assert.Equal(t, "10.0.0.1", ClientIp(req))
+
+	require.NoError(t, TrustProxies([]string{"10.0.0.1", "10.0.1.0/24"}))
+	assert.Equal(t, "2.2.2.2", ClientIp(req))
+
+	// header of untrusted clients is ignored
+	req.RemoteAddr = "3.3.3.3:1234"
+	assert.Equal(t, "3.3.3.3", ClientIp(req))
+
+	// all addresses are proxies
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("X-Forwarded-For", "10.0.1.2")
+	assert.Equal(t, "10.0.1.2", ClientIp(req))
+
+	assert.Error(t, TrustProxies([]string{"proxy"}))
+	assert.Error(t, TrustProxies([]string{"10.0.0.0/33"}))
+}
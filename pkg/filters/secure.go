@@ -0,0 +1,59 @@
+package filters
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/bearded-web/bearded/pkg/config"
+)
+
+// SecureHeaders is a negroni middleware which sets hardened http response headers.
+// Headers are set before the next handler is called, so a handler is able to override or remove them.
+type SecureHeaders struct {
+	cfg  config.Secure
+	skip []string
+}
+
+// NewSecureHeaders creates middleware, requests with skip path prefixes are served without security headers
+func NewSecureHeaders(cfg config.Secure, skip ...string) *SecureHeaders {
+	return &SecureHeaders{
+		cfg:  cfg,
+		skip: append(skip, cfg.SkipPaths...),
+	}
+}
+
+func (s *SecureHeaders) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	for _, prefix := range s.skip {
+		if prefix != "" && strings.HasPrefix(r.URL.Path, prefix) {
+			next(rw, r)
+			return
+		}
+	}
+	h := rw.Header()
+	// hsts makes sense only for https connections, including ones terminated by proxy
+	if s.cfg.HSTSMaxAge > 0 && isHttps(r) {
+		hsts := fmt.Sprintf("max-age=%d", s.cfg.HSTSMaxAge)
+		if s.cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		h.Set("Strict-Transport-Security", hsts)
+	}
+	if s.cfg.FrameOptions != "" {
+		h.Set("X-Frame-Options", s.cfg.FrameOptions)
+	}
+	if s.cfg.ContentTypeNosniff {
+		h.Set("X-Content-Type-Options", "nosniff")
+	}
+	if s.cfg.ReferrerPolicy != "" {
+		h.Set("Referrer-Policy", s.cfg.ReferrerPolicy)
+	}
+	if s.cfg.ContentSecurityPolicy != "" {
+		h.Set("Content-Security-Policy", s.cfg.ContentSecurityPolicy)
+	}
+	next(rw, r)
+}
+
+func isHttps(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
@@ -0,0 +1,42 @@
+package filters
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bearded-web/bearded/pkg/config"
+)
+
+func TestSecureHeaders(t *testing.T) {
+	cfg := config.NewDispatcher().Secure
+	s := NewSecureHeaders(cfg, "/swagger/")
+
+	do := func(path string, headers map[string]string) http.Header {
+		req, _ := http.NewRequest("GET", path, nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req, func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		})
+		return rec.Header()
+	}
+
+	h := do("/api/v1/me", nil)
+	assert.Equal(t, "DENY", h.Get("X-Frame-Options"))
+	assert.Equal(t, "nosniff", h.Get("X-Content-Type-Options"))
+	assert.Equal(t, cfg.ContentSecurityPolicy, h.Get("Content-Security-Policy"))
+	// plain http
+	assert.Equal(t, "", h.Get("Strict-Transport-Security"))
+
+	h = do("/", map[string]string{"X-Forwarded-Proto": "https"})
+	assert.Equal(t, "max-age=31536000", h.Get("Strict-Transport-Security"))
+
+	h = do("/swagger/index.html", nil)
+	assert.Equal(t, "", h.Get("X-Frame-Options"))
+	assert.Equal(t, "", h.Get("Content-Security-Policy"))
+}
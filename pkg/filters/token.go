@@ -4,48 +4,74 @@ import (
 	"strings"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/bearded-web/bearded/models/token"
 	"github.com/bearded-web/bearded/models/user"
 	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/services"
 	"github.com/emicklei/go-restful"
 )
 
+// request attribute with the token which authenticated the request
+const AttrTokenKey = "__token"
+
 func getUserByToken(mgr *manager.Manager, authorization string) *user.User {
+	_, u := authenticate(mgr, authorization)
+	return u
+}
+
+// authenticate returns the token from the authorization header and its user
+func authenticate(mgr *manager.Manager, authorization string) (*token.Token, *user.User) {
 	if authorization == "" {
-		return nil
+		return nil, nil
 	}
 	parts := strings.Split(authorization, " ")
 	if len(parts) != 2 || parts[0] != "Bearer" {
-		return nil
+		return nil, nil
 	}
 
 	tokenHash := parts[1]
 
-	mgrCopy := mgr.Copy()
-	defer mgrCopy.Close()
-
-	token, err := mgrCopy.Tokens.GetByHash(tokenHash)
+	tok, err := mgr.Tokens.GetByHash(tokenHash)
 	if err != nil {
-		if !mgrCopy.IsNotFound(err) {
+		if !mgr.IsNotFound(err) {
 			logrus.Error(err)
 		}
-		return nil
+		return nil, nil
 	}
-	u, err := mgrCopy.Users.GetById(token.User)
+	// ingest tokens are write-only, they are checked by the ingest service
+	if tok.IsIngest() {
+		return nil, nil
+	}
+	u, err := mgr.Users.GetById(tok.User)
 	if err != nil {
-		if !mgrCopy.IsNotFound(err) {
+		if !mgr.IsNotFound(err) {
 			logrus.Error(err)
 		}
-		return nil
+		return nil, nil
 	}
-	return u
+	return tok, u
 }
 
 func AuthTokenFilter(mgr *manager.Manager) restful.FilterFunction {
 	return func(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
-		if u := getUserByToken(mgr, req.Request.Header.Get("Authorization")); u != nil {
-			req.SetAttribute(AttrUserKey, u)
+		if authorization := req.Request.Header.Get("Authorization"); authorization != "" {
+			reqMgr := services.RequestManager(req, mgr)
+			tok, u := authenticate(reqMgr, authorization)
+			reqMgr.Close()
+			if u != nil {
+				req.SetAttribute(AttrUserKey, u)
+				req.SetAttribute(AttrTokenKey, tok)
+			}
 		}
 		chain.ProcessFilter(req, resp)
 
 	}
 }
+
+// GetToken returns the token which authenticated the request, nil for session requests
+func GetToken(req *restful.Request) *token.Token {
+	if tok, ok := req.Attribute(AttrTokenKey).(*token.Token); ok {
+		return tok
+	}
+	return nil
+}
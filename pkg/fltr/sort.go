@@ -11,6 +11,7 @@ const (
 	DefaultSortName       = "sort"
 	DefaultSortSeparator  = ","
 	DefaultSortFieldLimit = 3
+	DefaultSortTieBreaker = "_id"
 )
 
 type Sorter struct {
@@ -18,6 +19,10 @@ type Sorter struct {
 	SortSeparator  string
 	SortFieldLimit int
 	Fields         []string
+	// db fields used instead of sort fields, e.g. enums should be sorted by rank
+	Aliases map[string]string
+	// added to the end of non empty sort to make pagination stable, set empty to disable
+	TieBreaker string
 }
 
 func NewSorter(fields ...string) *Sorter {
@@ -26,29 +31,101 @@ func NewSorter(fields ...string) *Sorter {
 		SortSeparator:  DefaultSortSeparator,
 		SortFieldLimit: DefaultSortFieldLimit,
 		Fields:         fields,
+		Aliases:        map[string]string{},
+		TieBreaker:     DefaultSortTieBreaker,
 	}
 }
 
+// Alias adds sort field which is sorted by dbField
+func (s *Sorter) Alias(field, dbField string) *Sorter {
+	s.Fields = append(s.Fields, field)
+	s.Aliases[field] = dbField
+	return s
+}
+
 func (s *Sorter) Param() *restful.Parameter {
-	return restful.QueryParameter(s.SortName, fmt.Sprintf("sort by %s [-%s]", strings.Join(s.Fields, "|"), s.SortSeparator))
+	return restful.QueryParameter(s.SortName, fmt.Sprintf(
+		"sort by up to %d fields from [%s] separated by '%s', prefix field with - for descending order, e.g. -%s%s%s",
+		s.SortFieldLimit, strings.Join(s.Fields, "|"), s.SortSeparator, s.example(0), s.SortSeparator, s.example(1)))
 }
 
+// Parse returns db sort fields from request, wrong fields are skipped
 func (s *Sorter) Parse(req *restful.Request) []string {
+	return s.ParseString(req.QueryParameter(s.SortName))
+}
+
+// ParseStrict works like Parse, but returns an error for unknown, duplicated or too many fields
+func (s *Sorter) ParseStrict(req *restful.Request) ([]string, error) {
+	return s.ParseStringStrict(req.QueryParameter(s.SortName))
+}
+
+// ParseStringStrict works like ParseStrict, but takes sort fields from string
+func (s *Sorter) ParseStringStrict(p string) ([]string, error) {
+	return s.parse(p, true)
+}
+
+// ParseString works like Parse, but takes sort fields from string
+func (s *Sorter) ParseString(p string) []string {
+	result, _ := s.parse(p, false)
+	return result
+}
+
+func (s *Sorter) parse(p string, strict bool) ([]string, error) {
 	result := []string{}
-	p := req.QueryParameter(s.SortName)
 	if p == "" {
-		return result
+		return result, nil
 	}
 	fields := strings.Split(p, s.SortSeparator)
 	if len(fields) > s.SortFieldLimit {
+		if strict {
+			return nil, fmt.Errorf("%s: max %d fields are allowed", s.SortName, s.SortFieldLimit)
+		}
 		fields = fields[:s.SortFieldLimit]
 	}
+	used := map[string]bool{}
 	for _, field := range fields {
-		for _, exField := range s.Fields {
-			if exField == field || (strings.HasPrefix(field, "-") && len(field) == (len(exField)+1) && field[1:] == exField) {
-				result = append(result, field)
+		name := strings.TrimPrefix(strings.TrimSpace(field), "-")
+		desc := strings.HasPrefix(strings.TrimSpace(field), "-")
+		if !s.isAllowed(name) {
+			if strict {
+				return nil, fmt.Errorf("%s: unknown field %s", s.SortName, name)
 			}
+			continue
 		}
+		if used[name] {
+			if strict {
+				return nil, fmt.Errorf("%s: field %s is duplicated", s.SortName, name)
+			}
+			continue
+		}
+		used[name] = true
+		dbField := name
+		if alias, ok := s.Aliases[name]; ok {
+			dbField = alias
+		}
+		if desc {
+			dbField = "-" + dbField
+		}
+		result = append(result, dbField)
 	}
-	return result
+	if len(result) > 0 && s.TieBreaker != "" && !used[s.TieBreaker] {
+		result = append(result, s.TieBreaker)
+	}
+	return result, nil
+}
+
+func (s *Sorter) isAllowed(field string) bool {
+	for _, exField := range s.Fields {
+		if exField == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Sorter) example(i int) string {
+	if i < len(s.Fields) {
+		return s.Fields[i]
+	}
+	return "field"
 }
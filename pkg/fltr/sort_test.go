@@ -0,0 +1,51 @@
+package fltr
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/emicklei/go-restful"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sortReq(sort string) *restful.Request {
+	req, _ := http.NewRequest("GET", "/", nil)
+	q := req.URL.Query()
+	q.Set("sort", sort)
+	req.URL.RawQuery = q.Encode()
+	return restful.NewRequest(req)
+}
+
+func TestSorter(t *testing.T) {
+	s := NewSorter("created", "updated").Alias("severity", "severityRank")
+
+	assert.Equal(t, []string{"-severityRank", "created", "_id"}, s.Parse(sortReq("-severity,created,unknown")))
+	assert.Equal(t, []string{"severityRank", "-updated", "_id"}, s.ParseString("severity,-updated"))
+	assert.Equal(t, []string{}, s.ParseString(""))
+	assert.Equal(t, []string{}, s.ParseString("unknown"))
+	// duplicates are skipped
+	assert.Equal(t, []string{"created", "updated", "_id"}, s.ParseString("created,updated,-created"))
+
+	s.TieBreaker = ""
+	assert.Equal(t, []string{"-created"}, s.ParseString("-created"))
+}
+
+func TestSorterStrict(t *testing.T) {
+	s := NewSorter("created", "updated", "severity")
+
+	sort, err := s.ParseStrict(sortReq("-severity,created"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-severity", "created", "_id"}, sort)
+
+	sort, err = s.ParseStrict(sortReq(""))
+	require.NoError(t, err)
+	assert.Empty(t, sort)
+
+	for _, wrong := range []string{"bla", "created,-created", "created,updated,severity,created", "created,"} {
+		_, err = s.ParseStrict(sortReq(wrong))
+		assert.Error(t, err, wrong)
+		_, err = s.ParseStringStrict(wrong)
+		assert.Error(t, err, wrong)
+	}
+}
@@ -0,0 +1,107 @@
+package frontend
+
+//go:generate go-bindata -tags embed -pkg frontend -prefix ../../extra/frontend/ -o bindata.go ../../extra/frontend/...
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// embeddedAsset and embeddedNames are set to Asset and AssetNames of the generated bindata.go
+// in builds with the embed tag, they are nil if frontend isn't embedded to the binary
+var (
+	embeddedAsset func(name string) ([]byte, error)
+	embeddedNames func() []string
+)
+
+var ErrNotEmbedded = fmt.Errorf("frontend isn't embedded, run go generate ./pkg/frontend and build with -tags embed")
+
+// Embedded returns file system with assets embedded to the binary
+func Embedded() (http.FileSystem, error) {
+	if embeddedAsset == nil || embeddedNames == nil {
+		return nil, ErrNotEmbedded
+	}
+	return NewAssetFS(embeddedAsset, embeddedNames), nil
+}
+
+// AssetFS implements http.FileSystem over bindata functions
+type AssetFS struct {
+	asset func(name string) ([]byte, error)
+	names []string
+	mod   time.Time
+}
+
+func NewAssetFS(asset func(name string) ([]byte, error), names func() []string) *AssetFS {
+	n := names()
+	sort.Strings(n)
+	return &AssetFS{asset: asset, names: n, mod: time.Now()}
+}
+
+func (fs *AssetFS) Open(name string) (http.File, error) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if data, err := fs.asset(name); err == nil {
+		return &assetFile{Reader: bytes.NewReader(data), info: &assetInfo{
+			name: path.Base(name),
+			size: int64(len(data)),
+			mod:  fs.mod,
+		}}, nil
+	}
+	// check if the name is a directory
+	prefix := name + "/"
+	if name == "" {
+		prefix = ""
+	}
+	for _, n := range fs.names {
+		if strings.HasPrefix(n, prefix) {
+			return &assetFile{Reader: bytes.NewReader(nil), info: &assetInfo{
+				name: path.Base(name),
+				dir:  true,
+				mod:  fs.mod,
+			}}, nil
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+type assetFile struct {
+	*bytes.Reader
+	info *assetInfo
+}
+
+func (f *assetFile) Close() error {
+	return nil
+}
+
+func (f *assetFile) Readdir(count int) ([]os.FileInfo, error) {
+	// directory listing isn't supported
+	return []os.FileInfo{}, nil
+}
+
+func (f *assetFile) Stat() (os.FileInfo, error) {
+	return f.info, nil
+}
+
+type assetInfo struct {
+	name string
+	size int64
+	dir  bool
+	mod  time.Time
+}
+
+func (i *assetInfo) Name() string       { return i.name }
+func (i *assetInfo) Size() int64        { return i.size }
+func (i *assetInfo) ModTime() time.Time { return i.mod }
+func (i *assetInfo) IsDir() bool        { return i.dir }
+func (i *assetInfo) Sys() interface{}   { return nil }
+func (i *assetInfo) Mode() os.FileMode {
+	if i.dir {
+		return os.ModeDir | 0555
+	}
+	return 0444
+}
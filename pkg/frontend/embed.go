@@ -0,0 +1,10 @@
+//go:build embed
+// +build embed
+
+package frontend
+
+// bindata.go is generated with the same tag, so builds without it don't need the generated file
+func init() {
+	embeddedAsset = Asset
+	embeddedNames = AssetNames
+}
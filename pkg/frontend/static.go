@@ -0,0 +1,95 @@
+// Package frontend serves the web ui, optionally embedded to the binary.
+package frontend
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Static serves frontend files and falls back to the index file for unknown paths without extension,
+// so the routing could be done by the single page application.
+// It works as a negroni middleware.
+type Static struct {
+	Dir http.FileSystem
+	// Index file served for directories and unknown paths, "index.html" by default
+	Index string
+	// Cache max age in seconds for all files except index, caching is disabled if zero
+	MaxAge int
+	// requests with these path prefixes are passed to the next handler
+	Skip []string
+}
+
+func NewStatic(dir http.FileSystem, maxAge int, skip ...string) *Static {
+	return &Static{
+		Dir:    dir,
+		Index:  "index.html",
+		MaxAge: maxAge,
+		Skip:   skip,
+	}
+}
+
+func (s *Static) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		next(rw, r)
+		return
+	}
+	upath := path.Clean("/" + r.URL.Path)
+	for _, prefix := range s.Skip {
+		if strings.HasPrefix(upath, prefix) {
+			next(rw, r)
+			return
+		}
+	}
+	if s.serveFile(rw, r, upath) {
+		return
+	}
+	// paths with extension are real files, so they aren't found
+	if path.Ext(upath) != "" {
+		next(rw, r)
+		return
+	}
+	if !s.serveIndex(rw, r, "/") {
+		next(rw, r)
+	}
+}
+
+func (s *Static) serveFile(rw http.ResponseWriter, r *http.Request, name string) bool {
+	f, err := s.Dir.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	if fi.IsDir() {
+		return s.serveIndex(rw, r, name)
+	}
+	if path.Base(name) == s.Index {
+		rw.Header().Set("Cache-Control", "no-cache")
+	} else if s.MaxAge > 0 {
+		rw.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", s.MaxAge))
+	}
+	http.ServeContent(rw, r, fi.Name(), fi.ModTime(), f)
+	return true
+}
+
+func (s *Static) serveIndex(rw http.ResponseWriter, r *http.Request, dir string) bool {
+	name := path.Join(dir, s.Index)
+	f, err := s.Dir.Open(name)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil || fi.IsDir() {
+		return false
+	}
+	// index is always revalidated, because it refers to the latest assets
+	rw.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(rw, r, fi.Name(), fi.ModTime(), f)
+	return true
+}
@@ -0,0 +1,81 @@
+package frontend
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testFS() http.FileSystem {
+	files := map[string]string{
+		"index.html":      "index",
+		"js/app.js":       "app",
+		"docs/index.html": "docs",
+	}
+	return NewAssetFS(func(name string) ([]byte, error) {
+		if data, ok := files[name]; ok {
+			return []byte(data), nil
+		}
+		return nil, fmt.Errorf("asset %s not found", name)
+	}, func() []string {
+		names := []string{}
+		for name := range files {
+			names = append(names, name)
+		}
+		return names
+	})
+}
+
+func serve(s *Static, method, url string) (*httptest.ResponseRecorder, bool) {
+	rec := httptest.NewRecorder()
+	called := false
+	s.ServeHTTP(rec, httptest.NewRequest(method, url, nil), func(http.ResponseWriter, *http.Request) {
+		called = true
+	})
+	return rec, called
+}
+
+func TestStatic(t *testing.T) {
+	s := NewStatic(testFS(), 3600, "/api/")
+
+	rec, next := serve(s, "GET", "/js/app.js")
+	require.False(t, next)
+	assert.Equal(t, "app", rec.Body.String())
+	assert.Equal(t, "public, max-age=3600", rec.Header().Get("Cache-Control"))
+
+	rec, next = serve(s, "GET", "/")
+	require.False(t, next)
+	assert.Equal(t, "index", rec.Body.String())
+	assert.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+
+	rec, next = serve(s, "GET", "/docs/")
+	require.False(t, next)
+	assert.Equal(t, "docs", rec.Body.String())
+
+	// spa fallback
+	rec, next = serve(s, "GET", "/projects/123/issues")
+	require.False(t, next)
+	assert.Equal(t, "index", rec.Body.String())
+	assert.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+
+	// missing files aren't replaced with index
+	_, next = serve(s, "GET", "/js/missing.js")
+	assert.True(t, next)
+
+	_, next = serve(s, "GET", "/api/v1/me")
+	assert.True(t, next)
+
+	_, next = serve(s, "POST", "/projects")
+	assert.True(t, next)
+}
+
+func TestEmbedded(t *testing.T) {
+	if embeddedAsset == nil {
+		_, err := Embedded()
+		assert.Equal(t, ErrNotEmbedded, err)
+	}
+}
@@ -0,0 +1,516 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Resolver returns the value of the field for the source object.
+// Returned slices are treated as lists.
+type Resolver func(p Params) (interface{}, error)
+
+type Params struct {
+	Source  interface{}
+	Args    map[string]interface{}
+	Context interface{}
+}
+
+// Object describes fields with resolvers or nested types.
+// Fields which aren't described are taken from the json representation of the source,
+// if Strict isn't set.
+type Object struct {
+	Name   string
+	Fields map[string]*ObjectField
+	Strict bool
+}
+
+// BatchResolver returns values of the field for all sources of the same level at once,
+// values are in the order of sources. It's used to load children of many objects with one query.
+type BatchResolver func(p BatchParams) ([]interface{}, error)
+
+type BatchParams struct {
+	Sources []interface{}
+	Args    map[string]interface{}
+	Context interface{}
+}
+
+type ObjectField struct {
+	Type    *Object // nil for scalars and plain json objects
+	Resolve Resolver
+	Batch   BatchResolver // used instead of Resolve if set
+	List    bool          // the field returns a list, its cost is multiplied by the limit argument
+}
+
+type Schema struct {
+	Query    *Object
+	MaxDepth int // 0 means unlimited
+	// MaxCost limits the number of objects a query could return, 0 means unlimited.
+	// Every object field costs 1, list fields multiply the cost of their selections by the limit argument
+	// or by ListSize if the limit isn't set, so aliases and fan-out through nested lists are counted.
+	MaxCost  int
+	ListSize int
+}
+
+type Request struct {
+	Query         string                 `json:"query" description:"graphql query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+type Result struct {
+	Data   map[string]interface{} `json:"data,omitempty"`
+	Errors []*Error               `json:"errors,omitempty"`
+}
+
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+type executor struct {
+	schema *Schema
+	vars   map[string]interface{}
+	ctx    interface{}
+	errors []*Error
+}
+
+// Prepare parses the request and chooses the operation to execute.
+// Failed requests shouldn't be executed at all, so errors are returned instead of the result.
+func (s *Schema) Prepare(req *Request) (*Operation, map[string]interface{}, error) {
+	doc, err := Parse(req.Query)
+	if err != nil {
+		return nil, nil, err
+	}
+	var op *Operation
+	if req.OperationName == "" {
+		if len(doc.Operations) > 1 {
+			return nil, nil, fmt.Errorf("operationName is required for documents with several operations")
+		}
+		op = doc.Operations[0]
+	} else {
+		for _, o := range doc.Operations {
+			if o.Name == req.OperationName {
+				op = o
+				break
+			}
+		}
+		if op == nil {
+			return nil, nil, fmt.Errorf("Unknown operation %s", req.OperationName)
+		}
+	}
+	if op.Type != "query" {
+		return nil, nil, fmt.Errorf("%s operations aren't supported", op.Type)
+	}
+	if s.MaxDepth > 0 && depth(op.Selections) > s.MaxDepth {
+		return nil, nil, fmt.Errorf("query is deeper than %d levels", s.MaxDepth)
+	}
+	vars := map[string]interface{}{}
+	for _, def := range op.Variables {
+		v, ok := req.Variables[def.Name]
+		if !ok {
+			v = def.Default
+		}
+		if v == nil && strings.HasSuffix(def.Type, "!") {
+			return nil, nil, fmt.Errorf("Variable $%s of type %s is required", def.Name, def.Type)
+		}
+		vars[def.Name] = v
+	}
+	if s.MaxCost > 0 {
+		e := &executor{schema: s, vars: vars}
+		if c := e.cost(s.Query, op.Selections); c > s.MaxCost {
+			return nil, nil, fmt.Errorf("query could return more than %d objects, lower limits or select less", s.MaxCost)
+		}
+	}
+	return op, vars, nil
+}
+
+// Execute runs the request against the schema, ctx is passed to every resolver
+func (s *Schema) Execute(req *Request, ctx interface{}) *Result {
+	op, vars, err := s.Prepare(req)
+	if err != nil {
+		return &Result{Errors: []*Error{{Message: err.Error()}}}
+	}
+	e := &executor{schema: s, vars: vars, ctx: ctx}
+	data := e.objects(s.Query, []interface{}{nil}, op.Selections, [][]interface{}{nil})[0]
+	return &Result{Data: data, Errors: e.errors}
+}
+
+// cost estimates the number of objects the selection could return, it stops counting above MaxCost
+func (e *executor) cost(obj *Object, fields []*Field) int {
+	total := 0
+	for _, f := range fields {
+		if len(f.Selections) == 0 {
+			continue
+		}
+		var def *ObjectField
+		if obj != nil {
+			def = obj.Fields[f.Name]
+		}
+		var typ *Object
+		n := 1
+		if def != nil {
+			typ = def.Type
+			if def.List {
+				n = e.listSize(f)
+			}
+		}
+		total += n * (1 + e.cost(typ, f.Selections))
+		if total > e.schema.MaxCost {
+			return total
+		}
+	}
+	return total
+}
+
+// listSize returns the limit argument of the list field capped by ListSize
+func (e *executor) listSize(f *Field) int {
+	n := e.schema.ListSize
+	raw, ok := f.Args["limit"]
+	if !ok {
+		return n
+	}
+	v, err := e.resolve(raw)
+	if err != nil {
+		return n
+	}
+	limit := 0
+	switch l := v.(type) {
+	case int:
+		limit = l
+	case float64:
+		limit = int(l)
+	}
+	if limit > 0 && (n == 0 || limit < n) {
+		return limit
+	}
+	return n
+}
+
+func depth(fields []*Field) int {
+	max := 0
+	for _, f := range fields {
+		if d := depth(f.Selections); d > max {
+			max = d
+		}
+	}
+	if len(fields) == 0 {
+		return 0
+	}
+	return max + 1
+}
+
+func (e *executor) fail(path []interface{}, format string, args ...interface{}) {
+	p := make([]interface{}, len(path))
+	copy(p, path)
+	e.errors = append(e.errors, &Error{Message: fmt.Sprintf(format, args...), Path: p})
+}
+
+// objects completes the fields of all sources of the same level at once,
+// so batch resolvers are called once per field and level instead of once per source
+func (e *executor) objects(obj *Object, sources []interface{}, fields []*Field, paths [][]interface{}) []map[string]interface{} {
+	results := make([]map[string]interface{}, len(sources))
+	for i := range results {
+		results[i] = map[string]interface{}{}
+	}
+	if len(sources) == 0 {
+		return results
+	}
+	raws := make([]map[string]interface{}, len(sources)) // json representations of sources, made lazily
+	for _, f := range fields {
+		// directives don't depend on the source
+		if !e.included(f, paths[0]) {
+			continue
+		}
+		key := f.Key()
+		if f.Name == "__typename" {
+			for _, result := range results {
+				result[key] = obj.Name
+			}
+			continue
+		}
+		fieldPaths := make([][]interface{}, len(sources))
+		for i := range sources {
+			fieldPaths[i] = appendPath(paths[i], key)
+		}
+		values := make([]interface{}, len(sources))
+		errs := make([]error, len(sources))
+		args, err := e.args(f.Args)
+		def := obj.Fields[f.Name]
+		switch {
+		case err != nil:
+			for i := range errs {
+				errs[i] = err
+			}
+		case def != nil && def.Batch != nil:
+			values, err = def.Batch(BatchParams{Sources: sources, Args: args, Context: e.ctx})
+			if err == nil && len(values) != len(sources) {
+				err = fmt.Errorf("Field %s resolved %d values for %d objects", f.Name, len(values), len(sources))
+			}
+			if err != nil {
+				values = make([]interface{}, len(sources))
+				for i := range errs {
+					errs[i] = err
+				}
+			}
+		case def != nil && def.Resolve != nil:
+			for i, source := range sources {
+				values[i], errs[i] = def.Resolve(Params{Source: source, Args: args, Context: e.ctx})
+			}
+		case !obj.Strict || def != nil:
+			for i, source := range sources {
+				if raws[i] == nil {
+					if raws[i], errs[i] = toMap(source); errs[i] != nil {
+						continue
+					}
+				}
+				v, ok := raws[i][f.Name]
+				if !ok && def == nil && !hasJsonField(source, f.Name) {
+					errs[i] = fmt.Errorf("Cannot query field %s on type %s", f.Name, obj.Name)
+					continue
+				}
+				values[i] = v
+			}
+		default:
+			for i := range errs {
+				errs[i] = fmt.Errorf("Cannot query field %s on type %s", f.Name, obj.Name)
+			}
+		}
+		for i, err := range errs {
+			if err != nil {
+				e.fail(fieldPaths[i], "%s", err)
+				values[i] = nil
+			}
+		}
+		var typ *Object
+		if def != nil {
+			typ = def.Type
+		}
+		for i, v := range e.complete(typ, values, f, fieldPaths) {
+			results[i][key] = v
+		}
+	}
+	return results
+}
+
+// pending is an object which selections are completed with other objects of the same level
+type pending struct {
+	source interface{}
+	path   []interface{}
+	set    func(map[string]interface{})
+}
+
+// complete completes resolved values according to the selection set,
+// objects from all values and lists are collected to complete them together
+func (e *executor) complete(typ *Object, values []interface{}, f *Field, paths [][]interface{}) []interface{} {
+	results := make([]interface{}, len(values))
+	objs := []*pending{}
+	var collect func(value interface{}, path []interface{}, set func(interface{}))
+	collect = func(value interface{}, path []interface{}, set func(interface{})) {
+		if isNil(value) {
+			return
+		}
+		if typ != nil && len(f.Selections) == 0 {
+			e.fail(path, "Field %s of type %s must have a selection of subfields", f.Name, typ.Name)
+			return
+		}
+		if len(f.Selections) == 0 {
+			set(value)
+			return
+		}
+		rv := reflect.ValueOf(value)
+		if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+			list := make([]interface{}, rv.Len())
+			set(list)
+			for i := 0; i < rv.Len(); i++ {
+				i := i
+				collect(rv.Index(i).Interface(), appendPath(path, i), func(v interface{}) { list[i] = v })
+			}
+			return
+		}
+		objs = append(objs, &pending{source: value, path: path, set: func(v map[string]interface{}) { set(v) }})
+	}
+	for i, value := range values {
+		i := i
+		collect(value, paths[i], func(v interface{}) { results[i] = v })
+	}
+	if len(objs) == 0 {
+		return results
+	}
+	if typ == nil {
+		// plain json objects, like web or location, are selected without a schema
+		typ = &Object{Name: "Object"}
+	}
+	sources := make([]interface{}, len(objs))
+	objPaths := make([][]interface{}, len(objs))
+	for i, p := range objs {
+		sources[i] = p.source
+		objPaths[i] = p.path
+	}
+	for i, v := range e.objects(typ, sources, f.Selections, objPaths) {
+		objs[i].set(v)
+	}
+	return results
+}
+
+// appendPath copies the path, so paths of siblings don't share the backing array
+func appendPath(path []interface{}, elem interface{}) []interface{} {
+	p := make([]interface{}, len(path), len(path)+1)
+	copy(p, path)
+	return append(p, elem)
+}
+
+func (e *executor) included(f *Field, path []interface{}) bool {
+	for _, d := range f.Directives {
+		args, err := e.args(d.Args)
+		if err != nil {
+			e.fail(append(path, f.Key()), "%s", err)
+			return false
+		}
+		cond, ok := args["if"].(bool)
+		switch d.Name {
+		case "include":
+			if !ok {
+				e.fail(append(path, f.Key()), "@include requires boolean if argument")
+				return false
+			}
+			if !cond {
+				return false
+			}
+		case "skip":
+			if !ok {
+				e.fail(append(path, f.Key()), "@skip requires boolean if argument")
+				return false
+			}
+			if cond {
+				return false
+			}
+		default:
+			e.fail(append(path, f.Key()), "Unknown directive @%s", d.Name)
+			return false
+		}
+	}
+	return true
+}
+
+// args substitutes variables and converts enums to strings
+func (e *executor) args(raw map[string]interface{}) (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	for name, v := range raw {
+		value, err := e.resolve(v)
+		if err != nil {
+			return nil, err
+		}
+		args[name] = value
+	}
+	return args, nil
+}
+
+func (e *executor) resolve(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case Variable:
+		value, ok := e.vars[string(val)]
+		if !ok {
+			return nil, fmt.Errorf("Variable $%s is not defined", val)
+		}
+		return value, nil
+	case Enum:
+		return string(val), nil
+	case []interface{}:
+		list := make([]interface{}, len(val))
+		for i, item := range val {
+			r, err := e.resolve(item)
+			if err != nil {
+				return nil, err
+			}
+			list[i] = r
+		}
+		return list, nil
+	case map[string]interface{}:
+		obj := map[string]interface{}{}
+		for k, item := range val {
+			r, err := e.resolve(item)
+			if err != nil {
+				return nil, err
+			}
+			obj[k] = r
+		}
+		return obj, nil
+	}
+	return v, nil
+}
+
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+func toMap(source interface{}) (map[string]interface{}, error) {
+	if m, ok := source.(map[string]interface{}); ok {
+		return m, nil
+	}
+	result := map[string]interface{}{}
+	if isNil(source) {
+		return result, nil
+	}
+	data, err := json.Marshal(source)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// hasJsonField checks if the field is known but omitted from json because it's empty
+func hasJsonField(source interface{}, name string) bool {
+	t := reflect.TypeOf(source)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return false
+	}
+	return structHasField(t, name)
+}
+
+func structHasField(t reflect.Type, name string) bool {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := strings.Split(sf.Tag.Get("json"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+		ft := sf.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if sf.Anonymous && tag[0] == "" && ft.Kind() == reflect.Struct {
+			if structHasField(ft, name) {
+				return true
+			}
+			continue
+		}
+		jsonName := tag[0]
+		if jsonName == "" {
+			jsonName = sf.Name
+		}
+		if jsonName == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,247 @@
+package graphql
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testTarget struct {
+	Id     string          `json:"id"`
+	Domain string          `json:"domain,omitempty"`
+	Web    *testWeb        `json:"web,omitempty"`
+	Secret string          `json:"-"`
+	Tags   []string        `json:"tags"`
+	Meta   map[string]bool `json:"meta,omitempty"`
+}
+
+type testWeb struct {
+	Port int `json:"port"`
+}
+
+func TestParse(t *testing.T) {
+	doc, err := Parse(`
+		# comment
+		query Dashboard($project: ID!, $limit: Int = 10) {
+			project(id: $project) {
+				name
+				t: targets(limit: $limit, type: WEB, tags: ["a", "b"], filter: {deep: true}) @include(if: true) {
+					id
+				}
+			}
+		}`)
+	require.NoError(t, err)
+	require.Len(t, doc.Operations, 1)
+	op := doc.Operations[0]
+	assert.Equal(t, "query", op.Type)
+	assert.Equal(t, "Dashboard", op.Name)
+	require.Len(t, op.Variables, 2)
+	assert.Equal(t, "ID!", op.Variables[0].Type)
+	assert.Equal(t, 10, op.Variables[1].Default)
+
+	project := op.Selections[0]
+	assert.Equal(t, Variable("project"), project.Args["id"])
+	targets := project.Selections[1]
+	assert.Equal(t, "t", targets.Key())
+	assert.Equal(t, "targets", targets.Name)
+	assert.Equal(t, Enum("WEB"), targets.Args["type"])
+	assert.Equal(t, []interface{}{"a", "b"}, targets.Args["tags"])
+	assert.Equal(t, map[string]interface{}{"deep": true}, targets.Args["filter"])
+	require.Len(t, targets.Directives, 1)
+	assert.Equal(t, "include", targets.Directives[0].Name)
+
+	doc, err = Parse(`{ a(s: "x\"A\n", f: -1.5e2) }`)
+	require.NoError(t, err)
+	assert.Equal(t, "x\"A\n", doc.Operations[0].Selections[0].Args["s"])
+	assert.Equal(t, -150.0, doc.Operations[0].Selections[0].Args["f"])
+
+	for _, q := range []string{`{`, `{}`, `{ a(b: ) }`, `{ ...frag }`, `fragment f on A { a }`, `{ a(s: "x) }`, `{ a(b: 1, b: 2) }`, ``} {
+		_, err := Parse(q)
+		assert.Error(t, err, q)
+	}
+}
+
+func testSchema() *Schema {
+	target := &Object{Name: "Target"}
+	project := &Object{
+		Name: "Project",
+		Fields: map[string]*ObjectField{
+			"targets": &ObjectField{
+				Type: target,
+				Resolve: func(p Params) (interface{}, error) {
+					targets := []*testTarget{
+						{Id: "1", Domain: "a.com", Web: &testWeb{Port: 443}, Tags: []string{"x"}},
+						{Id: "2", Secret: "s"},
+					}
+					if limit, ok := p.Args["limit"].(int); ok && limit < len(targets) {
+						targets = targets[:limit]
+					}
+					return targets, nil
+				},
+			},
+			"broken": &ObjectField{
+				Resolve: func(p Params) (interface{}, error) {
+					return nil, fmt.Errorf("broken field")
+				},
+			},
+		},
+	}
+	return &Schema{
+		MaxDepth: 4,
+		Query: &Object{
+			Name:   "Query",
+			Strict: true,
+			Fields: map[string]*ObjectField{
+				"project": &ObjectField{
+					Type: project,
+					Resolve: func(p Params) (interface{}, error) {
+						if p.Args["id"] != "p1" {
+							return nil, nil
+						}
+						return map[string]interface{}{"id": "p1", "name": p.Context}, nil
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestExecute(t *testing.T) {
+	s := testSchema()
+	res := s.Execute(&Request{
+		Query: `query q($id: ID!, $skip: Boolean = false) {
+			project(id: $id) {
+				__typename
+				title: name
+				targets {
+					id
+					domain
+					web { port }
+					tags
+					meta @skip(if: $skip)
+				}
+				one: targets(limit: 1) { id }
+			}
+			missing: project(id: "none") { id }
+		}`,
+		Variables: map[string]interface{}{"id": "p1"},
+	}, "bearded")
+	require.Empty(t, res.Errors)
+	assert.Equal(t, map[string]interface{}{
+		"project": map[string]interface{}{
+			"__typename": "Project",
+			"title":      "bearded",
+			"targets": []interface{}{
+				map[string]interface{}{"id": "1", "domain": "a.com", "web": map[string]interface{}{"port": float64(443)}, "tags": []interface{}{"x"}, "meta": nil},
+				map[string]interface{}{"id": "2", "domain": nil, "web": nil, "tags": nil, "meta": nil},
+			},
+			"one": []interface{}{
+				map[string]interface{}{"id": "1"},
+			},
+		},
+		"missing": nil,
+	}, res.Data)
+}
+
+func TestExecuteErrors(t *testing.T) {
+	s := testSchema()
+
+	// field errors don't break the whole result
+	res := s.Execute(&Request{Query: `{ project(id: "p1") { name broken targets { id secret unknown } } }`}, "bearded")
+	require.Len(t, res.Errors, 5)
+	assert.Equal(t, "broken field", res.Errors[0].Message)
+	assert.Equal(t, []interface{}{"project", "broken"}, res.Errors[0].Path)
+	assert.Equal(t, "Cannot query field secret on type Target", res.Errors[1].Message)
+	assert.Equal(t, []interface{}{"project", "targets", 0, "secret"}, res.Errors[1].Path)
+	assert.Equal(t, "bearded", res.Data["project"].(map[string]interface{})["name"])
+
+	res = s.Execute(&Request{Query: `{ projects { id } }`}, nil)
+	require.Len(t, res.Errors, 1)
+	assert.Equal(t, "Cannot query field projects on type Query", res.Errors[0].Message)
+
+	res = s.Execute(&Request{Query: `{ project(id: "p1") { targets } }`}, nil)
+	require.Len(t, res.Errors, 1)
+	assert.Equal(t, "Field targets of type Target must have a selection of subfields", res.Errors[0].Message)
+
+	for _, req := range []*Request{
+		{Query: `mutation { project { id } }`},
+		{Query: `query a { project { id } } query b { project { id } }`},
+		{Query: `query a { project { id } }`, OperationName: "c"},
+		{Query: `query ($id: ID!) { project(id: $id) { id } }`},
+		{Query: `{ project(id: "p1") { targets { web { port { deep } } } } }`},
+	} {
+		_, _, err := s.Prepare(req)
+		assert.Error(t, err, req.Query)
+	}
+}
+
+func TestCost(t *testing.T) {
+	s := testSchema()
+	s.Query.Fields["project"].Type.Fields["targets"].List = true
+	s.ListSize = 10
+	s.MaxCost = 25
+
+	for _, q := range []string{
+		`{ project(id: "p1") { targets { id } } }`,
+		`{ project(id: "p1") { a: targets(limit: 10) { id } b: targets(limit: 5) { id } } }`,
+		`{ project(id: "p1") { targets(limit: 100) { id web { port } } } }`,
+	} {
+		_, _, err := s.Prepare(&Request{Query: q})
+		assert.NoError(t, err, q)
+	}
+	for _, q := range []string{
+		`{ project(id: "p1") { a: targets { id } b: targets { id } c: targets { id } } }`,
+		`{ project(id: "p1") { targets { id web { port } meta { x } } } }`,
+	} {
+		_, _, err := s.Prepare(&Request{Query: q})
+		assert.Error(t, err, q)
+	}
+
+	// limits from variables are counted
+	_, _, err := s.Prepare(&Request{
+		Query:     `query ($n: Int) { project(id: "p1") { a: targets(limit: $n) { id } b: targets(limit: $n) { id } } }`,
+		Variables: map[string]interface{}{"n": float64(5)},
+	})
+	assert.NoError(t, err)
+}
+
+func TestExecuteBatch(t *testing.T) {
+	calls := 0
+	web := &Object{
+		Name: "Web",
+		Fields: map[string]*ObjectField{
+			"ports": &ObjectField{
+				Batch: func(p BatchParams) ([]interface{}, error) {
+					calls++
+					values := make([]interface{}, len(p.Sources))
+					for i, source := range p.Sources {
+						values[i] = []int{source.(*testWeb).Port, source.(*testWeb).Port + 1}
+					}
+					return values, nil
+				},
+			},
+		},
+	}
+	s := &Schema{
+		Query: &Object{
+			Name: "Query",
+			Fields: map[string]*ObjectField{
+				"webs": &ObjectField{
+					Type: web,
+					Resolve: func(p Params) (interface{}, error) {
+						return [][]*testWeb{{{Port: 80}, nil}, {{Port: 443}}}, nil
+					},
+				},
+			},
+		},
+	}
+	res := s.Execute(&Request{Query: `{ webs { port ports } }`}, nil)
+	require.Empty(t, res.Errors)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, []interface{}{
+		[]interface{}{map[string]interface{}{"port": float64(80), "ports": []int{80, 81}}, nil},
+		[]interface{}{map[string]interface{}{"port": float64(443), "ports": []int{443, 444}}},
+	}, res.Data["webs"])
+}
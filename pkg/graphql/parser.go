@@ -0,0 +1,456 @@
+// Package graphql implements a small subset of GraphQL: queries with fields,
+// aliases, arguments, variables and @include/@skip directives.
+// Fragments, mutations and subscriptions aren't supported.
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type Document struct {
+	Operations []*Operation
+}
+
+type Operation struct {
+	Type       string // only query is executed
+	Name       string
+	Variables  []*VariableDef
+	Selections []*Field
+}
+
+type VariableDef struct {
+	Name    string
+	Type    string
+	Default interface{}
+}
+
+type Field struct {
+	Alias      string
+	Name       string
+	Args       map[string]interface{}
+	Directives []*Directive
+	Selections []*Field
+}
+
+type Directive struct {
+	Name string
+	Args map[string]interface{}
+}
+
+// Variable is a reference to the request variable, it's substituted during execution
+type Variable string
+
+// Enum is an unquoted enum value, it's passed to resolvers as a string
+type Enum string
+
+// Key returns the name of the field in the result
+func (f *Field) Key() string {
+	if f.Alias != "" {
+		return f.Alias
+	}
+	return f.Name
+}
+
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("Syntax error at %d: %s", e.Pos, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+type parser struct {
+	src string
+	pos int
+	tok token
+}
+
+// Parse parses query into the document
+func Parse(query string) (doc *Document, err error) {
+	p := &parser{src: query}
+	defer func() {
+		if r := recover(); r != nil {
+			if sErr, ok := r.(*SyntaxError); ok {
+				doc, err = nil, sErr
+				return
+			}
+			panic(r)
+		}
+	}()
+	p.next()
+	doc = &Document{}
+	for p.tok.kind != tokEOF {
+		doc.Operations = append(doc.Operations, p.parseOperation())
+	}
+	if len(doc.Operations) == 0 {
+		p.fail("document without operations")
+	}
+	return doc, nil
+}
+
+func (p *parser) fail(format string, args ...interface{}) {
+	panic(&SyntaxError{Pos: p.tok.pos, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (p *parser) next() {
+	p.tok = p.lex()
+}
+
+func (p *parser) lex() token {
+	// skip ignored tokens: whitespaces, commas and comments
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.pos++
+			continue
+		}
+		if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+			continue
+		}
+		break
+	}
+	start := p.pos
+	if p.pos >= len(p.src) {
+		return token{kind: tokEOF, pos: start}
+	}
+	c := p.src[p.pos]
+	switch {
+	case strings.IndexByte("!$():=@[]{}|", c) >= 0:
+		p.pos++
+		return token{kind: tokPunct, value: string(c), pos: start}
+	case c == '.':
+		if strings.HasPrefix(p.src[p.pos:], "...") {
+			p.pos += 3
+			return token{kind: tokPunct, value: "...", pos: start}
+		}
+	case c == '_' || isLetter(c):
+		for p.pos < len(p.src) && (p.src[p.pos] == '_' || isLetter(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+			p.pos++
+		}
+		return token{kind: tokName, value: p.src[start:p.pos], pos: start}
+	case c == '-' || isDigit(c):
+		return p.lexNumber()
+	case c == '"':
+		return p.lexString()
+	}
+	panic(&SyntaxError{Pos: start, Msg: fmt.Sprintf("unexpected character %q", c)})
+}
+
+func (p *parser) lexNumber() token {
+	start := p.pos
+	kind := tokInt
+	if p.src[p.pos] == '-' {
+		p.pos++
+	}
+	digits := func() {
+		s := p.pos
+		for p.pos < len(p.src) && isDigit(p.src[p.pos]) {
+			p.pos++
+		}
+		if s == p.pos {
+			panic(&SyntaxError{Pos: p.pos, Msg: "invalid number"})
+		}
+	}
+	digits()
+	if p.pos < len(p.src) && p.src[p.pos] == '.' {
+		kind = tokFloat
+		p.pos++
+		digits()
+	}
+	if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+		kind = tokFloat
+		p.pos++
+		if p.pos < len(p.src) && (p.src[p.pos] == '+' || p.src[p.pos] == '-') {
+			p.pos++
+		}
+		digits()
+	}
+	return token{kind: kind, value: p.src[start:p.pos], pos: start}
+}
+
+func (p *parser) lexString() token {
+	start := p.pos
+	p.pos++ // opening quote
+	buf := []byte{}
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' {
+			panic(&SyntaxError{Pos: start, Msg: "unterminated string"})
+		}
+		c := p.src[p.pos]
+		if c == '"' {
+			p.pos++
+			return token{kind: tokString, value: string(buf), pos: start}
+		}
+		if c != '\\' {
+			buf = append(buf, c)
+			p.pos++
+			continue
+		}
+		if p.pos+1 >= len(p.src) {
+			panic(&SyntaxError{Pos: p.pos, Msg: "unterminated string"})
+		}
+		esc := p.src[p.pos+1]
+		p.pos += 2
+		switch esc {
+		case '"', '\\', '/':
+			buf = append(buf, esc)
+		case 'b':
+			buf = append(buf, '\b')
+		case 'f':
+			buf = append(buf, '\f')
+		case 'n':
+			buf = append(buf, '\n')
+		case 'r':
+			buf = append(buf, '\r')
+		case 't':
+			buf = append(buf, '\t')
+		case 'u':
+			if p.pos+4 > len(p.src) {
+				panic(&SyntaxError{Pos: p.pos, Msg: "invalid unicode escape"})
+			}
+			code, err := strconv.ParseUint(p.src[p.pos:p.pos+4], 16, 32)
+			if err != nil {
+				panic(&SyntaxError{Pos: p.pos, Msg: "invalid unicode escape"})
+			}
+			p.pos += 4
+			rb := make([]byte, utf8.UTFMax)
+			buf = append(buf, rb[:utf8.EncodeRune(rb, rune(code))]...)
+		default:
+			panic(&SyntaxError{Pos: p.pos - 1, Msg: fmt.Sprintf("invalid escape \\%c", esc)})
+		}
+	}
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (p *parser) peek(value string) bool {
+	return p.tok.kind == tokPunct && p.tok.value == value
+}
+
+func (p *parser) expect(value string) {
+	if !p.peek(value) {
+		p.fail("expected %q, found %q", value, p.tok.value)
+	}
+	p.next()
+}
+
+func (p *parser) name() string {
+	if p.tok.kind != tokName {
+		p.fail("expected name, found %q", p.tok.value)
+	}
+	n := p.tok.value
+	p.next()
+	return n
+}
+
+func (p *parser) parseOperation() *Operation {
+	op := &Operation{Type: "query"}
+	if p.peek("{") {
+		op.Selections = p.parseSelections()
+		return op
+	}
+	if p.tok.kind != tokName {
+		p.fail("expected operation, found %q", p.tok.value)
+	}
+	switch p.tok.value {
+	case "query", "mutation", "subscription":
+		op.Type = p.name()
+	case "fragment":
+		p.fail("fragments aren't supported")
+	default:
+		p.fail("unknown operation %q", p.tok.value)
+	}
+	if p.tok.kind == tokName {
+		op.Name = p.name()
+	}
+	if p.peek("(") {
+		p.next()
+		for !p.peek(")") {
+			op.Variables = append(op.Variables, p.parseVariableDef())
+		}
+		p.next()
+	}
+	if len(p.parseDirectives()) > 0 {
+		p.fail("operation directives aren't supported")
+	}
+	op.Selections = p.parseSelections()
+	return op
+}
+
+func (p *parser) parseVariableDef() *VariableDef {
+	p.expect("$")
+	def := &VariableDef{Name: p.name()}
+	p.expect(":")
+	def.Type = p.parseType()
+	if p.peek("=") {
+		p.next()
+		def.Default = p.parseValue(true)
+	}
+	return def
+}
+
+func (p *parser) parseType() string {
+	var t string
+	if p.peek("[") {
+		p.next()
+		t = "[" + p.parseType() + "]"
+		p.expect("]")
+	} else {
+		t = p.name()
+	}
+	if p.peek("!") {
+		p.next()
+		t += "!"
+	}
+	return t
+}
+
+func (p *parser) parseSelections() []*Field {
+	p.expect("{")
+	fields := []*Field{}
+	for !p.peek("}") {
+		if p.peek("...") {
+			p.fail("fragments aren't supported")
+		}
+		fields = append(fields, p.parseField())
+	}
+	p.next()
+	if len(fields) == 0 {
+		p.fail("empty selection set")
+	}
+	return fields
+}
+
+func (p *parser) parseField() *Field {
+	f := &Field{Name: p.name()}
+	if p.peek(":") {
+		p.next()
+		f.Alias, f.Name = f.Name, p.name()
+	}
+	f.Args = p.parseArgs()
+	f.Directives = p.parseDirectives()
+	if p.peek("{") {
+		f.Selections = p.parseSelections()
+	}
+	return f
+}
+
+func (p *parser) parseArgs() map[string]interface{} {
+	if !p.peek("(") {
+		return nil
+	}
+	p.next()
+	args := map[string]interface{}{}
+	for !p.peek(")") {
+		name := p.name()
+		if _, ok := args[name]; ok {
+			p.fail("duplicate argument %q", name)
+		}
+		p.expect(":")
+		args[name] = p.parseValue(false)
+	}
+	p.next()
+	return args
+}
+
+func (p *parser) parseDirectives() []*Directive {
+	var dirs []*Directive
+	for p.peek("@") {
+		p.next()
+		dirs = append(dirs, &Directive{Name: p.name(), Args: p.parseArgs()})
+	}
+	return dirs
+}
+
+func (p *parser) parseValue(constant bool) interface{} {
+	tok := p.tok
+	switch tok.kind {
+	case tokInt:
+		p.next()
+		v, err := strconv.Atoi(tok.value)
+		if err != nil {
+			p.fail("invalid int %s", tok.value)
+		}
+		return v
+	case tokFloat:
+		p.next()
+		v, err := strconv.ParseFloat(tok.value, 64)
+		if err != nil {
+			p.fail("invalid float %s", tok.value)
+		}
+		return v
+	case tokString:
+		p.next()
+		return tok.value
+	case tokName:
+		p.next()
+		switch tok.value {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return Enum(tok.value)
+	case tokPunct:
+		switch tok.value {
+		case "$":
+			if constant {
+				p.fail("variables aren't allowed here")
+			}
+			p.next()
+			return Variable(p.name())
+		case "[":
+			p.next()
+			list := []interface{}{}
+			for !p.peek("]") {
+				list = append(list, p.parseValue(constant))
+			}
+			p.next()
+			return list
+		case "{":
+			p.next()
+			obj := map[string]interface{}{}
+			for !p.peek("}") {
+				name := p.name()
+				p.expect(":")
+				obj[name] = p.parseValue(constant)
+			}
+			p.next()
+			return obj
+		}
+	}
+	p.fail("unexpected %q", tok.value)
+	return nil
+}
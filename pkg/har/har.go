@@ -0,0 +1,130 @@
+// Package har records http traffic of plugins in HTTP Archive 1.2 format.
+// Recorder is a forward proxy which plugin containers are pointed to,
+// plain http exchanges are recorded in full, https tunnels are recorded as CONNECT entries
+// because the recorder doesn't intercept tls.
+package har
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SpecVersion is the version of HTTP Archive format
+const SpecVersion = "1.2"
+
+type Har struct {
+	Log *Log `json:"log"`
+}
+
+type Log struct {
+	Version string   `json:"version"`
+	Creator *Creator `json:"creator"`
+	Entries []*Entry `json:"entries"`
+	Comment string   `json:"comment,omitempty"`
+}
+
+type Creator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type Entry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+	Time            float64   `json:"time"`
+	Request         *Request  `json:"request"`
+	Response        *Response `json:"response"`
+	Cache           struct{}  `json:"cache"`
+	Timings         *Timings  `json:"timings"`
+	ServerIPAddress string    `json:"serverIPAddress,omitempty"`
+	Comment         string    `json:"comment,omitempty"`
+}
+
+type NameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type Request struct {
+	Method      string       `json:"method"`
+	Url         string       `json:"url"`
+	HttpVersion string       `json:"httpVersion"`
+	Cookies     []*NameValue `json:"cookies"`
+	Headers     []*NameValue `json:"headers"`
+	QueryString []*NameValue `json:"queryString"`
+	PostData    *PostData    `json:"postData,omitempty"`
+	HeadersSize int          `json:"headersSize"`
+	BodySize    int64        `json:"bodySize"`
+}
+
+type PostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+type Response struct {
+	Status      int          `json:"status"`
+	StatusText  string       `json:"statusText"`
+	HttpVersion string       `json:"httpVersion"`
+	Cookies     []*NameValue `json:"cookies"`
+	Headers     []*NameValue `json:"headers"`
+	Content     *Content     `json:"content"`
+	RedirectURL string       `json:"redirectURL"`
+	HeadersSize int          `json:"headersSize"`
+	BodySize    int64        `json:"bodySize"`
+}
+
+type Content struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// Timings in milliseconds, -1 if not applicable
+type Timings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func headers(h http.Header) []*NameValue {
+	res := []*NameValue{}
+	for name, values := range h {
+		for _, v := range values {
+			res = append(res, &NameValue{Name: name, Value: v})
+		}
+	}
+	return res
+}
+
+func cookies(list []*http.Cookie) []*NameValue {
+	res := []*NameValue{}
+	for _, c := range list {
+		res = append(res, &NameValue{Name: c.Name, Value: c.Value})
+	}
+	return res
+}
+
+// bodyText returns the body as text or base64 for binary data
+func bodyText(data []byte) (string, string) {
+	if utf8.Valid(data) {
+		return string(data), ""
+	}
+	return base64.StdEncoding.EncodeToString(data), "base64"
+}
+
+func proto(p string) string {
+	if p == "" {
+		return "HTTP/1.1"
+	}
+	return strings.ToUpper(p)
+}
@@ -0,0 +1,403 @@
+package har
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxBody is the max recorded size of request and response bodies, the rest is proxied only
+	DefaultMaxBody = 256 << 10
+	// DefaultMaxEntries limits memory of long scans, later exchanges are proxied without recording
+	DefaultMaxEntries = 10000
+
+	dialTimeout = 30 * time.Second
+)
+
+// headers which are meaningful only for a single connection
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Recorder is a forward proxy which records all exchanges, it's safe for concurrent use
+type Recorder struct {
+	MaxBody    int64
+	MaxEntries int
+
+	upstream  *url.URL
+	transport *http.Transport
+
+	lock    sync.Mutex
+	entries []*Entry
+	skipped int
+}
+
+// NewRecorder returns a recorder which sends traffic directly or through the upstream proxy if it isn't nil
+func NewRecorder(upstream *url.URL) *Recorder {
+	return &Recorder{
+		MaxBody:    DefaultMaxBody,
+		MaxEntries: DefaultMaxEntries,
+		upstream:   upstream,
+		transport: &http.Transport{
+			Proxy: func(*http.Request) (*url.URL, error) {
+				return upstream, nil
+			},
+			Dial: (&net.Dialer{Timeout: dialTimeout}).Dial,
+			// bodies are recorded as plugins see them
+			DisableCompression: true,
+		},
+	}
+}
+
+// Har returns recorded exchanges in order of their start
+func (r *Recorder) Har() *Har {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	log := &Log{
+		Version: SpecVersion,
+		Creator: &Creator{Name: "bearded-agent", Version: "1.0"},
+		Entries: append([]*Entry{}, r.entries...),
+	}
+	if r.skipped > 0 {
+		log.Comment = fmt.Sprintf("%d exchanges aren't recorded, max %d entries", r.skipped, r.MaxEntries)
+	}
+	return &Har{Log: log}
+}
+
+// Len returns the number of recorded entries
+func (r *Recorder) Len() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return len(r.entries)
+}
+
+func (r *Recorder) add(e *Entry) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.MaxEntries > 0 && len(r.entries) >= r.MaxEntries {
+		r.skipped++
+		return
+	}
+	r.entries = append(r.entries, e)
+}
+
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == "CONNECT" {
+		r.tunnel(w, req)
+		return
+	}
+	if !req.URL.IsAbs() {
+		http.Error(w, "only proxy requests are supported", http.StatusBadRequest)
+		return
+	}
+	start := time.Now()
+	entry := &Entry{
+		StartedDateTime: start.UTC(),
+		Request:         newRequest(req),
+	}
+	out := new(http.Request)
+	*out = *req
+	out.RequestURI = ""
+	out.Header = cloneHeader(req.Header)
+	removeHopHeaders(out.Header)
+	var reqBody *capture
+	if req.Body != nil {
+		reqBody = newCapture(req.Body, r.MaxBody)
+		out.Body = reqBody
+	}
+
+	resp, err := r.transport.RoundTrip(out)
+	if err != nil {
+		entry.Response = &Response{
+			HttpVersion: proto(""),
+			Cookies:     []*NameValue{},
+			Headers:     []*NameValue{},
+			Content:     &Content{},
+			HeadersSize: -1,
+		}
+		entry.Comment = err.Error()
+		r.finish(entry, reqBody, start, time.Now(), time.Now())
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	waited := time.Now()
+
+	removeHopHeaders(resp.Header)
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	respBody := newCapture(resp.Body, r.MaxBody)
+	io.Copy(w, respBody)
+
+	entry.Response = newResponse(resp, respBody)
+	r.finish(entry, reqBody, start, waited, time.Now())
+}
+
+func (r *Recorder) finish(entry *Entry, reqBody *capture, start, waited, end time.Time) {
+	if reqBody != nil {
+		data, size, truncated := reqBody.result()
+		entry.Request.BodySize = size
+		if size > 0 {
+			text, encoding := bodyText(data)
+			entry.Request.PostData = &PostData{
+				MimeType: entry.Request.header("Content-Type"),
+				Text:     text,
+				Encoding: encoding,
+			}
+		}
+		if truncated {
+			entry.Comment = fmt.Sprintf("request body is truncated to %d bytes", len(data))
+		}
+	}
+	entry.Timings = &Timings{Send: 0, Wait: millis(waited.Sub(start)), Receive: millis(end.Sub(waited))}
+	entry.Time = millis(end.Sub(start))
+	r.add(entry)
+}
+
+// tunnel connects the client to the requested host, tls traffic isn't decrypted,
+// so only the host and sizes are recorded
+func (r *Recorder) tunnel(w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	entry := &Entry{
+		StartedDateTime: start.UTC(),
+		Request:         newRequest(req),
+	}
+	entry.Request.Url = req.Host
+	fail := func(err error, code int) {
+		entry.Response = &Response{
+			Status:      code,
+			StatusText:  http.StatusText(code),
+			HttpVersion: proto(""),
+			Cookies:     []*NameValue{},
+			Headers:     []*NameValue{},
+			Content:     &Content{},
+			HeadersSize: -1,
+		}
+		entry.Comment = err.Error()
+		r.finish(entry, nil, start, time.Now(), time.Now())
+		http.Error(w, err.Error(), code)
+	}
+
+	upstream, err := r.dial(req.Host)
+	if err != nil {
+		fail(err, http.StatusBadGateway)
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		upstream.Close()
+		fail(fmt.Errorf("hijacking isn't supported"), http.StatusInternalServerError)
+		return
+	}
+	client, buf, err := hj.Hijack()
+	if err != nil {
+		upstream.Close()
+		fail(err, http.StatusInternalServerError)
+		return
+	}
+	connected := time.Now()
+	if _, err := io.WriteString(client, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+		client.Close()
+		upstream.Close()
+		return
+	}
+
+	done := make(chan int64)
+	go func() {
+		n, _ := io.Copy(upstream, buf)
+		upstream.Close()
+		client.Close()
+		done <- n
+	}()
+	received, _ := io.Copy(client, upstream)
+	upstream.Close()
+	client.Close()
+	sent := <-done
+
+	entry.Request.BodySize = sent
+	entry.Response = &Response{
+		Status:      http.StatusOK,
+		StatusText:  "Connection established",
+		HttpVersion: proto(""),
+		Cookies:     []*NameValue{},
+		Headers:     []*NameValue{},
+		Content: &Content{
+			Size:     received,
+			MimeType: "application/octet-stream",
+			Comment:  "tls tunnel, content isn't recorded",
+		},
+		HeadersSize: -1,
+		BodySize:    received,
+	}
+	r.finish(entry, nil, start, connected, time.Now())
+}
+
+// dial connects to the address directly or through the upstream http proxy
+func (r *Recorder) dial(addr string) (net.Conn, error) {
+	if r.upstream == nil {
+		return net.DialTimeout("tcp", addr, dialTimeout)
+	}
+	var (
+		conn net.Conn
+		err  error
+	)
+	switch r.upstream.Scheme {
+	case "http":
+		conn, err = net.DialTimeout("tcp", r.upstream.Host, dialTimeout)
+	case "https":
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, "tcp", r.upstream.Host,
+			&tls.Config{ServerName: r.upstream.Hostname()})
+	default:
+		return nil, fmt.Errorf("tunnels through %s proxy aren't supported while traffic is recorded", r.upstream.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	connect := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if u := r.upstream.User; u != nil {
+		password, _ := u.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(u.Username() + ":" + password))
+		connect.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	if err := connect.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), connect)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("upstream proxy responded %s", resp.Status)
+	}
+	return conn, nil
+}
+
+func newRequest(req *http.Request) *Request {
+	query := []*NameValue{}
+	for name, values := range req.URL.Query() {
+		for _, v := range values {
+			query = append(query, &NameValue{Name: name, Value: v})
+		}
+	}
+	return &Request{
+		Method:      req.Method,
+		Url:         req.URL.String(),
+		HttpVersion: proto(req.Proto),
+		Cookies:     cookies(req.Cookies()),
+		Headers:     headers(req.Header),
+		QueryString: query,
+		HeadersSize: -1,
+	}
+}
+
+func (r *Request) header(name string) string {
+	for _, h := range r.Headers {
+		if http.CanonicalHeaderKey(h.Name) == name {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+func newResponse(resp *http.Response, body *capture) *Response {
+	data, size, truncated := body.result()
+	text, encoding := bodyText(data)
+	content := &Content{
+		Size:     size,
+		MimeType: resp.Header.Get("Content-Type"),
+		Text:     text,
+		Encoding: encoding,
+	}
+	if truncated {
+		content.Comment = fmt.Sprintf("truncated to %d bytes", len(data))
+	}
+	return &Response{
+		Status:      resp.StatusCode,
+		StatusText:  http.StatusText(resp.StatusCode),
+		HttpVersion: proto(resp.Proto),
+		Cookies:     cookies(resp.Cookies()),
+		Headers:     headers(resp.Header),
+		Content:     content,
+		RedirectURL: resp.Header.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    size,
+	}
+}
+
+func cloneHeader(h http.Header) http.Header {
+	res := make(http.Header, len(h))
+	for name, values := range h {
+		res[name] = append([]string{}, values...)
+	}
+	return res
+}
+
+func removeHopHeaders(h http.Header) {
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+}
+
+// capture keeps the first max bytes of the body while it's read,
+// the request body is read by transport in another goroutine, so it's locked
+type capture struct {
+	io.ReadCloser
+	max int64
+
+	lock sync.Mutex
+	buf  bytes.Buffer
+	size int64
+}
+
+func newCapture(body io.ReadCloser, max int64) *capture {
+	return &capture{ReadCloser: body, max: max}
+}
+
+func (c *capture) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.size += int64(n)
+	if rest := c.max - int64(c.buf.Len()); rest > 0 {
+		if int64(n) < rest {
+			rest = int64(n)
+		}
+		c.buf.Write(p[:rest])
+	}
+	return n, err
+}
+
+func (c *capture) result() ([]byte, int64, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	data := append([]byte{}, c.buf.Bytes()...)
+	return data, c.size, c.size > int64(len(data))
+}
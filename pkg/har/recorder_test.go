@@ -0,0 +1,109 @@
+package har
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorderHttp(t *testing.T) {
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("hello " + string(body)))
+	}))
+	defer app.Close()
+
+	rec := NewRecorder(nil)
+	rec.MaxBody = 8
+	proxy := httptest.NewServer(rec)
+	defer proxy.Close()
+	proxyUrl, _ := url.Parse(proxy.URL)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyUrl)}}
+
+	resp, err := client.Post(app.URL+"/login?next=home", "text/plain", strings.NewReader("bob"))
+	require.NoError(t, err)
+	data, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "hello bob", string(data))
+
+	h := rec.Har()
+	assert.Equal(t, SpecVersion, h.Log.Version)
+	require.Len(t, h.Log.Entries, 1)
+	e := h.Log.Entries[0]
+	assert.Equal(t, "POST", e.Request.Method)
+	assert.Equal(t, app.URL+"/login?next=home", e.Request.Url)
+	assert.Equal(t, []*NameValue{{Name: "next", Value: "home"}}, e.Request.QueryString)
+	require.NotNil(t, e.Request.PostData)
+	assert.Equal(t, "bob", e.Request.PostData.Text)
+	assert.Equal(t, "text/plain", e.Request.PostData.MimeType)
+	assert.Equal(t, 200, e.Response.Status)
+	assert.Equal(t, int64(9), e.Response.Content.Size)
+	assert.Equal(t, "hello bo", e.Response.Content.Text)
+	assert.Contains(t, e.Response.Content.Comment, "truncated")
+}
+
+func TestRecorderTunnel(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 4)
+		io.ReadFull(conn, buf)
+		conn.Write(bytes.ToUpper(buf))
+	}()
+
+	rec := NewRecorder(nil)
+	proxy := httptest.NewServer(rec)
+	defer proxy.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(proxy.URL, "http://"))
+	require.NoError(t, err)
+	defer conn.Close()
+	io.WriteString(conn, "CONNECT "+ln.Addr().String()+" HTTP/1.1\r\nHost: "+ln.Addr().String()+"\r\n\r\n")
+	status := make([]byte, len("HTTP/1.1 200 Connection established\r\n\r\n"))
+	_, err = io.ReadFull(conn, status)
+	require.NoError(t, err)
+	assert.Contains(t, string(status), "200")
+	io.WriteString(conn, "ping")
+	data, err := ioutil.ReadAll(conn)
+	require.NoError(t, err)
+	assert.Equal(t, "PING", string(data))
+
+	// the entry is added after both sides are closed
+	for i := 0; i < 100 && rec.Len() == 0; i++ {
+		<-time.After(10 * time.Millisecond)
+	}
+	h := rec.Har()
+	require.Len(t, h.Log.Entries, 1)
+	e := h.Log.Entries[0]
+	assert.Equal(t, "CONNECT", e.Request.Method)
+	assert.Equal(t, ln.Addr().String(), e.Request.Url)
+	assert.Equal(t, int64(4), e.Request.BodySize)
+	assert.Equal(t, int64(4), e.Response.BodySize)
+}
+
+func TestRecorderMaxEntries(t *testing.T) {
+	rec := NewRecorder(nil)
+	rec.MaxEntries = 1
+	rec.add(&Entry{})
+	rec.add(&Entry{})
+	h := rec.Har()
+	assert.Len(t, h.Log.Entries, 1)
+	assert.Contains(t, h.Log.Comment, "1 exchanges")
+}
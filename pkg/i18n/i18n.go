@@ -0,0 +1,258 @@
+// Package i18n translates user facing messages with catalogs keyed by english format strings.
+// English is the fallback, so untranslated messages are returned as is.
+//
+// Catalogs are json files named by locale, like ru.json, with english formats as keys:
+//
+//	{"Project not found": "Проект не найден", "unknown timezone %s": "неизвестная временная зона %s"}
+//
+// Translations use the same verbs as formats, explicit argument indexes like %[2]s change the order.
+package i18n
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Fallback is the locale of formats, it doesn't need a catalog
+const Fallback = "en"
+
+// formatted texts are matched by formats with verbs replaced to groups
+var verb = regexp.MustCompile(`%(\[\d+\])?[-+# 0]*\d*(\.\d+)?[a-zA-Z%]`)
+
+var localeName = regexp.MustCompile(`^[a-z]{2,3}(-[A-Z]{2})?$`)
+
+type pattern struct {
+	re     *regexp.Regexp
+	format string // translated format with %s verbs for captured strings
+}
+
+type bySpecificity []*pattern
+
+func (s bySpecificity) Len() int      { return len(s) }
+func (s bySpecificity) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s bySpecificity) Less(i, j int) bool {
+	a, b := s[i].re.String(), s[j].re.String()
+	if len(a) != len(b) {
+		return len(a) > len(b)
+	}
+	return a < b
+}
+
+type catalog struct {
+	messages map[string]string
+	patterns []*pattern
+}
+
+// Bundle contains catalogs of all locales, it's safe to use nil bundle, english is returned then
+type Bundle struct {
+	catalogs map[string]*catalog
+}
+
+func New() *Bundle {
+	return &Bundle{
+		catalogs: map[string]*catalog{},
+	}
+}
+
+// Load adds catalogs from json files in the dir, missing dir is skipped
+func (b *Bundle) Load(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		messages := map[string]string{}
+		if err := json.Unmarshal(data, &messages); err != nil {
+			return fmt.Errorf("catalog %s: %s", file, err)
+		}
+		if err := b.Add(strings.TrimSuffix(filepath.Base(file), ".json"), messages); err != nil {
+			return fmt.Errorf("catalog %s: %s", file, err)
+		}
+	}
+	return nil
+}
+
+// Add merges messages to the catalog of the locale
+func (b *Bundle) Add(locale string, messages map[string]string) error {
+	if !localeName.MatchString(locale) {
+		return fmt.Errorf("locale %q should be like ru or pt-BR", locale)
+	}
+	c, ok := b.catalogs[locale]
+	if !ok {
+		c = &catalog{messages: map[string]string{}}
+		b.catalogs[locale] = c
+	}
+	for format, translation := range messages {
+		if translation != "" {
+			c.messages[format] = translation
+		}
+	}
+	c.patterns = []*pattern{}
+	for format, translation := range c.messages {
+		if p := newPattern(format, translation); p != nil {
+			c.patterns = append(c.patterns, p)
+		}
+	}
+	// longer formats are more specific, so they are tried first
+	sort.Sort(bySpecificity(c.patterns))
+	return nil
+}
+
+func newPattern(format, translation string) *pattern {
+	verbs := verb.FindAllStringIndex(format, -1)
+	args := 0
+	expr := "(?s)^"
+	last := 0
+	for _, loc := range verbs {
+		expr += regexp.QuoteMeta(format[last:loc[0]])
+		if format[loc[1]-1] == '%' {
+			expr += "%"
+		} else {
+			expr += "(.*?)"
+			args++
+		}
+		last = loc[1]
+	}
+	if args == 0 {
+		return nil
+	}
+	expr += regexp.QuoteMeta(format[last:]) + "$"
+	// captured args are strings, so every verb of the translation is printed as a string
+	translation = verb.ReplaceAllStringFunc(translation, func(v string) string {
+		if strings.HasSuffix(v, "%") {
+			return v
+		}
+		if m := verb.FindStringSubmatch(v); m[1] != "" {
+			return "%" + m[1] + "s"
+		}
+		return "%s"
+	})
+	return &pattern{re: regexp.MustCompile(expr), format: translation}
+}
+
+// Locales returns locales with catalogs, english isn't included
+func (b *Bundle) Locales() []string {
+	results := []string{}
+	if b == nil {
+		return results
+	}
+	for locale := range b.catalogs {
+		results = append(results, locale)
+	}
+	sort.Strings(results)
+	return results
+}
+
+// Has reports whether messages could be translated to the locale, english is always supported
+func (b *Bundle) Has(locale string) bool {
+	if locale == Fallback {
+		return true
+	}
+	return b.get(locale) != nil
+}
+
+// Sprintf formats the translation of the format
+func (b *Bundle) Sprintf(locale, format string, args ...interface{}) string {
+	if c := b.get(locale); c != nil {
+		if translation, ok := c.messages[format]; ok {
+			format = translation
+		}
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Translate returns the translation of the already formatted text, the format is found by the text
+func (b *Bundle) Translate(locale, text string) string {
+	c := b.get(locale)
+	if c == nil || text == "" {
+		return text
+	}
+	if translation, ok := c.messages[text]; ok {
+		return translation
+	}
+	for _, p := range c.patterns {
+		m := p.re.FindStringSubmatch(text)
+		if m == nil {
+			continue
+		}
+		args := make([]interface{}, len(m)-1)
+		for i, arg := range m[1:] {
+			args[i] = arg
+		}
+		return fmt.Sprintf(p.format, args...)
+	}
+	return text
+}
+
+// Match returns the best supported locale from the Accept-Language header, english if nothing matches
+func (b *Bundle) Match(accept string) string {
+	tags := []*tag{}
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		t := &tag{locale: strings.TrimSpace(fields[0]), q: 1}
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					t.q = q
+				}
+			}
+		}
+		if t.locale != "" && t.q > 0 {
+			tags = append(tags, t)
+		}
+	}
+	sort.Stable(byQuality(tags))
+	for _, t := range tags {
+		if locale := b.Supported(t.locale); locale != "" {
+			return locale
+		}
+	}
+	return Fallback
+}
+
+// Supported returns the locale with catalog for the language tag, like ru for ru-RU, or empty string
+func (b *Bundle) Supported(name string) string {
+	parts := strings.SplitN(strings.Replace(name, "_", "-", 1), "-", 2)
+	lang := strings.ToLower(parts[0])
+	if len(parts) == 2 {
+		if full := lang + "-" + strings.ToUpper(parts[1]); b.Has(full) {
+			return full
+		}
+	}
+	if b.Has(lang) {
+		return lang
+	}
+	return ""
+}
+
+type tag struct {
+	locale string
+	q      float64
+}
+
+type byQuality []*tag
+
+func (s byQuality) Len() int           { return len(s) }
+func (s byQuality) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byQuality) Less(i, j int) bool { return s[i].q > s[j].q }
+
+func (b *Bundle) get(locale string) *catalog {
+	if b == nil || locale == "" || locale == Fallback {
+		return nil
+	}
+	return b.catalogs[locale]
+}
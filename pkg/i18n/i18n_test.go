@@ -0,0 +1,62 @@
+package i18n
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundle(t *testing.T) {
+	b := New()
+	require.NoError(t, b.Add("ru", map[string]string{
+		"Project not found":                        "Проект не найден",
+		"unknown timezone %s":                      "неизвестная временная зона %s",
+		"Issue %q isn't acknowledged for %d hours": "Уязвимость %q не подтверждена уже %d ч.",
+		"Scan %s for target %s is failed":          "Цель %[2]s: сканирование %[1]s завершилось ошибкой",
+		"Validation error: %s":                     "Ошибка проверки: %s",
+	}))
+	assert.Error(t, b.Add("russian", map[string]string{}))
+	assert.Equal(t, []string{"ru"}, b.Locales())
+
+	assert.Equal(t, "Проект не найден", b.Sprintf("ru", "Project not found"))
+	assert.Equal(t, "Project not found", b.Sprintf("en", "Project not found"))
+	assert.Equal(t, "Project not found", b.Sprintf("de", "Project not found"))
+	assert.Equal(t, "Уязвимость \"Xss\" не подтверждена уже 4 ч.", b.Sprintf("ru", "Issue %q isn't acknowledged for %d hours", "Xss", 4))
+
+	// formatted texts are translated by formats
+	assert.Equal(t, "Проект не найден", b.Translate("ru", "Project not found"))
+	assert.Equal(t, "неизвестная временная зона Mars/Olympus", b.Translate("ru", "unknown timezone Mars/Olympus"))
+	assert.Equal(t, "Уязвимость \"Xss\" не подтверждена уже 4 ч.", b.Translate("ru", "Issue \"Xss\" isn't acknowledged for 4 hours"))
+	assert.Equal(t, "Цель example.com: сканирование 1a завершилось ошибкой", b.Translate("ru", "Scan 1a for target example.com is failed"))
+	assert.Equal(t, "Unknown message", b.Translate("ru", "Unknown message"))
+	assert.Equal(t, "unknown timezone Mars", b.Translate("en", "unknown timezone Mars"))
+
+	var nilBundle *Bundle
+	assert.Equal(t, "Hello, bob", nilBundle.Sprintf("ru", "Hello, %s", "bob"))
+	assert.Equal(t, "Project not found", nilBundle.Translate("ru", "Project not found"))
+	assert.Equal(t, Fallback, nilBundle.Match("ru"))
+}
+
+func TestMatch(t *testing.T) {
+	b := New()
+	require.NoError(t, b.Add("ru", map[string]string{"Not found": "Не найдено"}))
+	require.NoError(t, b.Add("pt-BR", map[string]string{"Not found": "Não encontrado"}))
+
+	assert.Equal(t, "ru", b.Match("ru-RU,ru;q=0.9,en;q=0.8"))
+	assert.Equal(t, "ru", b.Match("de;q=0.9,ru;q=0.5"))
+	assert.Equal(t, "en", b.Match("en,ru;q=0.5"))
+	assert.Equal(t, "pt-BR", b.Match("pt-br"))
+	assert.Equal(t, "en", b.Match("pt-PT,de"))
+	assert.Equal(t, "en", b.Match(""))
+	assert.Equal(t, "en", b.Match("ru;q=0"))
+}
+
+func TestLoad(t *testing.T) {
+	b := New()
+	require.NoError(t, b.Load("../../extra/locales"))
+	assert.Contains(t, b.Locales(), "ru")
+	assert.Equal(t, "требуется авторизация", b.Translate("ru", "authorization required"))
+
+	require.NoError(t, b.Load("missing"))
+}
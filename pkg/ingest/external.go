@@ -0,0 +1,72 @@
+package ingest
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/models/token"
+	"github.com/bearded-web/bearded/pkg/manager"
+)
+
+// ExternalResult counts issues pushed by an external tool
+type ExternalResult struct {
+	Created int `json:"created"`
+	Updated int `json:"updated" description:"issues with known uniqId, resolved issues are reopened"`
+	Skipped int `json:"skipped" description:"issues marked as false positive"`
+}
+
+// External stores issues pushed by external tools with the ingest token.
+// Issues are merged by uniqId like issues of plugin reports, tool is matched by project rules as plugin.
+func External(mgr *manager.Manager, tok *token.Token, tgt *target.Target, tool string, issues []*issue.Issue) (*ExternalResult, error) {
+	proj, err := mgr.Projects.GetById(tgt.Project)
+	if err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	away, err := mgr.Users.Away(proj.RuleAssignees(), time.Now().UTC())
+	if err != nil {
+		return nil, stackerr.Wrap(err)
+	}
+	result := &ExternalResult{}
+	for _, issueObj := range issues {
+		targetIssue := &issue.TargetIssue{
+			Target:   tgt.Id,
+			Project:  tgt.Project,
+			Issue:    *issueObj,
+			Exposure: tgt.Exposure,
+		}
+		targetIssue.AddUserReportActivity(tok.User)
+		attribute(tgt, &targetIssue.Issue)
+		project.ApplyRules(proj.Rules, tool, targetIssue)
+		proj.SkipAway(targetIssue, away)
+		if _, err := mgr.Issues.Create(targetIssue); err != nil {
+			if !mgr.IsDup(err) {
+				return nil, stackerr.Wrap(err)
+			}
+			existed, err := mgr.Issues.GetByUniqId(tgt.Id, targetIssue.UniqId)
+			if err != nil {
+				return nil, stackerr.Wrap(err)
+			}
+			if existed.False {
+				result.Skipped++
+				continue
+			}
+			existed.AddUserReportActivity(tok.User)
+			existed.Reopen()
+			if err := mgr.Issues.Update(existed); err != nil {
+				return nil, stackerr.Wrap(err)
+			}
+			result.Updated++
+			continue
+		}
+		if _, err := mgr.Feed.AddIssue(targetIssue, "", tok.User); err != nil {
+			logrus.Error(stackerr.Wrap(err))
+		}
+		result.Created++
+	}
+	return result, nil
+}
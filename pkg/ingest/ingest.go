@@ -0,0 +1,215 @@
+// Package ingest is the pipeline for plugin reports: reports are validated,
+// normalized and stored with target issues and techs. Malformed reports are quarantined.
+package ingest
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/plugin"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/report"
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/models/tech"
+	"github.com/bearded-web/bearded/pkg/discovery"
+	"github.com/bearded-web/bearded/pkg/manager"
+)
+
+// QuarantineError is returned when the report is malformed and saved to quarantine
+type QuarantineError struct {
+	Quarantine *report.Quarantine
+}
+
+func (e *QuarantineError) Error() string {
+	return fmt.Sprintf("report is quarantined as %s: %s", e.Quarantine.Id.Hex(), strings.Join(e.Quarantine.Errors, "; "))
+}
+
+// Ingest parses the report sent by plugin for the scan session and stores it.
+// Broken reports are put to quarantine and *QuarantineError is returned.
+func Ingest(mgr *manager.Manager, data []byte, sc *scan.Scan, sess *scan.Session) (*report.Report, error) {
+	sevMap, err := SeverityMap(mgr, sess)
+	if err != nil {
+		return nil, err
+	}
+	rep, problems := Parse(data, sevMap)
+	if len(problems) > 0 {
+		q, err := mgr.Quarantine.Create(&report.Quarantine{
+			Scan:        sc.Id,
+			ScanSession: sess.Id,
+			Errors:      problems,
+			Data:        string(data),
+		})
+		if err != nil {
+			return nil, stackerr.Wrap(err)
+		}
+		logrus.Warnf("Report for session %s is quarantined: %s", sess.Id.Hex(), strings.Join(problems, "; "))
+		return nil, &QuarantineError{Quarantine: q}
+	}
+	return Store(mgr, rep, sc, sess)
+}
+
+// SeverityMap returns severity mapping for the session plugin or nil if it isn't set up
+func SeverityMap(mgr *manager.Manager, sess *scan.Session) (*plugin.SeverityMap, error) {
+	if sess.Step == nil || sess.Step.Plugin == "" {
+		return nil, nil
+	}
+	sevMap, err := mgr.Severities.GetByPlugin(sess.Step.Plugin)
+	if err != nil {
+		if mgr.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, stackerr.Wrap(err)
+	}
+	return sevMap, nil
+}
+
+// Store saves valid report and creates target issues and techs from it.
+// Use mgr.IsDup to check if the report for this session is already existed.
+func Store(mgr *manager.Manager, raw *report.Report, sc *scan.Scan, sess *scan.Session) (*report.Report, error) {
+	raw.SetScan(sc.Id)
+	raw.SetScanSession(sess.Id)
+
+	// TODO (m0sth8): for raw reports check metadata for files (check if file existed, set right md5, size etc)
+	rep, err := mgr.Reports.Create(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// TODO (m0sth8): exclude to another process (maybe push to queue)
+	if err := createTargetIssues(mgr, rep, sc, sess); err != nil {
+		return nil, err
+	}
+	if err := createTargetTechs(mgr, rep, sc, sess); err != nil {
+		return nil, err
+	}
+	if hosts, err := discovery.SaveHosts(mgr, sc, rep.GetAllHosts()); err != nil {
+		return nil, err
+	} else if len(hosts) > 0 {
+		logrus.Infof("Discovery %s found %d new hosts", sc.Discovery.Hex(), len(hosts))
+	}
+
+	// update feed item
+	if err := mgr.Feed.UpdateScanReport(sc, rep); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+	}
+	return rep, nil
+}
+
+func createTargetIssues(mgr *manager.Manager, rep *report.Report, sc *scan.Scan, sess *scan.Session) error {
+	issues := rep.GetAllIssues()
+	if len(issues) == 0 {
+		return nil
+	}
+
+	proj, err := mgr.Projects.GetById(sc.Project)
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	plugin := ""
+	if sess.Step != nil {
+		plugin = sess.Step.Plugin
+	}
+	tgt, err := mgr.Targets.GetById(sc.Target)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		tgt = &target.Target{}
+	}
+	away, err := mgr.Users.Away(proj.RuleAssignees(), time.Now().UTC())
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+
+	for _, issueObj := range issues {
+		if issueObj.Severity == issue.SeverityError {
+			continue
+		}
+		targetIssue := &issue.TargetIssue{
+			Target:   sc.Target,
+			Project:  sc.Project,
+			Issue:    *issueObj,
+			Exposure: tgt.Exposure,
+		}
+		targetIssue.AddReportActivity(rep.Id, sc.Id, sess.Id)
+		if proj.RequireReview && !sc.IsReviewed() {
+			targetIssue.PendingScan = sc.Id
+		}
+		attribute(tgt, &targetIssue.Issue)
+		project.ApplyRules(proj.Rules, plugin, targetIssue)
+		proj.SkipAway(targetIssue, away)
+		_, err := mgr.Issues.Create(targetIssue)
+		if err != nil {
+			if mgr.IsDup(err) {
+				if targetIssue.UniqId != "" {
+					targetIssue, err = mgr.Issues.GetByUniqId(sc.Target, targetIssue.UniqId)
+					if err != nil {
+						logrus.Error(stackerr.Wrap(err))
+					}
+					if targetIssue.False {
+						continue
+					}
+					targetIssue.AddReportActivity(rep.Id, sc.Id, sess.Id)
+					targetIssue.Reopen()
+					err := mgr.Issues.Update(targetIssue)
+					if err != nil {
+						logrus.Error(stackerr.Wrap(err))
+					}
+				}
+				continue
+			} else {
+				return stackerr.Wrap(err)
+			}
+		}
+		if _, err := mgr.Feed.AddIssue(targetIssue, sc.Id, sc.Owner); err != nil {
+			logrus.Error(stackerr.Wrap(err))
+		}
+	}
+
+	return nil
+}
+
+// attribute links the issue to api operation or repository file of the target
+func attribute(tgt *target.Target, obj *issue.Issue) {
+	switch tgt.Type {
+	case target.TypeApi:
+		if obj.Operation == "" {
+			if e := tgt.Api.Match(obj.Request()); e != nil {
+				obj.Operation = e.Operation()
+			}
+		}
+	case target.TypeRepo:
+		if loc := obj.Location; loc != nil {
+			loc.Url = tgt.Repo.FileUrl(loc.Path, loc.Line)
+		}
+	}
+}
+
+func createTargetTechs(mgr *manager.Manager, rep *report.Report, sc *scan.Scan, sess *scan.Session) error {
+	techs := rep.GetAllTechs()
+	if len(techs) == 0 {
+		return nil
+	}
+
+	for _, techObj := range techs {
+		targetTech := &tech.TargetTech{
+			Target:  sc.Target,
+			Project: sc.Project,
+			Tech:    *techObj,
+		}
+		targetTech.AddReportActivity(rep.Id, sc.Id, sess.Id)
+		_, err := mgr.Techs.Create(targetTech)
+		if err != nil {
+			if mgr.IsDup(err) {
+				// TODO (m0sth8): should we make a report activity?
+			} else {
+				return stackerr.Wrap(err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/target"
+)
+
+func TestAttributeRepo(t *testing.T) {
+	tgt := &target.Target{
+		Type: target.TypeRepo,
+		Repo: &target.RepoTarget{Url: "git@github.com:bearded-web/bearded.git", Branch: "dev"},
+	}
+	obj := &issue.Issue{Location: &issue.Location{Path: "pkg/main.go", Line: 7}}
+	attribute(tgt, obj)
+	assert.Equal(t, "https://github.com/bearded-web/bearded/blob/dev/pkg/main.go#L7", obj.Location.Url)
+
+	// issues without location are untouched
+	obj = &issue.Issue{}
+	attribute(tgt, obj)
+	assert.Nil(t, obj.Location)
+}
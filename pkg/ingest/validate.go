@@ -0,0 +1,167 @@
+package ingest
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/plugin"
+	"github.com/bearded-web/bearded/models/report"
+)
+
+// severities which plugins use for the same things
+var severityAliases = map[string]issue.Severity{
+	"":              issue.SeverityInfo,
+	"info":          issue.SeverityInfo,
+	"informational": issue.SeverityInfo,
+	"low":           issue.SeverityLow,
+	"medium":        issue.SeverityMedium,
+	"moderate":      issue.SeverityMedium,
+	"high":          issue.SeverityHigh,
+	"critical":      issue.SeverityHigh,
+	"error":         issue.SeverityError,
+}
+
+// confidences which plugins use for the same things
+var confidenceAliases = map[string]issue.Confidence{
+	"certain":   issue.ConfidenceCertain,
+	"confirmed": issue.ConfidenceCertain,
+	"high":      issue.ConfidenceCertain,
+	"firm":      issue.ConfidenceFirm,
+	"medium":    issue.ConfidenceFirm,
+	"tentative": issue.ConfidenceTentative,
+	"low":       issue.ConfidenceTentative,
+}
+
+// Parse decodes plugin report, normalizes and validates it. sevMap could be nil.
+// Returns the report and the list of problems, the report can't be stored if there are any.
+func Parse(data []byte, sevMap *plugin.SeverityMap) (*report.Report, []string) {
+	rep := &report.Report{}
+	if err := json.Unmarshal(data, rep); err != nil {
+		return nil, []string{fmt.Sprintf("malformed json: %s", err)}
+	}
+	Normalize(rep, sevMap)
+	return rep, Validate(rep)
+}
+
+// Normalize fixes known differences in plugin output: severity and confidence aliases and raw http transactions.
+// Severities from the plugin severity map take precedence over common aliases.
+func Normalize(rep *report.Report, sevMap *plugin.SeverityMap) {
+	if rep == nil {
+		return
+	}
+	for _, sub := range rep.Multi {
+		Normalize(sub, sevMap)
+	}
+	for _, issueObj := range rep.Issues {
+		if issueObj == nil {
+			continue
+		}
+		if sev, ok := sevMap.Get(string(issueObj.Severity)); ok {
+			issueObj.Severity = sev
+		} else if sev, ok := severityAliases[strings.ToLower(strings.TrimSpace(string(issueObj.Severity)))]; ok {
+			issueObj.Severity = sev
+		}
+		if conf, ok := confidenceAliases[strings.ToLower(strings.TrimSpace(string(issueObj.Confidence)))]; ok {
+			issueObj.Confidence = conf
+		}
+		// plugins could send raw http messages, parse them to structured transactions
+		if err := issueObj.Vector.Normalize(); err != nil {
+			logrus.Warnf("Issue %s has broken http transaction: %s", issueObj.Summary, err)
+		}
+	}
+}
+
+// schema returns validation for reports of the plugin report version or nil if the version isn't accepted,
+// version 0 is reports of plugins which don't set the version.
+// Add a schema when the report structure is changed, so plugins built for older versions are still accepted.
+func schema(version int) func(rep *report.Report, path string, problems *[]string) {
+	switch version {
+	case 0, 1:
+		return validateV1
+	}
+	return nil
+}
+
+// Validate returns problems found in the report, empty if the report is fine
+func Validate(rep *report.Report) []string {
+	problems := []string{}
+	validate(rep, "report", &problems)
+	return problems
+}
+
+func validate(rep *report.Report, path string, problems *[]string) {
+	if rep == nil {
+		*problems = append(*problems, fmt.Sprintf("%s: report is null", path))
+		return
+	}
+	validateSchema := schema(rep.Version)
+	if validateSchema == nil {
+		*problems = append(*problems, fmt.Sprintf("%s: version %d isn't supported, the latest is %d", path, rep.Version, report.SchemaVersion))
+		return
+	}
+	validateSchema(rep, path, problems)
+}
+
+func validateV1(rep *report.Report, path string, problems *[]string) {
+	add := func(format string, args ...interface{}) {
+		*problems = append(*problems, fmt.Sprintf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+	switch rep.Type {
+	case report.TypeEmpty, report.TypeRaw:
+	case report.TypeMulti:
+		if len(rep.Multi) == 0 {
+			add("multi report without reports")
+		}
+		for i, sub := range rep.Multi {
+			validate(sub, fmt.Sprintf("%s.multi[%d]", path, i), problems)
+		}
+	case report.TypeIssues:
+		for i, issueObj := range rep.Issues {
+			if issueObj == nil {
+				add("issues[%d] is null", i)
+				continue
+			}
+			if strings.TrimSpace(issueObj.Summary) == "" {
+				add("issues[%d].summary is empty", i)
+			}
+			if issueObj.Severity != issue.SeverityError && !issueObj.Severity.IsValid() {
+				add("issues[%d].severity %q is unknown", i, issueObj.Severity)
+			}
+			if issueObj.Confidence != "" && !issueObj.Confidence.IsValid() {
+				add("issues[%d].confidence %q is unknown", i, issueObj.Confidence)
+			}
+			if loc := issueObj.Location; loc != nil {
+				if strings.TrimSpace(loc.Path) == "" {
+					add("issues[%d].location.path is empty", i)
+				}
+				if loc.Line < 0 || loc.EndLine < 0 || loc.Column < 0 {
+					add("issues[%d].location has negative position", i)
+				}
+			}
+		}
+	case report.TypeTechs:
+		for i, techObj := range rep.Techs {
+			if techObj == nil {
+				add("techs[%d] is null", i)
+				continue
+			}
+			if strings.TrimSpace(techObj.Name) == "" {
+				add("techs[%d].name is empty", i)
+			}
+		}
+	case report.TypeHosts:
+		for i, host := range rep.Hosts {
+			if strings.TrimSpace(host) == "" {
+				add("hosts[%d] is empty", i)
+			}
+		}
+	case "":
+		add("type is empty")
+	default:
+		add("type %q is unknown", rep.Type)
+	}
+}
@@ -0,0 +1,125 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/plugin"
+)
+
+func TestParse(t *testing.T) {
+	rep, problems := Parse([]byte(`{
+		"type": "multi",
+		"multi": [
+			{"type": "issues", "issues": [
+				{"summary": "xss", "severity": "Critical"},
+				{"summary": "info leak", "severity": "informational"},
+				{"summary": "no severity"}
+			]},
+			{"type": "techs", "techs": [{"name": "nginx"}]},
+			{"type": "hosts", "hosts": ["api.example.com"]},
+			{"type": "raw", "raw": "data"}
+		]
+	}`), nil)
+	require.Empty(t, problems)
+	issues := rep.GetAllIssues()
+	require.Len(t, issues, 3)
+	assert.Equal(t, issue.SeverityHigh, issues[0].Severity)
+	assert.Equal(t, issue.SeverityInfo, issues[1].Severity)
+	assert.Equal(t, issue.SeverityInfo, issues[2].Severity)
+	assert.Equal(t, []string{"api.example.com"}, rep.GetAllHosts())
+
+	_, problems = Parse([]byte(`{"type": "issues", "issues": [`), nil)
+	require.Len(t, problems, 1)
+	assert.Contains(t, problems[0], "malformed json")
+
+	_, problems = Parse([]byte(`{
+		"type": "multi",
+		"multi": [
+			{"type": "issues", "issues": [{"summary": "", "severity": "bla"}]},
+			{"type": "techs", "techs": [{"version": "1"}]},
+			{"type": "unknown"},
+			{"type": "multi"},
+			{"type": "hosts", "hosts": [" "]}
+		]
+	}`), nil)
+	assert.Equal(t, []string{
+		"report.multi[0]: issues[0].summary is empty",
+		`report.multi[0]: issues[0].severity "bla" is unknown`,
+		"report.multi[1]: techs[0].name is empty",
+		`report.multi[2]: type "unknown" is unknown`,
+		"report.multi[3]: multi report without reports",
+		"report.multi[4]: hosts[0] is empty",
+	}, problems)
+
+	_, problems = Parse([]byte(`{"type": "multi", "multi": [null, {"type": "issues", "issues": [null]}]}`), nil)
+	assert.Equal(t, []string{
+		"report.multi[0]: report is null",
+		"report.multi[1]: issues[0] is null",
+	}, problems)
+
+	_, problems = Parse([]byte(`{}`), nil)
+	assert.Equal(t, []string{"report: type is empty"}, problems)
+}
+
+func TestParseVersion(t *testing.T) {
+	_, problems := Parse([]byte(`{"type": "hosts", "version": 1, "hosts": ["api.example.com"]}`), nil)
+	assert.Empty(t, problems)
+
+	_, problems = Parse([]byte(`{"type": "multi", "multi": [{"type": "empty", "version": 99}]}`), nil)
+	assert.Equal(t, []string{"report.multi[0]: version 99 isn't supported, the latest is 1"}, problems)
+}
+
+func TestParseSeverityMap(t *testing.T) {
+	sevMap := &plugin.SeverityMap{
+		Plugin: "barbudo/nikto",
+		Mapping: map[string]issue.Severity{
+			"informational": issue.SeverityLow,
+			"osvdb":         issue.SeverityMedium,
+		},
+	}
+	rep, problems := Parse([]byte(`{"type": "issues", "issues": [
+		{"summary": "banner", "severity": "Informational"},
+		{"summary": "osvdb entry", "severity": "OSVDB"},
+		{"summary": "not mapped", "severity": "high"}
+	]}`), sevMap)
+	require.Empty(t, problems)
+	require.Len(t, rep.Issues, 3)
+	assert.Equal(t, issue.SeverityLow, rep.Issues[0].Severity)
+	assert.Equal(t, issue.SeverityMedium, rep.Issues[1].Severity)
+	assert.Equal(t, issue.SeverityHigh, rep.Issues[2].Severity)
+}
+
+func TestParseLocation(t *testing.T) {
+	rep, problems := Parse([]byte(`{"type": "issues", "issues": [
+		{"summary": "sql injection", "severity": "high", "location": {"path": "app/db.go", "line": 42}},
+		{"summary": "no path", "severity": "high", "location": {"line": 1}},
+		{"summary": "negative", "severity": "high", "location": {"path": "a.go", "line": -1}}
+	]}`), nil)
+	assert.Equal(t, []string{
+		"report: issues[1].location.path is empty",
+		"report: issues[2].location has negative position",
+	}, problems)
+	require.Len(t, rep.Issues, 3)
+	require.NotNil(t, rep.Issues[0].Location)
+	assert.Equal(t, "app/db.go", rep.Issues[0].Location.Path)
+	assert.Equal(t, 42, rep.Issues[0].Location.Line)
+}
+
+func TestParseConfidence(t *testing.T) {
+	rep, problems := Parse([]byte(`{"type": "issues", "issues": [
+		{"summary": "sqli", "severity": "high", "confidence": "Confirmed"},
+		{"summary": "xss", "severity": "medium", "confidence": "tentative"},
+		{"summary": "banner", "severity": "info"}
+	]}`), nil)
+	require.Empty(t, problems)
+	assert.Equal(t, issue.ConfidenceCertain, rep.Issues[0].Confidence)
+	assert.Equal(t, issue.ConfidenceTentative, rep.Issues[1].Confidence)
+	assert.Equal(t, issue.Confidence(""), rep.Issues[2].Confidence)
+
+	_, problems = Parse([]byte(`{"type": "issues", "issues": [{"summary": "xss", "confidence": "maybe"}]}`), nil)
+	assert.Equal(t, []string{`report: issues[0].confidence "maybe" is unknown`}, problems)
+}
@@ -92,3 +92,29 @@ func (m *AgentManager) Update(obj *agent.Agent) error {
 func (m *AgentManager) Remove(obj *agent.Agent) error {
 	return m.col.RemoveId(obj.Id)
 }
+
+// AddPull asks agents to pull images with their next jobs request
+func (m *AgentManager) AddPull(ids []bson.ObjectId, images []string) error {
+	_, err := m.col.UpdateAll(bson.M{"_id": bson.M{"$in": ids}},
+		bson.M{"$addToSet": bson.M{"pull": bson.M{"$each": images}}})
+	return err
+}
+
+// TakePull returns images which the agent should pull and removes them from the agent
+func (m *AgentManager) TakePull(id bson.ObjectId) ([]string, error) {
+	obj := &agent.Agent{}
+	_, err := m.col.Find(bson.M{"_id": id, "pull.0": bson.M{"$exists": true}}).Apply(mgo.Change{
+		Update: bson.M{"$unset": bson.M{"pull": ""}},
+	}, obj)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+	return obj.Pull, err
+}
+
+// SetImages merges images reported by the agent to its cache
+func (m *AgentManager) SetImages(obj *agent.Agent, images []*agent.Image, reported time.Time) error {
+	obj.MergeImages(images)
+	obj.ImagesReported = &reported
+	return m.col.UpdateId(obj.Id, bson.M{"$set": bson.M{"images": obj.Images, "imagesReported": reported}})
+}
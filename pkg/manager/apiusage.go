@@ -0,0 +1,134 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/apiusage"
+)
+
+// ApiUsageTtl is how long hourly api usage buckets are kept
+const ApiUsageTtl = 90 * 24 * time.Hour
+
+type ApiUsageManager struct {
+	manager *Manager
+	col     *mgo.Collection
+}
+
+func (s *ApiUsageManager) Init() error {
+	logrus.Infof("Initialize api usage indexes")
+	err := s.col.EnsureIndex(mgo.Index{
+		Key:        []string{"project", "hour", "token", "route"},
+		Background: true,
+	})
+	if err != nil {
+		return err
+	}
+	return s.col.EnsureIndex(mgo.Index{
+		Key:         []string{"hour"},
+		Background:  true,
+		ExpireAfter: ApiUsageTtl,
+	})
+}
+
+// Add increments stored buckets by counters of the given ones
+func (m *ApiUsageManager) Add(buckets []*apiusage.Bucket) error {
+	for _, b := range buckets {
+		selector := bson.M{"project": b.Project, "route": b.Route, "hour": b.Hour}
+		if b.Token != "" {
+			selector["token"] = b.Token
+		} else {
+			selector["token"] = bson.M{"$exists": false}
+		}
+		_, err := m.col.Upsert(selector, bson.M{
+			"$inc": bson.M{"calls": b.Calls, "errors": b.Errors, "latency": b.Latency},
+			"$max": bson.M{"maxlatency": b.MaxLatency},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Report sums calls to the project in the period [from, to), zero to isn't limited
+func (m *ApiUsageManager) Report(projectId bson.ObjectId, from, to time.Time) (*apiusage.Report, error) {
+	hour := bson.M{"$gte": from}
+	if !to.IsZero() {
+		hour["$lt"] = to
+	}
+	match := bson.M{"project": projectId, "hour": hour}
+	report := &apiusage.Report{Project: projectId, From: from, To: to}
+
+	var err error
+	if report.Tokens, err = m.stats(match, "token", bson.M{"calls": -1}); err != nil {
+		return nil, err
+	}
+	if report.Routes, err = m.stats(match, "route", bson.M{"calls": -1}); err != nil {
+		return nil, err
+	}
+	if report.Hours, err = m.stats(match, "hour", bson.M{"hour": 1}); err != nil {
+		return nil, err
+	}
+	for _, st := range report.Hours {
+		report.Calls += st.Calls
+	}
+	if err := m.nameTokens(report.Tokens); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// stats groups matched buckets by the field
+func (m *ApiUsageManager) stats(match bson.M, field string, sort bson.M) ([]*apiusage.Stat, error) {
+	results := []*apiusage.Stat{}
+	err := m.col.Pipe([]bson.M{
+		{"$match": match},
+		{"$group": bson.M{
+			"_id":        "$" + field,
+			"calls":      bson.M{"$sum": "$calls"},
+			"errors":     bson.M{"$sum": "$errors"},
+			"latency":    bson.M{"$sum": "$latency"},
+			"maxlatency": bson.M{"$max": "$maxlatency"},
+		}},
+		{"$project": bson.M{"_id": 0, field: "$_id", "calls": 1, "errors": 1, "latency": 1, "maxlatency": 1}},
+		{"$sort": sort},
+	}).All(&results)
+	if err != nil {
+		return nil, err
+	}
+	for _, st := range results {
+		if st.Calls > 0 {
+			st.AvgLatency = st.Latency / st.Calls
+		}
+	}
+	return results, nil
+}
+
+// nameTokens sets token names, so admins recognize integrations, removed tokens stay without name
+func (m *ApiUsageManager) nameTokens(stats []*apiusage.Stat) error {
+	ids := []bson.ObjectId{}
+	for _, st := range stats {
+		if st.Token != "" {
+			ids = append(ids, st.Token)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	tokens, _, err := m.manager.Tokens.FilterByQuery(bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return err
+	}
+	names := map[bson.ObjectId]string{}
+	for _, tok := range tokens {
+		names[tok.Id] = tok.Name
+	}
+	for _, st := range stats {
+		st.TokenName = names[st.Token]
+	}
+	return nil
+}
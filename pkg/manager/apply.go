@@ -0,0 +1,49 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/apply"
+)
+
+type ApplyStateManager struct {
+	manager *Manager
+	col     *mgo.Collection
+}
+
+func (m *ApplyStateManager) Init() error {
+	logrus.Infof("Initialize apply state indexes")
+	return m.col.EnsureIndex(mgo.Index{
+		Key:        []string{"owner", "name"},
+		Unique:     true,
+		Background: false,
+	})
+}
+
+func (m *ApplyStateManager) GetByName(owner bson.ObjectId, name string) (*apply.State, error) {
+	st := &apply.State{}
+	return st, m.manager.GetBy(m.col, &bson.M{"owner": owner, "name": name}, st)
+}
+
+// Save replaces the state of the bundle with the same owner and name
+func (m *ApplyStateManager) Save(st *apply.State) error {
+	st.Applied = time.Now().UTC()
+	info, err := m.col.Upsert(bson.M{"owner": st.Owner, "name": st.Name}, bson.M{
+		"$set": bson.M{
+			"bundle":    st.Bundle,
+			"resources": st.Resources,
+			"applied":   st.Applied,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if id, ok := info.UpsertedId.(bson.ObjectId); ok {
+		st.Id = id
+	}
+	return nil
+}
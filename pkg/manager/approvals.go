@@ -0,0 +1,183 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/approval"
+	"github.com/bearded-web/bearded/models/cascade"
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/pkg/fltr"
+)
+
+type ApprovalManager struct {
+	manager *Manager
+	col     *mgo.Collection
+}
+
+type ApprovalFltr struct {
+	Action    approval.Action `fltr:"action,in"`
+	Status    approval.Status `fltr:"status,in"`
+	Project   bson.ObjectId   `fltr:"project"`
+	Requester bson.ObjectId   `fltr:"requester"`
+	Created   time.Time       `fltr:"created,gte,gt,lte,lt"`
+}
+
+func (m *ApprovalManager) Init() error {
+	logrus.Infof("Initialize approval indexes")
+	for _, index := range []string{"status", "project", "requester"} {
+		err := m.col.EnsureIndex(mgo.Index{
+			Key:        []string{index},
+			Background: true,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *ApprovalManager) Fltr() *ApprovalFltr {
+	return &ApprovalFltr{}
+}
+
+func (m *ApprovalManager) GetById(id bson.ObjectId) (*approval.Approval, error) {
+	u := &approval.Approval{}
+	return u, m.manager.GetById(m.col, id, &u)
+}
+
+func (m *ApprovalManager) FilterBy(f *ApprovalFltr, opts ...Opts) ([]*approval.Approval, int, error) {
+	query := fltr.GetQuery(f)
+	return m.FilterByQuery(query, opts...)
+}
+
+func (m *ApprovalManager) FilterByQuery(query bson.M, opts ...Opts) ([]*approval.Approval, int, error) {
+	results := []*approval.Approval{}
+	count, err := m.manager.FilterBy(m.col, &query, &results, opts...)
+	return results, count, err
+}
+
+func (m *ApprovalManager) Update(obj *approval.Approval) error {
+	return m.col.UpdateId(obj.Id, obj)
+}
+
+// Required returns true if destructive actions should be confirmed by a second admin
+func (m *ApprovalManager) Required() bool {
+	return m.manager.Cfg.ApprovalWindow > 0
+}
+
+// Submit executes the action right away if approvals aren't required,
+// otherwise the action is saved as pending. Returns true if the action was executed.
+func (m *ApprovalManager) Submit(raw *approval.Approval) (bool, error) {
+	if err := m.checkChildren(raw); err != nil {
+		return false, err
+	}
+	if !m.Required() {
+		return true, m.Execute(raw)
+	}
+	raw.Id = bson.NewObjectId()
+	raw.Status = approval.StatusPending
+	raw.Created = time.Now().UTC()
+	raw.Expires = raw.Created.Add(m.manager.Cfg.ApprovalWindow)
+	return false, m.col.Insert(raw)
+}
+
+// Approve marks the pending action as approved by the reviewer and executes it.
+// Returns ErrNotFound if the approval was already resolved or expired, so the action is executed once.
+// If the action fails, the approval becomes pending again.
+func (m *ApprovalManager) Approve(obj *approval.Approval, reviewer bson.ObjectId) error {
+	if err := m.resolve(obj, approval.StatusApproved, reviewer); err != nil {
+		return err
+	}
+	if err := m.Execute(obj); err != nil {
+		uErr := m.col.UpdateId(obj.Id, bson.M{
+			"$set":   bson.M{"status": approval.StatusPending},
+			"$unset": bson.M{"reviewer": "", "resolved": ""},
+		})
+		if uErr != nil {
+			logrus.Errorf("Couldn't return approval %s to pending: %s", obj.Id.Hex(), uErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// Reject marks the pending action as rejected by the reviewer,
+// returns ErrNotFound if the approval was already resolved or expired
+func (m *ApprovalManager) Reject(obj *approval.Approval, reviewer bson.ObjectId) error {
+	return m.resolve(obj, approval.StatusRejected, reviewer)
+}
+
+// resolve atomically moves the approval from pending to the status
+func (m *ApprovalManager) resolve(obj *approval.Approval, status approval.Status, reviewer bson.ObjectId) error {
+	now := time.Now().UTC()
+	set := bson.M{"status": status, "reviewer": reviewer, "resolved": now}
+	if obj.Reason != "" {
+		set["reason"] = obj.Reason
+	}
+	_, err := m.col.Find(bson.M{
+		"_id":     obj.Id,
+		"status":  approval.StatusPending,
+		"expires": bson.M{"$gt": now},
+	}).Apply(mgo.Change{Update: bson.M{"$set": set}, ReturnNew: true}, obj)
+	return err
+}
+
+// ExpireAll marks pending approvals which weren't approved in time
+func (m *ApprovalManager) ExpireAll() error {
+	now := time.Now().UTC()
+	_, err := m.col.UpdateAll(
+		bson.M{"status": approval.StatusPending, "expires": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"status": approval.StatusExpired, "resolved": now}},
+	)
+	return err
+}
+
+// Execute runs the action, objects which are already removed are skipped
+func (m *ApprovalManager) Execute(obj *approval.Approval) error {
+	switch obj.Action {
+	case approval.ActionProjectDelete:
+		p, err := m.manager.Projects.GetById(obj.Project)
+		if err != nil {
+			return m.skipNotFound(err)
+		}
+		_, err = m.manager.Cascades.RemoveProject(p, obj.Requester)
+		return err
+	case approval.ActionTargetDelete:
+		t, err := m.manager.Targets.GetById(obj.Target)
+		if err != nil {
+			return m.skipNotFound(err)
+		}
+		_, err = m.manager.Cascades.RemoveTarget(t, obj.Requester)
+		return err
+	case approval.ActionIssuesDelete:
+		// issues are moved to the trash like removed one by one,
+		// their comments and worklogs are kept for restore and purged with them
+		query := bson.M{"_id": bson.M{"$in": obj.Issues}, "project": obj.Project}
+		return m.manager.Issues.Stream(query, Opts{}, func(iss *issue.TargetIssue) error {
+			return m.manager.Issues.Trash(iss, obj.Requester)
+		})
+	}
+	return nil
+}
+
+// checkChildren doesn't let to request deletion which would be blocked by the cascade policy
+func (m *ApprovalManager) checkChildren(obj *approval.Approval) error {
+	switch obj.Action {
+	case approval.ActionProjectDelete:
+		return m.manager.Cascades.Check(cascade.KindProject, obj.Project)
+	case approval.ActionTargetDelete:
+		return m.manager.Cascades.Check(cascade.KindTarget, obj.Target)
+	}
+	return nil
+}
+
+func (m *ApprovalManager) skipNotFound(err error) error {
+	if m.manager.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
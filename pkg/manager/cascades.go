@@ -0,0 +1,413 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/cascade"
+	"github.com/bearded-web/bearded/models/comment"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/models/tombstone"
+	"github.com/bearded-web/bearded/pkg/fltr"
+)
+
+// issues are removed by batches to report progress of the job
+const cascadeBatch = 500
+
+// working jobs which weren't updated during this time are taken again, e.g. after restart
+const cascadeStale = 5 * time.Minute
+
+// ChildrenError is returned when the block policy doesn't allow to remove an entity with issues or scans
+type ChildrenError struct {
+	Msg string
+}
+
+func (e *ChildrenError) Error() string {
+	return e.Msg
+}
+
+func IsChildren(err error) bool {
+	_, ok := err.(*ChildrenError)
+	return ok
+}
+
+type CascadeManager struct {
+	manager *Manager
+	col     *mgo.Collection
+}
+
+type CascadeFltr struct {
+	Kind    cascade.Kind   `fltr:"kind,in"`
+	Status  cascade.Status `fltr:"status,in"`
+	Entity  bson.ObjectId  `fltr:"entity"`
+	Project bson.ObjectId  `fltr:"project"`
+	User    bson.ObjectId  `fltr:"user"`
+	Created time.Time      `fltr:"created,gte,gt,lte,lt"`
+}
+
+func (m *CascadeManager) Init() error {
+	logrus.Infof("Initialize cascade indexes")
+	for _, index := range []string{"status", "entity", "project", "user"} {
+		err := m.col.EnsureIndex(mgo.Index{
+			Key:        []string{index},
+			Background: true,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *CascadeManager) Fltr() *CascadeFltr {
+	return &CascadeFltr{}
+}
+
+func (m *CascadeManager) GetById(id bson.ObjectId) (*cascade.Job, error) {
+	u := &cascade.Job{}
+	return u, m.manager.GetById(m.col, id, &u)
+}
+
+func (m *CascadeManager) FilterBy(f *CascadeFltr, opts ...Opts) ([]*cascade.Job, int, error) {
+	query := fltr.GetQuery(f)
+	return m.FilterByQuery(query, opts...)
+}
+
+func (m *CascadeManager) FilterByQuery(query bson.M, opts ...Opts) ([]*cascade.Job, int, error) {
+	results := []*cascade.Job{}
+	count, err := m.manager.FilterBy(m.col, &query, &results, opts...)
+	return results, count, err
+}
+
+func (m *CascadeManager) Update(obj *cascade.Job) error {
+	return m.col.UpdateId(obj.Id, obj)
+}
+
+// Policy returns the configured policy, children are cascaded by default
+func (m *CascadeManager) Policy() cascade.Policy {
+	if m.manager.Cfg.CascadePolicy == "" {
+		return cascade.PolicyCascade
+	}
+	return m.manager.Cfg.CascadePolicy
+}
+
+// Check returns ChildrenError if the block policy is set and the target or project has issues or scans
+func (m *CascadeManager) Check(kind cascade.Kind, id bson.ObjectId) error {
+	if m.Policy() != cascade.PolicyBlock {
+		return nil
+	}
+	field := "target"
+	if kind == cascade.KindProject {
+		field = "project"
+	}
+	issues, err := m.manager.Issues.col.Find(bson.M{field: id}).Count()
+	if err != nil {
+		return err
+	}
+	scans, err := m.manager.Scans.col.Find(bson.M{field: id}).Count()
+	if err != nil {
+		return err
+	}
+	if issues > 0 || scans > 0 {
+		return &ChildrenError{Msg: fmt.Sprintf("%s has %d issues and %d scans, remove them first", kind, issues, scans)}
+	}
+	return nil
+}
+
+// RemoveTarget removes the target and starts a job to remove its issues and scans
+func (m *CascadeManager) RemoveTarget(obj *target.Target, user bson.ObjectId) (*cascade.Job, error) {
+	if err := m.Check(cascade.KindTarget, obj.Id); err != nil {
+		return nil, err
+	}
+	if err := m.manager.Targets.Remove(obj); err != nil {
+		return nil, err
+	}
+	return m.create(cascade.KindTarget, obj.Id, obj.Project, user)
+}
+
+// RemoveProject removes the project and starts a job to remove its targets, issues and scans
+func (m *CascadeManager) RemoveProject(obj *project.Project, user bson.ObjectId) (*cascade.Job, error) {
+	if err := m.Check(cascade.KindProject, obj.Id); err != nil {
+		return nil, err
+	}
+	if err := m.manager.Projects.Remove(obj); err != nil {
+		return nil, err
+	}
+	return m.create(cascade.KindProject, obj.Id, obj.Id, user)
+}
+
+func (m *CascadeManager) create(kind cascade.Kind, entity, projectId, user bson.ObjectId) (*cascade.Job, error) {
+	field := "target"
+	if kind == cascade.KindProject {
+		field = "project"
+	}
+	now := time.Now().UTC()
+	obj := &cascade.Job{
+		Id:      bson.NewObjectId(),
+		Kind:    kind,
+		Entity:  entity,
+		Project: projectId,
+		User:    user,
+		Status:  cascade.StatusPending,
+		Created: now,
+		Updated: now,
+	}
+	cols := []*mgo.Collection{m.manager.Issues.col, m.manager.Scans.col}
+	if kind == cascade.KindProject {
+		cols = append(cols, m.manager.Targets.col)
+	}
+	for _, col := range cols {
+		count, err := col.Find(bson.M{field: entity}).Count()
+		if err != nil {
+			return nil, err
+		}
+		obj.Total += count
+	}
+	return obj, m.col.Insert(obj)
+}
+
+// Take marks the oldest pending or stale working job as working, returns ErrNotFound if there are no jobs
+func (m *CascadeManager) Take(now time.Time) (*cascade.Job, error) {
+	obj := &cascade.Job{}
+	_, err := m.col.Find(bson.M{"$or": []bson.M{
+		{"status": cascade.StatusPending},
+		{"status": cascade.StatusWorking, "updated": bson.M{"$lt": now.Add(-cascadeStale)}},
+	}}).Sort("created").Apply(mgo.Change{
+		Update:    bson.M{"$set": bson.M{"status": cascade.StatusWorking, "updated": now}},
+		ReturnNew: true,
+	}, obj)
+	return obj, err
+}
+
+// Process removes children of the job and saves progress after every batch.
+// Removal is idempotent, so a failed or interrupted job could be processed again.
+func (m *CascadeManager) Process(obj *cascade.Job) error {
+	var err error
+	if obj.Kind == cascade.KindProject {
+		err = m.removeProjectChildren(obj)
+	} else {
+		err = m.removeTargetChildren(obj, obj.Entity)
+	}
+	obj.Finish(err, time.Now().UTC())
+	if uErr := m.Update(obj); uErr != nil {
+		return uErr
+	}
+	return err
+}
+
+func (m *CascadeManager) removeProjectChildren(obj *cascade.Job) error {
+	query := bson.M{"project": obj.Entity}
+	for {
+		targets := []*target.Target{}
+		if err := m.manager.Targets.col.Find(query).Limit(cascadeBatch).All(&targets); err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			break
+		}
+		for _, t := range targets {
+			if err := m.removeTargetChildren(obj, t.Id); err != nil {
+				return err
+			}
+			if err := m.manager.Targets.Remove(t); err != nil && !m.manager.IsNotFound(err) {
+				return err
+			}
+			if err := m.progress(obj, 1); err != nil {
+				return err
+			}
+		}
+	}
+	// issues and scans of targets which were removed earlier without cascade
+	if err := m.removeIssues(obj, query); err != nil {
+		return err
+	}
+	if err := m.removeScans(obj, query); err != nil {
+		return err
+	}
+	if _, err := m.removeTrash(query); err != nil {
+		return err
+	}
+	cols := []*mgo.Collection{
+		m.manager.Feed.col,
+		m.manager.Worklogs.col,
+		m.manager.Discovery.col,
+		m.manager.Hosts.col,
+		m.manager.Pages.col,
+		m.manager.Pages.revisions,
+	}
+	for _, col := range cols {
+		if _, err := col.RemoveAll(query); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *CascadeManager) removeTargetChildren(obj *cascade.Job, targetId bson.ObjectId) error {
+	query := bson.M{"target": targetId}
+	if err := m.removeIssues(obj, query); err != nil {
+		return err
+	}
+	if err := m.removeScans(obj, query); err != nil {
+		return err
+	}
+	if _, err := m.removeTrash(query); err != nil {
+		return err
+	}
+	for _, col := range []*mgo.Collection{m.manager.Feed.col, m.manager.Discovery.col} {
+		if _, err := col.RemoveAll(query); err != nil {
+			return err
+		}
+	}
+	_, err := m.manager.Comments.col.RemoveAll(bson.M{"type": comment.Scan, "link": targetId})
+	return err
+}
+
+// removeIssues removes issues with their comments and worklogs by batches
+func (m *CascadeManager) removeIssues(obj *cascade.Job, query bson.M) error {
+	for {
+		results := []struct {
+			Id bson.ObjectId `bson:"_id"`
+		}{}
+		err := m.manager.Issues.col.Find(query).Select(bson.M{"_id": 1}).Limit(cascadeBatch).All(&results)
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			return nil
+		}
+		ids := make([]bson.ObjectId, len(results))
+		for i, r := range results {
+			ids[i] = r.Id
+		}
+		removed, err := m.removeIssueIds(ids)
+		if err != nil {
+			return err
+		}
+		if err := m.progress(obj, removed); err != nil {
+			return err
+		}
+	}
+}
+
+// removeIssueIds removes issues with their comments, worklogs and links from other issues,
+// returns count of removed issues
+func (m *CascadeManager) removeIssueIds(ids []bson.ObjectId) (int, error) {
+	defer m.manager.Issues.invalidate()
+	in := bson.M{"$in": ids}
+	projects, err := m.manager.Issues.projects(bson.M{"_id": in})
+	if err != nil {
+		return 0, err
+	}
+	if _, err := m.manager.Comments.col.RemoveAll(bson.M{"type": comment.Issue, "link": in}); err != nil {
+		return 0, err
+	}
+	if _, err := m.manager.Worklogs.col.RemoveAll(bson.M{"issue": in}); err != nil {
+		return 0, err
+	}
+	info, err := m.manager.Issues.col.RemoveAll(bson.M{"_id": in})
+	if err != nil {
+		return 0, err
+	}
+	m.manager.Tombstones.AddAll(tombstone.Issue, projects)
+	// issues of other targets could be linked to removed ones
+	if err := m.manager.Issues.unlinkAll(ids); err != nil {
+		return 0, err
+	}
+	return info.Removed, nil
+}
+
+// removeTrash removes trashed issues with their comments and worklogs by batches,
+// returns count of removed issues
+func (m *CascadeManager) removeTrash(query bson.M) (int, error) {
+	total := 0
+	for {
+		results := []struct {
+			Id bson.ObjectId `bson:"_id"`
+		}{}
+		err := m.manager.Issues.trash.Find(query).Select(bson.M{"_id": 1}).Limit(cascadeBatch).All(&results)
+		if err != nil {
+			return total, err
+		}
+		if len(results) == 0 {
+			return total, nil
+		}
+		ids := make([]bson.ObjectId, len(results))
+		for i, r := range results {
+			ids[i] = r.Id
+		}
+		in := bson.M{"$in": ids}
+		if _, err := m.manager.Comments.col.RemoveAll(bson.M{"type": comment.Issue, "link": in}); err != nil {
+			return total, err
+		}
+		if _, err := m.manager.Worklogs.col.RemoveAll(bson.M{"issue": in}); err != nil {
+			return total, err
+		}
+		info, err := m.manager.Issues.trash.RemoveAll(bson.M{"_id": in})
+		if err != nil {
+			return total, err
+		}
+		total += info.Removed
+	}
+}
+
+// removeScans removes scans with their reports, archived raw data and session artifacts by batches
+func (m *CascadeManager) removeScans(obj *cascade.Job, query bson.M) error {
+	for {
+		scans := []*scan.Scan{}
+		if err := m.manager.Scans.col.Find(query).Limit(cascadeBatch).All(&scans); err != nil {
+			return err
+		}
+		if len(scans) == 0 {
+			return nil
+		}
+		ids := make([]bson.ObjectId, len(scans))
+		projects := make(map[bson.ObjectId]bson.ObjectId, len(scans))
+		for i, sc := range scans {
+			ids[i] = sc.Id
+			projects[sc.Id] = sc.Project
+			if err := m.removeArtifacts(sc); err != nil {
+				return err
+			}
+		}
+		if err := m.manager.Reports.removeByScans(ids); err != nil {
+			return err
+		}
+		info, err := m.manager.Scans.col.RemoveAll(bson.M{"_id": bson.M{"$in": ids}})
+		if err != nil {
+			return err
+		}
+		m.manager.Tombstones.AddAll(tombstone.Scan, projects)
+		if err := m.progress(obj, info.Removed); err != nil {
+			return err
+		}
+	}
+}
+
+func (m *CascadeManager) removeArtifacts(sc *scan.Scan) error {
+	for _, sess := range sc.Sessions {
+		for _, s := range append([]*scan.Session{sess}, sess.GetAllChildren()...) {
+			for _, meta := range s.Artifacts {
+				if err := m.manager.Files.Remove(meta); err != nil && !m.manager.IsNotFound(err) {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (m *CascadeManager) progress(obj *cascade.Job, n int) error {
+	obj.Advance(n, time.Now().UTC())
+	return m.col.UpdateId(obj.Id, bson.M{"$set": bson.M{
+		"total":   obj.Total,
+		"done":    obj.Done,
+		"updated": obj.Updated,
+	}})
+}
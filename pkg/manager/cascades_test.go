@@ -0,0 +1,115 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/cascade"
+	"github.com/bearded-web/bearded/models/comment"
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/models/worklog"
+	"github.com/bearded-web/bearded/pkg/tests"
+)
+
+func TestCascadeRemoveTarget(t *testing.T) {
+	mongo, dbName, err := tests.RandomTestMongoUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tests.RandomTestMongoDown(mongo, dbName)
+
+	mgr := New(mongo.DB(dbName), ManagerConfig{CascadePolicy: cascade.PolicyBlock})
+	projectId, userId := bson.NewObjectId(), bson.NewObjectId()
+	obj, err := mgr.Targets.Create(&target.Target{Project: projectId, Type: target.TypeWeb})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err := mgr.Issues.Create(&issue.TargetIssue{Project: projectId, Target: obj.Id})
+		require.NoError(t, err)
+	}
+	_, err = mgr.Scans.Create(&scan.Scan{Project: projectId, Target: obj.Id, Plan: bson.NewObjectId(), Owner: userId})
+	require.NoError(t, err)
+
+	_, err = mgr.Cascades.RemoveTarget(obj, userId)
+	assert.True(t, IsChildren(err))
+	_, err = mgr.Targets.GetById(obj.Id)
+	require.NoError(t, err)
+
+	mgr.Cfg.CascadePolicy = cascade.PolicyCascade
+	job, err := mgr.Cascades.RemoveTarget(obj, userId)
+	require.NoError(t, err)
+	assert.Equal(t, 4, job.Total)
+	_, err = mgr.Targets.GetById(obj.Id)
+	assert.True(t, mgr.IsNotFound(err))
+
+	taken, err := mgr.Cascades.Take(time.Now().UTC())
+	require.NoError(t, err)
+	assert.Equal(t, job.Id, taken.Id)
+	require.NoError(t, mgr.Cascades.Process(taken))
+
+	job, err = mgr.Cascades.GetById(job.Id)
+	require.NoError(t, err)
+	assert.Equal(t, cascade.StatusFinished, job.Status)
+	assert.Equal(t, 4, job.Done)
+	_, count, err := mgr.Issues.FilterByQuery(bson.M{"target": obj.Id})
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	_, err = mgr.Cascades.Take(time.Now().UTC())
+	assert.True(t, mgr.IsNotFound(err))
+}
+
+func TestPurgeExpiredTrash(t *testing.T) {
+	mongo, dbName, err := tests.RandomTestMongoUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tests.RandomTestMongoDown(mongo, dbName)
+
+	mgr := New(mongo.DB(dbName))
+	require.NoError(t, mgr.Init())
+	projectId, userId := bson.NewObjectId(), bson.NewObjectId()
+	trash := func() *issue.TargetIssue {
+		obj, err := mgr.Issues.Create(&issue.TargetIssue{Project: projectId, Target: bson.NewObjectId()})
+		require.NoError(t, err)
+		_, err = mgr.Comments.Create(&comment.Comment{Owner: userId, Type: comment.Issue, Link: obj.Id, Text: "a"})
+		require.NoError(t, err)
+		_, err = mgr.Worklogs.Create(&worklog.Worklog{Issue: obj.Id, Project: projectId, User: userId})
+		require.NoError(t, err)
+		require.NoError(t, mgr.Issues.Trash(obj, userId))
+		return obj
+	}
+	trash()
+	trash()
+	purged, err := mgr.Issues.PurgeExpired(time.Now().UTC().Add(IssueTrashTtl + time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 2, purged)
+
+	expired, fresh := trash(), trash()
+	require.NoError(t, mgr.Issues.trash.UpdateId(expired.Id,
+		bson.M{"$set": bson.M{"removed": time.Now().UTC().Add(-IssueTrashTtl - time.Hour)}}))
+	purged, err = mgr.Issues.PurgeExpired(time.Now().UTC())
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	_, err = mgr.Issues.GetTrashed(expired.Id)
+	assert.True(t, mgr.IsNotFound(err))
+	_, count, err := mgr.Comments.FilterByQuery(bson.M{"link": expired.Id})
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+	_, count, err = mgr.Worklogs.FilterByQuery(bson.M{"issue": expired.Id})
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	// comments and worklogs of fresh issues are kept for restore
+	_, err = mgr.Issues.GetTrashed(fresh.Id)
+	require.NoError(t, err)
+	_, count, err = mgr.Comments.FilterByQuery(bson.M{"link": fresh.Id})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
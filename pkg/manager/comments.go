@@ -8,6 +8,7 @@ import (
 	"gopkg.in/mgo.v2/bson"
 
 	"github.com/bearded-web/bearded/models/comment"
+	"github.com/bearded-web/bearded/models/tombstone"
 	"github.com/bearded-web/bearded/pkg/fltr"
 )
 
@@ -50,7 +51,9 @@ func (m *CommentManager) Fltr() *CommentFltr {
 
 func (m *CommentManager) GetById(id bson.ObjectId) (*comment.Comment, error) {
 	u := &comment.Comment{}
-	return u, m.manager.GetById(m.col, id, &u)
+	err := m.manager.GetById(m.col, id, &u)
+	u.Text = m.manager.Cfg.Sanitizer.Sanitize(u.Text)
+	return u, err
 }
 
 func (m *CommentManager) FilterBy(f *CommentFltr, opts ...Opts) ([]*comment.Comment, int, error) {
@@ -61,14 +64,28 @@ func (m *CommentManager) FilterBy(f *CommentFltr, opts ...Opts) ([]*comment.Comm
 func (m *CommentManager) FilterByQuery(query bson.M, opts ...Opts) ([]*comment.Comment, int, error) {
 	results := []*comment.Comment{}
 	count, err := m.manager.FilterBy(m.col, &query, &results, opts...)
+	for _, obj := range results {
+		obj.Text = m.manager.Cfg.Sanitizer.Sanitize(obj.Text)
+	}
 	return results, count, err
 }
 
+// Stream calls fn for every comment of the query without loading all of them, see IssueManager.Stream
+func (m *CommentManager) Stream(query bson.M, opt Opts, fn func(*comment.Comment) error) error {
+	doc := func() interface{} { return &comment.Comment{} }
+	return m.manager.stream(m.col, query, opt, doc, func(raw interface{}) error {
+		obj := raw.(*comment.Comment)
+		obj.Text = m.manager.Cfg.Sanitizer.Sanitize(obj.Text)
+		return fn(obj)
+	})
+}
+
 func (m *CommentManager) Create(raw *comment.Comment) (*comment.Comment, error) {
 	// TODO (m0sth8): add validation
 	raw.Id = bson.NewObjectId()
 	raw.Created = time.Now().UTC()
 	raw.Updated = raw.Created
+	raw.Text = m.manager.Cfg.Sanitizer.Sanitize(raw.Text)
 	if err := m.col.Insert(raw); err != nil {
 		return nil, err
 	}
@@ -77,9 +94,28 @@ func (m *CommentManager) Create(raw *comment.Comment) (*comment.Comment, error)
 
 func (m *CommentManager) Update(obj *comment.Comment) error {
 	obj.Updated = time.Now().UTC()
+	obj.Text = m.manager.Cfg.Sanitizer.Sanitize(obj.Text)
 	return m.col.UpdateId(obj.Id, obj)
 }
 
 func (m *CommentManager) Remove(obj *comment.Comment) error {
-	return m.col.RemoveId(obj.Id)
+	if err := m.col.RemoveId(obj.Id); err != nil {
+		return err
+	}
+	// comments don't know their project, so it's taken from the commented entity
+	var project bson.ObjectId
+	switch obj.Type {
+	case comment.Issue:
+		if issueObj, err := m.manager.Issues.GetById(obj.Link); err == nil {
+			project = issueObj.Project
+		}
+	case comment.Scan:
+		if tgt, err := m.manager.Targets.GetById(obj.Link); err == nil {
+			project = tgt.Project
+		}
+	}
+	if project != "" {
+		m.manager.Tombstones.Add(tombstone.Comment, obj.Id, project)
+	}
+	return nil
 }
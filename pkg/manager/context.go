@@ -0,0 +1,154 @@
+package manager
+
+import (
+	"reflect"
+	"time"
+
+	"golang.org/x/net/context"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// the context is checked before every this count of fetched documents
+const contextCheckEvery = 100
+
+// SetContext makes list queries stop when the context is done, e.g. when the client aborts the request.
+// It should be set only on a copy which isn't shared between requests.
+func (m *Manager) SetContext(ctx context.Context) {
+	m.ctx = ctx
+}
+
+// queryContext returns the manager context limited by the query timeout
+func (m *Manager) queryContext() (context.Context, context.CancelFunc) {
+	ctx := m.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if m.Cfg.QueryTimeout > 0 {
+		return context.WithTimeout(ctx, m.Cfg.QueryTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// all works like Query.All, but stops fetching and closes the cursor as soon as the context is done
+func (m *Manager) all(q *mgo.Query, results interface{}) error {
+	if m.ctx != nil || m.Cfg.QueryTimeout > 0 {
+		ctx, cancel := m.queryContext()
+		setMaxTime(ctx, q)
+		cancel()
+	}
+	return m.iterAll(q.Iter(), results)
+}
+
+// maxTimer is implemented by mgo queries since r2015.05.29, the vendored r2015.01.24 doesn't have it
+type maxTimer interface {
+	SetMaxTime(d time.Duration) *mgo.Query
+}
+
+// setMaxTime makes the server stop the query at the context deadline if the mgo version supports it,
+// the context itself is checked only between fetched documents
+func setMaxTime(ctx context.Context, q *mgo.Query) {
+	mt, ok := interface{}(q).(maxTimer)
+	if !ok {
+		return
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+	d := deadline.Sub(time.Now())
+	if d < time.Millisecond {
+		// zero is no limit
+		d = time.Millisecond
+	}
+	mt.SetMaxTime(d)
+}
+
+// iterAll works like Iter.All, the context error is returned if the context is done before the end
+func (m *Manager) iterAll(iter *mgo.Iter, results interface{}) error {
+	if m.ctx == nil && m.Cfg.QueryTimeout <= 0 {
+		return iter.All(results)
+	}
+	ctx, cancel := m.queryContext()
+	defer cancel()
+
+	resultv := reflect.ValueOf(results)
+	if resultv.Kind() != reflect.Ptr || resultv.Elem().Kind() != reflect.Slice {
+		panic("result argument must be a slice address")
+	}
+	slicev := resultv.Elem()
+	slicev = slicev.Slice(0, 0)
+	elemt := slicev.Type().Elem()
+	for i := 0; ; i++ {
+		if i%contextCheckEvery == 0 {
+			select {
+			case <-ctx.Done():
+				// closing kills the server cursor, so the rest isn't materialized
+				iter.Close()
+				return ctx.Err()
+			default:
+			}
+		}
+		elemp := reflect.New(elemt)
+		if !iter.Next(elemp.Interface()) {
+			break
+		}
+		slicev = reflect.Append(slicev, elemp.Elem())
+	}
+	resultv.Elem().Set(slicev)
+	return iter.Close()
+}
+
+// contextErr returns the context error if the manager context is done, it's checked before extra queries
+func (m *Manager) contextErr() error {
+	if m.ctx == nil {
+		return nil
+	}
+	return m.ctx.Err()
+}
+
+// IsCanceled returns true if the query is stopped by the done context or the query timeout
+func (m *Manager) IsCanceled(err error) bool {
+	return err == context.Canceled || err == context.DeadlineExceeded
+}
+
+// stream iterates the query without loading all documents, doc returns a new document to fill
+// and fn is called with it. It stops when fn returns an error or the manager context is done,
+// the query timeout isn't applied because streams are long.
+func (m *Manager) stream(col *mgo.Collection, query bson.M, opt Opts, doc func() interface{}, fn func(interface{}) error) error {
+	q := col.Find(query)
+	if opt.Sort != nil {
+		q.Sort(opt.Sort...)
+	}
+	if opt.Skip != 0 {
+		q.Skip(opt.Skip)
+	}
+	if opt.Limit != 0 {
+		q.Limit(opt.Limit)
+	}
+	if m.ctx != nil {
+		setMaxTime(m.ctx, q)
+	}
+	iter := q.Iter()
+	for i := 0; ; i++ {
+		if i%contextCheckEvery == 0 {
+			if err := m.contextErr(); err != nil {
+				// closing kills the server cursor
+				iter.Close()
+				return err
+			}
+		}
+		obj := doc()
+		if !iter.Next(obj) {
+			break
+		}
+		if err := fn(obj); err != nil {
+			iter.Close()
+			if err == ErrStop {
+				return nil
+			}
+			return err
+		}
+	}
+	return iter.Close()
+}
@@ -0,0 +1,39 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/pkg/tests"
+)
+
+func TestManagerContext(t *testing.T) {
+	mongo, dbName, err := tests.RandomTestMongoUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tests.RandomTestMongoDown(mongo, dbName)
+
+	mgr := New(mongo.DB(dbName))
+	projectId := bson.NewObjectId()
+	for i := 0; i < contextCheckEvery+1; i++ {
+		_, err := mgr.Targets.Create(&target.Target{Project: projectId, Type: target.TypeWeb})
+		require.NoError(t, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	mgr.SetContext(ctx)
+	results, count, err := mgr.Targets.FilterByQuery(bson.M{"project": projectId})
+	require.NoError(t, err)
+	assert.Len(t, results, contextCheckEvery+1)
+	assert.Equal(t, contextCheckEvery+1, count)
+
+	cancel()
+	_, _, err = mgr.Targets.FilterByQuery(bson.M{"project": projectId})
+	assert.True(t, mgr.IsCanceled(err))
+}
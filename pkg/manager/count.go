@@ -0,0 +1,134 @@
+package manager
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// How to count results in FilterBy
+type CountMode int
+
+const (
+	CountExact   CountMode = iota // run count query every time
+	CountCached                   // exact count, cached for the same query
+	CountHasMore                  // don't count, just check if there are more results after the page
+)
+
+// ParseCountMode converts query parameter value to count mode, def is returned for unknown values
+func ParseCountMode(val string, def CountMode) CountMode {
+	switch val {
+	case "exact":
+		return CountExact
+	case "cached":
+		return CountCached
+	case "none":
+		return CountHasMore
+	}
+	return def
+}
+
+// maxCountItems bounds the cache, every filter combination of list requests makes a new key
+const maxCountItems = 1000
+
+type countItem struct {
+	count   int
+	expires time.Time
+}
+
+// CountCache keeps exact counts for repeated queries. It's shared between manager copies.
+// Managers should call Invalidate after modifying collections which use cached counts.
+// The cache is local to the process, so changes made by other api servers are visible after ttl.
+type CountCache struct {
+	ttl time.Duration
+
+	mu    sync.Mutex
+	items map[string]map[string]countItem // collection -> query -> count
+	size  int
+}
+
+func NewCountCache(ttl time.Duration) *CountCache {
+	return &CountCache{
+		ttl:   ttl,
+		items: map[string]map[string]countItem{},
+	}
+}
+
+func (c *CountCache) Get(col string, query interface{}) (int, bool) {
+	key, ok := countKey(query)
+	if !ok {
+		return 0, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	item, ok := c.items[col][key]
+	if !ok || time.Now().After(item.expires) {
+		return 0, false
+	}
+	return item.count, true
+}
+
+func (c *CountCache) Set(col string, query interface{}, count int) {
+	if c.ttl <= 0 {
+		return
+	}
+	key, ok := countKey(query)
+	if !ok {
+		return
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.items[col] == nil {
+		c.items[col] = map[string]countItem{}
+	}
+	if _, ok := c.items[col][key]; !ok {
+		if c.size >= maxCountItems {
+			c.evict(now)
+		}
+		c.size++
+	}
+	c.items[col][key] = countItem{count: count, expires: now.Add(c.ttl)}
+}
+
+// evict removes expired counts, if all of them are fresh, random counts are removed to free a tenth of the cache
+func (c *CountCache) evict(now time.Time) {
+	for col, items := range c.items {
+		for key, item := range items {
+			if now.After(item.expires) {
+				delete(items, key)
+				c.size--
+			}
+		}
+		if len(items) == 0 {
+			delete(c.items, col)
+		}
+	}
+	for col, items := range c.items {
+		for key := range items {
+			if c.size < maxCountItems*9/10 {
+				return
+			}
+			delete(items, key)
+			c.size--
+		}
+		delete(c.items, col)
+	}
+}
+
+// Invalidate removes all cached counts for collection
+func (c *CountCache) Invalidate(col string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.size -= len(c.items[col])
+	delete(c.items, col)
+}
+
+// json is used because it sorts map keys, so equal queries have equal keys
+func countKey(query interface{}) (string, bool) {
+	data, err := json.Marshal(query)
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
@@ -0,0 +1,64 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestCountCache(t *testing.T) {
+	c := NewCountCache(time.Minute)
+	id := bson.NewObjectId()
+
+	_, ok := c.Get("issues", bson.M{"target": id})
+	assert.False(t, ok)
+
+	c.Set("issues", bson.M{"target": id, "muted": false}, 10)
+	count, ok := c.Get("issues", bson.M{"muted": false, "target": id})
+	assert.True(t, ok)
+	assert.Equal(t, 10, count)
+
+	_, ok = c.Get("targets", bson.M{"target": id, "muted": false})
+	assert.False(t, ok)
+
+	c.Invalidate("issues")
+	_, ok = c.Get("issues", bson.M{"target": id, "muted": false})
+	assert.False(t, ok)
+
+	// expired
+	c = NewCountCache(time.Nanosecond)
+	c.Set("issues", bson.M{}, 1)
+	time.Sleep(time.Millisecond)
+	_, ok = c.Get("issues", bson.M{})
+	assert.False(t, ok)
+}
+
+func TestCountCacheBound(t *testing.T) {
+	c := NewCountCache(time.Minute)
+	for i := 0; i < maxCountItems*2; i++ {
+		c.Set("issues", bson.M{"skip": i}, i)
+	}
+	assert.True(t, c.size <= maxCountItems)
+	size := 0
+	for _, items := range c.items {
+		size += len(items)
+	}
+	assert.Equal(t, c.size, size)
+
+	// the last count is kept
+	count, ok := c.Get("issues", bson.M{"skip": maxCountItems*2 - 1})
+	assert.True(t, ok)
+	assert.Equal(t, maxCountItems*2-1, count)
+
+	c.Invalidate("issues")
+	assert.Equal(t, 0, c.size)
+}
+
+func TestParseCountMode(t *testing.T) {
+	assert.Equal(t, CountHasMore, ParseCountMode("none", CountExact))
+	assert.Equal(t, CountCached, ParseCountMode("cached", CountExact))
+	assert.Equal(t, CountExact, ParseCountMode("exact", CountCached))
+	assert.Equal(t, CountCached, ParseCountMode("", CountCached))
+}
@@ -0,0 +1,165 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/discovery"
+	"github.com/bearded-web/bearded/pkg/fltr"
+)
+
+type DiscoveryManager struct {
+	manager *Manager
+	col     *mgo.Collection
+}
+
+type DiscoveryFltr struct {
+	Project bson.ObjectId `fltr:"project"`
+	Target  bson.ObjectId `fltr:"target"`
+	Enabled *bool         `fltr:"enabled"`
+}
+
+func (m *DiscoveryManager) Init() error {
+	logrus.Infof("Initialize discovery indexes")
+	for _, index := range []string{"project", "target", "enabled"} {
+		err := m.col.EnsureIndex(mgo.Index{
+			Key:        []string{index},
+			Background: true,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *DiscoveryManager) Fltr() *DiscoveryFltr {
+	return &DiscoveryFltr{}
+}
+
+func (m *DiscoveryManager) GetById(id bson.ObjectId) (*discovery.Discovery, error) {
+	u := &discovery.Discovery{}
+	return u, m.manager.GetById(m.col, id, u)
+}
+
+func (m *DiscoveryManager) FilterBy(f *DiscoveryFltr, opts ...Opts) ([]*discovery.Discovery, int, error) {
+	query := fltr.GetQuery(f)
+	return m.FilterByQuery(query, opts...)
+}
+
+func (m *DiscoveryManager) FilterByQuery(query bson.M, opts ...Opts) ([]*discovery.Discovery, int, error) {
+	results := []*discovery.Discovery{}
+	count, err := m.manager.FilterBy(m.col, &query, &results, opts...)
+	return results, count, err
+}
+
+func (m *DiscoveryManager) Create(raw *discovery.Discovery) (*discovery.Discovery, error) {
+	raw.Id = bson.NewObjectId()
+	raw.Created = time.Now().UTC()
+	raw.Updated = raw.Created
+	if err := m.col.Insert(raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+func (m *DiscoveryManager) Update(obj *discovery.Discovery) error {
+	obj.Updated = time.Now().UTC()
+	return m.col.UpdateId(obj.Id, obj)
+}
+
+func (m *DiscoveryManager) Remove(obj *discovery.Discovery) error {
+	return m.col.RemoveId(obj.Id)
+}
+
+type HostManager struct {
+	manager *Manager
+	col     *mgo.Collection
+}
+
+type HostFltr struct {
+	Project   bson.ObjectId        `fltr:"project"`
+	Discovery bson.ObjectId        `fltr:"discovery"`
+	Status    discovery.HostStatus `fltr:"status,in"`
+	Name      string               `fltr:"name"`
+}
+
+func (m *HostManager) Init() error {
+	logrus.Infof("Initialize discovered host indexes")
+	err := m.col.EnsureIndex(mgo.Index{
+		Key:        []string{"project", "name"},
+		Unique:     true,
+		Background: false,
+	})
+	if err != nil {
+		return err
+	}
+	for _, index := range []string{"discovery", "status", "seen"} {
+		err := m.col.EnsureIndex(mgo.Index{
+			Key:        []string{index},
+			Background: true,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *HostManager) Fltr() *HostFltr {
+	return &HostFltr{}
+}
+
+func (m *HostManager) GetById(id bson.ObjectId) (*discovery.Host, error) {
+	u := &discovery.Host{}
+	return u, m.manager.GetById(m.col, id, u)
+}
+
+func (m *HostManager) GetByName(projectId bson.ObjectId, name string) (*discovery.Host, error) {
+	u := &discovery.Host{}
+	return u, m.manager.GetBy(m.col, &bson.M{"project": projectId, "name": name}, u)
+}
+
+func (m *HostManager) FilterBy(f *HostFltr, opts ...Opts) ([]*discovery.Host, int, error) {
+	query := fltr.GetQuery(f)
+	return m.FilterByQuery(query, opts...)
+}
+
+func (m *HostManager) FilterByQuery(query bson.M, opts ...Opts) ([]*discovery.Host, int, error) {
+	results := []*discovery.Host{}
+	count, err := m.manager.FilterBy(m.col, &query, &results, opts...)
+	return results, count, err
+}
+
+func (m *HostManager) Create(raw *discovery.Host) (*discovery.Host, error) {
+	raw.Id = bson.NewObjectId()
+	raw.Created = time.Now().UTC()
+	raw.Updated = raw.Created
+	if raw.Status == "" {
+		raw.Status = discovery.HostPending
+	}
+	if raw.Seen.IsZero() {
+		raw.Seen = raw.Created
+	}
+	if err := m.col.Insert(raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+func (m *HostManager) Update(obj *discovery.Host) error {
+	obj.Updated = time.Now().UTC()
+	return m.col.UpdateId(obj.Id, obj)
+}
+
+// Seen updates the last time when the host was reported
+func (m *HostManager) Seen(id bson.ObjectId, t time.Time) error {
+	return m.col.UpdateId(id, bson.M{"$set": bson.M{"seen": t}})
+}
+
+func (m *HostManager) Remove(obj *discovery.Host) error {
+	return m.col.RemoveId(obj.Id)
+}
@@ -0,0 +1,71 @@
+package manager
+
+import (
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/plugin"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/scan"
+)
+
+// EngagementError is returned when the scan breaks the project rules of engagement
+type EngagementError struct {
+	Violations []string
+}
+
+func (e *EngagementError) Error() string {
+	return "scan breaks the rules of engagement: " + strings.Join(e.Violations, "; ")
+}
+
+func IsEngagement(err error) bool {
+	_, ok := err.(*EngagementError)
+	return ok
+}
+
+// PluginGetter returns the plugin by id
+type PluginGetter func(id bson.ObjectId) (*plugin.Plugin, error)
+
+// CheckEngagement returns EngagementError if the scan plugins, its rate limit or the time t
+// aren't allowed by the project rules of engagement
+func (m *ScanManager) CheckEngagement(p *project.Project, sc *scan.Scan, t time.Time) error {
+	return m.CheckEngagementWith(p, sc, t, func(id bson.ObjectId) (*plugin.Plugin, error) {
+		return m.manager.Plugins.GetById(id.Hex())
+	})
+}
+
+// CheckEngagementWith works like CheckEngagement, plugins of all sessions including children are got by get,
+// e.g. from a cache
+func (m *ScanManager) CheckEngagementWith(p *project.Project, sc *scan.Scan, t time.Time, get PluginGetter) error {
+	if p.Engagement == nil {
+		return nil
+	}
+	plugins := []*plugin.Plugin{}
+	if len(p.Engagement.ForbiddenCategories) > 0 {
+		for _, sess := range sc.GetAllSessions() {
+			pl, err := get(sess.Plugin)
+			if err != nil {
+				if m.manager.IsNotFound(err) {
+					continue
+				}
+				return err
+			}
+			plugins = append(plugins, pl)
+		}
+	}
+	if v := p.Engagement.Violations(plugins, sc.Conf.RateLimit, t); len(v) > 0 {
+		return &EngagementError{Violations: v}
+	}
+	return nil
+}
+
+// CheckPlugin returns EngagementError if the plugin is in a forbidden category of the project,
+// sessions which are spawned by running scans are checked with it
+func (m *ScanManager) CheckPlugin(p *project.Project, pl *plugin.Plugin) error {
+	if v := p.Engagement.PluginViolations([]*plugin.Plugin{pl}); len(v) > 0 {
+		return &EngagementError{Violations: v}
+	}
+	return nil
+}
@@ -1,7 +1,13 @@
 package manager
 
-import "gopkg.in/mgo.v2"
+import (
+	"errors"
+
+	"gopkg.in/mgo.v2"
+)
 
 var (
 	ErrNotFound = mgo.ErrNotFound // alias
+	// ErrStop could be returned by stream callbacks to stop the stream without an error
+	ErrStop = errors.New("stop")
 )
@@ -0,0 +1,58 @@
+package manager
+
+// Exploits manager
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/exploit"
+)
+
+type ExploitManager struct {
+	manager *Manager
+	col     *mgo.Collection
+}
+
+func (m *ExploitManager) Init() error {
+	return m.col.EnsureIndex(mgo.Index{
+		Key:        []string{"updated"},
+		Background: true,
+	})
+}
+
+// Store replaces the stored catalog, cves which aren't in the catalog anymore are removed
+func (m *ExploitManager) Store(c exploit.Catalog, now time.Time) error {
+	for cve, refs := range c {
+		_, err := m.col.UpsertId(cve, &exploit.Exploit{Cve: cve, Refs: refs, Updated: now})
+		if err != nil {
+			return err
+		}
+	}
+	_, err := m.col.RemoveAll(bson.M{"updated": bson.M{"$lt": now}})
+	return err
+}
+
+// Catalog returns the stored exploits of cves
+func (m *ExploitManager) Catalog(cves []string) (exploit.Catalog, error) {
+	ids := []string{}
+	for _, cve := range cves {
+		if id, ok := exploit.NormalizeCve(cve); ok {
+			ids = append(ids, id)
+		}
+	}
+	c := exploit.Catalog{}
+	if len(ids) == 0 {
+		return c, nil
+	}
+	results := []*exploit.Exploit{}
+	if err := m.col.Find(bson.M{"_id": bson.M{"$in": ids}}).All(&results); err != nil {
+		return nil, err
+	}
+	for _, e := range results {
+		c[e.Cve] = e.Refs
+	}
+	return c, nil
+}
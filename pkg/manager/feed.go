@@ -17,6 +17,9 @@ import (
 	"github.com/bearded-web/bearded/pkg/fltr"
 )
 
+// similar feed items are collapsed if they are created within this window after the last one
+const feedAggregateWindow = 10 * time.Minute
+
 type FeedManager struct {
 	manager *Manager
 	col     *mgo.Collection
@@ -31,7 +34,7 @@ type FeedItemFltr struct {
 
 func (s *FeedManager) Init() error {
 	logrus.Infof("Initialize feed indexes")
-	for _, index := range []string{"target", "project", "updated", "type"} {
+	for _, index := range []string{"target", "project", "updated", "type", "scanid"} {
 		err := s.col.EnsureIndex(mgo.Index{
 			Key:        []string{index},
 			Background: true,
@@ -170,3 +173,69 @@ func (m *FeedManager) UpdateScanReport(sc *scan.Scan, rep *report.Report) error
 	}
 	return m.col.Update(query, update)
 }
+
+// AddIssue puts the created issue to the feed. Issues are collapsed with the count into the item
+// which was updated within the window: issues of a scan into the item of the scan,
+// other issues of the target into the item of the previous issue created by the same user,
+// so big scans don't flood the feed. A new issue item is created if there is no such item.
+func (m *FeedManager) AddIssue(obj *issue.TargetIssue, scanId, owner bson.ObjectId) (*feed.FeedItem, error) {
+	now := time.Now().UTC()
+	query := bson.M{
+		"project": obj.Project,
+		"target":  obj.Target,
+		"updated": bson.M{"$gte": now.Add(-feedAggregateWindow)},
+	}
+	if scanId != "" {
+		query["scanid"] = scanId
+		query["type"] = bson.M{"$in": []feed.ItemType{feed.TypeScan, feed.TypeIssue}}
+	} else {
+		// empty ids can't be queried by value
+		query["scanid"] = bson.M{"$exists": false}
+		query["type"] = feed.TypeIssue
+		query["owner"] = owner
+		if owner == "" {
+			query["owner"] = bson.M{"$exists": false}
+		}
+	}
+	change := mgo.Change{
+		Update: bson.M{
+			"$set": bson.M{"updated": now},
+			"$inc": bson.M{"count": 1},
+			"$push": bson.M{"issues": bson.M{
+				"$each":  []bson.ObjectId{obj.Id},
+				"$slice": feed.MaxAggregated,
+			}},
+		},
+		ReturnNew: true,
+	}
+	item := &feed.FeedItem{}
+	_, err := m.col.Find(query).Sort("-updated").Apply(change, item)
+	if err == nil {
+		return item, nil
+	}
+	if err != mgo.ErrNotFound {
+		return nil, err
+	}
+	return m.Create(&feed.FeedItem{
+		Type:    feed.TypeIssue,
+		Project: obj.Project,
+		Target:  obj.Target,
+		Owner:   owner,
+		ScanId:  scanId,
+		Count:   1,
+		Issues:  []bson.ObjectId{obj.Id},
+	})
+}
+
+// AddMove records that the target address is rewritten by the host move, issues is the count of rewritten issues
+func (m *FeedManager) AddMove(t *target.Target, from, to string, issues int, owner bson.ObjectId) (*feed.FeedItem, error) {
+	return m.Create(&feed.FeedItem{
+		Type:    feed.TypeMove,
+		Project: t.Project,
+		Target:  t.Id,
+		Owner:   owner,
+		From:    from,
+		To:      to,
+		Count:   issues,
+	})
+}
@@ -0,0 +1,58 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/feed"
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/pkg/tests"
+)
+
+func TestFeedAddIssue(t *testing.T) {
+	mongo, dbName, err := tests.RandomTestMongoUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tests.RandomTestMongoDown(mongo, dbName)
+
+	mgr := New(mongo.DB(dbName))
+	projectId, targetId := bson.NewObjectId(), bson.NewObjectId()
+	scanId, ownerId := bson.NewObjectId(), bson.NewObjectId()
+	newIssue := func() *issue.TargetIssue {
+		return &issue.TargetIssue{Id: bson.NewObjectId(), Project: projectId, Target: targetId}
+	}
+
+	// issues of a scan are collapsed into the scan item
+	scanItem, err := mgr.Feed.AddScan(&scan.Scan{Id: scanId, Project: projectId, Target: targetId, Owner: ownerId, Plan: bson.NewObjectId()})
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		item, err := mgr.Feed.AddIssue(newIssue(), scanId, ownerId)
+		require.NoError(t, err)
+		assert.Equal(t, scanItem.Id, item.Id)
+	}
+	// issues without scan aren't collapsed with the scan ones
+	manual, err := mgr.Feed.AddIssue(newIssue(), "", ownerId)
+	require.NoError(t, err)
+	assert.NotEqual(t, scanItem.Id, manual.Id)
+	assert.Equal(t, feed.TypeIssue, manual.Type)
+	item, err := mgr.Feed.AddIssue(newIssue(), "", ownerId)
+	require.NoError(t, err)
+	assert.Equal(t, manual.Id, item.Id)
+	// issues of another user are shown separately
+	other, err := mgr.Feed.AddIssue(newIssue(), "", "")
+	require.NoError(t, err)
+	assert.NotEqual(t, manual.Id, other.Id)
+
+	_, count, err := mgr.Feed.FilterByQuery(bson.M{"type": feed.TypeIssue})
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	scanItem, err = mgr.Feed.GetById(scanItem.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 4, scanItem.Count)
+	assert.Len(t, scanItem.Issues, 4)
+}
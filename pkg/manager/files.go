@@ -1,12 +1,15 @@
 package manager
 
 import (
+	"bytes"
 	"io"
 
+	"github.com/Sirupsen/logrus"
 	"github.com/facebookgo/stackerr"
 	"gopkg.in/mgo.v2"
 
 	"github.com/bearded-web/bearded/models/file"
+	"github.com/bearded-web/bearded/pkg/thumbnail"
 )
 
 type FileManager struct {
@@ -15,14 +18,21 @@ type FileManager struct {
 }
 
 func (m *FileManager) Init() error {
-	return nil
+	logrus.Infof("Initialize file indexes")
+	// project storage usage is summed by this field
+	return m.grid.Files.EnsureIndex(mgo.Index{
+		Key:        []string{"metadata.project"},
+		Sparse:     true,
+		Background: false,
+	})
 }
 
 // Get file by id, don't forget to close file after
 func (m *FileManager) GetById(id string) (*file.File, error) {
 	f, err := m.grid.OpenId(id)
 	if err != nil {
-		return nil, stackerr.Wrap(err)
+		// not found errors are returned as is, so callers could check them with IsNotFound
+		return nil, err
 	}
 	meta := &file.Meta{}
 	if err = f.GetMeta(meta); err != nil {
@@ -32,15 +42,45 @@ func (m *FileManager) GetById(id string) (*file.File, error) {
 	return &file.File{Meta: meta, ReadCloser: f}, nil
 }
 
-// create file with data
+// Get thumbnail of the file with exact size, don't forget to close file after
+func (m *FileManager) GetThumbnail(id string, size int) (*file.File, error) {
+	return m.GetById(file.ThumbnailId(id, size))
+}
+
+// Remove deletes the file with its thumbnails
+func (m *FileManager) Remove(meta *file.Meta) error {
+	if err := m.grid.RemoveId(meta.Id); err != nil {
+		return err
+	}
+	for _, size := range meta.Thumbnails {
+		if err := m.grid.RemoveId(file.ThumbnailId(meta.Id, size)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// create file with data, thumbnails are generated for images.
+// QuotaError of the reader from StorageLimit is returned as is.
 func (m *FileManager) Create(r io.Reader, metaInfo *file.Meta) (*file.Meta, error) {
 	f, err := m.grid.Create("")
 	// according to gridfs code, the error here is impossible
 	if err != nil {
 		return nil, stackerr.Wrap(err)
 	}
+	var img *limitedBuffer
+	if len(m.manager.Cfg.ThumbnailSizes) > 0 && thumbnail.IsImage(metaInfo.ContentType) {
+		img = &limitedBuffer{max: thumbnail.MaxFileSize}
+		r = io.TeeReader(r, img)
+	}
 	size, err := io.Copy(f, r)
 	if err != nil {
+		// written chunks are removed on close
+		f.Abort()
+		f.Close()
+		if IsQuota(err) {
+			return nil, err
+		}
 		return nil, stackerr.Wrap(err)
 	}
 	meta := &file.Meta{
@@ -48,6 +88,10 @@ func (m *FileManager) Create(r io.Reader, metaInfo *file.Meta) (*file.Meta, erro
 		Size:        int(size),
 		ContentType: metaInfo.ContentType,
 		Name:        metaInfo.Name,
+		Project:     metaInfo.Project,
+	}
+	if img != nil && !img.overflow {
+		meta.Thumbnails = m.createThumbnails(meta, img.Bytes())
 	}
 	f.SetId(meta.Id)
 	f.SetMeta(meta)
@@ -60,3 +104,68 @@ func (m *FileManager) Create(r io.Reader, metaInfo *file.Meta) (*file.Meta, erro
 	meta.MD5 = f.MD5()
 	return meta, nil
 }
+
+// create thumbnails for all configured sizes, returns successfully created sizes.
+// Broken images shouldn't break the upload, so errors are only logged.
+func (m *FileManager) createThumbnails(meta *file.Meta, data []byte) []int {
+	sizes := []int{}
+	for _, size := range m.manager.Cfg.ThumbnailSizes {
+		buf := &bytes.Buffer{}
+		contentType, err := thumbnail.Make(buf, bytes.NewReader(data), size)
+		if err != nil {
+			logrus.Warnf("Can't make thumbnail for file %s: %s", meta.Id, err)
+			return sizes
+		}
+		thumb := &file.Meta{
+			Id:          file.ThumbnailId(meta.Id, size),
+			Name:        meta.Name,
+			ContentType: contentType,
+			Project:     meta.Project,
+		}
+		if err := m.write(buf, thumb); err != nil {
+			logrus.Error(err)
+			continue
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes
+}
+
+// limitedBuffer keeps written data up to max bytes, bigger data is dropped
+// without errors, so the upload itself isn't broken
+type limitedBuffer struct {
+	bytes.Buffer
+	max      int
+	overflow bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.overflow {
+		return len(p), nil
+	}
+	if b.Len()+len(p) > b.max {
+		b.overflow = true
+		b.Buffer = bytes.Buffer{}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
+
+func (m *FileManager) write(r io.Reader, meta *file.Meta) error {
+	f, err := m.grid.Create("")
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	size, err := io.Copy(f, r)
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	meta.Size = int(size)
+	f.SetId(meta.Id)
+	f.SetMeta(meta)
+	f.SetContentType(meta.ContentType)
+	if err = f.Close(); err != nil {
+		return stackerr.Wrap(err)
+	}
+	return nil
+}
@@ -6,9 +6,11 @@ import (
 	"bytes"
 
 	"github.com/bearded-web/bearded/models/file"
+	"github.com/bearded-web/bearded/models/project"
 	"github.com/bearded-web/bearded/pkg/tests"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
 )
 
 func TestFileManager(t *testing.T) {
@@ -40,3 +42,52 @@ func TestFileManager(t *testing.T) {
 	_, err = mgr.Files.GetById("bad id")
 	require.Error(t, err)
 }
+
+func TestStorageLimit(t *testing.T) {
+	mongo, dbName, err := tests.RandomTestMongoUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tests.RandomTestMongoDown(mongo, dbName)
+
+	mgr := New(mongo.DB(dbName))
+	p := &project.Project{Id: bson.NewObjectId(), Quota: &project.Quota{Storage: 1}}
+
+	upload := func(size int) error {
+		limited, err := mgr.Files.StorageLimit(p, bytes.NewReader(make([]byte, size)))
+		if err != nil {
+			return err
+		}
+		_, err = mgr.Files.Create(limited, &file.Meta{Name: "artifact", Project: p.Id})
+		return err
+	}
+
+	require.NoError(t, upload(1<<20-10))
+	// the upload bigger than the remaining storage is removed
+	err = upload(20)
+	require.Error(t, err)
+	assert.True(t, IsQuota(err))
+	size, err := mgr.Files.ProjectSize(p.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 1<<20-10, size)
+
+	require.NoError(t, upload(10))
+	err = upload(1)
+	require.Error(t, err)
+	assert.True(t, IsQuota(err))
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	buf := &limitedBuffer{max: 4}
+	n, err := buf.Write([]byte("dat"))
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	assert.False(t, buf.overflow)
+
+	// bigger data is dropped without errors
+	n, err = buf.Write([]byte("ta"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.True(t, buf.overflow)
+	assert.Equal(t, 0, buf.Len())
+}
@@ -0,0 +1,84 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/inbox"
+	"github.com/bearded-web/bearded/models/notification"
+	"github.com/bearded-web/bearded/pkg/fltr"
+)
+
+type InboxManager struct {
+	manager *Manager
+	col     *mgo.Collection
+}
+
+type InboxFltr struct {
+	User    bson.ObjectId      `fltr:"user"`
+	Read    *bool              `fltr:"read"`
+	Event   notification.Event `fltr:"event,in"`
+	Created time.Time          `fltr:"created,gte,gt,lte,lt"`
+}
+
+func (s *InboxManager) Init() error {
+	logrus.Infof("Initialize inbox indexes")
+	err := s.col.EnsureIndex(mgo.Index{
+		Key:        []string{"user", "read", "-created"},
+		Background: true,
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (m *InboxManager) Fltr() *InboxFltr {
+	return &InboxFltr{}
+}
+
+func (m *InboxManager) GetById(id bson.ObjectId) (*inbox.Item, error) {
+	u := &inbox.Item{}
+	return u, m.manager.GetById(m.col, id, &u)
+}
+
+func (m *InboxManager) FilterBy(f *InboxFltr, opts ...Opts) ([]*inbox.Item, int, error) {
+	query := fltr.GetQuery(f)
+	return m.FilterByQuery(query, opts...)
+}
+
+func (m *InboxManager) FilterByQuery(query bson.M, opts ...Opts) ([]*inbox.Item, int, error) {
+	results := []*inbox.Item{}
+	count, err := m.manager.FilterBy(m.col, &query, &results, opts...)
+	return results, count, err
+}
+
+func (m *InboxManager) Create(raw *inbox.Item) (*inbox.Item, error) {
+	raw.Id = bson.NewObjectId()
+	raw.Created = time.Now().UTC()
+	if err := m.col.Insert(raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// MarkRead marks the user item as read
+func (m *InboxManager) MarkRead(userId, id bson.ObjectId) error {
+	return m.col.Update(bson.M{"_id": id, "user": userId}, bson.M{"$set": bson.M{"read": true}})
+}
+
+// MarkAllRead marks all user items as read and returns the number of changed items
+func (m *InboxManager) MarkAllRead(userId bson.ObjectId) (int, error) {
+	info, err := m.col.UpdateAll(bson.M{"user": userId, "read": false}, bson.M{"$set": bson.M{"read": true}})
+	if info != nil {
+		return info.Updated, err
+	}
+	return 0, err
+}
+
+func (m *InboxManager) UnreadCount(userId bson.ObjectId) (int, error) {
+	return m.col.Find(bson.M{"user": userId, "read": false}).Count()
+}
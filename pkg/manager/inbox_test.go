@@ -0,0 +1,50 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/inbox"
+	"github.com/bearded-web/bearded/models/notification"
+	"github.com/bearded-web/bearded/pkg/tests"
+)
+
+func TestInboxManager(t *testing.T) {
+	mongo, dbName, err := tests.RandomTestMongoUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tests.RandomTestMongoDown(mongo, dbName)
+
+	mgr := New(mongo.DB(dbName))
+	userId, otherId := bson.NewObjectId(), bson.NewObjectId()
+
+	first, err := mgr.Inbox.Create(&inbox.Item{User: userId, Event: notification.EventScanFailed, Subject: "1"})
+	require.NoError(t, err)
+	_, err = mgr.Inbox.Create(&inbox.Item{User: userId, Event: notification.EventIssueAssigned, Subject: "2"})
+	require.NoError(t, err)
+	_, err = mgr.Inbox.Create(&inbox.Item{User: otherId, Event: notification.EventIssueAssigned, Subject: "3"})
+	require.NoError(t, err)
+
+	count, err := mgr.Inbox.UnreadCount(userId)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	// other users can't mark items
+	assert.Error(t, mgr.Inbox.MarkRead(otherId, first.Id))
+	require.NoError(t, mgr.Inbox.MarkRead(userId, first.Id))
+	count, err = mgr.Inbox.UnreadCount(userId)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	updated, err := mgr.Inbox.MarkAllRead(userId)
+	require.NoError(t, err)
+	assert.Equal(t, 1, updated)
+
+	count, err = mgr.Inbox.UnreadCount(otherId)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
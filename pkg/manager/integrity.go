@@ -0,0 +1,254 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/comment"
+	"github.com/bearded-web/bearded/models/integrity"
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/models/target"
+)
+
+// fields of all checked objects, only references are selected
+type refRow struct {
+	Id      bson.ObjectId `bson:"_id"`
+	Target  bson.ObjectId `bson:"target"`
+	Project bson.ObjectId `bson:"project"`
+	Link    bson.ObjectId `bson:"link"`
+	Scan    bson.ObjectId `bson:"scan"`
+	Session bson.ObjectId `bson:"scanSession"`
+}
+
+// refSets keeps ids of existing objects, every set is loaded once on demand
+type refSets struct {
+	m        *Manager
+	targets  map[bson.ObjectId]bool
+	issues   map[bson.ObjectId]bool
+	scans    map[bson.ObjectId]bool
+	sessions map[bson.ObjectId]bool
+}
+
+func (r *refSets) ids(col *mgo.Collection) (map[bson.ObjectId]bool, error) {
+	set := map[bson.ObjectId]bool{}
+	row := refRow{}
+	iter := col.Find(nil).Select(bson.M{"_id": 1}).Iter()
+	for iter.Next(&row) {
+		set[row.Id] = true
+	}
+	return set, iter.Close()
+}
+
+func (r *refSets) Targets() (map[bson.ObjectId]bool, error) {
+	var err error
+	if r.targets == nil {
+		r.targets, err = r.ids(r.m.Targets.col)
+	}
+	return r.targets, err
+}
+
+func (r *refSets) Issues() (map[bson.ObjectId]bool, error) {
+	var err error
+	if r.issues == nil {
+		r.issues, err = r.ids(r.m.Issues.col)
+	}
+	return r.issues, err
+}
+
+// Sessions loads ids of scans and all their sessions
+func (r *refSets) Sessions() (map[bson.ObjectId]bool, map[bson.ObjectId]bool, error) {
+	if r.scans != nil {
+		return r.scans, r.sessions, nil
+	}
+	scans, sessions := map[bson.ObjectId]bool{}, map[bson.ObjectId]bool{}
+	obj := &scan.Scan{}
+	iter := r.m.Scans.col.Find(nil).Select(bson.M{"_id": 1, "sessions": 1}).Iter()
+	for iter.Next(obj) {
+		scans[obj.Id] = true
+		for _, sess := range obj.GetAllSessions() {
+			sessions[sess.Id] = true
+		}
+		obj = &scan.Scan{}
+	}
+	if err := iter.Close(); err != nil {
+		return nil, nil, err
+	}
+	r.scans, r.sessions = scans, sessions
+	return scans, sessions, nil
+}
+
+// Has reports whether the object referenced by the problem of the kind exists
+func (r *refSets) Has(kind integrity.Kind, ref bson.ObjectId) (bool, error) {
+	var (
+		exists map[bson.ObjectId]bool
+		err    error
+	)
+	switch kind {
+	case integrity.KindCommentIssue:
+		exists, err = r.Issues()
+	case integrity.KindReportSession:
+		scans, sessions, err := r.Sessions()
+		return scans[ref] || sessions[ref], err
+	default:
+		exists, err = r.Targets()
+	}
+	return exists[ref], err
+}
+
+// Integrity finds objects with dangling references, the report keeps at most limit problems of every kind
+func (m *Manager) Integrity(limit int) (*integrity.Report, error) {
+	report := integrity.NewReport(time.Now().UTC())
+	refs := &refSets{m: m}
+	for _, kind := range integrity.Kinds() {
+		err := m.danglingRefs(refs, kind, func(p *integrity.Problem) {
+			report.Add(p, limit)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// Repair deletes all objects with dangling references of the kind or reattaches them to the target,
+// target is used only for reattach action
+func (m *Manager) Repair(kind integrity.Kind, action integrity.Action, t *target.Target) (*integrity.Report, error) {
+	if action == integrity.ActionReattach && (!kind.CanReattach() || t == nil) {
+		return nil, fmt.Errorf("%s couldn't be reattached", kind)
+	}
+	report := integrity.NewReport(time.Now().UTC())
+	problems := []*integrity.Problem{}
+	err := m.danglingRefs(&refSets{m: m}, kind, func(p *integrity.Problem) {
+		problems = append(problems, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// objects created during the search could reference objects which weren't loaded,
+	// so problems are checked again with sets loaded after the search
+	fresh := &refSets{m: m}
+	ids := []bson.ObjectId{}
+	for _, p := range problems {
+		ok, err := fresh.Has(kind, p.Ref)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			continue
+		}
+		report.Add(p, 0)
+		ids = append(ids, p.Id)
+	}
+	col := m.integrityCol(kind)
+	defer m.Cfg.Counts.Invalidate(col.FullName)
+	for len(ids) > 0 {
+		batch := ids
+		if len(batch) > cascadeBatch {
+			batch = ids[:cascadeBatch]
+		}
+		ids = ids[len(batch):]
+		query := bson.M{"_id": bson.M{"$in": batch}}
+
+		if action == integrity.ActionReattach {
+			info, err := col.UpdateAll(query, bson.M{"$set": bson.M{"target": t.Id, "project": t.Project}})
+			if err != nil {
+				return nil, err
+			}
+			report.Repaired += info.Updated
+			continue
+		}
+		if kind == integrity.KindIssueTarget {
+			removed, err := m.Cascades.removeIssueIds(batch)
+			if err != nil {
+				return nil, err
+			}
+			report.Repaired += removed
+			continue
+		}
+		info, err := col.RemoveAll(query)
+		if err != nil {
+			return nil, err
+		}
+		report.Repaired += info.Removed
+	}
+	if action == integrity.ActionReattach && kind == integrity.KindIssueTarget && report.Repaired > 0 {
+		if err := m.Summaries.Rebuild(t.Id); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+func (m *Manager) integrityCol(kind integrity.Kind) *mgo.Collection {
+	switch kind {
+	case integrity.KindIssueTarget:
+		return m.Issues.col
+	case integrity.KindScanTarget:
+		return m.Scans.col
+	case integrity.KindCommentIssue, integrity.KindCommentTarget:
+		return m.Comments.col
+	}
+	return m.Reports.col
+}
+
+// danglingRefs calls fn for every object of the kind which references a missing object
+func (m *Manager) danglingRefs(refs *refSets, kind integrity.Kind, fn func(*integrity.Problem)) error {
+	var (
+		query  bson.M
+		exists map[bson.ObjectId]bool
+		err    error
+	)
+	switch kind {
+	case integrity.KindIssueTarget, integrity.KindScanTarget:
+		exists, err = refs.Targets()
+	case integrity.KindCommentIssue:
+		query = bson.M{"type": comment.Issue}
+		exists, err = refs.Issues()
+	case integrity.KindCommentTarget:
+		query = bson.M{"type": comment.Scan}
+		exists, err = refs.Targets()
+	case integrity.KindReportSession:
+		return m.danglingSessions(refs, fn)
+	default:
+		return fmt.Errorf("unknown kind %s", kind)
+	}
+	if err != nil {
+		return err
+	}
+	row := refRow{}
+	iter := m.integrityCol(kind).Find(query).Select(bson.M{"_id": 1, "target": 1, "project": 1, "link": 1}).Iter()
+	for iter.Next(&row) {
+		ref := row.Target
+		if kind == integrity.KindCommentIssue || kind == integrity.KindCommentTarget {
+			ref = row.Link
+		}
+		if !exists[ref] {
+			fn(&integrity.Problem{Kind: kind, Id: row.Id, Ref: ref, Project: row.Project})
+		}
+		row = refRow{}
+	}
+	return iter.Close()
+}
+
+// danglingSessions finds reports of missing scans or sessions, reports without them aren't checked
+func (m *Manager) danglingSessions(refs *refSets, fn func(*integrity.Problem)) error {
+	scans, sessions, err := refs.Sessions()
+	if err != nil {
+		return err
+	}
+	row := refRow{}
+	iter := m.Reports.col.Find(nil).Select(bson.M{"_id": 1, "scan": 1, "scanSession": 1}).Iter()
+	for iter.Next(&row) {
+		switch {
+		case row.Scan != "" && !scans[row.Scan]:
+			fn(&integrity.Problem{Kind: integrity.KindReportSession, Id: row.Id, Ref: row.Scan})
+		case row.Session != "" && !sessions[row.Session]:
+			fn(&integrity.Problem{Kind: integrity.KindReportSession, Id: row.Id, Ref: row.Session})
+		}
+		row = refRow{}
+	}
+	return iter.Close()
+}
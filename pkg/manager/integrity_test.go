@@ -0,0 +1,55 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/comment"
+	"github.com/bearded-web/bearded/models/integrity"
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/pkg/tests"
+)
+
+func TestIntegrity(t *testing.T) {
+	mongo, dbName, err := tests.RandomTestMongoUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tests.RandomTestMongoDown(mongo, dbName)
+
+	mgr := New(mongo.DB(dbName))
+	projectId := bson.NewObjectId()
+	obj, err := mgr.Targets.Create(&target.Target{Project: projectId, Type: target.TypeWeb})
+	require.NoError(t, err)
+	_, err = mgr.Issues.Create(&issue.TargetIssue{Project: projectId, Target: obj.Id})
+	require.NoError(t, err)
+	missing := bson.NewObjectId()
+	for i := 0; i < 2; i++ {
+		_, err = mgr.Issues.Create(&issue.TargetIssue{Project: projectId, Target: missing})
+		require.NoError(t, err)
+	}
+	_, err = mgr.Comments.Create(&comment.Comment{Owner: bson.NewObjectId(), Type: comment.Issue, Link: bson.NewObjectId()})
+	require.NoError(t, err)
+
+	report, err := mgr.Integrity(1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Counts[integrity.KindIssueTarget])
+	assert.Equal(t, 1, report.Counts[integrity.KindCommentIssue])
+	assert.Equal(t, 0, report.Counts[integrity.KindScanTarget])
+	assert.Len(t, report.Problems, 2)
+
+	report, err = mgr.Repair(integrity.KindIssueTarget, integrity.ActionReattach, obj)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Repaired)
+	report, err = mgr.Repair(integrity.KindCommentIssue, integrity.ActionDelete, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Repaired)
+
+	report, err = mgr.Integrity(1)
+	require.NoError(t, err)
+	assert.Equal(t, 0, report.Total())
+}
@@ -3,33 +3,59 @@ package manager
 // TargetIssues manager
 
 import (
+	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/Sirupsen/logrus"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 
+	"github.com/bearded-web/bearded/models/exploit"
 	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/models/tombstone"
 	"github.com/bearded-web/bearded/pkg/fltr"
+	"github.com/bearded-web/bearded/pkg/siem"
 )
 
+// how many times an issue is saved again if its votes are changed concurrently
+const replaceRetries = 3
+
+// IssueSorter parses sort of issue lists, it's used for the project issue sort too
+var IssueSorter = fltr.NewSorter("created", "updated", "risk", "target", "lastActivity", "votes").
+	Alias("severity", "severityRank").
+	Alias("status", "statusRank")
+
 type IssueManager struct {
 	manager *Manager
 	col     *mgo.Collection
+	trash   *mgo.Collection // removed issues which could be restored
 }
 
 type IssueFltr struct {
-	Updated    time.Time      `fltr:"updated,gte,gt,lte,lt"`
-	VulnType   int            `fltr:"vulnType"`
-	Created    time.Time      `fltr:"created,gte,gt,lte,lt"`
-	ResolvedAt time.Time      `fltr:"resolvedAt,gte,gt,lte,lt,in"`
-	Target     bson.ObjectId  `fltr:"target,in"`
-	Project    bson.ObjectId  `fltr:"project"`
-	Confirmed  *bool          `fltr:"confirmed"`
-	Muted      *bool          `fltr:"muted"`
-	Resolved   *bool          `fltr:"resolved"`
-	False      *bool          `fltr:"false"`
-	Severity   issue.Severity `fltr:"severity,in"`
+	Updated    time.Time        `fltr:"updated,gte,gt,lte,lt"`
+	VulnType   int              `fltr:"vulnType"`
+	Created    time.Time        `fltr:"created,gte,gt,lte,lt"`
+	ResolvedAt time.Time        `fltr:"resolvedAt,gte,gt,lte,lt,in"`
+	Target     bson.ObjectId    `fltr:"target,in"`
+	Project    bson.ObjectId    `fltr:"project"`
+	Confirmed  *bool            `fltr:"confirmed"`
+	Muted      *bool            `fltr:"muted"`
+	Resolved   *bool            `fltr:"resolved"`
+	False      *bool            `fltr:"false"`
+	Severity   issue.Severity   `fltr:"severity,in"`
+	Confidence issue.Confidence `fltr:"confidence,in"`
+	Risk       int              `fltr:"risk,gte,gt,lte,lt"`
+	Assignee   bson.ObjectId    `fltr:"assignee"`
+	Team       bson.ObjectId    `fltr:"team,in"`
+	Labels     string           `fltr:"labels,in"`
+	Operation  string           `fltr:"operation"`
+	// issues with known exploits of their cves
+	Exploitable *bool `fltr:"exploitable"`
+	// environment of the issue target
+	Environment target.Environment `fltr:"environment,in"`
 }
 
 func (s *IssueManager) Init() error {
@@ -43,8 +69,12 @@ func (s *IssueManager) Init() error {
 		return err
 	}
 
+	if err := s.initTrash(); err != nil {
+		return err
+	}
+
 	// TODO (m0sth8): check what indexes are really used
-	for _, index := range []string{"created", "updated", "target", "project", "resolvedAt"} {
+	for _, index := range []string{"created", "updated", "target", "project", "resolvedAt", "risk", "assignee", "labels", "operation", "links.issue", "pendingScan", "reopened", "environment", "team", "confidence", "votes", "exploitable"} {
 		err := s.col.EnsureIndex(mgo.Index{
 			Key:        []string{index},
 			Background: true,
@@ -53,6 +83,10 @@ func (s *IssueManager) Init() error {
 			return err
 		}
 	}
+	// TODO (m0sth8): exclude to migration
+	if err := s.updateRanks(); err != nil {
+		return err
+	}
 	if s.manager.Cfg.TextSearchEnable {
 		logrus.Infof("Create text indexes for issue")
 		err := s.col.EnsureIndex(mgo.Index{
@@ -69,18 +103,76 @@ func (s *IssueManager) Init() error {
 	return nil
 }
 
+// updateRanks refreshes sort fields of issues which were saved before the fields were introduced
+// or changed by $set updates without UpdateRanks
+func (m *IssueManager) updateRanks() error {
+	defer m.invalidate()
+	for _, sev := range []issue.Severity{issue.SeverityInfo, issue.SeverityLow, issue.SeverityMedium, issue.SeverityHigh} {
+		_, err := m.col.UpdateAll(
+			bson.M{"severity": sev, "severityRank": bson.M{"$ne": sev.Rank()}},
+			bson.M{"$set": bson.M{"severityRank": sev.Rank()}})
+		if err != nil {
+			return err
+		}
+	}
+	// the order is important, the first matched status wins
+	prev := []string{}
+	for _, field := range []string{"resolved", "false", "muted", "confirmed", ""} {
+		st := issue.Status{}
+		switch field {
+		case "resolved":
+			st.Resolved = true
+		case "false":
+			st.False = true
+		case "muted":
+			st.Muted = true
+		case "confirmed":
+			st.Confirmed = true
+		}
+		query := bson.M{"statusRank": bson.M{"$ne": st.Rank()}}
+		for _, p := range prev {
+			query[p] = bson.M{"$ne": true}
+		}
+		if field != "" {
+			query[field] = true
+			prev = append(prev, field)
+		}
+		_, err := m.col.UpdateAll(query, bson.M{"$set": bson.M{"statusRank": st.Rank()}})
+		if err != nil {
+			return err
+		}
+	}
+
+	iter := m.col.Find(bson.M{"lastActivity": bson.M{"$exists": false}}).
+		Select(bson.M{"created": 1, "activities": 1}).Iter()
+	obj := &issue.TargetIssue{}
+	for iter.Next(obj) {
+		obj.UpdateRanks()
+		if err := m.col.UpdateId(obj.Id, bson.M{"$set": bson.M{"lastActivity": obj.LastActivity}}); err != nil {
+			iter.Close()
+			return err
+		}
+		obj = &issue.TargetIssue{}
+	}
+	return iter.Close()
+}
+
 func (m *IssueManager) Fltr() *IssueFltr {
 	return &IssueFltr{}
 }
 
 func (m *IssueManager) GetById(id bson.ObjectId) (*issue.TargetIssue, error) {
 	u := &issue.TargetIssue{}
-	return u, m.manager.GetById(m.col, id, &u)
+	err := m.manager.GetById(m.col, id, &u)
+	m.sanitize(u)
+	return u, err
 }
 
 func (m *IssueManager) GetByUniqId(target bson.ObjectId, uniqId string) (*issue.TargetIssue, error) {
 	u := &issue.TargetIssue{}
-	return u, m.manager.GetBy(m.col, &bson.M{"target": target, "uniqId": uniqId}, &u)
+	err := m.manager.GetBy(m.col, &bson.M{"target": target, "uniqId": uniqId}, &u)
+	m.sanitize(u)
+	return u, err
 }
 
 func (m *IssueManager) FilterBy(f *IssueFltr, opts ...Opts) ([]*issue.TargetIssue, int, error) {
@@ -91,9 +183,160 @@ func (m *IssueManager) FilterBy(f *IssueFltr, opts ...Opts) ([]*issue.TargetIssu
 func (m *IssueManager) FilterByQuery(query bson.M, opts ...Opts) ([]*issue.TargetIssue, int, error) {
 	results := []*issue.TargetIssue{}
 	count, err := m.manager.FilterBy(m.col, &query, &results, opts...)
+	for _, obj := range results {
+		m.sanitize(obj)
+	}
 	return results, count, err
 }
 
+// FlappingQuery returns the query part for issues which are flapping or not
+func FlappingQuery(flapping bool) bson.M {
+	if flapping {
+		return bson.M{"$gte": issue.FlappingReopens}
+	}
+	return bson.M{"$not": bson.M{"$gte": issue.FlappingReopens}}
+}
+
+// Blocking returns the most risky open issues matched by the gate and their count, target is optional
+func (m *IssueManager) Blocking(p *project.Project, gate *project.Gate, tgt bson.ObjectId, limit int) ([]*issue.TargetIssue, int, error) {
+	query := bson.M{
+		"project":      p.Id,
+		"resolved":     false,
+		"false":        false,
+		"muted":        false,
+		"pendingScan":  bson.M{"$exists": false},
+		"severityRank": bson.M{"$gte": gate.MinSeverity().Rank()},
+	}
+	if tgt != "" {
+		query["target"] = tgt
+	}
+	if len(gate.Environments) > 0 {
+		query["environment"] = bson.M{"$in": gate.Environments}
+	}
+	if gate.Confidence != "" {
+		atLeast := bson.M{"confidence": bson.M{"$in": gate.Confidence.AtLeast()}}
+		// issues without confidence don't have the field
+		if issue.Confidence("").Rank() >= gate.Confidence.Rank() {
+			query["$or"] = []bson.M{atLeast, {"confidence": bson.M{"$exists": false}}}
+		} else {
+			query["confidence"] = atLeast["confidence"]
+		}
+	}
+	return m.FilterByQuery(query, Opts{Limit: limit, Sort: []string{"-risk"}, Count: CountExact})
+}
+
+// Flappiness counts reopenings of the project issues, only issues which were resolved at least once are taken
+func (m *IssueManager) Flappiness(project bson.ObjectId) (*issue.Flappiness, error) {
+	result := &issue.Flappiness{Project: project}
+	groups := []*issue.Flappiness{}
+	err := m.col.Pipe([]bson.M{
+		{"$match": bson.M{
+			"project": project,
+			"$or":     []bson.M{{"resolved": true}, {"reopened": bson.M{"$gt": 0}}},
+		}},
+		{"$group": bson.M{
+			"_id":      nil,
+			"issues":   bson.M{"$sum": 1},
+			"reopened": bson.M{"$sum": bson.M{"$cond": []interface{}{bson.M{"$gt": []interface{}{"$reopened", 0}}, 1, 0}}},
+			"flapping": bson.M{"$sum": bson.M{"$cond": []interface{}{bson.M{"$gte": []interface{}{"$reopened", issue.FlappingReopens}}, 1, 0}}},
+			"reopens":  bson.M{"$sum": "$reopened"},
+		}},
+	}).All(&groups)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) > 0 {
+		g := groups[0]
+		result.Issues, result.Reopened, result.Flapping, result.Reopens = g.Issues, g.Reopened, g.Flapping, g.Reopens
+		result.Rate = float64(g.Reopened) / float64(g.Issues)
+	}
+	return result, nil
+}
+
+// Aging counts open issues of the project by age buckets and severities
+func (m *IssueManager) Aging(project bson.ObjectId, now time.Time) (*issue.Aging, error) {
+	result := issue.NewAging(project, now)
+	// the bucket is the first one which start is before the issue creation
+	var bucket interface{} = len(issue.AgeLimits)
+	for i := len(issue.AgeLimits) - 1; i >= 0; i-- {
+		bucket = bson.M{"$cond": []interface{}{
+			bson.M{"$gt": []interface{}{"$created", issue.AgeBucketStart(now, i)}},
+			i,
+			bucket,
+		}}
+	}
+	groups := []struct {
+		Id struct {
+			Bucket   int            `bson:"bucket"`
+			Severity issue.Severity `bson:"severity"`
+		} `bson:"_id"`
+		Count int `bson:"count"`
+	}{}
+	err := m.col.Pipe([]bson.M{
+		{"$match": bson.M{
+			"project":     project,
+			"resolved":    false,
+			"false":       false,
+			"muted":       false,
+			"pendingScan": bson.M{"$exists": false},
+		}},
+		{"$group": bson.M{
+			"_id":   bson.M{"bucket": bucket, "severity": "$severity"},
+			"count": bson.M{"$sum": 1},
+		}},
+	}).All(&groups)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		result.Add(g.Id.Bucket, g.Id.Severity, g.Count)
+	}
+	return result, nil
+}
+
+// Mttr computes remediation times of the project issues resolved in the period [from, to)
+func (m *IssueManager) Mttr(project bson.ObjectId, from, to time.Time) (*issue.Mttr, error) {
+	result := issue.NewMttr(project, from, to)
+	groups := []struct {
+		Id struct {
+			Severity issue.Severity `bson:"severity"`
+			Assignee bson.ObjectId  `bson:"assignee"`
+			Year     int            `bson:"year"`
+			Month    int            `bson:"month"`
+		} `bson:"_id"`
+		Count int     `bson:"count"`
+		Hours float64 `bson:"hours"`
+	}{}
+	err := m.col.Pipe([]bson.M{
+		{"$match": bson.M{
+			"project":    project,
+			"resolved":   true,
+			"false":      false,
+			"resolvedAt": bson.M{"$gte": from, "$lt": to},
+		}},
+		{"$project": bson.M{
+			"severity": 1,
+			"assignee": 1,
+			"year":     bson.M{"$year": "$resolvedAt"},
+			"month":    bson.M{"$month": "$resolvedAt"},
+			"hours":    bson.M{"$divide": []interface{}{bson.M{"$subtract": []interface{}{"$resolvedAt", "$created"}}, int64(time.Hour / time.Millisecond)}},
+		}},
+		{"$group": bson.M{
+			"_id":   bson.M{"severity": "$severity", "assignee": "$assignee", "year": "$year", "month": "$month"},
+			"count": bson.M{"$sum": 1},
+			"hours": bson.M{"$sum": "$hours"},
+		}},
+	}).All(&groups)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		month := fmt.Sprintf("%04d-%02d", g.Id.Year, g.Id.Month)
+		result.Add(g.Id.Severity, g.Id.Assignee, month, g.Count, g.Hours)
+	}
+	return result, nil
+}
+
 func (m *IssueManager) Create(raw *issue.TargetIssue) (*issue.TargetIssue, error) {
 	// TODO (m0sth8): add validation
 	raw.Id = bson.NewObjectId()
@@ -105,23 +348,474 @@ func (m *IssueManager) Create(raw *issue.TargetIssue) (*issue.TargetIssue, error
 	if len(raw.UniqId) == 0 {
 		raw.UniqId = raw.Id.Hex()
 	}
+	raw.Fingerprint = issue.FingerprintVersion
+	m.manager.Vulndb.Enrich(&raw.Issue)
+	m.sanitize(raw)
+	raw.UpdateRanks()
+	if err := m.score(raw); err != nil {
+		return nil, err
+	}
 	if err := m.col.Insert(raw); err != nil {
 		return nil, err
 	}
+	m.invalidate()
+	m.touch(raw.Target)
+	if raw.Severity == issue.SeverityHigh {
+		m.manager.Cfg.Siem.Emit(siem.IssueEvent(raw))
+	}
 	return raw, nil
 }
 
 func (m *IssueManager) Update(obj *issue.TargetIssue) error {
 	obj.Updated = time.Now().UTC()
-	return m.col.UpdateId(obj.Id, obj)
+	m.sanitize(obj)
+	obj.UpdateRanks()
+	if err := m.score(obj); err != nil {
+		return err
+	}
+	err := m.replace(obj)
+	m.invalidate()
+	if err == nil {
+		m.touch(obj.Target)
+	}
+	return err
+}
+
+// replace saves the whole issue. Votes are changed by Vote without loading issues,
+// so the issue is replaced only if its voters weren't changed after it was loaded,
+// otherwise the current votes are taken and the issue is saved again.
+func (m *IssueManager) replace(obj *issue.TargetIssue) error {
+	for i := 0; ; i++ {
+		// voters are omitted when empty, null matches the missing field
+		var voters interface{}
+		if len(obj.Voters) > 0 {
+			voters = obj.Voters
+		}
+		err := m.col.Update(bson.M{"_id": obj.Id, "voters": voters, "votes": obj.Votes}, obj)
+		if err != mgo.ErrNotFound || i == replaceRetries {
+			return err
+		}
+		current := &issue.TargetIssue{}
+		if err := m.col.FindId(obj.Id).Select(bson.M{"voters": 1, "votes": 1}).One(current); err != nil {
+			return err
+		}
+		obj.Voters, obj.Votes = current.Voters, current.Votes
+	}
+}
+
+// UpdateRisk recalculates risk scores for all target issues, call it when target criticality is changed
+func (m *IssueManager) UpdateRisk(tgt *target.Target) error {
+	issues, _, err := m.FilterBy(&IssueFltr{Target: tgt.Id})
+	if err != nil {
+		return err
+	}
+	defer m.invalidate()
+	crit := criticality(tgt)
+	for _, obj := range issues {
+		score := m.manager.Cfg.Risk.Score(obj.Severity, crit, obj.Exposure, obj.Exploitable)
+		if score == obj.Risk && obj.Criticality == string(crit) {
+			continue
+		}
+		if err := m.col.UpdateId(obj.Id, bson.M{"$set": bson.M{"risk": score, "criticality": crit}}); err != nil {
+			return err
+		}
+	}
+	m.touch(tgt.Id)
+	return nil
+}
+
+// Escalate sets the escalation level of the issue if it isn't acknowledged or escalated since it was loaded,
+// other fields aren't written, so user edits made meanwhile are kept. Returns ErrNotFound if the issue is changed.
+func (m *IssueManager) Escalate(obj *issue.TargetIssue, level int) error {
+	var current interface{} = obj.EscalationLevel
+	if obj.EscalationLevel == 0 {
+		// issues created before escalation don't have the field
+		current = bson.M{"$in": []interface{}{0, nil}}
+	}
+	err := m.col.Update(bson.M{
+		"_id":             obj.Id,
+		"escalationLevel": current,
+		"acknowledged":    bson.M{"$exists": false},
+	}, bson.M{"$set": bson.M{"escalationLevel": level}})
+	if err != nil {
+		return err
+	}
+	obj.EscalationLevel = level
+	return nil
+}
+
+// UpdateEnvironment copies the target environment to its issues, call it when the environment is changed
+func (m *IssueManager) UpdateEnvironment(tgt *target.Target) error {
+	defer m.invalidate()
+	update := bson.M{"$set": bson.M{"environment": tgt.Environment}}
+	if tgt.Environment == "" {
+		update = bson.M{"$unset": bson.M{"environment": ""}}
+	}
+	_, err := m.col.UpdateAll(bson.M{"target": tgt.Id}, update)
+	return err
+}
+
+// RemoveField unsets the custom field in all project issues, call it when the field is removed from the project
+func (m *IssueManager) RemoveField(project bson.ObjectId, name string) error {
+	defer m.invalidate()
+	field := "fields." + name
+	_, err := m.col.UpdateAll(bson.M{"project": project, field: bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{field: ""}})
+	return err
+}
+
+// RemoveTeam unassigns issues of the removed project team
+func (m *IssueManager) RemoveTeam(project, team bson.ObjectId) error {
+	defer m.invalidate()
+	_, err := m.col.UpdateAll(bson.M{"project": project, "team": team},
+		bson.M{"$unset": bson.M{"team": ""}})
+	return err
+}
+
+// clean rich text fields, they are sanitized on write and again on read,
+// so issues saved before the policy was changed are cleaned too
+func (m *IssueManager) sanitize(obj *issue.TargetIssue) {
+	if obj == nil {
+		return
+	}
+	obj.Desc = m.manager.Cfg.Sanitizer.Sanitize(obj.Desc)
+	obj.Remediation = m.manager.Cfg.Sanitizer.Sanitize(obj.Remediation)
+}
+
+// RenderVars substitutes variables of targets and vectors in description and remediation of issues,
+// the issues mustn't be saved after that
+func (m *IssueManager) RenderVars(issues ...*issue.TargetIssue) error {
+	ids := []bson.ObjectId{}
+	seen := map[bson.ObjectId]bool{}
+	for _, obj := range issues {
+		if !seen[obj.Target] {
+			seen[obj.Target] = true
+			ids = append(ids, obj.Target)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	targets, _, err := m.manager.Targets.FilterByQuery(bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return err
+	}
+	vars := map[bson.ObjectId]issue.Vars{}
+	for _, tgt := range targets {
+		vars[tgt.Id] = tgt.IssueVars()
+	}
+	for _, obj := range issues {
+		obj.RenderVars(vars[obj.Target])
+	}
+	return nil
+}
+
+// drop cached counts, call it on every change
+func (m *IssueManager) invalidate() {
+	m.manager.Cfg.Counts.Invalidate(m.col.FullName)
+}
+
+// touch marks summaries of targets stale, call it on every change of counted issue fields.
+// Errors are only logged, summaries are rebuilt with the next change then.
+func (m *IssueManager) touch(targets ...bson.ObjectId) {
+	if err := m.manager.Summaries.Touch(targets...); err != nil {
+		logrus.Errorf("Couldn't mark summaries of targets %v stale: %s", targets, err)
+	}
+}
+
+// targets returns distinct targets of issues matched by the query
+func (m *IssueManager) targets(query bson.M) ([]bson.ObjectId, error) {
+	ids := []bson.ObjectId{}
+	return ids, m.col.Find(query).Distinct("target", &ids)
+}
+
+// set risk score based on target criticality and known exploits. Criticality and environment are copied
+// from the target when the issue is created, UpdateRisk and UpdateEnvironment keep them after target changes.
+func (m *IssueManager) score(obj *issue.TargetIssue) error {
+	known, err := m.manager.Exploits.Catalog(obj.Cve)
+	if err != nil {
+		return err
+	}
+	obj.Exploits = known.Refs(obj.Cve)
+	obj.Exploitable = len(obj.Exploits) > 0
+	// issues saved before criticality was copied don't have it
+	if obj.Criticality == "" && obj.Target != "" {
+		tgt, err := m.manager.Targets.GetById(obj.Target)
+		if err != nil && !m.manager.IsNotFound(err) {
+			return err
+		}
+		if err == nil {
+			obj.Criticality = string(criticality(tgt))
+			obj.Environment = string(tgt.Environment)
+		}
+	}
+	obj.Risk = m.manager.Cfg.Risk.Score(obj.Severity, target.Criticality(obj.Criticality), obj.Exposure, obj.Exploitable)
+	return nil
+}
+
+// criticality of the target for scoring, targets without criticality are medium
+func criticality(tgt *target.Target) target.Criticality {
+	if tgt.Criticality == "" {
+		return target.CriticalityMedium
+	}
+	return tgt.Criticality
+}
+
+// UpgradeUniqIds recomputes generated uniq ids of issues stored with older fingerprint versions,
+// so the next scans merge reports to them instead of creating duplicates.
+// If the issue is already duplicated, the old uniq id is kept. Returns the number of changed issues.
+func (m *IssueManager) UpgradeUniqIds() (int, error) {
+	query := bson.M{"$or": []bson.M{
+		{"fingerprint": bson.M{"$exists": false}},
+		{"fingerprint": bson.M{"$lt": issue.FingerprintVersion}},
+	}}
+	iter := m.col.Find(query).Iter()
+	obj := &issue.TargetIssue{}
+	n := 0
+	for iter.Next(obj) {
+		set := bson.M{"fingerprint": issue.FingerprintVersion}
+		if obj.UpgradeUniqId() {
+			_, err := m.GetByUniqId(obj.Target, obj.UniqId)
+			switch {
+			case m.manager.IsNotFound(err):
+				set["uniqId"] = obj.UniqId
+				n++
+			case err != nil:
+				iter.Close()
+				return n, err
+			}
+		}
+		if err := m.col.UpdateId(obj.Id, bson.M{"$set": set}); err != nil {
+			iter.Close()
+			return n, err
+		}
+		obj = &issue.TargetIssue{}
+	}
+	if n > 0 {
+		m.invalidate()
+	}
+	return n, iter.Close()
+}
+
+// Stream calls fn for every issue of the query in the order of sort without loading all of them.
+// It's stopped when fn returns an error or the manager context is done,
+// the query timeout isn't applied because streams of big projects are long.
+func (m *IssueManager) Stream(query bson.M, opt Opts, fn func(*issue.TargetIssue) error) error {
+	doc := func() interface{} { return &issue.TargetIssue{} }
+	return m.manager.stream(m.col, query, opt, doc, func(raw interface{}) error {
+		obj := raw.(*issue.TargetIssue)
+		m.sanitize(obj)
+		return fn(obj)
+	})
+}
+
+// UpdateExploits sets known exploits from the catalog to issues with cves and recalculates their risk,
+// returns the number of changed issues
+func (m *IssueManager) UpdateExploits(known exploit.Catalog) (int, error) {
+	defer m.invalidate()
+	crits := map[bson.ObjectId]target.Criticality{}
+	touched := map[bson.ObjectId]bool{}
+	query := bson.M{"$or": []bson.M{{"cve.0": bson.M{"$exists": true}}, {"exploitable": true}}}
+	iter := m.col.Find(query).Iter()
+	changed := 0
+	for {
+		obj := &issue.TargetIssue{}
+		if !iter.Next(obj) {
+			break
+		}
+		refs := known.Refs(obj.Cve)
+		if len(refs) == 0 && len(obj.Exploits) == 0 || reflect.DeepEqual(refs, obj.Exploits) {
+			continue
+		}
+		// issues saved before criticality was copied don't have it
+		crit := target.Criticality(obj.Criticality)
+		if crit == "" {
+			var ok bool
+			if crit, ok = crits[obj.Target]; !ok {
+				tgt, err := m.manager.Targets.GetById(obj.Target)
+				if err != nil && !m.manager.IsNotFound(err) {
+					iter.Close()
+					return changed, err
+				}
+				if err == nil {
+					crit = tgt.Criticality
+				}
+				crits[obj.Target] = crit
+			}
+		}
+		exploitable := len(refs) > 0
+		update := bson.M{
+			"exploitable": exploitable,
+			"risk":        m.manager.Cfg.Risk.Score(obj.Severity, crit, obj.Exposure, exploitable),
+		}
+		if exploitable {
+			update["exploits"] = refs
+		}
+		change := bson.M{"$set": update}
+		if !exploitable {
+			change["$unset"] = bson.M{"exploits": ""}
+		}
+		if err := m.col.UpdateId(obj.Id, change); err != nil {
+			iter.Close()
+			return changed, err
+		}
+		changed++
+		touched[obj.Target] = true
+	}
+	for id := range touched {
+		m.touch(id)
+	}
+	return changed, iter.Close()
+}
+
+// Vote adds or removes the user vote for the issue, returns false if the vote is already in that state.
+// Only votes are changed, so the updated time of the issue is kept.
+func (m *IssueManager) Vote(obj *issue.TargetIssue, userId bson.ObjectId, up bool) (bool, error) {
+	query := bson.M{"_id": obj.Id, "voters": bson.M{"$ne": userId}}
+	update := bson.M{"$addToSet": bson.M{"voters": userId}, "$inc": bson.M{"votes": 1}}
+	if !up {
+		query["voters"] = userId
+		update = bson.M{"$pull": bson.M{"voters": userId}, "$inc": bson.M{"votes": -1}}
+	}
+	if err := m.col.Update(query, update); err != nil {
+		if err == mgo.ErrNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
 }
 
 func (m *IssueManager) Remove(obj *issue.TargetIssue) error {
-	return m.col.RemoveId(obj.Id)
+	err := m.col.RemoveId(obj.Id)
+	m.invalidate()
+	if err == nil {
+		m.touch(obj.Target)
+		m.manager.Tombstones.Add(tombstone.Issue, obj.Id, obj.Project)
+		err = m.unlinkAll([]bson.ObjectId{obj.Id})
+	}
+	return err
+}
+
+// ReleasePending makes issues found by the reviewed scan counted in summaries
+func (m *IssueManager) ReleasePending(scanId bson.ObjectId) (int, error) {
+	query := bson.M{"pendingScan": scanId}
+	targets, err := m.targets(query)
+	if err != nil {
+		return 0, err
+	}
+	info, err := m.col.UpdateAll(query, bson.M{"$unset": bson.M{"pendingScan": ""}})
+	if err != nil {
+		return 0, err
+	}
+	m.invalidate()
+	m.touch(targets...)
+	return info.Updated, nil
+}
+
+// Link adds the link to the issue and the inverse link to the other issue
+func (m *IssueManager) Link(obj *issue.TargetIssue, typ issue.LinkType, other *issue.TargetIssue, user bson.ObjectId) (*issue.Link, error) {
+	now := time.Now().UTC()
+	link := &issue.Link{Type: typ, Issue: other.Id, Target: other.Target, User: user, Created: now}
+	back := &issue.Link{Type: typ.Inverse(), Issue: obj.Id, Target: obj.Target, User: user, Created: now}
+	if err := m.col.UpdateId(obj.Id, bson.M{"$push": bson.M{"links": link}, "$set": bson.M{"updated": now}}); err != nil {
+		return nil, err
+	}
+	if err := m.col.UpdateId(other.Id, bson.M{"$push": bson.M{"links": back}, "$set": bson.M{"updated": now}}); err != nil {
+		return nil, err
+	}
+	obj.Links = append(obj.Links, link)
+	obj.Updated = now
+	other.Links = append(other.Links, back)
+	other.Updated = now
+	return link, nil
+}
+
+// Unlink removes all links between the issue and the other one from both of them
+func (m *IssueManager) Unlink(obj *issue.TargetIssue, other bson.ObjectId) error {
+	now := time.Now().UTC()
+	if err := m.col.UpdateId(obj.Id, bson.M{"$pull": bson.M{"links": bson.M{"issue": other}}, "$set": bson.M{"updated": now}}); err != nil {
+		return err
+	}
+	err := m.col.UpdateId(other, bson.M{"$pull": bson.M{"links": bson.M{"issue": obj.Id}}, "$set": bson.M{"updated": now}})
+	if err != nil && err != mgo.ErrNotFound {
+		return err
+	}
+	links := []*issue.Link{}
+	for _, l := range obj.Links {
+		if l.Issue != other {
+			links = append(links, l)
+		}
+	}
+	obj.Links = links
+	obj.Updated = now
+	return nil
+}
+
+// remove links to removed issues
+func (m *IssueManager) unlinkAll(ids []bson.ObjectId) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	defer m.invalidate()
+	_, err := m.col.UpdateAll(bson.M{"links.issue": bson.M{"$in": ids}}, bson.M{
+		"$pull": bson.M{"links": bson.M{"issue": bson.M{"$in": ids}}},
+		"$set":  bson.M{"updated": time.Now().UTC()},
+	})
+	return err
+}
+
+// projects maps ids of issues matched by the query to their projects
+func (m *IssueManager) projects(query bson.M) (map[bson.ObjectId]bson.ObjectId, error) {
+	results := []struct {
+		Id      bson.ObjectId `bson:"_id"`
+		Project bson.ObjectId `bson:"project"`
+	}{}
+	if err := m.manager.all(m.col.Find(query).Select(bson.M{"_id": 1, "project": 1}), &results); err != nil {
+		return nil, err
+	}
+	projects := make(map[bson.ObjectId]bson.ObjectId, len(results))
+	for _, r := range results {
+		projects[r.Id] = r.Project
+	}
+	return projects, nil
+}
+
+// Ids returns ids of issues matched by the query
+func (m *IssueManager) Ids(query bson.M) ([]bson.ObjectId, error) {
+	results := []struct {
+		Id bson.ObjectId `bson:"_id"`
+	}{}
+	if err := m.manager.all(m.col.Find(query).Select(bson.M{"_id": 1}), &results); err != nil {
+		return nil, err
+	}
+	ids := make([]bson.ObjectId, len(results))
+	for i, r := range results {
+		ids[i] = r.Id
+	}
+	return ids, nil
 }
 
 func (m *IssueManager) RemoveAll(query bson.M) (int, error) {
+	projects, err := m.projects(query)
+	if err != nil {
+		return 0, err
+	}
+	targets, err := m.targets(query)
+	if err != nil {
+		return 0, err
+	}
 	info, err := m.col.RemoveAll(query)
+	m.invalidate()
+	m.touch(targets...)
+	if err == nil {
+		m.manager.Tombstones.AddAll(tombstone.Issue, projects)
+		ids := make([]bson.ObjectId, 0, len(projects))
+		for id := range projects {
+			ids = append(ids, id)
+		}
+		err = m.unlinkAll(ids)
+	}
 	if info != nil {
 		return info.Removed, err
 	}
@@ -0,0 +1,83 @@
+package manager
+
+// Locks manager
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/lock"
+	"github.com/bearded-web/bearded/pkg/utils"
+)
+
+// Instance identifies this process as an owner of locks
+var Instance = func() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), utils.RandomString(6))
+}()
+
+type LockManager struct {
+	manager *Manager
+	col     *mgo.Collection
+}
+
+func (m *LockManager) Init() error {
+	return nil
+}
+
+func (m *LockManager) GetByName(name string) (*lock.Lock, error) {
+	l := &lock.Lock{}
+	return l, m.manager.GetBy(m.col, &bson.M{"_id": name}, &l)
+}
+
+// Hold takes the lock or prolongs it for ttl if it's already held by this instance.
+// False is returned while another instance holds the lock and it isn't expired.
+func (m *LockManager) Hold(name string, ttl time.Duration) (bool, error) {
+	now := time.Now().UTC()
+	query := bson.M{
+		"_id": name,
+		"$or": []bson.M{
+			{"owner": Instance},
+			{"expires": bson.M{"$lt": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{
+		"owner":   Instance,
+		"expires": now.Add(ttl),
+		"updated": now,
+	}}
+	// the lock of another instance isn't matched, so upsert tries to insert the same _id
+	if _, err := m.col.Upsert(query, update); err != nil {
+		if m.manager.IsDup(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Release frees the lock if it's held by this instance, so others don't wait for expiration
+func (m *LockManager) Release(name string) error {
+	err := m.col.Remove(bson.M{"_id": name, "owner": Instance})
+	if m.manager.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// Lead returns true if this instance runs the periodic job now. The lock is held for two intervals,
+// so another instance takes the job only if this one misses a check. Errors are logged.
+func (m *LockManager) Lead(name string, interval time.Duration) bool {
+	held, err := m.Hold(name, 2*interval)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		return false
+	}
+	return held
+}
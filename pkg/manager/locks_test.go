@@ -0,0 +1,53 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bearded-web/bearded/pkg/tests"
+)
+
+func TestLocks(t *testing.T) {
+	mongo, dbName, err := tests.RandomTestMongoUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tests.RandomTestMongoDown(mongo, dbName)
+
+	mgr := New(mongo.DB(dbName))
+	self := Instance
+	defer func() { Instance = self }()
+
+	held, err := mgr.Locks.Hold("job", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+	// the owner prolongs the lock
+	held, err = mgr.Locks.Hold("job", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+
+	// another instance waits until the lock is expired
+	Instance = "other"
+	held, err = mgr.Locks.Hold("job", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, held)
+	held, err = mgr.Locks.Hold("expired", -time.Second)
+	require.NoError(t, err)
+	assert.True(t, held)
+	held, err = mgr.Locks.Hold("expired", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+
+	// released lock could be taken by others right away
+	Instance = self
+	require.NoError(t, mgr.Locks.Release("job"))
+	require.NoError(t, mgr.Locks.Release("expired"))
+	Instance = "other"
+	assert.True(t, mgr.Locks.Lead("job", time.Minute))
+	l, err := mgr.Locks.GetByName("job")
+	require.NoError(t, err)
+	assert.Equal(t, "other", l.Owner)
+}
@@ -1,21 +1,54 @@
 package manager
 
 import (
+	"reflect"
+	"sync/atomic"
 	"time"
 
+	"golang.org/x/net/context"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/cascade"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/pkg/risk"
+	"github.com/bearded-web/bearded/pkg/sanitize"
+	"github.com/bearded-web/bearded/pkg/siem"
 )
 
 type ManagerConfig struct {
 	TextSearchEnable bool
+	// sizes of thumbnails generated for uploaded images
+	ThumbnailSizes []int
+	// model for issue risk scores, default weights are used if nil
+	Risk *risk.Model
+	// raw reports bigger than this size are stored in files, 0 to disable
+	RawReportLimit int
+	// cache for CountCached mode, a cache without ttl is used if nil
+	Counts *CountCache
+	// policy for issue descriptions, remediations and comments, text isn't sanitized if nil
+	Sanitizer *sanitize.Policy
+	// default scan limits for projects without their own quota
+	Quota project.Quota
+	// destructive actions wait for a second admin during this window, 0 to execute them right away
+	ApprovalWindow time.Duration
+	// what to do with issues and scans of removed targets and projects, cascade by default
+	CascadePolicy cascade.Policy
+	// max sockets in use per mongo server, it's set to the session by the dispatcher and reported by PoolStats
+	PoolLimit int
+	// list queries are stopped after this time, 0 to fetch results until the request is finished
+	QueryTimeout time.Duration
+	// security events for SOC, they aren't streamed if nil
+	Siem *siem.Stream
 }
 
 // query options
 type Opts struct {
-	Limit int
-	Skip  int
-	Sort  []string
+	Limit  int
+	Skip   int
+	Sort   []string
+	Count  CountMode
+	Select bson.M // fields to load, e.g. bson.M{"data": 0} to skip big fields
 }
 
 type ManagerInterface interface {
@@ -26,25 +59,49 @@ type Manager struct {
 	db  *mgo.Database
 	Cfg ManagerConfig
 
-	Users    *UserManager
-	Plugins  *PluginManager
-	Projects *ProjectManager
-	Targets  *TargetManager
-	Plans    *PlanManager
-	Scans    *ScanManager
-	Agents   *AgentManager
-	Reports  *ReportManager
-	Feed     *FeedManager
-	Files    *FileManager
-	Comments *CommentManager
-	Issues   *IssueManager
-	Techs    *TechManager
-	Tokens   *TokenManager
+	Users      *UserManager
+	Plugins    *PluginManager
+	Projects   *ProjectManager
+	Targets    *TargetManager
+	Plans      *PlanManager
+	Scans      *ScanManager
+	Agents     *AgentManager
+	Reports    *ReportManager
+	Feed       *FeedManager
+	Files      *FileManager
+	Comments   *CommentManager
+	Issues     *IssueManager
+	Techs      *TechManager
+	Tokens     *TokenManager
+	Inbox      *InboxManager
+	Quarantine *QuarantineManager
+	Severities *SeverityMapManager
+	Discovery  *DiscoveryManager
+	Hosts      *HostManager
+	Tombstones *TombstoneManager
+	Worklogs   *WorklogManager
+	Approvals  *ApprovalManager
+	Cascades   *CascadeManager
+	Locks      *LockManager
+	ApiUsage   *ApiUsageManager
+	Applied    *ApplyStateManager
+	Pages      *PageManager
+	Exploits   *ExploitManager
+	Snapshots  *SnapshotManager
+	Summaries  *SummaryManager
 
 	Permission *PermissionManager
 	Vulndb     *VulndbManager
 
 	managers []ManagerInterface
+
+	// copies are counted until they are closed, closed is set atomically to count every copy once
+	copied bool
+	closed int32
+	// borrowed managers share the session with the owner, only the owner closes it
+	borrowed bool
+	// list queries are stopped when the context is done, nil for managers without requests
+	ctx context.Context
 }
 
 // Manager contains all available managers for different models
@@ -57,6 +114,12 @@ func New(db *mgo.Database, cfg ...ManagerConfig) *Manager {
 	if len(cfg) > 0 {
 		m.Cfg = cfg[0]
 	}
+	if m.Cfg.Risk == nil {
+		m.Cfg.Risk = risk.Default()
+	}
+	if m.Cfg.Counts == nil {
+		m.Cfg.Counts = NewCountCache(0)
+	}
 
 	// initialize different managers
 	m.Users = &UserManager{manager: m, col: db.C("users")}
@@ -70,9 +133,25 @@ func New(db *mgo.Database, cfg ...ManagerConfig) *Manager {
 	m.Feed = &FeedManager{manager: m, col: db.C("feed")}
 	m.Files = &FileManager{manager: m, grid: db.GridFS("fs")}
 	m.Comments = &CommentManager{manager: m, col: db.C("comments")}
-	m.Issues = &IssueManager{manager: m, col: db.C("issues")}
+	m.Issues = &IssueManager{manager: m, col: db.C("issues"), trash: db.C("issues_trash")}
 	m.Techs = &TechManager{manager: m, col: db.C("techs")}
 	m.Tokens = &TokenManager{manager: m, col: db.C("tokens")}
+	m.Inbox = &InboxManager{manager: m, col: db.C("inbox")}
+	m.Quarantine = &QuarantineManager{manager: m, col: db.C("quarantine")}
+	m.Severities = &SeverityMapManager{manager: m, col: db.C("severity_maps")}
+	m.Discovery = &DiscoveryManager{manager: m, col: db.C("discoveries")}
+	m.Hosts = &HostManager{manager: m, col: db.C("discovered_hosts")}
+	m.Tombstones = &TombstoneManager{manager: m, col: db.C("tombstones")}
+	m.Worklogs = &WorklogManager{manager: m, col: db.C("worklogs")}
+	m.Approvals = &ApprovalManager{manager: m, col: db.C("approvals")}
+	m.Cascades = &CascadeManager{manager: m, col: db.C("cascades")}
+	m.Locks = &LockManager{manager: m, col: db.C("locks")}
+	m.ApiUsage = &ApiUsageManager{manager: m, col: db.C("api_usage")}
+	m.Applied = &ApplyStateManager{manager: m, col: db.C("apply_states")}
+	m.Pages = &PageManager{manager: m, col: db.C("pages"), revisions: db.C("page_revisions")}
+	m.Exploits = &ExploitManager{manager: m, col: db.C("exploits")}
+	m.Snapshots = &SnapshotManager{manager: m, col: db.C("stats")}
+	m.Summaries = &SummaryManager{manager: m, col: db.C("target_summaries")}
 
 	m.Permission = &PermissionManager{manager: m}
 	m.Vulndb = &VulndbManager{manager: m}
@@ -92,6 +171,22 @@ func New(db *mgo.Database, cfg ...ManagerConfig) *Manager {
 		m.Issues,
 		m.Techs,
 		m.Tokens,
+		m.Inbox,
+		m.Quarantine,
+		m.Severities,
+		m.Discovery,
+		m.Hosts,
+		m.Tombstones,
+		m.Worklogs,
+		m.Approvals,
+		m.Cascades,
+		m.Locks,
+		m.ApiUsage,
+		m.Applied,
+		m.Pages,
+		m.Exploits,
+		m.Snapshots,
+		m.Summaries,
 
 		m.Permission,
 		m.Vulndb,
@@ -117,9 +212,19 @@ func (m *Manager) Copy() *Manager {
 	// TODO (m0sth8): implement copy through the interface
 	m.Permission.Copy(copy.Permission)
 	m.Vulndb.Copy(copy.Vulndb)
+	copy.copied = true
+	atomic.AddInt64(&openCopies, 1)
 	return copy
 }
 
+// Borrow returns the manager with the same session, Close of the borrowed manager doesn't do anything,
+// so it could be passed to code which closes managers, while the session is closed only by the owner
+func (m *Manager) Borrow() *Manager {
+	borrowed := *m
+	borrowed.borrowed = true
+	return &borrowed
+}
+
 // Clone works just like Copy, but also reuses the same socket as the original
 // session, in case it had already reserved one due to its consistency
 // guarantees.  This behavior ensures that writes performed in the old session
@@ -134,6 +239,12 @@ func (m *Manager) Clone() *Manager {
 // Close terminates the session.  It's a runtime error to use a session
 // after it has been closed.
 func (m *Manager) Close() {
+	if m.borrowed {
+		return
+	}
+	if m.copied && atomic.CompareAndSwapInt32(&m.closed, 0, 1) {
+		atomic.AddInt64(&openCopies, -1)
+	}
 	m.db.Session.Close()
 }
 
@@ -195,20 +306,55 @@ func (m *Manager) NewId() bson.ObjectId {
 	return bson.NewObjectId()
 }
 
+// FilterBy finds results for query and returns the total count, see CountMode for counting options.
+// In CountHasMore mode the count is skip + len(results), plus one if there are more results.
 func (m *Manager) FilterBy(col *mgo.Collection, query *bson.M, results interface{}, opts ...Opts) (int, error) {
 	q := col.Find(query)
+	mode := CountExact
+	limit, skip := 0, 0
 	for _, opt := range opts {
 		if opt.Limit != 0 {
-			q.Limit(opt.Limit)
+			limit = opt.Limit
 		}
 		if opt.Skip != 0 {
+			skip = opt.Skip
 			q.Skip(opt.Skip)
 		}
 		if opt.Sort != nil {
 			q.Sort(opt.Sort...)
 		}
+		if opt.Select != nil {
+			q.Select(opt.Select)
+		}
+		mode = opt.Count
+	}
+	if mode == CountHasMore && limit == 0 {
+		// everything is fetched, len(results) is the exact count
+		mode = CountExact
+	}
+	if mode == CountHasMore {
+		// fetch one more to know if there is the next page
+		q.Limit(limit + 1)
+	} else if limit != 0 {
+		q.Limit(limit)
 	}
-	if err := q.All(results); err != nil {
+	if err := m.all(q, results); err != nil {
+		return 0, err
+	}
+	switch mode {
+	case CountHasMore:
+		slice := reflect.ValueOf(results).Elem()
+		if slice.Len() > limit {
+			slice.Set(slice.Slice(0, limit))
+			return skip + limit + 1, nil
+		}
+		return skip + slice.Len(), nil
+	case CountCached:
+		if count, ok := m.Cfg.Counts.Get(col.FullName, query); ok {
+			return count, nil
+		}
+	}
+	if err := m.contextErr(); err != nil {
 		return 0, err
 	}
 	q.Limit(0)
@@ -217,6 +363,9 @@ func (m *Manager) FilterBy(col *mgo.Collection, query *bson.M, results interface
 	if err != nil {
 		return 0, err
 	}
+	if mode == CountCached {
+		m.Cfg.Counts.Set(col.FullName, query, count)
+	}
 	return count, nil
 }
 
@@ -225,7 +374,7 @@ func (m *Manager) FilterAndSortBy(col *mgo.Collection, query *bson.M, sort []str
 	if sort != nil && len(sort) > 0 {
 		q.Sort(sort...)
 	}
-	if err := q.All(results); err != nil {
+	if err := m.all(q, results); err != nil {
 		return 0, err
 	}
 	count, err := q.Count()
@@ -0,0 +1,96 @@
+package manager
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/migration"
+	"github.com/bearded-web/bearded/models/target"
+)
+
+// MoveHost rewrites addresses of targets on the old host and urls of their issues.
+// Changed objects get the new updated date, so synced clients see them. The old address is kept
+// in the target feed and in the moved activity of issues, owner is the user who moves the host.
+// Only first limit changes are kept in the result, nothing is stored if it's a dry run.
+func (m *Manager) MoveHost(mv *migration.HostMove, owner bson.ObjectId, dryRun bool, limit int) (*migration.Result, error) {
+	result := migration.NewResult(dryRun)
+	query := bson.M{"type": bson.M{"$in": []target.TargetType{target.TypeWeb, target.TypeApi}}}
+	if mv.Project != "" {
+		query["project"] = mv.Project
+	}
+	targets, _, err := m.Targets.FilterByQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range targets {
+		changes := mv.RewriteTarget(t)
+		if len(changes) == 0 {
+			continue
+		}
+		result.Add(changes, limit)
+		issues := result.Issues
+		if err := m.moveIssues(mv, t, owner, dryRun, result, limit); err != nil {
+			return nil, err
+		}
+		if dryRun {
+			continue
+		}
+		update := bson.M{"$set": bson.M{changes[0].Field: changes[0].New, "updated": time.Now().UTC()}}
+		if err := m.Targets.col.UpdateId(t.Id, update); err != nil {
+			return nil, err
+		}
+		if _, err := m.Feed.AddMove(t, changes[0].Old, changes[0].New, result.Issues-issues, owner); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (m *Manager) moveIssues(mv *migration.HostMove, t *target.Target, owner bson.ObjectId, dryRun bool, result *migration.Result, limit int) error {
+	issues, _, err := m.Issues.FilterByQuery(bson.M{"target": t.Id, "vector": bson.M{"$ne": nil}})
+	if err != nil {
+		return err
+	}
+	if !dryRun {
+		defer m.Issues.invalidate()
+	}
+	for _, obj := range issues {
+		oldUniqId := obj.UniqId
+		changes := mv.RewriteIssue(obj)
+		if len(changes) == 0 {
+			continue
+		}
+		if obj.UniqId != oldUniqId {
+			// the same issue could be already reported on the new host, keep both of them then
+			_, err := m.Issues.GetByUniqId(t.Id, obj.UniqId)
+			switch {
+			case err == nil:
+				obj.UniqId = oldUniqId
+				changes = changes[:len(changes)-1]
+			case !m.IsNotFound(err):
+				return err
+			}
+		}
+		result.Add(changes, limit)
+		if dryRun {
+			continue
+		}
+		now := time.Now().UTC()
+		update := bson.M{
+			"$set": bson.M{"vector": obj.Vector, "uniqId": obj.UniqId, "updated": now, "lastActivity": now},
+			"$push": bson.M{"activities": &issue.Activity{
+				Type:    issue.ActivityMoved,
+				Created: now,
+				User:    owner,
+				From:    mv.From,
+				To:      mv.To,
+			}},
+		}
+		if err := m.Issues.col.UpdateId(obj.Id, update); err != nil {
+			return err
+		}
+	}
+	return nil
+}
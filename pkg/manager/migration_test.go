@@ -0,0 +1,72 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/feed"
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/migration"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/pkg/tests"
+)
+
+func TestMoveHost(t *testing.T) {
+	mongo, dbName, err := tests.RandomTestMongoUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tests.RandomTestMongoDown(mongo, dbName)
+
+	mgr := New(mongo.DB(dbName))
+	require.NoError(t, mgr.Init())
+	adminId := bson.NewObjectId()
+	tgt, err := mgr.Targets.Create(&target.Target{
+		Project: bson.NewObjectId(),
+		Type:    target.TypeWeb,
+		Web:     &target.WebTarget{Domain: "https://staging.example.com"},
+	})
+	require.NoError(t, err)
+	obj, err := mgr.Issues.Create(&issue.TargetIssue{
+		Project: tgt.Project,
+		Target:  tgt.Id,
+		Issue:   issue.Issue{Summary: "xss", Vector: &issue.Vector{Url: "https://staging.example.com/search"}},
+	})
+	require.NoError(t, err)
+	// mongo keeps milliseconds
+	since := time.Now().UTC().Truncate(time.Millisecond)
+
+	mv := &migration.HostMove{From: "staging.example.com", To: "app.example.com"}
+	result, err := mgr.MoveHost(mv, adminId, false, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Targets)
+	assert.Equal(t, 1, result.Issues)
+
+	// changed objects are seen by the delta sync
+	tgt, err = mgr.Targets.GetById(tgt.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "https://app.example.com", tgt.Web.Domain)
+	assert.False(t, tgt.Updated.Before(since))
+	obj, err = mgr.Issues.GetById(obj.Id)
+	require.NoError(t, err)
+	assert.Equal(t, "https://app.example.com/search", obj.Vector.Url)
+	assert.False(t, obj.Updated.Before(since))
+
+	// the old address is kept
+	last := obj.Activities[len(obj.Activities)-1]
+	assert.Equal(t, issue.ActivityMoved, last.Type)
+	assert.Equal(t, adminId, last.User)
+	assert.Equal(t, "staging.example.com", last.From)
+	assert.Equal(t, "app.example.com", last.To)
+	items, _, err := mgr.Feed.FilterByQuery(bson.M{"target": tgt.Id, "type": feed.TypeMove})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "https://staging.example.com", items[0].From)
+	assert.Equal(t, "https://app.example.com", items[0].To)
+	assert.Equal(t, 1, items[0].Count)
+	assert.Equal(t, adminId, items[0].Owner)
+}
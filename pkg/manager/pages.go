@@ -0,0 +1,105 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/page"
+)
+
+type PageManager struct {
+	manager   *Manager
+	col       *mgo.Collection
+	revisions *mgo.Collection // snapshots of pages made by every change
+}
+
+func (m *PageManager) Init() error {
+	logrus.Infof("Initialize page indexes")
+	if err := m.col.EnsureIndex(mgo.Index{
+		Key:        []string{"project"},
+		Background: true,
+	}); err != nil {
+		return err
+	}
+	for _, key := range [][]string{{"page", "number"}, {"project"}} {
+		err := m.revisions.EnsureIndex(mgo.Index{
+			Key:        key,
+			Background: true,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *PageManager) GetById(id bson.ObjectId) (*page.Page, error) {
+	u := &page.Page{}
+	err := m.manager.GetById(m.col, id, u)
+	u.Text = m.manager.Cfg.Sanitizer.Sanitize(u.Text)
+	return u, err
+}
+
+func (m *PageManager) FilterByQuery(query bson.M, opts ...Opts) ([]*page.Page, int, error) {
+	results := []*page.Page{}
+	count, err := m.manager.FilterBy(m.col, &query, &results, opts...)
+	for _, obj := range results {
+		obj.Text = m.manager.Cfg.Sanitizer.Sanitize(obj.Text)
+	}
+	return results, count, err
+}
+
+// Create stores the page with its first revision
+func (m *PageManager) Create(raw *page.Page) (*page.Page, error) {
+	raw.Id = bson.NewObjectId()
+	raw.Created = time.Now().UTC()
+	raw.Updated = raw.Created
+	raw.Revision = 1
+	raw.Text = m.manager.Cfg.Sanitizer.Sanitize(raw.Text)
+	if err := m.col.Insert(raw); err != nil {
+		return nil, err
+	}
+	if err := m.revisions.Insert(raw.NewRevision()); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+// Update stores the page as the next revision, the previous one is kept in history
+func (m *PageManager) Update(obj *page.Page) error {
+	obj.Updated = time.Now().UTC()
+	obj.Text = m.manager.Cfg.Sanitizer.Sanitize(obj.Text)
+	prev := obj.Revision
+	obj.Revision++
+	// the revision is checked, so concurrent edits don't overwrite each other
+	if err := m.col.Update(bson.M{"_id": obj.Id, "revision": prev}, obj); err != nil {
+		obj.Revision = prev
+		return err
+	}
+	return m.revisions.Insert(obj.NewRevision())
+}
+
+// Revisions returns history of the page, the latest revision is the first
+func (m *PageManager) Revisions(id bson.ObjectId, opts ...Opts) ([]*page.Revision, int, error) {
+	results := []*page.Revision{}
+	if len(opts) == 0 {
+		opts = []Opts{{Sort: []string{"-number"}}}
+	}
+	count, err := m.manager.FilterBy(m.revisions, &bson.M{"page": id}, &results, opts...)
+	for _, obj := range results {
+		obj.Text = m.manager.Cfg.Sanitizer.Sanitize(obj.Text)
+	}
+	return results, count, err
+}
+
+// Remove deletes the page with its history
+func (m *PageManager) Remove(obj *page.Page) error {
+	if err := m.col.RemoveId(obj.Id); err != nil {
+		return err
+	}
+	_, err := m.revisions.RemoveAll(bson.M{"page": obj.Id})
+	return err
+}
@@ -0,0 +1,46 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/page"
+	"github.com/bearded-web/bearded/pkg/tests"
+)
+
+func TestPageRevisions(t *testing.T) {
+	mongo, dbName, err := tests.RandomTestMongoUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tests.RandomTestMongoDown(mongo, dbName)
+
+	mgr := New(mongo.DB(dbName))
+	obj, err := mgr.Pages.Create(&page.Page{Project: bson.NewObjectId(), Author: bson.NewObjectId(), Title: "Scope", Text: "only staging"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, obj.Revision)
+
+	stale := *obj
+	obj.Text = "staging and prod"
+	require.NoError(t, mgr.Pages.Update(obj))
+	assert.Equal(t, 2, obj.Revision)
+
+	// the stale copy doesn't overwrite the newer revision
+	stale.Text = "nothing"
+	assert.True(t, mgr.IsNotFound(mgr.Pages.Update(&stale)))
+	assert.Equal(t, 1, stale.Revision)
+
+	revisions, count, err := mgr.Pages.Revisions(obj.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, "staging and prod", revisions[0].Text)
+	assert.Equal(t, "only staging", revisions[1].Text)
+
+	require.NoError(t, mgr.Pages.Remove(obj))
+	_, count, err = mgr.Pages.Revisions(obj.Id)
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
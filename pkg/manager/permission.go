@@ -1,6 +1,8 @@
 package manager
 
 import (
+	"sync"
+
 	"github.com/bearded-web/bearded/models/project"
 	"github.com/bearded-web/bearded/models/user"
 	"gopkg.in/fatih/set.v0"
@@ -9,6 +11,9 @@ import (
 type PermissionManager struct {
 	manager *Manager
 
+	// admins are replaced on config reload while requests check them,
+	// the set itself isn't changed after it's stored
+	mu     sync.RWMutex
 	admins set.Interface
 }
 
@@ -27,19 +32,30 @@ func (m *PermissionManager) IsAdmin(u *user.User) bool {
 }
 
 func (m *PermissionManager) IsAdminEmail(email string) bool {
-	if m.admins == nil {
+	m.mu.RLock()
+	admins := m.admins
+	m.mu.RUnlock()
+	if admins == nil {
 		return false
 	}
-	return m.admins.Has(email)
+	return admins.Has(email)
 }
 
 func (m *PermissionManager) SetAdmins(emails []string) {
-	m.admins = set.New(AgentEmail)
+	admins := set.New(AgentEmail)
 	for _, email := range emails {
-		m.admins.Add(email)
+		admins.Add(email)
 	}
+	m.mu.Lock()
+	m.admins = admins
+	m.mu.Unlock()
 }
 
 func (m *PermissionManager) Copy(new *PermissionManager) {
-	new.admins = m.admins
+	m.mu.RLock()
+	admins := m.admins
+	m.mu.RUnlock()
+	new.mu.Lock()
+	new.admins = admins
+	new.mu.Unlock()
 }
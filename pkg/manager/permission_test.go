@@ -0,0 +1,35 @@
+package manager
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetAdminsConcurrently(t *testing.T) {
+	m := &PermissionManager{}
+	m.SetAdmins([]string{"admin@example.com"})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				m.SetAdmins([]string{"admin@example.com"})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				copied := &PermissionManager{}
+				m.Copy(copied)
+				assert.True(t, copied.IsAdminEmail("admin@example.com"))
+				assert.False(t, m.IsAdminEmail("user@example.com"))
+			}
+		}()
+	}
+	wg.Wait()
+	assert.True(t, m.IsAdminEmail(AgentEmail))
+}
@@ -55,6 +55,11 @@ func (m *PlanManager) GetById(id bson.ObjectId) (*plan.Plan, error) {
 	return u, m.manager.GetById(m.col, id, &u)
 }
 
+func (m *PlanManager) GetByName(name string) (*plan.Plan, error) {
+	u := &plan.Plan{}
+	return u, m.manager.GetBy(m.col, &bson.M{"name": name}, u)
+}
+
 func (m *PlanManager) All() ([]*plan.Plan, int, error) {
 	results := []*plan.Plan{}
 
@@ -24,6 +24,7 @@ type PluginFltr struct {
 	Version    string            `fltr:"version,in,nin,gte,gt,lte,lte"`
 	Type       plugin.PluginType `fltr:"type,in,nin"`
 	TargetType target.TargetType `fltr:"targetType,in"`
+	Category   plugin.Category   `fltr:"category,in" bson:"categories"`
 }
 
 func (s *PluginManager) Init() error {
@@ -36,7 +37,7 @@ func (s *PluginManager) Init() error {
 	if err != nil {
 		return err
 	}
-	for _, index := range []string{"name", "version", "type"} {
+	for _, index := range []string{"name", "version", "type", "categories"} {
 		err := s.col.EnsureIndex(mgo.Index{
 			Key:        []string{index},
 			Background: true,
@@ -120,3 +121,26 @@ func (m *PluginManager) Update(obj *plugin.Plugin) error {
 	obj.Updated = time.Now().UTC()
 	return m.col.UpdateId(obj.Id, obj)
 }
+
+// Import creates the plugin or updates the existing one with the same name and version,
+// the previous state of the updated plugin is returned. The enabled flag of existing plugins is kept.
+func (m *PluginManager) Import(raw *plugin.Plugin) (*plugin.Plugin, *plugin.Plugin, error) {
+	prev := &plugin.Plugin{}
+	err := m.col.Find(bson.M{"name": raw.Name, "version": raw.Version}).One(prev)
+	if err == mgo.ErrNotFound {
+		obj, err := m.Create(raw)
+		return obj, nil, err
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	raw.Id = prev.Id
+	raw.Created = prev.Created
+	raw.Enabled = prev.Enabled
+	return raw, prev, m.Update(raw)
+}
+
+// CountByArchive returns count of plugins which use the image archive
+func (m *PluginManager) CountByArchive(archive string) (int, error) {
+	return m.col.Find(bson.M{"container.archive": archive}).Count()
+}
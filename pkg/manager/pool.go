@@ -0,0 +1,50 @@
+package manager
+
+import (
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/mgo.v2"
+
+	"github.com/bearded-web/bearded/models/stats"
+)
+
+var (
+	// count of copied managers which aren't closed yet
+	openCopies   int64
+	statsEnabled int32
+)
+
+// EnableStats turns on counters of the mongo driver, they are global for all sessions
+func EnableStats() {
+	mgo.SetStats(true)
+	atomic.StoreInt32(&statsEnabled, 1)
+}
+
+// OpenCopies returns count of copied managers which aren't closed yet
+func OpenCopies() int {
+	return int(atomic.LoadInt64(&openCopies))
+}
+
+// PoolStats returns the state of mongo connections, driver counters are empty until EnableStats is called
+func (m *Manager) PoolStats() *stats.Pool {
+	p := &stats.Pool{
+		Limit:      m.Cfg.PoolLimit,
+		OpenCopies: OpenCopies(),
+		Generated:  time.Now().UTC(),
+	}
+	if atomic.LoadInt32(&statsEnabled) == 0 {
+		return p
+	}
+	s := mgo.GetStats()
+	p.Clusters = s.Clusters
+	p.MasterConns = s.MasterConns
+	p.SlaveConns = s.SlaveConns
+	p.SentOps = s.SentOps
+	p.ReceivedOps = s.ReceivedOps
+	p.ReceivedDocs = s.ReceivedDocs
+	p.SocketsAlive = s.SocketsAlive
+	p.SocketsInUse = s.SocketsInUse
+	p.SocketRefs = s.SocketRefs
+	return p
+}
@@ -0,0 +1,35 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bearded-web/bearded/pkg/tests"
+)
+
+func TestManagerBorrow(t *testing.T) {
+	mongo, dbName, err := tests.RandomTestMongoUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tests.RandomTestMongoDown(mongo, dbName)
+
+	mgr := New(mongo.DB(dbName))
+	open := OpenCopies()
+	copy := mgr.Copy()
+	assert.Equal(t, open+1, OpenCopies())
+
+	// closing of a borrowed manager doesn't close the session of the owner
+	borrowed := copy.Borrow()
+	borrowed.Close()
+	_, err = borrowed.Users.col.Count()
+	require.NoError(t, err)
+	assert.Equal(t, open+1, OpenCopies())
+
+	copy.Close()
+	copy.Close()
+	assert.Equal(t, open, OpenCopies())
+	assert.Equal(t, open, mgr.PoolStats().OpenCopies)
+}
@@ -94,3 +94,13 @@ func (m *ProjectManager) Update(obj *project.Project) error {
 	obj.Updated = time.Now().UTC()
 	return m.col.UpdateId(obj.Id, obj)
 }
+
+// SetDeliverySent updates only the last sent time of the project report delivery
+func (m *ProjectManager) SetDeliverySent(id bson.ObjectId, sent time.Time) error {
+	return m.col.UpdateId(id, bson.M{"$set": bson.M{"delivery.lastSent": sent}})
+}
+
+// Remove deletes the project with its targets, issues, scans and feed
+func (m *ProjectManager) Remove(obj *project.Project) error {
+	return m.col.RemoveId(obj.Id)
+}
@@ -0,0 +1,76 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/report"
+	"github.com/bearded-web/bearded/pkg/fltr"
+)
+
+type QuarantineManager struct {
+	manager *Manager
+	col     *mgo.Collection
+}
+
+type QuarantineFltr struct {
+	Status      report.QuarantineStatus `fltr:"status,in"`
+	Scan        bson.ObjectId           `fltr:"scan"`
+	ScanSession bson.ObjectId           `fltr:"scanSession"`
+	Created     time.Time               `fltr:"created,gte,gt,lte,lt"`
+}
+
+func (m *QuarantineManager) Init() error {
+	logrus.Infof("Initialize quarantine indexes")
+	for _, index := range []string{"status", "scan", "scanSession", "created"} {
+		err := m.col.EnsureIndex(mgo.Index{
+			Key:        []string{index},
+			Background: true,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *QuarantineManager) Fltr() *QuarantineFltr {
+	return &QuarantineFltr{}
+}
+
+func (m *QuarantineManager) GetById(id bson.ObjectId) (*report.Quarantine, error) {
+	u := &report.Quarantine{}
+	return u, m.manager.GetById(m.col, id, u)
+}
+
+func (m *QuarantineManager) FilterBy(f *QuarantineFltr, opts ...Opts) ([]*report.Quarantine, int, error) {
+	query := fltr.GetQuery(f)
+	return m.FilterByQuery(query, opts...)
+}
+
+func (m *QuarantineManager) FilterByQuery(query bson.M, opts ...Opts) ([]*report.Quarantine, int, error) {
+	results := []*report.Quarantine{}
+	count, err := m.manager.FilterBy(m.col, &query, &results, opts...)
+	return results, count, err
+}
+
+func (m *QuarantineManager) Create(raw *report.Quarantine) (*report.Quarantine, error) {
+	raw.Id = bson.NewObjectId()
+	raw.Created = time.Now().UTC()
+	raw.Updated = raw.Created
+	if raw.Status == "" {
+		raw.Status = report.QuarantinePending
+	}
+	if err := m.col.Insert(raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+func (m *QuarantineManager) Update(obj *report.Quarantine) error {
+	obj.Updated = time.Now().UTC()
+	return m.col.UpdateId(obj.Id, obj)
+}
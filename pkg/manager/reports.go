@@ -1,12 +1,17 @@
 package manager
 
 import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
 	"time"
 
 	"github.com/Sirupsen/logrus"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 
+	"github.com/bearded-web/bearded/models/file"
 	"github.com/bearded-web/bearded/models/report"
 	"github.com/bearded-web/bearded/models/scan"
 	"github.com/bearded-web/bearded/pkg/fltr"
@@ -49,13 +54,21 @@ func (m *ReportManager) Fltr() *ReportFltr {
 
 func (m *ReportManager) GetById(id bson.ObjectId) (*report.Report, error) {
 	u := &report.Report{}
-	return u, m.manager.GetById(m.col, id, &u)
+	if err := m.manager.GetById(m.col, id, &u); err != nil {
+		return u, err
+	}
+	report.Migrate(u)
+	return u, nil
 }
 
 func (m *ReportManager) GetBySession(sessId bson.ObjectId) (*report.Report, error) {
 	query := bson.M{"scanSession": sessId}
 	u := &report.Report{}
-	return u, m.manager.GetBy(m.col, &query, &u)
+	if err := m.manager.GetBy(m.col, &query, &u); err != nil {
+		return u, err
+	}
+	report.Migrate(u)
+	return u, nil
 }
 
 func (m *ReportManager) FilterBySessions(sessions []*scan.Session) ([]*report.Report, int, error) {
@@ -70,20 +83,50 @@ func (m *ReportManager) FilterBySessions(sessions []*scan.Session) ([]*report.Re
 func (m *ReportManager) All() ([]*report.Report, int, error) {
 	results := []*report.Report{}
 	count, err := m.manager.All(m.col, &results)
-	return results, count, err
+	return migrateAll(results), count, err
 }
 
 func (m *ReportManager) FilterBy(f *ReportFltr) ([]*report.Report, int, error) {
 	query := fltr.GetQuery(f)
 	results := []*report.Report{}
 	count, err := m.manager.FilterBy(m.col, &query, &results)
-	return results, count, err
+	return migrateAll(results), count, err
 }
 
 func (m *ReportManager) FilterByQuery(query bson.M) ([]*report.Report, int, error) {
 	results := []*report.Report{}
 	count, err := m.manager.FilterBy(m.col, &query, &results)
-	return results, count, err
+	return migrateAll(results), count, err
+}
+
+// Upgrade saves stored reports of old schema versions with the current one, so they aren't migrated on every read.
+// Returns the number of upgraded reports.
+func (m *ReportManager) Upgrade() (int, error) {
+	iter := m.col.Find(bson.M{"$or": []bson.M{
+		{"version": bson.M{"$exists": false}},
+		{"version": bson.M{"$lt": report.SchemaVersion}},
+	}}).Iter()
+	obj := &report.Report{}
+	n := 0
+	for iter.Next(obj) {
+		if report.Migrate(obj) {
+			if err := m.col.UpdateId(obj.Id, obj); err != nil {
+				iter.Close()
+				return n, err
+			}
+			n++
+		}
+		obj = &report.Report{}
+	}
+	return n, iter.Close()
+}
+
+// readers always get reports of the current schema version
+func migrateAll(reports []*report.Report) []*report.Report {
+	for _, rep := range reports {
+		report.Migrate(rep)
+	}
+	return reports
 }
 
 func (m *ReportManager) Create(raw *report.Report) (*report.Report, error) {
@@ -91,23 +134,110 @@ func (m *ReportManager) Create(raw *report.Report) (*report.Report, error) {
 	raw.Id = bson.NewObjectId()
 	raw.Created = time.Now().UTC()
 	raw.Updated = raw.Created
+	raw.Version = report.SchemaVersion
 	UpdateMulti(raw)
+	if err := m.archive(raw); err != nil {
+		return nil, err
+	}
 	if err := m.col.Insert(raw); err != nil {
 		return nil, err
 	}
 	return raw, nil
 }
 
+// OpenRaw returns raw data of the raw report, it's read from file storage for archived reports.
+// Don't forget to close the reader after.
+func (m *ReportManager) OpenRaw(obj *report.Report) (io.ReadCloser, error) {
+	if obj.Archive == nil {
+		return ioutil.NopCloser(strings.NewReader(obj.Raw.Raw)), nil
+	}
+	f, err := m.manager.Files.GetById(obj.Archive.Id)
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// move big raw data to file storage, so reports don't reach mongo document limit
+func (m *ReportManager) archive(obj *report.Report) error {
+	limit := m.manager.Cfg.RawReportLimit
+	for _, rep := range obj.GetAllRaws() {
+		if rep.Archive != nil {
+			if rep.Raw.Raw == "" {
+				continue
+			}
+			// raw data is replaced, so the archived one isn't needed anymore
+			if err := m.manager.Files.Remove(rep.Archive); err != nil && !m.manager.IsNotFound(err) {
+				return err
+			}
+			rep.Archive = nil
+		}
+		rep.Size = len(rep.Raw.Raw)
+		if limit <= 0 || rep.Size <= limit {
+			continue
+		}
+		meta, err := m.manager.Files.Create(strings.NewReader(rep.Raw.Raw), &file.Meta{
+			Name:        fmt.Sprintf("report-%s.txt", rep.Id.Hex()),
+			ContentType: "text/plain",
+		})
+		if err != nil {
+			return err
+		}
+		rep.Archive = meta
+		rep.Raw.Raw = ""
+	}
+	return nil
+}
+
 func (m *ReportManager) Update(obj *report.Report) error {
 	obj.Updated = time.Now().UTC()
 	UpdateMulti(obj)
+	if err := m.archive(obj); err != nil {
+		return err
+	}
 	return m.col.UpdateId(obj.Id, obj)
 }
 
+// Remove removes the report with its archived raw data
 func (m *ReportManager) Remove(obj *report.Report) error {
+	if err := m.removeArchives(obj); err != nil {
+		return err
+	}
 	return m.col.RemoveId(obj.Id)
 }
 
+// removeByScans removes reports of scans with their archived raw data
+func (m *ReportManager) removeByScans(scans []bson.ObjectId) error {
+	query := bson.M{"scan": bson.M{"$in": scans}}
+	iter := m.col.Find(query).Iter()
+	obj := &report.Report{}
+	for iter.Next(obj) {
+		if err := m.removeArchives(obj); err != nil {
+			iter.Close()
+			return err
+		}
+		obj = &report.Report{}
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+	_, err := m.col.RemoveAll(query)
+	return err
+}
+
+// removeArchives removes raw data of the report and its multi reports from file storage
+func (m *ReportManager) removeArchives(obj *report.Report) error {
+	for _, rep := range obj.GetAllRaws() {
+		if rep.Archive == nil {
+			continue
+		}
+		if err := m.manager.Files.Remove(rep.Archive); err != nil && !m.manager.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
 // upgrade underneath multi reports with created/updated, scan, scanSession fields
 func UpdateMulti(r *report.Report) {
 	if r.Type == report.TypeMulti {
@@ -122,6 +252,7 @@ func UpdateMulti(r *report.Report) {
 				rep.Created = now
 			}
 			rep.Updated = now
+			rep.Version = r.Version
 			UpdateMulti(rep)
 		}
 	}
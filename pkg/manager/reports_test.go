@@ -0,0 +1,84 @@
+package manager
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/report"
+	"github.com/bearded-web/bearded/pkg/tests"
+)
+
+func TestReportArchive(t *testing.T) {
+	mongo, dbName, err := tests.RandomTestMongoUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tests.RandomTestMongoDown(mongo, dbName)
+
+	mgr := New(mongo.DB(dbName), ManagerConfig{RawReportLimit: 10})
+
+	big := strings.Repeat("a", 11)
+	rep := &report.Report{
+		Type: report.TypeMulti,
+		Multi: []*report.Report{
+			{Type: report.TypeRaw, Raw: report.Raw{Raw: "small"}},
+			{Type: report.TypeRaw, Raw: report.Raw{Raw: big}},
+		},
+	}
+	rep.SetScan(bson.NewObjectId())
+	rep.SetScanSession(bson.NewObjectId())
+	rep, err = mgr.Reports.Create(rep)
+	require.NoError(t, err)
+
+	rep, err = mgr.Reports.GetById(rep.Id)
+	require.NoError(t, err)
+	raws := rep.GetAllRaws()
+	require.Len(t, raws, 2)
+	assert.Equal(t, "small", raws[0].Raw.Raw)
+	assert.Nil(t, raws[0].Archive)
+	assert.Equal(t, "", raws[1].Raw.Raw)
+	require.NotNil(t, raws[1].Archive)
+	assert.Equal(t, len(big), raws[1].Archive.Size)
+
+	for i, expected := range []string{"small", big} {
+		r, err := mgr.Reports.OpenRaw(raws[i])
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(r)
+		r.Close()
+		require.NoError(t, err)
+		assert.Equal(t, expected, string(data))
+	}
+
+	assert.Equal(t, len("small"), raws[0].Size)
+	assert.Equal(t, len(big), raws[1].Size)
+
+	// replaced raw data is archived on update and the old archive is removed
+	old := raws[1].Archive
+	raws[0].Raw.Raw = big + big
+	raws[1].Raw.Raw = "fixed"
+	require.NoError(t, mgr.Reports.Update(rep))
+	rep, err = mgr.Reports.GetById(rep.Id)
+	require.NoError(t, err)
+	raws = rep.GetAllRaws()
+	require.NotNil(t, raws[0].Archive)
+	assert.Equal(t, len(big+big), raws[0].Size)
+	assert.Nil(t, raws[1].Archive)
+	assert.Equal(t, "fixed", raws[1].Raw.Raw)
+	_, err = mgr.Files.GetById(old.Id)
+	assert.True(t, mgr.IsNotFound(err))
+
+	rep.Summarize()
+	assert.Equal(t, "", rep.GetAllRaws()[1].Raw.Raw)
+	assert.Equal(t, len("fixed"), rep.GetAllRaws()[1].Size)
+
+	// archives are removed with the report
+	archived := raws[0].Archive
+	require.NoError(t, mgr.Reports.Remove(rep))
+	_, err = mgr.Files.GetById(archived.Id)
+	assert.True(t, mgr.IsNotFound(err))
+}
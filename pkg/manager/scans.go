@@ -1,14 +1,23 @@
 package manager
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"text/template"
 	"time"
 
 	"github.com/Sirupsen/logrus"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 
+	"github.com/bearded-web/bearded/models/plan"
+	"github.com/bearded-web/bearded/models/project"
 	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/models/tombstone"
 	"github.com/bearded-web/bearded/pkg/fltr"
 )
 
@@ -55,7 +64,7 @@ func (m *ScanManager) GetByMulti(ids []bson.ObjectId) (map[bson.ObjectId]*scan.S
 	query := bson.M{
 		"id": bson.M{"$in": ids},
 	}
-	if err := m.col.Find(query).All(&scans); err != nil {
+	if err := m.manager.all(m.col.Find(query), &scans); err != nil {
 		return nil, err
 	}
 	results := map[bson.ObjectId]*scan.Scan{}
@@ -80,9 +89,9 @@ func (m *ScanManager) FilterBy(f *ScanFltr) ([]*scan.Scan, int, error) {
 
 }
 
-func (m *ScanManager) FilterByQuery(query bson.M) ([]*scan.Scan, int, error) {
+func (m *ScanManager) FilterByQuery(query bson.M, opts ...Opts) ([]*scan.Scan, int, error) {
 	results := []*scan.Scan{}
-	count, err := m.manager.FilterBy(m.col, &query, &results)
+	count, err := m.manager.FilterBy(m.col, &query, &results, opts...)
 	return results, count, err
 }
 
@@ -117,11 +126,25 @@ func (m *ScanManager) Update(obj *scan.Scan) error {
 			obj.Started = &now
 		}
 	}
+	obj.Usage = obj.CalcUsage(now)
 	return m.col.UpdateId(obj.Id, obj)
 }
 
+// SetWaiting updates only the reason why the scan isn't started, nil removes it
+func (m *ScanManager) SetWaiting(sc *scan.Scan, w *scan.Waiting) error {
+	sc.Waiting = w
+	if w == nil {
+		return m.col.UpdateId(sc.Id, bson.M{"$unset": bson.M{"waiting": ""}})
+	}
+	return m.col.UpdateId(sc.Id, bson.M{"$set": bson.M{"waiting": w}})
+}
+
 func (m *ScanManager) Remove(obj *scan.Scan) error {
-	return m.col.RemoveId(obj.Id)
+	if err := m.col.RemoveId(obj.Id); err != nil {
+		return err
+	}
+	m.manager.Tombstones.Add(tombstone.Scan, obj.Id, obj.Project)
+	return nil
 }
 
 // sessions
@@ -196,3 +219,233 @@ func (m *ScanManager) UpdateSession(sc *scan.Scan, obj *scan.Session) error {
 	m.col.UpdateId(sc.Id, update)
 	return m.Update(sc)
 }
+
+// ErrSessionTaken is returned by QueueSession if the session isn't created anymore,
+// e.g. another api instance has queued it
+var ErrSessionTaken = errors.New("session is already taken")
+
+// QueueSession queues the created session to the agent with a new callback token.
+// The session is changed in the database only if it's still created, so api instances
+// sharing the database don't queue it twice. The scan and the session are changed only on success.
+func (m *ScanManager) QueueSession(sc *scan.Scan, obj *scan.Session, agentId bson.ObjectId, token string) error {
+	path := sessionPath(sc.Sessions, obj.Id, "sessions")
+	if path == "" {
+		return mgo.ErrNotFound
+	}
+	now := time.Now().UTC()
+	set := bson.M{
+		path + ".status":       scan.StatusQueued,
+		path + ".agent":        agentId,
+		path + ".token":        token,
+		path + ".dates.queued": now,
+		"dates.updated":        now,
+	}
+	// queued root session queues the scan
+	scanQueued := !obj.HasParent() && (sc.Status == scan.StatusCreated || sc.Status == scan.StatusWorking)
+	if scanQueued {
+		set["status"] = scan.StatusQueued
+		if sc.Queued == nil {
+			set["dates.queued"] = now
+		}
+	}
+	selector := bson.M{"_id": sc.Id, path + ".id": obj.Id, path + ".status": scan.StatusCreated}
+	if err := m.col.Update(selector, bson.M{"$set": set}); err != nil {
+		if err != mgo.ErrNotFound {
+			return err
+		}
+		if n, cErr := m.col.FindId(sc.Id).Count(); cErr != nil || n == 0 {
+			return err
+		}
+		return ErrSessionTaken
+	}
+	obj.Status = scan.StatusQueued
+	obj.Agent = agentId
+	obj.Token = token
+	obj.Queued = &now
+	sc.Updated = &now
+	if scanQueued {
+		sc.Status = scan.StatusQueued
+		if sc.Queued == nil {
+			sc.Queued = &now
+		}
+	}
+	return nil
+}
+
+// sessionPath returns the dotted path of the session in the scan document, like sessions.1.children.0
+func sessionPath(sessions []*scan.Session, id bson.ObjectId, prefix string) string {
+	for i, sess := range sessions {
+		path := fmt.Sprintf("%s.%d", prefix, i)
+		if sess.Id == id {
+			return path
+		}
+		if child := sessionPath(sess.Children, id, path+".children"); child != "" {
+			return child
+		}
+	}
+	return ""
+}
+
+// api target scope is shared to plugin containers in this file
+const EndpointsFile = "endpoints.json"
+
+// StepError is returned by AddSessions when a plan step can't be turned into session
+type StepError struct {
+	Plugin string
+	Msg    string // set for wrong step templates
+	Err    error
+}
+
+func (e *StepError) Error() string {
+	if e.Msg != "" {
+		return fmt.Sprintf("%s: %s: %s", e.Plugin, e.Msg, e.Err)
+	}
+	return fmt.Sprintf("%s: %s", e.Plugin, e.Err)
+}
+
+// AddSessions creates sessions for the scan from plan workflow steps.
+// Step templates are executed with the scan conf, so it should be set before.
+func (m *ScanManager) AddSessions(sc *scan.Scan, planObj *plan.Plan) error {
+	now := time.Now().UTC()
+	for _, step := range planObj.Workflow {
+		plugin, err := m.manager.Plugins.GetByName(step.Plugin)
+		if err != nil {
+			return &StepError{Plugin: step.Plugin, Err: err}
+		}
+		// TODO (m0sth8): extract template execution
+		if step.Conf != nil {
+			if command := step.Conf.CommandArgs; command != "" {
+				out, err := executeConf(command, sc.Conf)
+				if err != nil {
+					return &StepError{Plugin: step.Plugin, Msg: "Wrong command args template", Err: err}
+				}
+				step.Conf.CommandArgs = out
+			}
+			if formData := step.Conf.FormData; formData != "" {
+				out, err := executeConf(formData, sc.Conf)
+				if err != nil {
+					return &StepError{Plugin: step.Plugin, Msg: "Wrong form data template", Err: err}
+				}
+				step.Conf.FormData = out
+			}
+			if target := step.Conf.Target; target == "" {
+				step.Conf.Target = sc.Conf.Target
+			}
+		} else {
+			step.Conf = &plan.Conf{
+				Target: sc.Conf.Target,
+			}
+		}
+		// target politeness overrides whatever plan has
+		step.Conf.RateLimit = sc.Conf.RateLimit
+		step.Conf.Repo = sc.Conf.Repo
+		step.Conf.Proxy = sc.Conf.Proxy
+		step.Conf.Record = sc.Conf.Record
+		if len(sc.Conf.Endpoints) > 0 {
+			data, err := json.Marshal(sc.Conf.Endpoints)
+			if err != nil {
+				return &StepError{Plugin: step.Plugin, Err: err}
+			}
+			step.Conf.Endpoints = sc.Conf.Endpoints
+			step.Conf.SharedFiles = append(step.Conf.SharedFiles, &plan.SharedFile{Path: EndpointsFile, Text: string(data)})
+		}
+
+		sess := scan.Session{
+			Id:     m.manager.NewId(),
+			Step:   step,
+			Plugin: plugin.Id,
+			Status: scan.StatusCreated,
+			Dates: scan.Dates{
+				Created: &now,
+				Updated: &now,
+			},
+		}
+		sc.Sessions = append(sc.Sessions, &sess)
+	}
+	return nil
+}
+
+// UpcomingImages returns sorted images of plugins used by scans which aren't started yet
+// and are scheduled before until or aren't scheduled
+func (m *ScanManager) UpcomingImages(until time.Time) ([]string, error) {
+	scans, _, err := m.FilterByQuery(bson.M{
+		"status": scan.StatusCreated,
+		"$or": []bson.M{
+			{"scheduled": bson.M{"$lte": until}},
+			{"scheduled": bson.M{"$exists": false}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ids := []bson.ObjectId{}
+	for _, sc := range scans {
+		for _, sess := range sc.Sessions {
+			ids = append(ids, sess.Plugin)
+		}
+	}
+	images := []string{}
+	if len(ids) == 0 {
+		return images, nil
+	}
+	plugins, _, err := m.manager.Plugins.FilterByQuery(bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, pl := range plugins {
+		if pl.Container == nil || pl.Container.Image == "" || seen[pl.Container.Image] {
+			continue
+		}
+		seen[pl.Container.Image] = true
+		images = append(images, pl.Container.Image)
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+// NewScan builds a scan of the target with sessions from the plan, the scan isn't saved
+func (m *ScanManager) NewScan(owner bson.ObjectId, proj *project.Project, tgt *target.Target, planObj *plan.Plan) (*scan.Scan, error) {
+	if planObj.TargetType != tgt.Type {
+		return nil, fmt.Errorf("plan %s isn't compatible with target %s", planObj.Id.Hex(), tgt.Id.Hex())
+	}
+	sc := &scan.Scan{
+		Status:  scan.StatusCreated,
+		Owner:   owner,
+		Plan:    planObj.Id,
+		Project: proj.Id,
+		Target:  tgt.Id,
+		Conf: scan.ScanConf{
+			Target:    tgt.Addr(),
+			RateLimit: proj.Engagement.RateLimit(tgt.RateLimit.WithDefaults(proj.RateLimit)),
+			Proxy:     tgt.Proxy,
+			Record:    tgt.Record,
+		},
+		Sessions: []*scan.Session{},
+	}
+	if sc.Conf.Proxy == nil {
+		sc.Conf.Proxy = proj.Proxy
+	}
+	switch tgt.Type {
+	case target.TypeApi:
+		sc.Conf.Endpoints = tgt.Api.Endpoints
+	case target.TypeRepo:
+		sc.Conf.Repo = tgt.Repo
+	}
+	if err := m.AddSessions(sc, planObj); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+func executeConf(text string, conf scan.ScanConf) (string, error) {
+	t, err := template.New("").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := t.Execute(buf, conf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
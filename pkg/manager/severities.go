@@ -0,0 +1,55 @@
+package manager
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/plugin"
+)
+
+type SeverityMapManager struct {
+	manager *Manager
+	col     *mgo.Collection
+}
+
+func (m *SeverityMapManager) Init() error {
+	logrus.Infof("Initialize severity map indexes")
+	return m.col.EnsureIndex(mgo.Index{
+		Key:        []string{"plugin"},
+		Unique:     true,
+		Background: true,
+	})
+}
+
+// GetByPlugin returns severity map for plugin name, version is ignored if name is in format "name:version"
+func (m *SeverityMapManager) GetByPlugin(name string) (*plugin.SeverityMap, error) {
+	obj := &plugin.SeverityMap{}
+	return obj, m.manager.GetBy(m.col, &bson.M{"plugin": strings.Split(name, ":")[0]}, obj)
+}
+
+// Set creates or replaces severity map for the plugin
+func (m *SeverityMapManager) Set(name string, mapping map[string]issue.Severity) (*plugin.SeverityMap, error) {
+	obj := &plugin.SeverityMap{
+		Plugin:  strings.Split(name, ":")[0],
+		Mapping: map[string]issue.Severity{},
+		Updated: time.Now().UTC(),
+	}
+	for k, v := range mapping {
+		obj.Mapping[strings.ToLower(strings.TrimSpace(k))] = v
+	}
+	old, err := m.GetByPlugin(obj.Plugin)
+	if err != nil {
+		if !m.manager.IsNotFound(err) {
+			return nil, err
+		}
+		obj.Id = bson.NewObjectId()
+		return obj, m.col.Insert(obj)
+	}
+	obj.Id = old.Id
+	return obj, m.col.UpdateId(obj.Id, obj)
+}
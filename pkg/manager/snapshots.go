@@ -0,0 +1,106 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/stats"
+)
+
+type SnapshotManager struct {
+	manager *Manager
+	col     *mgo.Collection
+}
+
+func (s *SnapshotManager) Init() error {
+	logrus.Infof("Initialize stats snapshot indexes")
+	err := s.col.EnsureIndex(mgo.Index{
+		Key:        []string{"project", "date"},
+		Unique:     true,
+		Background: true,
+	})
+	if err != nil {
+		return err
+	}
+	return s.col.EnsureIndex(mgo.Index{
+		Key:        []string{"date"},
+		Background: true,
+	})
+}
+
+// Save replaces the snapshot of the project for the date
+func (m *SnapshotManager) Save(raw *stats.Snapshot) error {
+	raw.Created = time.Now().UTC()
+	info, err := m.col.Upsert(bson.M{"project": raw.Project, "date": raw.Date}, bson.M{
+		"$set": bson.M{
+			"total":    raw.Total,
+			"active":   raw.Active,
+			"status":   raw.Status,
+			"severity": raw.Severity,
+			"created":  raw.Created,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if id, ok := info.UpsertedId.(bson.ObjectId); ok {
+		raw.Id = id
+	}
+	return nil
+}
+
+// Taken returns projects which have snapshots for the date
+func (m *SnapshotManager) Taken(date string) (map[bson.ObjectId]bool, error) {
+	results := map[bson.ObjectId]bool{}
+	item := struct {
+		Project bson.ObjectId `bson:"project"`
+	}{}
+	iter := m.col.Find(bson.M{"date": date}).Select(bson.M{"project": 1}).Iter()
+	for iter.Next(&item) {
+		results[item.Project] = true
+	}
+	return results, iter.Close()
+}
+
+// Range returns snapshots for dates [from, to] sorted by date, snapshots of all projects if project is empty
+func (m *SnapshotManager) Range(project bson.ObjectId, from, to string) ([]*stats.Snapshot, error) {
+	query := bson.M{"date": bson.M{"$gte": from, "$lte": to}}
+	if project != "" {
+		query["project"] = project
+	}
+	results := []*stats.Snapshot{}
+	return results, m.col.Find(query).Sort("date", "project").All(&results)
+}
+
+// Snapshot counts issues of the project by status and severity, issues of unreviewed scans aren't counted
+func (m *IssueManager) Snapshot(project bson.ObjectId, date string) (*stats.Snapshot, error) {
+	result := stats.NewSnapshot(project, date)
+	groups := []struct {
+		Id struct {
+			Rank     int            `bson:"rank"`
+			Severity issue.Severity `bson:"severity"`
+		} `bson:"_id"`
+		Count int `bson:"count"`
+	}{}
+	err := m.col.Pipe([]bson.M{
+		{"$match": bson.M{
+			"project":     project,
+			"pendingScan": bson.M{"$exists": false},
+		}},
+		{"$group": bson.M{
+			"_id":   bson.M{"rank": "$statusRank", "severity": "$severity"},
+			"count": bson.M{"$sum": 1},
+		}},
+	}).All(&groups)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range groups {
+		result.Add(g.Id.Rank, g.Id.Severity, g.Count)
+	}
+	return result, nil
+}
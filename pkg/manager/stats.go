@@ -0,0 +1,139 @@
+package manager
+
+import (
+	"fmt"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/agent"
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/models/stats"
+)
+
+// Stats counts objects of the whole instance, scans are counted per day for the last days
+func (m *Manager) Stats(now time.Time, days int) (*stats.Stats, error) {
+	now = now.UTC()
+	s := &stats.Stats{Generated: now}
+	var err error
+
+	if s.Users, err = m.Users.col.Count(); err != nil {
+		return nil, err
+	}
+	if s.Projects, err = m.Projects.col.Count(); err != nil {
+		return nil, err
+	}
+	if s.Targets, err = m.Targets.col.Count(); err != nil {
+		return nil, err
+	}
+	if err = m.Issues.stats(&s.Issues); err != nil {
+		return nil, err
+	}
+	if err = m.Scans.stats(&s.Scans, now, days); err != nil {
+		return nil, err
+	}
+	if err = m.agentStats(&s.Agents, now); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (m *IssueManager) stats(s *stats.Issues) error {
+	var err error
+	if s.Total, err = m.col.Count(); err != nil {
+		return err
+	}
+	// open and confirmed issues have the lowest ranks
+	active := bson.M{"statusRank": bson.M{"$lte": issue.Status{Confirmed: true}.Rank()}}
+	if s.Active, err = m.col.Find(active).Count(); err != nil {
+		return err
+	}
+	groups := []struct {
+		Severity issue.Severity `bson:"_id"`
+		Count    int            `bson:"count"`
+	}{}
+	err = m.col.Pipe([]bson.M{
+		{"$match": active},
+		{"$group": bson.M{"_id": "$severity", "count": bson.M{"$sum": 1}}},
+	}).All(&groups)
+	if err != nil {
+		return err
+	}
+	s.Severity = map[issue.Severity]int{}
+	for _, g := range groups {
+		s.Severity[g.Severity] = g.Count
+	}
+	return nil
+}
+
+func (m *ScanManager) stats(s *stats.Scans, now time.Time, days int) error {
+	var err error
+	if s.Total, err = m.col.Count(); err != nil {
+		return err
+	}
+	if s.Queued, err = m.col.Find(bson.M{"status": scan.StatusQueued}).Count(); err != nil {
+		return err
+	}
+	if s.Working, err = m.col.Find(bson.M{"status": scan.StatusWorking}).Count(); err != nil {
+		return err
+	}
+	if days <= 0 {
+		return nil
+	}
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, 1-days)
+	groups := []struct {
+		Id struct {
+			Year  int `bson:"year"`
+			Month int `bson:"month"`
+			Day   int `bson:"day"`
+		} `bson:"_id"`
+		Count int `bson:"count"`
+	}{}
+	err = m.col.Pipe([]bson.M{
+		{"$match": bson.M{"dates.created": bson.M{"$gte": from}}},
+		{"$group": bson.M{
+			"_id": bson.M{
+				"year":  bson.M{"$year": "$dates.created"},
+				"month": bson.M{"$month": "$dates.created"},
+				"day":   bson.M{"$dayOfMonth": "$dates.created"},
+			},
+			"count": bson.M{"$sum": 1},
+		}},
+	}).All(&groups)
+	if err != nil {
+		return err
+	}
+	counts := map[string]int{}
+	for _, g := range groups {
+		counts[fmt.Sprintf("%04d-%02d-%02d", g.Id.Year, g.Id.Month, g.Id.Day)] = g.Count
+	}
+	s.SetDays(stats.NewDays(now, days, counts))
+	return nil
+}
+
+func (m *Manager) agentStats(s *stats.Agents, now time.Time) error {
+	var err error
+	if s.Total, err = m.Agents.col.Count(); err != nil {
+		return err
+	}
+	if s.Approved, err = m.Agents.col.Find(bson.M{"status": agent.StatusApproved}).Count(); err != nil {
+		return err
+	}
+	total := []struct {
+		AgentSeconds int `bson:"agentSeconds"`
+	}{}
+	err = m.Scans.col.Pipe([]bson.M{
+		{"$match": bson.M{"dates.created": bson.M{"$gte": now.Add(-24 * time.Hour)}}},
+		{"$group": bson.M{"_id": nil, "agentSeconds": bson.M{"$sum": "$usage.agentSeconds"}}},
+	}).All(&total)
+	if err != nil {
+		return err
+	}
+	seconds := 0
+	if len(total) > 0 {
+		seconds = total[0].AgentSeconds
+	}
+	s.SetUtilization(seconds)
+	return nil
+}
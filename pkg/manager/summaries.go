@@ -0,0 +1,130 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/target"
+)
+
+// builds which aren't finished during this time are taken again, e.g. after the instance is stopped
+const summaryStale = 5 * time.Minute
+
+type SummaryManager struct {
+	manager *Manager
+	col     *mgo.Collection
+}
+
+func (m *SummaryManager) Init() error {
+	logrus.Infof("Initialize target summary indexes")
+	return m.col.EnsureIndex(mgo.Index{
+		Key:        []string{"stale", "taken"},
+		Background: true,
+	})
+}
+
+// Touch marks summaries of targets stale, they are rebuilt by the summary engine.
+// It's cheap, so IssueManager calls it on every write instead of rebuilding summaries.
+func (m *SummaryManager) Touch(ids ...bson.ObjectId) error {
+	for _, id := range ids {
+		_, err := m.col.UpsertId(id, bson.M{
+			"$set": bson.M{"stale": true},
+			"$inc": bson.M{"version": 1},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Take marks the stale summary as taken for the build, returns ErrNotFound if there are no stale summaries
+func (m *SummaryManager) Take(now time.Time) (*target.Summary, error) {
+	obj := &target.Summary{}
+	_, err := m.col.Find(bson.M{"stale": true, "$or": []bson.M{
+		{"taken": bson.M{"$exists": false}},
+		{"taken": bson.M{"$lt": now.Add(-summaryStale)}},
+	}}).Apply(mgo.Change{
+		Update:    bson.M{"$set": bson.M{"taken": now}},
+		ReturnNew: true,
+	}, obj)
+	return obj, err
+}
+
+// Build counts open issues of the taken summary and saves them.
+// The summary stays stale if issues are changed during the build, so it's taken again.
+func (m *SummaryManager) Build(obj *target.Summary) error {
+	summary, risk, err := m.manager.Targets.GetSummaryIssues(obj.Target)
+	if err != nil {
+		return err
+	}
+	obj.Issues = summary
+	obj.Risk = risk
+	obj.Updated = time.Now().UTC()
+	err = m.col.Update(bson.M{"_id": obj.Target, "version": obj.Version}, bson.M{
+		"$set":   bson.M{"issues": obj.Issues, "risk": obj.Risk, "updated": obj.Updated, "stale": false},
+		"$unset": bson.M{"taken": ""},
+	})
+	if err == mgo.ErrNotFound {
+		obj.Stale = true
+		// the summary is removed with the target or changed during the build
+		if err := m.col.UpdateId(obj.Target, bson.M{"$unset": bson.M{"taken": ""}}); err != nil && err != mgo.ErrNotFound {
+			return err
+		}
+		return nil
+	}
+	obj.Stale = false
+	return err
+}
+
+// Rebuild builds the summary of the target right away
+func (m *SummaryManager) Rebuild(id bson.ObjectId) error {
+	obj := &target.Summary{}
+	_, err := m.col.FindId(id).Apply(mgo.Change{
+		Update:    bson.M{"$set": bson.M{"stale": true, "taken": time.Now().UTC()}, "$inc": bson.M{"version": 1}},
+		Upsert:    true,
+		ReturnNew: true,
+	}, obj)
+	if err != nil {
+		return err
+	}
+	return m.Build(obj)
+}
+
+// Fill sets summary reports of targets from their summaries,
+// targets without summaries keep reports which were saved with them before summaries were moved
+func (m *SummaryManager) Fill(targets ...*target.Target) error {
+	ids := make([]bson.ObjectId, len(targets))
+	for i, t := range targets {
+		ids[i] = t.Id
+	}
+	summaries := []*target.Summary{}
+	if err := m.col.Find(bson.M{"_id": bson.M{"$in": ids}}).All(&summaries); err != nil {
+		return err
+	}
+	byTarget := map[bson.ObjectId]*target.Summary{}
+	for _, s := range summaries {
+		byTarget[s.Target] = s
+	}
+	for _, t := range targets {
+		if s, ok := byTarget[t.Id]; ok && !s.Updated.IsZero() {
+			t.SummaryReport = s.Report()
+		}
+		if t.SummaryReport == nil {
+			t.SummaryReport = &target.SummaryReport{Issues: map[issue.Severity]int{}}
+		}
+	}
+	return nil
+}
+
+func (m *SummaryManager) Remove(id bson.ObjectId) error {
+	err := m.col.RemoveId(id)
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	return err
+}
@@ -9,15 +9,16 @@ import (
 
 	"github.com/bearded-web/bearded/models/issue"
 	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/models/tombstone"
 	"github.com/bearded-web/bearded/pkg/fltr"
-	"github.com/bearded-web/bearded/pkg/utils"
 )
 
 type TargetFltr struct {
-	Project bson.ObjectId     `fltr:"project,in"`
-	Type    target.TargetType `fltr:"type,in"`
-	Updated time.Time         `fltr:"updated,gte,lte"`
-	Created time.Time         `fltr:"created,gte,lte"`
+	Project     bson.ObjectId      `fltr:"project,in"`
+	Type        target.TargetType  `fltr:"type,in"`
+	Environment target.Environment `fltr:"environment,in"`
+	Updated     time.Time          `fltr:"updated,gte,lte"`
+	Created     time.Time          `fltr:"created,gte,lte"`
 }
 
 type TargetManager struct {
@@ -39,7 +40,7 @@ func (m *TargetManager) All() ([]*target.Target, int, error) {
 
 	query := &bson.M{}
 	q := m.col.Find(query)
-	if err := q.All(&results); err != nil {
+	if err := m.manager.all(q, &results); err != nil {
 		return nil, 0, err
 	}
 	count, err := q.Count()
@@ -65,6 +66,29 @@ func (m *TargetManager) FilterByQuery(query bson.M, opts ...Opts) ([]*target.Tar
 	return results, count, err
 }
 
+// Ids returns ids of targets matched by the query
+func (m *TargetManager) Ids(query bson.M) ([]bson.ObjectId, error) {
+	results := []struct {
+		Id bson.ObjectId `bson:"_id"`
+	}{}
+	if err := m.manager.all(m.col.Find(query).Select(bson.M{"_id": 1}), &results); err != nil {
+		return nil, err
+	}
+	ids := make([]bson.ObjectId, len(results))
+	for i, r := range results {
+		ids[i] = r.Id
+	}
+	return ids, nil
+}
+
+// Stream calls fn for every target of the query without loading all of them, see IssueManager.Stream
+func (m *TargetManager) Stream(query bson.M, opt Opts, fn func(*target.Target) error) error {
+	doc := func() interface{} { return &target.Target{} }
+	return m.manager.stream(m.col, query, opt, doc, func(raw interface{}) error {
+		return fn(raw.(*target.Target))
+	})
+}
+
 func (m *TargetManager) Create(raw *target.Target) (*target.Target, error) {
 	// TODO (m0sth8): add validation
 	raw.Id = bson.NewObjectId()
@@ -84,46 +108,50 @@ func (m *TargetManager) Update(obj *target.Target) error {
 	return m.col.UpdateId(obj.Id, obj)
 }
 
-func (m *TargetManager) Remove(obj *target.Target) error {
-	return m.col.RemoveId(obj.Id)
+// SetMonitor updates only monitor state of the target
+func (m *TargetManager) SetMonitor(id bson.ObjectId, state *target.MonitorState) error {
+	return m.col.UpdateId(id, bson.M{"$set": bson.M{"monitor": state}})
 }
 
-func (m *TargetManager) UpdateSummary(obj *target.Target) error {
-	summary, err := m.GetSummaryIssues(obj.Id)
-	if err != nil {
+func (m *TargetManager) Remove(obj *target.Target) error {
+	if err := m.col.RemoveId(obj.Id); err != nil {
 		return err
 	}
-	if obj.SummaryReport == nil {
-		obj.SummaryReport = &target.SummaryReport{}
+	m.manager.Tombstones.Add(tombstone.Target, obj.Id, obj.Project)
+	if err := m.manager.Summaries.Remove(obj.Id); err != nil {
+		logrus.Error(err)
 	}
-	obj.SummaryReport.Issues = summary
-	// TODO(m0sth8): update only summary field
-	return m.Update(obj)
+	return nil
 }
 
-func (m *TargetManager) GetSummaryIssues(targetId bson.ObjectId) (map[issue.Severity]int, error) {
-	fltr := &IssueFltr{
-		Target:   targetId,
-		False:    utils.BoolP(false),
-		Resolved: utils.BoolP(false),
-		Muted:    utils.BoolP(false),
-	}
-	// TODO(m0sth8): get only severity/count through aggregation
-	issues, _, err := m.manager.Issues.FilterBy(fltr)
+// GetSummaryIssues returns count of open issues by severity and max risk score of them
+func (m *TargetManager) GetSummaryIssues(targetId bson.ObjectId) (map[issue.Severity]int, int, error) {
+	groups := []struct {
+		Severity issue.Severity `bson:"_id"`
+		Count    int            `bson:"count"`
+		Risk     int            `bson:"risk"`
+	}{}
+	err := m.manager.Issues.col.Pipe([]bson.M{
+		{"$match": bson.M{
+			"target":   targetId,
+			"false":    false,
+			"resolved": false,
+			"muted":    false,
+			// issues of unreviewed scans aren't counted
+			"pendingScan": bson.M{"$exists": false},
+		}},
+		{"$group": bson.M{"_id": "$severity", "count": bson.M{"$sum": 1}, "risk": bson.M{"$max": "$risk"}}},
+	}).All(&groups)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	summary := map[issue.Severity]int{}
-	for _, issue := range issues {
-		summary[issue.Severity] = summary[issue.Severity] + 1
-	}
-	return summary, nil
-}
-
-func (m *TargetManager) UpdateSummaryById(id bson.ObjectId) error {
-	obj, err := m.GetById(id)
-	if err != nil {
-		return err
+	risk := 0
+	for _, g := range groups {
+		summary[g.Severity] = g.Count
+		if g.Risk > risk {
+			risk = g.Risk
+		}
 	}
-	return m.UpdateSummary(obj)
+	return summary, risk, nil
 }
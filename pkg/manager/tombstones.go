@@ -0,0 +1,78 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/tombstone"
+)
+
+// TombstoneTtl is how long tombstones are kept, sync tokens older than that are expired
+const TombstoneTtl = 30 * 24 * time.Hour
+
+type TombstoneManager struct {
+	manager *Manager
+	col     *mgo.Collection
+}
+
+func (s *TombstoneManager) Init() error {
+	logrus.Infof("Initialize tombstone indexes")
+	err := s.col.EnsureIndex(mgo.Index{
+		Key:        []string{"project", "removed"},
+		Background: true,
+	})
+	if err != nil {
+		return err
+	}
+	return s.col.EnsureIndex(mgo.Index{
+		Key:         []string{"removed"},
+		Background:  true,
+		ExpireAfter: TombstoneTtl,
+	})
+}
+
+// Add records removal of the entity, errors are only logged because the entity is already removed
+func (m *TombstoneManager) Add(kind tombstone.Kind, entity, project bson.ObjectId) {
+	obj := &tombstone.Tombstone{
+		Id:      bson.NewObjectId(),
+		Kind:    kind,
+		Entity:  entity,
+		Project: project,
+		Removed: time.Now().UTC(),
+	}
+	if err := m.col.Insert(obj); err != nil {
+		logrus.Errorf("Couldn't save tombstone for %s %s: %s", kind, entity.Hex(), err)
+	}
+}
+
+// AddAll records removal of entities of the kind, entities map ids to their projects.
+// Errors are only logged because entities are already removed.
+func (m *TombstoneManager) AddAll(kind tombstone.Kind, entities map[bson.ObjectId]bson.ObjectId) {
+	if len(entities) == 0 {
+		return
+	}
+	now := time.Now().UTC()
+	docs := make([]interface{}, 0, len(entities))
+	for entity, project := range entities {
+		docs = append(docs, &tombstone.Tombstone{
+			Id:      bson.NewObjectId(),
+			Kind:    kind,
+			Entity:  entity,
+			Project: project,
+			Removed: now,
+		})
+	}
+	if err := m.col.Insert(docs...); err != nil {
+		logrus.Errorf("Couldn't save %d tombstones for %s: %s", len(docs), kind, err)
+	}
+}
+
+// Since returns tombstones of the project created after the time
+func (m *TombstoneManager) Since(project bson.ObjectId, since time.Time) ([]*tombstone.Tombstone, error) {
+	results := []*tombstone.Tombstone{}
+	query := bson.M{"project": project, "removed": bson.M{"$gt": since}}
+	return results, m.manager.all(m.col.Find(query).Sort("removed"), &results)
+}
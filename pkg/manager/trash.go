@@ -0,0 +1,104 @@
+package manager
+
+import (
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+)
+
+// IssueTrashTtl is how long removed issues could be restored
+const IssueTrashTtl = 30 * 24 * time.Hour
+
+func (m *IssueManager) initTrash() error {
+	err := m.trash.EnsureIndex(mgo.Index{
+		Key:        []string{"project", "-removed"},
+		Background: true,
+	})
+	if err != nil {
+		return err
+	}
+	// expired issues were removed by the ttl index before, which left their comments and worklogs,
+	// now they are purged by the cleanup engine. The error is ignored, new databases don't have the index.
+	m.trash.DropIndex("removed")
+	return m.trash.EnsureIndex(mgo.Index{
+		Key:        []string{"removed"},
+		Background: true,
+	})
+}
+
+// Trash moves the issue to the trash, it's removed the same way as by Remove
+func (m *IssueManager) Trash(obj *issue.TargetIssue, user bson.ObjectId) error {
+	trashed := &issue.TrashedIssue{
+		TargetIssue: *obj,
+		Removed:     time.Now().UTC(),
+		RemovedBy:   user,
+	}
+	if _, err := m.trash.UpsertId(obj.Id, trashed); err != nil {
+		return err
+	}
+	return m.Remove(obj)
+}
+
+func (m *IssueManager) GetTrashed(id bson.ObjectId) (*issue.TrashedIssue, error) {
+	obj := &issue.TrashedIssue{}
+	if err := m.manager.GetById(m.trash, id, &obj); err != nil {
+		return nil, err
+	}
+	obj.Expires = obj.Removed.Add(IssueTrashTtl)
+	return obj, nil
+}
+
+// FilterTrash returns removed issues matched by the query
+func (m *IssueManager) FilterTrash(query bson.M, opts ...Opts) ([]*issue.TrashedIssue, int, error) {
+	results := []*issue.TrashedIssue{}
+	count, err := m.manager.FilterBy(m.trash, &query, &results, opts...)
+	for _, obj := range results {
+		obj.Expires = obj.Removed.Add(IssueTrashTtl)
+	}
+	return results, count, err
+}
+
+// Restore moves the issue back from the trash. Inverse links are returned to linked issues
+// which still exist. Use IsDup to check if the same issue was reported again after removal.
+func (m *IssueManager) Restore(trashed *issue.TrashedIssue) (*issue.TargetIssue, error) {
+	obj := &trashed.TargetIssue
+	now := time.Now().UTC()
+	// sync clients get the restored issue as updated
+	obj.Updated = now
+	if err := m.col.Insert(obj); err != nil {
+		return nil, err
+	}
+	links := []*issue.Link{}
+	for _, l := range obj.Links {
+		back := &issue.Link{Type: l.Type.Inverse(), Issue: obj.Id, Target: obj.Target, User: l.User, Created: l.Created}
+		err := m.col.UpdateId(l.Issue, bson.M{"$push": bson.M{"links": back}, "$set": bson.M{"updated": now}})
+		if err == nil {
+			links = append(links, l)
+		} else if err != mgo.ErrNotFound {
+			return nil, err
+		}
+	}
+	if len(links) != len(obj.Links) {
+		obj.Links = links
+		if err := m.col.UpdateId(obj.Id, bson.M{"$set": bson.M{"links": links}}); err != nil {
+			return nil, err
+		}
+	}
+	m.invalidate()
+	m.touch(obj.Target)
+	return obj, m.trash.RemoveId(trashed.Id)
+}
+
+// Purge removes the issue from the trash permanently with its comments and worklogs
+func (m *IssueManager) Purge(trashed *issue.TrashedIssue) error {
+	_, err := m.manager.Cascades.removeTrash(bson.M{"_id": trashed.Id})
+	return err
+}
+
+// PurgeExpired purges issues which were removed longer than the trash ttl ago, returns count of purged issues
+func (m *IssueManager) PurgeExpired(now time.Time) (int, error) {
+	return m.manager.Cascades.removeTrash(bson.M{"removed": bson.M{"$lt": now.Add(-IssueTrashTtl)}})
+}
@@ -0,0 +1,246 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/scan"
+)
+
+// QuotaError is returned when the project quota doesn't allow to create or start something
+type QuotaError struct {
+	Msg string
+	// Rate quotas are restored with time, others require a bigger quota
+	Rate bool
+}
+
+func (e *QuotaError) Error() string {
+	return e.Msg
+}
+
+func IsQuota(err error) bool {
+	_, ok := err.(*QuotaError)
+	return ok
+}
+
+// Usage sums resources consumed by project scans created in the period [from, to),
+// zero times aren't limited
+func (m *ScanManager) Usage(projectId bson.ObjectId, from, to time.Time) (*scan.UsageReport, error) {
+	match := bson.M{"project": projectId}
+	created := bson.M{}
+	if !from.IsZero() {
+		created["$gte"] = from
+	}
+	if !to.IsZero() {
+		created["$lt"] = to
+	}
+	if len(created) > 0 {
+		match["dates.created"] = created
+	}
+	report := &scan.UsageReport{Project: projectId, From: from, To: to, Plugins: []*scan.PluginUsage{}}
+
+	total := []struct {
+		Scans        int `bson:"scans"`
+		AgentSeconds int `bson:"agentSeconds"`
+	}{}
+	err := m.col.Pipe([]bson.M{
+		{"$match": match},
+		{"$group": bson.M{
+			"_id":          nil,
+			"scans":        bson.M{"$sum": 1},
+			"agentSeconds": bson.M{"$sum": "$usage.agentSeconds"},
+		}},
+	}).All(&total)
+	if err != nil {
+		return nil, err
+	}
+	if len(total) > 0 {
+		report.Scans = total[0].Scans
+		report.AgentSeconds = total[0].AgentSeconds
+	}
+
+	err = m.col.Pipe([]bson.M{
+		{"$match": match},
+		{"$unwind": "$usage.plugins"},
+		{"$group": bson.M{
+			"_id":      "$usage.plugins.plugin",
+			"seconds":  bson.M{"$sum": "$usage.plugins.seconds"},
+			"sessions": bson.M{"$sum": "$usage.plugins.sessions"},
+		}},
+		{"$project": bson.M{"_id": 0, "plugin": "$_id", "seconds": 1, "sessions": 1}},
+		{"$sort": bson.M{"seconds": -1}},
+	}).All(&report.Plugins)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// CheckMinutes returns QuotaError if agent minutes of the project are used in this month
+func (m *ScanManager) CheckMinutes(p *project.Project) error {
+	quota := p.GetQuota(m.manager.Cfg.Quota)
+	if quota.AgentMinutes <= 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	usage, err := m.Usage(p.Id, month, time.Time{})
+	if err != nil {
+		return err
+	}
+	if usage.AgentSeconds >= quota.AgentMinutes*60 {
+		return &QuotaError{Msg: fmt.Sprintf("%d agent minutes per month are used", quota.AgentMinutes)}
+	}
+	return nil
+}
+
+// CheckRunning returns QuotaError if the project has max running scans already
+func (m *ScanManager) CheckRunning(p *project.Project) error {
+	quota := p.GetQuota(m.manager.Cfg.Quota)
+	if quota.MaxScans <= 0 {
+		return nil
+	}
+	running, err := m.Running(p.Id)
+	if err != nil {
+		return err
+	}
+	if running >= quota.MaxScans {
+		return &QuotaError{Msg: fmt.Sprintf("max %d scans could run at the same time", quota.MaxScans)}
+	}
+	return nil
+}
+
+// Running returns count of queued and working scans of the project
+func (m *ScanManager) Running(projectId bson.ObjectId) (int, error) {
+	return m.col.Find(bson.M{
+		"project": projectId,
+		"status":  bson.M{"$in": []scan.ScanStatus{scan.StatusQueued, scan.StatusWorking}},
+	}).Count()
+}
+
+// CreatedToday returns count of project scans created since the beginning of the UTC day
+func (m *ScanManager) CreatedToday(projectId bson.ObjectId) (int, error) {
+	now := time.Now().UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return m.col.Find(bson.M{"project": projectId, "dates.created": bson.M{"$gte": day}}).Count()
+}
+
+// CheckDaily returns QuotaError if the project has created max scans since the beginning of the UTC day
+func (m *ScanManager) CheckDaily(p *project.Project) error {
+	quota := p.GetQuota(m.manager.Cfg.Quota)
+	if quota.ScansPerDay <= 0 {
+		return nil
+	}
+	created, err := m.CreatedToday(p.Id)
+	if err != nil {
+		return err
+	}
+	if created >= quota.ScansPerDay {
+		return &QuotaError{Msg: fmt.Sprintf("%d scans per day are created", quota.ScansPerDay), Rate: true}
+	}
+	return nil
+}
+
+// Count returns count of project targets
+func (m *TargetManager) Count(projectId bson.ObjectId) (int, error) {
+	return m.col.Find(bson.M{"project": projectId}).Count()
+}
+
+// CheckCount returns QuotaError if the project has max targets already
+func (m *TargetManager) CheckCount(p *project.Project) error {
+	return m.CheckAdd(p, 1)
+}
+
+// CheckAdd returns QuotaError if n targets can't be added to the project, the project could be unsaved yet
+func (m *TargetManager) CheckAdd(p *project.Project, n int) error {
+	quota := p.GetQuota(m.manager.Cfg.Quota)
+	if quota.Targets <= 0 {
+		return nil
+	}
+	count := 0
+	if p.Id != "" {
+		var err error
+		if count, err = m.Count(p.Id); err != nil {
+			return err
+		}
+	}
+	if count+n > quota.Targets {
+		return &QuotaError{Msg: fmt.Sprintf("max %d targets could be added to the project", quota.Targets)}
+	}
+	return nil
+}
+
+// ProjectSize returns bytes of files uploaded to the project, thumbnails are included
+func (m *FileManager) ProjectSize(projectId bson.ObjectId) (int, error) {
+	total := []struct {
+		Size int `bson:"size"`
+	}{}
+	err := m.grid.Files.Pipe([]bson.M{
+		{"$match": bson.M{"metadata.project": projectId}},
+		{"$group": bson.M{"_id": nil, "size": bson.M{"$sum": "$length"}}},
+	}).All(&total)
+	if err != nil || len(total) == 0 {
+		return 0, err
+	}
+	return total[0].Size, nil
+}
+
+// StorageLimit returns QuotaError if uploaded files of the project take the whole storage quota,
+// otherwise the data is limited to the remaining storage, so one upload can't go past the quota
+func (m *FileManager) StorageLimit(p *project.Project, r io.Reader) (*StorageReader, error) {
+	limited := &StorageReader{r: r, left: -1}
+	quota := p.GetQuota(m.manager.Cfg.Quota)
+	if quota.Storage <= 0 {
+		return limited, nil
+	}
+	size, err := m.ProjectSize(p.Id)
+	if err != nil {
+		return nil, err
+	}
+	quotaErr := &QuotaError{Msg: fmt.Sprintf("%d MB of project storage are used", quota.Storage)}
+	if size >= quota.Storage<<20 {
+		return nil, quotaErr
+	}
+	limited.left = int64(quota.Storage<<20 - size)
+	limited.quotaErr = quotaErr
+	return limited, nil
+}
+
+// StorageReader fails with QuotaError when the data exceeds the remaining project storage
+type StorageReader struct {
+	r        io.Reader
+	left     int64 // negative if the storage isn't limited
+	quotaErr *QuotaError
+	err      error
+}
+
+func (l *StorageReader) Read(p []byte) (int, error) {
+	if l.err != nil {
+		return 0, l.err
+	}
+	if l.left < 0 {
+		return l.r.Read(p)
+	}
+	// one byte more is read to tell the data of the remaining size from the bigger one
+	if int64(len(p)) > l.left+1 {
+		p = p[:l.left+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) <= l.left {
+		l.left -= int64(n)
+		return n, err
+	}
+	n = int(l.left)
+	l.left = 0
+	l.err = l.quotaErr
+	return n, l.err
+}
+
+// Err returns QuotaError if the data exceeded the storage, readers like multipart forms wrap read errors
+func (l *StorageReader) Err() error {
+	return l.err
+}
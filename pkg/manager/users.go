@@ -24,6 +24,7 @@ type UserManager struct {
 type UserFltr struct {
 	Id      bson.ObjectId `fltr:"id,in" bson:"_id"`
 	Email   string        `fltr:"email"`
+	Status  user.Status   `fltr:"status,in"`
 	Created time.Time     `fltr:"created,gte,lte"`
 }
 
@@ -94,7 +95,7 @@ func (m *UserManager) All() ([]*user.User, int, error) {
 
 	query := &bson.M{}
 	q := m.col.Find(query)
-	if err := q.All(&results); err != nil {
+	if err := m.manager.all(q, &results); err != nil {
 		return nil, 0, err
 	}
 	count, err := q.Count()
@@ -117,12 +118,38 @@ func (m *UserManager) FilterByQuery(query bson.M, opts ...Opts) ([]*user.User, i
 	return results, count, err
 }
 
+// Away returns users from the list who are out of office at the moment
+func (m *UserManager) Away(ids []bson.ObjectId, now time.Time) (map[bson.ObjectId]bool, error) {
+	away := map[bson.ObjectId]bool{}
+	if len(ids) == 0 {
+		return away, nil
+	}
+	results := []struct {
+		Id bson.ObjectId `bson:"_id"`
+	}{}
+	err := m.col.Find(bson.M{
+		"_id":              bson.M{"$in": ids},
+		"outOfOffice.from": bson.M{"$lte": now},
+		"outOfOffice.to":   bson.M{"$gt": now},
+	}).Select(bson.M{"_id": 1}).All(&results)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		away[r.Id] = true
+	}
+	return away, nil
+}
+
 func (m *UserManager) Create(raw *user.User) (*user.User, error) {
 	// TODO (m0sth8): add validation
 	raw.Id = bson.NewObjectId()
 	raw.Created = time.Now().UTC()
 	raw.Updated = raw.Created
 	raw.Avatar = utils.GetGravatar(raw.Email, 38, utils.AvatarRetro)
+	if raw.Status == "" {
+		raw.Status = user.StatusActive
+	}
 	if raw.Nickname == "" {
 		raw.Nickname = strings.Split(raw.Email, "@")[0]
 	}
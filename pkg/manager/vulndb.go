@@ -1,12 +1,24 @@
 package manager
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/bearded-web/bearded/models/issue"
 	"github.com/bearded-web/bearded/models/vuln"
 	vulndb "github.com/vulndb/vulndb-go"
 	"github.com/vulndb/vulndb-go/bindata"
 )
 
+// fields which could be populated by Enrich
+const (
+	EnrichedDesc        = "desc"
+	EnrichedRemediation = "remediation"
+	EnrichedReferences  = "references"
+)
+
+const cveUrl = "https://nvd.nist.gov/vuln/detail/%s"
+
 type VulndbManager struct {
 	manager *Manager
 
@@ -39,6 +51,47 @@ func (m *VulndbManager) GetById(id int) *vuln.Vuln {
 	return nil
 }
 
+// Enrich populates empty description, remediation and references of the issue
+// from vulndb entry and links to CVE. Populated fields are added to obj.Enriched.
+func (m *VulndbManager) Enrich(obj *issue.Issue) {
+	enriched := map[string]bool{}
+	if obj.VulnType != 0 {
+		if v := m.GetById(obj.VulnType); v != nil {
+			if obj.Desc == "" && v.Description != "" {
+				obj.Desc = v.Description
+				enriched[EnrichedDesc] = true
+			}
+			if obj.Remediation == "" && v.Fix.Guidance != "" {
+				obj.Remediation = v.Fix.Guidance
+				enriched[EnrichedRemediation] = true
+			}
+			for _, ref := range v.References {
+				if ref.Url == "" || obj.HasReference(ref.Url) {
+					continue
+				}
+				obj.References = append(obj.References, &issue.Reference{Url: ref.Url, Title: ref.Title})
+				enriched[EnrichedReferences] = true
+			}
+		}
+	}
+	for _, cve := range obj.Cve {
+		url := fmt.Sprintf(cveUrl, strings.ToUpper(cve))
+		if obj.HasReference(url) {
+			continue
+		}
+		obj.References = append(obj.References, &issue.Reference{Url: url, Title: strings.ToUpper(cve)})
+		enriched[EnrichedReferences] = true
+	}
+	for _, field := range obj.Enriched {
+		delete(enriched, field)
+	}
+	for _, field := range []string{EnrichedDesc, EnrichedRemediation, EnrichedReferences} {
+		if enriched[field] {
+			obj.Enriched = append(obj.Enriched, field)
+		}
+	}
+}
+
 func (m *VulndbManager) Copy(new *VulndbManager) {
 	new.vulnList = m.vulnList
 }
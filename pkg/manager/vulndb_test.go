@@ -0,0 +1,47 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/vuln"
+)
+
+func TestVulndbEnrich(t *testing.T) {
+	m := &VulndbManager{vulnList: []*vuln.Vuln{
+		&vuln.Vuln{
+			Id:          1,
+			Description: "vulndb desc",
+			Fix:         vuln.VulnFix{Guidance: "fix it"},
+			References: []vuln.Reference{
+				{Url: "http://example.com/1", Title: "first"},
+				{Url: "http://example.com/2", Title: "second"},
+			},
+		},
+	}}
+
+	obj := &issue.Issue{
+		VulnType:   1,
+		Desc:       "plugin desc",
+		References: []*issue.Reference{{Url: "http://example.com/1"}},
+		Cve:        []string{"cve-2014-0160"},
+	}
+	m.Enrich(obj)
+	assert.Equal(t, "plugin desc", obj.Desc)
+	assert.Equal(t, "fix it", obj.Remediation)
+	assert.Len(t, obj.References, 3)
+	assert.Equal(t, "https://nvd.nist.gov/vuln/detail/CVE-2014-0160", obj.References[2].Url)
+	assert.Equal(t, []string{EnrichedRemediation, EnrichedReferences}, obj.Enriched)
+
+	// second call changes nothing
+	m.Enrich(obj)
+	assert.Len(t, obj.References, 3)
+	assert.Equal(t, []string{EnrichedRemediation, EnrichedReferences}, obj.Enriched)
+
+	// unknown vuln type
+	obj = &issue.Issue{VulnType: 2}
+	m.Enrich(obj)
+	assert.Empty(t, obj.Enriched)
+}
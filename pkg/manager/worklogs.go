@@ -0,0 +1,135 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/worklog"
+	"github.com/bearded-web/bearded/pkg/fltr"
+)
+
+type WorklogManager struct {
+	manager *Manager
+	col     *mgo.Collection
+}
+
+type WorklogFltr struct {
+	Issue   bson.ObjectId `fltr:"issue"`
+	Project bson.ObjectId `fltr:"project"`
+	User    bson.ObjectId `fltr:"user"`
+	Date    time.Time     `fltr:"date,gte,gt,lte,lt"`
+}
+
+func (s *WorklogManager) Init() error {
+	logrus.Infof("Initialize worklog indexes")
+	err := s.col.EnsureIndex(mgo.Index{
+		Key:        []string{"project", "date"},
+		Background: true,
+	})
+	if err != nil {
+		return err
+	}
+	for _, index := range []string{"issue", "user"} {
+		err := s.col.EnsureIndex(mgo.Index{
+			Key:        []string{index},
+			Background: true,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *WorklogManager) Fltr() *WorklogFltr {
+	return &WorklogFltr{}
+}
+
+func (m *WorklogManager) GetById(id bson.ObjectId) (*worklog.Worklog, error) {
+	u := &worklog.Worklog{}
+	return u, m.manager.GetById(m.col, id, &u)
+}
+
+func (m *WorklogManager) FilterBy(f *WorklogFltr, opts ...Opts) ([]*worklog.Worklog, int, error) {
+	query := fltr.GetQuery(f)
+	return m.FilterByQuery(query, opts...)
+}
+
+func (m *WorklogManager) FilterByQuery(query bson.M, opts ...Opts) ([]*worklog.Worklog, int, error) {
+	results := []*worklog.Worklog{}
+	count, err := m.manager.FilterBy(m.col, &query, &results, opts...)
+	return results, count, err
+}
+
+func (m *WorklogManager) Create(raw *worklog.Worklog) (*worklog.Worklog, error) {
+	raw.Id = bson.NewObjectId()
+	raw.Created = time.Now().UTC()
+	raw.Updated = raw.Created
+	if raw.Date.IsZero() {
+		raw.Date = raw.Created
+	}
+	m.sanitize(raw)
+	if err := m.col.Insert(raw); err != nil {
+		return nil, err
+	}
+	return raw, nil
+}
+
+func (m *WorklogManager) Update(obj *worklog.Worklog) error {
+	obj.Updated = time.Now().UTC()
+	m.sanitize(obj)
+	return m.col.UpdateId(obj.Id, obj)
+}
+
+func (m *WorklogManager) Remove(obj *worklog.Worklog) error {
+	return m.col.RemoveId(obj.Id)
+}
+
+// Effort sums worklog durations of the project in the period [from, to), zero times aren't limited
+func (m *WorklogManager) Effort(project bson.ObjectId, from, to time.Time) (*worklog.Effort, error) {
+	match := bson.M{"project": project}
+	date := bson.M{}
+	if !from.IsZero() {
+		date["$gte"] = from
+	}
+	if !to.IsZero() {
+		date["$lt"] = to
+	}
+	if len(date) > 0 {
+		match["date"] = date
+	}
+	effort := &worklog.Effort{Project: project, From: from, To: to}
+	var err error
+	if effort.Users, err = m.spent(match, "$user"); err != nil {
+		return nil, err
+	}
+	if effort.Issues, err = m.spent(match, "$issue"); err != nil {
+		return nil, err
+	}
+	for _, s := range effort.Users {
+		effort.Duration += s.Duration
+	}
+	return effort, nil
+}
+
+// spent groups matched worklogs by the field, the biggest durations go first
+func (m *WorklogManager) spent(match bson.M, field string) ([]*worklog.Spent, error) {
+	results := []*worklog.Spent{}
+	err := m.col.Pipe([]bson.M{
+		{"$match": match},
+		{"$group": bson.M{
+			"_id":      field,
+			"duration": bson.M{"$sum": "$duration"},
+			"entries":  bson.M{"$sum": 1},
+		}},
+		{"$sort": bson.M{"duration": -1}},
+	}).All(&results)
+	return results, err
+}
+
+func (m *WorklogManager) sanitize(obj *worklog.Worklog) {
+	obj.Note = m.manager.Cfg.Sanitizer.Sanitize(obj.Note)
+}
@@ -0,0 +1,65 @@
+package managertest
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/agent"
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/pkg/scheduler"
+	"github.com/bearded-web/bearded/pkg/template"
+)
+
+// Scheduler keeps all added and updated scans in memory
+type Scheduler struct {
+	mu      sync.Mutex
+	Added   []*scan.Scan
+	Updated []*scan.Scan
+}
+
+// Check compile time interface compatibilities
+var _ scheduler.Scheduler = (*Scheduler)(nil)
+
+func (s *Scheduler) AddScan(sc *scan.Scan) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Added = append(s.Added, sc)
+	return nil
+}
+
+func (s *Scheduler) UpdateScan(sc *scan.Scan) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Updated = append(s.Updated, sc)
+	return nil
+}
+
+func (s *Scheduler) GetSession(bson.ObjectId, agent.Load) (*scan.Session, error) {
+	return nil, nil
+}
+
+// Rendered template call
+type Rendered struct {
+	Name    string
+	Binding interface{}
+}
+
+// Renderer doesn't render anything, it writes template name and remembers the binding
+type Renderer struct {
+	mu       sync.Mutex
+	Rendered []*Rendered
+}
+
+// Check compile time interface compatibilities
+var _ template.Renderer = (*Renderer)(nil)
+
+func (r *Renderer) Render(wr io.Writer, name string, binding interface{}, opts ...template.RenderOptions) error {
+	r.mu.Lock()
+	r.Rendered = append(r.Rendered, &Rendered{Name: name, Binding: binding})
+	r.mu.Unlock()
+	_, err := fmt.Fprint(wr, name)
+	return err
+}
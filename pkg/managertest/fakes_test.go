@@ -0,0 +1,29 @@
+package managertest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bearded-web/bearded/models/scan"
+)
+
+func TestScheduler(t *testing.T) {
+	s := &Scheduler{}
+	sc := &scan.Scan{Status: scan.StatusCreated}
+	require.NoError(t, s.AddScan(sc))
+	require.NoError(t, s.UpdateScan(sc))
+	assert.Equal(t, []*scan.Scan{sc}, s.Added)
+	assert.Equal(t, []*scan.Scan{sc}, s.Updated)
+}
+
+func TestRenderer(t *testing.T) {
+	r := &Renderer{}
+	buf := &bytes.Buffer{}
+	require.NoError(t, r.Render(buf, "email/reset-password", 1))
+	assert.Equal(t, "email/reset-password", buf.String())
+	require.Len(t, r.Rendered, 1)
+	assert.Equal(t, 1, r.Rendered[0].Binding)
+}
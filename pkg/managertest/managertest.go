@@ -0,0 +1,126 @@
+// Package managertest helps to write handler tests for services.
+//
+// Scheduler, mailer and templates are replaced with in-memory fakes.
+// Managers work with the in-memory mongodb from the memdb package,
+// so tests don't need a running mongodb. Everything is dropped on Close.
+package managertest
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emicklei/go-restful"
+	"gopkg.in/mgo.v2"
+
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/config"
+	"github.com/bearded-web/bearded/pkg/email"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/managertest/memdb"
+	"github.com/bearded-web/bearded/pkg/passlib"
+	"github.com/bearded-web/bearded/services"
+)
+
+// Env contains everything required for testing services
+type Env struct {
+	Mgr       *manager.Manager
+	Base      *services.BaseService
+	Scheduler *Scheduler
+	Mailer    *email.MemoryBackend
+	Renderer  *Renderer
+	Session   *filters.Session
+
+	db    *memdb.Server
+	mongo *mgo.Session
+}
+
+const dbName = "bearded-test"
+
+// Start in-memory database and create base service with fakes.
+// Don't forget to call Close after.
+func New(cfg ...config.Api) (*Env, error) {
+	db, err := memdb.New()
+	if err != nil {
+		return nil, err
+	}
+	mongo, err := db.Dial(dbName)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	apiCfg := config.NewDispatcher().Api
+	if len(cfg) > 0 {
+		apiCfg = cfg[0]
+	}
+	env := &Env{
+		Mgr:       manager.New(mongo.DB(dbName)),
+		Scheduler: &Scheduler{},
+		Mailer:    email.NewMemoryBackend(100),
+		Renderer:  &Renderer{},
+		Session:   filters.NewSession(),
+		db:        db,
+		mongo:     mongo,
+	}
+	if err := env.Mgr.Init(); err != nil {
+		env.Close()
+		return nil, err
+	}
+	env.Base = services.New(env.Mgr, passlib.NewContext(), env.Scheduler, env.Mailer, apiCfg)
+	env.Base.Template = env.Renderer
+	return env, nil
+}
+
+// Open works like New, but fails the test on errors
+func Open(t testing.TB, cfg ...config.Api) *Env {
+	env, err := New(cfg...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return env
+}
+
+// Create user and authenticate it in the session used by container
+func (e *Env) Login(u *user.User) (*user.User, error) {
+	obj, err := e.Mgr.Users.Create(u)
+	if err != nil {
+		return nil, err
+	}
+	e.Session.Set(filters.SessionUserKey, obj.Id.Hex())
+	return obj, nil
+}
+
+// Remove user from the session
+func (e *Env) Logout() {
+	e.Session.Del(filters.SessionUserKey)
+}
+
+// Create restful container with mocked session and register services there
+func (e *Env) Container(srvs ...services.ServiceInterface) (*restful.Container, error) {
+	wsContainer := restful.NewContainer()
+	wsContainer.Router(restful.CurlyRouter{})
+	wsContainer.Filter(filters.SessionFilterMock(e.Session))
+	for _, s := range srvs {
+		if err := s.Init(); err != nil {
+			return nil, err
+		}
+		s.Register(wsContainer)
+	}
+	return wsContainer, nil
+}
+
+// Create container with services and start the test http server.
+// Don't forget to close the server.
+func (e *Env) Server(srvs ...services.ServiceInterface) (*httptest.Server, error) {
+	wsContainer, err := e.Container(srvs...)
+	if err != nil {
+		return nil, err
+	}
+	return httptest.NewServer(wsContainer), nil
+}
+
+// Close the session and drop all data
+func (e *Env) Close() {
+	e.mongo.Close()
+	e.db.Close()
+}
@@ -0,0 +1,22 @@
+package managertest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/filters"
+)
+
+func TestOpen(t *testing.T) {
+	env := Open(t)
+	defer env.Close()
+
+	u, err := env.Login(&user.User{Email: "user@example.com"})
+	require.NoError(t, err)
+	id, ok := env.Session.Get(filters.SessionUserKey)
+	assert.True(t, ok)
+	assert.Equal(t, u.Id.Hex(), id)
+}
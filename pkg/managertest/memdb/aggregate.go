@@ -0,0 +1,574 @@
+package memdb
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+func (s *Server) runStage(col *collection, docs []bson.D, stage bson.DocElem) ([]bson.D, error) {
+	switch stage.Name {
+	case "$match":
+		q, _ := stage.Value.(bson.D)
+		m := s.matcher(col, q)
+		res := []bson.D{}
+		for _, doc := range docs {
+			ok, err := m.match(doc)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				res = append(res, doc)
+			}
+		}
+		return res, nil
+	case "$project":
+		spec, _ := stage.Value.(bson.D)
+		return projectStage(docs, spec)
+	case "$group":
+		spec, _ := stage.Value.(bson.D)
+		return groupStage(docs, spec)
+	case "$sort":
+		spec, _ := stage.Value.(bson.D)
+		return docs, sortDocs(docs, spec)
+	case "$skip":
+		n, _ := toFloat(stage.Value)
+		if int(n) >= len(docs) {
+			return []bson.D{}, nil
+		}
+		return docs[int(n):], nil
+	case "$limit":
+		n, _ := toFloat(stage.Value)
+		if n <= 0 {
+			return nil, errorf(15958, "the limit must be positive")
+		}
+		if int(n) < len(docs) {
+			docs = docs[:int(n)]
+		}
+		return docs, nil
+	case "$unwind":
+		return unwindStage(docs, stage.Value)
+	}
+	return nil, errorf(16436, "Unrecognized pipeline stage name: '%s'", stage.Name)
+}
+
+func projectStage(docs []bson.D, spec bson.D) ([]bson.D, error) {
+	res := make([]bson.D, 0, len(docs))
+	for _, doc := range docs {
+		e := &evaluator{}
+		var out interface{} = bson.D{}
+		if v, ok := get(spec, "_id"); !ok || isInclusion(v) && truthy(v) {
+			if id, ok := get(doc, "_id"); ok {
+				out = bson.D{{Name: "_id", Value: id}}
+			}
+		}
+		for _, f := range spec {
+			path := splitPath(f.Name)
+			var v interface{}
+			var ok bool
+			if isInclusion(f.Value) {
+				if f.Name == "_id" || !truthy(f.Value) {
+					continue
+				}
+				v, ok = getPath(doc, path)
+			} else {
+				v, ok = e.eval(f.Value, doc)
+			}
+			if e.err != nil {
+				return nil, e.err
+			}
+			if !ok {
+				continue
+			}
+			var err error
+			if out, err = setPath(out, path, v); err != nil {
+				return nil, err
+			}
+		}
+		res = append(res, out.(bson.D))
+	}
+	return res, nil
+}
+
+// isInclusion reports whether the projected value includes or excludes the field
+func isInclusion(v interface{}) bool {
+	_, isBool := v.(bool)
+	return isBool || isNumber(v)
+}
+
+// group collects accumulated values of documents with the same id
+type group struct {
+	id   interface{}
+	accs []*accumulator
+}
+
+type accumulator struct {
+	name  string
+	op    string
+	arg   interface{}
+	value interface{}
+	count int
+	set   bool
+	list  []interface{}
+}
+
+func groupStage(docs []bson.D, spec bson.D) ([]bson.D, error) {
+	idExpr, ok := get(spec, "_id")
+	if !ok {
+		return nil, errorf(15955, "a group specification must include an _id")
+	}
+	groups := map[string]*group{}
+	order := []*group{}
+	e := &evaluator{}
+	for _, doc := range docs {
+		id, _ := e.eval(idExpr, doc)
+		key, err := valueKey(id)
+		if err != nil {
+			return nil, err
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = &group{id: id}
+			for _, f := range spec {
+				if f.Name == "_id" {
+					continue
+				}
+				acc, ok := f.Value.(bson.D)
+				if !ok || len(acc) != 1 {
+					return nil, errorf(15951, "the group aggregate field '%s' must be defined as an expression inside an object", f.Name)
+				}
+				g.accs = append(g.accs, &accumulator{name: f.Name, op: acc[0].Name, arg: acc[0].Value})
+			}
+			groups[key] = g
+			order = append(order, g)
+		}
+		for _, acc := range g.accs {
+			v, ok := e.eval(acc.arg, doc)
+			if err := acc.add(v, ok); err != nil {
+				return nil, err
+			}
+		}
+		if e.err != nil {
+			return nil, e.err
+		}
+	}
+	res := make([]bson.D, len(order))
+	for i, g := range order {
+		doc := bson.D{{Name: "_id", Value: g.id}}
+		for _, acc := range g.accs {
+			doc = append(doc, bson.DocElem{Name: acc.name, Value: acc.result()})
+		}
+		res[i] = doc
+	}
+	return res, nil
+}
+
+func (a *accumulator) add(v interface{}, ok bool) error {
+	switch a.op {
+	case "$sum", "$avg":
+		if isNumber(v) {
+			if a.value == nil {
+				a.value = 0
+			}
+			a.value = addNumbers(a.value, v)
+			a.count++
+		}
+	case "$min", "$max":
+		if !ok || v == nil {
+			return nil
+		}
+		if !a.set || a.op == "$min" && compare(v, a.value) < 0 || a.op == "$max" && compare(v, a.value) > 0 {
+			a.value, a.set = v, true
+		}
+	case "$first":
+		if !a.set {
+			a.value, a.set = v, true
+		}
+	case "$last":
+		a.value = v
+	case "$push":
+		if ok {
+			a.list = append(a.list, v)
+		}
+	case "$addToSet":
+		if !ok {
+			return nil
+		}
+		for _, el := range a.list {
+			if equal(el, v) {
+				return nil
+			}
+		}
+		a.list = append(a.list, v)
+	default:
+		return errorf(15952, "unknown group operator '%s'", a.op)
+	}
+	return nil
+}
+
+func (a *accumulator) result() interface{} {
+	switch a.op {
+	case "$sum":
+		if a.value == nil {
+			return 0
+		}
+		return a.value
+	case "$avg":
+		if a.count == 0 {
+			return nil
+		}
+		sum, _ := toFloat(a.value)
+		return sum / float64(a.count)
+	case "$push", "$addToSet":
+		if a.list == nil {
+			return []interface{}{}
+		}
+		return a.list
+	}
+	return a.value
+}
+
+// valueKey identifies group ids, numbers of different types are the same id
+func valueKey(v interface{}) (string, error) {
+	data, err := bson.Marshal(bson.D{{Name: "v", Value: normalize(v)}})
+	return string(data), err
+}
+
+func normalize(v interface{}) interface{} {
+	switch t := v.(type) {
+	case bson.D:
+		res := make(bson.D, len(t))
+		for i, e := range t {
+			res[i] = bson.DocElem{Name: e.Name, Value: normalize(e.Value)}
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(t))
+		for i, el := range t {
+			res[i] = normalize(el)
+		}
+		return res
+	}
+	if f, ok := toFloat(v); ok {
+		return f
+	}
+	return v
+}
+
+func unwindStage(docs []bson.D, spec interface{}) ([]bson.D, error) {
+	field := toString(spec)
+	preserve := false
+	if d, ok := spec.(bson.D); ok {
+		p, _ := get(d, "path")
+		field = toString(p)
+		keep, _ := get(d, "preserveNullAndEmptyArrays")
+		preserve = truthy(keep)
+	}
+	if !strings.HasPrefix(field, "$") {
+		return nil, errorf(28818, "path option to $unwind stage should be prefixed with a '$': %s", field)
+	}
+	path := splitPath(field[1:])
+	res := []bson.D{}
+	for _, doc := range docs {
+		v, ok := getPath(doc, path)
+		arr, isArr := v.([]interface{})
+		switch {
+		case !ok || v == nil || isArr && len(arr) == 0:
+			if preserve {
+				res = append(res, doc)
+			}
+		case !isArr:
+			res = append(res, doc)
+		default:
+			for _, el := range arr {
+				out, err := setPath(copyDoc(doc), path, copyValue(el))
+				if err != nil {
+					return nil, err
+				}
+				res = append(res, out.(bson.D))
+			}
+		}
+	}
+	return res, nil
+}
+
+// evaluator evaluates aggregation expressions, the first error is kept
+type evaluator struct {
+	err error
+}
+
+func (e *evaluator) fail(err error) (interface{}, bool) {
+	if e.err == nil {
+		e.err = err
+	}
+	return nil, false
+}
+
+// eval returns the value of the expression, false if the value is missing
+func (e *evaluator) eval(expr interface{}, doc bson.D) (interface{}, bool) {
+	switch v := expr.(type) {
+	case string:
+		switch {
+		case v == "$$ROOT" || v == "$$CURRENT":
+			return doc, true
+		case strings.HasPrefix(v, "$$ROOT.") || strings.HasPrefix(v, "$$CURRENT."):
+			return fieldPath(doc, splitPath(v[strings.Index(v, ".")+1:]))
+		case strings.HasPrefix(v, "$$"):
+			return e.fail(errorf(17276, "use of undefined variable: %s", v[2:]))
+		case strings.HasPrefix(v, "$"):
+			return fieldPath(doc, splitPath(v[1:]))
+		}
+		return v, true
+	case bson.D:
+		if isOperators(v) {
+			if len(v) != 1 {
+				return e.fail(errorf(15983, "an expression specification must contain exactly one field"))
+			}
+			return e.op(v[0].Name, v[0].Value, doc)
+		}
+		out := bson.D{}
+		for _, f := range v {
+			if val, ok := e.eval(f.Value, doc); ok {
+				out = append(out, bson.DocElem{Name: f.Name, Value: val})
+			}
+		}
+		return out, true
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, el := range v {
+			out[i], _ = e.eval(el, doc)
+		}
+		return out, true
+	}
+	return expr, true
+}
+
+// fieldPath returns the value of the field path, arrays of documents give arrays of values
+func fieldPath(v interface{}, path []string) (interface{}, bool) {
+	if len(path) == 0 {
+		return v, true
+	}
+	switch t := v.(type) {
+	case bson.D:
+		child, ok := get(t, path[0])
+		if !ok {
+			return nil, false
+		}
+		return fieldPath(child, path[1:])
+	case []interface{}:
+		out := []interface{}{}
+		for _, el := range t {
+			if _, isDoc := el.(bson.D); isDoc {
+				if val, ok := fieldPath(el, path); ok {
+					out = append(out, val)
+				}
+			}
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// args evaluates operator arguments, a single argument may be given without an array
+func (e *evaluator) args(arg interface{}, doc bson.D) []interface{} {
+	list, ok := arg.([]interface{})
+	if !ok {
+		list = []interface{}{arg}
+	}
+	res := make([]interface{}, len(list))
+	for i, el := range list {
+		res[i], _ = e.eval(el, doc)
+	}
+	return res
+}
+
+func (e *evaluator) op(name string, arg interface{}, doc bson.D) (interface{}, bool) {
+	switch name {
+	case "$literal":
+		return arg, true
+	case "$cond":
+		var cond, then, els interface{}
+		switch a := arg.(type) {
+		case bson.D:
+			cond, _ = get(a, "if")
+			then, _ = get(a, "then")
+			els, _ = get(a, "else")
+		case []interface{}:
+			if len(a) != 3 {
+				return e.fail(errorf(16020, "Expression $cond takes exactly 3 arguments"))
+			}
+			cond, then, els = a[0], a[1], a[2]
+		default:
+			return e.fail(errorf(16020, "Expression $cond takes exactly 3 arguments"))
+		}
+		if c, _ := e.eval(cond, doc); truthy(c) {
+			return e.eval(then, doc)
+		}
+		return e.eval(els, doc)
+	case "$ifNull":
+		list, ok := arg.([]interface{})
+		if !ok || len(list) != 2 {
+			return e.fail(errorf(16020, "Expression $ifNull takes exactly 2 arguments"))
+		}
+		if v, ok := e.eval(list[0], doc); ok && v != nil {
+			return v, true
+		}
+		return e.eval(list[1], doc)
+	}
+	args := e.args(arg, doc)
+	switch name {
+	case "$add", "$multiply":
+		var res interface{} = 0
+		if name == "$multiply" {
+			res = 1
+		}
+		var date *time.Time
+		for _, a := range args {
+			switch t := a.(type) {
+			case nil:
+				return nil, true
+			case time.Time:
+				if date != nil || name == "$multiply" {
+					return e.fail(errorf(16612, "only one Date allowed in an $add expression"))
+				}
+				date = &t
+			default:
+				if !isNumber(a) {
+					return e.fail(errorf(16554, "%s only supports numeric or date types", name))
+				}
+				if name == "$add" {
+					res = addNumbers(res, a)
+				} else {
+					res = mulNumbers(res, a)
+				}
+			}
+		}
+		if date != nil {
+			ms, _ := toFloat(res)
+			return date.Add(time.Duration(ms) * time.Millisecond), true
+		}
+		return res, true
+	case "$subtract", "$divide", "$mod":
+		if len(args) != 2 {
+			return e.fail(errorf(16020, "Expression %s takes exactly 2 arguments", name))
+		}
+		a, b := args[0], args[1]
+		if a == nil || b == nil {
+			return nil, true
+		}
+		ta, dateA := a.(time.Time)
+		tb, dateB := b.(time.Time)
+		switch {
+		case name == "$subtract" && dateA && dateB:
+			return int64(ta.Sub(tb) / time.Millisecond), true
+		case name == "$subtract" && dateA && isNumber(b):
+			ms, _ := toFloat(b)
+			return ta.Add(-time.Duration(ms) * time.Millisecond), true
+		case !isNumber(a) || !isNumber(b):
+			return e.fail(errorf(16556, "%s only supports numeric types", name))
+		case name == "$subtract":
+			return addNumbers(a, mulNumbers(b, -1)), true
+		}
+		fb, _ := toFloat(b)
+		if fb == 0 {
+			return e.fail(errorf(16608, "can't %s by zero", name[1:]))
+		}
+		fa, _ := toFloat(a)
+		if name == "$divide" {
+			return fa / fb, true
+		}
+		ia, intA := toInt64(a)
+		ib, intB := toInt64(b)
+		if intA && intB {
+			return intResult(a, b, ia%ib), true
+		}
+		return math.Mod(fa, fb), true
+	case "$eq", "$ne", "$gt", "$gte", "$lt", "$lte", "$cmp":
+		if len(args) != 2 {
+			return e.fail(errorf(16020, "Expression %s takes exactly 2 arguments", name))
+		}
+		c := compare(args[0], args[1])
+		switch name {
+		case "$eq":
+			return c == 0, true
+		case "$ne":
+			return c != 0, true
+		case "$gt":
+			return c > 0, true
+		case "$gte":
+			return c >= 0, true
+		case "$lt":
+			return c < 0, true
+		case "$lte":
+			return c <= 0, true
+		}
+		return c, true
+	case "$and":
+		for _, a := range args {
+			if !truthy(a) {
+				return false, true
+			}
+		}
+		return true, true
+	case "$or":
+		for _, a := range args {
+			if truthy(a) {
+				return true, true
+			}
+		}
+		return false, true
+	case "$not":
+		return !truthy(args[0]), true
+	case "$year", "$month", "$dayOfMonth", "$hour", "$minute", "$second", "$millisecond", "$dayOfWeek", "$dayOfYear":
+		t, ok := args[0].(time.Time)
+		if !ok {
+			return e.fail(errorf(16006, "can't convert from BSON type %T to Date", args[0]))
+		}
+		t = t.UTC()
+		switch name {
+		case "$year":
+			return t.Year(), true
+		case "$month":
+			return int(t.Month()), true
+		case "$dayOfMonth":
+			return t.Day(), true
+		case "$hour":
+			return t.Hour(), true
+		case "$minute":
+			return t.Minute(), true
+		case "$second":
+			return t.Second(), true
+		case "$millisecond":
+			return t.Nanosecond() / int(time.Millisecond), true
+		case "$dayOfWeek":
+			return int(t.Weekday()) + 1, true
+		}
+		return t.YearDay(), true
+	case "$size":
+		arr, ok := args[0].([]interface{})
+		if !ok {
+			return e.fail(errorf(17124, "The argument to $size must be an Array"))
+		}
+		return len(arr), true
+	case "$concat":
+		res := ""
+		for _, a := range args {
+			if a == nil {
+				return nil, true
+			}
+			s, ok := a.(string)
+			if !ok {
+				return e.fail(errorf(16702, "$concat only supports strings"))
+			}
+			res += s
+		}
+		return res, true
+	case "$toLower":
+		return strings.ToLower(toString(args[0])), true
+	case "$toUpper":
+		return strings.ToUpper(toString(args[0])), true
+	}
+	return e.fail(errorf(15999, "invalid operator '%s'", name))
+}
@@ -0,0 +1,239 @@
+package memdb
+
+import (
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// command runs the database command, errors are returned in the result like mongodb does
+func (s *Server) command(c *conn, dbName string, cmd bson.D) bson.D {
+	if len(cmd) == 0 {
+		return cmdError(errorf(codeNoCommand, "no such cmd"))
+	}
+	name := cmd[0].Name
+	colName := toString(cmd[0].Value)
+	var res bson.D
+	var err error
+	switch strings.ToLower(name) {
+	case "ismaster":
+		res = bson.D{
+			{Name: "ismaster", Value: true},
+			{Name: "maxBsonObjectSize", Value: 16 << 20},
+			{Name: "maxMessageSizeBytes", Value: maxMessageSize},
+			{Name: "localTime", Value: time.Now()},
+			{Name: "maxWireVersion", Value: 0},
+			{Name: "minWireVersion", Value: 0},
+		}
+	case "getnonce":
+		res = bson.D{{Name: "nonce", Value: "2375531c32080ae8"}}
+	case "ping", "logout", "reseterror":
+		res = bson.D{}
+	case "buildinfo":
+		res = bson.D{{Name: "version", Value: "2.4.0"}, {Name: "versionArray", Value: []interface{}{2, 4, 0, 0}}, {Name: "maxBsonObjectSize", Value: 16 << 20}}
+	case "getlasterror":
+		return c.lastError
+	case "count":
+		res, err = s.count(dbName, colName, cmd)
+	case "distinct":
+		res, err = s.distinct(dbName, colName, cmd)
+	case "findandmodify":
+		res, err = s.findAndModify(dbName, colName, cmd)
+	case "aggregate":
+		res, err = s.aggregate(dbName, colName, cmd)
+	case "create":
+		s.col(dbName, colName, true)
+	case "drop":
+		db := s.db(dbName, false)
+		if db == nil || db.cols[colName] == nil {
+			err = errorf(codeNotFound, "ns not found")
+			break
+		}
+		delete(db.cols, colName)
+	case "dropdatabase":
+		delete(s.dbs, dbName)
+		res = bson.D{{Name: "dropped", Value: dbName}}
+	case "dropindexes", "deleteindexes":
+		col := s.col(dbName, colName, false)
+		if col == nil {
+			err = errorf(codeNotFound, "ns not found")
+			break
+		}
+		idx, _ := get(cmd, "index")
+		err = col.dropIndex(toString(idx))
+	default:
+		err = errorf(codeNoCommand, "no such cmd: %s", name)
+	}
+	if err != nil {
+		return cmdError(err)
+	}
+	return okResult(res)
+}
+
+func cmdError(err error) bson.D {
+	return bson.D{{Name: "ok", Value: 0.0}, {Name: "errmsg", Value: err.Error()}, {Name: "code", Value: errCode(err)}}
+}
+
+// docArg returns the document argument of the command, it's empty if it's missing
+func docArg(cmd bson.D, key string) bson.D {
+	v, _ := get(cmd, key)
+	d, _ := v.(bson.D)
+	return d
+}
+
+func intArg(cmd bson.D, key string) int {
+	v, _ := get(cmd, key)
+	f, _ := toFloat(v)
+	return int(f)
+}
+
+func (s *Server) count(dbName, colName string, cmd bson.D) (bson.D, error) {
+	docs, err := s.find(dbName, colName, findOpts{
+		query: docArg(cmd, "query"),
+		skip:  intArg(cmd, "skip"),
+		limit: intArg(cmd, "limit"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bson.D{{Name: "n", Value: len(docs)}}, nil
+}
+
+func (s *Server) distinct(dbName, colName string, cmd bson.D) (bson.D, error) {
+	docs, err := s.find(dbName, colName, findOpts{query: docArg(cmd, "query")})
+	if err != nil {
+		return nil, err
+	}
+	key, _ := get(cmd, "key")
+	values := []interface{}{}
+	add := func(v interface{}) {
+		for _, existing := range values {
+			if equal(existing, v) {
+				return
+			}
+		}
+		values = append(values, v)
+	}
+	for _, doc := range docs {
+		for _, v := range lookup(doc, splitPath(toString(key))) {
+			if arr, ok := v.([]interface{}); ok {
+				for _, el := range arr {
+					add(el)
+				}
+			} else {
+				add(v)
+			}
+		}
+	}
+	return bson.D{{Name: "values", Value: values}}, nil
+}
+
+func (s *Server) findAndModify(dbName, colName string, cmd bson.D) (bson.D, error) {
+	query, update, fields := docArg(cmd, "query"), docArg(cmd, "update"), docArg(cmd, "fields")
+	newV, _ := get(cmd, "new")
+	upsertV, _ := get(cmd, "upsert")
+	removeV, _ := get(cmd, "remove")
+	returnNew, upsert, remove := truthy(newV), truthy(upsertV), truthy(removeV)
+
+	col := s.col(dbName, colName, upsert && !remove)
+	positions, err := col.matching(s.matcher(col, query))
+	if err != nil {
+		return nil, err
+	}
+	if sortSpec := docArg(cmd, "sort"); len(sortSpec) > 0 && len(positions) > 1 {
+		docs := make([]bson.D, len(positions))
+		for i, p := range positions {
+			docs[i] = col.docs[p]
+		}
+		if err := sortDocs(docs, sortSpec); err != nil {
+			return nil, err
+		}
+		positions = []int{col.position(docs[0])}
+	}
+
+	var value interface{}
+	var lastErr bson.D
+	switch {
+	case len(positions) == 0 && (!upsert || remove):
+		lastErr = bson.D{{Name: "n", Value: 0}, {Name: "updatedExisting", Value: false}}
+	case len(positions) == 0:
+		doc, err := upsertDoc(query, update)
+		if err != nil {
+			return nil, err
+		}
+		if err := col.insert(doc); err != nil {
+			return nil, err
+		}
+		id, _ := get(doc, "_id")
+		if returnNew {
+			value = copyDoc(doc)
+		}
+		lastErr = bson.D{{Name: "n", Value: 1}, {Name: "updatedExisting", Value: false}, {Name: "upserted", Value: id}}
+	case remove:
+		value = col.docs[positions[0]]
+		col.removeAt(positions[:1])
+		lastErr = bson.D{{Name: "n", Value: 1}}
+	default:
+		i := positions[0]
+		old := col.docs[i]
+		if err := col.replace(i, update); err != nil {
+			return nil, err
+		}
+		value = copyDoc(old)
+		if returnNew {
+			value = copyDoc(col.docs[i])
+		}
+		lastErr = bson.D{{Name: "n", Value: 1}, {Name: "updatedExisting", Value: true}}
+	}
+	if doc, ok := value.(bson.D); ok {
+		if value, err = project(doc, fields); err != nil {
+			return nil, err
+		}
+	}
+	return bson.D{{Name: "lastErrorObject", Value: lastErr}, {Name: "value", Value: value}}, nil
+}
+
+// position returns the index of the stored document
+func (c *collection) position(doc bson.D) int {
+	id, _ := get(doc, "_id")
+	for i, d := range c.docs {
+		if other, _ := get(d, "_id"); equal(id, other) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *Server) aggregate(dbName, colName string, cmd bson.D) (bson.D, error) {
+	col := s.col(dbName, colName, false)
+	v, _ := get(cmd, "pipeline")
+	pipeline, ok := v.([]interface{})
+	if !ok {
+		return nil, errorf(codeBadValue, "pipeline has to be an array")
+	}
+	docs := []bson.D{}
+	if col != nil {
+		for _, doc := range col.docs {
+			docs = append(docs, copyDoc(doc))
+		}
+	}
+	for _, item := range pipeline {
+		stage, ok := item.(bson.D)
+		if !ok || len(stage) != 1 {
+			return nil, errorf(codeBadValue, "a pipeline stage specification object must contain exactly one field")
+		}
+		var err error
+		if docs, err = s.runStage(col, docs, stage[0]); err != nil {
+			return nil, err
+		}
+	}
+	result := make([]interface{}, len(docs))
+	for i, doc := range docs {
+		result[i] = doc
+	}
+	if _, ok := get(cmd, "cursor"); ok {
+		return bson.D{{Name: "cursor", Value: bson.D{{Name: "id", Value: int64(0)}, {Name: "ns", Value: dbName + "." + colName}, {Name: "firstBatch", Value: result}}}}, nil
+	}
+	return bson.D{{Name: "result", Value: result}}, nil
+}
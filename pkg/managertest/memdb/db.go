@@ -0,0 +1,499 @@
+package memdb
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// error codes which are checked by mgo
+const (
+	codeDup       = 11000
+	codeBadValue  = 2
+	codeNotFound  = 26
+	codeNoIndex   = 27
+	codeNoCommand = 59
+	codeImmutable = 16837
+)
+
+// Error is returned to clients with the code like mongodb errors
+type Error struct {
+	Code int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+func errorf(code int, format string, args ...interface{}) error {
+	return &Error{Code: code, Msg: fmt.Sprintf(format, args...)}
+}
+
+func errCode(err error) int {
+	if e, ok := err.(*Error); ok {
+		return e.Code
+	}
+	return 0
+}
+
+// errResult is a query failure document
+func errResult(err error) bson.D {
+	return bson.D{{Name: "$err", Value: err.Error()}, {Name: "code", Value: errCode(err)}}
+}
+
+// lastError is the result of the write returned by getLastError
+func lastError(n int, updated bool, upserted interface{}, err error) bson.D {
+	res := bson.D{{Name: "n", Value: n}, {Name: "connectionId", Value: 1}}
+	if err != nil {
+		res = append(res, bson.DocElem{Name: "err", Value: err.Error()}, bson.DocElem{Name: "code", Value: errCode(err)})
+	} else {
+		res = append(res, bson.DocElem{Name: "err", Value: nil})
+	}
+	if updated {
+		res = append(res, bson.DocElem{Name: "updatedExisting", Value: true})
+	}
+	if upserted != nil {
+		res = append(res, bson.DocElem{Name: "upserted", Value: upserted})
+	}
+	return append(res, bson.DocElem{Name: "ok", Value: 1.0})
+}
+
+func okResult(doc bson.D) bson.D {
+	return append(doc, bson.DocElem{Name: "ok", Value: 1.0})
+}
+
+type database struct {
+	name string
+	cols map[string]*collection
+}
+
+type collection struct {
+	db      string
+	name    string
+	docs    []bson.D
+	indexes []*index
+}
+
+type index struct {
+	name   string
+	key    bson.D
+	unique bool
+	sparse bool
+	text   []string // fields of the text index
+	spec   bson.D   // as it's inserted to system.indexes
+}
+
+func (s *Server) db(name string, create bool) *database {
+	db, ok := s.dbs[name]
+	if !ok && create {
+		db = &database{name: name, cols: map[string]*collection{}}
+		s.dbs[name] = db
+	}
+	return db
+}
+
+// col returns the collection, it's nil if the collection doesn't exist and create is false
+func (s *Server) col(dbName, name string, create bool) *collection {
+	db := s.db(dbName, create)
+	if db == nil {
+		return nil
+	}
+	col, ok := db.cols[name]
+	if !ok && create {
+		col = &collection{db: dbName, name: name}
+		col.indexes = []*index{{
+			name: "_id_",
+			key:  bson.D{{Name: "_id", Value: 1}},
+			spec: bson.D{{Name: "v", Value: 1}, {Name: "key", Value: bson.D{{Name: "_id", Value: 1}}}, {Name: "name", Value: "_id_"}, {Name: "ns", Value: dbName + "." + name}},
+		}}
+		db.cols[name] = col
+	}
+	return col
+}
+
+func (c *collection) ns() string {
+	return c.db + "." + c.name
+}
+
+// textFields returns fields of the text index, nil if there is no one
+func (c *collection) textFields() []string {
+	if c == nil {
+		return nil
+	}
+	for _, idx := range c.indexes {
+		if idx.text != nil {
+			return idx.text
+		}
+	}
+	return nil
+}
+
+func (s *Server) matcher(c *collection, query bson.D) *matcher {
+	return &matcher{query: query, text: c.textFields()}
+}
+
+// matching returns positions of documents matching the query
+func (c *collection) matching(m *matcher) ([]int, error) {
+	res := []int{}
+	if c == nil {
+		return res, nil
+	}
+	for i, doc := range c.docs {
+		ok, err := m.match(doc)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			res = append(res, i)
+		}
+	}
+	return res, nil
+}
+
+type findOpts struct {
+	query  bson.D
+	sort   bson.D
+	skip   int
+	limit  int
+	fields bson.D
+}
+
+// find returns copies of matching documents, sorted, skipped and projected
+func (s *Server) find(dbName, colName string, opts findOpts) ([]bson.D, error) {
+	var docs []bson.D
+	var col *collection
+	switch colName {
+	case "system.indexes":
+		docs = s.indexSpecs(dbName)
+	case "system.namespaces":
+		docs = s.namespaces(dbName)
+	default:
+		col = s.col(dbName, colName, false)
+		if col != nil {
+			docs = col.docs
+		}
+	}
+	m := s.matcher(col, opts.query)
+	res := []bson.D{}
+	for _, doc := range docs {
+		ok, err := m.match(doc)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			res = append(res, doc)
+		}
+	}
+	if len(opts.sort) > 0 {
+		if err := sortDocs(res, opts.sort); err != nil {
+			return nil, err
+		}
+	}
+	if opts.skip > 0 {
+		if opts.skip >= len(res) {
+			res = res[:0]
+		} else {
+			res = res[opts.skip:]
+		}
+	}
+	if opts.limit > 0 && opts.limit < len(res) {
+		res = res[:opts.limit]
+	}
+	out := make([]bson.D, len(res))
+	for i, doc := range res {
+		projected, err := project(copyDoc(doc), opts.fields)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = projected
+	}
+	return out, nil
+}
+
+func (s *Server) indexSpecs(dbName string) []bson.D {
+	res := []bson.D{}
+	db := s.db(dbName, false)
+	if db == nil {
+		return res
+	}
+	for _, name := range db.names() {
+		for _, idx := range db.cols[name].indexes {
+			res = append(res, idx.spec)
+		}
+	}
+	return res
+}
+
+func (s *Server) namespaces(dbName string) []bson.D {
+	res := []bson.D{}
+	db := s.db(dbName, false)
+	if db == nil {
+		return res
+	}
+	for _, name := range db.names() {
+		res = append(res, bson.D{{Name: "name", Value: dbName + "." + name}})
+	}
+	return res
+}
+
+func (db *database) names() []string {
+	names := make([]string, 0, len(db.cols))
+	for name := range db.cols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (s *Server) insert(ns string, docs []bson.D, continueOnError bool) bson.D {
+	dbName, colName := splitNs(ns)
+	if colName == "system.indexes" {
+		for _, spec := range docs {
+			if err := s.createIndex(dbName, spec); err != nil {
+				return lastError(0, false, nil, err)
+			}
+		}
+		return lastError(0, false, nil, nil)
+	}
+	col := s.col(dbName, colName, true)
+	var first error
+	for _, doc := range docs {
+		if err := col.insert(doc); err != nil {
+			if first == nil {
+				first = err
+			}
+			if !continueOnError {
+				break
+			}
+		}
+	}
+	return lastError(0, false, nil, first)
+}
+
+// insert adds the copy of the document, the id is generated if it's missing
+func (c *collection) insert(doc bson.D) error {
+	doc = withId(copyDoc(doc))
+	if err := c.checkUnique(doc, -1); err != nil {
+		return err
+	}
+	c.docs = append(c.docs, doc)
+	return nil
+}
+
+// withId moves the id to the first place or generates it
+func withId(doc bson.D) bson.D {
+	for i, e := range doc {
+		if e.Name == "_id" {
+			if i == 0 {
+				return doc
+			}
+			res := bson.D{e}
+			res = append(res, doc[:i]...)
+			return append(res, doc[i+1:]...)
+		}
+	}
+	return append(bson.D{{Name: "_id", Value: bson.NewObjectId()}}, doc...)
+}
+
+func (s *Server) update(ns string, selector, update bson.D, upsert, multi bool) bson.D {
+	dbName, colName := splitNs(ns)
+	col := s.col(dbName, colName, upsert)
+	if multi && !isOperators(update) && len(update) > 0 {
+		return lastError(0, false, nil, errorf(9, "multi update only works with $ operators"))
+	}
+	positions, err := col.matching(s.matcher(col, selector))
+	if err != nil {
+		return lastError(0, false, nil, err)
+	}
+	if len(positions) == 0 {
+		if !upsert {
+			return lastError(0, false, nil, nil)
+		}
+		doc, err := upsertDoc(selector, update)
+		if err != nil {
+			return lastError(0, false, nil, err)
+		}
+		if err := col.insert(doc); err != nil {
+			return lastError(0, false, nil, err)
+		}
+		id, _ := get(doc, "_id")
+		return lastError(1, false, id, nil)
+	}
+	if !multi {
+		positions = positions[:1]
+	}
+	for n, i := range positions {
+		if err := col.replace(i, update); err != nil {
+			return lastError(n, n > 0, nil, err)
+		}
+	}
+	return lastError(len(positions), true, nil, nil)
+}
+
+// replace applies the update to the document at position i
+func (c *collection) replace(i int, update bson.D) error {
+	doc, err := applyUpdate(c.docs[i], update, false)
+	if err != nil {
+		return err
+	}
+	oldId, _ := get(c.docs[i], "_id")
+	newId, _ := get(doc, "_id")
+	if !equal(oldId, newId) {
+		return errorf(codeImmutable, "The _id field cannot be changed from {_id: %v} to {_id: %v}", oldId, newId)
+	}
+	if err := c.checkUnique(doc, i); err != nil {
+		return err
+	}
+	c.docs[i] = withId(doc)
+	return nil
+}
+
+func (s *Server) remove(ns string, selector bson.D, single bool) bson.D {
+	dbName, colName := splitNs(ns)
+	col := s.col(dbName, colName, false)
+	positions, err := col.matching(s.matcher(col, selector))
+	if err != nil {
+		return lastError(0, false, nil, err)
+	}
+	if single && len(positions) > 1 {
+		positions = positions[:1]
+	}
+	col.removeAt(positions)
+	return lastError(len(positions), false, nil, nil)
+}
+
+// removeAt removes documents at sorted positions
+func (c *collection) removeAt(positions []int) {
+	if len(positions) == 0 {
+		return
+	}
+	docs := make([]bson.D, 0, len(c.docs)-len(positions))
+	next := 0
+	for i, doc := range c.docs {
+		if next < len(positions) && positions[next] == i {
+			next++
+			continue
+		}
+		docs = append(docs, doc)
+	}
+	c.docs = docs
+}
+
+func (s *Server) createIndex(dbName string, spec bson.D) error {
+	ns, _ := get(spec, "ns")
+	nsStr, _ := ns.(string)
+	specDb, colName := splitNs(nsStr)
+	if specDb != dbName || colName == "" {
+		return errorf(codeBadValue, "bad index namespace %q", nsStr)
+	}
+	name, _ := get(spec, "name")
+	key, _ := get(spec, "key")
+	idx := &index{spec: copyDoc(spec)}
+	idx.name, _ = name.(string)
+	idx.key, _ = key.(bson.D)
+	if idx.name == "" || len(idx.key) == 0 {
+		return errorf(codeBadValue, "index name and key are required")
+	}
+	unique, _ := get(spec, "unique")
+	sparse, _ := get(spec, "sparse")
+	idx.unique, idx.sparse = truthy(unique), truthy(sparse)
+	for _, k := range idx.key {
+		if k.Value == "text" {
+			idx.text = []string{}
+		}
+	}
+	if idx.text != nil {
+		weights, _ := get(spec, "weights")
+		w, _ := weights.(bson.D)
+		for _, f := range w {
+			idx.text = append(idx.text, f.Name)
+		}
+	}
+	col := s.col(dbName, colName, true)
+	for _, existing := range col.indexes {
+		if existing.name == idx.name {
+			return nil
+		}
+	}
+	if idx.unique {
+		for i, doc := range col.docs {
+			for j := 0; j < i; j++ {
+				if idx.conflicts(doc, col.docs[j]) {
+					return errorf(codeDup, "E11000 duplicate key error index: %s.$%s", col.ns(), idx.name)
+				}
+			}
+		}
+	}
+	col.indexes = append(col.indexes, idx)
+	return nil
+}
+
+// checkUnique checks unique indexes for the document, the document at position skip is replaced by it
+func (c *collection) checkUnique(doc bson.D, skip int) error {
+	for _, idx := range c.indexes {
+		if !idx.unique && idx.name != "_id_" {
+			continue
+		}
+		for i, other := range c.docs {
+			if i != skip && idx.conflicts(doc, other) {
+				return errorf(codeDup, "E11000 duplicate key error index: %s.$%s dup key: %v", c.ns(), idx.name, idx.values(doc))
+			}
+		}
+	}
+	return nil
+}
+
+// values of index fields in the document, missing fields are nil
+func (idx *index) values(doc bson.D) []interface{} {
+	res := make([]interface{}, len(idx.key))
+	for i, k := range idx.key {
+		if vals := lookup(doc, splitPath(k.Name)); len(vals) > 0 {
+			res[i] = vals[0]
+		}
+	}
+	return res
+}
+
+func (idx *index) conflicts(a, b bson.D) bool {
+	if idx.sparse && (idx.missing(a) || idx.missing(b)) {
+		return false
+	}
+	va, vb := idx.values(a), idx.values(b)
+	for i := range va {
+		if !equal(va[i], vb[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// missing reports whether all index fields are missing in the document
+func (idx *index) missing(doc bson.D) bool {
+	for _, k := range idx.key {
+		if len(lookup(doc, splitPath(k.Name))) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *collection) dropIndex(name string) error {
+	if name == "*" {
+		c.indexes = c.indexes[:1]
+		return nil
+	}
+	for i, idx := range c.indexes {
+		if idx.name == name && name != "_id_" {
+			c.indexes = append(c.indexes[:i], c.indexes[i+1:]...)
+			return nil
+		}
+	}
+	return errorf(codeNoIndex, "index not found with name [%s]", name)
+}
+
+func splitPath(path string) []string {
+	return strings.Split(path, ".")
+}
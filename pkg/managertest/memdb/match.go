@@ -0,0 +1,461 @@
+package memdb
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// matcher checks documents against the query
+type matcher struct {
+	query bson.D
+	text  []string // fields of the text index for $text queries
+}
+
+func (m *matcher) match(doc bson.D) (bool, error) {
+	return m.matchDoc(doc, m.query)
+}
+
+func (m *matcher) matchDoc(doc bson.D, query bson.D) (bool, error) {
+	for _, e := range query {
+		ok, err := m.matchElem(doc, e)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (m *matcher) matchElem(doc bson.D, e bson.DocElem) (bool, error) {
+	switch e.Name {
+	case "$and", "$or", "$nor":
+		list, ok := e.Value.([]interface{})
+		if !ok || len(list) == 0 {
+			return false, errorf(codeBadValue, "%s needs a nonempty array", e.Name)
+		}
+		for _, item := range list {
+			q, ok := item.(bson.D)
+			if !ok {
+				return false, errorf(codeBadValue, "%s entries need to be full objects", e.Name)
+			}
+			matched, err := m.matchDoc(doc, q)
+			if err != nil {
+				return false, err
+			}
+			switch {
+			case e.Name == "$and" && !matched:
+				return false, nil
+			case e.Name == "$or" && matched:
+				return true, nil
+			case e.Name == "$nor" && matched:
+				return false, nil
+			}
+		}
+		return e.Name != "$or", nil
+	case "$text":
+		return m.matchText(doc, e.Value)
+	case "$comment":
+		return true, nil
+	}
+	if strings.HasPrefix(e.Name, "$") {
+		return false, errorf(codeBadValue, "unknown top level operator: %s", e.Name)
+	}
+	return matchValue(lookup(doc, splitPath(e.Name)), e.Value)
+}
+
+// matchText looks for any of the search terms in fields of the text index
+func (m *matcher) matchText(doc bson.D, v interface{}) (bool, error) {
+	if m.text == nil {
+		return false, errorf(codeNoIndex, "text index required for $text query")
+	}
+	spec, _ := v.(bson.D)
+	search, _ := get(spec, "$search")
+	terms := strings.Fields(strings.ToLower(strings.Replace(toString(search), `"`, " ", -1)))
+	for _, field := range m.text {
+		for _, val := range lookup(doc, splitPath(field)) {
+			for _, s := range strs(val) {
+				s = strings.ToLower(s)
+				for _, term := range terms {
+					if strings.Contains(s, term) {
+						return true, nil
+					}
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+// strs returns the string or strings of the array
+func strs(v interface{}) []string {
+	if arr, ok := v.([]interface{}); ok {
+		var res []string
+		for _, el := range arr {
+			if s, ok := el.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+	if s, ok := v.(string); ok {
+		return []string{s}
+	}
+	return nil
+}
+
+func matchValue(vals []interface{}, cond interface{}) (bool, error) {
+	if ops, ok := cond.(bson.D); ok && isOperators(ops) {
+		return matchOps(vals, ops)
+	}
+	return matchEq(vals, cond)
+}
+
+func matchOps(vals []interface{}, ops bson.D) (bool, error) {
+	options := ""
+	if o, ok := get(ops, "$options"); ok {
+		options = toString(o)
+	}
+	for _, op := range ops {
+		var ok bool
+		var err error
+		switch op.Name {
+		case "$eq":
+			ok, err = matchEq(vals, op.Value)
+		case "$ne":
+			ok, err = matchEq(vals, op.Value)
+			ok = !ok
+		case "$gt", "$gte", "$lt", "$lte":
+			ok = matchCmp(vals, op.Name, op.Value)
+		case "$in", "$nin":
+			list, isList := op.Value.([]interface{})
+			if !isList {
+				return false, errorf(codeBadValue, "%s needs an array", op.Name)
+			}
+			ok, err = matchIn(vals, list)
+			if op.Name == "$nin" {
+				ok = !ok
+			}
+		case "$all":
+			list, isList := op.Value.([]interface{})
+			if !isList {
+				return false, errorf(codeBadValue, "$all needs an array")
+			}
+			ok = len(list) > 0
+			for _, el := range list {
+				matched, err := matchEq(vals, el)
+				if err != nil {
+					return false, err
+				}
+				ok = ok && matched
+			}
+		case "$exists":
+			ok = (len(vals) > 0) == truthy(op.Value)
+		case "$size":
+			n, isNum := toFloat(op.Value)
+			if !isNum {
+				return false, errorf(codeBadValue, "$size needs a number")
+			}
+			for _, v := range vals {
+				if arr, isArr := v.([]interface{}); isArr && float64(len(arr)) == n {
+					ok = true
+				}
+			}
+		case "$elemMatch":
+			cond, isDoc := op.Value.(bson.D)
+			if !isDoc {
+				return false, errorf(codeBadValue, "$elemMatch needs an Object")
+			}
+			ok, err = matchElemMatch(vals, cond)
+		case "$not":
+			switch v := op.Value.(type) {
+			case bson.D:
+				ok, err = matchOps(vals, v)
+			case bson.RegEx:
+				ok, err = matchEq(vals, v)
+			default:
+				return false, errorf(codeBadValue, "$not needs a regex or a document")
+			}
+			ok = !ok
+		case "$regex":
+			re := bson.RegEx{Options: options}
+			switch v := op.Value.(type) {
+			case string:
+				re.Pattern = v
+			case bson.RegEx:
+				re = v
+				if options != "" {
+					re.Options = options
+				}
+			default:
+				return false, errorf(codeBadValue, "$regex has to be a string")
+			}
+			ok, err = matchEq(vals, re)
+		case "$options":
+			ok = true
+		case "$mod":
+			args, isList := op.Value.([]interface{})
+			if !isList || len(args) != 2 {
+				return false, errorf(codeBadValue, "malformed mod, needs to be an array of 2 numbers")
+			}
+			div, _ := toFloat(args[0])
+			rem, _ := toFloat(args[1])
+			if div == 0 {
+				return false, errorf(codeBadValue, "divisor cannot be 0")
+			}
+			for _, v := range vals {
+				if f, isNum := toFloat(v); isNum && math.Mod(math.Trunc(f), div) == rem {
+					ok = true
+				}
+			}
+		default:
+			return false, errorf(codeBadValue, "unknown operator: %s", op.Name)
+		}
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// matchEq matches the value or any element of array values, null matches missing values
+func matchEq(vals []interface{}, target interface{}) (bool, error) {
+	if re, ok := target.(bson.RegEx); ok {
+		return matchRegex(vals, re)
+	}
+	if target == nil && len(vals) == 0 {
+		return true, nil
+	}
+	for _, v := range vals {
+		if equal(v, target) {
+			return true, nil
+		}
+		if arr, ok := v.([]interface{}); ok {
+			for _, el := range arr {
+				if equal(el, target) {
+					return true, nil
+				}
+			}
+		}
+	}
+	return false, nil
+}
+
+func matchIn(vals []interface{}, list []interface{}) (bool, error) {
+	for _, el := range list {
+		ok, err := matchEq(vals, el)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// matchCmp compares values of the same type only like mongodb does
+func matchCmp(vals []interface{}, op string, target interface{}) bool {
+	check := func(v interface{}) bool {
+		if typeOrder(v) != typeOrder(target) {
+			return false
+		}
+		c := compare(v, target)
+		switch op {
+		case "$gt":
+			return c > 0
+		case "$gte":
+			return c >= 0
+		case "$lt":
+			return c < 0
+		}
+		return c <= 0
+	}
+	for _, v := range vals {
+		if check(v) {
+			return true
+		}
+		if arr, ok := v.([]interface{}); ok {
+			for _, el := range arr {
+				if check(el) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func compileRegex(re bson.RegEx) (*regexp.Regexp, error) {
+	flags := ""
+	for _, o := range re.Options {
+		switch o {
+		case 'i', 'm', 's':
+			flags += string(o)
+		}
+	}
+	pattern := re.Pattern
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	r, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errorf(codeBadValue, "bad regex %q: %v", re.Pattern, err)
+	}
+	return r, nil
+}
+
+func matchRegex(vals []interface{}, re bson.RegEx) (bool, error) {
+	r, err := compileRegex(re)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range vals {
+		if stored, ok := v.(bson.RegEx); ok && stored == re {
+			return true, nil
+		}
+		for _, s := range strs(v) {
+			if r.MatchString(s) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func matchElemMatch(vals []interface{}, cond bson.D) (bool, error) {
+	for _, v := range vals {
+		arr, ok := v.([]interface{})
+		if !ok {
+			continue
+		}
+		for _, el := range arr {
+			matched, err := matchElement(el, cond)
+			if err != nil || matched {
+				return matched, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// matchElement matches an array element by operators or as a document by the query
+func matchElement(el interface{}, cond bson.D) (bool, error) {
+	if isOperators(cond) && !isLogical(cond[0].Name) {
+		return matchOps([]interface{}{el}, cond)
+	}
+	doc, ok := el.(bson.D)
+	if !ok {
+		return false, nil
+	}
+	return (&matcher{}).matchDoc(doc, cond)
+}
+
+func isLogical(op string) bool {
+	return op == "$and" || op == "$or" || op == "$nor"
+}
+
+// sortDocs sorts documents by the sort specification, arrays are ordered by the min or max element
+func sortDocs(docs []bson.D, spec bson.D) error {
+	type key struct {
+		path []string
+		desc bool
+	}
+	keys := []key{}
+	for _, e := range spec {
+		if _, isDoc := e.Value.(bson.D); isDoc {
+			// {$meta: "textScore"} keeps the order
+			continue
+		}
+		f, ok := toFloat(e.Value)
+		if !ok || f == 0 {
+			return errorf(codeBadValue, "bad sort specification for %s", e.Name)
+		}
+		keys = append(keys, key{splitPath(e.Name), f < 0})
+	}
+	sort.Stable(docSorter{docs, func(a, b bson.D) bool {
+		for _, k := range keys {
+			c := compare(sortValue(a, k.path, k.desc), sortValue(b, k.path, k.desc))
+			if k.desc {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	}})
+	return nil
+}
+
+func sortValue(doc bson.D, path []string, desc bool) interface{} {
+	vals := lookup(doc, path)
+	var res interface{}
+	found := false
+	for _, v := range vals {
+		items := []interface{}{v}
+		if arr, ok := v.([]interface{}); ok {
+			items = arr
+		}
+		for _, item := range items {
+			if !found || (desc && compare(item, res) > 0) || (!desc && compare(item, res) < 0) {
+				res, found = item, true
+			}
+		}
+	}
+	return res
+}
+
+type docSorter struct {
+	docs []bson.D
+	less func(a, b bson.D) bool
+}
+
+func (s docSorter) Len() int           { return len(s.docs) }
+func (s docSorter) Swap(i, j int)      { s.docs[i], s.docs[j] = s.docs[j], s.docs[i] }
+func (s docSorter) Less(i, j int) bool { return s.less(s.docs[i], s.docs[j]) }
+
+// project applies the inclusion or exclusion projection to the document
+func project(doc bson.D, fields bson.D) (bson.D, error) {
+	if len(fields) == 0 {
+		return doc, nil
+	}
+	include, noId := false, false
+	for _, f := range fields {
+		if _, isDoc := f.Value.(bson.D); isDoc {
+			return nil, errorf(codeBadValue, "unsupported projection of %s", f.Name)
+		}
+		if f.Name == "_id" {
+			noId = !truthy(f.Value)
+		} else if truthy(f.Value) {
+			include = true
+		}
+	}
+	if !include {
+		var res interface{} = doc
+		for _, f := range fields {
+			if !truthy(f.Value) {
+				res = unsetPath(res, splitPath(f.Name))
+			}
+		}
+		return res.(bson.D), nil
+	}
+	var res interface{} = bson.D{}
+	if id, ok := get(doc, "_id"); ok && !noId {
+		res = bson.D{{Name: "_id", Value: id}}
+	}
+	for _, f := range fields {
+		if f.Name == "_id" || !truthy(f.Value) {
+			continue
+		}
+		path := splitPath(f.Name)
+		v, ok := getPath(doc, path)
+		if !ok {
+			continue
+		}
+		var err error
+		if res, err = setPath(res, path, v); err != nil {
+			return nil, err
+		}
+	}
+	return res.(bson.D), nil
+}
@@ -0,0 +1,263 @@
+package memdb
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+type item struct {
+	Id      bson.ObjectId `bson:"_id"`
+	Name    string        `bson:"name"`
+	Count   int           `bson:"count"`
+	Tags    []string      `bson:"tags,omitempty"`
+	Owner   *owner        `bson:"owner,omitempty"`
+	Created time.Time     `bson:"created"`
+}
+
+type owner struct {
+	Email string `bson:"email"`
+}
+
+func dial(t *testing.T) (*Server, *mgo.Collection) {
+	s, err := New()
+	require.NoError(t, err)
+	sess, err := s.Dial("test")
+	if err != nil {
+		s.Close()
+		t.Fatal(err)
+	}
+	return s, sess.DB("").C("items")
+}
+
+func names(items []*item) []string {
+	res := []string{}
+	for _, obj := range items {
+		res = append(res, obj.Name)
+	}
+	return res
+}
+
+func TestQueries(t *testing.T) {
+	s, col := dial(t)
+	defer s.Close()
+	defer col.Database.Session.Close()
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	for i, name := range []string{"a", "b", "c", "d"} {
+		require.NoError(t, col.Insert(&item{
+			Id:      bson.NewObjectId(),
+			Name:    name,
+			Count:   i,
+			Tags:    []string{name, "all"},
+			Owner:   &owner{Email: name + "@example.com"},
+			Created: now.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+
+	find := func(query interface{}, sort ...string) []string {
+		items := []*item{}
+		q := col.Find(query)
+		if len(sort) > 0 {
+			q = q.Sort(sort...)
+		}
+		require.NoError(t, q.All(&items))
+		return names(items)
+	}
+	assert.Equal(t, []string{"b"}, find(bson.M{"name": "b"}))
+	assert.Equal(t, []string{"c", "d"}, find(bson.M{"count": bson.M{"$gte": 2}}))
+	assert.Equal(t, []string{"a", "d"}, find(bson.M{"$or": []bson.M{{"name": "a"}, {"count": 3}}}))
+	assert.Equal(t, []string{"b", "c"}, find(bson.M{"name": bson.M{"$in": []string{"b", "c", "x"}}}))
+	assert.Equal(t, []string{"a", "b", "c", "d"}, find(bson.M{"tags": "all"}))
+	assert.Equal(t, []string{"c"}, find(bson.M{"owner.email": "c@example.com"}))
+	assert.Equal(t, []string{}, find(bson.M{"missing": bson.M{"$exists": true}}))
+	assert.Equal(t, []string{"a", "b", "c", "d"}, find(bson.M{"missing": nil}))
+	assert.Equal(t, []string{"a", "b"}, find(bson.M{"created": bson.M{"$lt": now.Add(90 * time.Minute)}}))
+	assert.Equal(t, []string{"d"}, find(bson.M{"name": bson.RegEx{Pattern: "^D", Options: "i"}}))
+	assert.Equal(t, []string{"d", "c", "b", "a"}, find(nil, "-count"))
+
+	items := []*item{}
+	require.NoError(t, col.Find(nil).Sort("name").Skip(1).Limit(2).All(&items))
+	assert.Equal(t, []string{"b", "c"}, names(items))
+
+	// batches are returned by cursors
+	iter := col.Find(nil).Sort("name").Batch(1).Iter()
+	obj := &item{}
+	got := []string{}
+	for iter.Next(obj) {
+		got = append(got, obj.Name)
+	}
+	require.NoError(t, iter.Close())
+	assert.Equal(t, []string{"a", "b", "c", "d"}, got)
+
+	n, err := col.Find(bson.M{"count": bson.M{"$ne": 0}}).Count()
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	var tags []string
+	require.NoError(t, col.Find(nil).Distinct("tags", &tags))
+	assert.Len(t, tags, 5)
+
+	partial := bson.M{}
+	require.NoError(t, col.Find(bson.M{"name": "a"}).Select(bson.M{"name": 1, "_id": 0}).One(&partial))
+	assert.Equal(t, bson.M{"name": "a"}, partial)
+
+	assert.Equal(t, mgo.ErrNotFound, col.Find(bson.M{"name": "x"}).One(obj))
+
+	err = col.Find(bson.M{"name": bson.M{"$unknown": 1}}).One(obj)
+	assert.Error(t, err)
+}
+
+func TestUpdates(t *testing.T) {
+	s, col := dial(t)
+	defer s.Close()
+	defer col.Database.Session.Close()
+
+	id := bson.NewObjectId()
+	require.NoError(t, col.Insert(&item{Id: id, Name: "a", Count: 1}))
+
+	require.NoError(t, col.UpdateId(id, bson.M{
+		"$inc":      bson.M{"count": 2},
+		"$set":      bson.M{"owner.email": "a@example.com"},
+		"$addToSet": bson.M{"tags": "x"},
+	}))
+	require.NoError(t, col.UpdateId(id, bson.M{"$push": bson.M{"tags": bson.M{"$each": []string{"y", "z"}, "$slice": -2}}}))
+	obj := &item{}
+	require.NoError(t, col.FindId(id).One(obj))
+	assert.Equal(t, 3, obj.Count)
+	assert.Equal(t, "a@example.com", obj.Owner.Email)
+	assert.Equal(t, []string{"y", "z"}, obj.Tags)
+
+	require.NoError(t, col.UpdateId(id, bson.M{"$pull": bson.M{"tags": "y"}, "$unset": bson.M{"owner": ""}}))
+	require.NoError(t, col.FindId(id).One(obj))
+	assert.Equal(t, []string{"z"}, obj.Tags)
+
+	// replacement keeps the id
+	require.NoError(t, col.UpdateId(id, bson.M{"name": "b"}))
+	obj = &item{}
+	require.NoError(t, col.FindId(id).One(obj))
+	assert.Equal(t, id, obj.Id)
+	assert.Equal(t, "b", obj.Name)
+	assert.Equal(t, 0, obj.Count)
+
+	assert.Equal(t, mgo.ErrNotFound, col.Update(bson.M{"name": "x"}, bson.M{"$set": bson.M{"count": 1}}))
+
+	info, err := col.Upsert(bson.M{"name": "c"}, bson.M{"$set": bson.M{"count": 5}})
+	require.NoError(t, err)
+	require.NotNil(t, info.UpsertedId)
+	require.NoError(t, col.FindId(info.UpsertedId).One(obj))
+	assert.Equal(t, "c", obj.Name)
+	assert.Equal(t, 5, obj.Count)
+
+	info, err = col.UpdateAll(nil, bson.M{"$set": bson.M{"count": 7}})
+	require.NoError(t, err)
+	assert.Equal(t, 2, info.Updated)
+
+	// find and modify returns the old or the new document
+	obj = &item{}
+	_, err = col.Find(bson.M{"name": "c"}).Apply(mgo.Change{Update: bson.M{"$inc": bson.M{"count": 1}}, ReturnNew: true}, obj)
+	require.NoError(t, err)
+	assert.Equal(t, 8, obj.Count)
+	_, err = col.Find(bson.M{"name": "x"}).Apply(mgo.Change{Update: bson.M{"$inc": bson.M{"count": 1}}}, obj)
+	assert.Equal(t, mgo.ErrNotFound, err)
+	_, err = col.Find(bson.M{"name": "c"}).Apply(mgo.Change{Remove: true}, obj)
+	require.NoError(t, err)
+
+	info, err = col.RemoveAll(nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, info.Removed)
+	assert.Equal(t, mgo.ErrNotFound, col.RemoveId(id))
+}
+
+func TestIndexes(t *testing.T) {
+	s, col := dial(t)
+	defer s.Close()
+	defer col.Database.Session.Close()
+
+	require.NoError(t, col.EnsureIndex(mgo.Index{Key: []string{"name"}, Unique: true}))
+	require.NoError(t, col.EnsureIndex(mgo.Index{Key: []string{"$text:tags"}}))
+	indexes, err := col.Indexes()
+	require.NoError(t, err)
+	assert.Len(t, indexes, 3)
+
+	id := bson.NewObjectId()
+	require.NoError(t, col.Insert(&item{Id: id, Name: "a", Tags: []string{"Stored XSS"}}))
+	err = col.Insert(&item{Id: bson.NewObjectId(), Name: "a"})
+	assert.True(t, mgo.IsDup(err), "%v", err)
+	assert.True(t, mgo.IsDup(col.Insert(&item{Id: id, Name: "b"})))
+
+	n, err := col.Find(bson.M{"$text": bson.M{"$search": "xss"}}).Count()
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	require.NoError(t, col.DropIndex("name"))
+	require.NoError(t, col.Insert(&item{Id: bson.NewObjectId(), Name: "a"}))
+	require.NoError(t, col.DropCollection())
+	assert.Error(t, col.DropCollection())
+}
+
+func TestAggregate(t *testing.T) {
+	s, col := dial(t)
+	defer s.Close()
+	defer col.Database.Session.Close()
+
+	day := time.Date(2015, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i, name := range []string{"a", "a", "b", "c"} {
+		require.NoError(t, col.Insert(&item{Id: bson.NewObjectId(), Name: name, Count: i + 1, Created: day}))
+	}
+	type group struct {
+		Name  string `bson:"_id"`
+		Total int    `bson:"total"`
+		Big   int    `bson:"big"`
+		Month int    `bson:"month"`
+	}
+	result := []*group{}
+	err := col.Pipe([]bson.M{
+		{"$match": bson.M{"count": bson.M{"$lt": 4}}},
+		{"$group": bson.M{
+			"_id":   "$name",
+			"total": bson.M{"$sum": "$count"},
+			"big":   bson.M{"$sum": bson.M{"$cond": []interface{}{bson.M{"$gt": []interface{}{"$count", 1}}, 1, 0}}},
+			"month": bson.M{"$max": bson.M{"$month": "$created"}},
+		}},
+		{"$sort": bson.M{"total": -1}},
+	}).All(&result)
+	require.NoError(t, err)
+	require.Len(t, result, 2)
+	assert.Equal(t, &group{Name: "a", Total: 3, Big: 1, Month: 5}, result[0])
+	assert.Equal(t, &group{Name: "b", Total: 3, Big: 1, Month: 5}, result[1])
+
+	err = col.Pipe([]bson.M{{"$bad": 1}}).All(&result)
+	assert.Error(t, err)
+}
+
+func TestGridFS(t *testing.T) {
+	s, col := dial(t)
+	defer s.Close()
+	defer col.Database.Session.Close()
+
+	fs := col.Database.GridFS("fs")
+	f, err := fs.Create("data.txt")
+	require.NoError(t, err)
+	data := bytes.Repeat([]byte("data"), 100000)
+	_, err = f.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	f, err = fs.Open("data.txt")
+	require.NoError(t, err)
+	read, err := ioutil.ReadAll(f)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	assert.Equal(t, data, read)
+
+	require.NoError(t, fs.Remove("data.txt"))
+	_, err = fs.Open("data.txt")
+	assert.Equal(t, mgo.ErrNotFound, err)
+}
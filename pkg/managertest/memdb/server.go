@@ -0,0 +1,408 @@
+// Package memdb is an in-memory mongodb for tests.
+//
+// It speaks the legacy wire protocol used by the vendored mgo, so managers work with it
+// without changes. Queries, updates, indexes and aggregations used by managers are supported,
+// unknown operators return errors like an old mongodb does. Everything is kept in memory
+// and lost on Close.
+package memdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	opReply       = 1
+	opUpdate      = 2001
+	opInsert      = 2002
+	opQuery       = 2004
+	opGetMore     = 2005
+	opDelete      = 2006
+	opKillCursors = 2007
+
+	// mongodb closes connections with bigger messages
+	maxMessageSize = 48 << 20
+)
+
+// Server accepts mgo connections on the local port
+type Server struct {
+	l net.Listener
+
+	mu      sync.Mutex
+	dbs     map[string]*database
+	cursors map[int64]*cursor
+	cursor  int64
+	conns   map[net.Conn]bool
+	closed  bool
+	wg      sync.WaitGroup
+}
+
+// New starts the server on a random local port
+func New() (*Server, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		l:       l,
+		dbs:     map[string]*database{},
+		cursors: map[int64]*cursor{},
+		conns:   map[net.Conn]bool{},
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// Addr returns host:port of the server
+func (s *Server) Addr() string {
+	return s.l.Addr().String()
+}
+
+// Dial connects to the server, dbName is the default database of the session
+func (s *Server) Dial(dbName string) (*mgo.Session, error) {
+	return mgo.DialWithTimeout(fmt.Sprintf("mongodb://%s/%s", s.Addr(), dbName), 2*time.Second)
+}
+
+// Close stops the server and drops all data
+func (s *Server) Close() {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed = true
+	s.l.Close()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.dbs = map[string]*database{}
+	s.cursors = map[int64]*cursor{}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		c, err := s.l.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			c.Close()
+			return
+		}
+		s.conns[c] = true
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go s.handle(c)
+	}
+}
+
+// conn keeps the result of the last write for getLastError
+type conn struct {
+	net.Conn
+	lastError bson.D
+}
+
+func (s *Server) handle(c net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+		c.Close()
+	}()
+	cn := &conn{Conn: c, lastError: lastError(0, false, nil, nil)}
+	header := make([]byte, 16)
+	for {
+		if _, err := io.ReadFull(c, header); err != nil {
+			return
+		}
+		size := int(getInt32(header, 0))
+		if size < 16 || size > maxMessageSize {
+			return
+		}
+		body := make([]byte, size-16)
+		if _, err := io.ReadFull(c, body); err != nil {
+			return
+		}
+		reply, err := s.dispatch(cn, getInt32(header, 12), body)
+		if err != nil {
+			return
+		}
+		if reply == nil {
+			continue
+		}
+		if err := reply.write(c, getInt32(header, 4)); err != nil {
+			return
+		}
+	}
+}
+
+// reply to a query or a get more request
+type reply struct {
+	flags  int32
+	cursor int64
+	from   int32
+	docs   []bson.D
+}
+
+const (
+	flagCursorNotFound = 1
+	flagQueryFailure   = 2
+)
+
+func (r *reply) write(w io.Writer, responseTo int32) error {
+	buf := make([]byte, 36)
+	for _, doc := range r.docs {
+		data, err := bson.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, data...)
+	}
+	setInt32(buf, 0, int32(len(buf)))
+	setInt32(buf, 8, responseTo)
+	setInt32(buf, 12, opReply)
+	setInt32(buf, 16, r.flags)
+	binary.LittleEndian.PutUint64(buf[20:], uint64(r.cursor))
+	setInt32(buf, 28, r.from)
+	setInt32(buf, 32, int32(len(r.docs)))
+	_, err := w.Write(buf)
+	return err
+}
+
+func (s *Server) dispatch(c *conn, op int32, body []byte) (*reply, error) {
+	m := &message{data: body}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch op {
+	case opQuery:
+		m.int32() // flags
+		ns := m.cstring()
+		skip := m.int32()
+		limit := m.int32()
+		query := m.doc()
+		var fields bson.D
+		if m.more() {
+			fields = m.doc()
+		}
+		if m.err != nil {
+			return nil, m.err
+		}
+		return s.query(c, ns, int(skip), int(limit), query, fields), nil
+	case opGetMore:
+		m.int32()
+		m.cstring()
+		limit := m.int32()
+		id := m.int64()
+		if m.err != nil {
+			return nil, m.err
+		}
+		return s.getMore(id, int(limit)), nil
+	case opInsert:
+		flags := m.int32()
+		ns := m.cstring()
+		docs := []bson.D{}
+		for m.more() {
+			docs = append(docs, m.doc())
+		}
+		if m.err != nil {
+			return nil, m.err
+		}
+		c.lastError = s.insert(ns, docs, flags&1 != 0)
+		return nil, nil
+	case opUpdate:
+		m.int32()
+		ns := m.cstring()
+		flags := m.int32()
+		selector := m.doc()
+		update := m.doc()
+		if m.err != nil {
+			return nil, m.err
+		}
+		c.lastError = s.update(ns, selector, update, flags&1 != 0, flags&2 != 0)
+		return nil, nil
+	case opDelete:
+		m.int32()
+		ns := m.cstring()
+		flags := m.int32()
+		selector := m.doc()
+		if m.err != nil {
+			return nil, m.err
+		}
+		c.lastError = s.remove(ns, selector, flags&1 != 0)
+		return nil, nil
+	case opKillCursors:
+		m.int32()
+		n := m.int32()
+		for i := int32(0); i < n; i++ {
+			delete(s.cursors, m.int64())
+		}
+		return nil, m.err
+	}
+	return nil, fmt.Errorf("unknown op code %d", op)
+}
+
+// query runs commands on $cmd collections and finds documents in others
+func (s *Server) query(c *conn, ns string, skip, limit int, query, fields bson.D) *reply {
+	dbName, colName := splitNs(ns)
+	if colName == "$cmd" {
+		return &reply{docs: []bson.D{s.command(c, dbName, query)}}
+	}
+	opts := findOpts{skip: skip, fields: fields}
+	if q, ok := get(query, "$query"); ok {
+		opts.query, _ = q.(bson.D)
+		if o, ok := get(query, "$orderby"); ok {
+			opts.sort, _ = o.(bson.D)
+		}
+	} else {
+		opts.query = query
+	}
+	docs, err := s.find(dbName, colName, opts)
+	if err != nil {
+		return &reply{flags: flagQueryFailure, docs: []bson.D{errResult(err)}}
+	}
+	return s.batch(ns, docs, limit)
+}
+
+// batch returns the first batch of documents, the rest is kept for get more requests
+func (s *Server) batch(ns string, docs []bson.D, limit int) *reply {
+	single := limit < 0 || limit == 1
+	if limit < 0 {
+		limit = -limit
+	}
+	if limit == 0 || limit >= len(docs) {
+		return &reply{docs: docs}
+	}
+	r := &reply{docs: docs[:limit]}
+	if !single {
+		s.cursor++
+		s.cursors[s.cursor] = &cursor{ns: ns, docs: docs[limit:], pos: limit}
+		r.cursor = s.cursor
+	}
+	return r
+}
+
+func (s *Server) getMore(id int64, limit int) *reply {
+	cur, ok := s.cursors[id]
+	if !ok {
+		return &reply{flags: flagCursorNotFound}
+	}
+	if limit <= 0 || limit >= len(cur.docs) {
+		delete(s.cursors, id)
+		return &reply{docs: cur.docs, from: int32(cur.pos)}
+	}
+	r := &reply{cursor: id, docs: cur.docs[:limit], from: int32(cur.pos)}
+	cur.docs = cur.docs[limit:]
+	cur.pos += limit
+	return r
+}
+
+// cursor keeps documents which aren't returned yet
+type cursor struct {
+	ns   string
+	docs []bson.D
+	pos  int
+}
+
+func splitNs(ns string) (db, col string) {
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[:i], ns[i+1:]
+	}
+	return ns, ""
+}
+
+// message reads fields of the wire protocol message, the first error stops reading
+type message struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (m *message) more() bool {
+	return m.err == nil && m.pos < len(m.data)
+}
+
+func (m *message) int32() int32 {
+	if m.err != nil {
+		return 0
+	}
+	if m.pos+4 > len(m.data) {
+		m.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	v := getInt32(m.data, m.pos)
+	m.pos += 4
+	return v
+}
+
+func (m *message) int64() int64 {
+	if m.err != nil {
+		return 0
+	}
+	if m.pos+8 > len(m.data) {
+		m.err = io.ErrUnexpectedEOF
+		return 0
+	}
+	v := int64(binary.LittleEndian.Uint64(m.data[m.pos:]))
+	m.pos += 8
+	return v
+}
+
+func (m *message) cstring() string {
+	if m.err != nil {
+		return ""
+	}
+	for i := m.pos; i < len(m.data); i++ {
+		if m.data[i] == 0 {
+			v := string(m.data[m.pos:i])
+			m.pos = i + 1
+			return v
+		}
+	}
+	m.err = io.ErrUnexpectedEOF
+	return ""
+}
+
+func (m *message) doc() bson.D {
+	if m.err != nil {
+		return nil
+	}
+	if m.pos+4 > len(m.data) {
+		m.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	size := int(getInt32(m.data, m.pos))
+	if size < 5 || m.pos+size > len(m.data) {
+		m.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	doc := bson.D{}
+	if err := bson.Unmarshal(m.data[m.pos:m.pos+size], &doc); err != nil {
+		m.err = err
+		return nil
+	}
+	m.pos += size
+	return doc
+}
+
+func getInt32(b []byte, pos int) int32 {
+	return int32(binary.LittleEndian.Uint32(b[pos:]))
+}
+
+func setInt32(b []byte, pos int, v int32) {
+	binary.LittleEndian.PutUint32(b[pos:], uint32(v))
+}
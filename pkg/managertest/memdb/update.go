@@ -0,0 +1,330 @@
+package memdb
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+// applyUpdate returns the updated copy of the document, insert is set for upserted documents
+func applyUpdate(doc bson.D, update bson.D, insert bool) (bson.D, error) {
+	if !isOperators(update) {
+		return replacement(doc, update)
+	}
+	res := copyDoc(doc)
+	if res == nil {
+		res = bson.D{}
+	}
+	for _, op := range update {
+		fields, ok := op.Value.(bson.D)
+		if !ok {
+			return nil, errorf(9, "Modifier %s allowed for objects only", op.Name)
+		}
+		if op.Name == "$setOnInsert" && !insert {
+			continue
+		}
+		for _, f := range fields {
+			if strings.Contains(f.Name, "$") {
+				return nil, errorf(codeBadValue, "positional operator in %s isn't supported", f.Name)
+			}
+			var err error
+			if res, err = applyOp(res, op.Name, splitPath(f.Name), f.Value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return res, nil
+}
+
+// replacement keeps the id of the document
+func replacement(doc bson.D, update bson.D) (bson.D, error) {
+	res := bson.D{}
+	id, hasId := get(doc, "_id")
+	if hasId {
+		res = append(res, bson.DocElem{Name: "_id", Value: id})
+	}
+	for _, e := range update {
+		if strings.HasPrefix(e.Name, "$") {
+			return nil, errorf(codeBadValue, "mixed replacement and %s", e.Name)
+		}
+		if e.Name == "_id" {
+			if hasId && !equal(e.Value, id) {
+				return nil, errorf(codeImmutable, "The _id field cannot be changed")
+			}
+			if !hasId {
+				res = append(bson.D{e}, res...)
+			}
+			continue
+		}
+		res = append(res, bson.DocElem{Name: e.Name, Value: copyValue(e.Value)})
+	}
+	return res, nil
+}
+
+func applyOp(doc bson.D, op string, path []string, arg interface{}) (bson.D, error) {
+	cur, exists := getPath(doc, path)
+	var value interface{}
+	switch op {
+	case "$set", "$setOnInsert":
+		value = copyValue(arg)
+	case "$unset":
+		return unsetPath(doc, path).(bson.D), nil
+	case "$inc", "$mul":
+		if !isNumber(arg) {
+			return nil, errorf(14, "Cannot %s with non-numeric argument", op[1:])
+		}
+		switch {
+		case exists && !isNumber(cur):
+			return nil, errorf(codeImmutable, "Cannot apply %s to a value of non-numeric type", op)
+		case !exists && op == "$inc":
+			value = arg
+		case !exists:
+			value = mulNumbers(arg, 0)
+		case op == "$inc":
+			value = addNumbers(cur, arg)
+		default:
+			value = mulNumbers(cur, arg)
+		}
+	case "$min", "$max":
+		if exists && (op == "$min" && compare(arg, cur) >= 0 || op == "$max" && compare(arg, cur) <= 0) {
+			return doc, nil
+		}
+		value = copyValue(arg)
+	case "$currentDate":
+		value = time.Now()
+	case "$rename":
+		if !exists {
+			return doc, nil
+		}
+		doc = unsetPath(doc, path).(bson.D)
+		path = splitPath(toString(arg))
+		value = cur
+	case "$push", "$pushAll", "$addToSet":
+		arr, err := arrayAt(cur, exists, op)
+		if err != nil {
+			return nil, err
+		}
+		if value, err = push(arr, op, arg); err != nil {
+			return nil, err
+		}
+	case "$pull", "$pullAll":
+		if !exists {
+			return doc, nil
+		}
+		arr, err := arrayAt(cur, exists, op)
+		if err != nil {
+			return nil, err
+		}
+		kept := []interface{}{}
+		for _, el := range arr {
+			remove, err := pulled(el, op, arg)
+			if err != nil {
+				return nil, err
+			}
+			if !remove {
+				kept = append(kept, el)
+			}
+		}
+		value = kept
+	case "$pop":
+		if !exists {
+			return doc, nil
+		}
+		arr, err := arrayAt(cur, exists, op)
+		if err != nil {
+			return nil, err
+		}
+		if len(arr) > 0 {
+			if f, _ := toFloat(arg); f >= 1 {
+				arr = arr[:len(arr)-1]
+			} else {
+				arr = arr[1:]
+			}
+		}
+		value = arr
+	default:
+		return nil, errorf(9, "Unknown modifier: %s", op)
+	}
+	res, err := setPath(doc, path, value)
+	if err != nil {
+		return nil, err
+	}
+	return res.(bson.D), nil
+}
+
+func arrayAt(cur interface{}, exists bool, op string) ([]interface{}, error) {
+	if !exists || cur == nil {
+		return []interface{}{}, nil
+	}
+	arr, ok := cur.([]interface{})
+	if !ok {
+		return nil, errorf(codeBadValue, "Cannot apply %s to a non-array value", op)
+	}
+	return append([]interface{}{}, arr...), nil
+}
+
+// push adds values to the array with $each, $position, $sort and $slice modifiers
+func push(arr []interface{}, op string, arg interface{}) ([]interface{}, error) {
+	items := []interface{}{arg}
+	var mods bson.D
+	if op == "$pushAll" {
+		list, ok := arg.([]interface{})
+		if !ok {
+			return nil, errorf(codeBadValue, "$pushAll requires an array of values")
+		}
+		items = list
+	} else if d, ok := arg.(bson.D); ok && isOperators(d) {
+		each, ok := get(d, "$each")
+		if list, isList := each.([]interface{}); ok && isList {
+			items, mods = list, d
+		}
+	}
+	if op == "$addToSet" {
+		for _, item := range items {
+			found := false
+			for _, el := range arr {
+				if equal(el, item) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				arr = append(arr, copyValue(item))
+			}
+		}
+		return arr, nil
+	}
+	pos := len(arr)
+	if p, ok := get(mods, "$position"); ok {
+		if n, ok := toInt64(p); ok && int(n) < pos && n >= 0 {
+			pos = int(n)
+		}
+	}
+	res := append([]interface{}{}, arr[:pos]...)
+	for _, item := range items {
+		res = append(res, copyValue(item))
+	}
+	res = append(res, arr[pos:]...)
+	if spec, ok := get(mods, "$sort"); ok {
+		sortValues(res, spec)
+	}
+	if s, ok := get(mods, "$slice"); ok {
+		n, _ := toInt64(s)
+		switch {
+		case n >= 0 && int(n) < len(res):
+			res = res[:n]
+		case n < 0 && int(-n) < len(res):
+			res = res[len(res)+int(n):]
+		}
+	}
+	return res, nil
+}
+
+// sortValues sorts array elements by the direction or by fields of documents
+func sortValues(arr []interface{}, spec interface{}) {
+	var keys bson.D
+	if d, ok := spec.(bson.D); ok {
+		keys = d
+	} else {
+		keys = bson.D{{Name: "", Value: spec}}
+	}
+	sort.Stable(valueSorter{arr, func(a, b interface{}) bool {
+		for _, k := range keys {
+			va, vb := a, b
+			if k.Name != "" {
+				va, _ = getPath(a, splitPath(k.Name))
+				vb, _ = getPath(b, splitPath(k.Name))
+			}
+			c := compare(va, vb)
+			if f, _ := toFloat(k.Value); f < 0 {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	}})
+}
+
+type valueSorter struct {
+	values []interface{}
+	less   func(a, b interface{}) bool
+}
+
+func (s valueSorter) Len() int           { return len(s.values) }
+func (s valueSorter) Swap(i, j int)      { s.values[i], s.values[j] = s.values[j], s.values[i] }
+func (s valueSorter) Less(i, j int) bool { return s.less(s.values[i], s.values[j]) }
+
+// pulled reports whether the array element is removed by $pull or $pullAll
+func pulled(el interface{}, op string, arg interface{}) (bool, error) {
+	if op == "$pullAll" {
+		list, ok := arg.([]interface{})
+		if !ok {
+			return false, errorf(codeBadValue, "$pullAll requires an array argument")
+		}
+		for _, item := range list {
+			if equal(el, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	switch cond := arg.(type) {
+	case bson.D:
+		if _, isDoc := el.(bson.D); !isDoc && !isOperators(cond) {
+			return false, nil
+		}
+		return matchElement(el, cond)
+	case bson.RegEx:
+		return matchRegex([]interface{}{el}, cond)
+	}
+	return equal(el, arg), nil
+}
+
+// upsertDoc builds the inserted document from equality conditions of the query and the update
+func upsertDoc(query, update bson.D) (bson.D, error) {
+	var base interface{} = bson.D{}
+	var add func(q bson.D) error
+	add = func(q bson.D) error {
+		for _, e := range q {
+			if e.Name == "$and" {
+				list, _ := e.Value.([]interface{})
+				for _, item := range list {
+					if d, ok := item.(bson.D); ok {
+						if err := add(d); err != nil {
+							return err
+						}
+					}
+				}
+				continue
+			}
+			if strings.HasPrefix(e.Name, "$") {
+				continue
+			}
+			v := e.Value
+			if d, ok := v.(bson.D); ok && isOperators(d) {
+				eq, ok := get(d, "$eq")
+				if !ok {
+					continue
+				}
+				v = eq
+			}
+			var err error
+			if base, err = setPath(base, splitPath(e.Name), copyValue(v)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := add(query); err != nil {
+		return nil, err
+	}
+	doc, err := applyUpdate(base.(bson.D), update, true)
+	if err != nil {
+		return nil, err
+	}
+	return withId(doc), nil
+}
@@ -0,0 +1,445 @@
+package memdb
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+func get(doc bson.D, key string) (interface{}, bool) {
+	for _, e := range doc {
+		if e.Name == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+// isOperators reports whether the document holds operators like {$gt: 1}
+func isOperators(doc bson.D) bool {
+	return len(doc) > 0 && strings.HasPrefix(doc[0].Name, "$")
+}
+
+// lookup returns values of the dotted path, documents in arrays are expanded like mongodb does
+func lookup(v interface{}, path []string) []interface{} {
+	if len(path) == 0 {
+		return []interface{}{v}
+	}
+	switch t := v.(type) {
+	case bson.D:
+		child, ok := get(t, path[0])
+		if !ok {
+			return nil
+		}
+		return lookup(child, path[1:])
+	case []interface{}:
+		if i, err := strconv.Atoi(path[0]); err == nil {
+			if i >= 0 && i < len(t) {
+				return lookup(t[i], path[1:])
+			}
+			return nil
+		}
+		var res []interface{}
+		for _, el := range t {
+			if d, ok := el.(bson.D); ok {
+				res = append(res, lookup(d, path)...)
+			}
+		}
+		return res
+	}
+	return nil
+}
+
+// getPath returns the value of the dotted path without expanding arrays
+func getPath(v interface{}, path []string) (interface{}, bool) {
+	for _, p := range path {
+		switch t := v.(type) {
+		case bson.D:
+			child, ok := get(t, p)
+			if !ok {
+				return nil, false
+			}
+			v = child
+		case []interface{}:
+			i, err := strconv.Atoi(p)
+			if err != nil || i < 0 || i >= len(t) {
+				return nil, false
+			}
+			v = t[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// setPath sets the value of the dotted path, missing documents on the path are created.
+// It returns the changed container which has to replace the original one.
+func setPath(v interface{}, path []string, value interface{}) (interface{}, error) {
+	p := path[0]
+	switch t := v.(type) {
+	case nil:
+		return setPath(bson.D{}, path, value)
+	case bson.D:
+		for i, e := range t {
+			if e.Name != p {
+				continue
+			}
+			if len(path) == 1 {
+				t[i].Value = value
+				return t, nil
+			}
+			child, err := setPath(e.Value, path[1:], value)
+			if err != nil {
+				return nil, err
+			}
+			t[i].Value = child
+			return t, nil
+		}
+		if len(path) == 1 {
+			return append(t, bson.DocElem{Name: p, Value: value}), nil
+		}
+		child, err := setPath(bson.D{}, path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		return append(t, bson.DocElem{Name: p, Value: child}), nil
+	case []interface{}:
+		i, err := strconv.Atoi(p)
+		if err != nil || i < 0 {
+			break
+		}
+		for len(t) <= i {
+			t = append(t, nil)
+		}
+		if len(path) == 1 {
+			t[i] = value
+			return t, nil
+		}
+		child, err := setPath(t[i], path[1:], value)
+		if err != nil {
+			return nil, err
+		}
+		t[i] = child
+		return t, nil
+	}
+	return nil, errorf(codeImmutable, "cannot use the part (%s) of (%s) to traverse the element", p, strings.Join(path, "."))
+}
+
+// unsetPath removes the value of the dotted path, array elements are set to null
+func unsetPath(v interface{}, path []string) interface{} {
+	p := path[0]
+	switch t := v.(type) {
+	case bson.D:
+		for i, e := range t {
+			if e.Name != p {
+				continue
+			}
+			if len(path) == 1 {
+				return append(t[:i:i], t[i+1:]...)
+			}
+			t[i].Value = unsetPath(e.Value, path[1:])
+			return t
+		}
+	case []interface{}:
+		i, err := strconv.Atoi(p)
+		if err != nil || i < 0 || i >= len(t) {
+			return t
+		}
+		if len(path) == 1 {
+			t[i] = nil
+		} else {
+			t[i] = unsetPath(t[i], path[1:])
+		}
+		return t
+	}
+	return v
+}
+
+func copyDoc(doc bson.D) bson.D {
+	if doc == nil {
+		return nil
+	}
+	return copyValue(doc).(bson.D)
+}
+
+func copyValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case bson.D:
+		res := make(bson.D, len(t))
+		for i, e := range t {
+			res[i] = bson.DocElem{Name: e.Name, Value: copyValue(e.Value)}
+		}
+		return res
+	case bson.M:
+		res := bson.D{}
+		for k, val := range t {
+			res = append(res, bson.DocElem{Name: k, Value: copyValue(val)})
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(t))
+		for i, el := range t {
+			res[i] = copyValue(el)
+		}
+		return res
+	case []byte:
+		return append([]byte{}, t...)
+	}
+	return v
+}
+
+// canonical order of bson types used for comparison
+const (
+	orderMinKey = iota
+	orderNull
+	orderNumber
+	orderString
+	orderDoc
+	orderArray
+	orderBinary
+	orderObjectId
+	orderBool
+	orderDate
+	orderTimestamp
+	orderRegex
+	orderOther
+	orderMaxKey
+)
+
+func typeOrder(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return orderNull
+	case int, int32, int64, float64, float32:
+		return orderNumber
+	case bson.ObjectId:
+		return orderObjectId
+	case string, bson.Symbol:
+		return orderString
+	case bson.D:
+		return orderDoc
+	case []interface{}:
+		return orderArray
+	case []byte, bson.Binary:
+		return orderBinary
+	case bool:
+		return orderBool
+	case time.Time:
+		return orderDate
+	case bson.MongoTimestamp:
+		return orderTimestamp
+	case bson.RegEx:
+		return orderRegex
+	}
+	switch v {
+	case bson.MinKey:
+		return orderMinKey
+	case bson.MaxKey:
+		return orderMaxKey
+	case bson.Undefined:
+		return orderNull
+	}
+	return orderOther
+}
+
+func isNumber(v interface{}) bool {
+	return typeOrder(v) == orderNumber
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case int:
+		return float64(t), true
+	case int32:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	}
+	return 0, false
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch t := v.(type) {
+	case int:
+		return int64(t), true
+	case int32:
+		return int64(t), true
+	case int64:
+		return t, true
+	}
+	return 0, false
+}
+
+func truthy(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return t
+	}
+	if f, ok := toFloat(v); ok {
+		return f != 0
+	}
+	return v != bson.Undefined
+}
+
+func equal(a, b interface{}) bool {
+	return typeOrder(a) == typeOrder(b) && compare(a, b) == 0
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// compare orders values like mongodb, values of different types are ordered by the type
+func compare(a, b interface{}) int {
+	oa, ob := typeOrder(a), typeOrder(b)
+	if oa != ob {
+		return sign(oa - ob)
+	}
+	switch oa {
+	case orderNumber:
+		ia, okA := toInt64(a)
+		ib, okB := toInt64(b)
+		if okA && okB {
+			return compareInt64(ia, ib)
+		}
+		fa, _ := toFloat(a)
+		fb, _ := toFloat(b)
+		switch {
+		case fa < fb:
+			return -1
+		case fa > fb:
+			return 1
+		}
+		return 0
+	case orderString:
+		return strings.Compare(toString(a), toString(b))
+	case orderDoc:
+		da, db := a.(bson.D), b.(bson.D)
+		for i := 0; i < len(da) && i < len(db); i++ {
+			if c := strings.Compare(da[i].Name, db[i].Name); c != 0 {
+				return c
+			}
+			if c := compare(da[i].Value, db[i].Value); c != 0 {
+				return c
+			}
+		}
+		return sign(len(da) - len(db))
+	case orderArray:
+		la, lb := a.([]interface{}), b.([]interface{})
+		for i := 0; i < len(la) && i < len(lb); i++ {
+			if c := compare(la[i], lb[i]); c != 0 {
+				return c
+			}
+		}
+		return sign(len(la) - len(lb))
+	case orderBinary:
+		ba, bb := toBytes(a), toBytes(b)
+		if len(ba) != len(bb) {
+			return sign(len(ba) - len(bb))
+		}
+		return bytes.Compare(ba, bb)
+	case orderObjectId:
+		return strings.Compare(string(a.(bson.ObjectId)), string(b.(bson.ObjectId)))
+	case orderBool:
+		ta, tb := a.(bool), b.(bool)
+		switch {
+		case ta == tb:
+			return 0
+		case tb:
+			return -1
+		}
+		return 1
+	case orderDate:
+		ta, tb := a.(time.Time), b.(time.Time)
+		switch {
+		case ta.Before(tb):
+			return -1
+		case ta.After(tb):
+			return 1
+		}
+		return 0
+	case orderTimestamp:
+		return compareInt64(int64(a.(bson.MongoTimestamp)), int64(b.(bson.MongoTimestamp)))
+	case orderRegex:
+		ra, rb := a.(bson.RegEx), b.(bson.RegEx)
+		if c := strings.Compare(ra.Pattern, rb.Pattern); c != 0 {
+			return c
+		}
+		return strings.Compare(ra.Options, rb.Options)
+	}
+	return 0
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func toString(v interface{}) string {
+	if s, ok := v.(bson.Symbol); ok {
+		return string(s)
+	}
+	s, _ := v.(string)
+	return s
+}
+
+func toBytes(v interface{}) []byte {
+	if b, ok := v.(bson.Binary); ok {
+		return b.Data
+	}
+	b, _ := v.([]byte)
+	return b
+}
+
+// numbers keep the widest type of arguments like mongodb arithmetic
+func addNumbers(a, b interface{}) interface{} {
+	ia, okA := toInt64(a)
+	ib, okB := toInt64(b)
+	if okA && okB {
+		return intResult(a, b, ia+ib)
+	}
+	fa, _ := toFloat(a)
+	fb, _ := toFloat(b)
+	return fa + fb
+}
+
+func mulNumbers(a, b interface{}) interface{} {
+	ia, okA := toInt64(a)
+	ib, okB := toInt64(b)
+	if okA && okB {
+		return intResult(a, b, ia*ib)
+	}
+	fa, _ := toFloat(a)
+	fb, _ := toFloat(b)
+	return fa * fb
+}
+
+func intResult(a, b interface{}, v int64) interface{} {
+	_, longA := a.(int64)
+	_, longB := b.(int64)
+	if longA || longB || v > 1<<31-1 || v < -1<<31 {
+		return v
+	}
+	return int(v)
+}
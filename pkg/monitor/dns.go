@@ -0,0 +1,97 @@
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/bearded-web/bearded/models/issue"
+)
+
+// Resolver looks up dns records, net package is used by default
+type Resolver interface {
+	LookupHost(host string) ([]string, error)
+	LookupCNAME(host string) (string, error)
+	LookupMX(name string) ([]*net.MX, error)
+	LookupNS(name string) ([]*net.NS, error)
+}
+
+type netResolver struct{}
+
+func (netResolver) LookupHost(host string) ([]string, error) { return net.LookupHost(host) }
+func (netResolver) LookupCNAME(host string) (string, error)  { return net.LookupCNAME(host) }
+func (netResolver) LookupMX(name string) ([]*net.MX, error)  { return net.LookupMX(name) }
+func (netResolver) LookupNS(name string) ([]*net.NS, error)  { return net.LookupNS(name) }
+
+// Records returns sorted dns records of the host like "A 127.0.0.1" or "MX mx.example.com."
+func (m *Monitor) Records(host string) ([]string, error) {
+	addrs, err := m.Resolver.LookupHost(host)
+	if err != nil {
+		return nil, err
+	}
+	records := []string{}
+	for _, addr := range addrs {
+		if strings.Contains(addr, ":") {
+			records = append(records, "AAAA "+addr)
+		} else {
+			records = append(records, "A "+addr)
+		}
+	}
+	// the rest of records are optional
+	if cname, err := m.Resolver.LookupCNAME(host); err == nil && cname != "" && cname != host+"." {
+		records = append(records, "CNAME "+cname)
+	}
+	if mxs, err := m.Resolver.LookupMX(host); err == nil {
+		for _, mx := range mxs {
+			records = append(records, "MX "+mx.Host)
+		}
+	}
+	if nss, err := m.Resolver.LookupNS(host); err == nil {
+		for _, ns := range nss {
+			records = append(records, "NS "+ns.Host)
+		}
+	}
+	sort.Strings(records)
+	return records, nil
+}
+
+// CheckDns compares records with the previous ones, nothing is reported for the first check.
+// Changes are reported as the same issue, which is reopened by the next change after it's resolved.
+func (m *Monitor) CheckDns(host string, prev, records []string) []*issue.Issue {
+	if prev == nil {
+		return nil
+	}
+	added, removed := diff(prev, records), diff(records, prev)
+	if len(added) == 0 && len(removed) == 0 {
+		return nil
+	}
+	desc := []string{fmt.Sprintf("DNS records for %s are changed.", host)}
+	for _, r := range added {
+		desc = append(desc, "+ "+r)
+	}
+	for _, r := range removed {
+		desc = append(desc, "- "+r)
+	}
+	return []*issue.Issue{{
+		UniqId:   "monitor:dns-change",
+		Summary:  "DNS records are changed",
+		Severity: issue.SeverityInfo,
+		Desc:     strings.Join(desc, "\n"),
+	}}
+}
+
+// returns records from b which aren't in a
+func diff(a, b []string) []string {
+	existed := map[string]bool{}
+	for _, r := range a {
+		existed[r] = true
+	}
+	res := []string{}
+	for _, r := range b {
+		if !existed[r] {
+			res = append(res, r)
+		}
+	}
+	return res
+}
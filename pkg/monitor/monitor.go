@@ -0,0 +1,230 @@
+// Package monitor periodically checks tls certificates and dns records of web targets
+// and creates issues for problems found without running plugins.
+package monitor
+
+import (
+	"crypto/x509"
+	"net"
+	"regexp"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/facebookgo/stackerr"
+	"golang.org/x/net/context"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/pkg/manager"
+)
+
+// lock in mongo which is held by the instance running checks
+const lockName = "monitor"
+
+// uniq id prefix of tls issues, they are resolved when tls check doesn't report them anymore
+const tlsPrefix = "monitor:tls-"
+
+type Monitor struct {
+	CertExpiry time.Duration  // certificates which expire sooner are reported
+	Timeout    time.Duration  // dial timeout
+	Roots      *x509.CertPool // system roots if nil
+	Resolver   Resolver
+
+	mgr *manager.Manager
+	now func() time.Time
+}
+
+func New(mgr *manager.Manager) *Monitor {
+	return &Monitor{
+		CertExpiry: 30 * 24 * time.Hour,
+		Timeout:    10 * time.Second,
+		Resolver:   netResolver{},
+		mgr:        mgr,
+		now:        func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Run checks targets every interval until the context is done
+func (m *Monitor) Run(ctx context.Context, interval time.Duration) {
+	logrus.Infof("Monitor is started, check interval %s", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			if err := m.mgr.Locks.Release(lockName); err != nil {
+				logrus.Error(err)
+			}
+			return
+		case <-time.After(interval):
+			// only one api instance runs the check, others wait until the lock is expired
+			if !m.mgr.Locks.Lead(lockName, interval) {
+				continue
+			}
+			if err := m.Check(); err != nil {
+				logrus.Error(err)
+			}
+		}
+	}
+}
+
+// Check monitors all web targets
+func (m *Monitor) Check() error {
+	mgr := m.mgr.Copy()
+	defer mgr.Close()
+
+	targets, _, err := mgr.Targets.FilterByQuery(bson.M{"type": target.TypeWeb})
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	for _, t := range targets {
+		if err := m.checkTarget(mgr, t); err != nil {
+			logrus.Error(err)
+		}
+	}
+	return nil
+}
+
+// Issues returns problems of the target, the new monitor state and uniq id prefixes of checks which are done,
+// issues with these prefixes which aren't returned are fixed
+func (m *Monitor) Issues(t *target.Target) ([]*issue.Issue, *target.MonitorState, []string) {
+	state := &target.MonitorState{Checked: m.now()}
+	if t.Monitor != nil {
+		state.Dns = t.Monitor.Dns
+	}
+	issues := []*issue.Issue{}
+	checked := []string{}
+	host, port, secure := splitDomain(t.Addr())
+	if host == "" {
+		return issues, state, checked
+	}
+
+	if ip := net.ParseIP(host); ip == nil {
+		records, err := m.Records(host)
+		if err != nil {
+			logrus.Warnf("Dns lookup for %s failed: %s", host, err)
+		} else {
+			issues = append(issues, m.CheckDns(host, state.Dns, records)...)
+			state.Dns = records
+		}
+	}
+
+	if !secure {
+		// tls issues found before the target was switched to http are stale
+		return issues, state, append(checked, tlsPrefix)
+	}
+	tlsIssues, err := m.CheckTLS(net.JoinHostPort(host, port), host)
+	if err != nil {
+		// unreachable servers keep their issues till the next successful check
+		logrus.Warnf("Tls check for %s failed: %s", host, err)
+		return issues, state, checked
+	}
+	return append(issues, tlsIssues...), state, append(checked, tlsPrefix)
+}
+
+func (m *Monitor) checkTarget(mgr *manager.Manager, t *target.Target) error {
+	issues, state, checked := m.Issues(t)
+	reported := map[string]bool{}
+	for _, obj := range issues {
+		reported[obj.UniqId] = true
+		if err := m.saveIssue(mgr, t, obj); err != nil {
+			logrus.Error(err)
+		}
+	}
+	for _, prefix := range checked {
+		if err := m.resolveFixed(mgr, t, prefix, reported); err != nil {
+			logrus.Error(err)
+		}
+	}
+	return stackerr.Wrap(mgr.Targets.SetMonitor(t.Id, state))
+}
+
+// resolveFixed resolves open issues of the check which aren't reported anymore
+func (m *Monitor) resolveFixed(mgr *manager.Manager, t *target.Target, prefix string, reported map[string]bool) error {
+	issues, _, err := mgr.Issues.FilterByQuery(bson.M{
+		"target":   t.Id,
+		"resolved": false,
+		"false":    false,
+		"uniqId":   bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)},
+	})
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	for _, obj := range issues {
+		if reported[obj.UniqId] {
+			continue
+		}
+		obj.Move(issue.ColumnResolved, "")
+		if err := mgr.Issues.Update(obj); err != nil {
+			return stackerr.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// saveIssue creates the issue or reopens the existing one
+func (m *Monitor) saveIssue(mgr *manager.Manager, t *target.Target, obj *issue.Issue) error {
+	act := &issue.Activity{Type: issue.ActivityReported, Created: m.now()}
+	targetIssue := &issue.TargetIssue{
+		Target:     t.Id,
+		Project:    t.Project,
+		Issue:      *obj,
+		Exposure:   t.Exposure,
+		Activities: []*issue.Activity{act},
+	}
+	if _, err := mgr.Issues.Create(targetIssue); err == nil {
+		if _, err := mgr.Feed.AddIssue(targetIssue, "", ""); err != nil {
+			logrus.Error(stackerr.Wrap(err))
+		}
+		return nil
+	} else if !mgr.IsDup(err) {
+		return stackerr.Wrap(err)
+	}
+
+	targetIssue, err := mgr.Issues.GetByUniqId(t.Id, obj.UniqId)
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	if targetIssue.False {
+		return nil
+	}
+	if !targetIssue.Resolved {
+		// the problem is still there, the description could be changed, e.g. days till certificate expiration
+		if targetIssue.Desc == obj.Desc {
+			return nil
+		}
+		targetIssue.Desc = obj.Desc
+		return stackerr.Wrap(mgr.Issues.Update(targetIssue))
+	}
+	// the problem is back
+	targetIssue.Reopen()
+	targetIssue.Desc = obj.Desc
+	targetIssue.Activities = append(targetIssue.Activities, act)
+	if err := mgr.Issues.Update(targetIssue); err != nil {
+		return stackerr.Wrap(err)
+	}
+	return nil
+}
+
+// splitDomain returns host, port and whether tls is used from target domain like "https://example.com:8443/path",
+// domains without scheme are https
+func splitDomain(domain string) (string, string, bool) {
+	port, secure := "443", true
+	for _, prefix := range []string{"https://", "http://"} {
+		if len(domain) > len(prefix) && domain[:len(prefix)] == prefix {
+			domain = domain[len(prefix):]
+			if prefix == "http://" {
+				port, secure = "80", false
+			}
+			break
+		}
+	}
+	for i, c := range domain {
+		if c == '/' || c == '?' || c == '#' {
+			domain = domain[:i]
+			break
+		}
+	}
+	if host, p, err := net.SplitHostPort(domain); err == nil {
+		return host, p, secure
+	}
+	return domain, port, secure
+}
@@ -0,0 +1,183 @@
+package monitor
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/tests"
+)
+
+type fakeResolver struct {
+	hosts []string
+	mx    []*net.MX
+}
+
+func (r *fakeResolver) LookupHost(host string) ([]string, error) {
+	if r.hosts == nil {
+		return nil, fmt.Errorf("no such host %s", host)
+	}
+	return r.hosts, nil
+}
+func (r *fakeResolver) LookupCNAME(host string) (string, error) { return host + ".", nil }
+func (r *fakeResolver) LookupMX(name string) ([]*net.MX, error) { return r.mx, nil }
+func (r *fakeResolver) LookupNS(name string) ([]*net.NS, error) { return nil, nil }
+
+func uniqIds(issues []*issue.Issue) []string {
+	ids := []string{}
+	for _, i := range issues {
+		ids = append(ids, i.UniqId)
+	}
+	return ids
+}
+
+func TestCheckTLS(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "https://")
+
+	m := New(nil)
+	m.Timeout = time.Second
+	issues, err := m.CheckTLS(addr, "example.com")
+	require.NoError(t, err)
+	ids := uniqIds(issues)
+	// test certificate is self signed
+	assert.Contains(t, ids, "monitor:tls-chain")
+	assert.NotContains(t, ids, "monitor:tls-expired")
+	assert.NotContains(t, ids, "monitor:tls-expires-soon")
+
+	leaf := srv.Certificate()
+	m.now = func() time.Time { return leaf.NotAfter.Add(-24 * time.Hour) }
+	issues, err = m.CheckTLS(addr, "example.com")
+	require.NoError(t, err)
+	assert.Contains(t, uniqIds(issues), "monitor:tls-expires-soon")
+
+	m.now = func() time.Time { return leaf.NotAfter.Add(time.Hour) }
+	issues, err = m.CheckTLS(addr, "example.com")
+	require.NoError(t, err)
+	ids = uniqIds(issues)
+	assert.Contains(t, ids, "monitor:tls-expired")
+	assert.NotContains(t, ids, "monitor:tls-expires-soon")
+	assert.Equal(t, issue.SeverityHigh, issues[0].Severity)
+
+	_, err = m.CheckTLS("127.0.0.1:1", "localhost")
+	assert.Error(t, err)
+}
+
+func TestCheckDns(t *testing.T) {
+	res := &fakeResolver{
+		hosts: []string{"10.0.0.2", "10.0.0.1", "::1"},
+		mx:    []*net.MX{{Host: "mx.example.com."}},
+	}
+	m := New(nil)
+	m.Resolver = res
+
+	records, err := m.Records("example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"A 10.0.0.1", "A 10.0.0.2", "AAAA ::1", "MX mx.example.com."}, records)
+
+	// nothing to compare with
+	assert.Len(t, m.CheckDns("example.com", nil, records), 0)
+	assert.Len(t, m.CheckDns("example.com", records, records), 0)
+
+	changed := []string{"A 10.0.0.1", "A 10.0.0.3", "AAAA ::1", "MX mx.example.com."}
+	issues := m.CheckDns("example.com", records, changed)
+	require.Len(t, issues, 1)
+	assert.Equal(t, issue.SeverityInfo, issues[0].Severity)
+	assert.Contains(t, issues[0].Desc, "+ A 10.0.0.3")
+	assert.Contains(t, issues[0].Desc, "- A 10.0.0.2")
+	// all changes are reported as the same issue
+	assert.Equal(t, issues[0].UniqId, m.CheckDns("example.com", changed, records)[0].UniqId)
+
+	res.hosts = nil
+	_, err = m.Records("example.com")
+	assert.Error(t, err)
+}
+
+func TestIssues(t *testing.T) {
+	m := New(nil)
+	m.Timeout = time.Second
+	m.Resolver = &fakeResolver{hosts: []string{"10.0.0.1"}}
+
+	tg := &target.Target{Type: target.TypeWeb, Web: &target.WebTarget{Domain: "http://example.com:1/path"}}
+	issues, state, checked := m.Issues(tg)
+	assert.Len(t, issues, 0)
+	assert.Equal(t, []string{"A 10.0.0.1"}, state.Dns)
+	// tls isn't dialed for http targets, so their tls issues are fixed
+	assert.Equal(t, []string{tlsPrefix}, checked)
+
+	m.Resolver = &fakeResolver{hosts: []string{"10.0.0.2"}}
+	tg.Monitor = state
+	issues, state, _ = m.Issues(tg)
+	require.Len(t, issues, 1)
+	assert.Equal(t, []string{"A 10.0.0.2"}, state.Dns)
+
+	// unreachable https server keeps its tls issues
+	tg.Web.Domain = "https://127.0.0.1:1"
+	_, _, checked = m.Issues(tg)
+	assert.Len(t, checked, 0)
+}
+
+func TestResolveFixed(t *testing.T) {
+	mongo, dbName, err := tests.RandomTestMongoUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tests.RandomTestMongoDown(mongo, dbName)
+	mgr := manager.New(mongo.DB(dbName))
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	m := New(mgr)
+	m.Timeout = time.Second
+	tg, err := mgr.Targets.Create(&target.Target{Project: bson.NewObjectId(), Type: target.TypeWeb, Web: &target.WebTarget{Domain: srv.URL}})
+	require.NoError(t, err)
+	require.NoError(t, m.checkTarget(mgr, tg))
+	obj, err := mgr.Issues.GetByUniqId(tg.Id, "monitor:tls-chain")
+	require.NoError(t, err)
+	assert.False(t, obj.Resolved)
+
+	// the server isn't reachable, the issue stays open
+	srv.Close()
+	require.NoError(t, m.checkTarget(mgr, tg))
+	obj, err = mgr.Issues.GetByUniqId(tg.Id, "monitor:tls-chain")
+	require.NoError(t, err)
+	assert.False(t, obj.Resolved)
+
+	tg.Web.Domain = strings.Replace(srv.URL, "https://", "http://", 1)
+	require.NoError(t, m.checkTarget(mgr, tg))
+	obj, err = mgr.Issues.GetByUniqId(tg.Id, "monitor:tls-chain")
+	require.NoError(t, err)
+	assert.True(t, obj.Resolved)
+}
+
+func TestSplitDomain(t *testing.T) {
+	data := []struct {
+		domain, host, port string
+	}{
+		{"example.com", "example.com", "443"},
+		{"https://example.com/path?q", "example.com", "443"},
+		{"http://example.com:8443", "example.com", "8443"},
+		{"http://example.com", "example.com", "80"},
+		{"[::1]:444", "::1", "444"},
+		{"127.0.0.1", "127.0.0.1", "443"},
+	}
+	for _, d := range data {
+		host, port, secure := splitDomain(d.domain)
+		assert.Equal(t, d.host, host, d.domain)
+		assert.Equal(t, d.port, port, d.domain)
+		assert.Equal(t, !strings.HasPrefix(d.domain, "http://"), secure, d.domain)
+	}
+}
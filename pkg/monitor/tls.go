@@ -0,0 +1,137 @@
+package monitor
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/bearded-web/bearded/models/issue"
+)
+
+// cipher suites which are considered broken
+var weakCiphers = map[uint16]string{
+	tls.TLS_RSA_WITH_RC4_128_SHA:            "TLS_RSA_WITH_RC4_128_SHA",
+	tls.TLS_ECDHE_RSA_WITH_RC4_128_SHA:      "TLS_ECDHE_RSA_WITH_RC4_128_SHA",
+	tls.TLS_ECDHE_ECDSA_WITH_RC4_128_SHA:    "TLS_ECDHE_ECDSA_WITH_RC4_128_SHA",
+	tls.TLS_RSA_WITH_3DES_EDE_CBC_SHA:       "TLS_RSA_WITH_3DES_EDE_CBC_SHA",
+	tls.TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA: "TLS_ECDHE_RSA_WITH_3DES_EDE_CBC_SHA",
+}
+
+var weakSignatures = map[x509.SignatureAlgorithm]bool{
+	x509.MD2WithRSA:    true,
+	x509.MD5WithRSA:    true,
+	x509.SHA1WithRSA:   true,
+	x509.DSAWithSHA1:   true,
+	x509.ECDSAWithSHA1: true,
+}
+
+// CheckTLS connects to addr in host:port form and looks for certificate and protocol problems
+func (m *Monitor) CheckTLS(addr, serverName string) ([]*issue.Issue, error) {
+	conn, err := m.handshake(addr, &tls.Config{ServerName: serverName})
+	if err != nil {
+		return nil, err
+	}
+	state := conn.ConnectionState()
+	conn.Close()
+	if len(state.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("server %s didn't send certificates", addr)
+	}
+	leaf := state.PeerCertificates[0]
+	vector := &issue.Vector{Url: "https://" + addr}
+	now := m.now()
+
+	issues := []*issue.Issue{}
+	verifyAt := now
+	switch left := leaf.NotAfter.Sub(now); {
+	case left <= 0:
+		issues = append(issues, &issue.Issue{
+			UniqId:   "monitor:tls-expired",
+			Summary:  "TLS certificate is expired",
+			Severity: issue.SeverityHigh,
+			Desc:     fmt.Sprintf("Certificate for %s expired at %s", leaf.Subject.CommonName, leaf.NotAfter.UTC().Format(time.RFC3339)),
+			Vector:   vector,
+		})
+		// check the chain separately from expiration
+		verifyAt = leaf.NotAfter.Add(-time.Second)
+	case left < m.CertExpiry:
+		issues = append(issues, &issue.Issue{
+			UniqId:   "monitor:tls-expires-soon",
+			Summary:  "TLS certificate expires soon",
+			Severity: issue.SeverityMedium,
+			Desc: fmt.Sprintf("Certificate for %s expires at %s, in %d days",
+				leaf.Subject.CommonName, leaf.NotAfter.UTC().Format(time.RFC3339), int(left.Hours()/24)),
+			Vector: vector,
+		})
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Intermediates: intermediates,
+		Roots:         m.Roots,
+		CurrentTime:   verifyAt,
+	})
+	if err != nil {
+		issues = append(issues, &issue.Issue{
+			UniqId:   "monitor:tls-chain",
+			Summary:  "TLS certificate chain is invalid",
+			Severity: issue.SeverityMedium,
+			Desc:     fmt.Sprintf("Certificate verification failed: %s", err),
+			Vector:   vector,
+		})
+	}
+
+	if weakSignatures[leaf.SignatureAlgorithm] {
+		issues = append(issues, &issue.Issue{
+			UniqId:   "monitor:tls-weak-signature",
+			Summary:  "TLS certificate has weak signature",
+			Severity: issue.SeverityLow,
+			Desc:     fmt.Sprintf("Certificate is signed with %s", leaf.SignatureAlgorithm),
+			Vector:   vector,
+		})
+	}
+
+	if conn, err := m.handshake(addr, &tls.Config{ServerName: serverName, MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS11}); err == nil {
+		version := "1.0"
+		if conn.ConnectionState().Version == tls.VersionTLS11 {
+			version = "1.1"
+		}
+		conn.Close()
+		issues = append(issues, &issue.Issue{
+			UniqId:   "monitor:tls-old-protocol",
+			Summary:  "Deprecated TLS protocol is supported",
+			Severity: issue.SeverityLow,
+			Desc:     fmt.Sprintf("Server accepts TLS %s connections", version),
+			Vector:   vector,
+		})
+	}
+
+	suites := []uint16{}
+	for suite := range weakCiphers {
+		suites = append(suites, suite)
+	}
+	if conn, err := m.handshake(addr, &tls.Config{ServerName: serverName, CipherSuites: suites, MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS12}); err == nil {
+		suite := conn.ConnectionState().CipherSuite
+		conn.Close()
+		issues = append(issues, &issue.Issue{
+			UniqId:   "monitor:tls-weak-cipher",
+			Summary:  "Weak TLS cipher suites are supported",
+			Severity: issue.SeverityMedium,
+			Desc:     fmt.Sprintf("Server accepts %s cipher suite", weakCiphers[suite]),
+			Vector:   vector,
+		})
+	}
+
+	return issues, nil
+}
+
+func (m *Monitor) handshake(addr string, cfg *tls.Config) (*tls.Conn, error) {
+	// certificates are verified by hand to report problems instead of failing
+	cfg.InsecureSkipVerify = true
+	return tls.DialWithDialer(&net.Dialer{Timeout: m.Timeout}, "tcp", addr, cfg)
+}
@@ -0,0 +1,81 @@
+// Package netguard keeps requests which servers make to user supplied urls out of internal networks.
+//
+// Hosts are resolved on every dial and the connection is made to the checked address,
+// so redirects and dns records changed after the check don't reach loopback, private or link-local addresses.
+package netguard
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+var blocked = []*net.IPNet{}
+
+func init() {
+	for _, cidr := range []string{
+		"0.0.0.0/8",      // this network
+		"10.0.0.0/8",     // private
+		"100.64.0.0/10",  // carrier grade nat
+		"127.0.0.0/8",    // loopback
+		"169.254.0.0/16", // link-local, cloud metadata
+		"172.16.0.0/12",  // private
+		"192.168.0.0/16", // private
+		"::1/128",        // loopback
+		"fc00::/7",       // unique local
+		"fe80::/10",      // link-local
+	} {
+		_, n, _ := net.ParseCIDR(cidr)
+		blocked = append(blocked, n)
+	}
+}
+
+// Check returns an error if the address is internal
+func Check(ip net.IP) error {
+	if ip.IsUnspecified() || ip.IsMulticast() {
+		return fmt.Errorf("address %s isn't allowed", ip)
+	}
+	for _, n := range blocked {
+		if n.Contains(ip) {
+			return fmt.Errorf("address %s is internal", ip)
+		}
+	}
+	return nil
+}
+
+// Dial resolves the host of addr and connects to the first address which isn't internal,
+// it fails if all addresses are internal
+func Dial(network, addr string, timeout time.Duration) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return nil, err
+	}
+	for _, ip := range ips {
+		if err = Check(ip); err != nil {
+			continue
+		}
+		return net.DialTimeout(network, net.JoinHostPort(ip.String(), port), timeout)
+	}
+	if err == nil {
+		err = fmt.Errorf("host %s isn't resolved", host)
+	}
+	return nil, fmt.Errorf("host %s: %s", host, err)
+}
+
+// Client returns http client which can't reach internal addresses, including through redirects
+func Client(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Dial: func(network, addr string) (net.Conn, error) {
+				return Dial(network, addr, timeout)
+			},
+			TLSHandshakeTimeout: 10 * time.Second,
+		},
+	}
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"fmt"
+
+	"github.com/bearded-web/bearded/pkg/email"
+	"github.com/bearded-web/bearded/pkg/i18n"
+	"github.com/bearded-web/bearded/pkg/reply"
+)
+
+// EmailSender sends notifications as plain text emails
+type EmailSender struct {
+	mailer email.Mailer
+	from   string
+	host   string // used to make absolute links
+
+	Reply *reply.Addresser // set reply addresses for issue notifications, replies aren't accepted if nil
+	I18n  *i18n.Bundle     // translates the text added to notifications, could be nil
+}
+
+func NewEmailSender(mailer email.Mailer, from, host string) *EmailSender {
+	return &EmailSender{
+		mailer: mailer,
+		from:   from,
+		host:   host,
+	}
+}
+
+func (s *EmailSender) Send(n *Notification) error {
+	msg := email.NewMessage()
+	msg.SetHeader("From", msg.FormatAddress(s.from, "Bearded"))
+	msg.SetHeader("To", msg.FormatAddress(n.User.Email, n.User.Nickname))
+	msg.SetHeader("Subject", n.Subject)
+	replies := s.Reply != nil && n.Issue != ""
+	if replies {
+		msg.SetHeader("Reply-To", s.Reply.For(n.Issue, n.User.Id))
+	}
+	msg.SetBody("text/plain", EmailBody(n, s.host, replies, s.I18n))
+	return s.mailer.Send(msg)
+}
+
+// EmailBody returns the text of the notification email, replies tells that the email could be replied
+func EmailBody(n *Notification, host string, replies bool, b *i18n.Bundle) string {
+	body := n.Text
+	if n.Link != "" {
+		body = fmt.Sprintf("%s\n\n%s%s", body, host, n.Link)
+	}
+	if !n.Time.IsZero() && n.User != nil {
+		body = fmt.Sprintf("%s\n\n%s", body, n.User.FormatTime(n.Time))
+	}
+	if replies {
+		locale := ""
+		if n.User != nil {
+			locale = n.User.Locale
+		}
+		body = fmt.Sprintf("%s\n\n%s", body, b.Sprintf(locale, "Reply to this email to comment on the issue."))
+	}
+	return body
+}
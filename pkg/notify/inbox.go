@@ -0,0 +1,29 @@
+package notify
+
+import (
+	"github.com/bearded-web/bearded/models/inbox"
+	"github.com/bearded-web/bearded/pkg/manager"
+)
+
+// InboxSender saves notifications to the user inbox
+type InboxSender struct {
+	mgr *manager.Manager
+}
+
+func NewInboxSender(mgr *manager.Manager) *InboxSender {
+	return &InboxSender{mgr: mgr}
+}
+
+func (s *InboxSender) Send(n *Notification) error {
+	mgr := s.mgr.Copy()
+	defer mgr.Close()
+
+	_, err := mgr.Inbox.Create(&inbox.Item{
+		User:    n.User.Id,
+		Event:   n.Event,
+		Subject: n.Subject,
+		Text:    n.Text,
+		Link:    n.Link,
+	})
+	return err
+}
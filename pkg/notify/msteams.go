@@ -0,0 +1,125 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+
+	"github.com/bearded-web/bearded/models/project"
+)
+
+// max size of the error response kept in the error message
+const msTeamsMaxError = 512
+
+var ErrNotRegistered = errors.New("channel isn't configured on the server")
+
+// MsTeamsSender posts notifications to project channels in Microsoft Teams as connector cards
+type MsTeamsSender struct {
+	Client *http.Client
+	host   string // used to make absolute links
+}
+
+func NewMsTeamsSender(host string) *MsTeamsSender {
+	return &MsTeamsSender{
+		Client: &http.Client{Timeout: 10 * time.Second},
+		host:   host,
+	}
+}
+
+type msTeamsCard struct {
+	Type            string           `json:"@type"`
+	Context         string           `json:"@context"`
+	Summary         string           `json:"summary"`
+	Title           string           `json:"title"`
+	Text            string           `json:"text"`
+	PotentialAction []*msTeamsAction `json:"potentialAction,omitempty"`
+}
+
+type msTeamsAction struct {
+	Type    string           `json:"@type"`
+	Name    string           `json:"name"`
+	Targets []*msTeamsTarget `json:"targets"`
+}
+
+type msTeamsTarget struct {
+	Os  string `json:"os"`
+	Uri string `json:"uri"`
+}
+
+// Post sends the notification to the webhook url, the error contains the response of teams if it's rejected
+func (s *MsTeamsSender) Post(webhook string, n *Notification) error {
+	card := &msTeamsCard{
+		Type:    "MessageCard",
+		Context: "https://schema.org/extensions",
+		Summary: n.Subject,
+		Title:   n.Subject,
+		Text:    n.Text,
+	}
+	if n.Link != "" {
+		card.PotentialAction = []*msTeamsAction{{
+			Type:    "OpenUri",
+			Name:    "Open in Bearded",
+			Targets: []*msTeamsTarget{{Os: "default", Uri: s.host + n.Link}},
+		}}
+	}
+	data, err := json.Marshal(card)
+	if err != nil {
+		return err
+	}
+	resp, err := s.Client.Post(webhook, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, msTeamsMaxError))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("teams responded %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	// legacy connectors respond 200 with an error text instead of "1"
+	if text := string(bytes.TrimSpace(body)); text != "" && text != "1" {
+		return fmt.Errorf("teams responded: %s", text)
+	}
+	return nil
+}
+
+// NotifyProject posts the notification to project channels which allow its event,
+// it's called once per event unlike Notify which is called for every user.
+// It's safe to call NotifyProject on nil dispatcher.
+func (d *Dispatcher) NotifyProject(p *project.Project, n *Notification) error {
+	if d == nil || p == nil || !p.MsTeams.Allows(n.Event, n.Environment) {
+		return nil
+	}
+	err := d.PostMsTeams(p.MsTeams.Url, n)
+	if err != nil {
+		logrus.Errorf("Couldn't post %s notification of project %s to teams: %s", n.Event, p, err)
+	}
+	return err
+}
+
+// PostMsTeams sends the notification to the teams webhook, e.g. to test the channel
+func (d *Dispatcher) PostMsTeams(webhook string, n *Notification) error {
+	if d == nil {
+		return ErrNotRegistered
+	}
+	d.m.RLock()
+	teams := d.msTeams
+	d.m.RUnlock()
+	if teams == nil {
+		return ErrNotRegistered
+	}
+	return teams.Post(webhook, n)
+}
+
+// RegisterMsTeams sets sender for project channels in Microsoft Teams
+func (d *Dispatcher) RegisterMsTeams(s *MsTeamsSender) {
+	d.m.Lock()
+	d.msTeams = s
+	d.m.Unlock()
+}
@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bearded-web/bearded/models/notification"
+	"github.com/bearded-web/bearded/models/project"
+)
+
+func TestMsTeamsSender(t *testing.T) {
+	var card map[string]interface{}
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		card = nil
+		json.NewDecoder(req.Body).Decode(&card)
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			w.Write([]byte("Invalid webhook request"))
+			return
+		}
+		w.Write([]byte("1"))
+	}))
+	defer srv.Close()
+
+	d := New()
+	n := &Notification{Event: notification.EventScanFailed, Subject: "Scan failed", Text: "Scan is failed", Link: "/#/scan/1"}
+	assert.Equal(t, ErrNotRegistered, d.PostMsTeams(srv.URL, n))
+
+	d.RegisterMsTeams(NewMsTeamsSender("http://bearded"))
+	require.NoError(t, d.PostMsTeams(srv.URL, n))
+	assert.Equal(t, "MessageCard", card["@type"])
+	assert.Equal(t, "Scan failed", card["title"])
+	actions := card["potentialAction"].([]interface{})
+	require.Len(t, actions, 1)
+	targets := actions[0].(map[string]interface{})["targets"].([]interface{})
+	assert.Equal(t, "http://bearded/#/scan/1", targets[0].(map[string]interface{})["uri"])
+
+	status = http.StatusBadRequest
+	err := d.PostMsTeams(srv.URL, n)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid webhook request")
+
+	// project channel is posted only for allowed events
+	status = http.StatusOK
+	card = nil
+	p := &project.Project{MsTeams: &project.MsTeams{Enabled: true, Url: srv.URL,
+		Events: []notification.Event{notification.EventIssueEscalated}}}
+	assert.NoError(t, d.NotifyProject(p, n))
+	assert.Nil(t, card)
+	p.MsTeams.Events = nil
+	assert.NoError(t, d.NotifyProject(p, n))
+	assert.NotNil(t, card)
+}
@@ -0,0 +1,104 @@
+// Package notify delivers user notifications to channels according to user preferences.
+package notify
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/notification"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/i18n"
+)
+
+type Notification struct {
+	Event   notification.Event
+	User    *user.User
+	Subject string
+	Text    string
+	Link    string        // link to the object related to the notification
+	Issue   bson.ObjectId // issue of the notification, email replies to it are added as comments
+	Time    time.Time     // when the event happened, emails show it in the user timezone and date format
+
+	// environment of the target related to the notification, project channels could be limited to environments
+	Environment target.Environment
+
+	Channels []notification.Channel // send only to these channels instead of user preferences
+}
+
+// Sender delivers notification to one channel
+type Sender interface {
+	Send(n *Notification) error
+}
+
+type SenderFunc func(n *Notification) error
+
+func (f SenderFunc) Send(n *Notification) error {
+	return f(n)
+}
+
+type Dispatcher struct {
+	senders map[notification.Channel]Sender
+	msTeams *MsTeamsSender // project channels
+	m       sync.RWMutex
+
+	I18n *i18n.Bundle // translates notifications to user locales, could be nil
+}
+
+func New() *Dispatcher {
+	return &Dispatcher{
+		senders: map[notification.Channel]Sender{},
+	}
+}
+
+// Register sets sender for the channel, channels without senders are skipped
+func (d *Dispatcher) Register(ch notification.Channel, s Sender) {
+	d.m.Lock()
+	d.senders[ch] = s
+	d.m.Unlock()
+}
+
+// Notify sends notification to every channel enabled in the user preferences
+// or to the channels set in the notification.
+// All channels are tried, the first error is returned.
+// It's safe to call Notify on nil dispatcher.
+func (d *Dispatcher) Notify(n *Notification) error {
+	if d == nil || n.User == nil {
+		return nil
+	}
+	d.m.RLock()
+	defer d.m.RUnlock()
+	chs := n.Channels
+	if len(chs) == 0 {
+		chs = n.User.Notifications.Channels(n.Event)
+	}
+	if n.User.Locale != "" {
+		translated := *n
+		translated.Subject = d.I18n.Translate(n.User.Locale, n.Subject)
+		translated.Text = d.I18n.Translate(n.User.Locale, n.Text)
+		n = &translated
+	}
+	if n.Time.IsZero() {
+		// the notification is shared by users, so it's copied
+		stamped := *n
+		stamped.Time = time.Now().UTC()
+		n = &stamped
+	}
+	var first error
+	for _, ch := range chs {
+		s, ok := d.senders[ch]
+		if !ok {
+			continue
+		}
+		if err := s.Send(n); err != nil {
+			logrus.Errorf("Couldn't send %s notification to %s via %s: %s", n.Event, n.User, ch, err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
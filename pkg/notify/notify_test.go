@@ -0,0 +1,130 @@
+package notify
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/notification"
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/email"
+	"github.com/bearded-web/bearded/pkg/i18n"
+	"github.com/bearded-web/bearded/pkg/reply"
+)
+
+func TestDispatcher(t *testing.T) {
+	sent := map[notification.Channel]int{}
+	sender := func(ch notification.Channel, err error) Sender {
+		return SenderFunc(func(n *Notification) error {
+			sent[ch]++
+			return err
+		})
+	}
+	// the channel without default preferences
+	pager := notification.Channel("pager")
+	d := New()
+	d.Register(notification.ChannelEmail, sender(notification.ChannelEmail, nil))
+	d.Register(pager, sender(pager, errors.New("pager is down")))
+
+	u := &user.User{}
+	assert.NoError(t, d.Notify(&Notification{Event: notification.EventScanFailed, User: u}))
+	assert.Equal(t, 1, sent[notification.ChannelEmail])
+	assert.Equal(t, 0, sent[pager])
+
+	u.Notifications = notification.Preferences{
+		notification.EventScanFailed: []notification.Channel{pager, notification.ChannelEmail},
+	}
+	assert.Error(t, d.Notify(&Notification{Event: notification.EventScanFailed, User: u}))
+	assert.Equal(t, 2, sent[notification.ChannelEmail])
+	assert.Equal(t, 1, sent[pager])
+
+	assert.NoError(t, d.Notify(&Notification{Event: notification.EventScanFailed, User: u,
+		Channels: []notification.Channel{notification.ChannelEmail}}))
+	assert.Equal(t, 3, sent[notification.ChannelEmail])
+	assert.Equal(t, 1, sent[pager])
+
+	var nilDispatcher *Dispatcher
+	assert.NoError(t, nilDispatcher.Notify(&Notification{User: u}))
+}
+
+func TestEmailSender(t *testing.T) {
+	backend := email.NewMemoryBackend(1)
+	s := NewEmailSender(backend, "admin@localhost", "http://bearded")
+	err := s.Send(&Notification{
+		User:    &user.User{Email: "user@localhost"},
+		Subject: "Scan failed",
+		Text:    "Scan is failed",
+		Link:    "/#/scan/1",
+	})
+	require.NoError(t, err)
+	msg := <-backend.Messages()
+	body, err := ioutil.ReadAll(msg.Export().Body)
+	require.NoError(t, err)
+	assert.Equal(t, "Scan is failed\n\nhttp://bearded/#/scan/1", string(body))
+}
+
+func TestEmailSenderReply(t *testing.T) {
+	backend := email.NewMemoryBackend(2)
+	s := NewEmailSender(backend, "admin@localhost", "http://bearded")
+	addresser, err := reply.New("reply@bearded.example", "secret")
+	require.NoError(t, err)
+	s.Reply = addresser
+
+	u := &user.User{Id: bson.NewObjectId(), Email: "user@localhost"}
+	issueId := bson.NewObjectId()
+	require.NoError(t, s.Send(&Notification{User: u, Subject: "Issue is assigned", Text: "Issue is assigned", Issue: issueId}))
+	msg := <-backend.Messages()
+	assert.Equal(t, []string{addresser.For(issueId, u.Id)}, msg.GetHeader("Reply-To"))
+
+	// notifications without issues can't be replied
+	require.NoError(t, s.Send(&Notification{User: u, Subject: "Scan failed", Text: "Scan is failed"}))
+	msg = <-backend.Messages()
+	assert.Empty(t, msg.GetHeader("Reply-To"))
+}
+
+func TestDispatcherTranslates(t *testing.T) {
+	b := i18n.New()
+	require.NoError(t, b.Add("ru", map[string]string{
+		"Scan failed":                     "Сканирование завершилось ошибкой",
+		"Scan %s for target %s is failed": "Сканирование %s цели %s завершилось ошибкой",
+	}))
+	var got *Notification
+	d := New()
+	d.I18n = b
+	d.Register(notification.ChannelEmail, SenderFunc(func(n *Notification) error {
+		got = n
+		return nil
+	}))
+	n := &Notification{
+		Event:    notification.EventScanFailed,
+		User:     &user.User{Locale: "ru"},
+		Subject:  "Scan failed",
+		Text:     "Scan 1a for target example.com is failed",
+		Channels: []notification.Channel{notification.ChannelEmail},
+	}
+	require.NoError(t, d.Notify(n))
+	assert.Equal(t, "Сканирование завершилось ошибкой", got.Subject)
+	assert.Equal(t, "Сканирование 1a цели example.com завершилось ошибкой", got.Text)
+	// the notification is shared by users, so it isn't changed
+	assert.Equal(t, "Scan failed", n.Subject)
+	assert.False(t, got.Time.IsZero())
+	assert.True(t, n.Time.IsZero())
+}
+
+func TestEmailBodyTime(t *testing.T) {
+	n := &Notification{
+		User: &user.User{Timezone: "Europe/Moscow", DateFormat: user.DateEu},
+		Text: "Scan is failed",
+		Link: "/#/scan/1",
+		Time: time.Date(2015, 3, 4, 22, 30, 0, 0, time.UTC),
+	}
+	assert.Equal(t, "Scan is failed\n\nhttp://bearded/#/scan/1\n\n05.03.2015 01:30 MSK", EmailBody(n, "http://bearded", false, nil))
+
+	n.User = &user.User{}
+	assert.Equal(t, "Scan is failed\n\nhttp://bearded/#/scan/1\n\n2015-03-04 22:30 UTC", EmailBody(n, "http://bearded", false, nil))
+}
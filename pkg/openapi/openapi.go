@@ -0,0 +1,181 @@
+// Package openapi extracts operations from Swagger 2.0 and OpenAPI 3 specs in json format.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/bearded-web/bearded/models/target"
+)
+
+// max size of the downloaded spec
+const maxSpecSize = 5 << 20
+
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+type Spec struct {
+	Title     string
+	Version   string
+	BaseUrl   string
+	Endpoints []*target.Endpoint
+}
+
+type operation struct {
+	OperationId string `json:"operationId"`
+	Summary     string `json:"summary"`
+}
+
+type rawSpec struct {
+	Swagger string `json:"swagger"`
+	OpenApi string `json:"openapi"`
+	Info    struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	} `json:"info"`
+
+	// swagger 2.0
+	Host     string   `json:"host"`
+	BasePath string   `json:"basePath"`
+	Schemes  []string `json:"schemes"`
+
+	// openapi 3
+	Servers []struct {
+		Url string `json:"url"`
+	} `json:"servers"`
+
+	Paths map[string]map[string]json.RawMessage `json:"paths"`
+}
+
+// Parse reads the spec. Relative server urls are resolved against specUrl if it's set.
+func Parse(data []byte, specUrl string) (*Spec, error) {
+	raw := &rawSpec{}
+	if err := json.Unmarshal(data, raw); err != nil {
+		return nil, fmt.Errorf("spec should be a json document: %s", err)
+	}
+	if raw.Swagger == "" && raw.OpenApi == "" {
+		return nil, fmt.Errorf("swagger or openapi version field is required")
+	}
+	if raw.Swagger != "" && !strings.HasPrefix(raw.Swagger, "2.") {
+		return nil, fmt.Errorf("swagger version %s is not supported", raw.Swagger)
+	}
+	if raw.OpenApi != "" && !strings.HasPrefix(raw.OpenApi, "3.") {
+		return nil, fmt.Errorf("openapi version %s is not supported", raw.OpenApi)
+	}
+
+	spec := &Spec{
+		Title:     raw.Info.Title,
+		Version:   raw.Info.Version,
+		BaseUrl:   baseUrl(raw, specUrl),
+		Endpoints: []*target.Endpoint{},
+	}
+	for path, item := range raw.Paths {
+		for _, method := range methods {
+			data, ok := item[method]
+			if !ok {
+				continue
+			}
+			op := &operation{}
+			if err := json.Unmarshal(data, op); err != nil {
+				return nil, fmt.Errorf("wrong operation %s %s: %s", strings.ToUpper(method), path, err)
+			}
+			spec.Endpoints = append(spec.Endpoints, &target.Endpoint{
+				Method:      strings.ToUpper(method),
+				Path:        path,
+				OperationId: op.OperationId,
+				Summary:     op.Summary,
+			})
+		}
+	}
+	if len(spec.Endpoints) == 0 {
+		return nil, fmt.Errorf("spec doesn't have operations")
+	}
+	sort.Sort(byPath(spec.Endpoints))
+	return spec, nil
+}
+
+func baseUrl(raw *rawSpec, specUrl string) string {
+	var base string
+	if raw.Swagger != "" {
+		scheme := "https"
+		if len(raw.Schemes) > 0 {
+			scheme = raw.Schemes[0]
+		}
+		if raw.Host != "" {
+			base = fmt.Sprintf("%s://%s%s", scheme, raw.Host, raw.BasePath)
+		} else {
+			base = raw.BasePath
+		}
+	} else if len(raw.Servers) > 0 {
+		base = raw.Servers[0].Url
+	}
+	if specUrl == "" {
+		return base
+	}
+	// spec without host is served by the api itself
+	ref, err := url.Parse(base)
+	if err != nil {
+		return base
+	}
+	root, err := url.Parse(specUrl)
+	if err != nil {
+		return base
+	}
+	if base == "" {
+		ref = &url.URL{Path: "/"}
+	}
+	return strings.TrimSuffix(root.ResolveReference(ref).String(), "/")
+}
+
+// Fetch downloads the spec with the client. Spec urls are given by users,
+// so servers should use a client which can't reach internal networks, like netguard.Client.
+func Fetch(client *http.Client, specUrl string) ([]byte, error) {
+	u, err := url.Parse(specUrl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("scheme must be http or https")
+	}
+	resp, err := client.Get(specUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("spec url returned %s", resp.Status)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxSpecSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxSpecSize {
+		return nil, fmt.Errorf("spec is bigger than %d bytes", maxSpecSize)
+	}
+	return data, nil
+}
+
+type byPath []*target.Endpoint
+
+func (e byPath) Len() int      { return len(e) }
+func (e byPath) Swap(i, j int) { e[i], e[j] = e[j], e[i] }
+func (e byPath) Less(i, j int) bool {
+	if e[i].Path != e[j].Path {
+		return e[i].Path < e[j].Path
+	}
+	return methodIndex(e[i].Method) < methodIndex(e[j].Method)
+}
+
+func methodIndex(method string) int {
+	for i, m := range methods {
+		if strings.ToUpper(m) == method {
+			return i
+		}
+	}
+	return len(methods)
+}
@@ -0,0 +1,101 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const swagger = `{
+	"swagger": "2.0",
+	"info": {"title": "Petstore", "version": "1.0.0"},
+	"host": "petstore.example.com",
+	"basePath": "/v1",
+	"schemes": ["http"],
+	"paths": {
+		"/pets/{id}": {
+			"parameters": [{"name": "id", "in": "path"}],
+			"delete": {"operationId": "deletePet"},
+			"get": {"operationId": "getPet", "summary": "Info for a pet"}
+		},
+		"/pets": {
+			"get": {"operationId": "listPets"},
+			"post": {"operationId": "createPet"}
+		}
+	}
+}`
+
+func TestParseSwagger(t *testing.T) {
+	spec, err := Parse([]byte(swagger), "")
+	require.NoError(t, err)
+	assert.Equal(t, "Petstore", spec.Title)
+	assert.Equal(t, "1.0.0", spec.Version)
+	assert.Equal(t, "http://petstore.example.com/v1", spec.BaseUrl)
+
+	ops := []string{}
+	for _, e := range spec.Endpoints {
+		ops = append(ops, e.Operation())
+	}
+	assert.Equal(t, []string{"GET /pets", "POST /pets", "GET /pets/{id}", "DELETE /pets/{id}"}, ops)
+	assert.Equal(t, "getPet", spec.Endpoints[2].OperationId)
+	assert.Equal(t, "Info for a pet", spec.Endpoints[2].Summary)
+}
+
+func TestParseOpenApi(t *testing.T) {
+	data := []byte(`{
+		"openapi": "3.0.1",
+		"info": {"title": "Users"},
+		"servers": [{"url": "/api"}],
+		"paths": {"/users": {"get": {}}}
+	}`)
+	spec, err := Parse(data, "https://example.com/docs/openapi.json")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/api", spec.BaseUrl)
+	require.Len(t, spec.Endpoints, 1)
+	assert.Equal(t, "GET /users", spec.Endpoints[0].Operation())
+
+	// without servers the spec host is used
+	spec, err = Parse([]byte(`{"openapi": "3.0.0", "paths": {"/": {"get": {}}}}`), "https://example.com/openapi.json")
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", spec.BaseUrl)
+}
+
+func TestParseErrors(t *testing.T) {
+	data := []string{
+		`openapi: 3.0.0`,
+		`{"info": {}}`,
+		`{"swagger": "1.2", "paths": {"/": {"get": {}}}}`,
+		`{"openapi": "3.0.0", "paths": {}}`,
+		`{"openapi": "3.0.0", "paths": {"/": {"get": []}}}`,
+	}
+	for _, d := range data {
+		_, err := Parse([]byte(d), "")
+		assert.Error(t, err, d)
+	}
+}
+
+func TestFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/swagger.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(swagger))
+	}))
+	defer srv.Close()
+	client := &http.Client{Timeout: time.Second}
+
+	data, err := Fetch(client, srv.URL+"/swagger.json")
+	require.NoError(t, err)
+	assert.Equal(t, swagger, string(data))
+
+	_, err = Fetch(client, srv.URL+"/missing.json")
+	assert.Error(t, err)
+
+	_, err = Fetch(client, "ftp://example.com/swagger.json")
+	assert.Error(t, err)
+}
@@ -4,4 +4,6 @@ type Meta struct {
 	Count    int    `json:"count"`
 	Next     string `json:"next"`
 	Previous string `json:"previous"`
+	// count isn't calculated, it's only known if there is the next page
+	Approximate bool `json:"approximate,omitempty"`
 }
@@ -0,0 +1,129 @@
+// Package reply makes signed reply addresses for issue notifications and parses replies sent to them.
+// The address carries the issue and the user, like reply+<token>@bearded.example,
+// so a reply could be added as a comment of the user without logging in.
+package reply
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	idLen  = 12 // length of bson object id
+	sigLen = 10 // signature is truncated to fit the local part in 64 chars
+)
+
+var (
+	ErrNoAddress      = errors.New("reply address isn't found in recipients")
+	ErrMalformedToken = errors.New("malformed reply token")
+	ErrWrongSignature = errors.New("wrong reply token signature")
+)
+
+// local parts are case insensitive for some servers, so lower base32 is used
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Addresser makes and parses reply addresses of one mailbox
+type Addresser struct {
+	local  string
+	domain string
+	secret []byte
+}
+
+// New returns addresser for the mailbox, like reply@bearded.example. The secret signs tokens.
+func New(address, secret string) (*Addresser, error) {
+	addr, err := mail.ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	i := strings.LastIndex(addr.Address, "@")
+	if i <= 0 {
+		return nil, fmt.Errorf("address %s has no domain", address)
+	}
+	if secret == "" {
+		return nil, errors.New("secret is required")
+	}
+	return &Addresser{
+		local:  addr.Address[:i],
+		domain: strings.ToLower(addr.Address[i+1:]),
+		secret: []byte(secret),
+	}, nil
+}
+
+// For returns the reply address of the user for the issue
+func (a *Addresser) For(issueId, userId bson.ObjectId) string {
+	b := make([]byte, 0, idLen*2+sigLen)
+	b = append(b, []byte(issueId)...)
+	b = append(b, []byte(userId)...)
+	b = append(b, a.sign(b)...)
+	return fmt.Sprintf("%s+%s@%s", a.local, strings.ToLower(encoding.EncodeToString(b)), a.domain)
+}
+
+// Parse finds the reply address in the recipient list and returns the issue and the user of it
+func (a *Addresser) Parse(recipients string) (bson.ObjectId, bson.ObjectId, error) {
+	list, err := mail.ParseAddressList(recipients)
+	if err != nil {
+		// providers pass bare addresses too
+		list = []*mail.Address{}
+		for _, addr := range strings.Split(recipients, ",") {
+			list = append(list, &mail.Address{Address: strings.TrimSpace(addr)})
+		}
+	}
+	prefix := strings.ToLower(a.local + "+")
+	suffix := "@" + a.domain
+	for _, addr := range list {
+		email := strings.ToLower(addr.Address)
+		if !strings.HasPrefix(email, prefix) || !strings.HasSuffix(email, suffix) {
+			continue
+		}
+		return a.verify(email[len(prefix) : len(email)-len(suffix)])
+	}
+	return "", "", ErrNoAddress
+}
+
+func (a *Addresser) verify(token string) (bson.ObjectId, bson.ObjectId, error) {
+	b, err := encoding.DecodeString(strings.ToUpper(token))
+	if err != nil || len(b) != idLen*2+sigLen {
+		return "", "", ErrMalformedToken
+	}
+	data, sig := b[:idLen*2], b[idLen*2:]
+	if subtle.ConstantTimeCompare(a.sign(data), sig) != 1 {
+		return "", "", ErrWrongSignature
+	}
+	return bson.ObjectId(data[:idLen]), bson.ObjectId(data[idLen:]), nil
+}
+
+func (a *Addresser) sign(data []byte) []byte {
+	m := hmac.New(sha256.New, a.secret)
+	m.Write(data)
+	return m.Sum(nil)[:sigLen]
+}
+
+// the line which mail clients put before the quoted message, like "On Mon, Jan 2, 2006, John <john@example.com> wrote:"
+var quoteHeader = regexp.MustCompile(`(?i)^(on\s.+wrote:|-+\s*original message\s*-+|from:\s.+)$`)
+
+// Strip returns the reply text without the quoted message and the signature
+func Strip(text string) string {
+	lines := []string{}
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "--" || line == "-- " || quoteHeader.MatchString(strings.TrimSpace(line)) {
+			break
+		}
+		if strings.HasPrefix(line, ">") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
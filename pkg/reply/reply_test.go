@@ -0,0 +1,50 @@
+package reply
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/mgo.v2/bson"
+)
+
+func TestAddresser(t *testing.T) {
+	a, err := New("Bearded <reply@Bearded.example>", "secret")
+	require.NoError(t, err)
+	issueId, userId := bson.NewObjectId(), bson.NewObjectId()
+
+	addr := a.For(issueId, userId)
+	assert.True(t, strings.HasPrefix(addr, "reply+"))
+	assert.True(t, strings.HasSuffix(addr, "@bearded.example"))
+	assert.True(t, len(addr[:strings.Index(addr, "@")]) <= 64)
+
+	gotIssue, gotUser, err := a.Parse("team@example.com, Bearded <" + strings.ToUpper(addr) + ">")
+	require.NoError(t, err)
+	assert.Equal(t, issueId, gotIssue)
+	assert.Equal(t, userId, gotUser)
+
+	_, _, err = a.Parse("team@example.com")
+	assert.Equal(t, ErrNoAddress, err)
+	_, _, err = a.Parse("reply+abc@bearded.example")
+	assert.Equal(t, ErrMalformedToken, err)
+
+	other, err := New("reply@bearded.example", "other")
+	require.NoError(t, err)
+	_, _, err = other.Parse(addr)
+	assert.Equal(t, ErrWrongSignature, err)
+
+	_, err = New("reply@bearded.example", "")
+	assert.Error(t, err)
+}
+
+func TestStrip(t *testing.T) {
+	text := "Fixed in the last release.\r\nPlease retest.\r\n\r\n" +
+		"On Mon, Jan 2, 2006 at 3:04 PM, Bearded <reply+abc@bearded.example> wrote:\r\n" +
+		"> Issue \"Sql injection\" is assigned to your team\r\n"
+	assert.Equal(t, "Fixed in the last release.\nPlease retest.", Strip(text))
+
+	assert.Equal(t, "Ok", Strip("Ok\n-- \nJohn, security team"))
+	assert.Equal(t, "Inline answer", Strip("> quoted\nInline answer\n"))
+	assert.Equal(t, "", Strip("> quoted only"))
+}
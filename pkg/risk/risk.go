@@ -0,0 +1,91 @@
+// Package risk computes composite risk scores for issues
+// from severity, target criticality and exposure.
+package risk
+
+import (
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/pkg/config"
+)
+
+// MaxScore is the upper bound for scores
+const MaxScore = 100
+
+var (
+	severityOrder    = []issue.Severity{issue.SeverityInfo, issue.SeverityLow, issue.SeverityMedium, issue.SeverityHigh}
+	criticalityOrder = []target.Criticality{target.CriticalityLow, target.CriticalityMedium, target.CriticalityHigh, target.CriticalityCritical}
+)
+
+type Model struct {
+	severity       map[issue.Severity]int
+	criticality    map[target.Criticality]int
+	internetFacing int
+	authRequired   int
+	exploitable    int
+}
+
+// New creates the scoring model from config, wrong values are taken from the default config.
+// Zero multipliers are allowed, they make scores of such issues zero.
+func New(cfg config.Risk) *Model {
+	def := config.NewDispatcher().Risk
+	if len(cfg.Severity) != len(severityOrder) {
+		cfg.Severity = def.Severity
+	}
+	if len(cfg.Criticality) != len(criticalityOrder) {
+		cfg.Criticality = def.Criticality
+	}
+	if cfg.InternetFacing < 0 {
+		cfg.InternetFacing = def.InternetFacing
+	}
+	if cfg.AuthRequired < 0 {
+		cfg.AuthRequired = def.AuthRequired
+	}
+	if cfg.Exploitable < 0 {
+		cfg.Exploitable = def.Exploitable
+	}
+	m := &Model{
+		severity:       map[issue.Severity]int{},
+		criticality:    map[target.Criticality]int{},
+		internetFacing: cfg.InternetFacing,
+		authRequired:   cfg.AuthRequired,
+		exploitable:    cfg.Exploitable,
+	}
+	for i, sev := range severityOrder {
+		m.severity[sev] = cfg.Severity[i]
+	}
+	for i, crit := range criticalityOrder {
+		m.criticality[crit] = cfg.Criticality[i]
+	}
+	return m
+}
+
+// Default returns the model with default weights
+func Default() *Model {
+	return New(config.NewDispatcher().Risk)
+}
+
+// Score returns the risk score in range [0, MaxScore], exploitable issues have known exploits
+func (m *Model) Score(sev issue.Severity, crit target.Criticality, exp issue.Exposure, exploitable bool) int {
+	score := m.severity[sev]
+	if mult, ok := m.criticality[crit]; ok {
+		score = score * mult / 100
+	} else {
+		score = score * m.criticality[target.CriticalityMedium] / 100
+	}
+	if exp.InternetFacing {
+		score = score * m.internetFacing / 100
+	}
+	if exp.AuthRequired {
+		score = score * m.authRequired / 100
+	}
+	if exploitable {
+		score = score * m.exploitable / 100
+	}
+	if score > MaxScore {
+		score = MaxScore
+	}
+	if score < 0 {
+		score = 0
+	}
+	return score
+}
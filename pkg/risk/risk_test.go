@@ -0,0 +1,41 @@
+package risk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/pkg/config"
+)
+
+func TestScore(t *testing.T) {
+	m := Default()
+	assert.Equal(t, 0, m.Score(issue.SeverityInfo, target.CriticalityCritical, issue.Exposure{InternetFacing: true}, false))
+	assert.Equal(t, 80, m.Score(issue.SeverityHigh, target.CriticalityHigh, issue.Exposure{}, false))
+	// empty criticality is medium
+	assert.Equal(t, 40, m.Score(issue.SeverityMedium, "", issue.Exposure{}, false))
+	assert.Equal(t, 100, m.Score(issue.SeverityHigh, target.CriticalityCritical, issue.Exposure{InternetFacing: true}, false))
+	assert.Equal(t, 35, m.Score(issue.SeverityMedium, target.CriticalityHigh, issue.Exposure{AuthRequired: true}, false))
+	assert.Equal(t, 0, m.Score(issue.SeverityError, target.CriticalityHigh, issue.Exposure{}, false))
+	assert.Equal(t, 60, m.Score(issue.SeverityMedium, target.CriticalityMedium, issue.Exposure{}, true))
+	assert.Equal(t, 96, m.Score(issue.SeverityHigh, target.CriticalityMedium, issue.Exposure{}, true))
+}
+
+func TestNew(t *testing.T) {
+	m := New(config.Risk{
+		Severity:       []int{1, 2, 3, 4},
+		Criticality:    []int{100, 100, 100, 200},
+		InternetFacing: 300,
+	})
+	assert.Equal(t, 8, m.Score(issue.SeverityHigh, target.CriticalityCritical, issue.Exposure{}, false))
+	assert.Equal(t, 9, m.Score(issue.SeverityMedium, target.CriticalityLow, issue.Exposure{InternetFacing: true}, false))
+	// wrong length, default is used
+	m = New(config.Risk{Severity: []int{1}})
+	assert.Equal(t, 80, m.Score(issue.SeverityHigh, target.CriticalityHigh, issue.Exposure{}, false))
+	// zero multiplier is allowed, negative is replaced by default
+	m = New(config.Risk{InternetFacing: 0, Exploitable: -1})
+	assert.Equal(t, 0, m.Score(issue.SeverityHigh, target.CriticalityHigh, issue.Exposure{InternetFacing: true}, false))
+	assert.Equal(t, 96, m.Score(issue.SeverityHigh, target.CriticalityMedium, issue.Exposure{}, true))
+}
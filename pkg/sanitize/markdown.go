@@ -0,0 +1,184 @@
+package sanitize
+
+import (
+	"strings"
+)
+
+// markdown finds code in the text, which is kept as is. Code is detected conservatively,
+// because text taken for code isn't sanitized: fences start only at the first column and
+// nothing is code in raw html blocks, where the renderer doesn't look for markdown.
+// Lines starting with a tag are taken for html blocks, which end with a blank line
+// or with the line closing the tag for pre-like blocks.
+type markdown struct {
+	html     bool
+	htmlEnd  string // closing tag of pre-like blocks, which could contain blank lines
+	htmlDone bool   // the html block ends with the current line
+	// there was a backtick string without the closing one, the renderer could match it
+	// with a backtick string of a later line, so spans aren't detected till the end of the paragraph
+	unmatched bool
+}
+
+// tags which raw html blocks end with the closing tag instead of a blank line
+var preTags = map[string]bool{"pre": true, "script": true, "style": true, "textarea": true}
+
+// line is called at the start of every line, it returns the end of the fenced code block
+// starting at i or i if there is no block
+func (m *markdown) line(s string, i int) int {
+	if m.htmlDone {
+		m.html, m.htmlEnd, m.htmlDone = false, "", false
+	}
+	line := s[i:lineEnd(s, i)]
+	if strings.TrimSpace(line) == "" {
+		if m.htmlEnd == "" {
+			m.html = false
+		}
+		m.unmatched = false
+		return i
+	}
+	if m.html {
+		m.htmlDone = m.htmlEnd != "" && strings.Contains(strings.ToLower(line), m.htmlEnd)
+		return i
+	}
+	if name, ok := htmlStart(s, i); ok {
+		m.html = true
+		if preTags[name] {
+			m.htmlEnd = "</" + name
+			m.htmlDone = strings.Contains(strings.ToLower(line), m.htmlEnd)
+		}
+		return i
+	}
+	fence := s[i]
+	if fence != '`' && fence != '~' {
+		return i
+	}
+	n := run(s, i)
+	if n < 3 || (fence == '`' && strings.IndexByte(line[n:], '`') >= 0) {
+		return i
+	}
+	m.unmatched = false
+	// the block without the closing fence lasts till the end of the text
+	for j := lineEnd(s, i); j < len(s); j = lineEnd(s, j) {
+		if closingFence(s[j:lineEnd(s, j)], fence, n) {
+			return lineEnd(s, j)
+		}
+	}
+	return len(s)
+}
+
+// span returns the end of the code span starting at i,
+// if there is no span, the end of the backtick string is returned
+func (m *markdown) span(s string, i int) int {
+	n := run(s, i)
+	if m.html {
+		return i + n
+	}
+	if m.unmatched || escaped(s, i) {
+		m.unmatched = true
+		return i + n
+	}
+	// spans are matched only inside one line and one table cell, the renderer could match
+	// them across lines, but the text between would be sanitized then, which is safe
+	end := lineEnd(s, i)
+	for j := i + n; j < end; {
+		if s[j] == '|' {
+			break
+		}
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		k := run(s, j)
+		if k == n {
+			return j + k
+		}
+		j += k
+	}
+	m.unmatched = true
+	return i + n
+}
+
+// htmlStart reports if the line starting at i is taken for raw html, the tag name is returned.
+// List and quote markers are skipped, comments are skipped too, because they are removed.
+func htmlStart(s string, i int) (string, bool) {
+	j := i
+	for j < len(s) {
+		switch {
+		case s[j] == ' ' || s[j] == '\t' || s[j] == '>':
+			j++
+		case (s[j] == '-' || s[j] == '*' || s[j] == '+') && j+1 < len(s) && (s[j+1] == ' ' || s[j+1] == '\t'):
+			j += 2
+		case s[j] >= '0' && s[j] <= '9':
+			k := j
+			for k < len(s) && s[k] >= '0' && s[k] <= '9' {
+				k++
+			}
+			if k+1 >= len(s) || (s[k] != '.' && s[k] != ')') || (s[k+1] != ' ' && s[k+1] != '\t') {
+				return "", false
+			}
+			j = k + 2
+		case strings.HasPrefix(s[j:], "<!--"):
+			end := strings.Index(s[j+4:], "-->")
+			if end < 0 {
+				return "", false
+			}
+			j += 4 + end + 3
+		default:
+			if s[j] != '<' || j+1 >= len(s) {
+				return "", false
+			}
+			j++
+			if s[j] == '/' {
+				j++
+			}
+			start := j
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+			if j == start || !isLetter(s[start]) {
+				return "", false
+			}
+			return strings.ToLower(s[start:j]), true
+		}
+	}
+	return "", false
+}
+
+// closingFence reports if the line closes the block opened by n fence characters
+func closingFence(line string, fence byte, n int) bool {
+	i := 0
+	for i < len(line) && i < 3 && line[i] == ' ' {
+		i++
+	}
+	if i >= len(line) || line[i] != fence {
+		return false
+	}
+	k := run(line, i)
+	return k >= n && strings.TrimSpace(line[i+k:]) == ""
+}
+
+// run returns the length of the string of the same characters starting at i
+func run(s string, i int) int {
+	j := i
+	for j < len(s) && s[j] == s[i] {
+		j++
+	}
+	return j - i
+}
+
+// escaped reports if the character at i is escaped with a backslash
+func escaped(s string, i int) bool {
+	n := 0
+	for j := i - 1; j >= 0 && s[j] == '\\'; j-- {
+		n++
+	}
+	return n%2 == 1
+}
+
+// lineEnd returns the position after the end of the line
+func lineEnd(s string, i int) int {
+	end := strings.IndexByte(s[i:], '\n')
+	if end < 0 {
+		return len(s)
+	}
+	return i + end + 1
+}
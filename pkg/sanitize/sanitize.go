@@ -0,0 +1,314 @@
+// Package sanitize cleans user supplied rich text from dangerous html.
+//
+// Allowed tags are kept with allowed attributes only, other tags are escaped,
+// so they are shown as text instead of being silently removed.
+// Text is not escaped otherwise, because fields are usually markdown.
+// Markdown code in fences and spans is kept as is, the renderer escapes it anyway.
+// Sanitize is idempotent, so already sanitized text could be passed again.
+package sanitize
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+)
+
+const (
+	PolicyStrict = "strict" // no tags at all
+	PolicyBasic  = "basic"  // formatting tags and links
+	PolicyNone   = "none"   // sanitizing is disabled
+)
+
+// attributes with urls, their values are checked against allowed schemes
+var urlAttrs = map[string]bool{"href": true, "src": true, "cite": true}
+
+var basicTags = map[string][]string{
+	"a":          {"href", "title"},
+	"b":          nil,
+	"strong":     nil,
+	"i":          nil,
+	"em":         nil,
+	"u":          nil,
+	"s":          nil,
+	"del":        nil,
+	"sub":        nil,
+	"sup":        nil,
+	"code":       nil,
+	"pre":        nil,
+	"p":          nil,
+	"br":         nil,
+	"hr":         nil,
+	"ul":         nil,
+	"ol":         nil,
+	"li":         nil,
+	"blockquote": {"cite"},
+	"h1":         nil,
+	"h2":         nil,
+	"h3":         nil,
+	"h4":         nil,
+	"h5":         nil,
+	"h6":         nil,
+	"table":      nil,
+	"thead":      nil,
+	"tbody":      nil,
+	"tr":         nil,
+	"th":         nil,
+	"td":         nil,
+}
+
+type Policy struct {
+	// allowed tags with their allowed attributes
+	Tags map[string][]string
+	// allowed url schemes, relative urls are always allowed
+	Schemes []string
+}
+
+// New returns the policy by name, extra tags are allowed without attributes.
+// Nil policy is returned for PolicyNone, it keeps text as is.
+func New(name string, extra []string) (*Policy, error) {
+	p := &Policy{
+		Tags:    map[string][]string{},
+		Schemes: []string{"http", "https", "mailto"},
+	}
+	switch name {
+	case PolicyNone:
+		return nil, nil
+	case PolicyStrict:
+	case PolicyBasic, "":
+		for tag, attrs := range basicTags {
+			p.Tags[tag] = attrs
+		}
+	default:
+		return nil, fmt.Errorf("Unknown sanitize policy %s, should be one of [strict|basic|none]", name)
+	}
+	for _, tag := range extra {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if !isName(tag) {
+			return nil, fmt.Errorf("Wrong tag name %q", tag)
+		}
+		switch tag {
+		case "script", "style", "iframe", "object", "embed", "frame", "frameset", "base", "meta", "link", "form":
+			return nil, fmt.Errorf("Tag %s couldn't be allowed", tag)
+		}
+		if _, ok := p.Tags[tag]; !ok {
+			p.Tags[tag] = nil
+		}
+	}
+	return p, nil
+}
+
+// AllowedTags returns sorted list of allowed tags
+func (p *Policy) AllowedTags() []string {
+	if p == nil {
+		return nil
+	}
+	tags := []string{}
+	for tag := range p.Tags {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// Sanitize returns s with dangerous html escaped, nil policy returns s as is
+func (p *Policy) Sanitize(s string) string {
+	if p == nil || !strings.Contains(s, "<") {
+		return s
+	}
+	buf := make([]byte, 0, len(s))
+	md := &markdown{}
+	for i := 0; i < len(s); {
+		if i == 0 || s[i-1] == '\n' {
+			if end := md.line(s, i); end > i {
+				buf = append(buf, s[i:end]...)
+				i = end
+				continue
+			}
+		}
+		if s[i] == '`' {
+			end := md.span(s, i)
+			buf = append(buf, s[i:end]...)
+			i = end
+			continue
+		}
+		if s[i] != '<' {
+			buf = append(buf, s[i])
+			i++
+			continue
+		}
+		// html comments could hide conditional markup, so they are removed completely
+		if strings.HasPrefix(s[i:], "<!--") {
+			end := strings.Index(s[i+4:], "-->")
+			if end < 0 {
+				i = len(s)
+			} else {
+				i += 4 + end + 3
+			}
+			continue
+		}
+		end, tag := scanTag(s, i)
+		if end < 0 {
+			buf = append(buf, "&lt;"...)
+			i++
+			continue
+		}
+		if out, ok := p.tag(tag); ok {
+			buf = append(buf, out...)
+		} else {
+			buf = append(buf, escape(s[i:end])...)
+		}
+		i = end
+	}
+	return string(buf)
+}
+
+type token struct {
+	name    string
+	closing bool
+	attrs   [][2]string
+}
+
+// scanTag parses the tag starting at i, it returns the position after the tag or -1
+// if there is no tag
+func scanTag(s string, i int) (int, *token) {
+	t := &token{}
+	j := i + 1
+	if j < len(s) && s[j] == '/' {
+		t.closing = true
+		j++
+	}
+	start := j
+	for j < len(s) && isNameChar(s[j]) {
+		j++
+	}
+	if j == start || !isLetter(s[start]) {
+		return -1, nil
+	}
+	t.name = strings.ToLower(s[start:j])
+	for {
+		for j < len(s) && (isSpace(s[j]) || s[j] == '/') {
+			j++
+		}
+		if j >= len(s) {
+			return -1, nil
+		}
+		if s[j] == '>' {
+			return j + 1, t
+		}
+		if s[j] == '<' {
+			return -1, nil
+		}
+		// attribute name
+		start = j
+		for j < len(s) && !isSpace(s[j]) && s[j] != '=' && s[j] != '>' && s[j] != '/' {
+			j++
+		}
+		name := strings.ToLower(s[start:j])
+		for j < len(s) && isSpace(s[j]) {
+			j++
+		}
+		value := ""
+		if j < len(s) && s[j] == '=' {
+			j++
+			for j < len(s) && isSpace(s[j]) {
+				j++
+			}
+			if j >= len(s) {
+				return -1, nil
+			}
+			if q := s[j]; q == '"' || q == '\'' {
+				end := strings.IndexByte(s[j+1:], q)
+				if end < 0 {
+					return -1, nil
+				}
+				value = s[j+1 : j+1+end]
+				j += end + 2
+			} else {
+				start = j
+				for j < len(s) && !isSpace(s[j]) && s[j] != '>' {
+					j++
+				}
+				value = s[start:j]
+			}
+		}
+		t.attrs = append(t.attrs, [2]string{name, html.UnescapeString(value)})
+	}
+}
+
+// tag returns the clean tag if it's allowed
+func (p *Policy) tag(t *token) (string, bool) {
+	allowed, ok := p.Tags[t.name]
+	if !ok {
+		return "", false
+	}
+	if t.closing {
+		return "</" + t.name + ">", true
+	}
+	out := "<" + t.name
+	for _, attr := range t.attrs {
+		if !contains(allowed, attr[0]) {
+			continue
+		}
+		if urlAttrs[attr[0]] && !p.safeUrl(attr[1]) {
+			continue
+		}
+		out += fmt.Sprintf(` %s="%s"`, attr[0], html.EscapeString(attr[1]))
+	}
+	return out + ">", true
+}
+
+func (p *Policy) safeUrl(u string) bool {
+	// browsers ignore control characters and spaces inside of schemes, like java\tscript:
+	clean := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, u)
+	colon := strings.IndexByte(clean, ':')
+	if colon < 0 || strings.IndexAny(clean[:colon], "/?#") >= 0 {
+		// relative url
+		return true
+	}
+	scheme := strings.ToLower(clean[:colon])
+	return contains(p.Schemes, scheme)
+}
+
+// backticks of escaped tags could start code spans in the rendered text, unlike in the sanitized one
+func escape(s string) string {
+	return strings.NewReplacer("<", "&lt;", ">", "&gt;", "`", "&#96;").Replace(s)
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+func isName(s string) bool {
+	if s == "" || !isLetter(s[0]) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !isNameChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isNameChar(c byte) bool {
+	return isLetter(c) || (c >= '0' && c <= '9') || c == '-'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
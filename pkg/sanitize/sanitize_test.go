@@ -0,0 +1,99 @@
+package sanitize
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeBasic(t *testing.T) {
+	p, err := New(PolicyBasic, nil)
+	require.NoError(t, err)
+
+	for in, out := range map[string]string{
+		"plain **markdown** & text":                "plain **markdown** & text",
+		"a < b > c":                                "a &lt; b > c",
+		"<b>bold</b> <STRONG>strong</STRONG>":      "<b>bold</b> <strong>strong</strong>",
+		"<script>alert(1)</script>":                "&lt;script&gt;alert(1)&lt;/script&gt;",
+		`<p onclick="alert(1)" style="x">text</p>`: "<p>text</p>",
+		`<a href="http://example.com?a=1&amp;b=2" title='t' target=_blank>link</a>`: `<a href="http://example.com?a=1&amp;b=2" title="t">link</a>`,
+		`<a href="javascript:alert(1)">x</a>`:                                       "<a>x</a>",
+		`<a href="java&#x09;script:alert(1)">x</a>`:                                 "<a>x</a>",
+		`<a href=" JavaScript:alert(1)">x</a>`:                                      "<a>x</a>",
+		`<a href="/issues/1#note">x</a>`:                                            `<a href="/issues/1#note">x</a>`,
+		`<a href="mailto:a@b.c">x</a>`:                                              `<a href="mailto:a@b.c">x</a>`,
+		`<img src=x onerror=alert(1)>`:                                              "&lt;img src=x onerror=alert(1)&gt;",
+		"<!--[if IE]><script>x</script><![endif]-->ok":                              "ok",
+		"<!-- unterminated":                                                         "",
+		"<b unterminated":                                                           "&lt;b unterminated",
+		`<a href="x>y">z</a>`:                                                       `<a href="x&gt;y">z</a>`,
+		"<br/><br />":                                                               "<br><br>",
+		"<123>":                                                                     "&lt;123>",
+	} {
+		res := p.Sanitize(in)
+		assert.Equal(t, out, res, in)
+		// the second pass doesn't change anything
+		assert.Equal(t, res, p.Sanitize(res), in)
+	}
+}
+
+func TestSanitizeStrict(t *testing.T) {
+	p, err := New(PolicyStrict, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "&lt;b&gt;x&lt;/b&gt;", p.Sanitize("<b>x</b>"))
+	assert.Empty(t, p.AllowedTags())
+
+	p, err = New(PolicyStrict, []string{"B", " kbd "})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "kbd"}, p.AllowedTags())
+	assert.Equal(t, "<b>x</b><kbd>y</kbd>", p.Sanitize(`<b>x</b><kbd class="c">y</kbd>`))
+}
+
+func TestNew(t *testing.T) {
+	p, err := New(PolicyNone, nil)
+	require.NoError(t, err)
+	assert.Nil(t, p)
+	assert.Equal(t, "<script>", p.Sanitize("<script>"))
+
+	_, err = New("unknown", nil)
+	assert.Error(t, err)
+	_, err = New(PolicyBasic, []string{"script"})
+	assert.Error(t, err)
+	_, err = New(PolicyBasic, []string{"a b"})
+	assert.Error(t, err)
+}
+
+func TestSanitizeMarkdownCode(t *testing.T) {
+	p, err := New(PolicyBasic, nil)
+	require.NoError(t, err)
+
+	img := "<img src=x onerror=alert(1)>"
+	escaped := "&lt;img src=x onerror=alert(1)&gt;"
+	for in, out := range map[string]string{
+		// code is kept as is
+		"```html\n<script>alert(1)</script>\n```\n<script>": "```html\n<script>alert(1)</script>\n```\n&lt;script&gt;",
+		"~~~\n" + img + "\n  ~~~~\n" + img:                  "~~~\n" + img + "\n  ~~~~\n" + escaped,
+		"```\n" + img:                                       "```\n" + img,
+		"use `" + img + "` or ``a ` " + img + "``":          "use `" + img + "` or ``a ` " + img + "``",
+		"a <b>`" + img + "`</b>":                            "a <b>`" + img + "`</b>",
+		// the renderer doesn't take these for code
+		"`" + img:                                "`" + escaped,
+		"\\`" + img + "`":                        "\\`" + escaped + "`",
+		"`a\nb` " + img + " `c`":                 "`a\nb` " + escaped + " `c`",
+		"| `a | " + img + " | b` |":              "| `a | " + escaped + " | b` |",
+		"<p>\n`" + img + "`":                     "<p>\n`" + escaped + "`",
+		"<p>\n```\n" + img + "\n```":             "<p>\n```\n" + escaped + "\n```",
+		"- <p>\n  ```\n  " + img + "\n  ```":     "- <p>\n  ```\n  " + escaped + "\n  ```",
+		"<pre>\n\n```\n" + img + "\n```\n</pre>": "<pre>\n\n```\n" + escaped + "\n```\n</pre>",
+		"  ```\n" + img + "\n```":                "  ```\n" + escaped + "\n```",
+		"``` a`b\n" + img:                        "``` a`b\n" + escaped,
+		"<p>\n\n```\n" + img + "\n```":           "<p>\n\n```\n" + img + "\n```",
+		// backticks of escaped tags aren't code delimiters anymore
+		"see <http://x`>`" + img + "`": "see &lt;http://x&#96;&gt;`" + img + "`",
+	} {
+		res := p.Sanitize(in)
+		assert.Equal(t, out, res, in)
+		assert.Equal(t, res, p.Sanitize(res), in)
+	}
+}
@@ -0,0 +1,118 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/agent"
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/pkg/utils"
+)
+
+// agents which didn't ask for jobs longer than agentTimeout are offline
+const agentTimeout = 30 * time.Second
+
+// sessions queued longer than stealAfter are given to a new agent if it's less loaded
+const stealAfter = 10 * time.Second
+
+type agentState struct {
+	load agent.Load
+	seen time.Time
+}
+
+// report remembers the agent load and reports whether the agent has just come online
+func (s *MemoryScheduler) report(agentId bson.ObjectId, load agent.Load, now time.Time) bool {
+	s.am.Lock()
+	defer s.am.Unlock()
+	st, ok := s.agents[agentId]
+	s.agents[agentId] = &agentState{load: load, seen: now}
+	return !ok || now.Sub(st.seen) > agentTimeout
+}
+
+// assigned counts the session as queued on the agent until the next report
+func (s *MemoryScheduler) assigned(agentId bson.ObjectId) {
+	s.am.Lock()
+	defer s.am.Unlock()
+	if st, ok := s.agents[agentId]; ok {
+		st.load.Queued++
+	}
+}
+
+// shouldServe reports whether the agent has free capacity and no online agent is less loaded
+func (s *MemoryScheduler) shouldServe(agentId bson.ObjectId, now time.Time) bool {
+	s.am.Lock()
+	defer s.am.Unlock()
+	me, ok := s.agents[agentId]
+	if !ok {
+		return false
+	}
+	if me.load.Full() {
+		return false
+	}
+	for id, st := range s.agents {
+		if id == agentId {
+			continue
+		}
+		if now.Sub(st.seen) > agentTimeout {
+			delete(s.agents, id)
+			continue
+		}
+		if !st.load.Full() && st.load.Busy() < me.load.Busy() {
+			return false
+		}
+	}
+	return true
+}
+
+// busier reports whether the session owner is more loaded than the agent, offline owners are always busier
+func (s *MemoryScheduler) busier(owner, agentId bson.ObjectId, now time.Time) bool {
+	s.am.Lock()
+	defer s.am.Unlock()
+	st, ok := s.agents[owner]
+	if !ok || now.Sub(st.seen) > agentTimeout {
+		return true
+	}
+	me := s.agents[agentId]
+	return !me.load.Full() && st.load.Busy() > me.load.Busy()
+}
+
+// rebalance returns sessions which wait too long on busier agents to the queue, so the new agent can take them.
+// The previous agent gets conflict when it tries to start the session.
+func (s *MemoryScheduler) rebalance(agentId bson.ObjectId, now time.Time) {
+	s.rw.Lock()
+	defer s.rw.Unlock()
+
+scans:
+	for id, sc := range s.scans {
+		for _, root := range sc.Sessions {
+			for _, sess := range append([]*scan.Session{root}, root.GetAllChildren()...) {
+				if sess.Status != scan.StatusQueued || sess.Agent == "" || sess.Agent == agentId {
+					continue
+				}
+				if sess.Queued == nil || now.Sub(*sess.Queued) < stealAfter || !s.busier(sess.Agent, agentId, now) {
+					continue
+				}
+				logrus.Infof("Session %s is returned to the queue from agent %s", sess.Id.Hex(), sess.Agent.Hex())
+				sess.Status = scan.StatusCreated
+				sess.Agent = ""
+				sess.Token = ""
+				sess.Queued = nil
+				if err := s.mgr.Scans.UpdateSession(sc, sess); err != nil {
+					if s.mgr.IsNotFound(err) {
+						delete(s.scans, id)
+						continue scans
+					}
+					logrus.Error(err)
+				}
+			}
+		}
+	}
+}
+
+// queue marks the session as queued to the agent and gives it a new callback token.
+// The session is queued only if it's still created in the database, other api instances could take it.
+func (s *MemoryScheduler) queue(sc *scan.Scan, sess *scan.Session, agentId bson.ObjectId) error {
+	return s.mgr.Scans.QueueSession(sc, sess, agentId, utils.RandomString(16))
+}
@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/plugin"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/pkg/manager"
+)
+
+// projects are checked for every created scan on every agent poll,
+// so they are cached and changes of blackouts and rules are applied after projectTTL
+const projectTTL = 10 * time.Second
+
+type cachedProject struct {
+	project *project.Project
+	loaded  time.Time
+}
+
+// project returns the cached project or loads it if the cached one is older than projectTTL
+func (s *MemoryScheduler) project(id bson.ObjectId, now time.Time) (*project.Project, error) {
+	s.pm.Lock()
+	defer s.pm.Unlock()
+	if c, ok := s.projects[id]; ok && now.Sub(c.loaded) < projectTTL {
+		return c.project, nil
+	}
+	p, err := s.mgr.Projects.GetById(id)
+	if err != nil {
+		delete(s.projects, id)
+		return nil, err
+	}
+	for key, c := range s.projects {
+		if now.Sub(c.loaded) >= projectTTL {
+			delete(s.projects, key)
+		}
+	}
+	s.projects[id] = &cachedProject{project: p, loaded: now}
+	return p, nil
+}
+
+type cachedPlugin struct {
+	plugin *plugin.Plugin
+	loaded time.Time
+}
+
+// plugin returns the cached plugin or loads it if the cached one is older than projectTTL,
+// plugins of sessions are checked against rules of engagement on every agent poll too
+func (s *MemoryScheduler) plugin(id bson.ObjectId, now time.Time) (*plugin.Plugin, error) {
+	s.pm.Lock()
+	defer s.pm.Unlock()
+	if c, ok := s.plugins[id]; ok && now.Sub(c.loaded) < projectTTL {
+		return c.plugin, nil
+	}
+	pl, err := s.mgr.Plugins.GetById(id.Hex())
+	if err != nil {
+		delete(s.plugins, id)
+		return nil, err
+	}
+	for key, c := range s.plugins {
+		if now.Sub(c.loaded) >= projectTTL {
+			delete(s.plugins, key)
+		}
+	}
+	s.plugins[id] = &cachedPlugin{plugin: pl, loaded: now}
+	return pl, nil
+}
+
+// poll keeps results of checks made during one agent poll, so every created scan of a project
+// is checked against the same quota without counting project scans again
+type poll struct {
+	quota map[bson.ObjectId]bool // projects which exceeded quota
+}
+
+func newPoll() *poll {
+	return &poll{quota: map[bson.ObjectId]bool{}}
+}
+
+// quotaExceeded checks running scans and agent minutes of the project once per poll
+func (s *MemoryScheduler) quotaExceeded(p *project.Project, pl *poll) bool {
+	if exceeded, ok := pl.quota[p.Id]; ok {
+		return exceeded
+	}
+	exceeded := false
+	for _, check := range []func(*project.Project) error{s.mgr.Scans.CheckRunning, s.mgr.Scans.CheckMinutes} {
+		if err := check(p); err != nil {
+			if manager.IsQuota(err) {
+				exceeded = true
+				break
+			}
+			logrus.Error(err)
+		}
+	}
+	pl.quota[p.Id] = exceeded
+	return exceeded
+}
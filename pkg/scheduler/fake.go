@@ -1,6 +1,11 @@
 package scheduler
 
-import "github.com/bearded-web/bearded/models/scan"
+import (
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/agent"
+	"github.com/bearded-web/bearded/models/scan"
+)
 
 type Fake struct {
 }
@@ -12,7 +17,7 @@ func NewFake() Scheduler {
 func (f *Fake) AddScan(*scan.Scan) error {
 	return nil
 }
-func (f *Fake) GetSession() (*scan.Session, error) {
+func (f *Fake) GetSession(bson.ObjectId, agent.Load) (*scan.Session, error) {
 	return nil, nil
 }
 func (f *Fake) UpdateScan(*scan.Scan) error {
@@ -1,9 +1,16 @@
 package scheduler
 
 import (
+	"reflect"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/Sirupsen/logrus"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/agent"
+	"github.com/bearded-web/bearded/models/plugin"
 	"github.com/bearded-web/bearded/models/scan"
 	"github.com/bearded-web/bearded/pkg/manager"
 )
@@ -13,14 +20,25 @@ type Scheduler interface {
 	//	GetJobs(context.Context, *agent.Agent) ([]*agent.Job, error)
 
 	AddScan(*scan.Scan) error
-	GetSession() (*scan.Session, error)
+	// GetSession returns a session for the agent or nil, the agent reports its load with every call
+	GetSession(bson.ObjectId, agent.Load) (*scan.Session, error)
 	UpdateScan(*scan.Scan) error
 }
 
 type MemoryScheduler struct {
+	// scans aren't started while another scan is active against the same target
+	TargetLock bool
+
 	mgr   *manager.Manager
 	scans map[string]*scan.Scan
 	rw    sync.RWMutex
+
+	agents map[bson.ObjectId]*agentState
+	am     sync.Mutex
+
+	projects map[bson.ObjectId]*cachedProject
+	plugins  map[bson.ObjectId]*cachedPlugin
+	pm       sync.Mutex
 }
 
 var _ Scheduler = &MemoryScheduler{} // check interface compatibility
@@ -28,8 +46,12 @@ var _ Scheduler = &MemoryScheduler{} // check interface compatibility
 // Memory scheduler is just a prototype of scheduler, it mustn't be used in production environment
 func NewMemoryScheduler(mgr *manager.Manager) *MemoryScheduler {
 	return &MemoryScheduler{
-		scans: map[string]*scan.Scan{},
-		mgr:   mgr,
+		TargetLock: true,
+		scans:      map[string]*scan.Scan{},
+		agents:     map[bson.ObjectId]*agentState{},
+		projects:   map[bson.ObjectId]*cachedProject{},
+		plugins:    map[bson.ObjectId]*cachedPlugin{},
+		mgr:        mgr,
 	}
 }
 
@@ -48,27 +70,42 @@ func (s *MemoryScheduler) UpdateScan(sc *scan.Scan) error {
 	return nil
 }
 
-func (s *MemoryScheduler) GetSession() (*scan.Session, error) {
-	s.rw.RLock()
-	defer s.rw.RUnlock()
+// GetSession returns the next session for the agent, if the agent is the least loaded of online agents.
+// Agents which come online take sessions which wait too long on busier agents.
+func (s *MemoryScheduler) GetSession(agentId bson.ObjectId, load agent.Load) (*scan.Session, error) {
+	now := time.Now().UTC()
+	if s.report(agentId, load, now) {
+		s.rebalance(agentId, now)
+	}
+	if !s.shouldServe(agentId, now) {
+		return nil, nil
+	}
+
+	// the write lock is held while sessions are picked and queued, so concurrent polls of agents
+	// don't start two scans against the same target and don't queue the same session twice
+	s.rw.Lock()
+	defer s.rw.Unlock()
 
+	pl := newPoll()
 scans:
 	for id, sc := range s.scans {
+		if sc.Status == scan.StatusCreated {
+			w := s.waiting(sc, now, pl)
+			s.setWaiting(sc, w)
+			if w != nil {
+				continue scans
+			}
+		}
 	sessions:
 		for _, sess := range sc.Sessions {
 			switch sess.Status {
 
 			case scan.StatusCreated:
-				sess.Status = scan.StatusQueued
-				err := s.mgr.Scans.UpdateSession(sc, sess)
-				if err != nil {
-					if s.mgr.IsNotFound(err) {
-						delete(s.scans, id)
-						continue scans
-					}
-					logrus.Error(err)
+				if err := s.queue(sc, sess, agentId); err != nil {
+					s.queueFailed(id, sc, err)
 					continue scans
 				}
+				s.assigned(agentId)
 				return sess, nil
 			case scan.StatusQueued:
 				// all scans session run in sequence order
@@ -81,16 +118,11 @@ scans:
 				if len(sess.Children) > 0 {
 					child := s.GetChild(sc, sess.Children)
 					if child != nil {
-						child.Status = scan.StatusQueued
-						err := s.mgr.Scans.UpdateSession(sc, child)
-						if err != nil {
-							if s.mgr.IsNotFound(err) {
-								delete(s.scans, id)
-								continue scans
-							}
-							logrus.Error(err)
+						if err := s.queue(sc, child, agentId); err != nil {
+							s.queueFailed(id, sc, err)
 							continue scans
 						}
+						s.assigned(agentId)
 						return child, nil
 					}
 				}
@@ -109,23 +141,96 @@ scans:
 	return nil, nil
 }
 
+// queueFailed drops removed scans and reloads scans which are changed by other api instances
+func (s *MemoryScheduler) queueFailed(id string, sc *scan.Scan, err error) {
+	switch {
+	case s.mgr.IsNotFound(err):
+		delete(s.scans, id)
+	case err == manager.ErrSessionTaken:
+		fresh, err := s.mgr.Scans.GetById(sc.Id)
+		if err != nil {
+			if s.mgr.IsNotFound(err) {
+				delete(s.scans, id)
+				return
+			}
+			logrus.Error(err)
+			return
+		}
+		s.scans[id] = fresh
+	default:
+		logrus.Error(err)
+	}
+}
+
+// Waiting returns why the scan which isn't started yet isn't allowed to start at the time or nil.
+// Scans are postponed till the scheduled time, the end of project blackouts, while they break project rules
+// of engagement, while project quota is exceeded and while another scan is active against the same target.
+func (s *MemoryScheduler) Waiting(sc *scan.Scan, now time.Time) *scan.Waiting {
+	return s.waiting(sc, now, newPoll())
+}
+
+func (s *MemoryScheduler) waiting(sc *scan.Scan, now time.Time, pl *poll) *scan.Waiting {
+	if sc.Scheduled != nil && now.Before(*sc.Scheduled) {
+		return &scan.Waiting{Reason: scan.WaitScheduled}
+	}
+	p, err := s.project(sc.Project, now)
+	if err != nil {
+		if !s.mgr.IsNotFound(err) {
+			logrus.Error(err)
+		}
+		return nil
+	}
+	if !p.Blocked(sc.Target, now).IsZero() {
+		return &scan.Waiting{Reason: scan.WaitBlackout}
+	}
+	// rules could be changed after the scan is created, or the scan is created by the server
+	getPlugin := func(id bson.ObjectId) (*plugin.Plugin, error) {
+		return s.plugin(id, now)
+	}
+	if err := s.mgr.Scans.CheckEngagementWith(p, sc, now, getPlugin); err != nil {
+		if eErr, ok := err.(*manager.EngagementError); ok {
+			return &scan.Waiting{Reason: scan.WaitEngagement, Message: strings.Join(eErr.Violations, "; ")}
+		}
+		logrus.Error(err)
+	}
+	if s.quotaExceeded(p, pl) {
+		return &scan.Waiting{Reason: scan.WaitQuota}
+	}
+	if s.TargetLock {
+		if other := s.activeOnTarget(sc); other != nil {
+			return &scan.Waiting{Reason: scan.WaitTarget, Scan: other.Id}
+		}
+	}
+	return nil
+}
+
+// activeOnTarget returns another active scan of the same target, the caller should hold the lock
+func (s *MemoryScheduler) activeOnTarget(sc *scan.Scan) *scan.Scan {
+	for _, other := range s.scans {
+		if other.Id != sc.Id && other.Target == sc.Target && other.IsActive() {
+			return other
+		}
+	}
+	return nil
+}
+
+// setWaiting saves the reason if it's changed, the caller should hold the write lock
+func (s *MemoryScheduler) setWaiting(sc *scan.Scan, w *scan.Waiting) {
+	if reflect.DeepEqual(sc.Waiting, w) {
+		return
+	}
+	if err := s.mgr.Scans.SetWaiting(sc, w); err != nil && !s.mgr.IsNotFound(err) {
+		logrus.Error(err)
+	}
+}
+
+// GetChild returns the child session which should be queued next
 func (s *MemoryScheduler) GetChild(sc *scan.Scan, sessions []*scan.Session) *scan.Session {
-	id := s.mgr.FromId(sc.Id)
 sessions:
 	for _, sess := range sessions {
 		switch sess.Status {
 
 		case scan.StatusCreated:
-			sess.Status = scan.StatusQueued
-			err := s.mgr.Scans.UpdateSession(sc, sess)
-			if err != nil {
-				if s.mgr.IsNotFound(err) {
-					delete(s.scans, id)
-					return nil
-				}
-				logrus.Error(err)
-				return nil
-			}
 			return sess
 		case scan.StatusQueued:
 			// all scans session run in sequence order
@@ -1,8 +1,10 @@
 package script
 
 import (
+	"github.com/bearded-web/bearded/models/file"
 	"github.com/bearded-web/bearded/models/plan"
 	"github.com/bearded-web/bearded/models/report"
+	"github.com/bearded-web/bearded/models/scan"
 	"golang.org/x/net/context"
 )
 
@@ -13,4 +15,9 @@ type ClientV1 interface {
 	RunPlugin(ctx context.Context, step *plan.WorkflowStep) (*report.Report, error)
 	SendReport(ctx context.Context, rep *report.Report) error
 	DownloadFile(ctx context.Context, fileId string) ([]byte, error)
+	// callbacks
+	GetCredentials(ctx context.Context, name string) (string, error)
+	ReportProgress(ctx context.Context, progress *scan.Progress) error
+	InScope(ctx context.Context, url string) (bool, error)
+	UploadArtifact(ctx context.Context, name string, data []byte) (*file.Meta, error)
 }
@@ -3,8 +3,10 @@ package script
 import (
 	"fmt"
 
+	"github.com/bearded-web/bearded/models/file"
 	"github.com/bearded-web/bearded/models/plan"
 	"github.com/bearded-web/bearded/models/report"
+	"github.com/bearded-web/bearded/models/scan"
 	"golang.org/x/net/context"
 )
 
@@ -38,3 +40,19 @@ func (f *FakeClient) GetConfig(ctx context.Context) (*plan.Conf, error) {
 func (f *FakeClient) DownloadFile(ctx context.Context, fileId string) ([]byte, error) {
 	return nil, nil
 }
+
+func (f *FakeClient) GetCredentials(ctx context.Context, name string) (string, error) {
+	return "", fmt.Errorf("No credentials")
+}
+
+func (f *FakeClient) ReportProgress(ctx context.Context, progress *scan.Progress) error {
+	return nil
+}
+
+func (f *FakeClient) InScope(ctx context.Context, url string) (bool, error) {
+	return false, nil
+}
+
+func (f *FakeClient) UploadArtifact(ctx context.Context, name string, data []byte) (*file.Meta, error) {
+	return &file.Meta{Name: name, Size: len(data)}, nil
+}
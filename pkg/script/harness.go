@@ -0,0 +1,224 @@
+package script
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/context"
+
+	"github.com/bearded-web/bearded/models/discovery"
+	"github.com/bearded-web/bearded/models/file"
+	"github.com/bearded-web/bearded/models/plan"
+	"github.com/bearded-web/bearded/models/report"
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/pkg/agent/api"
+	"github.com/bearded-web/bearded/pkg/transport"
+	"github.com/bearded-web/bearded/pkg/utils/load"
+)
+
+// Fixture describes an environment for running a script locally, without agent and dispatcher
+type Fixture struct {
+	// config returned to the script, Target is a fixture target
+	Conf *plan.Conf `json:"conf"`
+	// available plugin versions by plugin name
+	Plugins map[string][]string `json:"plugins,omitempty"`
+	// reports returned for running plugins by "name:version" or just by name
+	Reports map[string]*report.Report `json:"reports,omitempty"`
+	// files content by file id
+	Files map[string][]byte `json:"files,omitempty"`
+	// credential secrets by name
+	Credentials map[string]string `json:"credentials,omitempty"`
+}
+
+// Create a fixture with web target. Every plugin run returns an empty report.
+func NewTargetFixture(target string) *Fixture {
+	return &Fixture{
+		Conf:        &plan.Conf{Target: target},
+		Plugins:     map[string][]string{},
+		Reports:     map[string]*report.Report{},
+		Files:       map[string][]byte{},
+		Credentials: map[string]string{},
+	}
+}
+
+// Load fixture from json, yaml or toml file
+func LoadFixture(filename string) (*Fixture, error) {
+	f := NewTargetFixture("")
+	if err := load.FromFile(filename, f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// MockServer works like an agent side of the session protocol, but takes all data from fixture.
+// Sent reports, plugin runs, progress and artifacts are recorded for later checks.
+type MockServer struct {
+	Fixture *Fixture
+
+	mu        sync.Mutex
+	reports   []*report.Report
+	runs      []*plan.WorkflowStep
+	progress  []*scan.Progress
+	artifacts []*api.Artifact
+}
+
+func NewMockServer(fixture *Fixture) *MockServer {
+	return &MockServer{Fixture: fixture}
+}
+
+func (s *MockServer) Handle(ctx context.Context, msg transport.Extractor) (interface{}, error) {
+	req := api.RequestV1{}
+	resp := api.ResponseV1{}
+	if err := msg.Extract(&req); err != nil {
+		return nil, err
+	}
+
+	switch req.Method {
+	case api.GetConfig:
+		resp.GetConfig = s.Fixture.Conf
+	case api.GetPluginVersions:
+		resp.GetPluginVersions = s.Fixture.Plugins[req.GetPluginVersions]
+	case api.RunPlugin:
+		rep, err := s.runPlugin(req.RunPlugin)
+		if err != nil {
+			return nil, err
+		}
+		resp.RunPlugin = rep
+	case api.SendReport:
+		s.mu.Lock()
+		s.reports = append(s.reports, req.SendReport)
+		s.mu.Unlock()
+	case api.DownloadFile:
+		data, ok := s.Fixture.Files[req.DownloadFile]
+		if !ok {
+			return nil, fmt.Errorf("File %s not found", req.DownloadFile)
+		}
+		resp.DownloadFile = data
+	case api.GetCredentials:
+		secret, ok := s.Fixture.Credentials[req.GetCredentials]
+		if !ok {
+			return nil, fmt.Errorf("Credentials %s aren't referenced by the target", req.GetCredentials)
+		}
+		resp.GetCredentials = secret
+	case api.ReportProgress:
+		if req.ReportProgress == nil {
+			return nil, fmt.Errorf("Progress is empty")
+		}
+		s.mu.Lock()
+		s.progress = append(s.progress, req.ReportProgress)
+		s.mu.Unlock()
+	case api.InScope:
+		resp.InScope = inScope(req.InScope, s.Fixture.Conf.Target)
+	case api.UploadArtifact:
+		artifact := req.UploadArtifact
+		if artifact == nil || artifact.Name == "" {
+			return nil, fmt.Errorf("Artifact name is required")
+		}
+		s.mu.Lock()
+		s.artifacts = append(s.artifacts, artifact)
+		s.mu.Unlock()
+		resp.UploadArtifact = &file.Meta{
+			Id:   file.UniqueFileId(),
+			Name: artifact.Name,
+			Size: len(artifact.Data),
+		}
+	default:
+		return nil, fmt.Errorf("Unknown method requested %s", req.Method)
+	}
+	return resp, nil
+}
+
+func (s *MockServer) runPlugin(step *plan.WorkflowStep) (*report.Report, error) {
+	if step == nil {
+		return nil, fmt.Errorf("Workflow step is required")
+	}
+	s.mu.Lock()
+	s.runs = append(s.runs, step)
+	s.mu.Unlock()
+
+	if rep, ok := s.Fixture.Reports[step.Plugin]; ok {
+		return rep, nil
+	}
+	// try without version
+	name, _ := splitPlugin(step.Plugin)
+	if rep, ok := s.Fixture.Reports[name]; ok {
+		return rep, nil
+	}
+	return &report.Report{Type: report.TypeEmpty}, nil
+}
+
+// Reports sent by script
+func (s *MockServer) Reports() []*report.Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*report.Report{}, s.reports...)
+}
+
+// Plugin steps requested by script
+func (s *MockServer) Runs() []*plan.WorkflowStep {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*plan.WorkflowStep{}, s.runs...)
+}
+
+// Progress reported by script
+func (s *MockServer) Progress() []*scan.Progress {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*scan.Progress{}, s.progress...)
+}
+
+// Artifacts uploaded by script
+func (s *MockServer) Artifacts() []*api.Artifact {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*api.Artifact{}, s.artifacts...)
+}
+
+// localTransport passes requests directly to the handler,
+// but data is still encoded like in the real transport
+type localTransport struct {
+	transport.Fake
+	handler transport.Handler
+}
+
+func (t *localTransport) Request(ctx context.Context, send, recv interface{}) error {
+	msg, err := transport.NewMessage(transport.CmdRequest, send)
+	if err != nil {
+		return err
+	}
+	data, err := t.handler.Handle(ctx, msg)
+	if err != nil {
+		return err
+	}
+	resp, err := transport.NewMessage(transport.CmdResponse, data)
+	if err != nil {
+		return err
+	}
+	return resp.GetData(recv)
+}
+
+// RunLocal runs the script against the mock server with the fixture.
+// Returned server contains all reports sent by the script.
+func RunLocal(ctx context.Context, s Scripter, fixture *Fixture) (*MockServer, error) {
+	server := NewMockServer(fixture)
+	client, err := NewRemoteClient(&localTransport{handler: server})
+	if err != nil {
+		return nil, err
+	}
+	return server, s.Handle(ctx, client, fixture.Conf)
+}
+
+func splitPlugin(plugin string) (name, version string) {
+	if i := strings.LastIndex(plugin, ":"); i >= 0 {
+		return plugin[:i], plugin[i+1:]
+	}
+	return plugin, ""
+}
+
+// inScope works like the server scope check, the url host is the target host or its subdomain
+func inScope(url, target string) bool {
+	host, root := discovery.NormalizeHost(url), discovery.NormalizeHost(target)
+	return host != "" && (host == root || discovery.InScope(host, root))
+}
@@ -0,0 +1,138 @@
+package script
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/plan"
+	"github.com/bearded-web/bearded/models/report"
+	"github.com/bearded-web/bearded/models/scan"
+)
+
+type testScript struct{}
+
+func (s *testScript) Handle(ctx context.Context, client ClientV1, conf *plan.Conf) error {
+	pl, err := client.GetPlugin(ctx, "barbudo/wappalyzer")
+	if err != nil {
+		return err
+	}
+	rep, err := pl.Run(ctx, pl.LatestVersion(), &plan.Conf{CommandArgs: conf.Target})
+	if err != nil {
+		return err
+	}
+	return client.SendReport(ctx, rep)
+}
+
+func TestRunLocal(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	fixture := NewTargetFixture("http://example.com")
+	fixture.Plugins["barbudo/wappalyzer"] = []string{"0.0.1", "0.0.2"}
+	fixture.Reports["barbudo/wappalyzer"] = &report.Report{
+		Type:   report.TypeIssues,
+		Issues: []*issue.Issue{{Summary: "issue"}},
+	}
+
+	server, err := RunLocal(ctx, &testScript{}, fixture)
+	require.NoError(t, err)
+
+	runs := server.Runs()
+	require.Len(t, runs, 1)
+	assert.Equal(t, "barbudo/wappalyzer:0.0.2", runs[0].Plugin)
+	assert.Equal(t, "http://example.com", runs[0].Conf.CommandArgs)
+
+	reports := server.Reports()
+	require.Len(t, reports, 1)
+	require.Len(t, reports[0].Issues, 1)
+	assert.Equal(t, "issue", reports[0].Issues[0].Summary)
+}
+
+func TestMockServerFiles(t *testing.T) {
+	ctx := context.Background()
+	fixture := NewTargetFixture("")
+	fixture.Files["1"] = []byte("data")
+	client, err := NewRemoteClient(&localTransport{handler: NewMockServer(fixture)})
+	require.NoError(t, err)
+
+	data, err := client.DownloadFile(ctx, "1")
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+
+	_, err = client.DownloadFile(ctx, "2")
+	assert.Error(t, err)
+}
+
+func TestMockServerCredentials(t *testing.T) {
+	ctx := context.Background()
+	fixture := NewTargetFixture("")
+	fixture.Credentials["deploy"] = "secret"
+	client, err := NewRemoteClient(&localTransport{handler: NewMockServer(fixture)})
+	require.NoError(t, err)
+
+	secret, err := client.GetCredentials(ctx, "deploy")
+	require.NoError(t, err)
+	assert.Equal(t, "secret", secret)
+
+	_, err = client.GetCredentials(ctx, "other")
+	assert.Error(t, err)
+}
+
+func TestMockServerProgress(t *testing.T) {
+	ctx := context.Background()
+	server := NewMockServer(NewTargetFixture(""))
+	client, err := NewRemoteClient(&localTransport{handler: server})
+	require.NoError(t, err)
+
+	require.NoError(t, client.ReportProgress(ctx, &scan.Progress{Percent: 50, Message: "crawling"}))
+	assert.Error(t, client.ReportProgress(ctx, nil))
+
+	progress := server.Progress()
+	require.Len(t, progress, 1)
+	assert.Equal(t, 50, progress[0].Percent)
+	assert.Equal(t, "crawling", progress[0].Message)
+}
+
+func TestMockServerInScope(t *testing.T) {
+	ctx := context.Background()
+	client, err := NewRemoteClient(&localTransport{handler: NewMockServer(NewTargetFixture("http://example.com"))})
+	require.NoError(t, err)
+
+	for url, expected := range map[string]bool{
+		"http://example.com/login":   true,
+		"https://api.example.com/v1": true,
+		"http://example.org/":        false,
+		"http://notexample.com/":     false,
+		"":                           false,
+	} {
+		in, err := client.InScope(ctx, url)
+		require.NoError(t, err)
+		assert.Equal(t, expected, in, url)
+	}
+}
+
+func TestMockServerArtifacts(t *testing.T) {
+	ctx := context.Background()
+	server := NewMockServer(NewTargetFixture(""))
+	client, err := NewRemoteClient(&localTransport{handler: server})
+	require.NoError(t, err)
+
+	meta, err := client.UploadArtifact(ctx, "screenshot.png", []byte("data"))
+	require.NoError(t, err)
+	assert.NotEmpty(t, meta.Id)
+	assert.Equal(t, "screenshot.png", meta.Name)
+	assert.Equal(t, 4, meta.Size)
+
+	_, err = client.UploadArtifact(ctx, "", []byte("data"))
+	assert.Error(t, err)
+
+	artifacts := server.Artifacts()
+	require.Len(t, artifacts, 1)
+	assert.Equal(t, "screenshot.png", artifacts[0].Name)
+	assert.Equal(t, "data", string(artifacts[0].Data))
+}
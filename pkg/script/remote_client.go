@@ -3,8 +3,10 @@ package script
 import (
 	"fmt"
 
+	"github.com/bearded-web/bearded/models/file"
 	"github.com/bearded-web/bearded/models/plan"
 	"github.com/bearded-web/bearded/models/report"
+	"github.com/bearded-web/bearded/models/scan"
 	"github.com/bearded-web/bearded/pkg/agent/api"
 	"github.com/bearded-web/bearded/pkg/transport"
 	"golang.org/x/net/context"
@@ -112,3 +114,54 @@ func (c *RemoteClient) DownloadFile(ctx context.Context, fileId string) ([]byte,
 	}
 	return resp.DownloadFile, nil
 }
+
+// Callbacks
+
+// GetCredentials returns the secret of credentials referenced by the scan target
+func (c *RemoteClient) GetCredentials(ctx context.Context, name string) (string, error) {
+	req := api.RequestV1{
+		Method:         api.GetCredentials,
+		GetCredentials: name,
+	}
+	resp := api.ResponseV1{}
+	if err := c.transp.Request(ctx, req, &resp); err != nil {
+		return "", err
+	}
+	return resp.GetCredentials, nil
+}
+
+// ReportProgress updates the progress of the current session
+func (c *RemoteClient) ReportProgress(ctx context.Context, progress *scan.Progress) error {
+	req := api.RequestV1{
+		Method:         api.ReportProgress,
+		ReportProgress: progress,
+	}
+	resp := api.ResponseV1{}
+	return c.transp.Request(ctx, req, &resp)
+}
+
+// InScope reports whether the url is in scope of the scan target
+func (c *RemoteClient) InScope(ctx context.Context, url string) (bool, error) {
+	req := api.RequestV1{
+		Method:  api.InScope,
+		InScope: url,
+	}
+	resp := api.ResponseV1{}
+	if err := c.transp.Request(ctx, req, &resp); err != nil {
+		return false, err
+	}
+	return resp.InScope, nil
+}
+
+// UploadArtifact keeps the file with the current session apart from the report
+func (c *RemoteClient) UploadArtifact(ctx context.Context, name string, data []byte) (*file.Meta, error) {
+	req := api.RequestV1{
+		Method:         api.UploadArtifact,
+		UploadArtifact: &api.Artifact{Name: name, Data: data},
+	}
+	resp := api.ResponseV1{}
+	if err := c.transp.Request(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.UploadArtifact, nil
+}
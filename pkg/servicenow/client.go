@@ -0,0 +1,118 @@
+package servicenow
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bearded-web/bearded/models/project"
+)
+
+// Record is a row of the table, only synced fields are requested
+type Record struct {
+	SysId  string `json:"sys_id"`
+	Number string `json:"number"`
+	State  string `json:"state"`
+	Impact string `json:"impact"`
+}
+
+const recordFields = "sys_id,number,state,impact"
+
+const clientTimeout = 30 * time.Second
+
+// Client works with records of one table through the table api
+type Client struct {
+	Instance string
+	Table    project.ServiceNowTable
+	User     string
+	Password string
+	Http     *http.Client
+}
+
+// NewClient returns the client for the project configuration, the secret is user:password
+func NewClient(conf *project.ServiceNow, secret string) *Client {
+	parts := strings.SplitN(secret, ":", 2)
+	c := &Client{
+		Instance: strings.TrimRight(conf.Instance, "/"),
+		Table:    conf.GetTable(),
+		User:     parts[0],
+		Http:     &http.Client{Timeout: clientTimeout},
+	}
+	if len(parts) == 2 {
+		c.Password = parts[1]
+	}
+	return c
+}
+
+// RecordUrl returns the link to the record in the ServiceNow ui
+func (c *Client) RecordUrl(id string) string {
+	return fmt.Sprintf("%s/nav_to.do?uri=%s", c.Instance, url.QueryEscape(fmt.Sprintf("%s.do?sys_id=%s", c.Table, id)))
+}
+
+func (c *Client) Create(fields map[string]string) (*Record, error) {
+	return c.do("POST", "", fields)
+}
+
+func (c *Client) Get(id string) (*Record, error) {
+	return c.do("GET", id, nil)
+}
+
+func (c *Client) Update(id string, fields map[string]string) (*Record, error) {
+	return c.do("PATCH", id, fields)
+}
+
+func (c *Client) do(method, id string, fields map[string]string) (*Record, error) {
+	u := fmt.Sprintf("%s/api/now/table/%s", c.Instance, c.Table)
+	if id != "" {
+		u = fmt.Sprintf("%s/%s", u, url.PathEscape(id))
+	}
+	u = fmt.Sprintf("%s?sysparm_fields=%s&sysparm_exclude_reference_link=true", u, recordFields)
+	var body io.Reader
+	if fields != nil {
+		data, err := json.Marshal(fields)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(c.User, c.Password)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.Http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	result := struct {
+		Result *Record `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+			Detail  string `json:"detail"`
+		} `json:"error"`
+	}{}
+	decodeErr := json.NewDecoder(resp.Body).Decode(&result)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if result.Error != nil {
+			return nil, fmt.Errorf("servicenow responded %s: %s %s", resp.Status, result.Error.Message, result.Error.Detail)
+		}
+		return nil, fmt.Errorf("servicenow responded %s", resp.Status)
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("broken servicenow response: %v", decodeErr)
+	}
+	if result.Result == nil || result.Result.SysId == "" {
+		return nil, fmt.Errorf("servicenow responded without record")
+	}
+	return result.Result, nil
+}
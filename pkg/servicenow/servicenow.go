@@ -0,0 +1,241 @@
+// Package servicenow creates ServiceNow records for severe project issues and keeps them in sync.
+// States and impacts are synced both ways, changes made in ServiceNow since the last sync win.
+package servicenow
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/facebookgo/stackerr"
+	"golang.org/x/net/context"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/pkg/credentials"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/netguard"
+)
+
+// lock in mongo which is held by the instance running syncs
+const lockName = "servicenow"
+
+// MaxCreated is the max number of records created for a project in one check, the rest wait for next checks
+const MaxCreated = 50
+
+type Engine struct {
+	mgr  *manager.Manager
+	host string // used to make absolute links
+}
+
+func New(mgr *manager.Manager, host string) *Engine {
+	return &Engine{
+		mgr:  mgr,
+		host: host,
+	}
+}
+
+// Run syncs projects every interval until the context is done
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	logrus.Infof("ServiceNow sync is started, interval %s", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			if err := e.mgr.Locks.Release(lockName); err != nil {
+				logrus.Error(err)
+			}
+			return
+		case <-time.After(interval):
+			// only one api instance runs the sync, others wait until the lock is expired
+			if !e.mgr.Locks.Lead(lockName, interval) {
+				continue
+			}
+			if err := e.Check(time.Now().UTC()); err != nil {
+				logrus.Error(err)
+			}
+		}
+	}
+}
+
+// Check syncs all projects with enabled integration
+func (e *Engine) Check(now time.Time) error {
+	mgr := e.mgr.Copy()
+	defer mgr.Close()
+
+	projects, _, err := mgr.Projects.FilterByQuery(bson.M{"serviceNow.enabled": true})
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	for _, p := range projects {
+		if err := e.SyncProject(mgr, p, now); err != nil {
+			logrus.Errorf("ServiceNow sync of project %s: %s", p, err)
+		}
+	}
+	return nil
+}
+
+// SyncProject creates records for new severe issues and syncs linked ones
+func (e *Engine) SyncProject(mgr *manager.Manager, p *project.Project, now time.Time) error {
+	conf := p.ServiceNow
+	u, err := url.Parse(conf.Instance)
+	if err != nil {
+		return err
+	}
+	if err := credentials.CheckProject(conf.Credentials, p.Id.Hex()); err != nil {
+		return err
+	}
+	secret, err := credentials.Lookup(conf.Credentials, u.Host)
+	if err != nil {
+		return err
+	}
+	c := NewClient(conf, secret)
+	// the instance is set by project owners, so it mustn't reach the server network
+	c.Http = netguard.Client(clientTimeout)
+
+	query := bson.M{
+		"project":      p.Id,
+		"resolved":     false,
+		"false":        false,
+		"muted":        false,
+		"pendingScan":  bson.M{"$exists": false},
+		"ticket":       bson.M{"$exists": false},
+		"severityRank": bson.M{"$gte": conf.MinSeverity().Rank()},
+	}
+	issues, _, err := mgr.Issues.FilterByQuery(query, manager.Opts{Sort: []string{"-risk", "created"}, Limit: MaxCreated})
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	for _, obj := range issues {
+		// variables are rendered in a copy, the issue is saved with the ticket
+		rendered := *obj
+		if err := mgr.Issues.RenderVars(&rendered); err != nil {
+			return stackerr.Wrap(err)
+		}
+		rec, err := c.Create(e.Fields(conf, &rendered))
+		if err != nil {
+			return err
+		}
+		obj.Ticket = &issue.Ticket{
+			System: issue.TicketServiceNow,
+			Id:     rec.SysId,
+			Number: rec.Number,
+			Url:    c.RecordUrl(rec.SysId),
+			State:  rec.State,
+			Impact: rec.Impact,
+			Synced: now,
+		}
+		if err := mgr.Issues.Update(obj); err != nil {
+			return stackerr.Wrap(err)
+		}
+	}
+
+	// records of resolved issues are synced till they are closed, so reopened issues reopen them
+	query = bson.M{
+		"project":       p.Id,
+		"ticket.system": issue.TicketServiceNow,
+		"$or":           []bson.M{{"resolved": false}, {"ticket.closed": false}},
+	}
+	linked, _, err := mgr.Issues.FilterByQuery(query)
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	for _, obj := range linked {
+		rec, err := c.Get(obj.Ticket.Id)
+		if err != nil {
+			logrus.Errorf("ServiceNow record %s of issue %s: %s", obj.Ticket.Number, obj.Id.Hex(), err)
+			continue
+		}
+		fields, changed := Sync(c.Table, obj, rec)
+		if len(fields) > 0 {
+			if rec, err = c.Update(rec.SysId, fields); err != nil {
+				logrus.Errorf("ServiceNow record %s of issue %s: %s", obj.Ticket.Number, obj.Id.Hex(), err)
+				continue
+			}
+		}
+		if !changed && len(fields) == 0 && obj.Ticket.State == rec.State && obj.Ticket.Impact == rec.Impact {
+			continue
+		}
+		obj.Ticket.State = rec.State
+		obj.Ticket.Impact = rec.Impact
+		obj.Ticket.Closed = c.Table.IsResolved(rec.State) || c.Table.IsCanceled(rec.State)
+		obj.Ticket.Synced = now
+		if err := mgr.Issues.Update(obj); err != nil {
+			return stackerr.Wrap(err)
+		}
+	}
+	return nil
+}
+
+// Fields returns fields of the new record for the issue
+func (e *Engine) Fields(conf *project.ServiceNow, obj *issue.TargetIssue) map[string]string {
+	impact := project.ServiceNowImpact(obj.Severity)
+	desc := obj.Desc
+	if _, url := obj.Request(); url != "" {
+		desc = fmt.Sprintf("%s\n\nUrl: %s", desc, url)
+	}
+	fields := map[string]string{
+		"short_description":   obj.Summary,
+		"description":         strings.TrimSpace(fmt.Sprintf("%s\n\n%s/#/issue/%s", desc, e.host, obj.Id.Hex())),
+		"impact":              impact,
+		"urgency":             impact,
+		"correlation_id":      obj.Id.Hex(),
+		"correlation_display": "bearded",
+	}
+	if conf.AssignmentGroup != "" {
+		fields["assignment_group"] = conf.AssignmentGroup
+	}
+	return fields
+}
+
+// Sync merges the record to the issue and returns fields which should be updated in the record,
+// changed is true if the issue is changed. Changes made in ServiceNow since the last sync win,
+// otherwise the issue state and severity are pushed to the record.
+func Sync(table project.ServiceNowTable, obj *issue.TargetIssue, rec *Record) (map[string]string, bool) {
+	t := obj.Ticket
+	fields := map[string]string{}
+	changed := false
+	move := func(to issue.Column) {
+		if obj.Column() != to {
+			obj.Move(to, "")
+			changed = true
+		}
+	}
+
+	if rec.State != t.State {
+		switch {
+		case table.IsCanceled(rec.State):
+			move(issue.ColumnFalse)
+		case table.IsResolved(rec.State):
+			move(issue.ColumnResolved)
+		case obj.Resolved || obj.False:
+			move(issue.ColumnOpen)
+		}
+	} else {
+		closed := table.IsResolved(rec.State) || table.IsCanceled(rec.State)
+		switch {
+		case obj.Resolved && !table.IsResolved(rec.State):
+			fields["state"] = table.ResolvedState()
+			fields["close_code"] = "Solved (Permanently)"
+			fields["close_notes"] = "The issue is resolved in Bearded"
+		case obj.False && !table.IsCanceled(rec.State):
+			fields["state"] = table.CanceledState()
+			fields["close_notes"] = "The issue is marked as false positive in Bearded"
+		case !obj.Resolved && !obj.False && closed:
+			fields["state"] = table.OpenState()
+		}
+	}
+
+	if rec.Impact != t.Impact {
+		if sev, ok := project.ServiceNowSeverity(rec.Impact); ok && sev != obj.Severity {
+			obj.Severity = sev
+			changed = true
+		}
+	} else if impact := project.ServiceNowImpact(obj.Severity); impact != rec.Impact {
+		fields["impact"] = impact
+		fields["urgency"] = impact
+	}
+	return fields, changed
+}
@@ -0,0 +1,124 @@
+package servicenow
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/project"
+)
+
+func linkedIssue(state, impact string) *issue.TargetIssue {
+	obj := &issue.TargetIssue{}
+	obj.Severity = issue.SeverityHigh
+	obj.Ticket = &issue.Ticket{System: issue.TicketServiceNow, Id: "abc", State: state, Impact: impact}
+	return obj
+}
+
+func TestSyncRemoteWins(t *testing.T) {
+	table := project.ServiceNowIncident
+
+	obj := linkedIssue("2", "1")
+	fields, changed := Sync(table, obj, &Record{SysId: "abc", State: "6", Impact: "1"})
+	assert.True(t, changed)
+	assert.Empty(t, fields)
+	assert.True(t, obj.Resolved)
+
+	obj = linkedIssue("2", "1")
+	fields, changed = Sync(table, obj, &Record{SysId: "abc", State: "8", Impact: "1"})
+	assert.True(t, changed)
+	assert.True(t, obj.False)
+
+	// reopened in servicenow
+	obj = linkedIssue("6", "1")
+	obj.Move(issue.ColumnResolved, "")
+	fields, changed = Sync(table, obj, &Record{SysId: "abc", State: "2", Impact: "1"})
+	assert.True(t, changed)
+	assert.False(t, obj.Resolved)
+	assert.Equal(t, 1, obj.Reopened)
+
+	obj = linkedIssue("2", "1")
+	fields, changed = Sync(table, obj, &Record{SysId: "abc", State: "2", Impact: "2"})
+	assert.True(t, changed)
+	assert.Empty(t, fields)
+	assert.Equal(t, issue.SeverityMedium, obj.Severity)
+}
+
+func TestSyncLocalPushed(t *testing.T) {
+	table := project.ServiceNowIncident
+
+	obj := linkedIssue("2", "1")
+	fields, changed := Sync(table, obj, &Record{SysId: "abc", State: "2", Impact: "1"})
+	assert.False(t, changed)
+	assert.Empty(t, fields)
+
+	obj.Move(issue.ColumnResolved, "")
+	fields, changed = Sync(table, obj, &Record{SysId: "abc", State: "2", Impact: "1"})
+	assert.False(t, changed)
+	assert.Equal(t, "6", fields["state"])
+	assert.NotEmpty(t, fields["close_code"])
+
+	obj = linkedIssue("2", "1")
+	obj.Move(issue.ColumnFalse, "")
+	fields, _ = Sync(table, obj, &Record{SysId: "abc", State: "2", Impact: "1"})
+	assert.Equal(t, "8", fields["state"])
+
+	// reopened in bearded
+	obj = linkedIssue("7", "1")
+	fields, _ = Sync(table, obj, &Record{SysId: "abc", State: "7", Impact: "1"})
+	assert.Equal(t, "2", fields["state"])
+
+	obj = linkedIssue("2", "1")
+	obj.Severity = issue.SeverityLow
+	fields, _ = Sync(table, obj, &Record{SysId: "abc", State: "2", Impact: "1"})
+	assert.Equal(t, "3", fields["impact"])
+	assert.Equal(t, "3", fields["urgency"])
+}
+
+func TestClient(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		user, pass, _ := req.BasicAuth()
+		if user != "admin" || pass != "se:cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":{"message":"User Not Authenticated","detail":"Required to provide Auth information"}}`))
+			return
+		}
+		got = nil
+		json.NewDecoder(req.Body).Decode(&got)
+		switch {
+		case req.Method == "POST" && req.URL.Path == "/api/now/table/incident":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"result":{"sys_id":"abc","number":"INC0010001","state":"1","impact":"1"}}`))
+		case req.Method == "PATCH" && req.URL.Path == "/api/now/table/incident/abc":
+			w.Write([]byte(`{"result":{"sys_id":"abc","number":"INC0010001","state":"6","impact":"1"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":{"message":"No Record found"}}`))
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(&project.ServiceNow{Instance: srv.URL + "/"}, "admin:se:cret")
+	rec, err := c.Create(map[string]string{"short_description": "Sql injection"})
+	require.NoError(t, err)
+	assert.Equal(t, "INC0010001", rec.Number)
+	assert.Equal(t, "Sql injection", got["short_description"])
+
+	rec, err = c.Update("abc", map[string]string{"state": "6"})
+	require.NoError(t, err)
+	assert.Equal(t, "6", rec.State)
+
+	_, err = c.Get("missing")
+	assert.Error(t, err)
+
+	c.Password = "wrong"
+	_, err = c.Get("abc")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "User Not Authenticated")
+}
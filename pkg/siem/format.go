@@ -0,0 +1,72 @@
+package siem
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	vendor  = "Bearded"
+	product = "Bearded"
+	version = "1.0"
+)
+
+// Format converts the event to a single line message, host is the name of sending server
+type Format func(e *Event, host string) string
+
+func GetFormat(name string) (Format, error) {
+	switch name {
+	case "cef", "":
+		return CEF, nil
+	case "leef":
+		return LEEF, nil
+	}
+	return nil, fmt.Errorf("unknown siem format %s", name)
+}
+
+// CEF formats the event in ArcSight Common Event Format
+func CEF(e *Event, host string) string {
+	header := strings.NewReplacer(`\`, `\\`, `|`, `\|`)
+	ext := strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+
+	pairs := []string{
+		"rt=" + fmt.Sprint(e.Time.UnixNano()/1e6),
+		"dvchost=" + ext.Replace(host),
+	}
+	for _, k := range keys(e.Ext) {
+		pairs = append(pairs, k+"="+ext.Replace(e.Ext[k]))
+	}
+	return fmt.Sprintf("CEF:0|%s|%s|%s|%s|%s|%d|%s",
+		vendor, product, version, header.Replace(e.Class), header.Replace(e.Name), e.Severity,
+		strings.Join(pairs, " "))
+}
+
+// LEEF formats the event in IBM QRadar Log Event Extended Format 1.0, attributes are separated by tabs
+func LEEF(e *Event, host string) string {
+	header := strings.NewReplacer(`|`, `\|`)
+	attr := strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+
+	pairs := []string{
+		"devTime=" + e.Time.Format("Jan 02 2006 15:04:05"),
+		"devTimeFormat=MMM dd yyyy HH:mm:ss",
+		"sev=" + fmt.Sprint(e.Severity),
+		"cat=" + attr.Replace(e.Name),
+		"identHostName=" + attr.Replace(host),
+	}
+	for _, k := range keys(e.Ext) {
+		pairs = append(pairs, k+"="+attr.Replace(e.Ext[k]))
+	}
+	return fmt.Sprintf("LEEF:1.0|%s|%s|%s|%s|%s",
+		vendor, product, version, header.Replace(e.Class), strings.Join(pairs, "\t"))
+}
+
+// extensions are sorted to make messages stable
+func keys(m map[string]string) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
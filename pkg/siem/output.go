@@ -0,0 +1,146 @@
+package siem
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bearded-web/bearded/pkg/config"
+)
+
+// syslog facility for security and authorization messages
+const facilityAuthPriv = 10
+
+const timeout = 10 * time.Second
+
+// Output delivers formatted messages
+type Output interface {
+	Write(e *Event, msg string) error
+	Close() error
+}
+
+func GetOutput(cfg config.Siem) (Output, error) {
+	switch cfg.Output {
+	case "syslog", "":
+		if cfg.Syslog.Addr == "" {
+			return nil, fmt.Errorf("syslog address is required")
+		}
+		return NewSyslog(cfg.Syslog.Network, cfg.Syslog.Addr), nil
+	case "kafka":
+		if cfg.Kafka.RestProxy == "" || cfg.Kafka.Topic == "" {
+			return nil, fmt.Errorf("kafka rest proxy and topic are required")
+		}
+		return NewKafka(cfg.Kafka.RestProxy, cfg.Kafka.Topic), nil
+	}
+	return nil, fmt.Errorf("unknown siem output %s", cfg.Output)
+}
+
+// Syslog sends RFC 5424 messages, tcp messages are separated by new lines.
+// The connection is opened again after errors.
+type Syslog struct {
+	network string
+	addr    string
+	host    string
+
+	m    sync.Mutex
+	conn net.Conn
+}
+
+func NewSyslog(network, addr string) *Syslog {
+	if network == "" {
+		network = "udp"
+	}
+	host, _ := os.Hostname()
+	return &Syslog{network: network, addr: addr, host: host}
+}
+
+// priority maps CEF severity to syslog severity: critical, warning or informational
+func priority(sev int) int {
+	switch {
+	case sev >= 8:
+		return facilityAuthPriv*8 + 2
+	case sev >= 5:
+		return facilityAuthPriv*8 + 4
+	}
+	return facilityAuthPriv*8 + 6
+}
+
+func (s *Syslog) Write(e *Event, msg string) error {
+	line := fmt.Sprintf("<%d>1 %s %s bearded - %s - %s\n",
+		priority(e.Severity), e.Time.Format(time.RFC3339), s.host, e.Class, msg)
+
+	s.m.Lock()
+	defer s.m.Unlock()
+	var err error
+	for i := 0; i < 2; i++ {
+		if s.conn == nil {
+			if s.conn, err = net.DialTimeout(s.network, s.addr, timeout); err != nil {
+				return err
+			}
+		}
+		s.conn.SetWriteDeadline(time.Now().Add(timeout))
+		if _, err = s.conn.Write([]byte(line)); err == nil {
+			return nil
+		}
+		s.conn.Close()
+		s.conn = nil
+	}
+	return err
+}
+
+func (s *Syslog) Close() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// Kafka produces messages through Confluent REST proxy, so kafka client isn't required
+type Kafka struct {
+	url    string
+	client *http.Client
+}
+
+func NewKafka(restProxy, topic string) *Kafka {
+	return &Kafka{
+		url:    fmt.Sprintf("%s/topics/%s", strings.TrimRight(restProxy, "/"), topic),
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+func (k *Kafka) Write(e *Event, msg string) error {
+	body, err := json.Marshal(map[string][]*kafkaRecord{
+		"records": {{Key: e.Class, Value: msg}},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := k.client.Post(k.url, "application/vnd.kafka.json.v2+json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("kafka rest proxy returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (k *Kafka) Close() error {
+	return nil
+}
@@ -0,0 +1,140 @@
+// Package siem streams security events in CEF or LEEF format to syslog or kafka for SOC.
+package siem
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"golang.org/x/net/context"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/pkg/config"
+)
+
+// Event classes, they are sent as CEF signature id and LEEF event id
+const (
+	ClassIssue       = "issue.high"
+	ClassLoginFailed = "auth.loginFailed"
+	ClassAdmin       = "admin.action"
+)
+
+// events are dropped if the output is slower than producers
+const bufferSize = 1024
+
+// Event is a security event, extensions are sent as key=value pairs
+type Event struct {
+	Class    string
+	Name     string
+	Severity int // 0-10 like in CEF
+	Time     time.Time
+	Ext      map[string]string
+}
+
+// IssueEvent is sent when a new high severity issue is found
+func IssueEvent(obj *issue.TargetIssue) *Event {
+	return &Event{
+		Class:    ClassIssue,
+		Name:     obj.Summary,
+		Severity: 9,
+		Time:     time.Now().UTC(),
+		Ext: map[string]string{
+			"externalId": obj.Id.Hex(),
+			"cs1Label":   "project",
+			"cs1":        obj.Project.Hex(),
+			"cs2Label":   "target",
+			"cs2":        obj.Target.Hex(),
+		},
+	}
+}
+
+// LoginFailedEvent is sent for wrong passwords and unknown emails
+func LoginFailedEvent(email, src, reason string) *Event {
+	return &Event{
+		Class:    ClassLoginFailed,
+		Name:     "Login failed",
+		Severity: 5,
+		Time:     time.Now().UTC(),
+		Ext: map[string]string{
+			"suser":  email,
+			"src":    src,
+			"reason": reason,
+		},
+	}
+}
+
+// AdminEvent is sent for changes made by admins
+func AdminEvent(email, src, action string, status int) *Event {
+	return &Event{
+		Class:    ClassAdmin,
+		Name:     "Admin action",
+		Severity: 3,
+		Time:     time.Now().UTC(),
+		Ext: map[string]string{
+			"suser":   email,
+			"src":     src,
+			"act":     action,
+			"outcome": fmt.Sprint(status),
+		},
+	}
+}
+
+// Stream formats events and writes them to the output in background
+type Stream struct {
+	format Format
+	out    Output
+	events chan *Event
+}
+
+// New returns the stream configured by cfg, nil is returned if it's disabled
+func New(cfg config.Siem) (*Stream, error) {
+	if !cfg.Enable {
+		return nil, nil
+	}
+	format, err := GetFormat(cfg.Format)
+	if err != nil {
+		return nil, err
+	}
+	out, err := GetOutput(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewStream(format, out), nil
+}
+
+func NewStream(format Format, out Output) *Stream {
+	return &Stream{
+		format: format,
+		out:    out,
+		events: make(chan *Event, bufferSize),
+	}
+}
+
+// Emit puts the event to the queue without blocking, it's safe to call Emit on nil stream
+func (s *Stream) Emit(e *Event) {
+	if s == nil {
+		return
+	}
+	select {
+	case s.events <- e:
+	default:
+		logrus.Warnf("Siem queue is full, %s event is dropped", e.Class)
+	}
+}
+
+// Run writes events until the context is done
+func (s *Stream) Run(ctx context.Context) {
+	host, _ := os.Hostname()
+	for {
+		select {
+		case <-ctx.Done():
+			s.out.Close()
+			return
+		case e := <-s.events:
+			if err := s.out.Write(e, s.format(e, host)); err != nil {
+				logrus.Errorf("Couldn't send %s event to siem: %s", e.Class, err)
+			}
+		}
+	}
+}
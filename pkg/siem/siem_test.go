@@ -0,0 +1,100 @@
+package siem
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+
+	"github.com/bearded-web/bearded/pkg/config"
+)
+
+func testEvent() *Event {
+	return &Event{
+		Class:    ClassLoginFailed,
+		Name:     "Login | failed",
+		Severity: 5,
+		Time:     time.Date(2015, 6, 1, 10, 20, 30, 0, time.UTC),
+		Ext: map[string]string{
+			"suser":  "a=b@example.com",
+			"reason": "wrong\tpassword\n",
+		},
+	}
+}
+
+func TestCEF(t *testing.T) {
+	assert.Equal(t,
+		`CEF:0|Bearded|Bearded|1.0|auth.loginFailed|Login \| failed|5|rt=1433154030000 dvchost=host reason=wrong	password\n suser=a\=b@example.com`,
+		CEF(testEvent(), "host"))
+}
+
+func TestLEEF(t *testing.T) {
+	assert.Equal(t,
+		"LEEF:1.0|Bearded|Bearded|1.0|auth.loginFailed|devTime=Jun 01 2015 10:20:30\tdevTimeFormat=MMM dd yyyy HH:mm:ss\t"+
+			"sev=5\tcat=Login | failed\tidentHostName=host\treason=wrong password \tsuser=a=b@example.com",
+		LEEF(testEvent(), "host"))
+}
+
+func TestNew(t *testing.T) {
+	stream, err := New(config.Siem{})
+	assert.NoError(t, err)
+	assert.Nil(t, stream)
+	// nil stream is safe to use
+	stream.Emit(testEvent())
+
+	_, err = New(config.Siem{Enable: true, Format: "xml"})
+	assert.Error(t, err)
+	_, err = New(config.Siem{Enable: true, Output: "kafka"})
+	assert.Error(t, err)
+	_, err = New(config.Siem{Enable: true, Syslog: config.Syslog{Addr: "127.0.0.1:514"}})
+	assert.NoError(t, err)
+}
+
+func TestSyslog(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+
+	stream := NewStream(CEF, NewSyslog("udp", conn.LocalAddr().String()))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go stream.Run(ctx)
+	stream.Emit(testEvent())
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	require.NoError(t, err)
+	msg := string(buf[:n])
+	// authpriv facility with warning severity
+	assert.True(t, strings.HasPrefix(msg, "<84>1 2015-06-01T10:20:30Z "), msg)
+	assert.Contains(t, msg, " bearded - auth.loginFailed - CEF:0|Bearded|")
+}
+
+func TestKafka(t *testing.T) {
+	records := make(chan map[string][]*kafkaRecord, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/topics/events", r.URL.Path)
+		assert.Equal(t, "application/vnd.kafka.json.v2+json", r.Header.Get("Content-Type"))
+		body := map[string][]*kafkaRecord{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		records <- body
+	}))
+	defer ts.Close()
+
+	out := NewKafka(ts.URL+"/", "events")
+	require.NoError(t, out.Write(testEvent(), "message"))
+	body := <-records
+	require.Len(t, body["records"], 1)
+	assert.Equal(t, &kafkaRecord{Key: ClassLoginFailed, Value: "message"}, body["records"][0])
+
+	ts.Close()
+	assert.Error(t, out.Write(testEvent(), "message"))
+}
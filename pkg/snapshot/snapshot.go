@@ -0,0 +1,93 @@
+// Package snapshot takes nightly snapshots of project issue counts to the stats collection.
+// Trends are read from snapshots, so they don't aggregate issues on requests
+// and stay the same after issues are purged or projects are removed.
+package snapshot
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/facebookgo/stackerr"
+	"golang.org/x/net/context"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/stats"
+	"github.com/bearded-web/bearded/pkg/manager"
+)
+
+// lock in mongo which is held by the instance running checks
+const lockName = "snapshot"
+
+type Engine struct {
+	mgr *manager.Manager
+}
+
+func New(mgr *manager.Manager) *Engine {
+	return &Engine{
+		mgr: mgr,
+	}
+}
+
+// Run takes missing snapshots every interval until the context is done
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	logrus.Infof("Snapshot engine is started, check interval %s", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			if err := e.mgr.Locks.Release(lockName); err != nil {
+				logrus.Error(err)
+			}
+			return
+		case <-time.After(interval):
+			// only one api instance runs the check, others wait until the lock is expired
+			if !e.mgr.Locks.Lead(lockName, interval) {
+				continue
+			}
+			if err := e.Check(time.Now().UTC()); err != nil {
+				logrus.Error(err)
+			}
+		}
+	}
+}
+
+// Date returns the day which snapshots are taken for at now, it's the last finished UTC day
+func Date(now time.Time) string {
+	return now.UTC().AddDate(0, 0, -1).Format(stats.DateLayout)
+}
+
+// Check takes snapshots of projects which don't have them for the last finished day.
+// The day isn't filled later if the server was down the whole next day.
+func (e *Engine) Check(now time.Time) error {
+	mgr := e.mgr.Copy()
+	defer mgr.Close()
+
+	date := Date(now)
+	taken, err := mgr.Snapshots.Taken(date)
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	projects, _, err := mgr.Projects.FilterByQuery(bson.M{})
+	if err != nil {
+		return stackerr.Wrap(err)
+	}
+	count := 0
+	for _, p := range projects {
+		if taken[p.Id] {
+			continue
+		}
+		s, err := mgr.Issues.Snapshot(p.Id, date)
+		if err != nil {
+			logrus.Error(stackerr.Wrap(err))
+			continue
+		}
+		if err := mgr.Snapshots.Save(s); err != nil {
+			logrus.Error(stackerr.Wrap(err))
+			continue
+		}
+		count++
+	}
+	if count > 0 {
+		logrus.Infof("%d project snapshots are taken for %s", count, date)
+	}
+	return nil
+}
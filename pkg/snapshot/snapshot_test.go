@@ -0,0 +1,16 @@
+package snapshot
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDate(t *testing.T) {
+	assert.Equal(t, "2015-06-30", Date(time.Date(2015, 7, 1, 0, 5, 0, 0, time.UTC)))
+	assert.Equal(t, "2015-06-30", Date(time.Date(2015, 7, 1, 23, 59, 0, 0, time.UTC)))
+	// days are in UTC
+	loc := time.FixedZone("UTC+3", 3*60*60)
+	assert.Equal(t, "2015-06-29", Date(time.Date(2015, 7, 1, 2, 0, 0, 0, loc)))
+}
@@ -0,0 +1,74 @@
+// Package summary rebuilds materialized summaries of targets which are marked stale after issue changes.
+// Issue handlers don't count issues themselves, so slow counts and db errors don't affect them.
+package summary
+
+import (
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/facebookgo/stackerr"
+	"golang.org/x/net/context"
+
+	"github.com/bearded-web/bearded/pkg/manager"
+)
+
+// lock in mongo which is held by the instance building summaries
+const lockName = "summary"
+
+type Engine struct {
+	mgr *manager.Manager
+}
+
+func New(mgr *manager.Manager) *Engine {
+	return &Engine{
+		mgr: mgr,
+	}
+}
+
+// Run rebuilds stale summaries every interval until the context is done.
+// Only one api instance builds summaries, they are taken atomically, so a new leader continues after the lease.
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	logrus.Infof("Summary engine is started, check interval %s", interval)
+	for {
+		select {
+		case <-ctx.Done():
+			if err := e.mgr.Locks.Release(lockName); err != nil {
+				logrus.Error(err)
+			}
+			return
+		case <-time.After(interval):
+			// only one api instance runs the check, others wait until the lock is expired
+			if !e.mgr.Locks.Lead(lockName, interval) {
+				continue
+			}
+			if err := e.Check(ctx); err != nil {
+				logrus.Error(err)
+			}
+		}
+	}
+}
+
+// Check rebuilds summaries one by one while there are stale ones
+func (e *Engine) Check(ctx context.Context) error {
+	mgr := e.mgr.Copy()
+	defer mgr.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+		obj, err := mgr.Summaries.Take(time.Now().UTC())
+		if err != nil {
+			if mgr.IsNotFound(err) {
+				return nil
+			}
+			return stackerr.Wrap(err)
+		}
+		if err := mgr.Summaries.Build(obj); err != nil {
+			// the taken summary is built again when it's stale
+			logrus.Errorf("Summary of target %s isn't built: %s", obj.Target.Hex(), err)
+		}
+	}
+}
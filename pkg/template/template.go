@@ -12,6 +12,8 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/bearded-web/bearded/pkg/i18n"
 )
 
 type Renderer interface {
@@ -26,6 +28,8 @@ var helperFuncs = template.FuncMap{
 	"current": func() (string, error) {
 		return "", nil
 	},
+	// t translates the format to the locale, english is used until a bundle is set in Opts.Funcs
+	"t": (*i18n.Bundle)(nil).Sprintf,
 }
 
 // Delims represents a set of Left and Right delimiters for HTML template rendering.
@@ -110,15 +114,15 @@ func (t *Template) compileTemplatesFromDir() {
 				}
 
 				name := (rel[0 : len(rel)-len(ext)])
-				tmpl := t.templates.New(filepath.ToSlash(name))
+				tmpl := t.templates.New(filepath.ToSlash(name)).Funcs(helperFuncs)
 
-				// Add our funcmaps.
+				// Add our funcmaps, they could override helpers like t.
 				for _, funcs := range t.opt.Funcs {
 					tmpl.Funcs(funcs)
 				}
 
 				// Break out if this parsing fails. We don't want any silent server starts.
-				template.Must(tmpl.Funcs(helperFuncs).Parse(string(buf)))
+				template.Must(tmpl.Parse(string(buf)))
 				break
 			}
 		}
@@ -156,15 +160,15 @@ func (t *Template) compileTemplatesFromAsset() {
 				}
 
 				name := (rel[0 : len(rel)-len(ext)])
-				tmpl := t.templates.New(filepath.ToSlash(name))
+				tmpl := t.templates.New(filepath.ToSlash(name)).Funcs(helperFuncs)
 
-				// Add our funcmaps.
+				// Add our funcmaps, they could override helpers like t.
 				for _, funcs := range t.opt.Funcs {
 					tmpl.Funcs(funcs)
 				}
 
 				// Break out if this parsing fails. We don't want any silent server starts.
-				template.Must(tmpl.Funcs(helperFuncs).Parse(string(buf)))
+				template.Must(tmpl.Parse(string(buf)))
 				break
 			}
 		}
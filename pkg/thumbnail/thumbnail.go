@@ -0,0 +1,126 @@
+// Package thumbnail makes downscaled copies of uploaded images.
+package thumbnail
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+)
+
+// MaxPixels limits dimensions of decoded images, small files could declare huge images
+// which take gigabytes of memory when they are decoded
+const MaxPixels = 4096 * 4096
+
+// MaxFileSize limits images which are kept in memory to make thumbnails, bigger uploads don't get them
+const MaxFileSize = 16 << 20
+
+// IsImage reports whether thumbnails can be generated for the content type
+func IsImage(contentType string) bool {
+	switch strings.ToLower(contentType) {
+	case "image/png", "image/jpeg", "image/jpg", "image/gif":
+		return true
+	}
+	return false
+}
+
+// Resize scales img down so the largest side is not bigger than size.
+// Images which are already small enough are returned as is.
+func Resize(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if size <= 0 || (w <= size && h <= size) {
+		return img
+	}
+	nw, nh := size, size
+	if w > h {
+		nh = h * size / w
+	} else {
+		nw = w * size / h
+	}
+	if nw < 1 {
+		nw = 1
+	}
+	if nh < 1 {
+		nh = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, nw, nh))
+	// box filter: every destination pixel is an average of the source block
+	for y := 0; y < nh; y++ {
+		y0 := b.Min.Y + y*h/nh
+		y1 := b.Min.Y + (y+1)*h/nh
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < nw; x++ {
+			x0 := b.Min.X + x*w/nw
+			x1 := b.Min.X + (x+1)*w/nw
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					bl += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(bl / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
+
+// Make decodes an image from r, resizes it and writes the result to w.
+// Jpeg images stay jpeg, everything else is encoded as png.
+// Returns the content type of the written thumbnail.
+func Make(w io.Writer, r io.Reader, size int) (string, error) {
+	// the header read by the check is decoded again with the rest of the image
+	head := &bytes.Buffer{}
+	if err := CheckSize(io.TeeReader(r, head)); err != nil {
+		return "", err
+	}
+	img, format, err := image.Decode(io.MultiReader(head, r))
+	if err != nil {
+		return "", err
+	}
+	return Encode(w, Resize(img, size), format)
+}
+
+// CheckSize reads the image header and returns an error if the image is broken or has more than MaxPixels
+func CheckSize(r io.Reader) error {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > MaxPixels {
+		return fmt.Errorf("image %dx%d is too large, max %d pixels", cfg.Width, cfg.Height, MaxPixels)
+	}
+	return nil
+}
+
+// Encode writes img to w in a format suitable for thumbnails
+func Encode(w io.Writer, img image.Image, format string) (string, error) {
+	switch format {
+	case "jpeg":
+		return "image/jpeg", jpeg.Encode(w, img, &jpeg.Options{Quality: 85})
+	case "png", "gif":
+		return "image/png", png.Encode(w, img)
+	}
+	return "", fmt.Errorf("unsupported image format %s", format)
+}
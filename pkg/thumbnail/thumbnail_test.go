@@ -0,0 +1,71 @@
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsImage(t *testing.T) {
+	assert.True(t, IsImage("image/png"))
+	assert.True(t, IsImage("IMAGE/JPEG"))
+	assert.False(t, IsImage("text/plain"))
+	assert.False(t, IsImage(""))
+}
+
+func TestResize(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			src.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+
+	dst := Resize(src, 100)
+	assert.Equal(t, 100, dst.Bounds().Dx())
+	assert.Equal(t, 50, dst.Bounds().Dy())
+	r, g, b, a := dst.At(10, 10).RGBA()
+	assert.Equal(t, uint32(0xffff), r)
+	assert.Equal(t, uint32(0), g)
+	assert.Equal(t, uint32(0), b)
+	assert.Equal(t, uint32(0xffff), a)
+
+	// small images are untouched
+	assert.Equal(t, src, Resize(src, 500))
+}
+
+func TestMake(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 30, 60))
+	buf := &bytes.Buffer{}
+	require.NoError(t, png.Encode(buf, src))
+
+	out := &bytes.Buffer{}
+	contentType, err := Make(out, buf, 20)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", contentType)
+
+	img, err := png.Decode(out)
+	require.NoError(t, err)
+	assert.Equal(t, 10, img.Bounds().Dx())
+	assert.Equal(t, 20, img.Bounds().Dy())
+
+	_, err = Make(out, bytes.NewBufferString("not an image"), 20)
+	assert.Error(t, err)
+}
+
+func TestCheckSize(t *testing.T) {
+	buf := &bytes.Buffer{}
+	require.NoError(t, png.Encode(buf, image.NewRGBA(image.Rect(0, 0, 30, 60))))
+	assert.NoError(t, CheckSize(bytes.NewReader(buf.Bytes())))
+
+	// only the header is read, so the pixel data isn't needed
+	buf.Reset()
+	require.NoError(t, png.Encode(buf, image.NewGray(image.Rect(0, 0, 5000, 5000))))
+	assert.Error(t, CheckSize(buf))
+	assert.Error(t, CheckSize(bytes.NewBufferString("not an image")))
+}
@@ -0,0 +1,100 @@
+// Package urlnorm normalizes urls reported by plugins, so trivially different
+// urls of the same resource are fingerprinted as one issue.
+//
+// Scheme and host are lowercased, default ports and fragments are removed,
+// trailing slashes are trimmed from paths and query params are sorted by name.
+// Path case and param values are kept, they are significant for most servers.
+package urlnorm
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ws":    "80",
+	"wss":   "443",
+	"ftp":   "21",
+}
+
+// Normalize returns the canonical form of the url,
+// the raw url is returned trimmed if it couldn't be parsed or it's not absolute.
+func Normalize(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return raw
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = normHost(u.Scheme, u.Host)
+	u.Fragment = ""
+
+	if len(u.Path) > 1 {
+		u.Path = strings.TrimRight(u.Path, "/")
+		u.RawPath = strings.TrimRight(u.RawPath, "/")
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.RawQuery = normQuery(u.RawQuery)
+	return u.String()
+}
+
+// Equal reports whether both urls have the same canonical form
+func Equal(a, b string) bool {
+	return Normalize(a) == Normalize(b)
+}
+
+func normHost(scheme, host string) string {
+	host = strings.ToLower(host)
+	h, port, err := net.SplitHostPort(host)
+	if err != nil {
+		// there is no port
+		return strings.TrimSuffix(host, ".")
+	}
+	h = strings.TrimSuffix(h, ".")
+	if port == "" || defaultPorts[scheme] == port {
+		if strings.Contains(h, ":") {
+			// ipv6 address
+			return "[" + h + "]"
+		}
+		return h
+	}
+	return net.JoinHostPort(h, port)
+}
+
+// normQuery sorts params by name, values of the same param keep the order,
+// because it could be significant for the application
+func normQuery(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	params := strings.Split(raw, "&")
+	kept := params[:0]
+	for _, p := range params {
+		if p != "" {
+			kept = append(kept, p)
+		}
+	}
+	sort.Stable(byName(kept))
+	return strings.Join(kept, "&")
+}
+
+type byName []string
+
+func (s byName) Len() int      { return len(s) }
+func (s byName) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byName) Less(i, j int) bool {
+	return paramName(s[i]) < paramName(s[j])
+}
+
+func paramName(p string) string {
+	if i := strings.Index(p, "="); i >= 0 {
+		return p[:i]
+	}
+	return p
+}
@@ -0,0 +1,31 @@
+package urlnorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	for raw, expected := range map[string]string{
+		"HTTP://Example.COM":                 "http://example.com/",
+		"http://example.com:80/admin/":       "http://example.com/admin",
+		"https://example.com:443/Admin//":    "https://example.com/Admin",
+		"https://example.com:8443/":          "https://example.com:8443/",
+		"http://example.com./a#top":          "http://example.com/a",
+		"http://example.com/?b=2&a=1&&a=0":   "http://example.com/?a=1&a=0&b=2",
+		"http://[::1]:80/":                   "http://[::1]/",
+		"http://[::1]:8080/":                 "http://[::1]:8080/",
+		" http://example.com/a%2Fb?q=x%20y ": "http://example.com/a%2Fb?q=x%20y",
+		"/relative/path/":                    "/relative/path/",
+		"not a url":                          "not a url",
+	} {
+		assert.Equal(t, expected, Normalize(raw), raw)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	assert.True(t, Equal("http://EXAMPLE.com:80/login/?next=/&user=a", "http://example.com/login?user=a&next=/"))
+	assert.False(t, Equal("http://example.com/a?x=1&x=2", "http://example.com/a?x=2&x=1"))
+	assert.False(t, Equal("http://example.com/", "https://example.com/"))
+}
@@ -1,6 +1,9 @@
 package load
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
 	"io"
 	"io/ioutil"
 
@@ -17,3 +20,56 @@ func LoadYaml(r io.Reader, dst interface{}) error {
 	}
 	return err
 }
+
+// UnmarshalYamlStrict decodes yaml document to dst using json names of fields, unknown fields are rejected
+func UnmarshalYamlStrict(data []byte, dst interface{}) error {
+	data, err := YamlToJson(data)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(dst)
+}
+
+// YamlToJson converts yaml document to json, so it could be decoded with json names of fields
+func YamlToJson(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	v, err := jsonable(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// jsonable converts yaml maps with interface keys to maps with string keys
+func jsonable(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("key %v should be a string", k)
+			}
+			val, err := jsonable(val)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = val
+		}
+		return m, nil
+	case []interface{}:
+		for i, val := range v {
+			val, err := jsonable(val)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = val
+		}
+	}
+	return v, nil
+}
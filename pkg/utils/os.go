@@ -6,6 +6,7 @@ import (
 	"os/signal"
 	"runtime"
 	"strings"
+	"syscall"
 )
 
 func IsBoot2Docker() bool {
@@ -31,3 +32,9 @@ func NotifyInterrupt() <-chan os.Signal {
 	signal.Notify(c, os.Interrupt)
 	return c
 }
+
+func NotifyHangup() <-chan os.Signal {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, syscall.SIGHUP)
+	return c
+}
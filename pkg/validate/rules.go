@@ -0,0 +1,167 @@
+package validate
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/asaskevich/govalidator"
+)
+
+// Rule checks the field value, param is a part of the tag after "=", e.g. 80 for max=80.
+// Pointers are dereferenced before the rule is called, nil pointers are checked only by nonzero.
+// Field of the returned error is set by Struct.
+type Rule func(v reflect.Value, param string) *FieldError
+
+var (
+	rulesMu sync.RWMutex
+	rules   = map[string]Rule{
+		"nonzero":  nonzeroRule,
+		"min":      minRule,
+		"max":      maxRule,
+		"bsonId":   bsonIdRule,
+		"email":    emailRule,
+		"password": passwordRule,
+	}
+)
+
+// Register adds a rule which could be used in tags, a rule with the same name is replaced
+func Register(name string, rule Rule) {
+	rulesMu.Lock()
+	defer rulesMu.Unlock()
+	rules[name] = rule
+}
+
+func apply(v reflect.Value, rule string) *FieldError {
+	name, param := rule, ""
+	if i := strings.Index(rule, "="); i >= 0 {
+		name, param = rule[:i], rule[i+1:]
+	}
+	rulesMu.RLock()
+	fn, ok := rules[name]
+	rulesMu.RUnlock()
+	if !ok {
+		return &FieldError{Code: CodeUnsupported, Message: fmt.Sprintf("unknown rule %s", name)}
+	}
+	if name != "nonzero" {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil
+			}
+			v = v.Elem()
+		}
+	}
+	return fn(v, param)
+}
+
+func unsupported(v reflect.Value) *FieldError {
+	return &FieldError{Code: CodeUnsupported, Message: fmt.Sprintf("unsupported type %s", v.Type())}
+}
+
+// nonzeroRule checks pointers only for nil, like validator.v2 does,
+// so fields of the pointed struct report their own errors
+func nonzeroRule(v reflect.Value, _ string) *FieldError {
+	var zero bool
+	switch v.Kind() {
+	case reflect.Ptr:
+		zero = v.IsNil()
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		zero = v.Len() == 0
+	default:
+		zero = v.IsZero()
+	}
+	if zero {
+		return &FieldError{Code: CodeRequired, Message: "shouldn't be empty"}
+	}
+	return nil
+}
+
+// size returns length of strings in symbols, length of collections or numeric value
+func size(v reflect.Value) (float64, string, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(len([]rune(v.String()))), " symbols", true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), " items", true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), "", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), "", true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), "", true
+	}
+	return 0, "", false
+}
+
+func minRule(v reflect.Value, param string) *FieldError {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return &FieldError{Code: CodeUnsupported, Message: fmt.Sprintf("wrong min param %s", param)}
+	}
+	n, unit, ok := size(v)
+	if !ok {
+		return unsupported(v)
+	}
+	if n < limit {
+		return &FieldError{Code: CodeMin, Message: fmt.Sprintf("should be at least %s%s", param, unit)}
+	}
+	return nil
+}
+
+func maxRule(v reflect.Value, param string) *FieldError {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return &FieldError{Code: CodeUnsupported, Message: fmt.Sprintf("wrong max param %s", param)}
+	}
+	n, unit, ok := size(v)
+	if !ok {
+		return unsupported(v)
+	}
+	if n > limit {
+		return &FieldError{Code: CodeMax, Message: fmt.Sprintf("should be at most %s%s", param, unit)}
+	}
+	return nil
+}
+
+// BsonId returns true if s is bson uuid in hex form
+func BsonId(s string) bool {
+	if len(s) != 24 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+func bsonIdRule(v reflect.Value, _ string) *FieldError {
+	if v.Kind() != reflect.String {
+		return unsupported(v)
+	}
+	if !BsonId(v.String()) {
+		return &FieldError{Code: CodeBsonId, Message: "should be bson uuid in hex form"}
+	}
+	return nil
+}
+
+// emailRule doesn't check empty values, nonzero should be used for required emails
+func emailRule(v reflect.Value, _ string) *FieldError {
+	if v.Kind() != reflect.String {
+		return unsupported(v)
+	}
+	if v.Len() > 0 && !govalidator.IsEmail(v.String()) {
+		return &FieldError{Code: CodeEmail, Message: "should be a valid email"}
+	}
+	return nil
+}
+
+func passwordRule(v reflect.Value, _ string) *FieldError {
+	if v.Kind() != reflect.String {
+		return unsupported(v)
+	}
+	if valid, reason := Password(v.String()); !valid {
+		return &FieldError{Code: CodePassword, Message: reason}
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Codes of field errors, codes of tag rules are the same as their names
+const (
+	CodeRequired    = "required"
+	CodeMin         = "min"
+	CodeMax         = "max"
+	CodeBsonId      = "bsonId"
+	CodeEmail       = "email"
+	CodePassword    = "password"
+	CodeInvalid     = "invalid"
+	CodeUnsupported = "unsupported"
+)
+
+// DefaultTag is always checked by Struct, other tags are checked only when they are passed
+const DefaultTag = "validate"
+
+// FieldError describes why the field value is invalid
+type FieldError struct {
+	Field   string `json:"field" description:"json path of the field, e.g. web.domain"`
+	Code    string `json:"code" description:"machine readable reason, e.g. required, max or invalid"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// Errors is a list of field errors, all validations of the project return it
+type Errors []*FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Add appends a field error with the formatted message
+func (e *Errors) Add(field, code, msg string, args ...interface{}) {
+	if len(args) > 0 {
+		msg = fmt.Sprintf(msg, args...)
+	}
+	*e = append(*e, &FieldError{Field: field, Code: code, Message: msg})
+}
+
+// Extend appends errors of a nested object, their fields are prefixed with the field name
+func (e *Errors) Extend(field string, err error) {
+	if err == nil {
+		return
+	}
+	for _, fe := range FromError(err) {
+		name := field
+		if fe.Field != "" {
+			name = join(field, fe.Field)
+		}
+		*e = append(*e, &FieldError{Field: name, Code: fe.Code, Message: fe.Message})
+	}
+}
+
+// Err returns nil if there are no errors, so the result could be compared with nil
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// NewError returns errors with a single field error
+func NewError(field, code, msg string, args ...interface{}) error {
+	errs := Errors{}
+	errs.Add(field, code, msg, args...)
+	return errs
+}
+
+// Nested prefixes fields of err with the name of the field which holds the nested object
+func Nested(field string, err error) error {
+	errs := Errors{}
+	errs.Extend(field, err)
+	return errs.Err()
+}
+
+// FromError converts any error to field errors, errors without fields get the invalid code
+func FromError(err error) Errors {
+	switch e := err.(type) {
+	case nil:
+		return nil
+	case Errors:
+		return e
+	case *FieldError:
+		return Errors{e}
+	}
+	return Errors{{Code: CodeInvalid, Message: err.Error()}}
+}
+
+// Validator is implemented by objects with checks which couldn't be described by tags
+type Validator interface {
+	Validate() error
+}
+
+// Entity checks obj by Struct and calls its Validate method, entities read from requests are checked by it
+func Entity(obj interface{}, tags ...string) error {
+	errs := Struct(obj, tags...)
+	if v, ok := obj.(Validator); ok {
+		errs = append(errs, FromError(v.Validate())...)
+	}
+	return errs.Err()
+}
+
+// Struct checks rules from the default tag and the passed tags of obj fields, e.g. `validate:"nonzero,max=80"`.
+// Nested structs are checked too, their Validate methods are called if they implement Validator.
+// Validate of obj itself isn't called, so Validate methods should check only what tags can't describe.
+func Struct(obj interface{}, tags ...string) Errors {
+	errs := Errors{}
+	walk(reflect.ValueOf(obj), "", append([]string{DefaultTag}, tags...), &errs)
+	return errs
+}
+
+func walk(v reflect.Value, path string, tags []string, errs *Errors) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		// fields of embedded structs are promoted even if the struct isn't exported
+		if f.PkgPath != "" && !f.Anonymous {
+			continue
+		}
+		name, skip := fieldName(f)
+		if skip {
+			continue
+		}
+		fv := v.Field(i)
+		if f.Anonymous {
+			walk(fv, path, tags, errs)
+			continue
+		}
+		name = join(path, name)
+		if fe := check(fv, f.Tag, tags); fe != nil {
+			fe.Field = name
+			*errs = append(*errs, fe)
+			continue
+		}
+		if obj, ok := nested(fv); ok {
+			walk(fv, name, tags, errs)
+			if vObj, ok := obj.(Validator); ok {
+				errs.Extend(name, vObj.Validate())
+			}
+		}
+	}
+}
+
+// check returns the first failed rule of the field
+func check(v reflect.Value, tag reflect.StructTag, tags []string) *FieldError {
+	for _, name := range tags {
+		for _, rule := range strings.Split(tag.Get(name), ",") {
+			if rule == "" {
+				continue
+			}
+			if fe := apply(v, rule); fe != nil {
+				return fe
+			}
+		}
+	}
+	return nil
+}
+
+// nested returns the struct value as an interface, it's addressable if possible to find pointer methods
+func nested(v reflect.Value) (interface{}, bool) {
+	if !v.CanInterface() {
+		return nil, false
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || v.Elem().Kind() != reflect.Struct {
+			return nil, false
+		}
+		return v.Interface(), true
+	case reflect.Struct:
+		if v.CanAddr() {
+			return v.Addr().Interface(), true
+		}
+		return v.Interface(), true
+	}
+	return nil, false
+}
+
+func fieldName(f reflect.StructField) (string, bool) {
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", true
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name, false
+	}
+	return f.Name, false
+}
+
+func join(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
@@ -0,0 +1,155 @@
+package validate
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type limitEntity struct {
+	Rps int `json:"rps" validate:"min=0"`
+}
+
+func (l *limitEntity) Validate() error {
+	if l.Rps > 100 {
+		return NewError("rps", CodeMax, "too fast")
+	}
+	return nil
+}
+
+type inlineEntity struct {
+	Note string `json:"note" validate:"max=5"`
+}
+
+type testEntity struct {
+	Name    string       `json:"name,omitempty" create:"nonzero" validate:"max=10"`
+	Project string       `json:"project" create:"nonzero,bsonId"`
+	Email   string       `json:"email" validate:"email"`
+	Summary *string      `json:"summary,omitempty" validate:"min=3"`
+	Limit   *limitEntity `json:"limit,omitempty"`
+	Ignored string       `json:"-" validate:"nonzero"`
+
+	inlineEntity `json:",inline"`
+}
+
+func TestStruct(t *testing.T) {
+	short := "ab"
+	obj := &testEntity{
+		Name:         "very long name",
+		Email:        "invalid",
+		Summary:      &short,
+		Limit:        &limitEntity{Rps: -1},
+		inlineEntity: inlineEntity{Note: "too long"},
+	}
+	errs := Struct(obj)
+	require.Len(t, errs, 5)
+	assert.Equal(t, &FieldError{Field: "name", Code: CodeMax, Message: "should be at most 10 symbols"}, errs[0])
+	assert.Equal(t, "email", errs[1].Field)
+	assert.Equal(t, CodeEmail, errs[1].Code)
+	assert.Equal(t, &FieldError{Field: "summary", Code: CodeMin, Message: "should be at least 3 symbols"}, errs[2])
+	assert.Equal(t, "limit.rps", errs[3].Field)
+	assert.Equal(t, "note", errs[4].Field)
+
+	// Validate of nested objects is called
+	obj = &testEntity{Limit: &limitEntity{Rps: 200}}
+	errs = Struct(obj)
+	require.Len(t, errs, 1)
+	assert.Equal(t, &FieldError{Field: "limit.rps", Code: CodeMax, Message: "too fast"}, errs[0])
+
+	// Validate of the object itself is called only by Entity
+	assert.Empty(t, Struct(&limitEntity{Rps: 200}))
+	assert.EqualError(t, Entity(&limitEntity{Rps: 200}), "rps: too fast")
+
+	// tags are checked only when they are passed
+	assert.NoError(t, Struct(&testEntity{}).Err())
+	errs = Struct(&testEntity{}, "create")
+	require.Len(t, errs, 2)
+	assert.Equal(t, CodeRequired, errs[0].Code)
+	assert.Equal(t, "project", errs[1].Field)
+	assert.Equal(t, "name: shouldn't be empty; project: shouldn't be empty", errs.Error())
+}
+
+func TestBsonIdRule(t *testing.T) {
+	type B struct {
+		Id string `validate:"bsonId"`
+	}
+	type C struct {
+		Id int `validate:"bsonId"`
+	}
+
+	testData := []struct {
+		id    string
+		valid bool
+	}{
+		{"", false},
+		{"12345678901234567890123j", false},
+		{"123456789012345678901234", true},
+	}
+	for _, td := range testData {
+		errs := Struct(B{Id: td.id})
+		if td.valid {
+			assert.Empty(t, errs, td.id)
+			continue
+		}
+		require.Len(t, errs, 1, td.id)
+		assert.Equal(t, &FieldError{Field: "Id", Code: CodeBsonId, Message: "should be bson uuid in hex form"}, errs[0])
+	}
+
+	errs := Struct(C{})
+	require.Len(t, errs, 1)
+	assert.Equal(t, CodeUnsupported, errs[0].Code)
+}
+
+func TestRegister(t *testing.T) {
+	Register("even", func(v reflect.Value, _ string) *FieldError {
+		if v.Int()%2 != 0 {
+			return &FieldError{Code: "even", Message: "should be even"}
+		}
+		return nil
+	})
+	type E struct {
+		N int `json:"n" validate:"even"`
+		U int `json:"u" validate:"unknown"`
+	}
+	errs := Struct(&E{N: 3})
+	require.Len(t, errs, 2)
+	assert.Equal(t, &FieldError{Field: "n", Code: "even", Message: "should be even"}, errs[0])
+	assert.Equal(t, CodeUnsupported, errs[1].Code)
+}
+
+func TestErrors(t *testing.T) {
+	errs := Errors{}
+	assert.Nil(t, errs.Err())
+
+	errs.Add("name", CodeMax, "should be at most %d symbols", 80)
+	errs.Extend("repo", NewError("url", CodeInvalid, "host is empty"))
+	errs.Extend("rateLimit", fmt.Errorf("wrong limit"))
+	assert.Equal(t, Errors{
+		{Field: "name", Code: CodeMax, Message: "should be at most 80 symbols"},
+		{Field: "repo.url", Code: CodeInvalid, Message: "host is empty"},
+		{Field: "rateLimit", Code: CodeInvalid, Message: "wrong limit"},
+	}, errs)
+
+	assert.Nil(t, Nested("repo", nil))
+	assert.Equal(t, Errors{{Code: CodeInvalid, Message: "boom"}}, FromError(fmt.Errorf("boom")))
+}
+
+func TestNonzeroPointer(t *testing.T) {
+	type Web struct {
+		Domain string `json:"domain" validate:"nonzero"`
+	}
+	type T struct {
+		Web *Web `json:"web" validate:"nonzero"`
+	}
+	errs := Struct(&T{})
+	require.Len(t, errs, 1)
+	assert.Equal(t, "web", errs[0].Field)
+
+	// the pointed struct reports its own fields
+	errs = Struct(&T{Web: &Web{}})
+	require.Len(t, errs, 1)
+	assert.Equal(t, "web.domain", errs[0].Field)
+}
@@ -0,0 +1,193 @@
+package admin
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/notification"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/delivery"
+	"github.com/bearded-web/bearded/pkg/email"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/i18n"
+	"github.com/bearded-web/bearded/pkg/notify"
+	"github.com/bearded-web/bearded/services"
+)
+
+const ParamTemplate = "template-name"
+
+// EmailTestEntity is the recipient of the test email
+type EmailTestEntity struct {
+	To string `json:"to,omitempty" description:"email of the current admin if empty"`
+}
+
+// EmailPreview is a rendered email which isn't sent
+type EmailPreview struct {
+	Template    string   `json:"template"`
+	Subject     string   `json:"subject"`
+	ContentType string   `json:"contentType" description:"one of [text/plain|text/html]"`
+	Body        string   `json:"body"`
+	Attachments []string `json:"attachments,omitempty" description:"names of attached files"`
+}
+
+type EmailPreviewList struct {
+	Results []string `json:"results" description:"names of email templates"`
+}
+
+// previews render emails with sample data
+var previews = map[string]func(s *AdminService, locale string) (*EmailPreview, error){
+	"verify-email":   htmlPreview("email/verify-email", "Verify email in bearded-web service", "/api/v1/auth/verify?token=sample"),
+	"reset-password": htmlPreview("email/reset-password", "Reset password in bearded-web service", "/#/reset-password?token=sample"),
+	"notification":   notificationPreview,
+	"delivery":       deliveryPreview,
+}
+
+func (s *AdminService) RegisterEmail(ws *restful.WebService) {
+	r := ws.POST("email/test").To(s.emailTest)
+	addDefaults(r)
+	r.Doc("emailTest")
+	r.Operation("emailTest")
+	r.Notes("Authorization required, only for admins. Sends a test email with the current email config, " +
+		"the error of the backend is returned if the email isn't sent")
+	r.Reads(EmailTestEntity{})
+	r.Do(services.Returns(http.StatusNoContent))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.GET("email/templates").To(s.emailTemplates)
+	addDefaults(r)
+	r.Doc("emailTemplates")
+	r.Operation("emailTemplates")
+	r.Writes(EmailPreviewList{})
+	r.Do(services.Returns(http.StatusOK))
+	ws.Route(r)
+
+	r = ws.GET(fmt.Sprintf("email/templates/{%s}", ParamTemplate)).To(s.emailPreview)
+	addDefaults(r)
+	r.Doc("emailPreview")
+	r.Operation("emailPreview")
+	r.Notes("Authorization required, only for admins. Renders the email with sample data, nothing is sent")
+	r.Param(ws.PathParameter(ParamTemplate, ""))
+	r.Param(ws.QueryParameter("locale", "render for users with the locale, english by default"))
+	r.Writes(EmailPreview{})
+	r.Do(services.Returns(http.StatusOK, http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+}
+
+func (s *AdminService) emailTest(req *restful.Request, resp *restful.Response) {
+	raw := &EmailTestEntity{}
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
+	}
+	u := filters.GetUser(req)
+	if raw.To == "" {
+		raw.To = u.Email
+	}
+	cfg := s.ApiCfg()
+	msg := email.NewMessage()
+	msg.SetHeader("From", msg.FormatAddress(cfg.SystemEmail, "Bearded"))
+	msg.SetHeader("To", raw.To)
+	msg.SetHeader("Subject", "Test email from Bearded")
+	msg.SetBody("text/plain", fmt.Sprintf("The email config of %s works, the test is sent by %s at %s.",
+		cfg.Host, u.Email, time.Now().UTC().Format(time.RFC1123)))
+	if err := s.Mailer().Send(msg); err != nil {
+		logrus.Errorf("Test email to %s isn't sent: %s", raw.To, err)
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Test email isn't sent: %s", err))
+		return
+	}
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+func (s *AdminService) emailTemplates(req *restful.Request, resp *restful.Response) {
+	result := &EmailPreviewList{Results: []string{}}
+	for name := range previews {
+		result.Results = append(result.Results, name)
+	}
+	sort.Strings(result.Results)
+	resp.WriteEntity(result)
+}
+
+func (s *AdminService) emailPreview(req *restful.Request, resp *restful.Response) {
+	name := req.PathParameter(ParamTemplate)
+	fn, ok := previews[name]
+	if !ok {
+		resp.WriteErrorString(http.StatusNotFound, "Not found")
+		return
+	}
+	locale := req.QueryParameter("locale")
+	if locale == "" {
+		locale = i18n.Fallback
+	}
+	if !s.I18n.Has(locale) {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("unsupported locale %s", locale))
+		return
+	}
+	result, err := fn(s, locale)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.NewAppErr(fmt.Sprintf("Template isn't rendered: %s", err)))
+		return
+	}
+	result.Template = name
+	resp.WriteEntity(result)
+}
+
+// Previews
+
+func htmlPreview(tmpl, subject, path string) func(s *AdminService, locale string) (*EmailPreview, error) {
+	return func(s *AdminService, locale string) (*EmailPreview, error) {
+		cfg := s.ApiCfg()
+		data := map[string]string{
+			"ReqUrl":       fmt.Sprintf("%s%s", cfg.Host, path),
+			"Nickname":     "sample",
+			"SystemEmail":  cfg.SystemEmail,
+			"ContactEmail": cfg.ContactEmail,
+			"Locale":       locale,
+		}
+		buf := &bytes.Buffer{}
+		if err := s.Template.Render(buf, tmpl, data); err != nil {
+			return nil, err
+		}
+		return &EmailPreview{Subject: subject, ContentType: "text/html", Body: buf.String()}, nil
+	}
+}
+
+func notificationPreview(s *AdminService, locale string) (*EmailPreview, error) {
+	cfg := s.ApiCfg()
+	n := &notify.Notification{
+		Event:   notification.EventIssueAssigned,
+		User:    &user.User{Locale: locale},
+		Subject: s.I18n.Translate(locale, "Issue is assigned to Backend"),
+		Text:    s.I18n.Translate(locale, `Issue "Sql injection" is assigned to your team Backend`),
+		Link:    fmt.Sprintf("/#/issue/%s", bson.NewObjectId().Hex()),
+		Time:    time.Now().UTC(),
+	}
+	return &EmailPreview{
+		Subject:     n.Subject,
+		ContentType: "text/plain",
+		Body:        notify.EmailBody(n, cfg.Host, cfg.Reply.Address != "", s.I18n),
+	}, nil
+}
+
+func deliveryPreview(s *AdminService, _ string) (*EmailPreview, error) {
+	now := time.Now().UTC()
+	p := &project.Project{Id: bson.NewObjectId(), Name: "sample", Delivery: &project.Delivery{}}
+	return &EmailPreview{
+		Subject:     delivery.Subject(p, now, &user.User{}),
+		ContentType: "text/plain",
+		Body:        delivery.Body(p, delivery.MaxIssues, delivery.MaxIssues+20, s.ApiCfg().Host),
+		Attachments: []string{delivery.Filename(p, now, &user.User{})},
+	}, nil
+}
@@ -0,0 +1,27 @@
+package admin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bearded-web/bearded/pkg/config"
+	"github.com/bearded-web/bearded/pkg/template"
+	"github.com/bearded-web/bearded/services"
+)
+
+func TestEmailPreviews(t *testing.T) {
+	cfg := config.NewDispatcher().Api
+	cfg.Host = "http://bearded"
+	base := services.New(nil, nil, nil, nil, cfg)
+	base.Template = template.New(&template.Opts{Directory: "../../extra/templates"})
+	s := New(base)
+
+	for name, fn := range previews {
+		result, err := fn(s, "en")
+		require.NoError(t, err, name)
+		assert.NotEmpty(t, result.Subject, name)
+		assert.Contains(t, result.Body, "http://bearded", name)
+	}
+}
@@ -0,0 +1,120 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/integrity"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/services"
+)
+
+const (
+	defaultProblems = 100
+	maxProblems     = 1000
+)
+
+func (s *AdminService) RegisterIntegrity(ws *restful.WebService) {
+	r := ws.GET("integrity").To(s.integrityCheck)
+	addDefaults(r)
+	r.Doc("integrityCheck")
+	r.Operation("integrityCheck")
+	r.Notes("Authorization required, only for admins. Find issues, scans, comments and reports which reference missing objects")
+	r.Param(ws.QueryParameter("limit", "max problems of every kind in the report, 100 by default, max 1000").DataType("integer"))
+	r.Writes(integrity.Report{})
+	r.Do(services.Returns(http.StatusOK))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.POST("integrity/repair").To(s.integrityRepair)
+	addDefaults(r)
+	r.Doc("integrityRepair")
+	r.Operation("integrityRepair")
+	r.Notes("Authorization required, only for admins. Delete all objects with dangling references of the kind, " +
+		"issues and scans could be reattached to an existing target instead")
+	r.Reads(integrity.Repair{})
+	r.Writes(integrity.Report{})
+	r.Do(services.Returns(http.StatusOK))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+}
+
+func (s *AdminService) integrityCheck(req *restful.Request, resp *restful.Response) {
+	limit := defaultProblems
+	if p := req.QueryParameter("limit"); p != "" {
+		val, err := strconv.Atoi(p)
+		if err != nil || val < 0 || val > maxProblems {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("limit should be from 0 to %d", maxProblems))
+			return
+		}
+		limit = val
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	result, err := mgr.Integrity(limit)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(result)
+}
+
+func (s *AdminService) integrityRepair(req *restful.Request, resp *restful.Response) {
+	raw := &integrity.Repair{}
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
+	}
+	if !raw.Kind.Valid() {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("unknown kind %s", raw.Kind))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	var t *target.Target
+	switch raw.Action {
+	case integrity.ActionDelete:
+	case integrity.ActionReattach:
+		if !raw.Kind.CanReattach() {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("only issues and scans could be reattached"))
+			return
+		}
+		if !raw.Target.Valid() {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("target is required to reattach"))
+			return
+		}
+		obj, err := mgr.Targets.GetById(raw.Target)
+		if err != nil {
+			if mgr.IsNotFound(err) {
+				resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("target not found"))
+				return
+			}
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+		t = obj
+	default:
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("action should be delete or reattach"))
+		return
+	}
+
+	result, err := mgr.Repair(raw.Kind, raw.Action, t)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	logrus.Infof("Integrity repair: %s %d of %d %s", raw.Action, result.Repaired, result.Counts[raw.Kind], raw.Kind)
+	resp.WriteEntity(result)
+}
@@ -0,0 +1,61 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/migration"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/services"
+)
+
+// max changed fields in the response, counts of moved objects are always full
+const maxMoveChanges = 1000
+
+func (s *AdminService) RegisterMigration(ws *restful.WebService) {
+	r := ws.POST("hosts/move").To(s.hostMove)
+	addDefaults(r)
+	r.Doc("hostMove")
+	r.Operation("hostMove")
+	r.Notes("Authorization required, only for admins. Rewrite addresses of web and api targets on the old host " +
+		"and urls of their issues, e.g. when the application is moved from staging.example.com to app.example.com. " +
+		"Issues are updated in place, so history is kept, generated uniq ids are recalculated for the new urls. " +
+		"Moved targets get a move feed item with the old address and rewritten issues get a moved activity")
+	r.Param(ws.QueryParameter("dryRun", "return changes without storing them").DataType("boolean"))
+	r.Reads(migration.HostMove{})
+	r.Writes(migration.Result{})
+	r.Do(services.Returns(http.StatusOK))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+}
+
+func (s *AdminService) hostMove(req *restful.Request, resp *restful.Response) {
+	raw := &migration.HostMove{}
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
+	}
+	if err := raw.Validate(); err != nil {
+		services.NewValidationErr(err).Write(resp)
+		return
+	}
+	dryRun := req.QueryParameter("dryRun") == "true"
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	result, err := mgr.MoveHost(raw, filters.GetUser(req).Id, dryRun, maxMoveChanges)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	if !dryRun {
+		logrus.Infof("Host %s is moved to %s: %d targets, %d issues", raw.From, raw.To, result.Targets, result.Issues)
+	}
+	resp.WriteEntity(result)
+}
@@ -0,0 +1,188 @@
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/stats"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/siem"
+	"github.com/bearded-web/bearded/services"
+)
+
+const (
+	// stats are heavy for big instances, so they are recalculated not more often than once in ttl
+	statsTtl = time.Minute
+
+	defaultDays = 14
+	maxDays     = 90
+
+	defaultTrendDays = 30
+	maxTrendDays     = 366
+)
+
+type AdminService struct {
+	*services.BaseService
+
+	mu    sync.Mutex
+	stats map[int]*stats.Stats // days -> cached stats
+}
+
+func New(base *services.BaseService) *AdminService {
+	return &AdminService{
+		BaseService: base,
+		stats:       map[int]*stats.Stats{},
+	}
+}
+
+func addDefaults(r *restful.RouteBuilder) {
+	r.Notes("Authorization required, only for admins")
+	r.Do(services.ReturnsE(
+		http.StatusUnauthorized,
+		http.StatusForbidden,
+		http.StatusInternalServerError,
+	))
+}
+
+func (s *AdminService) Register(container *restful.Container) {
+	ws := &restful.WebService{}
+	ws.Path("/api/v1/admin")
+	ws.Doc("Instance administration")
+	ws.Consumes(restful.MIME_JSON)
+	ws.Produces(restful.MIME_JSON)
+	ws.Filter(filters.AuthTokenFilter(s.BaseManager()))
+	ws.Filter(filters.AuthRequiredFilter(s.BaseManager()))
+	ws.Filter(filters.AdminRequiredFilter(s.BaseManager()))
+	ws.Filter(s.audit)
+
+	r := ws.GET("stats").To(s.statsGet)
+	addDefaults(r)
+	r.Doc("stats")
+	r.Operation("stats")
+	r.Param(ws.QueryParameter("days", "count scans for the last days, 14 by default, max 90").DataType("integer"))
+	r.Param(ws.QueryParameter("fresh", "recalculate cached stats").DataType("boolean"))
+	r.Writes(stats.Stats{})
+	r.Do(services.Returns(http.StatusOK))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.GET("stats/trend").To(s.trendGet)
+	addDefaults(r)
+	r.Doc("trend")
+	r.Operation("trend")
+	r.Notes("Authorization required, only for admins. Issues by status and severity at the end of every day " +
+		"summed for all projects, they are taken from nightly snapshots. Removed projects are counted till their removal")
+	r.Param(ws.QueryParameter("days", fmt.Sprintf("the last days, %d by default, max %d", defaultTrendDays, maxTrendDays)).DataType("integer"))
+	r.Param(ws.QueryParameter("project", "only this project, it could be removed already"))
+	r.Writes(stats.Trend{})
+	r.Do(services.Returns(http.StatusOK))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.GET("pool").To(s.poolGet)
+	addDefaults(r)
+	r.Doc("pool")
+	r.Operation("pool")
+	r.Notes("Authorization required, only for admins. Mongo connections and driver counters since the start")
+	r.Writes(stats.Pool{})
+	r.Do(services.Returns(http.StatusOK))
+	ws.Route(r)
+
+	s.RegisterIntegrity(ws)
+	s.RegisterMigration(ws)
+	s.RegisterEmail(ws)
+
+	container.Add(ws)
+}
+
+func (s *AdminService) statsGet(req *restful.Request, resp *restful.Response) {
+	days := defaultDays
+	if p := req.QueryParameter("days"); p != "" {
+		val, err := strconv.Atoi(p)
+		if err != nil || val <= 0 || val > maxDays {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("days should be from 1 to %d", maxDays))
+			return
+		}
+		days = val
+	}
+	now := time.Now().UTC()
+	fresh := req.QueryParameter("fresh") == "true"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cached, ok := s.stats[days]; ok && !fresh && now.Sub(cached.Generated) < statsTtl {
+		resp.WriteEntity(cached)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	result, err := mgr.Stats(now, days)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	s.stats[days] = result
+	resp.WriteEntity(result)
+}
+
+func (s *AdminService) trendGet(req *restful.Request, resp *restful.Response) {
+	days := defaultTrendDays
+	if p := req.QueryParameter("days"); p != "" {
+		val, err := strconv.Atoi(p)
+		if err != nil || val <= 0 || val > maxTrendDays {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("days should be from 1 to %d", maxTrendDays))
+			return
+		}
+		days = val
+	}
+	var projectId bson.ObjectId
+	if p := req.QueryParameter("project"); p != "" {
+		if !bson.IsObjectIdHex(p) {
+			resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+			return
+		}
+		projectId = bson.ObjectIdHex(p)
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	result := stats.NewTrend(projectId, time.Now().UTC(), days)
+	snapshots, err := mgr.Snapshots.Range(projectId, result.From, result.To)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	if projectId == "" {
+		snapshots = stats.Sum(snapshots)
+	}
+	result.Results = snapshots
+	resp.WriteEntity(result)
+}
+
+func (s *AdminService) poolGet(_ *restful.Request, resp *restful.Response) {
+	resp.WriteEntity(s.BaseManager().PoolStats())
+}
+
+// audit streams admin changes to siem, reads aren't streamed
+func (s *AdminService) audit(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	chain.ProcessFilter(req, resp)
+
+	if req.Request.Method != "GET" {
+		action := fmt.Sprintf("%s %s", req.Request.Method, req.Request.URL.Path)
+		s.BaseManager().Cfg.Siem.Emit(siem.AdminEvent(filters.GetUser(req).Email, filters.ClientIp(req.Request), action, resp.StatusCode()))
+	}
+}
@@ -0,0 +1,177 @@
+package agent
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/agent"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/services"
+)
+
+const (
+	defaultPrePullHours = 24
+	maxPrePullHours     = 7 * 24
+)
+
+func (s *AgentService) RegisterImages(ws *restful.WebService) {
+	r := ws.POST("prepull").To(s.prePull)
+	addDefaults(r)
+	r.Doc("prePull")
+	r.Operation("prePull")
+	r.Notes("Ask agents to pull images of plugins used by scans which aren't started yet and are scheduled " +
+		"during the next hours, so downloads don't eat into scan windows. Images are sent to agents " +
+		"with their next jobs request. Admin required")
+	r.Reads(agent.PrePull{})
+	r.Writes(agent.PrePull{})
+	r.Do(services.Returns(http.StatusOK))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+
+	r = ws.GET(fmt.Sprintf("{%s}/images", ParamId)).To(s.TakeAgent(s.images))
+	addDefaults(r)
+	r.Doc("images")
+	r.Operation("images")
+	r.Notes("Image cache reported by the agent and images which the agent is asked to pull")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Writes(agent.ImageCache{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.PUT(fmt.Sprintf("{%s}/images", ParamId)).To(s.TakeAgent(s.imagesReport))
+	addDefaults(r)
+	r.Doc("imagesReport")
+	r.Operation("imagesReport")
+	r.Notes("Agents report their image cache after pulling images, reported images replace the cached ones with the same name. " +
+		"Only the agent user could report")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Reads([]*agent.Image{})
+	r.Writes(agent.ImageCache{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+}
+
+func (s *AgentService) prePull(req *restful.Request, resp *restful.Response) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	// pulls are queued for every agent and images of all projects are returned
+	if !mgr.Permission.IsAdmin(filters.GetUser(req)) {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+
+	raw := &agent.PrePull{}
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
+	}
+	if raw.Hours == 0 {
+		raw.Hours = defaultPrePullHours
+	}
+	if raw.Hours < 0 || raw.Hours > maxPrePullHours {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("hours should be from 1 to %d", maxPrePullHours))
+		return
+	}
+
+	query := bson.M{"status": agent.StatusApproved}
+	if len(raw.Agents) > 0 {
+		ids := []bson.ObjectId{}
+		for _, id := range raw.Agents {
+			if !s.IsId(id) {
+				resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+				return
+			}
+			ids = append(ids, mgr.ToId(id))
+		}
+		query["_id"] = bson.M{"$in": ids}
+	}
+	agents, _, err := mgr.Agents.FilterByQuery(query)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	if len(agents) == 0 {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("there are no approved agents"))
+		return
+	}
+
+	until := time.Now().UTC().Add(time.Duration(raw.Hours) * time.Hour)
+	images, err := mgr.Scans.UpcomingImages(until)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	if len(images) > 0 {
+		ids := make([]bson.ObjectId, len(agents))
+		raw.Agents = make([]string, len(agents))
+		for i, ag := range agents {
+			ids[i] = ag.Id
+			raw.Agents[i] = ag.Id.Hex()
+		}
+		if err := mgr.Agents.AddPull(ids, images); err != nil {
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+	}
+	raw.Until = &until
+	raw.Images = images
+	resp.WriteEntity(raw)
+}
+
+func (s *AgentService) images(_ *restful.Request, resp *restful.Response, ag *agent.Agent) {
+	resp.WriteEntity(agent.NewImageCache(ag))
+}
+
+func (s *AgentService) imagesReport(req *restful.Request, resp *restful.Response, ag *agent.Agent) {
+	if filters.GetUser(req).Email != manager.AgentEmail {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	raw := []*agent.Image{}
+	if err := req.ReadEntity(&raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
+	}
+	for i, img := range raw {
+		if img == nil || img.Name == "" {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("image %d should have a name", i))
+			return
+		}
+	}
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if err := mgr.Agents.SetImages(ag, raw, time.Now().UTC()); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(agent.NewImageCache(ag))
+}
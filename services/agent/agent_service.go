@@ -3,6 +3,7 @@ package agent
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/Sirupsen/logrus"
@@ -80,18 +81,16 @@ func (s *AgentService) Register(container *restful.Container) {
 	r.Do(services.ReturnsE(http.StatusBadRequest))
 	ws.Route(r)
 
-	r = ws.PUT(fmt.Sprintf("{%s}", ParamId)).To(s.TakeAgent(s.update))
-	// docs
-	r.Doc("update")
-	r.Operation("update")
-	r.Param(ws.PathParameter(ParamId, ""))
-	r.Writes(agent.Agent{})
-	r.Reads(agent.Agent{})
-	r.Do(services.Returns(
-		http.StatusOK,
-		http.StatusNotFound))
-	r.Do(services.ReturnsE(http.StatusBadRequest))
-	ws.Route(r) //disable put
+	for _, r := range services.UpdateRoutes(ws, fmt.Sprintf("{%s}", ParamId), s.TakeAgent(s.update)) {
+		r.Param(ws.PathParameter(ParamId, ""))
+		r.Writes(agent.Agent{})
+		r.Reads(agent.Agent{})
+		r.Do(services.Returns(
+			http.StatusOK,
+			http.StatusNotFound))
+		r.Do(services.ReturnsE(http.StatusBadRequest))
+		ws.Route(r)
+	}
 
 	r = ws.DELETE(fmt.Sprintf("{%s}", ParamId)).To(s.TakeAgent(s.delete))
 	// docs
@@ -121,13 +120,19 @@ func (s *AgentService) Register(container *restful.Container) {
 	addDefaults(r)
 	r.Doc("jobs")
 	r.Operation("jobs")
-	r.Notes("Get jobs for the agent")
+	r.Notes("Get jobs for the agent. Sessions are given to the least loaded agent which asked for jobs recently, " +
+		"images requested by prepull are sent with the pull job")
 	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.QueryParameter("running", "sessions which are handled by the agent").DataType("integer"))
+	r.Param(ws.QueryParameter("queued", "sessions which wait for a free slot on the agent").DataType("integer"))
+	r.Param(ws.QueryParameter("capacity", "max parallel sessions, 0 is unlimited").DataType("integer"))
 	//	r.Param(ws.HeaderParameter("X-Client-Timeout", "time for request to wait"))
 	r.Do(services.Returns(http.StatusOK))
 	r.Do(services.ReturnsE(http.StatusBadRequest))
 	ws.Route(r)
 
+	s.RegisterImages(ws)
+
 	container.Add(ws)
 }
 
@@ -143,7 +148,7 @@ func (s *AgentService) create(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	raw.Type = agent.System
@@ -179,7 +184,7 @@ func (s *AgentService) list(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	results, count, err := mgr.Agents.FilterByQuery(query)
@@ -205,28 +210,28 @@ func (s *AgentService) update(req *restful.Request, resp *restful.Response, pl *
 
 	raw := &agent.Agent{}
 
-	if err := req.ReadEntity(raw); err != nil {
+	mask, err := services.ReadMasked(req, raw)
+	if err != nil {
 		logrus.Error(stackerr.Wrap(err))
 		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
 		return
 	}
-	mgr := s.Manager()
-	defer mgr.Close()
 
-	raw.Id = pl.Id
+	// id and dates are never taken from the body
+	mask.Apply(pl, raw, "name", "status", "type")
 
-	if err := s.updateAgent(resp, raw); err != nil {
+	if err := s.updateAgent(resp, pl); err != nil {
 		return
 	}
 
 	resp.WriteHeader(http.StatusOK)
-	resp.WriteEntity(raw)
+	resp.WriteEntity(pl)
 }
 
-func (s *AgentService) delete(_ *restful.Request, resp *restful.Response, obj *agent.Agent) {
+func (s *AgentService) delete(req *restful.Request, resp *restful.Response, obj *agent.Agent) {
 	// TODO (m0sth8): Check permissions
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	mgr.Agents.Remove(obj)
@@ -243,10 +248,34 @@ func (s *AgentService) approve(_ *restful.Request, resp *restful.Response, ag *a
 	resp.WriteEntity(ag)
 }
 
-func (s *AgentService) jobs(_ *restful.Request, resp *restful.Response, _ *agent.Agent) {
+func (s *AgentService) jobs(req *restful.Request, resp *restful.Response, ag *agent.Agent) {
 	jobs := []*agent.Job{}
 
-	sess, err := s.Scheduler().GetSession()
+	load := agent.Load{}
+	for name, val := range map[string]*int{"running": &load.Running, "queued": &load.Queued, "capacity": &load.Capacity} {
+		if p := req.QueryParameter(name); p != "" {
+			n, err := strconv.Atoi(p)
+			if err != nil || n < 0 {
+				resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("%s should be a non-negative integer", name))
+				return
+			}
+			*val = n
+		}
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	// images are pulled in background, so they are sent with scans
+	images, err := mgr.Agents.TakePull(ag.Id)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+	}
+	if len(images) > 0 {
+		jobs = append(jobs, &agent.Job{Cmd: agent.CmdPull, Images: images})
+	}
+
+	sess, err := s.Scheduler().GetSession(ag.Id, load)
 	if err != nil {
 		logrus.Error(stackerr.Wrap(err))
 		resp.WriteServiceError(http.StatusInternalServerError, services.AppErr)
@@ -254,13 +283,14 @@ func (s *AgentService) jobs(_ *restful.Request, resp *restful.Response, _ *agent
 	}
 	if sess == nil {
 		time.Sleep(2 * time.Second)
-		sess, err = s.Scheduler().GetSession()
+		sess, err = s.Scheduler().GetSession(ag.Id, load)
 
 	}
 	if sess != nil {
 		job := agent.Job{
-			Cmd:  agent.CmdScan,
-			Scan: sess,
+			Cmd:   agent.CmdScan,
+			Scan:  sess,
+			Token: sess.Token,
 		}
 		jobs = append(jobs, &job)
 	}
@@ -301,7 +331,7 @@ func (s *AgentService) TakeAgent(fn func(*restful.Request,
 			return
 		}
 
-		mgr := s.Manager()
+		mgr := s.RequestManager(req)
 		defer mgr.Close()
 
 		obj, err := mgr.Agents.GetById(mgr.ToId(id))
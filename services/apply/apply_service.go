@@ -0,0 +1,156 @@
+package apply
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/apply"
+	applyEngine "github.com/bearded-web/bearded/pkg/apply"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/validate"
+	"github.com/bearded-web/bearded/services"
+)
+
+const (
+	MimeYaml = "application/x-yaml"
+
+	// BundleMaxSize limits the size of applied bundle
+	BundleMaxSize = 4 << 20
+)
+
+type ApplyService struct {
+	*services.BaseService
+}
+
+func New(base *services.BaseService) *ApplyService {
+	return &ApplyService{
+		BaseService: base,
+	}
+}
+
+func (s *ApplyService) Register(container *restful.Container) {
+	ws := &restful.WebService{}
+	ws.Path("/api/v1/apply")
+	ws.Doc("Apply declarative bundles of projects, targets, schedules, plans and webhooks")
+	ws.Consumes(MimeYaml)
+	ws.Produces(restful.MIME_JSON)
+	ws.Filter(filters.AuthTokenFilter(s.BaseManager()))
+	ws.Filter(filters.AuthRequiredFilter(s.BaseManager()))
+
+	r := ws.POST("").To(s.apply)
+	r.Doc("apply")
+	r.Operation("apply")
+	r.Notes("Authorization required. Resources are created or updated by names, projects are matched among projects of the user")
+	r.Param(ws.QueryParameter("dryRun", "only return changes without saving them").DataType("boolean"))
+	r.Writes(applyEngine.Result{})
+	r.Do(services.Returns(http.StatusOK))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusUnauthorized,
+		http.StatusPaymentRequired,
+		http.StatusInternalServerError,
+	))
+	ws.Route(r)
+
+	r = ws.GET("state").To(s.state)
+	r.Doc("state")
+	r.Operation("state")
+	r.Notes("Authorization required. Resources of the applied bundle are compared with the bundle, changed ones are drifted and removed ones are missing")
+	r.Param(ws.QueryParameter("bundle", "name of the applied bundle").Required(true))
+	r.Writes(apply.StateReport{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusUnauthorized,
+		http.StatusConflict,
+		http.StatusInternalServerError,
+	))
+	ws.Route(r)
+
+	container.Add(ws)
+}
+
+// ====== service operations
+
+func (s *ApplyService) apply(req *restful.Request, resp *restful.Response) {
+	data, err := ioutil.ReadAll(http.MaxBytesReader(resp.ResponseWriter, req.Request.Body, BundleMaxSize))
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("bundle is too big or broken"))
+		return
+	}
+	b, err := apply.ParseBundle(data)
+	if err != nil {
+		if _, ok := err.(validate.Errors); ok {
+			services.NewValidationErr(err).Write(resp)
+			return
+		}
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
+
+	u := filters.GetUser(req)
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	result, err := applyEngine.Apply(mgr, u, b, req.QueryParameter("dryRun") == "true")
+	if err != nil {
+		if _, ok := err.(validate.Errors); ok {
+			services.NewValidationErr(err).Write(resp)
+			return
+		}
+		if sErr := services.QuotaErr(err); sErr != nil {
+			sErr.Write(resp)
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteEntity(result)
+}
+
+func (s *ApplyService) state(req *restful.Request, resp *restful.Response) {
+	name := req.QueryParameter("bundle")
+	if name == "" {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("bundle is required"))
+		return
+	}
+
+	u := filters.GetUser(req)
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	st, err := mgr.Applied.GetByName(u.Id, name)
+	if err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	report, err := applyEngine.Drift(mgr, u, st)
+	if err != nil {
+		// referenced plugins could be removed after the bundle is applied
+		if _, ok := err.(validate.Errors); ok {
+			resp.WriteServiceError(http.StatusConflict, services.NewBadReq("bundle couldn't be compared: %s", err))
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteEntity(report)
+}
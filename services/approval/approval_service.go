@@ -0,0 +1,265 @@
+package approval
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/approval"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/fltr"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/pkg/siem"
+	"github.com/bearded-web/bearded/pkg/validate"
+	"github.com/bearded-web/bearded/services"
+)
+
+const ParamId = "approval-id"
+
+type ApprovalService struct {
+	*services.BaseService
+}
+
+func New(base *services.BaseService) *ApprovalService {
+	return &ApprovalService{
+		BaseService: base,
+	}
+}
+
+func addDefaults(r *restful.RouteBuilder) {
+	r.Notes("Authorization required")
+	r.Do(services.ReturnsE(
+		http.StatusUnauthorized,
+		http.StatusForbidden,
+		http.StatusInternalServerError,
+	))
+}
+
+func (s *ApprovalService) Register(container *restful.Container) {
+	ws := &restful.WebService{}
+	ws.Path("/api/v1/approvals")
+	ws.Doc("Destructive actions waiting for confirmation by a second admin")
+	ws.Consumes(restful.MIME_JSON)
+	ws.Produces(restful.MIME_JSON)
+	ws.Filter(filters.AuthTokenFilter(s.BaseManager()))
+	ws.Filter(filters.AuthRequiredFilter(s.BaseManager()))
+
+	r := ws.GET("").To(s.list)
+	addDefaults(r)
+	r.Doc("list")
+	r.Operation("list")
+	r.Notes("Authorization required. Admins see all approvals, other users see only their requests")
+	s.SetParams(r, fltr.GetParams(ws, manager.ApprovalFltr{}))
+	r.Param(s.Paginator.SkipParam())
+	r.Param(s.Paginator.LimitParam())
+	r.Writes(approval.ApprovalList{})
+	r.Do(services.Returns(http.StatusOK))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.GET(fmt.Sprintf("{%s}", ParamId)).To(s.TakeApproval(s.get))
+	addDefaults(r)
+	r.Doc("get")
+	r.Operation("get")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Writes(approval.Approval{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/approve", ParamId)).To(s.TakeApproval(s.approve))
+	addDefaults(r)
+	r.Doc("approve")
+	r.Operation("approve")
+	r.Notes("Authorization required. Execute the action, only an admin who isn't the requester can approve it")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Writes(approval.Approval{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusConflict))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/reject", ParamId)).To(s.TakeApproval(s.reject))
+	addDefaults(r)
+	r.Doc("reject")
+	r.Operation("reject")
+	r.Notes("Authorization required. Admins can reject actions, requesters can cancel their own ones")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Reads(RejectEntity{})
+	r.Writes(approval.Approval{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	container.Add(ws)
+}
+
+// ====== service operations
+
+func (s *ApprovalService) list(req *restful.Request, resp *restful.Response) {
+	query, err := fltr.FromRequest(req, manager.ApprovalFltr{})
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if u := filters.GetUser(req); !mgr.Permission.IsAdmin(u) {
+		query["requester"] = u.Id
+	}
+	if err := mgr.Approvals.ExpireAll(); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	skip, limit := s.Paginator.Parse(req)
+	results, count, err := mgr.Approvals.FilterByQuery(query,
+		manager.Opts{Sort: []string{"-created"}, Skip: skip, Limit: limit})
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	previous, next := s.Paginator.Urls(req, skip, limit, count)
+	resp.WriteEntity(&approval.ApprovalList{
+		Meta:    pagination.Meta{Count: count, Previous: previous, Next: next},
+		Results: results,
+	})
+}
+
+func (s *ApprovalService) get(_ *restful.Request, resp *restful.Response, obj *approval.Approval) {
+	resp.WriteEntity(obj)
+}
+
+func (s *ApprovalService) approve(req *restful.Request, resp *restful.Response, obj *approval.Approval) {
+	u := filters.GetUser(req)
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if !mgr.Permission.IsAdmin(u) || obj.Requester == u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	if sErr := checkPending(obj); sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+	if err := mgr.Approvals.Approve(obj, u.Id); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("approval is already resolved"))
+			return
+		}
+		if sErr := services.ChildrenErr(err); sErr != nil {
+			sErr.Write(resp)
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	mgr.Cfg.Siem.Emit(siem.AdminEvent(u.Email, filters.ClientIp(req.Request),
+		fmt.Sprintf("approve %s %s", obj.Action, obj.Id.Hex()), http.StatusOK))
+	resp.WriteEntity(obj)
+}
+
+func (s *ApprovalService) reject(req *restful.Request, resp *restful.Response, obj *approval.Approval) {
+	raw := &RejectEntity{}
+	if req.Request.ContentLength != 0 {
+		if err := req.ReadEntity(raw); err != nil {
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+			return
+		}
+	}
+	if err := validate.Entity(raw); err != nil {
+		services.NewValidationErr(err).Write(resp)
+		return
+	}
+	u := filters.GetUser(req)
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if !mgr.Permission.IsAdmin(u) && obj.Requester != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	if sErr := checkPending(obj); sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+	obj.Reason = raw.Reason
+	if err := mgr.Approvals.Reject(obj, u.Id); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("approval is already resolved"))
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	// requesters could cancel their own actions, only rejections by admins are streamed
+	if obj.Requester != u.Id {
+		mgr.Cfg.Siem.Emit(siem.AdminEvent(u.Email, filters.ClientIp(req.Request),
+			fmt.Sprintf("reject %s %s", obj.Action, obj.Id.Hex()), http.StatusOK))
+	}
+	resp.WriteEntity(obj)
+}
+
+// Helpers
+
+func checkPending(obj *approval.Approval) *services.ErrResp {
+	if !obj.IsPending(time.Now().UTC()) {
+		return &services.ErrResp{
+			Code: http.StatusBadRequest,
+			Err:  services.NewBadReq("only pending approvals could be resolved, this one is %s", obj.Status),
+		}
+	}
+	return nil
+}
+
+func (s *ApprovalService) TakeApproval(fn func(*restful.Request,
+	*restful.Response, *approval.Approval)) restful.RouteFunction {
+	return func(req *restful.Request, resp *restful.Response) {
+		id := req.PathParameter(ParamId)
+		if !s.IsId(id) {
+			resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+			return
+		}
+
+		mgr := s.RequestManager(req)
+		defer mgr.Close()
+
+		obj, err := mgr.Approvals.GetById(mgr.ToId(id))
+		if err != nil {
+			if mgr.IsNotFound(err) {
+				resp.WriteErrorString(http.StatusNotFound, "Not found")
+				return
+			}
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+		if u := filters.GetUser(req); !mgr.Permission.IsAdmin(u) && obj.Requester != u.Id {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		mgr.Close()
+		fn(req, resp, obj)
+	}
+}
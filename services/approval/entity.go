@@ -0,0 +1,5 @@
+package approval
+
+type RejectEntity struct {
+	Reason string `json:"reason,omitempty" description:"max 1000 symbols" validate:"max=1000"`
+}
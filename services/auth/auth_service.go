@@ -3,17 +3,21 @@ package auth
 import (
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/Sirupsen/logrus"
-	"github.com/asaskevich/govalidator"
 	"github.com/emicklei/go-restful"
 	"github.com/facebookgo/stackerr"
 
 	"github.com/bearded-web/bearded/models/user"
 	"github.com/bearded-web/bearded/pkg/email"
 	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/i18n"
+	"github.com/bearded-web/bearded/pkg/manager"
 	"github.com/bearded-web/bearded/pkg/passlib/reset"
+	"github.com/bearded-web/bearded/pkg/siem"
 	"github.com/bearded-web/bearded/pkg/validate"
 	"github.com/bearded-web/bearded/services"
 )
@@ -37,6 +41,8 @@ func addDefaults(r *restful.RouteBuilder) {
 
 func (s *AuthService) Register(container *restful.Container) {
 	authRequired := filters.AuthRequiredFilter(s.BaseManager())
+	// protect against bruteforce and mass registration
+	rateLimit := filters.RateLimitFilter(filters.NewRateLimiter(s.ApiCfg().Signup.RateLimit, time.Minute))
 
 	ws := &restful.WebService{}
 	ws.Path("/api/v1/auth")
@@ -48,8 +54,12 @@ func (s *AuthService) Register(container *restful.Container) {
 	r.Doc("login")
 	r.Operation("login")
 	r.Reads(authEntity{})
+	r.Filter(rateLimit)
 	r.Returns(http.StatusCreated, "Session created", sessionEntity{})
-	r.Do(services.ReturnsE(http.StatusBadRequest))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden,
+		http.StatusTooManyRequests))
 	addDefaults(r)
 	ws.Route(r)
 
@@ -75,8 +85,11 @@ func (s *AuthService) Register(container *restful.Container) {
 	r.Doc("reset user password")
 	r.Operation("resetPassword")
 	r.Reads(resetPasswordEntity{})
+	r.Filter(rateLimit)
 	r.Returns(http.StatusCreated, "Token created", "")
-	r.Do(services.ReturnsE(http.StatusBadRequest))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusTooManyRequests))
 	addDefaults(r)
 	ws.Route(r)
 
@@ -94,8 +107,23 @@ func (s *AuthService) Register(container *restful.Container) {
 	r.Doc("register")
 	r.Operation("register")
 	r.Reads(registerEntity{})
+	r.Filter(rateLimit)
+	r.Notes("Session is created only if user is active, otherwise the status is returned")
 	r.Returns(http.StatusCreated, "User registered", sessionEntity{})
-	r.Do(services.ReturnsE(http.StatusBadRequest))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden,
+		http.StatusConflict,
+		http.StatusTooManyRequests))
+	addDefaults(r)
+	ws.Route(r)
+
+	r = ws.GET("verify").To(s.verifyEmail)
+	r.Param(ws.QueryParameter("token", "token for email verification"))
+	r.Doc("verify email")
+	r.Operation("verifyEmail")
+	r.Filter(rateLimit)
+	r.Returns(http.StatusTemporaryRedirect, "Status", "")
 	addDefaults(r)
 	ws.Route(r)
 
@@ -123,7 +151,7 @@ func (s *AuthService) login(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	// get user
@@ -131,7 +159,7 @@ func (s *AuthService) login(req *restful.Request, resp *restful.Response) {
 	if err != nil {
 		if mgr.IsNotFound(err) {
 			// TODO (m0sth8): add captcha to protect against bruteforce
-			resp.WriteServiceError(http.StatusUnauthorized, services.AuthFailedErr)
+			loginFailed(req, resp, mgr, raw.Email, "unknown email")
 			return
 		}
 		logrus.Error(stackerr.Wrap(err))
@@ -140,7 +168,7 @@ func (s *AuthService) login(req *restful.Request, resp *restful.Response) {
 	}
 	// users without password can't login
 	if u.Password == "" {
-		resp.WriteServiceError(http.StatusUnauthorized, services.AuthFailedErr)
+		loginFailed(req, resp, mgr, raw.Email, "password isn't set")
 		return
 	}
 
@@ -152,7 +180,11 @@ func (s *AuthService) login(req *restful.Request, resp *restful.Response) {
 		return
 	}
 	if !verified {
-		resp.WriteServiceError(http.StatusUnauthorized, services.AuthFailedErr)
+		loginFailed(req, resp, mgr, raw.Email, "wrong password")
+		return
+	}
+	if !u.IsActive() {
+		resp.WriteServiceError(http.StatusForbidden, statusErr(u.Status))
 		return
 	}
 
@@ -175,6 +207,12 @@ func (s *AuthService) logout(req *restful.Request, resp *restful.Response) {
 
 func (s *AuthService) register(req *restful.Request, resp *restful.Response) {
 	session := filters.GetSession(req)
+	cfg := s.ApiCfg()
+
+	if cfg.Signup.Disable {
+		resp.WriteServiceError(http.StatusForbidden, services.NewError(services.CodeAuthForbid, "signup is disabled"))
+		return
+	}
 
 	raw := &registerEntity{}
 
@@ -186,18 +224,17 @@ func (s *AuthService) register(req *restful.Request, resp *restful.Response) {
 
 	// TODO (m0sth8): add captcha support
 
-	// check email
-	if valid, err := govalidator.ValidateStruct(raw); !valid {
-		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+	// check email and password
+	if err := validate.Entity(raw); err != nil {
+		services.NewValidationErr(err).Write(resp)
 		return
 	}
-	// check password
-	if valid, reason := validate.Password(raw.Password); !valid {
-		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Password %s", reason))
+	if !domainAllowed(raw.Email, cfg.Signup.Domains) {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("signup isn't allowed for this email domain"))
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	pass, err := s.PassCtx().Encrypt(raw.Password)
@@ -210,6 +247,16 @@ func (s *AuthService) register(req *restful.Request, resp *restful.Response) {
 	u := &user.User{
 		Email:    raw.Email,
 		Password: pass,
+		Status:   user.StatusActive,
+	}
+	// the locale of the browser is kept for emails until the user changes it
+	if locale := filters.Locale(req, s.I18n); locale != i18n.Fallback {
+		u.Locale = locale
+	}
+	if cfg.Signup.Verify {
+		u.Status = user.StatusUnverified
+	} else if cfg.Signup.Approve {
+		u.Status = user.StatusPending
 	}
 
 	u, err = mgr.Users.Create(u)
@@ -225,9 +272,125 @@ func (s *AuthService) register(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	session.Set(filters.SessionUserKey, u.Id.Hex())
+	if u.Status == user.StatusUnverified {
+		s.sendVerification(u, req.Request.URL)
+	}
+	if u.IsActive() {
+		session.Set(filters.SessionUserKey, u.Id.Hex())
+	}
 	resp.WriteHeader(http.StatusCreated)
-	resp.WriteEntity(sessionEntity{Token: "not ready"})
+	resp.WriteEntity(sessionEntity{Token: "not ready", Status: u.Status})
+}
+
+// verification token is signed by user status, so it can't be used twice
+func verifySecret(u *user.User) []byte {
+	return []byte(string(u.Status) + u.Password)
+}
+
+func (s *AuthService) sendVerification(u *user.User, reqUrl *url.URL) {
+	// TODO (m0sth8): send email in worker
+	go func() {
+		cfg := s.ApiCfg()
+		dur := time.Second * time.Duration(cfg.Signup.VerifyDuration)
+		token := reset.NewToken(u.Email, dur, verifySecret(u), []byte(cfg.ResetPasswordSecret))
+		verifyUrl := &url.URL{
+			Path:     strings.TrimSuffix(reqUrl.Path, "register") + "verify",
+			RawQuery: url.Values{"token": []string{token}}.Encode(),
+		}
+		msg := email.NewMessage()
+		msg.SetHeader("From", msg.FormatAddress(cfg.SystemEmail, "Bearded"))
+		msg.SetHeader("To", msg.FormatAddress(u.Email, u.Nickname))
+		msg.SetHeader("Subject", s.I18n.Sprintf(u.Locale, "Verify email in bearded-web service"))
+		wr := msg.GetBodyWriter("text/html")
+		data := map[string]string{
+			"ReqUrl":       fmt.Sprintf("%s%s", cfg.Host, verifyUrl.String()),
+			"Nickname":     u.Nickname,
+			"SystemEmail":  cfg.SystemEmail,
+			"ContactEmail": cfg.ContactEmail,
+			"Locale":       u.Locale,
+		}
+		if err := s.Template.Render(wr, "email/verify-email", data); err != nil {
+			logrus.Error(err)
+			return
+		}
+		if err := s.Mailer().Send(msg); err != nil {
+			logrus.Error(err)
+			return
+		}
+	}()
+}
+
+func (s *AuthService) verifyEmail(req *restful.Request, resp *restful.Response) {
+	token := req.QueryParameter("token")
+
+	redirect := func(query string) {
+		http.Redirect(resp.ResponseWriter, req.Request, fmt.Sprintf("/#/verify-end?%s", query), http.StatusTemporaryRedirect)
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	var u *user.User
+	getUser := func(email string) ([]byte, error) {
+		var err error
+		u, err = mgr.Users.GetByEmail(email)
+		if err != nil {
+			return nil, err
+		}
+		return verifySecret(u), nil
+	}
+
+	cfg := s.ApiCfg()
+	if _, err := reset.VerifyToken(token, getUser, []byte(cfg.ResetPasswordSecret)); err != nil {
+		if err == reset.ErrExpiredToken {
+			redirect("error=Token+expired")
+			return
+		}
+		redirect("error=Wrong+token")
+		return
+	}
+	if u == nil || u.Status != user.StatusUnverified {
+		redirect("error=Wrong+token")
+		return
+	}
+	u.Status = user.StatusActive
+	if cfg.Signup.Approve {
+		u.Status = user.StatusPending
+	}
+	if err := mgr.Users.Update(u); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	if u.IsActive() {
+		session := filters.GetSession(req)
+		session.Set(filters.SessionUserKey, u.Id.Hex())
+	}
+	redirect(url.Values{"status": []string{string(u.Status)}}.Encode())
+}
+
+func domainAllowed(email string, domains []string) bool {
+	if len(domains) == 0 {
+		return true
+	}
+	parts := strings.Split(email, "@")
+	domain := strings.ToLower(parts[len(parts)-1])
+	for _, d := range domains {
+		if strings.ToLower(d) == domain {
+			return true
+		}
+	}
+	return false
+}
+
+func statusErr(status user.Status) restful.ServiceError {
+	switch status {
+	case user.StatusUnverified:
+		return services.NewError(services.CodeAuthState, "email isn't verified")
+	case user.StatusPending:
+		return services.NewError(services.CodeAuthState, "user is waiting for approval")
+	}
+	return services.NewError(services.CodeAuthState, "user isn't active")
 }
 
 func (s *AuthService) resetPassword(req *restful.Request, resp *restful.Response) {
@@ -240,12 +403,12 @@ func (s *AuthService) resetPassword(req *restful.Request, resp *restful.Response
 		return
 	}
 
-	if ok, err := govalidator.ValidateStruct(raw); !ok {
-		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+	if err := validate.Entity(raw); err != nil {
+		services.NewValidationErr(err).Write(resp)
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	// TODO (m0sth8): add captcha support
@@ -261,6 +424,10 @@ func (s *AuthService) resetPassword(req *restful.Request, resp *restful.Response
 	}
 
 	reqUrl := req.Request.URL
+	locale := u.Locale
+	if locale == "" {
+		locale = filters.Locale(req, s.I18n)
+	}
 	// TODO (m0sth8): send email in worker
 	go func() {
 		cfg := s.ApiCfg()
@@ -269,7 +436,7 @@ func (s *AuthService) resetPassword(req *restful.Request, resp *restful.Response
 		msg := email.NewMessage()
 		msg.SetHeader("From", msg.FormatAddress(cfg.SystemEmail, "Bearded"))
 		msg.SetHeader("To", msg.FormatAddress(u.Email, u.Nickname))
-		msg.SetHeader("Subject", "Reset password in bearded-web service")
+		msg.SetHeader("Subject", s.I18n.Sprintf(locale, "Reset password in bearded-web service"))
 		reqUrlVal := reqUrl.Query()
 		reqUrlVal.Add("token", token)
 		reqUrl.RawQuery = reqUrlVal.Encode()
@@ -279,6 +446,7 @@ func (s *AuthService) resetPassword(req *restful.Request, resp *restful.Response
 			"Nickname":     u.Nickname,
 			"SystemEmail":  cfg.SystemEmail,
 			"ContactEmail": cfg.ContactEmail,
+			"Locale":       locale,
 		}
 		if err := s.Template.Render(wr, "email/reset-password", data); err != nil {
 			logrus.Error(err)
@@ -297,7 +465,7 @@ func (s *AuthService) resetPassword(req *restful.Request, resp *restful.Response
 func (s *AuthService) checkResetToken(req *restful.Request, resp *restful.Response) {
 	token := req.QueryParameter("token")
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	// TODO (m0sth8: take variables from config
@@ -333,9 +501,19 @@ func (s *AuthService) checkResetToken(req *restful.Request, resp *restful.Respon
 		resp.WriteServiceError(http.StatusInternalServerError, services.AppErr)
 		return
 	}
+	if !u.IsActive() {
+		redirectErr(req, resp, "User isn't active")
+		return
+	}
 	// is that a good way to login user here?
 	session := filters.GetSession(req)
 	session.Set(filters.SessionUserKey, u.Id.Hex())
 
 	redirect(req, resp, token)
 }
+
+// loginFailed writes 401 and streams the failure to siem
+func loginFailed(req *restful.Request, resp *restful.Response, mgr *manager.Manager, email, reason string) {
+	mgr.Cfg.Siem.Emit(siem.LoginFailedEvent(email, filters.ClientIp(req.Request), reason))
+	resp.WriteServiceError(http.StatusUnauthorized, services.AuthFailedErr)
+}
@@ -18,8 +18,10 @@ import (
 	"github.com/bearded-web/bearded/models/user"
 	"github.com/bearded-web/bearded/pkg/config"
 	"github.com/bearded-web/bearded/pkg/email"
+	"github.com/bearded-web/bearded/pkg/filters"
 	"github.com/bearded-web/bearded/pkg/manager"
 	"github.com/bearded-web/bearded/pkg/passlib"
+	"github.com/bearded-web/bearded/pkg/passlib/reset"
 	"github.com/bearded-web/bearded/pkg/scheduler"
 	"github.com/bearded-web/bearded/pkg/template"
 	"github.com/bearded-web/bearded/pkg/tests"
@@ -65,7 +67,7 @@ func TestResetPassword(t *testing.T) {
 				c.So(resp.StatusCode, c.ShouldEqual, http.StatusBadRequest)
 				sErr := getServiceError(t, resp)
 				c.So(sErr.Code, c.ShouldEqual, services.CodeWrongData)
-				c.So(sErr.Message, c.ShouldContainSubstring, "non zero value required")
+				c.So(sErr.Message, c.ShouldContainSubstring, "email: shouldn't be empty")
 			})
 		})
 		c.Convey("Send invalid email", func() {
@@ -75,7 +77,7 @@ func TestResetPassword(t *testing.T) {
 				c.So(resp.StatusCode, c.ShouldEqual, http.StatusBadRequest)
 				sErr := getServiceError(t, resp)
 				c.So(sErr.Code, c.ShouldEqual, services.CodeWrongData)
-				c.So(sErr.Message, c.ShouldContainSubstring, "does not validate as email")
+				c.So(sErr.Message, c.ShouldContainSubstring, "email: should be a valid email")
 
 			})
 		})
@@ -137,3 +139,101 @@ func getServiceError(t *testing.T, res *http.Response) *restful.ServiceError {
 	}
 	return e
 }
+
+func TestRegister(t *testing.T) {
+	mongo, dbName, err := tests.RandomTestMongoUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tests.RandomTestMongoDown(mongo, dbName)
+
+	mgr := manager.New(mongo.DB(dbName))
+	cfg := config.NewDispatcher().Api
+	cfg.Signup.Verify = true
+	cfg.Signup.Approve = true
+	cfg.Signup.Domains = []string{"allowed.ru"}
+
+	emailBackend := email.NewMemoryBackend(100)
+	service := New(services.New(mgr, passlib.NewContext(), scheduler.NewFake(), emailBackend, cfg))
+	service.Template = template.New(&template.Opts{Directory: "testdata/templates"})
+	wsContainer := restful.NewContainer()
+	wsContainer.Router(restful.CurlyRouter{})
+	session := filters.NewSession()
+	wsContainer.Filter(filters.SessionFilterMock(session))
+	service.Register(wsContainer)
+
+	ts := httptest.NewServer(wsContainer)
+	defer ts.Close()
+
+	c.Convey("Given signup with verification and approval", t, func() {
+		c.Convey("Register with not allowed domain", func() {
+			resp, err := register(ts.URL, &registerEntity{Email: "user@other.ru", Password: "Password123"})
+			c.So(err, c.ShouldBeNil)
+			c.So(resp.StatusCode, c.ShouldEqual, http.StatusBadRequest)
+		})
+		c.Convey("Register with allowed domain", func() {
+			resp, err := register(ts.URL, &registerEntity{Email: "user@allowed.ru", Password: "Password123"})
+			c.So(err, c.ShouldBeNil)
+			c.So(resp.StatusCode, c.ShouldEqual, http.StatusCreated)
+			ent := &sessionEntity{}
+			c.So(json.NewDecoder(resp.Body).Decode(ent), c.ShouldBeNil)
+			c.So(ent.Status, c.ShouldEqual, user.StatusUnverified)
+
+			select {
+			case msg := <-emailBackend.Messages():
+				body, err := ioutil.ReadAll(msg.Export().Body)
+				require.NoError(t, err)
+				c.So(string(body), c.ShouldEqual, "Verify email")
+			case <-time.After(time.Second * 1):
+				t.Fatal("Timeout exceeded")
+			}
+
+			u, err := mgr.Users.GetByEmail("user@allowed.ru")
+			c.So(err, c.ShouldBeNil)
+			c.So(u.IsActive(), c.ShouldBeFalse)
+			// inactive users aren't logged in
+			_, logged := session.Get(filters.SessionUserKey)
+			c.So(logged, c.ShouldBeFalse)
+
+			// wrong token isn't accepted
+			resp, err = verify(ts.URL, "bad")
+			c.So(err, c.ShouldBeNil)
+			c.So(resp.StatusCode, c.ShouldEqual, http.StatusTemporaryRedirect)
+			c.So(resp.Header.Get("Location"), c.ShouldEqual, "/#/verify-end?error=Wrong+token")
+
+			token := reset.NewToken(u.Email, time.Minute, verifySecret(u), []byte(cfg.ResetPasswordSecret))
+			resp, err = verify(ts.URL, token)
+			c.So(err, c.ShouldBeNil)
+			c.So(resp.StatusCode, c.ShouldEqual, http.StatusTemporaryRedirect)
+			c.So(resp.Header.Get("Location"), c.ShouldEqual, "/#/verify-end?status=pending")
+			u, err = mgr.Users.GetByEmail("user@allowed.ru")
+			c.So(err, c.ShouldBeNil)
+			c.So(u.Status, c.ShouldEqual, user.StatusPending)
+			// pending users wait for approval without session
+			_, logged = session.Get(filters.SessionUserKey)
+			c.So(logged, c.ShouldBeFalse)
+
+			// token is signed by the status, so it can't be used twice
+			resp, err = verify(ts.URL, token)
+			c.So(err, c.ShouldBeNil)
+			c.So(resp.Header.Get("Location"), c.ShouldEqual, "/#/verify-end?error=Wrong+token")
+		})
+	})
+}
+
+func register(baseUrl string, entity *registerEntity) (*http.Response, error) {
+	buf := bytes.NewBuffer(nil)
+	if err := json.NewEncoder(buf).Encode(entity); err != nil {
+		return nil, err
+	}
+	req, _ := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/auth/register", baseUrl), buf)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	return http.DefaultClient.Do(req)
+}
+
+// verify doesn't follow the redirect to the frontend
+func verify(baseUrl, token string) (*http.Response, error) {
+	req, _ := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/auth/verify?token=%s", baseUrl, url.QueryEscape(token)), nil)
+	return http.DefaultTransport.RoundTrip(req)
+}
@@ -1,12 +1,15 @@
 package auth
 
+import "github.com/bearded-web/bearded/models/user"
+
 type authEntity struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
 type sessionEntity struct {
-	Token string `json:"token" description:"isn't implemented yet"`
+	Token  string      `json:"token" description:"isn't implemented yet"`
+	Status user.Status `json:"status,omitempty"`
 }
 
 type passwordEntity struct {
@@ -14,10 +17,10 @@ type passwordEntity struct {
 }
 
 type registerEntity struct {
-	Email    string `json:"email" valid:"email,required"`
-	Password string `json:"password" valid:",required"`
+	Email    string `json:"email" validate:"nonzero,email"`
+	Password string `json:"password" validate:"password"`
 }
 
 type resetPasswordEntity struct {
-	Email string `json:"email" valid:"email,required"`
+	Email string `json:"email" validate:"nonzero,email"`
 }
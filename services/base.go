@@ -3,7 +3,9 @@ package services
 import (
 	"github.com/bearded-web/bearded/pkg/config"
 	"github.com/bearded-web/bearded/pkg/email"
+	"github.com/bearded-web/bearded/pkg/i18n"
 	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/notify"
 	"github.com/bearded-web/bearded/pkg/pagination"
 	"github.com/bearded-web/bearded/pkg/passlib"
 	"github.com/bearded-web/bearded/pkg/scheduler"
@@ -11,6 +13,9 @@ import (
 	"github.com/emicklei/go-restful"
 )
 
+// AttrManagerKey is a restful attribute with the manager of the request, it's set by filters.ManagerFilter
+const AttrManagerKey = "__manager"
+
 type BaseService struct {
 	manager   *manager.Manager
 	passCtx   *passlib.Context
@@ -19,6 +24,8 @@ type BaseService struct {
 	apiCfg    config.Api
 	Template  template.Renderer
 	Paginator *pagination.Paginator
+	Notifier  *notify.Dispatcher // could be nil, notifications aren't sent then
+	I18n      *i18n.Bundle       // could be nil, english is used then
 }
 
 func New(mgr *manager.Manager, passCtx *passlib.Context,
@@ -39,6 +46,21 @@ func (s *BaseService) Manager() *manager.Manager {
 	return s.manager.Copy()
 }
 
+// RequestManager returns the manager shared by all filters and handlers of the request.
+// Closing of it doesn't do anything, the session is closed when the request is finished.
+// A copy is returned if the request has no manager, so don't forget to close it anyway.
+func (s *BaseService) RequestManager(req *restful.Request) *manager.Manager {
+	return RequestManager(req, s.manager)
+}
+
+// RequestManager returns the manager of the request or a copy of mgr if the request has no manager
+func RequestManager(req *restful.Request, mgr *manager.Manager) *manager.Manager {
+	if reqMgr, ok := req.Attribute(AttrManagerKey).(*manager.Manager); ok {
+		return reqMgr
+	}
+	return mgr.Copy()
+}
+
 // Get the original manager, don't close it!
 func (s *BaseService) BaseManager() *manager.Manager {
 	return s.manager
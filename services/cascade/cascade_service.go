@@ -0,0 +1,132 @@
+package cascade
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/cascade"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/fltr"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/services"
+)
+
+const ParamId = "cascade-id"
+
+type CascadeService struct {
+	*services.BaseService
+}
+
+func New(base *services.BaseService) *CascadeService {
+	return &CascadeService{
+		BaseService: base,
+	}
+}
+
+func addDefaults(r *restful.RouteBuilder) {
+	r.Notes("Authorization required")
+	r.Do(services.ReturnsE(
+		http.StatusUnauthorized,
+		http.StatusInternalServerError,
+	))
+}
+
+func (s *CascadeService) Register(container *restful.Container) {
+	ws := &restful.WebService{}
+	ws.Path("/api/v1/cascades")
+	ws.Doc("Background removal of issues and scans of removed targets and projects")
+	ws.Consumes(restful.MIME_JSON)
+	ws.Produces(restful.MIME_JSON)
+	ws.Filter(filters.AuthTokenFilter(s.BaseManager()))
+	ws.Filter(filters.AuthRequiredFilter(s.BaseManager()))
+
+	r := ws.GET("").To(s.list)
+	addDefaults(r)
+	r.Doc("list")
+	r.Operation("list")
+	r.Notes("Authorization required. Admins see all jobs, other users see only jobs of their deletions")
+	s.SetParams(r, fltr.GetParams(ws, manager.CascadeFltr{}))
+	r.Param(s.Paginator.SkipParam())
+	r.Param(s.Paginator.LimitParam())
+	r.Writes(cascade.JobList{})
+	r.Do(services.Returns(http.StatusOK))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.GET(fmt.Sprintf("{%s}", ParamId)).To(s.get)
+	addDefaults(r)
+	r.Doc("get")
+	r.Operation("get")
+	r.Notes("Authorization required. Progress of the job is done of total children")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Writes(cascade.Job{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	container.Add(ws)
+}
+
+// ====== service operations
+
+func (s *CascadeService) list(req *restful.Request, resp *restful.Response) {
+	query, err := fltr.FromRequest(req, manager.CascadeFltr{})
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("%s", err.Error()))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if u := filters.GetUser(req); !mgr.Permission.IsAdmin(u) {
+		query["user"] = u.Id
+	}
+	skip, limit := s.Paginator.Parse(req)
+	results, count, err := mgr.Cascades.FilterByQuery(query,
+		manager.Opts{Sort: []string{"-created"}, Skip: skip, Limit: limit})
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	previous, next := s.Paginator.Urls(req, skip, limit, count)
+	resp.WriteEntity(&cascade.JobList{
+		Meta:    pagination.Meta{Count: count, Previous: previous, Next: next},
+		Results: results,
+	})
+}
+
+func (s *CascadeService) get(req *restful.Request, resp *restful.Response) {
+	id := req.PathParameter(ParamId)
+	if !s.IsId(id) {
+		resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	obj, err := mgr.Cascades.GetById(mgr.ToId(id))
+	if err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	if u := filters.GetUser(req); !mgr.Permission.IsAdmin(u) && obj.User != u.Id {
+		resp.WriteErrorString(http.StatusNotFound, "Not found")
+		return
+	}
+	resp.WriteEntity(obj)
+}
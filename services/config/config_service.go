@@ -5,6 +5,7 @@ import (
 
 	"github.com/emicklei/go-restful"
 
+	"github.com/bearded-web/bearded/pkg/i18n"
 	"github.com/bearded-web/bearded/services"
 )
 
@@ -34,6 +35,22 @@ func (s *ConfigService) Register(container *restful.Container) {
 	ws.Route(r)
 
 	container.Add(ws)
+
+	// the same config on the root, so frontend could load it before the api prefix is known
+	root := &restful.WebService{}
+	root.Path("/config.json")
+	root.Doc("Configuration options for frontend")
+	root.Produces(restful.MIME_JSON)
+
+	r = root.GET("").To(s.get)
+	r.Doc("getJson")
+	r.Operation("getJson")
+	r.Writes(ConfigEntity{})
+	r.Do(services.Returns(
+		http.StatusOK))
+	root.Route(r)
+
+	container.Add(root)
 }
 
 // ====== service operations
@@ -43,7 +60,10 @@ func (s *ConfigService) get(_ *restful.Request, resp *restful.Response) {
 	ent := &ConfigEntity{
 		Signup: Signup{
 			Disable: cfg.Signup.Disable,
+			Verify:  cfg.Signup.Verify,
+			Approve: cfg.Signup.Approve,
 		},
+		Locales: append([]string{i18n.Fallback}, s.I18n.Locales()...),
 	}
 	if cfg.Raven != "" {
 		ent.Raven.Enable = true
@@ -12,10 +12,14 @@ type GA struct {
 
 type Signup struct {
 	Disable bool `json:"disable"`
+	Verify  bool `json:"verify"`
+	Approve bool `json:"approve"`
 }
 
 type ConfigEntity struct {
 	Raven  Raven  `json:"raven"`
 	GA     GA     `json:"ga"`
 	Signup Signup `json:"signup"`
+
+	Locales []string `json:"locales" description:"supported locales of messages, english is always supported"`
 }
@@ -0,0 +1,18 @@
+package enum
+
+import "github.com/bearded-web/bearded/pkg/pagination"
+
+type ValueEntity struct {
+	Name  string `json:"name"`
+	Value int    `json:"value" description:"rank for ordered enums like issue.severity, position in the enum otherwise"`
+}
+
+type EnumEntity struct {
+	Name   string         `json:"name" description:"model and field, e.g. issue.severity"`
+	Values []*ValueEntity `json:"values"`
+}
+
+type EnumList struct {
+	pagination.Meta `json:",inline"`
+	Results         []*EnumEntity `json:"results"`
+}
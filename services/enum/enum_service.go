@@ -0,0 +1,149 @@
+package enum
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/emicklei/go-restful"
+
+	"github.com/bearded-web/bearded/models/agent"
+	"github.com/bearded-web/bearded/models/approval"
+	"github.com/bearded-web/bearded/models/cascade"
+	"github.com/bearded-web/bearded/models/discovery"
+	"github.com/bearded-web/bearded/models/feed"
+	"github.com/bearded-web/bearded/models/integrity"
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/notification"
+	"github.com/bearded-web/bearded/models/plugin"
+	"github.com/bearded-web/bearded/models/report"
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/models/tech"
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/services"
+)
+
+const ParamName = "enum-name"
+
+// enum values are taken from models, so they are always the same as the server uses
+var enums = []struct {
+	name   string
+	values []interface{}
+}{
+	{"agent.status", agent.Status("").Enum()},
+	{"agent.type", agent.Type("").Enum()},
+	{"approval.action", approval.Action("").Enum()},
+	{"approval.status", approval.Status("").Enum()},
+	{"cascade.kind", cascade.Kind("").Enum()},
+	{"cascade.status", cascade.Status("").Enum()},
+	{"discovery.hostStatus", discovery.HostStatus("").Enum()},
+	{"feed.type", feed.ItemType("").Enum()},
+	{"integrity.action", integrity.Action("").Enum()},
+	{"integrity.kind", integrity.Kind("").Enum()},
+	{"issue.activity", issue.ActivityType("").Enum()},
+	{"issue.retest", issue.RetestStatus("").Enum()},
+	{"issue.severity", issue.Severity("").Enum()},
+	{"issue.status", columns()},
+	{"notification.channel", notification.Channel("").Enum()},
+	{"notification.event", notification.Event("").Enum()},
+	{"plugin.type", plugin.PluginType("").Enum()},
+	{"plugin.weight", plugin.PluginWeight("").Enum()},
+	{"report.quarantine", report.QuarantineStatus("").Enum()},
+	{"report.type", report.ReportType("").Enum()},
+	{"scan.status", scan.ScanStatus("").Enum()},
+	{"target.criticality", target.Criticality("").Enum()},
+	{"target.type", target.TargetType("").Enum()},
+	{"tech.activity", tech.ActivityType("").Enum()},
+	{"tech.status", tech.StatusType("").Enum()},
+	{"user.dateFormat", user.DateFormat("").Enum()},
+	{"user.status", user.Status("").Enum()},
+}
+
+// issue status isn't a single field, board columns are used for it
+func columns() []interface{} {
+	values := make([]interface{}, len(issue.Columns))
+	for i, col := range issue.Columns {
+		values[i] = col
+	}
+	return values
+}
+
+type ranker interface {
+	Rank() int
+}
+
+type EnumService struct {
+	*services.BaseService
+	all *EnumList
+}
+
+func New(base *services.BaseService) *EnumService {
+	return &EnumService{
+		BaseService: base,
+		all:         newList(),
+	}
+}
+
+func newList() *EnumList {
+	list := &EnumList{Results: make([]*EnumEntity, 0, len(enums))}
+	for _, e := range enums {
+		ent := &EnumEntity{Name: e.name, Values: make([]*ValueEntity, len(e.values))}
+		for i, v := range e.values {
+			value := i
+			if r, ok := v.(ranker); ok {
+				value = r.Rank()
+			}
+			ent.Values[i] = &ValueEntity{Name: fmt.Sprint(v), Value: value}
+		}
+		list.Results = append(list.Results, ent)
+	}
+	list.Meta = pagination.Meta{Count: len(list.Results)}
+	return list
+}
+
+func (s *EnumService) Register(container *restful.Container) {
+	ws := &restful.WebService{}
+	ws.Path("/api/v1/enums")
+	ws.Doc("Names and integer values of model enumerations")
+	ws.Consumes(restful.MIME_JSON)
+	ws.Produces(restful.MIME_JSON)
+
+	r := ws.GET("").To(s.list)
+	r.Doc("list")
+	r.Operation("list")
+	r.Notes("All enumerations sorted by name, values keep the server order")
+	r.Writes(EnumList{})
+	r.Do(services.Returns(
+		http.StatusOK))
+	ws.Route(r)
+
+	r = ws.GET(fmt.Sprintf("{%s}", ParamName)).To(s.get)
+	r.Doc("get")
+	r.Operation("get")
+	r.Param(ws.PathParameter(ParamName, "e.g. issue.severity"))
+	r.Writes(EnumEntity{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	ws.Route(r)
+
+	container.Add(ws)
+}
+
+// ====== service operations
+
+func (s *EnumService) list(_ *restful.Request, resp *restful.Response) {
+	resp.WriteEntity(s.all)
+}
+
+func (s *EnumService) get(req *restful.Request, resp *restful.Response) {
+	name := req.PathParameter(ParamName)
+	for _, ent := range s.all.Results {
+		if ent.Name == name {
+			resp.WriteEntity(ent)
+			return
+		}
+	}
+	resp.WriteErrorString(http.StatusNotFound, "Not found")
+}
@@ -0,0 +1,74 @@
+package enum
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emicklei/go-restful"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bearded-web/bearded/services"
+)
+
+func TestEnums(t *testing.T) {
+	service := New(&services.BaseService{})
+	wsContainer := restful.NewContainer()
+	wsContainer.Router(restful.CurlyRouter{})
+	service.Register(wsContainer)
+
+	ts := httptest.NewServer(wsContainer)
+	defer ts.Close()
+
+	get := func(path string, entity interface{}) int {
+		res, err := http.Get(ts.URL + "/api/v1/enums" + path)
+		require.NoError(t, err)
+		defer res.Body.Close()
+		if entity != nil && res.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(entity))
+		}
+		return res.StatusCode
+	}
+
+	// all enums are sorted by name
+	list := &EnumList{}
+	require.Equal(t, http.StatusOK, get("", list))
+	assert.Equal(t, len(enums), list.Count)
+	names := map[string]bool{}
+	for i, ent := range list.Results {
+		names[ent.Name] = true
+		assert.NotEmpty(t, ent.Values, ent.Name)
+		if i > 0 {
+			assert.True(t, ent.Name > list.Results[i-1].Name, ent.Name)
+		}
+	}
+	for _, name := range []string{"issue.status", "issue.severity", "scan.status", "target.type", "agent.status"} {
+		assert.True(t, names[name], name)
+	}
+
+	// ranked enums use their ranks as values
+	ent := &EnumEntity{}
+	require.Equal(t, http.StatusOK, get("/issue.severity", ent))
+	assert.Equal(t, []*ValueEntity{
+		{Name: "info", Value: 1},
+		{Name: "low", Value: 2},
+		{Name: "medium", Value: 3},
+		{Name: "high", Value: 4},
+		{Name: "error", Value: 0},
+	}, ent.Values)
+
+	ent = &EnumEntity{}
+	require.Equal(t, http.StatusOK, get("/issue.status", ent))
+	require.Len(t, ent.Values, 5)
+	assert.Equal(t, &ValueEntity{Name: "open", Value: 0}, ent.Values[0])
+	assert.Equal(t, &ValueEntity{Name: "resolved", Value: 4}, ent.Values[4])
+
+	// other enums use positions
+	ent = &EnumEntity{}
+	require.Equal(t, http.StatusOK, get("/scan.status", ent))
+	assert.Equal(t, &ValueEntity{Name: "created", Value: 0}, ent.Values[0])
+
+	assert.Equal(t, http.StatusNotFound, get("/unknown", nil))
+}
@@ -5,6 +5,8 @@ import (
 	"net/http"
 
 	"github.com/emicklei/go-restful"
+
+	"github.com/bearded-web/bearded/pkg/validate"
 )
 
 type CodeErr int
@@ -15,15 +17,22 @@ const (
 	CodeDb        CodeErr = 18
 	CodeIdHex     CodeErr = 19
 	CodeDuplicate CodeErr = 20
+	CodeChildren  CodeErr = 21 // deletion is blocked by issues or scans
 
 	// Bad Request
 	CodeWrongData   CodeErr = 40
 	CodeWrongEntity CodeErr = 41
+	CodeEngagement  CodeErr = 42 // scan breaks project rules of engagement
 
 	// error codes related to auth
 	CodeAuthReq    CodeErr = 60
 	CodeAuthFailed CodeErr = 61
 	CodeAuthForbid CodeErr = 62
+	CodeAuthState  CodeErr = 63 // user isn't active
+
+	// too many requests
+	CodeTooManyReq CodeErr = 70
+	CodeQuota      CodeErr = 71 // project quota is exceeded
 )
 
 var (
@@ -35,6 +44,7 @@ var (
 	AuthReqErr     = NewError(CodeAuthReq, "authorization required")
 	AuthFailedErr  = NewError(CodeAuthFailed, "authorization failed")
 	AuthForbidErr  = NewError(CodeAuthForbid, "you have no permission to this resource")
+	TooManyReqErr  = NewError(CodeTooManyReq, "too many requests, try again later")
 )
 
 func NewError(c CodeErr, msg string) restful.ServiceError {
@@ -49,6 +59,33 @@ func NewAppErr(msg string) restful.ServiceError {
 	return NewError(CodeApp, msg)
 }
 
+// ValidationErr is the error envelope with machine readable errors of every invalid field
+type ValidationErr struct {
+	Code    int
+	Message string
+	Fields  validate.Errors
+}
+
+// NewValidationErr converts err to field errors, errors without fields are kept as a single error
+func NewValidationErr(err error) *ValidationErr {
+	fields := validate.FromError(err)
+	return &ValidationErr{
+		Code:    int(CodeWrongData),
+		Message: fmt.Sprintf("Validation error: %s", fields.Error()),
+		Fields:  fields,
+	}
+}
+
+func (e *ValidationErr) Error() string {
+	return fmt.Sprintf("[ServiceError:%v] %v", e.Code, e.Message)
+}
+
+// Write responds with 400 status and the envelope
+func (e *ValidationErr) Write(rw *restful.Response) {
+	rw.WriteHeader(http.StatusBadRequest)
+	rw.WriteEntity(e)
+}
+
 type ErrResp struct {
 	Code int
 	Err  error
@@ -61,6 +98,9 @@ func (e *ErrResp) Write(rw *restful.Response) {
 	}
 	if sErr, casted := e.Err.(restful.ServiceError); casted {
 		rw.WriteServiceError(code, sErr)
+	} else if vErr, casted := e.Err.(*ValidationErr); casted {
+		rw.WriteHeader(code)
+		rw.WriteEntity(vErr)
 	} else {
 		rw.WriteError(code, e.Err)
 	}
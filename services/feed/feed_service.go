@@ -7,10 +7,12 @@ import (
 	"github.com/Sirupsen/logrus"
 	"github.com/emicklei/go-restful"
 	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2/bson"
 
 	"strconv"
 
 	"github.com/bearded-web/bearded/models/feed"
+	"github.com/bearded-web/bearded/models/issue"
 	"github.com/bearded-web/bearded/pkg/filters"
 	"github.com/bearded-web/bearded/pkg/fltr"
 	"github.com/bearded-web/bearded/pkg/manager"
@@ -72,6 +74,23 @@ func (s *FeedService) Register(container *restful.Container) {
 	r.Do(services.ReturnsE(http.StatusBadRequest))
 	ws.Route(r)
 
+	r = ws.GET(fmt.Sprintf("{%s}/issues", ParamId)).To(s.TakeFeed(s.issues))
+	addDefaults(r)
+	r.Doc("issues")
+	r.Operation("issues")
+	r.Notes("Issues collapsed into the feed item of type: issue or scan")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.QueryParameter("limit", "show limit").DataType("integer"))
+	r.Param(ws.QueryParameter("skip", "skip n elements").DataType("integer"))
+	r.Writes(issue.TargetIssueList{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+
 	r = ws.DELETE(fmt.Sprintf("{%s}", ParamId)).To(s.TakeFeed(s.delete))
 	addDefaults(r)
 	r.Doc("delete")
@@ -90,7 +109,7 @@ func (s *FeedService) Register(container *restful.Container) {
 
 func (s *FeedService) list(req *restful.Request, resp *restful.Response) {
 	// TODO (m0sth8): check if this user has access to feed items
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	query, err := fltr.FromRequest(req, manager.FeedItemFltr{})
@@ -135,8 +154,52 @@ func (s *FeedService) get(_ *restful.Request, resp *restful.Response, pl *feed.F
 	resp.WriteEntity(pl)
 }
 
-func (s *FeedService) delete(_ *restful.Request, resp *restful.Response, obj *feed.FeedItem) {
-	mgr := s.Manager()
+func (s *FeedService) issues(req *restful.Request, resp *restful.Response, obj *feed.FeedItem) {
+	if obj.Type != feed.TypeIssue && obj.Type != feed.TypeScan {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("only issue and scan feed items have issues"))
+		return
+	}
+	skip := 0
+	if p := req.QueryParameter("skip"); p != "" {
+		if val, err := strconv.Atoi(p); err != nil {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("skip: %s", err.Error()))
+			return
+		} else {
+			skip = val
+		}
+	}
+	limit := 20
+	if p := req.QueryParameter("limit"); p != "" {
+		if val, err := strconv.Atoi(p); err != nil {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("limit: %s", err.Error()))
+			return
+		} else {
+			limit = val
+		}
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if sErr := services.Must(services.HasProjectIdPermission(mgr, filters.GetUser(req), obj.Project)); sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+	query := bson.M{"_id": bson.M{"$in": obj.Issues}}
+	results, count, err := mgr.Issues.FilterByQuery(query, mgr.Opts(skip, limit, []string{"-severityRank", "-created"}))
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(&issue.TargetIssueList{
+		Meta:    pagination.Meta{Count: count},
+		Results: results,
+	})
+}
+
+func (s *FeedService) delete(req *restful.Request, resp *restful.Response, obj *feed.FeedItem) {
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	mgr.Feed.Remove(obj)
@@ -152,7 +215,7 @@ func (s *FeedService) TakeFeed(fn func(*restful.Request,
 			return
 		}
 
-		mgr := s.Manager()
+		mgr := s.RequestManager(req)
 		defer mgr.Close()
 
 		obj, err := mgr.Feed.GetById(mgr.ToId(id))
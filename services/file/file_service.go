@@ -5,10 +5,12 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/emicklei/go-restful"
 	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2/bson"
 
 	"github.com/bearded-web/bearded/models/file"
 	"github.com/bearded-web/bearded/pkg/filters"
@@ -51,9 +53,10 @@ func (s *FileService) Register(container *restful.Container) {
 	r.Operation("create")
 	r.Consumes("multipart/form-data")
 	r.Param(ws.FormParameter("file", "file to upload").DataType("File"))
+	r.Param(ws.FormParameter("project", "project id, the file counts toward its storage quota"))
 	r.Writes(file.Meta{})
 	r.Do(services.Returns(http.StatusCreated))
-	r.Do(services.ReturnsE(http.StatusConflict))
+	r.Do(services.ReturnsE(http.StatusConflict, http.StatusPaymentRequired))
 	addDefaults(r)
 	ws.Route(r)
 
@@ -71,6 +74,7 @@ func (s *FileService) Register(container *restful.Container) {
 	r.Doc("download")
 	r.Operation("download")
 	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.QueryParameter("size", "download the nearest image thumbnail which is not less than size").DataType("integer"))
 	r.Do(services.Returns(
 		http.StatusOK,
 		http.StatusNotFound))
@@ -97,11 +101,49 @@ func (s *FileService) create(req *restful.Request, resp *restful.Response) {
 		ContentType: contentType,
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
-	obj, err := mgr.Files.Create(f, meta)
+	var data io.Reader = f
+	if projectId := req.Request.FormValue("project"); projectId != "" {
+		if !bson.IsObjectIdHex(projectId) {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Wrong project id"))
+			return
+		}
+		p, err := mgr.Projects.GetById(bson.ObjectIdHex(projectId))
+		if err != nil {
+			if mgr.IsNotFound(err) {
+				resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Project not found"))
+				return
+			}
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+		if sErr := services.Must(services.HasProjectPermission(mgr, filters.GetUser(req), p)); sErr != nil {
+			sErr.Write(resp)
+			return
+		}
+		limited, err := mgr.Files.StorageLimit(p, f)
+		if err != nil {
+			if sErr := services.QuotaErr(err); sErr != nil {
+				sErr.Write(resp)
+				return
+			}
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+		data = limited
+		meta.Project = p.Id
+	}
+
+	obj, err := mgr.Files.Create(data, meta)
 	if err != nil {
+		if sErr := services.QuotaErr(err); sErr != nil {
+			sErr.Write(resp)
+			return
+		}
 		logrus.Error(stackerr.Wrap(err))
 		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
 		return
@@ -115,7 +157,32 @@ func (s *FileService) get(_ *restful.Request, resp *restful.Response, obj *file.
 	resp.WriteEntity(obj.Meta)
 }
 
-func (s *FileService) download(_ *restful.Request, resp *restful.Response, obj *file.File) {
+func (s *FileService) download(req *restful.Request, resp *restful.Response, obj *file.File) {
+	if p := req.QueryParameter("size"); p != "" {
+		size, err := strconv.Atoi(p)
+		if err != nil || size <= 0 {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("size must be a positive integer"))
+			return
+		}
+		// if there is no suitable thumbnail, the original file is served
+		if thumbSize := obj.Meta.ThumbnailSize(size); thumbSize > 0 {
+			mgr := s.RequestManager(req)
+			defer mgr.Close()
+
+			thumb, err := mgr.Files.GetThumbnail(obj.Meta.Id, thumbSize)
+			if err != nil {
+				if !mgr.IsNotFound(err) {
+					logrus.Error(stackerr.Wrap(err))
+					resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+					return
+				}
+			} else {
+				defer thumb.Close()
+				obj = thumb
+			}
+		}
+	}
+
 	resp.AddHeader("Content-Type", "application/octet-stream")
 
 	if filename := obj.Meta.Name; filename != "" {
@@ -132,7 +199,7 @@ func (s *FileService) TakeFile(fn func(*restful.Request,
 		// TODO (m0sth8): Add token for file to close access to file for everyone
 		id := req.PathParameter(ParamId)
 
-		mgr := s.Manager()
+		mgr := s.RequestManager(req)
 		defer mgr.Close()
 
 		obj, err := mgr.Files.GetById(id)
@@ -0,0 +1,401 @@
+package graphql
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/comment"
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/fltr"
+	"github.com/bearded-web/bearded/pkg/graphql"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/services"
+)
+
+// resolvers report only the fact of db failures, details are logged
+var errDb = errors.New("db error")
+
+type GraphQLService struct {
+	*services.BaseService
+	schema *graphql.Schema
+}
+
+func New(base *services.BaseService) *GraphQLService {
+	return &GraphQLService{
+		BaseService: base,
+	}
+}
+
+// context is passed to every resolver, manager is shared by the whole query
+type context struct {
+	mgr  *manager.Manager
+	user *user.User
+}
+
+func (s *GraphQLService) Init() error {
+	cfg := s.ApiCfg().GraphQL
+	s.schema = newSchema(cfg.MaxDepth, s.Paginator.LimitMax)
+	s.schema.MaxCost = cfg.MaxCost
+	return nil
+}
+
+func (s *GraphQLService) Register(container *restful.Container) {
+	ws := &restful.WebService{}
+	ws.Path("/api/graphql")
+	ws.Doc("GraphQL façade for projects, targets, issues and comments")
+	ws.Consumes(restful.MIME_JSON)
+	ws.Produces(restful.MIME_JSON)
+	ws.Filter(filters.AuthTokenFilter(s.BaseManager()))
+	ws.Filter(filters.AuthRequiredFilter(s.BaseManager()))
+
+	r := ws.POST("").To(s.query)
+	r.Doc("query")
+	r.Operation("query")
+	r.Notes("Authorization required. Only queries are supported, fragments aren't supported yet.")
+	r.Reads(graphql.Request{})
+	r.Writes(graphql.Result{})
+	r.Do(services.Returns(http.StatusOK))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusUnauthorized,
+		http.StatusInternalServerError,
+	))
+	ws.Route(r)
+
+	r = ws.GET("").To(s.query)
+	r.Doc("query")
+	r.Operation("queryGet")
+	r.Param(ws.QueryParameter("query", "graphql query"))
+	r.Param(ws.QueryParameter("operationName", ""))
+	r.Writes(graphql.Result{})
+	r.Do(services.Returns(http.StatusOK))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusUnauthorized,
+		http.StatusInternalServerError,
+	))
+	ws.Route(r)
+
+	container.Add(ws)
+}
+
+func (s *GraphQLService) query(req *restful.Request, resp *restful.Response) {
+	raw := &graphql.Request{}
+	if req.Request.Method == "GET" {
+		raw.Query = req.QueryParameter("query")
+		raw.OperationName = req.QueryParameter("operationName")
+	} else if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
+	}
+	if raw.Query == "" {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("query is required"))
+		return
+	}
+	// the whole request is rejected for syntax errors, unlike field errors
+	if _, _, err := s.schema.Prepare(raw); err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("%s", err.Error()))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	result := s.schema.Execute(raw, &context{mgr: mgr, user: filters.GetUser(req)})
+	resp.WriteEntity(result)
+}
+
+func newSchema(maxDepth, limitMax int) *graphql.Schema {
+	commentType := &graphql.Object{Name: "Comment"}
+	issueType := &graphql.Object{
+		Name: "Issue",
+		Fields: map[string]*graphql.ObjectField{
+			"comments": &graphql.ObjectField{
+				Type:  commentType,
+				Batch: resolveComments(limitMax),
+				List:  true,
+			},
+		},
+	}
+	targetType := &graphql.Object{
+		Name: "Target",
+		Fields: map[string]*graphql.ObjectField{
+			"addr": &graphql.ObjectField{
+				Resolve: func(p graphql.Params) (interface{}, error) {
+					return p.Source.(*target.Target).Addr(), nil
+				},
+			},
+			"issues": &graphql.ObjectField{
+				Type: issueType,
+				Batch: resolveIssues(limitMax, "target", func(source interface{}) bson.ObjectId {
+					return source.(*target.Target).Id
+				}),
+				List: true,
+			},
+		},
+	}
+	projectType := &graphql.Object{
+		Name: "Project",
+		Fields: map[string]*graphql.ObjectField{
+			"targets": &graphql.ObjectField{
+				Type:  targetType,
+				Batch: resolveTargets(limitMax),
+				List:  true,
+			},
+			"issues": &graphql.ObjectField{
+				Type: issueType,
+				Batch: resolveIssues(limitMax, "project", func(source interface{}) bson.ObjectId {
+					return source.(*project.Project).Id
+				}),
+				List: true,
+			},
+		},
+	}
+	query := &graphql.Object{
+		Name:   "Query",
+		Strict: true,
+		Fields: map[string]*graphql.ObjectField{
+			"projects": &graphql.ObjectField{
+				Type:    projectType,
+				Resolve: resolveProjects(limitMax),
+				List:    true,
+			},
+			"project": &graphql.ObjectField{
+				Type:    projectType,
+				Resolve: resolveProject,
+			},
+		},
+	}
+	return &graphql.Schema{Query: query, MaxDepth: maxDepth, ListSize: limitMax}
+}
+
+func resolveProjects(limitMax int) graphql.Resolver {
+	return func(p graphql.Params) (interface{}, error) {
+		ctx := p.Context.(*context)
+		opts, err := getOpts(p.Args, limitMax)
+		if err != nil {
+			return nil, err
+		}
+		query := manager.Or(fltr.GetQuery(&manager.ProjectFltr{Owner: ctx.user.Id, Member: ctx.user.Id}))
+		results, _, err := ctx.mgr.Projects.FilterByQuery(query, opts)
+		if err != nil {
+			logrus.Error(stackerr.Wrap(err))
+			return nil, errDb
+		}
+		return results, nil
+	}
+}
+
+func resolveProject(p graphql.Params) (interface{}, error) {
+	ctx := p.Context.(*context)
+	id, ok := p.Args["id"].(string)
+	if !ok || !ctx.mgr.IsId(id) {
+		return nil, fmt.Errorf("id argument should be a project id")
+	}
+	proj, err := ctx.mgr.Projects.GetById(ctx.mgr.ToId(id))
+	if err != nil {
+		if ctx.mgr.IsNotFound(err) {
+			return nil, nil
+		}
+		logrus.Error(stackerr.Wrap(err))
+		return nil, errDb
+	}
+	// inaccessible projects look the same as unknown ones
+	if !ctx.mgr.Permission.HasProjectAccess(proj, ctx.user) {
+		return nil, nil
+	}
+	return proj, nil
+}
+
+// window collects children of parents from one query, skip and limit are applied for every parent
+type window struct {
+	opts    manager.Opts
+	index   map[bson.ObjectId]int
+	ids     []bson.ObjectId
+	seen    []int
+	results [][]interface{}
+	full    int
+}
+
+func newWindow(sources []interface{}, parent func(interface{}) bson.ObjectId, opts manager.Opts) *window {
+	w := &window{opts: opts, index: map[bson.ObjectId]int{}}
+	for _, source := range sources {
+		id := parent(source)
+		if _, ok := w.index[id]; ok {
+			continue
+		}
+		w.index[id] = len(w.ids)
+		w.ids = append(w.ids, id)
+	}
+	w.seen = make([]int, len(w.ids))
+	w.results = make([][]interface{}, len(w.ids))
+	for i := range w.results {
+		w.results[i] = []interface{}{}
+	}
+	return w
+}
+
+// add keeps the child if it's in the window of the parent,
+// manager.ErrStop is returned when windows of all parents are full
+func (w *window) add(parent bson.ObjectId, obj interface{}) error {
+	i, ok := w.index[parent]
+	if !ok || w.seen[i] >= w.opts.Skip+w.opts.Limit {
+		return nil
+	}
+	w.seen[i]++
+	if w.seen[i] > w.opts.Skip {
+		w.results[i] = append(w.results[i], obj)
+	}
+	if w.seen[i] == w.opts.Skip+w.opts.Limit {
+		w.full++
+		if w.full == len(w.ids) {
+			return manager.ErrStop
+		}
+	}
+	return nil
+}
+
+// values returns children in the order of sources
+func (w *window) values(sources []interface{}, parent func(interface{}) bson.ObjectId) []interface{} {
+	values := make([]interface{}, len(sources))
+	for i, source := range sources {
+		values[i] = w.results[w.index[parent(source)]]
+	}
+	return values
+}
+
+func projectId(source interface{}) bson.ObjectId {
+	return source.(*project.Project).Id
+}
+
+func issueId(source interface{}) bson.ObjectId {
+	return source.(*issue.TargetIssue).Id
+}
+
+// targets and issues are reached only through the project, so its access is already checked.
+// Children of all parents of the level are loaded with one query, which stops when every parent has enough children.
+func resolveTargets(limitMax int) graphql.BatchResolver {
+	return func(p graphql.BatchParams) ([]interface{}, error) {
+		ctx := p.Context.(*context)
+		opts, err := getOpts(p.Args, limitMax)
+		if err != nil {
+			return nil, err
+		}
+		w := newWindow(p.Sources, projectId, opts)
+		query := bson.M{"project": bson.M{"$in": w.ids}}
+		if t, ok := p.Args["type"].(string); ok {
+			query["type"] = target.TargetType(t)
+		}
+		err = ctx.mgr.Targets.Stream(query, manager.Opts{}, func(obj *target.Target) error {
+			return w.add(obj.Project, obj)
+		})
+		if err != nil {
+			logrus.Error(stackerr.Wrap(err))
+			return nil, errDb
+		}
+		return w.values(p.Sources, projectId), nil
+	}
+}
+
+// field is the issue field with the parent id, parent returns the id of the source
+func resolveIssues(limitMax int, field string, parent func(interface{}) bson.ObjectId) graphql.BatchResolver {
+	return func(p graphql.BatchParams) ([]interface{}, error) {
+		ctx := p.Context.(*context)
+		opts, err := getOpts(p.Args, limitMax)
+		if err != nil {
+			return nil, err
+		}
+		f := &manager.IssueFltr{}
+		if sev, ok := p.Args["severity"].(string); ok {
+			f.Severity = issue.Severity(sev)
+		}
+		for name, val := range map[string]**bool{
+			"confirmed": &f.Confirmed,
+			"muted":     &f.Muted,
+			"resolved":  &f.Resolved,
+			"false":     &f.False,
+		} {
+			if v, ok := p.Args[name].(bool); ok {
+				*val = &v
+			}
+		}
+		w := newWindow(p.Sources, parent, opts)
+		query := fltr.GetQuery(f)
+		query[field] = bson.M{"$in": w.ids}
+		issues := []*issue.TargetIssue{}
+		err = ctx.mgr.Issues.Stream(query, manager.Opts{}, func(obj *issue.TargetIssue) error {
+			issues = append(issues, obj)
+			if field == "target" {
+				return w.add(obj.Target, obj)
+			}
+			return w.add(obj.Project, obj)
+		})
+		if err == nil {
+			// like in the rest api, issues are read only here
+			err = ctx.mgr.Issues.RenderVars(issues...)
+		}
+		if err != nil {
+			logrus.Error(stackerr.Wrap(err))
+			return nil, errDb
+		}
+		return w.values(p.Sources, parent), nil
+	}
+}
+
+func resolveComments(limitMax int) graphql.BatchResolver {
+	return func(p graphql.BatchParams) ([]interface{}, error) {
+		ctx := p.Context.(*context)
+		opts, err := getOpts(p.Args, limitMax)
+		if err != nil {
+			return nil, err
+		}
+		w := newWindow(p.Sources, issueId, opts)
+		query := bson.M{"type": comment.Issue, "link": bson.M{"$in": w.ids}}
+		err = ctx.mgr.Comments.Stream(query, manager.Opts{}, func(obj *comment.Comment) error {
+			return w.add(obj.Link, obj)
+		})
+		if err != nil {
+			logrus.Error(stackerr.Wrap(err))
+			return nil, errDb
+		}
+		return w.values(p.Sources, issueId), nil
+	}
+}
+
+// getOpts reads limit and skip arguments, limit is capped like in the rest api
+func getOpts(args map[string]interface{}, limitMax int) (manager.Opts, error) {
+	opts := manager.Opts{Limit: limitMax}
+	for name, val := range map[string]*int{"limit": &opts.Limit, "skip": &opts.Skip} {
+		raw, ok := args[name]
+		if !ok || raw == nil {
+			continue
+		}
+		// variables come from json, so they are float
+		switch v := raw.(type) {
+		case int:
+			*val = v
+		case float64:
+			*val = int(v)
+		default:
+			return opts, fmt.Errorf("%s argument should be an integer", name)
+		}
+		if *val < 0 {
+			return opts, fmt.Errorf("%s argument should be positive", name)
+		}
+	}
+	if opts.Limit == 0 || opts.Limit > limitMax {
+		opts.Limit = limitMax
+	}
+	return opts, nil
+}
@@ -0,0 +1,131 @@
+package graphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/comment"
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/graphql"
+	"github.com/bearded-web/bearded/pkg/managertest"
+)
+
+func TestGraphQL(t *testing.T) {
+	env := managertest.Open(t)
+	defer env.Close()
+	mgr := env.Mgr
+	u, err := env.Login(&user.User{Email: "user@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts, err := env.Server(New(env.Base))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+
+	c.Convey("Given project with target, issue and comment", t, func() {
+		// every convey leaf runs this again, names are unique for the owner
+		name := bson.NewObjectId().Hex()
+		projectObj, err := mgr.Projects.Create(&project.Project{Name: name, Owner: u.Id})
+		c.So(err, c.ShouldBeNil)
+		foreign, err := mgr.Projects.Create(&project.Project{Name: name, Owner: bson.NewObjectId()})
+		c.So(err, c.ShouldBeNil)
+		targetObj, err := mgr.Targets.Create(&target.Target{
+			Project: projectObj.Id,
+			Type:    target.TypeWeb,
+			Web:     &target.WebTarget{Domain: "http://example.com"},
+		})
+		c.So(err, c.ShouldBeNil)
+		issueObj, err := mgr.Issues.Create(&issue.TargetIssue{
+			Target:  targetObj.Id,
+			Project: projectObj.Id,
+			Issue:   issue.Issue{Summary: "xss", Severity: issue.SeverityHigh, Desc: "Found on {{target.host}}"},
+		})
+		c.So(err, c.ShouldBeNil)
+		_, err = mgr.Comments.Create(&comment.Comment{
+			Owner: u.Id,
+			Type:  comment.Issue,
+			Link:  issueObj.Id,
+			Text:  "confirmed",
+		})
+		c.So(err, c.ShouldBeNil)
+
+		c.Convey("Query the whole tree with one request", func() {
+			res, result := query(t, ts.URL, &graphql.Request{
+				Query: `query ($id: ID!) {
+					project(id: $id) {
+						name
+						targets(limit: 10) {
+							addr web { domain }
+							issues(severity: high, limit: 10) { summary desc comments(limit: 10) { text } }
+						}
+					}
+				}`,
+				Variables: map[string]interface{}{"id": projectObj.Id.Hex()},
+			})
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+			c.So(result.Errors, c.ShouldBeEmpty)
+			proj := result.Data["project"].(map[string]interface{})
+			c.So(proj["name"], c.ShouldEqual, name)
+			tgt := proj["targets"].([]interface{})[0].(map[string]interface{})
+			c.So(tgt["addr"], c.ShouldEqual, "http://example.com")
+			_, selected := tgt["id"]
+			c.So(selected, c.ShouldBeFalse)
+			iss := tgt["issues"].([]interface{})[0].(map[string]interface{})
+			c.So(iss["summary"], c.ShouldEqual, "xss")
+			c.So(iss["desc"], c.ShouldEqual, "Found on example.com")
+			comments := iss["comments"].([]interface{})
+			c.So(len(comments), c.ShouldEqual, 1)
+			c.So(comments[0].(map[string]interface{})["text"], c.ShouldEqual, "confirmed")
+		})
+
+		c.Convey("Foreign project isn't accessible", func() {
+			res, result := query(t, ts.URL, &graphql.Request{
+				Query: `{ project(id: "` + foreign.Id.Hex() + `") { name } }`,
+			})
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+			c.So(result.Data["project"], c.ShouldBeNil)
+		})
+
+		c.Convey("Queries with big fan-out are rejected", func() {
+			res, _ := query(t, ts.URL, &graphql.Request{
+				Query: `{ projects { targets { issues { summary } } } }`,
+			})
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusBadRequest)
+		})
+
+		c.Convey("Syntax errors are rejected", func() {
+			res, _ := query(t, ts.URL, &graphql.Request{Query: `{ project( }`})
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusBadRequest)
+		})
+	})
+}
+
+func query(t *testing.T, url string, raw *graphql.Request) (*http.Response, *graphql.Result) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.Post(url+"/api/graphql", "application/json", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	result := &graphql.Result{}
+	if res.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return res, result
+}
@@ -4,9 +4,11 @@ import (
 	"net/http"
 
 	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
 	"github.com/facebookgo/stackerr"
 	"gopkg.in/mgo.v2/bson"
 
+	"github.com/bearded-web/bearded/models/approval"
 	"github.com/bearded-web/bearded/models/project"
 	"github.com/bearded-web/bearded/models/user"
 	"github.com/bearded-web/bearded/pkg/manager"
@@ -47,3 +49,58 @@ func Must(ok bool, sErr *ErrResp) *ErrResp {
 	}
 	return nil
 }
+
+// QuotaErr converts exceeded quota errors to responses, nil is returned for other errors.
+// Rate quotas are restored with time, so they are 429, otherwise 402 is returned
+func QuotaErr(err error) *ErrResp {
+	qErr, ok := err.(*manager.QuotaError)
+	if !ok {
+		return nil
+	}
+	code := http.StatusPaymentRequired
+	if qErr.Rate {
+		code = http.StatusTooManyRequests
+	}
+	return &ErrResp{Code: code, Err: NewError(CodeQuota, qErr.Msg)}
+}
+
+// EngagementErr converts errors of broken project rules of engagement to 400 responses with all violations,
+// nil is returned for other errors
+func EngagementErr(err error) *ErrResp {
+	eErr, ok := err.(*manager.EngagementError)
+	if !ok {
+		return nil
+	}
+	return &ErrResp{Code: http.StatusBadRequest, Err: NewError(CodeEngagement, eErr.Error())}
+}
+
+// ChildrenErr converts errors of the block cascade policy to 409 responses, nil is returned for other errors
+func ChildrenErr(err error) *ErrResp {
+	cErr, ok := err.(*manager.ChildrenError)
+	if !ok {
+		return nil
+	}
+	return &ErrResp{Code: http.StatusConflict, Err: NewError(CodeChildren, cErr.Msg)}
+}
+
+// SubmitApproval executes the destructive action or leaves it for a second admin.
+// 204 is written if the action is executed, otherwise 202 with the pending approval.
+// 409 is written if the cascade policy blocks the deletion.
+func SubmitApproval(resp *restful.Response, mgr *manager.Manager, a *approval.Approval) {
+	executed, err := mgr.Approvals.Submit(a)
+	if err != nil {
+		if sErr := ChildrenErr(err); sErr != nil {
+			sErr.Write(resp)
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, DbErr)
+		return
+	}
+	if executed {
+		resp.WriteHeader(http.StatusNoContent)
+		return
+	}
+	resp.WriteHeader(http.StatusAccepted)
+	resp.WriteEntity(a)
+}
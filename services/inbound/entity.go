@@ -0,0 +1,137 @@
+package inbound
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// EmailEntity is an inbound email posted by the mail provider.
+// Fields of mailgun and sendgrid forms and of postmark json are accepted.
+type EmailEntity struct {
+	To       string `json:"to" description:"recipients with the reply address"`
+	From     string `json:"from"`
+	Subject  string `json:"subject,omitempty"`
+	Text     string `json:"text" description:"plain text body, the quoted message is stripped"`
+	Envelope string `json:"envelope,omitempty" description:"envelope sender, spf verdicts are about its domain"`
+	Spf      string `json:"spf,omitempty" description:"spf verdict of the provider"`
+	Dkim     string `json:"dkim,omitempty" description:"dkim verdict of the provider"`
+}
+
+// Authenticated reports if the provider verified that the email is sent by the domain of the sender,
+// the From header alone can be spoofed by anyone. Verdicts count only if they are about the sender domain:
+// spf checks the envelope sender, so it has to be of the same domain, and dkim needs a signature of the domain.
+// Dkim verdicts without signing domains, like the mailgun one, aren't accepted.
+func (e *EmailEntity) Authenticated(domain string) bool {
+	domain = strings.ToLower(domain)
+	if domain == "" {
+		return false
+	}
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(e.Spf)), "pass") && addressDomain(e.Envelope) == domain {
+		return true
+	}
+	// sendgrid lists results of all signatures, like {@example.com : pass, @mailer.example : fail}
+	dkim := strings.ToLower(strings.TrimSpace(e.Dkim))
+	for _, result := range strings.Split(strings.Trim(dkim, "{}"), ",") {
+		parts := strings.SplitN(result, ":", 2)
+		if len(parts) == 2 && strings.TrimSpace(parts[0]) == "@"+domain &&
+			strings.TrimSpace(parts[1]) == "pass" {
+			return true
+		}
+	}
+	return false
+}
+
+// addressDomain returns the lower cased domain of the bare or bracketed address, like <bounce@example.com>
+func addressDomain(addr string) string {
+	addr = strings.Trim(strings.TrimSpace(addr), "<>")
+	i := strings.LastIndex(addr, "@")
+	if i < 0 {
+		return ""
+	}
+	return strings.ToLower(addr[i+1:])
+}
+
+// envelopeFrom returns the sender from the sendgrid envelope, like {"to":["reply@example.com"],"from":"bounce@example.com"}
+func envelopeFrom(envelope string) string {
+	if envelope == "" {
+		return ""
+	}
+	raw := struct {
+		From string `json:"from"`
+	}{}
+	if err := json.Unmarshal([]byte(envelope), &raw); err != nil {
+		return ""
+	}
+	return raw.From
+}
+
+// postmark sends json with capitalized fields and the reply already stripped
+type postmarkEmail struct {
+	OriginalRecipient string
+	To                string
+	From              string
+	Subject           string
+	TextBody          string
+	StrippedTextReply string
+	Headers           []postmarkHeader
+}
+
+type postmarkHeader struct {
+	Name  string
+	Value string
+}
+
+// maxEmailSize limits the parsed form, attachments are dropped anyway
+const maxEmailSize = 10 << 20
+
+func readEmail(req *http.Request) (*EmailEntity, error) {
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/json") {
+		raw := &postmarkEmail{}
+		if err := json.NewDecoder(http.MaxBytesReader(nil, req.Body, maxEmailSize)).Decode(raw); err != nil {
+			return nil, err
+		}
+		return &EmailEntity{
+			To:      first(raw.OriginalRecipient, raw.To),
+			From:    raw.From,
+			Subject: raw.Subject,
+			Text:    first(raw.StrippedTextReply, raw.TextBody),
+
+			Envelope: raw.header("Return-Path"),
+			Spf:      raw.header("Received-SPF"),
+		}, nil
+	}
+	if err := req.ParseMultipartForm(maxEmailSize); err != nil && err != http.ErrNotMultipart {
+		return nil, err
+	}
+	form := req.Form
+	return &EmailEntity{
+		To:      first(form.Get("recipient"), form.Get("to")),
+		From:    first(form.Get("sender"), form.Get("from")),
+		Subject: form.Get("subject"),
+		Text:    first(form.Get("stripped-text"), form.Get("body-plain"), form.Get("text")),
+
+		// mailgun sends the envelope sender as sender, sendgrid sends the whole envelope
+		Envelope: first(form.Get("sender"), envelopeFrom(form.Get("envelope"))),
+		Spf:      first(form.Get("X-Mailgun-Spf"), form.Get("SPF")),
+		Dkim:     first(form.Get("X-Mailgun-Dkim-Check-Result"), form.Get("dkim")),
+	}, nil
+}
+
+func (e *postmarkEmail) header(name string) string {
+	for _, h := range e.Headers {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value
+		}
+	}
+	return ""
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
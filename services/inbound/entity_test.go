@@ -0,0 +1,76 @@
+package inbound
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadEmail(t *testing.T) {
+	form := url.Values{}
+	form.Set("recipient", "reply+abc@bearded.example")
+	form.Set("from", "John <john@example.com>")
+	form.Set("sender", "bounce@example.com")
+	form.Set("body-plain", "Fixed\n> quoted")
+	form.Set("stripped-text", "Fixed")
+	form.Set("X-Mailgun-Spf", "Pass")
+	req, _ := http.NewRequest("POST", "/api/v1/inbound/email", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	raw, err := readEmail(req)
+	require.NoError(t, err)
+	assert.Equal(t, &EmailEntity{To: "reply+abc@bearded.example", From: "bounce@example.com", Text: "Fixed",
+		Envelope: "bounce@example.com", Spf: "Pass"}, raw)
+
+	body := &bytes.Buffer{}
+	w := multipart.NewWriter(body)
+	w.WriteField("to", "reply+abc@bearded.example")
+	w.WriteField("from", "john@example.com")
+	w.WriteField("text", "Fixed")
+	w.WriteField("dkim", "{@example.com : pass}")
+	w.WriteField("envelope", `{"to":["reply+abc@bearded.example"],"from":"bounce@mailer.example"}`)
+	w.Close()
+	req, _ = http.NewRequest("POST", "/api/v1/inbound/email", body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	raw, err = readEmail(req)
+	require.NoError(t, err)
+	assert.Equal(t, "reply+abc@bearded.example", raw.To)
+	assert.Equal(t, "Fixed", raw.Text)
+	assert.Equal(t, "{@example.com : pass}", raw.Dkim)
+	assert.Equal(t, "bounce@mailer.example", raw.Envelope)
+
+	req, _ = http.NewRequest("POST", "/api/v1/inbound/email", strings.NewReader(
+		`{"OriginalRecipient":"reply+abc@bearded.example","To":"Bearded <reply+abc@bearded.example>",`+
+			`"From":"john@example.com","TextBody":"Fixed\n> quoted","StrippedTextReply":"Fixed",`+
+			`"Headers":[{"Name":"Received-SPF","Value":"Pass (sender SPF authorized) identity=mailfrom"},`+
+			`{"Name":"Return-Path","Value":"<bounce@example.com>"}]}`))
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	raw, err = readEmail(req)
+	require.NoError(t, err)
+	assert.Equal(t, "reply+abc@bearded.example", raw.To)
+	assert.Equal(t, "Fixed", raw.Text)
+	assert.True(t, raw.Authenticated("example.com"))
+}
+
+func TestAuthenticated(t *testing.T) {
+	assert.False(t, (&EmailEntity{}).Authenticated("example.com"))
+	assert.False(t, (&EmailEntity{Envelope: "john@example.com", Spf: "SoftFail", Dkim: "Fail"}).Authenticated("example.com"))
+	assert.True(t, (&EmailEntity{Envelope: "<bounce@Example.com>", Spf: "pass"}).Authenticated("example.com"))
+	assert.True(t, (&EmailEntity{Dkim: "{@mailer.example : fail, @example.com : pass}"}).Authenticated("example.com"))
+
+	// spf of the envelope sender from another domain doesn't authenticate the From header
+	assert.False(t, (&EmailEntity{Envelope: "bounce@attacker.example", Spf: "pass"}).Authenticated("example.com"))
+	assert.False(t, (&EmailEntity{Envelope: "bounce@mail.example.com", Spf: "pass"}).Authenticated("example.com"))
+	assert.False(t, (&EmailEntity{Spf: "pass"}).Authenticated("example.com"))
+	// signature of another domain doesn't authenticate the sender
+	assert.False(t, (&EmailEntity{Dkim: "{@mailer.example : pass}"}).Authenticated("example.com"))
+	assert.False(t, (&EmailEntity{Dkim: "{@attacker.example : pass, @example.com : fail}"}).Authenticated("example.com"))
+	// the signing domain is unknown
+	assert.False(t, (&EmailEntity{Dkim: "Pass"}).Authenticated("example.com"))
+	assert.False(t, (&EmailEntity{Envelope: "bounce@example.com", Spf: "pass"}).Authenticated(""))
+}
@@ -0,0 +1,161 @@
+package inbound
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/comment"
+	"github.com/bearded-web/bearded/pkg/reply"
+	"github.com/bearded-web/bearded/services"
+)
+
+type InboundService struct {
+	*services.BaseService
+	reply *reply.Addresser // nil if replies are disabled
+}
+
+func New(base *services.BaseService) *InboundService {
+	return &InboundService{
+		BaseService: base,
+	}
+}
+
+func (s *InboundService) Init() error {
+	cfg := s.ApiCfg().Reply
+	if cfg.Address == "" {
+		return nil
+	}
+	addresser, err := reply.New(cfg.Address, cfg.TokenSecret)
+	if err != nil {
+		return fmt.Errorf("Wrong reply config: %s", err)
+	}
+	if cfg.Secret == "" {
+		return fmt.Errorf("Wrong reply config: webhook secret is required")
+	}
+	s.reply = addresser
+	return nil
+}
+
+func (s *InboundService) Register(container *restful.Container) {
+	ws := &restful.WebService{}
+	ws.Path("/api/v1/inbound")
+	ws.Doc("Inbound emails posted by mail providers")
+	ws.Consumes(restful.MIME_JSON, "application/x-www-form-urlencoded", "multipart/form-data")
+	ws.Produces(restful.MIME_JSON)
+
+	r := ws.POST("email").To(s.email)
+	r.Doc("email")
+	r.Operation("email")
+	r.Notes("The reply to an issue notification is added as a comment of the notified user. " +
+		"The secret from the server config is required as the password of basic auth, " +
+		"the sender should be the user and pass spf checks of an envelope sender from the same domain " +
+		"or have a dkim signature of the sender domain. " +
+		"Mailgun and sendgrid forms and postmark json are accepted")
+	r.Reads(EmailEntity{})
+	r.Writes(comment.Comment{})
+	r.Do(services.Returns(http.StatusCreated))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusUnauthorized,
+		http.StatusForbidden,
+		http.StatusNotFound,
+		http.StatusInternalServerError,
+	))
+	ws.Route(r)
+
+	container.Add(ws)
+}
+
+// ====== service operations
+
+func (s *InboundService) email(req *restful.Request, resp *restful.Response) {
+	if s.reply == nil {
+		resp.WriteServiceError(http.StatusNotFound, services.NewBadReq("Email replies are disabled"))
+		return
+	}
+	// providers pass credentials of the webhook url as basic auth, query params would end up in access logs
+	_, password, _ := req.Request.BasicAuth()
+	secret := []byte(s.ApiCfg().Reply.Secret)
+	if subtle.ConstantTimeCompare([]byte(password), secret) != 1 {
+		resp.WriteServiceError(http.StatusUnauthorized, services.AuthFailedErr)
+		return
+	}
+	raw, err := readEmail(req.Request)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
+	}
+	issueId, userId, err := s.reply.Parse(raw.To)
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Wrong recipient: %s", err))
+		return
+	}
+	text := reply.Strip(raw.Text)
+	if text == "" {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Reply is empty"))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	u, err := mgr.Users.GetById(userId)
+	if err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("User not found"))
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	// the address could be forwarded, so replies are accepted only from the notified user
+	from, err := mail.ParseAddress(raw.From)
+	if err != nil || !strings.EqualFold(from.Address, u.Email) {
+		logrus.Warnf("Reply of user %s is sent from %q", u, raw.From)
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	if domain := from.Address[strings.LastIndex(from.Address, "@")+1:]; !raw.Authenticated(domain) {
+		logrus.Warnf("Reply of user %s isn't authenticated, envelope %q, spf %q, dkim %q", u, raw.Envelope, raw.Spf, raw.Dkim)
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	obj, err := mgr.Issues.GetById(issueId)
+	if err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteServiceError(http.StatusNotFound, services.NewBadReq("Issue not found"))
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	// the user could lose access after the notification
+	if sErr := services.Must(services.HasProjectIdPermission(mgr, u, obj.Project)); sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+
+	c, err := mgr.Comments.Create(&comment.Comment{
+		Owner: u.Id,
+		Type:  comment.Issue,
+		Link:  obj.Id,
+		Text:  text,
+	})
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteHeader(http.StatusCreated)
+	resp.WriteEntity(c)
+}
@@ -0,0 +1,59 @@
+package ingest
+
+import (
+	"fmt"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+type IssueEntity struct {
+	UniqId      string         `json:"uniqId,omitempty" description:"id for merging the same findings, e.g. rule id with location" validate:"max=256"`
+	Summary     string         `json:"summary" validate:"nonzero,min=3,max=120"`
+	Severity    issue.Severity `json:"severity,omitempty" description:"one of [high medium low info], info by default"`
+	Desc        string         `json:"desc,omitempty"`
+	Remediation string         `json:"remediation,omitempty"`
+	Url         string         `json:"url,omitempty" description:"where the issue is found"`
+	Cve         []string       `json:"cve,omitempty" description:"related CVE identifiers, like CVE-2014-0160"`
+}
+
+func (e *IssueEntity) Validate() error {
+	if e.Severity != "" && !e.Severity.IsValid() {
+		return validate.NewError("severity", validate.CodeInvalid, "should be one of [high medium low info]")
+	}
+	return nil
+}
+
+// Transform returns the issue which is stored for the target
+func (e *IssueEntity) Transform() *issue.Issue {
+	obj := &issue.Issue{
+		UniqId:      e.UniqId,
+		Summary:     e.Summary,
+		Severity:    e.Severity,
+		Desc:        e.Desc,
+		Remediation: e.Remediation,
+		Cve:         e.Cve,
+	}
+	if e.Url != "" {
+		obj.Vector = &issue.Vector{Url: e.Url}
+	}
+	return obj
+}
+
+type IngestEntity struct {
+	Target string         `json:"target" description:"id of the project target" validate:"nonzero,bsonId"`
+	Tool   string         `json:"tool,omitempty" description:"name of the tool, project rules match it as plugin" validate:"max=80"`
+	Issues []*IssueEntity `json:"issues" description:"max 1000" validate:"nonzero,max=1000"`
+}
+
+func (e *IngestEntity) Validate() error {
+	errs := validate.Errors{}
+	for i, obj := range e.Issues {
+		if obj == nil {
+			errs.Add(fmt.Sprintf("issues.%d", i), validate.CodeRequired, "shouldn't be empty")
+			continue
+		}
+		errs.Extend(fmt.Sprintf("issues.%d", i), validate.Entity(obj))
+	}
+	return errs.Err()
+}
@@ -0,0 +1,38 @@
+package ingest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/pkg/validate"
+)
+
+func TestIngestEntity(t *testing.T) {
+	raw := &IngestEntity{
+		Target: "123456789012345678901234",
+		Issues: []*IssueEntity{
+			{Summary: "Sql injection", Severity: issue.SeverityHigh, Url: "http://example.com/?id=1"},
+			{Summary: "x"},
+			nil,
+			{Summary: "Weak cipher", Severity: "critical"},
+		},
+	}
+	err := validate.Entity(raw)
+	require.Error(t, err)
+	errs := validate.FromError(err)
+	require.Len(t, errs, 3)
+	assert.Equal(t, &validate.FieldError{Field: "issues.1.summary", Code: validate.CodeMin, Message: "should be at least 3 symbols"}, errs[0])
+	assert.Equal(t, "issues.2", errs[1].Field)
+	assert.Equal(t, "issues.3.severity", errs[2].Field)
+
+	assert.Error(t, validate.Entity(&IngestEntity{Target: "wrong"}))
+
+	obj := raw.Issues[0].Transform()
+	assert.Equal(t, "Sql injection", obj.Summary)
+	require.NotNil(t, obj.Vector)
+	assert.Equal(t, "http://example.com/?id=1", obj.Vector.Url)
+	assert.Nil(t, raw.Issues[1].Transform().Vector)
+}
@@ -0,0 +1,129 @@
+package ingest
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/token"
+	"github.com/bearded-web/bearded/pkg/filters"
+	ingestEngine "github.com/bearded-web/bearded/pkg/ingest"
+	"github.com/bearded-web/bearded/pkg/validate"
+	"github.com/bearded-web/bearded/services"
+)
+
+type IngestService struct {
+	*services.BaseService
+}
+
+func New(base *services.BaseService) *IngestService {
+	return &IngestService{
+		BaseService: base,
+	}
+}
+
+func (s *IngestService) Register(container *restful.Container) {
+	ws := &restful.WebService{}
+	ws.Path("/api/v1/ingest")
+	ws.Doc("Push issues from external tools")
+	ws.Consumes(restful.MIME_JSON)
+	ws.Produces(restful.MIME_JSON)
+
+	r := ws.POST("issues").To(s.TakeToken(s.issues))
+	r.Doc("issues")
+	r.Operation("issues")
+	r.Notes("Authorization: Bearer header with ingest token is required, " +
+		"it's created by POST /api/v1/tokens with the project")
+	r.Reads(IngestEntity{})
+	r.Writes(ingestEngine.ExternalResult{})
+	r.Do(services.Returns(http.StatusCreated))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusUnauthorized,
+		http.StatusInternalServerError,
+	))
+	ws.Route(r)
+
+	container.Add(ws)
+}
+
+// ====== service operations
+
+func (s *IngestService) issues(req *restful.Request, resp *restful.Response, tok *token.Token) {
+	raw := &IngestEntity{}
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
+	}
+	if err := validate.Entity(raw); err != nil {
+		services.NewValidationErr(err).Write(resp)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	// token could push issues only to targets of its project
+	tgt, err := mgr.Targets.GetById(mgr.ToId(raw.Target))
+	if err != nil && !mgr.IsNotFound(err) {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	if err != nil || tgt.Project != tok.Project {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Target not found"))
+		return
+	}
+
+	issues := make([]*issue.Issue, len(raw.Issues))
+	for i, obj := range raw.Issues {
+		issues[i] = obj.Transform()
+	}
+	result, err := ingestEngine.External(mgr, tok, tgt, raw.Tool, issues)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteHeader(http.StatusCreated)
+	resp.WriteEntity(result)
+}
+
+// Helpers
+
+// TakeToken authenticates the request by ingest token, user tokens and sessions aren't accepted
+func (s *IngestService) TakeToken(fn func(*restful.Request,
+	*restful.Response, *token.Token)) restful.RouteFunction {
+	return func(req *restful.Request, resp *restful.Response) {
+		parts := strings.Split(req.Request.Header.Get("Authorization"), " ")
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			resp.WriteServiceError(http.StatusUnauthorized, services.AuthReqErr)
+			return
+		}
+
+		mgr := s.RequestManager(req)
+		defer mgr.Close()
+
+		tok, err := mgr.Tokens.GetByHash(parts[1])
+		if err != nil {
+			if mgr.IsNotFound(err) {
+				resp.WriteServiceError(http.StatusUnauthorized, services.AuthFailedErr)
+				return
+			}
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+		if tok.Removed || !tok.IsIngest() || tok.Project == "" {
+			resp.WriteServiceError(http.StatusUnauthorized, services.AuthFailedErr)
+			return
+		}
+		req.SetAttribute(filters.AttrTokenKey, tok)
+		fn(req, resp, tok)
+	}
+}
@@ -1,10 +1,17 @@
 package issue
 
 import (
+	"fmt"
 	"net/http"
 	"time"
 
+	"gopkg.in/mgo.v2/bson"
+
 	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/validate"
+	"github.com/bearded-web/bearded/services"
 )
 
 type StatusEntity struct {
@@ -35,6 +42,7 @@ func TransformHeader(he []*HeaderMyEntity) http.Header {
 
 type HttpMyEntity struct {
 	Status string `json:"status"`
+	Proto  string `json:"proto,omitempty"`
 	// The problem with header is that map isn't supported in swagger api
 	// So we transform map[string][]string to []struct{key:string,values:[]string}
 	Header []*HeaderMyEntity `json:"header"`
@@ -47,6 +55,7 @@ func (he *HttpMyEntity) Transform() *issue.HttpEntity {
 	}
 	dst := &issue.HttpEntity{
 		Status: he.Status,
+		Proto:  he.Proto,
 		Body:   he.Body,
 		Header: TransformHeader(he.Header),
 	}
@@ -60,6 +69,9 @@ type HttpTransactionEntity struct {
 	Method   string        `json:"method"`
 	Request  *HttpMyEntity `json:"request,omitempty"`
 	Response *HttpMyEntity `json:"response,omitempty"`
+
+	RawRequest  string `json:"rawRequest,omitempty" description:"raw http request, it's parsed to the request field"`
+	RawResponse string `json:"rawResponse,omitempty" description:"raw http response, it's parsed to the response field"`
 }
 
 type VectorEntity struct {
@@ -82,6 +94,9 @@ func (v *VectorEntity) Transform() *issue.Vector {
 			Method:   tr.Method,
 			Request:  tr.Request.Transform(),
 			Response: tr.Response.Transform(),
+
+			RawRequest:  tr.RawRequest,
+			RawResponse: tr.RawResponse,
 		}
 
 		vec.HttpTransactions = append(vec.HttpTransactions, httpTr)
@@ -90,41 +105,79 @@ func (v *VectorEntity) Transform() *issue.Vector {
 }
 
 type IssueEntity struct {
-	Summary    *string            `json:"summary,omitempty" creating:"nonzero,min=3,max=120"`
+	Summary    *string            `json:"summary,omitempty" creating:"nonzero" validate:"min=3,max=120"`
 	VulnType   *int               `json:"vulnType,omitempty" bson:"vulnType" description:"vulnerability type from vulndb"`
 	Severity   *issue.Severity    `json:"severity,omitempty" description:"one of [high medium low info]"`
+	Confidence *issue.Confidence  `json:"confidence,omitempty" description:"one of [certain firm tentative], null resets it"`
 	References []*issue.Reference `json:"references,omitempty" bson:"references" description:"information about vulnerability"`
 	Desc       *string            `json:"desc,omitempty"`
 	Vector     *VectorEntity      `json:"vector,omitempty"`
+
+	Cve         []string `json:"cve,omitempty" description:"related CVE identifiers"`
+	Remediation *string  `json:"remediation,omitempty"`
 }
 
 type TargetIssueEntity struct {
-	Target string `json:"target,omitempty" creating:"nonzero,bsonId"`
+	Target   string          `json:"target,omitempty" creating:"nonzero,bsonId"`
+	Exposure *issue.Exposure `json:"exposure,omitempty"`
+	Template string          `json:"template,omitempty" description:"id of project issue template, empty fields are taken from it"`
+
+	Fields map[string]interface{} `json:"fields,omitempty" description:"values of custom project fields, null removes the value"`
+	Team   *string                `json:"team,omitempty" description:"id of the project team responsible for the issue, null unassigns the team"`
 
 	StatusEntity `json:",inline"`
 	IssueEntity  `json:",inline"`
 }
 
+func (e *TargetIssueEntity) Validate() error {
+	errs := validate.Errors{}
+	if e.Confidence != nil && !e.Confidence.IsValid() {
+		errs.Add("confidence", validate.CodeInvalid, "should be one of [certain|firm|tentative]")
+	}
+	return errs.Err()
+}
+
 func isValidSeverity(sev issue.Severity) bool {
-	if sev == issue.SeverityHigh ||
-		sev == issue.SeverityMedium ||
-		sev == issue.SeverityLow ||
-		sev == issue.SeverityInfo {
+	return sev.IsValid()
+}
 
-		return true
+// fill fields which are not set in entity from the template
+func applyTemplate(raw *TargetIssueEntity, t *project.IssueTemplate) {
+	if raw.Summary == nil && t.Summary != "" {
+		raw.Summary = &t.Summary
+	}
+	if raw.Desc == nil && t.Desc != "" {
+		raw.Desc = &t.Desc
+	}
+	if raw.Remediation == nil && t.Remediation != "" {
+		raw.Remediation = &t.Remediation
+	}
+	if raw.Severity == nil && t.Severity != "" {
+		raw.Severity = &t.Severity
+	}
+	if raw.VulnType == nil && t.VulnType != 0 {
+		raw.VulnType = &t.VulnType
+	}
+	if raw.References == nil && t.References != nil {
+		raw.References = t.References
 	}
-	return false
 }
 
 // Update all fields for dst with entity data if they present
-// Return true if target should rebuild summary for issues
-func updateTargetIssue(raw *TargetIssueEntity, dst *issue.TargetIssue) bool {
-	rebuildSummary := false
+func updateTargetIssue(raw *TargetIssueEntity, dst *issue.TargetIssue) {
 	if raw.Summary != nil {
 		dst.Summary = *raw.Summary
 	}
 	if raw.Desc != nil {
 		dst.Desc = *raw.Desc
+		dst.Enriched = removeField(dst.Enriched, manager.EnrichedDesc)
+	}
+	if raw.Remediation != nil {
+		dst.Remediation = *raw.Remediation
+		dst.Enriched = removeField(dst.Enriched, manager.EnrichedRemediation)
+	}
+	if raw.Cve != nil {
+		dst.Cve = raw.Cve
 	}
 	if raw.References != nil {
 		dst.References = raw.References
@@ -139,27 +192,99 @@ func updateTargetIssue(raw *TargetIssueEntity, dst *issue.TargetIssue) bool {
 		dst.Confirmed = *raw.Confirmed
 	}
 	if raw.False != nil {
-		rebuildSummary = true
 		dst.False = *raw.False
 	}
 	if raw.Resolved != nil {
-		rebuildSummary = true
-		dst.Resolved = *raw.Resolved
 		if *raw.Resolved {
+			dst.Resolved = true
 			dst.ResolvedAt = time.Now()
-		} else {
+		} else if !dst.Reopen() {
 			dst.ResolvedAt = time.Time{}
 		}
 	}
 	if raw.Muted != nil {
-		rebuildSummary = true
 		dst.Muted = *raw.Muted
 	}
+	if raw.Exposure != nil {
+		dst.Exposure = *raw.Exposure
+	}
 	if raw.Severity != nil {
 		if isValidSeverity(*raw.Severity) {
-			rebuildSummary = true
 			dst.Severity = *raw.Severity
 		}
 	}
-	return rebuildSummary
+	if raw.Confidence != nil && raw.Confidence.IsValid() {
+		dst.Confidence = *raw.Confidence
+	}
+}
+
+// resetTargetIssue clears optional fields which are null in the body, updateTargetIssue skips them
+func resetTargetIssue(mask services.Mask, raw *TargetIssueEntity, dst *issue.TargetIssue) {
+	if mask.Has("desc") && raw.Desc == nil {
+		dst.Desc = ""
+		dst.Enriched = removeField(dst.Enriched, manager.EnrichedDesc)
+	}
+	if mask.Has("remediation") && raw.Remediation == nil {
+		dst.Remediation = ""
+		dst.Enriched = removeField(dst.Enriched, manager.EnrichedRemediation)
+	}
+	if mask.Has("cve") && raw.Cve == nil {
+		dst.Cve = nil
+	}
+	if mask.Has("confidence") && raw.Confidence == nil {
+		dst.Confidence = ""
+	}
+	if mask.Has("references") && raw.References == nil {
+		dst.References = nil
+	}
+}
+
+// setTeam assigns the issue to the project team, null team in the body unassigns it.
+// Returns true if the team is changed.
+func setTeam(mask services.Mask, raw *TargetIssueEntity, p *project.Project, dst *issue.TargetIssue) (bool, error) {
+	if raw.Team == nil {
+		if mask.Has("team") && dst.Team != "" {
+			dst.Team = ""
+			return true, nil
+		}
+		return false, nil
+	}
+	if !bson.IsObjectIdHex(*raw.Team) {
+		return false, fmt.Errorf("team should be bson id")
+	}
+	t := p.GetTeam(bson.ObjectIdHex(*raw.Team))
+	if t == nil {
+		return false, fmt.Errorf("team should be a project team")
+	}
+	changed := dst.Team != t.Id
+	dst.Team = t.Id
+	return changed, nil
+}
+
+// mergeFields returns current custom field values updated with the new ones
+func mergeFields(current, update map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for name, val := range current {
+		result[name] = val
+	}
+	for name, val := range update {
+		result[name] = val
+	}
+	return result
+}
+
+// fields edited by user aren't enriched anymore
+func removeField(fields []string, field string) []string {
+	result := []string{}
+	for _, f := range fields {
+		if f != field {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+type BulkDeleteEntity struct {
+	Project bson.ObjectId   `json:"project"`
+	Issues  []bson.ObjectId `json:"issues" description:"ids of project issues, max 1000" validate:"nonzero,max=1000"`
 }
@@ -0,0 +1,70 @@
+package issue
+
+import (
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/approval"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/validate"
+	"github.com/bearded-web/bearded/services"
+)
+
+func (s *IssueService) RegisterBulk(ws *restful.WebService) {
+	r := ws.POST("bulk-delete").To(s.bulkDelete)
+	addDefaults(r)
+	r.Doc("bulkDelete")
+	r.Operation("bulkDelete")
+	r.Notes("Authorization required. Delete issues of the project. " +
+		"If approvals are enabled, issues are deleted after confirmation by a second admin")
+	r.Reads(BulkDeleteEntity{})
+	r.Writes(approval.Approval{})
+	r.Do(services.Returns(
+		http.StatusNoContent,
+		http.StatusAccepted))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+}
+
+func (s *IssueService) bulkDelete(req *restful.Request, resp *restful.Response) {
+	raw := &BulkDeleteEntity{}
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
+	}
+	if err := validate.Entity(raw); err != nil {
+		services.NewValidationErr(err).Write(resp)
+		return
+	}
+	u := filters.GetUser(req)
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if sErr := services.Must(services.HasProjectIdPermission(mgr, u, raw.Project)); sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+	ids, err := mgr.Issues.Ids(bson.M{"_id": bson.M{"$in": raw.Issues}, "project": raw.Project})
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	if len(ids) == 0 {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("there are no such issues in the project"))
+		return
+	}
+
+	services.SubmitApproval(resp, mgr, &approval.Approval{
+		Action:    approval.ActionIssuesDelete,
+		Project:   raw.Project,
+		Issues:    ids,
+		Requester: u.Id,
+	})
+}
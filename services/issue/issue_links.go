@@ -0,0 +1,150 @@
+package issue
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/services"
+)
+
+const ParamLinked = "linkedId"
+
+type LinkEntity struct {
+	Type  issue.LinkType `json:"type" description:"one of [duplicates duplicated-by caused-by causes blocks blocked-by related-to]"`
+	Issue string         `json:"issue" description:"id of the issue from the same project"`
+}
+
+func (s *IssueService) RegisterLinks(ws *restful.WebService) {
+	r := ws.GET(fmt.Sprintf("{%s}/links", ParamId)).To(s.TakeIssue(s.links))
+	addDefaults(r)
+	r.Doc("links")
+	r.Operation("links")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Writes(issue.LinkList{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/links", ParamId)).To(s.TakeIssue(s.linksCreate))
+	addDefaults(r)
+	r.Doc("linksCreate")
+	r.Operation("linksCreate")
+	r.Notes("Authorization required. The inverse link is added to the linked issue, " +
+		"like caused-by for causes")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Reads(LinkEntity{})
+	r.Writes(issue.Link{})
+	r.Do(services.Returns(
+		http.StatusCreated,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusConflict))
+	ws.Route(r)
+
+	r = ws.DELETE(fmt.Sprintf("{%s}/links/{%s}", ParamId, ParamLinked)).To(s.TakeIssue(s.linksDelete))
+	addDefaults(r)
+	r.Doc("linksDelete")
+	r.Operation("linksDelete")
+	r.Notes("Authorization required. All links between issues are removed from both of them")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(ParamLinked, "linked issue id"))
+	r.Do(services.Returns(
+		http.StatusNoContent,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+}
+
+func (s *IssueService) links(_ *restful.Request, resp *restful.Response, obj *issue.TargetIssue) {
+	results := obj.Links
+	if results == nil {
+		results = []*issue.Link{}
+	}
+	resp.WriteEntity(&issue.LinkList{
+		Meta:    pagination.Meta{Count: len(results)},
+		Results: results,
+	})
+}
+
+func (s *IssueService) linksCreate(req *restful.Request, resp *restful.Response, obj *issue.TargetIssue) {
+	raw := &LinkEntity{}
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
+	}
+	if !raw.Type.IsValid() {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Type should be one of [duplicates duplicated-by caused-by causes blocks blocked-by related-to]"))
+		return
+	}
+	if !s.IsId(raw.Issue) {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Issue is wrong"))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	other, err := mgr.Issues.GetById(mgr.ToId(raw.Issue))
+	if err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Issue not found"))
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	// links across projects would leak issues to members of the other project
+	if other.Id == obj.Id || other.Project != obj.Project {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Issue should be another issue from the same project"))
+		return
+	}
+	for _, l := range obj.GetLinks(other.Id) {
+		if l.Type == raw.Type {
+			resp.WriteServiceError(http.StatusConflict, services.DuplicateErr)
+			return
+		}
+	}
+
+	link, err := mgr.Issues.Link(obj, raw.Type, other, filters.GetUser(req).Id)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteHeader(http.StatusCreated)
+	resp.WriteEntity(link)
+}
+
+func (s *IssueService) linksDelete(req *restful.Request, resp *restful.Response, obj *issue.TargetIssue) {
+	id := req.PathParameter(ParamLinked)
+	if !s.IsId(id) {
+		resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	other := mgr.ToId(id)
+	if len(obj.GetLinks(other)) == 0 {
+		resp.WriteErrorString(http.StatusNotFound, "Not found")
+		return
+	}
+	if err := mgr.Issues.Unlink(obj, other); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,161 @@
+package issue
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/comment"
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/services"
+)
+
+// RenderTemplate is rendered with IssuePage data
+const RenderTemplate = "issue/render"
+
+// evidence bigger than that is cut in the printed page
+const maxEvidenceSize = 64 * 1024
+
+type IssuePage struct {
+	Issue     *issue.TargetIssue
+	Target    string
+	Project   string
+	Evidence  []*PageEvidence
+	Comments  []*PageComment
+	Generated time.Time
+	Locale    string
+}
+
+type PageEvidence struct {
+	Title    string
+	Request  string
+	Response string
+}
+
+type PageComment struct {
+	Author  string
+	Created time.Time
+	Text    string
+}
+
+func (s *IssueService) RegisterRender(ws *restful.WebService) {
+	r := ws.GET(fmt.Sprintf("{%s}/render", ParamId)).To(s.TakeIssue(s.render))
+	addDefaults(r)
+	r.Doc("render")
+	r.Operation("render")
+	r.Notes("Authorization required. Self-contained page with description, evidence, " +
+		"comments and remediation for printing or emailing the issue")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.QueryParameter("format", "one of [html], html by default"))
+	r.Produces("text/html")
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+}
+
+func (s *IssueService) render(req *restful.Request, resp *restful.Response, obj *issue.TargetIssue) {
+	if format := req.QueryParameter("format"); format != "" && format != "html" {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("format should be one of [html]"))
+		return
+	}
+	if s.Template == nil {
+		logrus.Error("Template renderer isn't configured")
+		resp.WriteServiceError(http.StatusInternalServerError, services.AppErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	page, err := issuePage(mgr, obj)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	page.Locale = filters.Locale(req, s.I18n)
+
+	// render to the buffer first, so template errors don't produce half of the page
+	buf := &bytes.Buffer{}
+	if err := s.Template.Render(buf, RenderTemplate, page); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.AppErr)
+		return
+	}
+	resp.AddHeader("Content-Type", "text/html; charset=utf-8")
+	resp.AddHeader("Content-Disposition", fmt.Sprintf("inline; filename=\"issue-%s.html\"", obj.Id.Hex()))
+	// the page is opened outside of the frontend, nothing except inline styles is allowed there
+	resp.AddHeader("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'")
+	resp.Write(buf.Bytes())
+}
+
+func issuePage(mgr *manager.Manager, obj *issue.TargetIssue) (*IssuePage, error) {
+	page := &IssuePage{
+		Issue:     obj,
+		Generated: time.Now().UTC(),
+	}
+	if tgt, err := mgr.Targets.GetById(obj.Target); err == nil {
+		page.Target = tgt.Addr()
+		obj.RenderVars(tgt.IssueVars())
+	} else if !mgr.IsNotFound(err) {
+		return nil, err
+	}
+	if proj, err := mgr.Projects.GetById(obj.Project); err == nil {
+		page.Project = proj.Name
+	} else if !mgr.IsNotFound(err) {
+		return nil, err
+	}
+
+	if obj.Vector != nil {
+		for i, tr := range obj.Vector.HttpTransactions {
+			if tr == nil {
+				continue
+			}
+			ev := &PageEvidence{Title: fmt.Sprintf("#%d %s %s", i, tr.Method, tr.Url)}
+			if dump, err := tr.DumpRequest(); err == nil {
+				ev.Request = cut(string(dump))
+			}
+			if dump, err := tr.DumpResponse(); err == nil && dump != nil {
+				ev.Response = cut(string(dump))
+			}
+			page.Evidence = append(page.Evidence, ev)
+		}
+	}
+
+	comments, _, err := mgr.Comments.FilterBy(&manager.CommentFltr{Type: comment.Issue, Link: obj.Id},
+		manager.Opts{Sort: []string{"created"}})
+	if err != nil {
+		return nil, err
+	}
+	authors := map[string]string{}
+	for _, c := range comments {
+		author, ok := authors[c.Owner.Hex()]
+		if !ok {
+			if u, err := mgr.Users.GetById(c.Owner); err == nil {
+				author = u.Nickname
+				if author == "" {
+					author = u.Email
+				}
+			}
+			authors[c.Owner.Hex()] = author
+		}
+		page.Comments = append(page.Comments, &PageComment{Author: author, Created: c.Created, Text: c.Text})
+	}
+	return page, nil
+}
+
+func cut(s string) string {
+	if len(s) <= maxEvidenceSize {
+		return s
+	}
+	return s[:maxEvidenceSize] + "\n... truncated"
+}
@@ -3,23 +3,37 @@ package issue
 import (
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/emicklei/go-restful"
 	"github.com/facebookgo/stackerr"
 	"gopkg.in/mgo.v2/bson"
-	"gopkg.in/validator.v2"
 
 	"github.com/bearded-web/bearded/models/comment"
 	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/notification"
+	"github.com/bearded-web/bearded/models/plan"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/models/target"
 	"github.com/bearded-web/bearded/pkg/filters"
 	"github.com/bearded-web/bearded/pkg/fltr"
 	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/notify"
 	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/pkg/validate"
 	"github.com/bearded-web/bearded/services"
 )
 
-const ParamId = "issueId"
+const (
+	ParamId       = "issueId"
+	ParamEvidence = "n"
+
+	fieldParamPrefix = "field."
+)
 
 type IssueService struct {
 	*services.BaseService
@@ -29,7 +43,7 @@ type IssueService struct {
 func New(base *services.BaseService) *IssueService {
 	return &IssueService{
 		BaseService: base,
-		sorter:      fltr.NewSorter("created", "updated"),
+		sorter:      manager.IssueSorter,
 	}
 }
 
@@ -57,9 +71,18 @@ func (s *IssueService) Register(container *restful.Container) {
 	r.Operation("list")
 	s.SetParams(r, fltr.GetParams(ws, manager.IssueFltr{}))
 	r.Param(ws.QueryParameter("search", "search by summary and description"))
+	r.Param(ws.QueryParameter("flapping", fmt.Sprintf("issues which were reopened %d times or more", issue.FlappingReopens)).DataType("boolean"))
+	r.Param(ws.QueryParameter("field.{name}", "filter by custom field value, modifiers _gt, _gte, _lt, _lte and _in are "+
+		"supported like field.{name}_gte. Project is required"))
+	r.Param(ws.QueryParameter("count", "one of [exact|cached|none], cached by default. Use none to skip counting, then count is approximate"))
+	r.Param(rawVarsQueryParam(ws))
 	r.Param(s.sorter.Param())
 	r.Param(s.Paginator.SkipParam())
 	r.Param(s.Paginator.LimitParam())
+	r.Notes(fmt.Sprintf("With Accept: %s all issues of the query are streamed as newline-delimited json, "+
+		"one issue per line, without pagination unless limit is set. If the stream is broken, "+
+		`the last line is {"error": "..."}`, MimeNdjson))
+	r.Produces(restful.MIME_JSON, MimeNdjson)
 	r.Writes(issue.TargetIssueList{})
 	r.Do(services.Returns(http.StatusOK))
 	ws.Route(r)
@@ -81,7 +104,12 @@ func (s *IssueService) Register(container *restful.Container) {
 	addDefaults(r)
 	r.Doc("get")
 	r.Operation("get")
+	r.Notes("Authorization required. Variables in description and remediation are substituted: " +
+		"{{target.host}}, {{target.addr}}, {{target.type}}, {{vector.url}}, {{vector.host}} and {{vector.path}}. " +
+		"They are substituted in graphql, sync, rendered pages and servicenow tickets too, summaries aren't templated, " +
+		"so notifications and delivered csv reports are sent as is")
 	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(rawVarsQueryParam(ws))
 	r.Writes(issue.TargetIssue{})
 	r.Do(services.Returns(
 		http.StatusOK,
@@ -89,30 +117,138 @@ func (s *IssueService) Register(container *restful.Container) {
 	r.Do(services.ReturnsE(http.StatusBadRequest))
 	ws.Route(r)
 
-	r = ws.PUT(fmt.Sprintf("{%s}", ParamId)).To(s.TakeIssue(s.update))
+	for _, r := range services.UpdateRoutes(ws, fmt.Sprintf("{%s}", ParamId), s.TakeIssue(s.update)) {
+		r.Param(ws.PathParameter(ParamId, ""))
+		r.Writes(issue.TargetIssue{})
+		r.Reads(TargetIssueEntity{})
+		r.Do(services.Returns(
+			http.StatusOK,
+			http.StatusNotFound))
+		r.Do(services.ReturnsE(http.StatusBadRequest))
+		ws.Route(r)
+	}
+
+	r = ws.DELETE(fmt.Sprintf("{%s}", ParamId)).To(s.TakeIssue(s.delete))
 	// docs
-	r.Doc("update")
-	r.Operation("update")
+	r.Doc("delete")
+	r.Operation("delete")
+	r.Notes("Move the issue to the trash, it could be restored for 30 days")
 	r.Param(ws.PathParameter(ParamId, ""))
-	r.Writes(issue.TargetIssue{})
-	r.Reads(TargetIssueEntity{})
+	r.Do(services.Returns(
+		http.StatusNoContent,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.GET(fmt.Sprintf("{%s}/evidence", ParamId)).To(s.TakeIssue(s.evidence))
+	addDefaults(r)
+	r.Doc("evidence")
+	r.Operation("evidence")
+	r.Notes("Authorization required. Http transactions from the issue vector")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Writes([]issue.HttpTransaction{})
 	r.Do(services.Returns(
 		http.StatusOK,
 		http.StatusNotFound))
 	r.Do(services.ReturnsE(http.StatusBadRequest))
 	ws.Route(r)
 
-	r = ws.DELETE(fmt.Sprintf("{%s}", ParamId)).To(s.TakeIssue(s.delete))
-	// docs
-	r.Doc("delete")
-	r.Operation("delete")
+	r = ws.GET(fmt.Sprintf("{%s}/evidence/{%s}/raw", ParamId, ParamEvidence)).To(s.TakeIssue(s.evidenceRaw))
+	addDefaults(r)
+	r.Doc("evidenceRaw")
+	r.Operation("evidenceRaw")
+	r.Notes("Authorization required. Download http request and response in wire format")
 	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(ParamEvidence, "transaction index in the vector, starting from 0").DataType("integer"))
+	r.Produces("text/plain")
 	r.Do(services.Returns(
-		http.StatusNoContent,
+		http.StatusOK,
 		http.StatusNotFound))
 	r.Do(services.ReturnsE(http.StatusBadRequest))
 	ws.Route(r)
 
+	r = ws.GET(fmt.Sprintf("{%s}/evidence/{%s}/curl", ParamId, ParamEvidence)).To(s.TakeIssue(s.evidenceCurl))
+	addDefaults(r)
+	r.Doc("evidenceCurl")
+	r.Operation("evidenceCurl")
+	r.Notes("Authorization required. Command to reproduce the request")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(ParamEvidence, "transaction index in the vector, starting from 0").DataType("integer"))
+	r.Param(ws.QueryParameter("format", "one of [curl|http], curl by default"))
+	r.Produces("text/plain")
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	s.RegisterRender(ws)
+	s.RegisterLinks(ws)
+	s.RegisterWorklogs(ws)
+	s.RegisterBulk(ws)
+	s.RegisterTrash(ws)
+
+	r = ws.POST(fmt.Sprintf("{%s}/retest", ParamId)).To(s.TakeIssue(s.retest))
+	addDefaults(r)
+	r.Doc("retest")
+	r.Operation("retest")
+	r.Notes("Authorization required. Create a scan with the plugin which reported the issue. " +
+		"The result is saved to issue.retest when the scan is done")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Writes(issue.TargetIssue{})
+	r.Do(services.Returns(
+		http.StatusCreated,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusConflict))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/acknowledge", ParamId)).To(s.TakeIssue(s.acknowledge))
+	addDefaults(r)
+	r.Doc("acknowledge")
+	r.Operation("acknowledge")
+	r.Notes("Authorization required. Acknowledged issues aren't escalated anymore")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Writes(issue.TargetIssue{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusConflict))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/vote", ParamId)).To(s.TakeIssue(s.vote))
+	addDefaults(r)
+	r.Doc("vote")
+	r.Operation("vote")
+	r.Notes("Authorization required. Upvote the issue as important for business, sort issues by -votes to see the most wanted ones")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Writes(issue.TargetIssue{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusConflict))
+	ws.Route(r)
+
+	r = ws.DELETE(fmt.Sprintf("{%s}/vote", ParamId)).To(s.TakeIssue(s.unvote))
+	addDefaults(r)
+	r.Doc("unvote")
+	r.Operation("unvote")
+	r.Notes("Authorization required. Remove the vote of the current user")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Writes(issue.TargetIssue{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusConflict))
+	ws.Route(r)
+
 	r = ws.GET(fmt.Sprintf("{%s}/comments", ParamId)).To(s.TakeIssue(s.comments))
 	r.Doc("comments")
 	r.Operation("comments")
@@ -160,16 +296,15 @@ func (s *IssueService) create(req *restful.Request, resp *restful.Response) {
 		)
 		return
 	}
-	// validate other fields
-	if err := validator.WithTag("creating").Validate(raw); err != nil {
+	if raw.Template != "" && !s.IsId(raw.Template) {
 		resp.WriteServiceError(
 			http.StatusBadRequest,
-			services.NewBadReq("Validation error: %s", err.Error()),
+			services.NewBadReq("Template is wrong"),
 		)
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	// load target and project
@@ -192,11 +327,45 @@ func (s *IssueService) create(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
+	p, err := mgr.Projects.GetById(t.Project)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	if raw.Template != "" {
+		tmpl := p.GetTemplate(mgr.ToId(raw.Template))
+		if tmpl == nil {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Template not found"))
+			return
+		}
+		applyTemplate(raw, tmpl)
+	}
+
+	// validate other fields
+	if err := validate.Entity(raw, "creating"); err != nil {
+		services.NewValidationErr(err).Write(resp)
+		return
+	}
+
 	newObj := &issue.TargetIssue{
 		Project: t.Project,
 		Target:  t.Id,
 	}
 	updateTargetIssue(raw, newObj)
+	if err := newObj.Vector.Normalize(); err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
+	if newObj.Fields, err = p.CheckFields(raw.Fields); err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Fields: %s", err.Error()))
+		return
+	}
+	teamAssigned, err := setTeam(nil, raw, p, newObj)
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
 	newObj.AddUserReportActivity(u.Id)
 
 	obj, err := mgr.Issues.Create(newObj)
@@ -211,19 +380,12 @@ func (s *IssueService) create(req *restful.Request, resp *restful.Response) {
 		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
 		return
 	}
-	// TODO (m0sth8): extract to worker
-	func(mgr *manager.Manager) {
-		tgt, err := mgr.Targets.GetById(obj.Target)
-		if err != nil {
-			logrus.Error(stackerr.Wrap(err))
-			return
-		}
-		err = mgr.Targets.UpdateSummary(tgt)
-		if err != nil {
-			logrus.Error(stackerr.Wrap(err))
-			return
-		}
-	}(s.Manager())
+	if _, err := mgr.Feed.AddIssue(obj, "", u.Id); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+	}
+	if teamAssigned {
+		s.notifyTeam(mgr, p, obj, u.Id)
+	}
 	resp.WriteHeader(http.StatusCreated)
 	resp.WriteEntity(obj)
 }
@@ -236,7 +398,13 @@ func (s *IssueService) list(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	mgr := s.Manager()
+	stream := acceptsNdjson(req)
+	if stream {
+		// errors before the stream are written as json
+		resp.SetRequestAccepts(restful.MIME_JSON)
+	}
+
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	if search := req.QueryParameter("search"); search != "" {
@@ -244,13 +412,55 @@ func (s *IssueService) list(req *restful.Request, resp *restful.Response) {
 			query["$text"] = &bson.M{"$search": search}
 		}
 	}
+	if p := req.QueryParameter("flapping"); p != "" {
+		flapping, err := strconv.ParseBool(p)
+		if err != nil {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("flapping should be true or false"))
+			return
+		}
+		query["reopened"] = manager.FlappingQuery(flapping)
+	}
 
 	skip, limit := s.Paginator.Parse(req)
+	sort, err := s.sorter.ParseStrict(req)
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
 
 	opt := manager.Opts{
-		Sort:  s.sorter.Parse(req),
+		Sort:  sort,
 		Limit: limit,
 		Skip:  skip,
+		Count: manager.ParseCountMode(req.QueryParameter("count"), manager.CountCached),
+	}
+	var p *project.Project
+	if projectId, ok := query["project"].(bson.ObjectId); ok {
+		p, err = mgr.Projects.GetById(projectId)
+		if err != nil && !mgr.IsNotFound(err) {
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+		if err != nil {
+			p = nil
+		}
+	}
+	// use project default sort for issues if it's not set in request
+	if p != nil && len(opt.Sort) == 0 {
+		opt.Sort = s.sorter.ParseString(p.IssueSort)
+	}
+	if err := fieldsQuery(req, p, query); err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
+	if stream {
+		// everything is streamed unless the limit is set explicitly
+		if req.QueryParameter(s.Paginator.LimitName) == "" {
+			opt.Limit = 0
+		}
+		s.stream(req, resp, mgr, query, opt)
+		return
 	}
 	results, count, err := mgr.Issues.FilterByQuery(query, opt)
 	if err != nil {
@@ -258,19 +468,33 @@ func (s *IssueService) list(req *restful.Request, resp *restful.Response) {
 		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
 		return
 	}
+	if err := renderVars(req, mgr, results...); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
 	previous, next := s.Paginator.Urls(req, skip, limit, count)
 	result := &issue.TargetIssueList{
 		Meta: pagination.Meta{
-			Count:    count,
-			Previous: previous,
-			Next:     next,
+			Count:       count,
+			Previous:    previous,
+			Next:        next,
+			Approximate: opt.Count == manager.CountHasMore,
 		},
 		Results: results,
 	}
 	resp.WriteEntity(result)
 }
 
-func (s *IssueService) get(_ *restful.Request, resp *restful.Response, issueObj *issue.TargetIssue) {
+func (s *IssueService) get(req *restful.Request, resp *restful.Response, issueObj *issue.TargetIssue) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if err := renderVars(req, mgr, issueObj); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
 	resp.WriteEntity(issueObj)
 }
 
@@ -279,16 +503,47 @@ func (s *IssueService) update(req *restful.Request, resp *restful.Response, issu
 
 	raw := &TargetIssueEntity{}
 
-	if err := req.ReadEntity(raw); err != nil {
+	mask, err := services.ReadMasked(req, raw)
+	if err != nil {
 		logrus.Error(stackerr.Wrap(err))
 		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
 		return
 	}
-	mgr := s.Manager()
+	if err := validate.Entity(raw); err != nil {
+		services.NewValidationErr(err).Write(resp)
+		return
+	}
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	// update issue object from entity
-	rebuildSummary := updateTargetIssue(raw, issueObj)
+	updateTargetIssue(raw, issueObj)
+	resetTargetIssue(mask, raw, issueObj)
+	if err := issueObj.Vector.Normalize(); err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
+	var p *project.Project
+	if raw.Fields != nil || mask.Has("team") {
+		if p, err = mgr.Projects.GetById(issueObj.Project); err != nil {
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+	}
+	if raw.Fields != nil {
+		if issueObj.Fields, err = p.CheckFields(mergeFields(issueObj.Fields, raw.Fields)); err != nil {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Fields: %s", err.Error()))
+			return
+		}
+	}
+	teamAssigned := false
+	if p != nil {
+		if teamAssigned, err = setTeam(mask, raw, p, issueObj); err != nil {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+			return
+		}
+	}
 
 	if err := mgr.Issues.Update(issueObj); err != nil {
 		if mgr.IsNotFound(err) {
@@ -305,36 +560,250 @@ func (s *IssueService) update(req *restful.Request, resp *restful.Response, issu
 		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
 		return
 	}
-	if rebuildSummary {
-		// TODO (m0sth8): extract to worker
-		func(mgr *manager.Manager) {
-			tgt, err := mgr.Targets.GetById(issueObj.Target)
-			if err != nil {
-				logrus.Error(stackerr.Wrap(err))
-				return
-			}
-			err = mgr.Targets.UpdateSummary(tgt)
-			if err != nil {
-				logrus.Error(stackerr.Wrap(err))
-				return
-			}
-		}(s.Manager())
+	if teamAssigned && issueObj.Team != "" {
+		s.notifyTeam(mgr, p, issueObj, filters.GetUser(req).Id)
 	}
 
+	if err := renderVars(req, mgr, issueObj); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+	}
 	resp.WriteHeader(http.StatusOK)
 	resp.WriteEntity(issueObj)
 }
 
-func (s *IssueService) delete(_ *restful.Request, resp *restful.Response, obj *issue.TargetIssue) {
-	mgr := s.Manager()
+func (s *IssueService) delete(req *restful.Request, resp *restful.Response, obj *issue.TargetIssue) {
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
-	mgr.Issues.Remove(obj)
+	if err := mgr.Issues.Trash(obj, filters.GetUser(req).Id); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
 	resp.WriteHeader(http.StatusNoContent)
 }
 
-func (s *IssueService) comments(_ *restful.Request, resp *restful.Response, obj *issue.TargetIssue) {
-	mgr := s.Manager()
+func (s *IssueService) evidence(_ *restful.Request, resp *restful.Response, obj *issue.TargetIssue) {
+	results := []*issue.HttpTransaction{}
+	if obj.Vector != nil && obj.Vector.HttpTransactions != nil {
+		results = obj.Vector.HttpTransactions
+	}
+	resp.WriteEntity(results)
+}
+
+func (s *IssueService) evidenceRaw(req *restful.Request, resp *restful.Response, obj *issue.TargetIssue) {
+	n, tr, sErr := takeTransaction(req, obj)
+	if sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+	dump, err := tr.DumpRequest()
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.AppErr)
+		return
+	}
+	respDump, err := tr.DumpResponse()
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.AppErr)
+		return
+	}
+	if respDump != nil {
+		dump = append(dump, "\r\n\r\n"...)
+		dump = append(dump, respDump...)
+	}
+	resp.AddHeader("Content-Type", "text/plain; charset=utf-8")
+	resp.AddHeader("Content-Disposition", fmt.Sprintf("attachment; filename=\"issue-%s-%d.txt\"", obj.Id.Hex(), n))
+	resp.Write(dump)
+}
+
+func (s *IssueService) evidenceCurl(req *restful.Request, resp *restful.Response, obj *issue.TargetIssue) {
+	n, tr, sErr := takeTransaction(req, obj)
+	if sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+	var (
+		out      string
+		err      error
+		filename string
+	)
+	switch format := req.QueryParameter("format"); format {
+	case "", "curl":
+		out, err = tr.Curl()
+		filename = fmt.Sprintf("issue-%s-%d.sh", obj.Id.Hex(), n)
+	case "http":
+		out, err = tr.HttpFile()
+		filename = fmt.Sprintf("issue-%s-%d.http", obj.Id.Hex(), n)
+	default:
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("format should be one of [curl|http]"))
+		return
+	}
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.AppErr)
+		return
+	}
+	resp.AddHeader("Content-Type", "text/plain; charset=utf-8")
+	resp.AddHeader("Content-Disposition", fmt.Sprintf("inline; filename=\"%s\"", filename))
+	resp.Write([]byte(out))
+}
+
+func (s *IssueService) retest(req *restful.Request, resp *restful.Response, obj *issue.TargetIssue) {
+	if obj.Retest != nil && obj.Retest.Status == issue.RetestPending {
+		resp.WriteServiceError(http.StatusConflict, services.NewError(services.CodeDuplicate, "retest is in progress"))
+		return
+	}
+	rep := obj.LastScanReport()
+	if rep == nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("issue wasn't reported by a scan"))
+		return
+	}
+	u := filters.GetUser(req)
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	origin, err := mgr.Scans.GetById(rep.Scan)
+	if err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("original scan not found"))
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	originSess := origin.GetSession(rep.ScanSession)
+	if originSess == nil || originSess.Step == nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("original scan session not found"))
+		return
+	}
+
+	// narrow the scan down to the issue url
+	tgtAddr := origin.Conf.Target
+	if obj.Vector != nil && obj.Vector.Url != "" {
+		tgtAddr = obj.Vector.Url
+	}
+	step := *originSess.Step
+	conf := plan.Conf{}
+	if step.Conf != nil {
+		conf = *step.Conf
+	}
+	conf.Target = tgtAddr
+	step.Conf = &conf
+
+	now := time.Now().UTC()
+	sc := &scan.Scan{
+		Status:  scan.StatusCreated,
+		Owner:   u.Id,
+		Plan:    origin.Plan,
+		Project: obj.Project,
+		Target:  obj.Target,
+		Retest:  obj.Id,
+		Conf: scan.ScanConf{
+			Target: tgtAddr,
+		},
+		Sessions: []*scan.Session{
+			&scan.Session{
+				Id:     mgr.NewId(),
+				Step:   &step,
+				Plugin: originSess.Plugin,
+				Status: scan.StatusCreated,
+				Dates: scan.Dates{
+					Created: &now,
+					Updated: &now,
+				},
+			},
+		},
+	}
+	sc, err = mgr.Scans.Create(sc)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	obj.Retest = &issue.Retest{
+		Status:      issue.RetestPending,
+		User:        u.Id,
+		Scan:        sc.Id,
+		ScanSession: sc.Sessions[0].Id,
+		Created:     now,
+	}
+	if err := mgr.Issues.Update(obj); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	// put scan to queue
+	s.Scheduler().AddScan(sc)
+	if _, err := mgr.Feed.AddScan(sc); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+	}
+
+	resp.WriteHeader(http.StatusCreated)
+	resp.WriteEntity(obj)
+}
+
+func (s *IssueService) acknowledge(req *restful.Request, resp *restful.Response, obj *issue.TargetIssue) {
+	if obj.Acknowledged != nil {
+		resp.WriteServiceError(http.StatusConflict, services.NewError(services.CodeDuplicate, "issue is already acknowledged"))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	obj.Acknowledge(filters.GetUser(req).Id)
+	if err := mgr.Issues.Update(obj); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(obj)
+}
+
+func (s *IssueService) vote(req *restful.Request, resp *restful.Response, obj *issue.TargetIssue) {
+	s.setVote(req, resp, obj, true)
+}
+
+func (s *IssueService) unvote(req *restful.Request, resp *restful.Response, obj *issue.TargetIssue) {
+	s.setVote(req, resp, obj, false)
+}
+
+func (s *IssueService) setVote(req *restful.Request, resp *restful.Response, obj *issue.TargetIssue, up bool) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	u := filters.GetUser(req)
+	changed, err := mgr.Issues.Vote(obj, u.Id, up)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	if !changed {
+		msg := "issue isn't voted by the user"
+		if up {
+			msg = "issue is already voted by the user"
+		}
+		resp.WriteServiceError(http.StatusConflict, services.NewError(services.CodeDuplicate, msg))
+		return
+	}
+	obj, err = mgr.Issues.GetById(obj.Id)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(obj)
+}
+
+func (s *IssueService) comments(req *restful.Request, resp *restful.Response, obj *issue.TargetIssue) {
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	results, count, err := mgr.Comments.FilterBy(&manager.CommentFltr{Type: comment.Issue, Link: obj.Id})
@@ -373,7 +842,7 @@ func (s *IssueService) commentsAdd(req *restful.Request, resp *restful.Response,
 		Text:  ent.Text,
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	obj, err := mgr.Comments.Create(raw)
@@ -389,6 +858,71 @@ func (s *IssueService) commentsAdd(req *restful.Request, resp *restful.Response,
 
 // Helpers
 
+// fieldsQuery adds filters by custom fields from params like field.ticket or field.score_gte,
+// values are parsed by the project field type
+func fieldsQuery(req *restful.Request, p *project.Project, query bson.M) error {
+	for param, values := range req.Request.URL.Query() {
+		if !strings.HasPrefix(param, fieldParamPrefix) || len(values) == 0 || values[0] == "" {
+			continue
+		}
+		if p == nil {
+			return fmt.Errorf("param %s: project is required for field filters", param)
+		}
+		name, modifier := strings.TrimPrefix(param, fieldParamPrefix), ""
+		if i := strings.LastIndex(name, fltr.ModifierDivider); i >= 0 {
+			name, modifier = name[:i], name[i+1:]
+		}
+		f := p.GetFieldByName(name)
+		if f == nil {
+			return fmt.Errorf("param %s: unknown field %s", param, name)
+		}
+		raw := []string{values[0]}
+		switch modifier {
+		case "", "gt", "gte", "lt", "lte":
+		case "in":
+			raw = strings.Split(values[0], ",")
+		default:
+			return fmt.Errorf("param %s: unknown modifier %s", param, modifier)
+		}
+		vals := []interface{}{}
+		for _, r := range raw {
+			v, err := f.Value(r)
+			if err != nil {
+				return fmt.Errorf("param %s: %v", param, err)
+			}
+			vals = append(vals, v)
+		}
+		key := "fields." + name
+		switch modifier {
+		case "":
+			query[key] = vals[0]
+		case "in":
+			query[key] = bson.M{"$in": vals}
+		default:
+			// several range modifiers for the same field are combined
+			cond, _ := query[key].(bson.M)
+			if cond == nil {
+				cond = bson.M{}
+			}
+			cond["$"+modifier] = vals[0]
+			query[key] = cond
+		}
+	}
+	return nil
+}
+
+// take http transaction by the index from path parameter
+func takeTransaction(req *restful.Request, obj *issue.TargetIssue) (int, *issue.HttpTransaction, *services.ErrResp) {
+	n, err := strconv.Atoi(req.PathParameter(ParamEvidence))
+	if err != nil || n < 0 {
+		return 0, nil, &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewBadReq("evidence index should be a non negative integer")}
+	}
+	if obj.Vector == nil || n >= len(obj.Vector.HttpTransactions) {
+		return 0, nil, &services.ErrResp{Code: http.StatusNotFound, Err: fmt.Errorf("evidence %d not found", n)}
+	}
+	return n, obj.Vector.HttpTransactions[n], nil
+}
+
 func (s *IssueService) TakeIssue(fn func(*restful.Request,
 	*restful.Response, *issue.TargetIssue)) restful.RouteFunction {
 	return func(req *restful.Request, resp *restful.Response) {
@@ -398,7 +932,7 @@ func (s *IssueService) TakeIssue(fn func(*restful.Request,
 			return
 		}
 
-		mgr := s.Manager()
+		mgr := s.RequestManager(req)
 		defer mgr.Close()
 
 		obj, err := mgr.Issues.GetById(mgr.ToId(id))
@@ -423,3 +957,37 @@ func (s *IssueService) TakeIssue(fn func(*restful.Request,
 		fn(req, resp, obj)
 	}
 }
+
+// notifyTeam tells members of the issue team that the issue is assigned to them, except the user who assigned it
+func (s *IssueService) notifyTeam(mgr *manager.Manager, p *project.Project, obj *issue.TargetIssue, by bson.ObjectId) {
+	t := p.GetTeam(obj.Team)
+	if t == nil {
+		return
+	}
+	go s.Notifier.NotifyProject(p, &notify.Notification{
+		Event:       notification.EventIssueAssigned,
+		Subject:     fmt.Sprintf("Issue is assigned to %s", t.Name),
+		Text:        fmt.Sprintf("Issue %q is assigned to the team %s", obj.Summary, t.Name),
+		Link:        fmt.Sprintf("/#/issue/%s", obj.Id.Hex()),
+		Environment: target.Environment(obj.Environment),
+	})
+	for _, userId := range t.Members {
+		if userId == by {
+			continue
+		}
+		u, err := mgr.Users.GetById(userId)
+		if err != nil {
+			logrus.Error(stackerr.Wrap(err))
+			continue
+		}
+		n := &notify.Notification{
+			Event:   notification.EventIssueAssigned,
+			User:    u,
+			Subject: fmt.Sprintf("Issue is assigned to %s", t.Name),
+			Text:    fmt.Sprintf("Issue %q is assigned to your team %s", obj.Summary, t.Name),
+			Link:    fmt.Sprintf("/#/issue/%s", obj.Id.Hex()),
+			Issue:   obj.Id,
+		}
+		go s.Notifier.Notify(n)
+	}
+}
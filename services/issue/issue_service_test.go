@@ -4,25 +4,32 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/emicklei/go-restful"
 	c "github.com/smartystreets/goconvey/convey"
+	"golang.org/x/net/context"
 	"gopkg.in/mgo.v2/bson"
 
 	"github.com/bearded-web/bearded/models/issue"
 	"github.com/bearded-web/bearded/models/project"
 	"github.com/bearded-web/bearded/models/target"
 	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/models/worklog"
 	"github.com/bearded-web/bearded/pkg/config"
 	"github.com/bearded-web/bearded/pkg/email"
 	"github.com/bearded-web/bearded/pkg/filters"
 	"github.com/bearded-web/bearded/pkg/manager"
 	"github.com/bearded-web/bearded/pkg/scheduler"
+	"github.com/bearded-web/bearded/pkg/summary"
+	"github.com/bearded-web/bearded/pkg/template"
 	"github.com/bearded-web/bearded/pkg/tests"
 	"github.com/bearded-web/bearded/pkg/utils"
 	"github.com/bearded-web/bearded/services"
@@ -105,9 +112,108 @@ func TestTargetIssues(t *testing.T) {
 			c.So(targetIssue.False, c.ShouldEqual, false)
 			c.So(targetIssue.Resolved, c.ShouldEqual, false)
 			c.So(targetIssue.Severity, c.ShouldEqual, issue.SeverityInfo)
-			err = testMgr.Targets.UpdateSummary(targetObj)
+			err = testMgr.Summaries.Rebuild(targetObj.Id)
 			c.So(err, c.ShouldBeNil)
 
+			c.Convey("Log time", func() {
+				day := time.Date(2015, 6, 1, 0, 0, 0, 0, time.UTC)
+				for _, d := range []int{30, 90} {
+					_, err := testMgr.Worklogs.Create(&worklog.Worklog{
+						Issue:    targetIssue.Id,
+						Project:  projectObj.Id,
+						User:     u.Id,
+						Duration: d,
+						Date:     day,
+					})
+					c.So(err, c.ShouldBeNil)
+				}
+				effort, err := testMgr.Worklogs.Effort(projectObj.Id, day, day.AddDate(0, 0, 1))
+				c.So(err, c.ShouldBeNil)
+				c.So(effort.Duration, c.ShouldEqual, 120)
+				c.So(len(effort.Users), c.ShouldEqual, 1)
+				c.So(effort.Issues[0].Id, c.ShouldEqual, targetIssue.Id)
+				c.So(effort.Issues[0].Entries, c.ShouldEqual, 2)
+
+				effort, err = testMgr.Worklogs.Effort(projectObj.Id, day.AddDate(0, 0, 1), time.Time{})
+				c.So(err, c.ShouldBeNil)
+				c.So(effort.Duration, c.ShouldEqual, 0)
+			})
+
+			c.Convey("Link issues", func() {
+				other, err := testMgr.Issues.Create(&issue.TargetIssue{
+					Target:  targetObj.Id,
+					Project: projectObj.Id,
+					Issue:   issue.Issue{Summary: "root cause"},
+				})
+				c.So(err, c.ShouldBeNil)
+				_, err = testMgr.Issues.Link(targetIssue, issue.LinkCausedBy, other, u.Id)
+				c.So(err, c.ShouldBeNil)
+
+				other, err = testMgr.Issues.GetById(other.Id)
+				c.So(err, c.ShouldBeNil)
+				c.So(len(other.Links), c.ShouldEqual, 1)
+				c.So(other.Links[0].Type, c.ShouldEqual, issue.LinkCauses)
+				c.So(other.Links[0].Issue, c.ShouldEqual, targetIssue.Id)
+
+				c.So(testMgr.Issues.Remove(other), c.ShouldBeNil)
+				targetIssue, err = testMgr.Issues.GetById(targetIssue.Id)
+				c.So(err, c.ShouldBeNil)
+				c.So(len(targetIssue.Links), c.ShouldEqual, 0)
+			})
+
+			c.Convey("Trash and restore issue", func() {
+				req, err := http.NewRequest("DELETE", fmt.Sprintf("%s/api/v1/issues/%s", ts.URL, targetIssue.Id.Hex()), nil)
+				c.So(err, c.ShouldBeNil)
+				res, err := http.DefaultClient.Do(req)
+				c.So(err, c.ShouldBeNil)
+				c.So(res.StatusCode, c.ShouldEqual, http.StatusNoContent)
+
+				_, err = testMgr.Issues.GetById(targetIssue.Id)
+				c.So(testMgr.IsNotFound(err), c.ShouldBeTrue)
+				targetObj2, err := builtTarget(targetObj.Id)
+				c.So(err, c.ShouldBeNil)
+				c.So(targetObj2.SummaryReport.Issues[issue.SeverityInfo], c.ShouldEqual, 0)
+
+				trashed, count, err := testMgr.Issues.FilterTrash(bson.M{"project": projectObj.Id})
+				c.So(err, c.ShouldBeNil)
+				c.So(count, c.ShouldEqual, 1)
+				c.So(trashed[0].RemovedBy, c.ShouldEqual, u.Id)
+
+				res, err = http.Post(fmt.Sprintf("%s/api/v1/issues/trash/%s/restore", ts.URL, targetIssue.Id.Hex()), "application/json", nil)
+				c.So(err, c.ShouldBeNil)
+				c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+				_, err = testMgr.Issues.GetById(targetIssue.Id)
+				c.So(err, c.ShouldBeNil)
+				_, count, err = testMgr.Issues.FilterTrash(bson.M{"project": projectObj.Id})
+				c.So(err, c.ShouldBeNil)
+				c.So(count, c.ShouldEqual, 0)
+			})
+
+			c.Convey("Vote for issue", func() {
+				voteUrl := fmt.Sprintf("%s/api/v1/issues/%s/vote", ts.URL, targetIssue.Id.Hex())
+				res, err := http.Post(voteUrl, "application/json", nil)
+				c.So(err, c.ShouldBeNil)
+				c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+				res, err = http.Post(voteUrl, "application/json", nil)
+				c.So(err, c.ShouldBeNil)
+				c.So(res.StatusCode, c.ShouldEqual, http.StatusConflict)
+
+				voted, err := testMgr.Issues.GetById(targetIssue.Id)
+				c.So(err, c.ShouldBeNil)
+				c.So(voted.Votes, c.ShouldEqual, 1)
+				c.So(voted.HasVoter(u.Id), c.ShouldBeTrue)
+				c.So(voted.Updated.Unix(), c.ShouldEqual, targetIssue.Updated.Unix())
+
+				req, err := http.NewRequest("DELETE", voteUrl, nil)
+				c.So(err, c.ShouldBeNil)
+				res, err = http.DefaultClient.Do(req)
+				c.So(err, c.ShouldBeNil)
+				c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+				voted, err = testMgr.Issues.GetById(targetIssue.Id)
+				c.So(err, c.ShouldBeNil)
+				c.So(voted.Votes, c.ShouldEqual, 0)
+			})
+
 			c.Convey("Get list of all issues", func() {
 				res, issues := getIssues(t, ts.URL, nil)
 				c.Convey("Response should have a new issue", func() {
@@ -116,12 +222,28 @@ func TestTargetIssues(t *testing.T) {
 					c.So(len(issues.Results), c.ShouldEqual, 1)
 					c.So(issues.Results[0].Id, c.ShouldEqual, targetIssue.Id)
 				})
-				targetObj2, err := testMgr.Targets.GetById(targetObj.Id)
+				targetObj2, err := builtTarget(targetObj.Id)
 				c.So(err, c.ShouldBeNil)
 				c.So(len(targetObj2.SummaryReport.Issues), c.ShouldEqual, 1)
 				c.So(targetObj2.SummaryReport.Issues[issue.SeverityInfo], c.ShouldEqual, 1)
 			})
 
+			c.Convey("Stream list of all issues", func() {
+				req, err := http.NewRequest("GET", ts.URL+"/api/v1/issues", nil)
+				c.So(err, c.ShouldBeNil)
+				req.Header.Set("Accept", MimeNdjson)
+				res, err := http.DefaultClient.Do(req)
+				c.So(err, c.ShouldBeNil)
+				defer res.Body.Close()
+				c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+				c.So(res.Header.Get("Content-Type"), c.ShouldEqual, MimeNdjson)
+				dec := json.NewDecoder(res.Body)
+				streamed := &issue.TargetIssue{}
+				c.So(dec.Decode(streamed), c.ShouldBeNil)
+				c.So(streamed.Id, c.ShouldEqual, targetIssue.Id)
+				c.So(dec.Decode(&issue.TargetIssue{}), c.ShouldEqual, io.EOF)
+			})
+
 			c.Convey("Set issue status confirmed", func() {
 				res, issueObj := updateIssue(t, ts.URL, testMgr.FromId(targetIssue.Id), &TargetIssueEntity{
 					StatusEntity: StatusEntity{
@@ -148,7 +270,7 @@ func TestTargetIssues(t *testing.T) {
 				c.So(issueObj.False, c.ShouldEqual, false)
 				c.So(issueObj.Resolved, c.ShouldEqual, false)
 
-				targetObj2, err := testMgr.Targets.GetById(targetObj.Id)
+				targetObj2, err := builtTarget(targetObj.Id)
 				c.So(err, c.ShouldBeNil)
 				c.So(len(targetObj2.SummaryReport.Issues), c.ShouldEqual, 0)
 			})
@@ -165,7 +287,7 @@ func TestTargetIssues(t *testing.T) {
 				c.So(issueObj.False, c.ShouldEqual, true)
 				c.So(issueObj.Resolved, c.ShouldEqual, false)
 
-				targetObj2, err := testMgr.Targets.GetById(targetObj.Id)
+				targetObj2, err := builtTarget(targetObj.Id)
 				c.So(err, c.ShouldBeNil)
 				c.So(len(targetObj2.SummaryReport.Issues), c.ShouldEqual, 0)
 			})
@@ -182,7 +304,7 @@ func TestTargetIssues(t *testing.T) {
 				c.So(issueObj.False, c.ShouldEqual, false)
 				c.So(issueObj.Resolved, c.ShouldEqual, true)
 
-				targetObj2, err := testMgr.Targets.GetById(targetObj.Id)
+				targetObj2, err := builtTarget(targetObj.Id)
 				c.So(err, c.ShouldBeNil)
 				c.So(len(targetObj2.SummaryReport.Issues), c.ShouldEqual, 0)
 			})
@@ -197,7 +319,7 @@ func TestTargetIssues(t *testing.T) {
 				c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
 				c.So(issueObj.Severity, c.ShouldEqual, issue.SeverityHigh)
 
-				targetObj2, err := testMgr.Targets.GetById(targetObj.Id)
+				targetObj2, err := builtTarget(targetObj.Id)
 				c.So(err, c.ShouldBeNil)
 				c.So(len(targetObj2.SummaryReport.Issues), c.ShouldEqual, 1)
 				c.So(targetObj2.SummaryReport.Issues[issue.SeverityHigh], c.ShouldEqual, 1)
@@ -295,11 +417,48 @@ func TestTargetIssues(t *testing.T) {
 				c.So(hTr.Response.Status, c.ShouldEqual, "200 OK")
 				c.So(hTr.Response.Body.Content, c.ShouldEqual, "response content")
 
-				targetObj2, err := testMgr.Targets.GetById(targetObj.Id)
+				targetObj2, err := builtTarget(targetObj.Id)
 				c.So(err, c.ShouldBeNil)
 				c.So(len(targetObj2.SummaryReport.Issues), c.ShouldEqual, 1)
 				c.So(targetObj2.SummaryReport.Issues[issue.SeverityInfo], c.ShouldEqual, 2)
 			})
+			c.Convey("Create issue from template", func() {
+				tmpl := &project.IssueTemplate{
+					Id:          bson.NewObjectId(),
+					Name:        "xss",
+					Summary:     "Cross site scripting",
+					Severity:    issue.SeverityMedium,
+					Desc:        "## Steps to reproduce",
+					Remediation: "Escape user input",
+				}
+				projectObj.Templates = []*project.IssueTemplate{tmpl}
+				c.So(testMgr.Projects.Update(projectObj), c.ShouldBeNil)
+
+				res, issueObj, err := createIssue(t, ts.URL, &TargetIssueEntity{
+					IssueEntity: IssueEntity{
+						Desc: utils.StringP("custom desc"),
+					},
+					Target:   testMgr.FromId(targetObj.Id),
+					Template: tmpl.Id.Hex(),
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				c.So(res.StatusCode, c.ShouldEqual, http.StatusCreated)
+				c.So(issueObj.Summary, c.ShouldEqual, "Cross site scripting")
+				c.So(issueObj.Severity, c.ShouldEqual, issue.SeverityMedium)
+				c.So(issueObj.Desc, c.ShouldEqual, "custom desc")
+				c.So(issueObj.Remediation, c.ShouldEqual, "Escape user input")
+
+				res, _, err = createIssue(t, ts.URL, &TargetIssueEntity{
+					Target:   testMgr.FromId(targetObj.Id),
+					Template: bson.NewObjectId().Hex(),
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				c.So(res.StatusCode, c.ShouldEqual, http.StatusBadRequest)
+			})
 			// TODO (m0sth8): test errors for creation
 		})
 
@@ -412,3 +571,48 @@ func getServiceError(t *testing.T, res *http.Response) *restful.ServiceError {
 	}
 	return e
 }
+
+func TestRenderTemplate(t *testing.T) {
+	tmpl := template.New(&template.Opts{Directory: "../../extra/templates"})
+	page := &IssuePage{
+		Issue: &issue.TargetIssue{
+			Issue: issue.Issue{
+				Summary:     "<script>alert(1)</script>",
+				Severity:    issue.SeverityHigh,
+				Desc:        "reflected xss",
+				Remediation: "escape output",
+				References:  []*issue.Reference{{Url: "javascript:alert(1)", Title: "bad"}},
+			},
+		},
+		Target:   "http://example.com",
+		Evidence: []*PageEvidence{{Title: "#0 GET /", Request: "GET / HTTP/1.1"}},
+		Comments: []*PageComment{{Author: "m0sth8", Text: "<b>confirmed</b>"}},
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Render(buf, RenderTemplate, page); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, s := range []string{"&lt;script&gt;", "reflected xss", "escape output", "GET / HTTP/1.1", "&lt;b&gt;confirmed&lt;/b&gt;", "http://example.com"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("%q not found in the page", s)
+		}
+	}
+	for _, s := range []string{"<script>", "javascript:", "<b>"} {
+		if strings.Contains(out, s) {
+			t.Errorf("%q should be sanitized", s)
+		}
+	}
+}
+
+// builtTarget returns the target with the summary built like in background after issue changes
+func builtTarget(id bson.ObjectId) (*target.Target, error) {
+	if err := summary.New(testMgr).Check(context.Background()); err != nil {
+		return nil, err
+	}
+	obj, err := testMgr.Targets.GetById(id)
+	if err != nil {
+		return nil, err
+	}
+	return obj, testMgr.Summaries.Fill(obj)
+}
@@ -0,0 +1,78 @@
+package issue
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/pkg/manager"
+)
+
+// MimeNdjson is newline-delimited json, issue lists are streamed in it
+const MimeNdjson = "application/x-ndjson"
+
+// issues are written and flushed by batches, variables of a batch are rendered at once
+const streamBatch = 100
+
+func acceptsNdjson(req *restful.Request) bool {
+	for _, accept := range strings.Split(req.HeaderParameter("Accept"), ",") {
+		if strings.TrimSpace(strings.Split(accept, ";")[0]) == MimeNdjson {
+			return true
+		}
+	}
+	return false
+}
+
+// StreamError is the last line of the broken stream, the status can't be changed after the first line
+type StreamError struct {
+	Error string `json:"error"`
+}
+
+// stream writes issues of the query from the cursor, so whole projects are exported with one request
+func (s *IssueService) stream(req *restful.Request, resp *restful.Response, mgr *manager.Manager, query bson.M, opt manager.Opts) {
+	resp.Header().Set("Content-Type", MimeNdjson)
+	resp.ResponseWriter.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(resp)
+	flusher, _ := resp.ResponseWriter.(http.Flusher)
+	batch := make([]*issue.TargetIssue, 0, streamBatch)
+	write := func() error {
+		if err := renderVars(req, mgr, batch...); err != nil {
+			return err
+		}
+		for _, obj := range batch {
+			if err := enc.Encode(obj); err != nil {
+				return err
+			}
+		}
+		batch = batch[:0]
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	}
+	err := mgr.Issues.Stream(query, opt, func(obj *issue.TargetIssue) error {
+		batch = append(batch, obj)
+		if len(batch) < streamBatch {
+			return nil
+		}
+		return write()
+	})
+	if err == nil {
+		err = write()
+	}
+	if err != nil {
+		if mgr.IsCanceled(err) {
+			logrus.Warnf("Issue stream is stopped: %s", err)
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		enc.Encode(&StreamError{Error: "Stream is broken"})
+	}
+}
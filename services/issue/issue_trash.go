@@ -0,0 +1,165 @@
+package issue
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/services"
+)
+
+func (s *IssueService) RegisterTrash(ws *restful.WebService) {
+	r := ws.GET("trash").To(s.trash)
+	addDefaults(r)
+	r.Doc("trash")
+	r.Operation("trash")
+	r.Notes("Removed issues of the project, they are kept for 30 days")
+	r.Param(ws.QueryParameter("project", "project id").Required(true))
+	r.Param(ws.QueryParameter("target", "target id"))
+	r.Param(s.Paginator.SkipParam())
+	r.Param(s.Paginator.LimitParam())
+	r.Writes(issue.TrashedIssueList{})
+	r.Do(services.Returns(http.StatusOK))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("trash/{%s}/restore", ParamId)).To(s.TakeTrashed(s.trashRestore))
+	addDefaults(r)
+	r.Doc("trashRestore")
+	r.Operation("trashRestore")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Writes(issue.TargetIssue{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusConflict))
+	ws.Route(r)
+
+	r = ws.DELETE(fmt.Sprintf("trash/{%s}", ParamId)).To(s.TakeTrashed(s.trashPurge))
+	addDefaults(r)
+	r.Doc("trashPurge")
+	r.Operation("trashPurge")
+	r.Notes("Remove the issue permanently")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusNoContent,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+}
+
+func (s *IssueService) trash(req *restful.Request, resp *restful.Response) {
+	query := bson.M{}
+	for _, name := range []string{"project", "target"} {
+		p := req.QueryParameter(name)
+		if p == "" {
+			continue
+		}
+		if !s.IsId(p) {
+			resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+			return
+		}
+		query[name] = bson.ObjectIdHex(p)
+	}
+	projectId, ok := query["project"].(bson.ObjectId)
+	if !ok {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("project is required"))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if sErr := services.Must(services.HasProjectIdPermission(mgr, filters.GetUser(req), projectId)); sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+	skip, limit := s.Paginator.Parse(req)
+	results, count, err := mgr.Issues.FilterTrash(query,
+		manager.Opts{Sort: []string{"-removed"}, Skip: skip, Limit: limit})
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	previous, next := s.Paginator.Urls(req, skip, limit, count)
+	resp.WriteEntity(&issue.TrashedIssueList{
+		Meta:    pagination.Meta{Count: count, Previous: previous, Next: next},
+		Results: results,
+	})
+}
+
+func (s *IssueService) trashRestore(req *restful.Request, resp *restful.Response, trashed *issue.TrashedIssue) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	obj, err := mgr.Issues.Restore(trashed)
+	if err != nil {
+		if mgr.IsDup(err) {
+			resp.WriteServiceError(http.StatusConflict,
+				services.NewError(services.CodeDuplicate, "the same issue was reported after removal"))
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(obj)
+}
+
+func (s *IssueService) trashPurge(req *restful.Request, resp *restful.Response, trashed *issue.TrashedIssue) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if err := mgr.Issues.Purge(trashed); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+func (s *IssueService) TakeTrashed(fn func(*restful.Request,
+	*restful.Response, *issue.TrashedIssue)) restful.RouteFunction {
+	return func(req *restful.Request, resp *restful.Response) {
+		id := req.PathParameter(ParamId)
+		if !s.IsId(id) {
+			resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+			return
+		}
+
+		mgr := s.RequestManager(req)
+		defer mgr.Close()
+
+		obj, err := mgr.Issues.GetTrashed(mgr.ToId(id))
+		if err != nil {
+			if mgr.IsNotFound(err) {
+				resp.WriteErrorString(http.StatusNotFound, "Not found")
+				return
+			}
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+
+		sErr := services.Must(services.HasProjectIdPermission(mgr, filters.GetUser(req), obj.Project))
+		if sErr != nil {
+			sErr.Write(resp)
+			return
+		}
+
+		mgr.Close()
+
+		fn(req, resp, obj)
+	}
+}
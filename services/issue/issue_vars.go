@@ -0,0 +1,27 @@
+package issue
+
+import (
+	"strconv"
+
+	"github.com/emicklei/go-restful"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/pkg/manager"
+)
+
+// query parameter to get description and remediation with variables, like for editing
+const rawVarsParam = "raw"
+
+func rawVarsQueryParam(ws *restful.WebService) *restful.Parameter {
+	return ws.QueryParameter(rawVarsParam, "true to return description and remediation without "+
+		"substitution of variables like {{target.host}} and {{vector.url}}").DataType("boolean")
+}
+
+// renderVars substitutes target and vector variables in description and remediation of issues,
+// unless raw text is requested. Issues with variables rendered mustn't be saved.
+func renderVars(req *restful.Request, mgr *manager.Manager, issues ...*issue.TargetIssue) error {
+	if raw, _ := strconv.ParseBool(req.QueryParameter(rawVarsParam)); raw {
+		return nil
+	}
+	return mgr.Issues.RenderVars(issues...)
+}
@@ -0,0 +1,221 @@
+package issue
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/worklog"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/pkg/validate"
+	"github.com/bearded-web/bearded/services"
+)
+
+const ParamWorklog = "worklogId"
+
+type WorklogEntity struct {
+	Duration int        `json:"duration" description:"spent minutes, a day max" validate:"min=1,max=1440"`
+	Note     string     `json:"note,omitempty" validate:"max=1000"`
+	Date     *time.Time `json:"date,omitempty" description:"when the work was done, now by default"`
+}
+
+func (s *IssueService) RegisterWorklogs(ws *restful.WebService) {
+	r := ws.GET(fmt.Sprintf("{%s}/worklogs", ParamId)).To(s.TakeIssue(s.worklogs))
+	addDefaults(r)
+	r.Doc("worklogs")
+	r.Operation("worklogs")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(s.Paginator.SkipParam())
+	r.Param(s.Paginator.LimitParam())
+	r.Writes(worklog.WorklogList{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/worklogs", ParamId)).To(s.TakeIssue(s.worklogsCreate))
+	addDefaults(r)
+	r.Doc("worklogsCreate")
+	r.Operation("worklogsCreate")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Reads(WorklogEntity{})
+	r.Writes(worklog.Worklog{})
+	r.Do(services.Returns(
+		http.StatusCreated,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.PUT(fmt.Sprintf("{%s}/worklogs/{%s}", ParamId, ParamWorklog)).To(s.TakeIssue(s.takeWorklog(s.worklogsUpdate)))
+	addDefaults(r)
+	r.Doc("worklogsUpdate")
+	r.Operation("worklogsUpdate")
+	r.Notes("Authorization required. Only the author can change the worklog")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(ParamWorklog, ""))
+	r.Reads(WorklogEntity{})
+	r.Writes(worklog.Worklog{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.DELETE(fmt.Sprintf("{%s}/worklogs/{%s}", ParamId, ParamWorklog)).To(s.TakeIssue(s.takeWorklog(s.worklogsDelete)))
+	addDefaults(r)
+	r.Doc("worklogsDelete")
+	r.Operation("worklogsDelete")
+	r.Notes("Authorization required. Only the author can remove the worklog")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(ParamWorklog, ""))
+	r.Do(services.Returns(
+		http.StatusNoContent,
+		http.StatusNotFound))
+	ws.Route(r)
+}
+
+func (s *IssueService) worklogs(req *restful.Request, resp *restful.Response, obj *issue.TargetIssue) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	skip, limit := s.Paginator.Parse(req)
+	results, count, err := mgr.Worklogs.FilterBy(&manager.WorklogFltr{Issue: obj.Id},
+		manager.Opts{Sort: []string{"-date"}, Skip: skip, Limit: limit})
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	previous, next := s.Paginator.Urls(req, skip, limit, count)
+	resp.WriteEntity(&worklog.WorklogList{
+		Meta:    pagination.Meta{Count: count, Previous: previous, Next: next},
+		Results: results,
+	})
+}
+
+func (s *IssueService) worklogsCreate(req *restful.Request, resp *restful.Response, obj *issue.TargetIssue) {
+	raw := &WorklogEntity{}
+	if sErr := readWorklog(req, raw); sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	w := &worklog.Worklog{
+		Issue:    obj.Id,
+		Project:  obj.Project,
+		User:     filters.GetUser(req).Id,
+		Duration: raw.Duration,
+		Note:     raw.Note,
+	}
+	if raw.Date != nil {
+		w.Date = raw.Date.UTC()
+	}
+	w, err := mgr.Worklogs.Create(w)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteHeader(http.StatusCreated)
+	resp.WriteEntity(w)
+}
+
+func (s *IssueService) worklogsUpdate(req *restful.Request, resp *restful.Response, obj *issue.TargetIssue, w *worklog.Worklog) {
+	raw := &WorklogEntity{}
+	if sErr := readWorklog(req, raw); sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	w.Duration = raw.Duration
+	w.Note = raw.Note
+	if raw.Date != nil {
+		w.Date = raw.Date.UTC()
+	}
+	if err := mgr.Worklogs.Update(w); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(w)
+}
+
+func (s *IssueService) worklogsDelete(req *restful.Request, resp *restful.Response, obj *issue.TargetIssue, w *worklog.Worklog) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if err := mgr.Worklogs.Remove(w); err != nil && !mgr.IsNotFound(err) {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+func readWorklog(req *restful.Request, raw *WorklogEntity) *services.ErrResp {
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.WrongEntityErr}
+	}
+	if err := validate.Entity(raw); err != nil {
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewValidationErr(err)}
+	}
+	if raw.Date != nil && raw.Date.After(time.Now().Add(time.Hour)) {
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewBadReq("Date shouldn't be in the future")}
+	}
+	return nil
+}
+
+// take the worklog of the issue which belongs to the current user
+func (s *IssueService) takeWorklog(fn func(*restful.Request, *restful.Response,
+	*issue.TargetIssue, *worklog.Worklog)) func(*restful.Request, *restful.Response, *issue.TargetIssue) {
+	return func(req *restful.Request, resp *restful.Response, obj *issue.TargetIssue) {
+		id := req.PathParameter(ParamWorklog)
+		if !s.IsId(id) {
+			resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+			return
+		}
+
+		mgr := s.RequestManager(req)
+		defer mgr.Close()
+
+		w, err := mgr.Worklogs.GetById(mgr.ToId(id))
+		if err != nil {
+			if mgr.IsNotFound(err) {
+				resp.WriteErrorString(http.StatusNotFound, "Not found")
+				return
+			}
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+		if w.Issue != obj.Id {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		if w.User != filters.GetUser(req).Id {
+			resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+			return
+		}
+		mgr.Close()
+
+		fn(req, resp, obj, w)
+	}
+}
@@ -1,7 +1,15 @@
 package me
 
+import "github.com/bearded-web/bearded/models/user"
+
 type ChangePasswordEntity struct {
 	Token string `json:"token,omitempty" description:"reset password token"`
 	Old   string `json:"old,omitempty"`
-	New   string `json:"new"`
+	New   string `json:"new" validate:"password"`
+}
+
+type SettingsEntity struct {
+	Timezone   string          `json:"timezone" description:"IANA timezone name, f.e Europe/Moscow"`
+	DateFormat user.DateFormat `json:"dateFormat" description:"one of [iso|eu|us]"`
+	Locale     string          `json:"locale" description:"one of supported locales from /api/v1/config, english if empty"`
 }
@@ -1,21 +1,35 @@
 package me
 
 import (
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/emicklei/go-restful"
 	"github.com/facebookgo/stackerr"
 
+	"github.com/bearded-web/bearded/models/file"
+	"github.com/bearded-web/bearded/models/inbox"
 	"github.com/bearded-web/bearded/models/me"
+	"github.com/bearded-web/bearded/models/notification"
+	"github.com/bearded-web/bearded/models/user"
 	"github.com/bearded-web/bearded/pkg/filters"
 	"github.com/bearded-web/bearded/pkg/fltr"
 	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/pagination"
 	"github.com/bearded-web/bearded/pkg/passlib/reset"
+	"github.com/bearded-web/bearded/pkg/thumbnail"
 	"github.com/bearded-web/bearded/pkg/validate"
 	"github.com/bearded-web/bearded/services"
 )
 
+// max size of uploaded avatar in bytes
+const AvatarMaxSize = 2 << 20
+
+// avatar thumbnail size used in the user avatar url
+const AvatarSize = 64
+
 type MeService struct {
 	*services.BaseService
 }
@@ -63,11 +77,123 @@ func (s *MeService) Register(container *restful.Container) {
 	addDefaults(r)
 	ws.Route(r)
 
+	r = ws.PUT("/settings").To(s.changeSettings)
+	r.Doc("changeSettings")
+	r.Operation("changeSettings")
+	r.Reads(SettingsEntity{})
+	r.Writes(user.User{}) // on the response
+	r.Do(services.Returns(http.StatusOK))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	addDefaults(r)
+	ws.Route(r)
+
+	r = ws.PUT("/out-of-office").To(s.setOutOfOffice)
+	r.Doc("setOutOfOffice")
+	r.Operation("setOutOfOffice")
+	r.Notes("Authorization required. During the period rules don't assign issues to the user " +
+		"and escalations notify the user teams instead")
+	r.Reads(user.OutOfOffice{})
+	r.Writes(user.User{}) // on the response
+	r.Do(services.Returns(http.StatusOK))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	addDefaults(r)
+	ws.Route(r)
+
+	r = ws.DELETE("/out-of-office").To(s.deleteOutOfOffice)
+	r.Doc("deleteOutOfOffice")
+	r.Operation("deleteOutOfOffice")
+	r.Writes(user.User{}) // on the response
+	r.Do(services.Returns(http.StatusOK))
+	addDefaults(r)
+	ws.Route(r)
+
+	r = ws.GET("/notifications").To(s.getNotifications)
+	r.Doc("getNotifications")
+	r.Operation("getNotifications")
+	r.Notes("Authorization required. Returns enabled channels for every event type")
+	r.Writes(notification.Preferences{}) // on the response
+	r.Do(services.Returns(http.StatusOK))
+	addDefaults(r)
+	ws.Route(r)
+
+	r = ws.PUT("/notifications").To(s.changeNotifications)
+	r.Doc("changeNotifications")
+	r.Operation("changeNotifications")
+	r.Notes("Authorization required. Events which aren't set get default channels")
+	r.Reads(notification.Preferences{})
+	r.Writes(notification.Preferences{}) // on the response
+	r.Do(services.Returns(http.StatusOK))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	addDefaults(r)
+	ws.Route(r)
+
+	r = ws.GET("/inbox").To(s.inboxList)
+	r.Doc("inboxList")
+	r.Operation("inboxList")
+	r.Notes("Authorization required. Personal notifications, the newest first")
+	s.SetParams(r, fltr.GetParams(ws, manager.InboxFltr{}))
+	r.Param(s.Paginator.SkipParam())
+	r.Param(s.Paginator.LimitParam())
+	r.Writes(inbox.ItemList{}) // on the response
+	r.Do(services.Returns(http.StatusOK))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	addDefaults(r)
+	ws.Route(r)
+
+	r = ws.GET("/inbox/unread").To(s.inboxUnread)
+	r.Doc("inboxUnread")
+	r.Operation("inboxUnread")
+	r.Writes(inbox.Unread{}) // on the response
+	r.Do(services.Returns(http.StatusOK))
+	addDefaults(r)
+	ws.Route(r)
+
+	r = ws.POST("/inbox/read").To(s.inboxReadAll)
+	r.Doc("inboxReadAll")
+	r.Operation("inboxReadAll")
+	r.Notes("Authorization required. Mark all items as read")
+	r.Writes(inbox.Unread{}) // on the response
+	r.Do(services.Returns(http.StatusOK))
+	addDefaults(r)
+	ws.Route(r)
+
+	r = ws.POST("/inbox/{item-id}/read").To(s.inboxRead)
+	r.Doc("inboxRead")
+	r.Operation("inboxRead")
+	r.Param(ws.PathParameter("item-id", ""))
+	r.Writes(inbox.Unread{}) // on the response
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	addDefaults(r)
+	ws.Route(r)
+
+	r = ws.PUT("/avatar").To(s.uploadAvatar)
+	r.Doc("uploadAvatar")
+	r.Operation("uploadAvatar")
+	r.Consumes("multipart/form-data")
+	r.Param(ws.FormParameter("file", "image to upload").DataType("File"))
+	r.Writes(user.User{}) // on the response
+	r.Do(services.Returns(http.StatusOK))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	addDefaults(r)
+	ws.Route(r)
+
+	r = ws.DELETE("/avatar").To(s.deleteAvatar)
+	r.Doc("deleteAvatar")
+	r.Operation("deleteAvatar")
+	r.Notes("Authorization required. Gravatar is used after deletion")
+	r.Writes(user.User{}) // on the response
+	r.Do(services.Returns(http.StatusOK))
+	addDefaults(r)
+	ws.Route(r)
+
 	container.Add(ws)
 }
 
 func (s *MeService) info(req *restful.Request, resp *restful.Response) {
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	u := filters.GetUser(req)
@@ -152,8 +278,8 @@ func (s *MeService) changePassword(req *restful.Request, resp *restful.Response)
 
 	}
 
-	if valid, reason := validate.Password(raw.New); !valid {
-		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("New password %s", reason))
+	if err := validate.Entity(raw); err != nil {
+		services.NewValidationErr(err).Write(resp)
 		return
 	}
 
@@ -165,7 +291,7 @@ func (s *MeService) changePassword(req *restful.Request, resp *restful.Response)
 	}
 	u.Password = pass
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	err = mgr.Users.Update(u)
@@ -176,3 +302,293 @@ func (s *MeService) changePassword(req *restful.Request, resp *restful.Response)
 	}
 	resp.WriteHeader(http.StatusOK)
 }
+
+func (s *MeService) changeSettings(req *restful.Request, resp *restful.Response) {
+	raw := &SettingsEntity{}
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
+	}
+	if raw.Timezone != "" {
+		if _, err := time.LoadLocation(raw.Timezone); err != nil {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("unknown timezone %s", raw.Timezone))
+			return
+		}
+	}
+	if raw.DateFormat != "" && !raw.DateFormat.IsValid() {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("unknown date format %s", raw.DateFormat))
+		return
+	}
+	if raw.Locale != "" && !s.I18n.Has(raw.Locale) {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("unsupported locale %s", raw.Locale))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	u := filters.GetUser(req)
+	u.Timezone = raw.Timezone
+	u.DateFormat = raw.DateFormat
+	u.Locale = raw.Locale
+
+	if err := mgr.Users.Update(u); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(u)
+}
+
+func (s *MeService) setOutOfOffice(req *restful.Request, resp *restful.Response) {
+	raw := &user.OutOfOffice{}
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
+	}
+	if err := validate.Entity(raw); err != nil {
+		services.NewValidationErr(err).Write(resp)
+		return
+	}
+	raw.From, raw.To = raw.From.UTC(), raw.To.UTC()
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	u := filters.GetUser(req)
+	u.OutOfOffice = raw
+	if err := mgr.Users.Update(u); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(u)
+}
+
+func (s *MeService) deleteOutOfOffice(req *restful.Request, resp *restful.Response) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	u := filters.GetUser(req)
+	u.OutOfOffice = nil
+	if err := mgr.Users.Update(u); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(u)
+}
+
+func (s *MeService) getNotifications(req *restful.Request, resp *restful.Response) {
+	u := filters.GetUser(req)
+	resp.WriteEntity(u.Notifications.Full())
+}
+
+func (s *MeService) changeNotifications(req *restful.Request, resp *restful.Response) {
+	raw := notification.Preferences{}
+	if err := req.ReadEntity(&raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
+	}
+	if err := validate.Entity(raw); err != nil {
+		services.NewValidationErr(err).Write(resp)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	u := filters.GetUser(req)
+	u.Notifications = raw
+	if err := mgr.Users.Update(u); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(u.Notifications.Full())
+}
+
+func (s *MeService) inboxList(req *restful.Request, resp *restful.Response) {
+	query, err := fltr.FromRequest(req, manager.InboxFltr{})
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
+	// users see only their own items
+	query["user"] = filters.GetUser(req).Id
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	skip, limit := s.Paginator.Parse(req)
+	opt := manager.Opts{
+		Sort:  []string{"-created"},
+		Limit: limit,
+		Skip:  skip,
+	}
+	results, count, err := mgr.Inbox.FilterByQuery(query, opt)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	previous, next := s.Paginator.Urls(req, skip, limit, count)
+	result := &inbox.ItemList{
+		Meta:    pagination.Meta{Count: count, Previous: previous, Next: next},
+		Results: results,
+	}
+	resp.WriteEntity(result)
+}
+
+func (s *MeService) inboxUnread(req *restful.Request, resp *restful.Response) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	s.writeUnread(mgr, filters.GetUser(req), resp)
+}
+
+func (s *MeService) inboxReadAll(req *restful.Request, resp *restful.Response) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	u := filters.GetUser(req)
+	if _, err := mgr.Inbox.MarkAllRead(u.Id); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	s.writeUnread(mgr, u, resp)
+}
+
+func (s *MeService) inboxRead(req *restful.Request, resp *restful.Response) {
+	itemId := req.PathParameter("item-id")
+	if !s.IsId(itemId) {
+		resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	u := filters.GetUser(req)
+	if err := mgr.Inbox.MarkRead(u.Id, mgr.ToId(itemId)); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	s.writeUnread(mgr, u, resp)
+}
+
+// write the number of unread items, so client could update a counter without additional request
+func (s *MeService) writeUnread(mgr *manager.Manager, u *user.User, resp *restful.Response) {
+	count, err := mgr.Inbox.UnreadCount(u.Id)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(inbox.Unread{Count: count})
+}
+
+func (s *MeService) uploadAvatar(req *restful.Request, resp *restful.Response) {
+	req.Request.Body = http.MaxBytesReader(resp.ResponseWriter, req.Request.Body, AvatarMaxSize)
+	f, header, err := req.Request.FormFile("file")
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Couldn't read file"))
+		return
+	}
+	defer f.Close()
+
+	contentType := header.Header.Get("Content-Type")
+	if !thumbnail.IsImage(contentType) {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("avatar should be png, jpeg or gif image"))
+		return
+	}
+	// dimensions are checked before thumbnails decode the whole image
+	if err := thumbnail.CheckSize(f); err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("avatar should be png, jpeg or gif image up to %d pixels", thumbnail.MaxPixels))
+		return
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.AppErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	meta, err := mgr.Files.Create(f, &file.Meta{
+		Name:        header.Filename,
+		ContentType: contentType,
+	})
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	u := filters.GetUser(req)
+	prev := u.AvatarFile
+	u.AvatarFile = meta.Id
+	u.Avatar = avatarUrl(meta)
+	if err := mgr.Users.Update(u); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	removeAvatar(mgr, prev)
+	resp.WriteEntity(u)
+}
+
+func (s *MeService) deleteAvatar(req *restful.Request, resp *restful.Response) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	u := filters.GetUser(req)
+	prev := u.AvatarFile
+	u.AvatarFile = ""
+	u.Avatar = "" // manager sets gravatar for empty avatar
+	if err := mgr.Users.Update(u); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	removeAvatar(mgr, prev)
+	resp.WriteEntity(u)
+}
+
+// removeAvatar removes the replaced avatar file with its thumbnails,
+// the user is already updated, so failures are only logged
+func removeAvatar(mgr *manager.Manager, id string) {
+	if id == "" {
+		return
+	}
+	f, err := mgr.Files.GetById(id)
+	if err != nil {
+		if !mgr.IsNotFound(err) {
+			logrus.Error(stackerr.Wrap(err))
+		}
+		return
+	}
+	f.Close()
+	if err := mgr.Files.Remove(f.Meta); err != nil && !mgr.IsNotFound(err) {
+		logrus.Error(stackerr.Wrap(err))
+	}
+}
+
+func avatarUrl(meta *file.Meta) string {
+	url := fmt.Sprintf("/api/v1/files/%s/download", meta.Id)
+	if meta.ThumbnailSize(AvatarSize) > 0 {
+		url = fmt.Sprintf("%s?size=%d", url, AvatarSize)
+	}
+	return url
+}
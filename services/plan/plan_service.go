@@ -2,13 +2,19 @@ package plan
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/emicklei/go-restful"
 	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2/bson"
 
 	"github.com/bearded-web/bearded/models/plan"
+	"github.com/bearded-web/bearded/models/plugin"
+	"github.com/bearded-web/bearded/models/target"
 	"github.com/bearded-web/bearded/pkg/filters"
 	"github.com/bearded-web/bearded/pkg/fltr"
 	"github.com/bearded-web/bearded/pkg/manager"
@@ -18,6 +24,13 @@ import (
 
 const ParamId = "plan-id"
 
+const (
+	MimeYaml = "application/x-yaml"
+
+	// DocumentMaxSize limits the size of imported plan document
+	DocumentMaxSize = 1 << 20
+)
+
 type PlanService struct {
 	*services.BaseService
 }
@@ -68,31 +81,71 @@ func (s *PlanService) Register(container *restful.Container) {
 	))
 	ws.Route(r)
 
-	r = ws.GET(fmt.Sprintf("{%s}", ParamId)).To(s.TakePlan(s.get))
+	r = ws.POST("import").To(s.importPlan)
 	addDefaults(r)
-	r.Doc("get")
-	r.Operation("get")
-	r.Param(ws.PathParameter(ParamId, ""))
+	r.Doc("import plan from yaml document")
+	r.Operation("importPlan")
+	r.Consumes(MimeYaml)
+	r.Param(ws.QueryParameter("replace", "update the plan with the same name instead of conflict").DataType("boolean"))
 	r.Writes(plan.Plan{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusCreated))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusConflict,
+	))
+	ws.Route(r)
+
+	r = ws.GET(fmt.Sprintf("{%s}/export", ParamId)).To(s.TakePlan(s.export))
+	addDefaults(r)
+	r.Doc("export plan as yaml document")
+	r.Operation("export")
+	r.Produces(MimeYaml)
+	r.Param(ws.PathParameter(ParamId, ""))
 	r.Do(services.Returns(
 		http.StatusOK,
 		http.StatusNotFound))
 	r.Do(services.ReturnsE(http.StatusBadRequest))
 	ws.Route(r)
 
-	r = ws.PUT(fmt.Sprintf("{%s}", ParamId)).To(s.TakePlan(s.update))
-	// docs
-	r.Doc("update")
-	r.Operation("update")
+	r = ws.GET("suggest").To(s.suggest)
+	addDefaults(r)
+	r.Doc("suggest")
+	r.Operation("suggest")
+	r.Notes("Compose a plan for the target type from enabled plugins, the plan isn't saved. " +
+		"Steps are ordered by plugin categories: discovery, web-passive, ssl, cms, web-active, " +
+		"plugins of dos and bruteforce categories aren't suggested")
+	r.Param(ws.QueryParameter("targetType", "one of [web|android|api|repo]").Required(true))
+	r.Param(ws.QueryParameter("category", "use plugins of these categories only, all if empty").AllowMultiple(true))
+	r.Writes(plan.Plan{})
+	r.Do(services.Returns(http.StatusOK))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.GET(fmt.Sprintf("{%s}", ParamId)).To(s.TakePlan(s.get))
+	addDefaults(r)
+	r.Doc("get")
+	r.Operation("get")
 	r.Param(ws.PathParameter(ParamId, ""))
 	r.Writes(plan.Plan{})
-	r.Reads(plan.Plan{})
 	r.Do(services.Returns(
 		http.StatusOK,
 		http.StatusNotFound))
 	r.Do(services.ReturnsE(http.StatusBadRequest))
 	ws.Route(r)
 
+	for _, r := range services.UpdateRoutes(ws, fmt.Sprintf("{%s}", ParamId), s.TakePlan(s.update)) {
+		r.Param(ws.PathParameter(ParamId, ""))
+		r.Writes(plan.Plan{})
+		r.Reads(plan.Plan{})
+		r.Do(services.Returns(
+			http.StatusOK,
+			http.StatusNotFound))
+		r.Do(services.ReturnsE(http.StatusBadRequest))
+		ws.Route(r)
+	}
+
 	r = ws.DELETE(fmt.Sprintf("{%s}", ParamId)).To(s.TakePlan(s.delete))
 	// docs
 	r.Doc("delete")
@@ -119,7 +172,7 @@ func (s *PlanService) create(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	obj, err := mgr.Plans.Create(raw)
@@ -146,7 +199,7 @@ func (s *PlanService) list(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	results, count, err := mgr.Plans.FilterByQuery(query)
@@ -163,6 +216,36 @@ func (s *PlanService) list(req *restful.Request, resp *restful.Response) {
 	resp.WriteEntity(result)
 }
 
+func (s *PlanService) suggest(req *restful.Request, resp *restful.Response) {
+	targetType := target.TargetType(req.QueryParameter("targetType"))
+	if !targetType.IsValid() {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("targetType should be one of [web|android|api|repo]"))
+		return
+	}
+	categories := []plugin.Category{}
+	for _, val := range req.Request.URL.Query()["category"] {
+		for _, c := range strings.Split(val, ",") {
+			category := plugin.Category(c)
+			if !category.IsValid() {
+				resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("unknown category %s", c))
+				return
+			}
+			categories = append(categories, category)
+		}
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	plugins, _, err := mgr.Plugins.FilterByQuery(bson.M{"enabled": true, "targetType": targetType})
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(plan.Suggest(targetType, plugins, categories))
+}
+
 func (s *PlanService) get(_ *restful.Request, resp *restful.Response, pl *plan.Plan) {
 	resp.WriteEntity(pl)
 }
@@ -172,17 +255,19 @@ func (s *PlanService) update(req *restful.Request, resp *restful.Response, pl *p
 
 	raw := &plan.Plan{}
 
-	if err := req.ReadEntity(raw); err != nil {
+	mask, err := services.ReadMasked(req, raw)
+	if err != nil {
 		logrus.Error(stackerr.Wrap(err))
 		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
 		return
 	}
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
-	raw.Id = pl.Id
+	// id and dates are never taken from the body
+	mask.Apply(pl, raw, "name", "desc", "workflow", "targetType")
 
-	if err := mgr.Plans.Update(raw); err != nil {
+	if err := mgr.Plans.Update(pl); err != nil {
 		if mgr.IsNotFound(err) {
 			resp.WriteErrorString(http.StatusNotFound, "Not found")
 			return
@@ -199,17 +284,99 @@ func (s *PlanService) update(req *restful.Request, resp *restful.Response, pl *p
 	}
 
 	resp.WriteHeader(http.StatusOK)
-	resp.WriteEntity(raw)
+	resp.WriteEntity(pl)
 }
 
-func (s *PlanService) delete(_ *restful.Request, resp *restful.Response, obj *plan.Plan) {
-	mgr := s.Manager()
+func (s *PlanService) delete(req *restful.Request, resp *restful.Response, obj *plan.Plan) {
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	mgr.Plans.Remove(obj)
 	resp.WriteHeader(http.StatusNoContent)
 }
 
+func (s *PlanService) export(_ *restful.Request, resp *restful.Response, pl *plan.Plan) {
+	data, err := plan.NewDocument(pl).MarshalYaml()
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.AppErr)
+		return
+	}
+	resp.AddHeader("Content-Type", MimeYaml)
+	resp.AddHeader("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s.yaml\"", url.QueryEscape(pl.Name)))
+	resp.WriteHeader(http.StatusOK)
+	resp.Write(data)
+}
+
+// importPlan creates the plan from yaml document, plugins of the workflow should be existed
+func (s *PlanService) importPlan(req *restful.Request, resp *restful.Response) {
+	data, err := ioutil.ReadAll(http.MaxBytesReader(resp.ResponseWriter, req.Request.Body, DocumentMaxSize))
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("document is too big or broken"))
+		return
+	}
+	doc, err := plan.ParseDocument(data)
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	for i, step := range doc.Workflow {
+		if _, err := mgr.Plugins.GetByName(step.Plugin); err != nil {
+			if mgr.IsNotFound(err) {
+				resp.WriteServiceError(http.StatusBadRequest,
+					services.NewBadReq("workflow.%d.plugin: plugin %s is not found", i, step.Plugin))
+				return
+			}
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+	}
+
+	raw := doc.Plan()
+	if req.QueryParameter("replace") == "true" {
+		existed, err := mgr.Plans.GetByName(doc.Name)
+		if err == nil {
+			existed.Desc = raw.Desc
+			existed.TargetType = raw.TargetType
+			existed.Workflow = raw.Workflow
+			if err := mgr.Plans.Update(existed); err != nil {
+				logrus.Error(stackerr.Wrap(err))
+				resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+				return
+			}
+			resp.WriteHeader(http.StatusOK)
+			resp.WriteEntity(existed)
+			return
+		}
+		if !mgr.IsNotFound(err) {
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+	}
+
+	obj, err := mgr.Plans.Create(raw)
+	if err != nil {
+		if mgr.IsDup(err) {
+			resp.WriteServiceError(
+				http.StatusConflict,
+				services.DuplicateErr)
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteHeader(http.StatusCreated)
+	resp.WriteEntity(obj)
+}
+
 func (s *PlanService) TakePlan(fn func(*restful.Request,
 	*restful.Response, *plan.Plan)) restful.RouteFunction {
 	return func(req *restful.Request, resp *restful.Response) {
@@ -219,7 +386,7 @@ func (s *PlanService) TakePlan(fn func(*restful.Request,
 			return
 		}
 
-		mgr := s.Manager()
+		mgr := s.RequestManager(req)
 		defer mgr.Close()
 
 		obj, err := mgr.Plans.GetById(mgr.ToId(id))
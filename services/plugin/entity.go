@@ -0,0 +1,7 @@
+package plugin
+
+import "github.com/bearded-web/bearded/models/issue"
+
+type SeverityMapEntity struct {
+	Mapping map[string]issue.Severity `json:"mapping" description:"plugin severity -> one of [info|low|medium|high]"`
+}
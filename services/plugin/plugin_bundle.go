@@ -0,0 +1,125 @@
+package plugin
+
+import (
+	"io"
+	"net/http"
+	"path"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/file"
+	"github.com/bearded-web/bearded/models/plugin"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/services"
+)
+
+func (s *PluginService) RegisterBundles(ws *restful.WebService) {
+	r := ws.POST("bundles").To(s.bundleImport)
+	addDefaults(r)
+	r.Doc("bundleImport")
+	r.Operation("bundleImport")
+	r.Notes("Only admins can import bundles. Import plugins with their images from the offline bundle " +
+		"for deployments without access to docker registries. The bundle is a tarball, optionally gzipped, " +
+		"with bundle.json manifest and image archives made by docker save. Archives are kept in the file storage " +
+		"and agents load them instead of pulling. Plugins with the same name and version are updated")
+	r.Consumes("multipart/form-data")
+	r.Param(ws.FormParameter("file", "bundle tarball").DataType("File"))
+	r.Writes(plugin.BundleResult{})
+	r.Do(services.Returns(http.StatusOK))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+}
+
+func (s *PluginService) bundleImport(req *restful.Request, resp *restful.Response) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if !mgr.Permission.IsAdmin(filters.GetUser(req)) {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	f, _, err := req.Request.FormFile("file")
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Couldn't read file"))
+		return
+	}
+	defer f.Close()
+
+	stored := map[string]*file.Meta{}
+	// archives which aren't used by plugins are removed, so failed imports don't leave garbage
+	cleanup := func(used map[string]bool) {
+		for id, meta := range stored {
+			if used[id] {
+				continue
+			}
+			if err := mgr.Files.Remove(meta); err != nil {
+				logrus.Error(stackerr.Wrap(err))
+			}
+		}
+	}
+	bundle, images, err := plugin.ReadBundle(f, func(name string, r io.Reader) (string, error) {
+		meta, err := mgr.Files.Create(r, &file.Meta{Name: path.Base(name), ContentType: "application/x-tar"})
+		if err != nil {
+			return "", err
+		}
+		stored[meta.Id] = meta
+		return meta.Id, nil
+	})
+	if err != nil {
+		cleanup(nil)
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Couldn't read bundle: %s", err))
+		return
+	}
+	if err := bundle.Validate(images); err != nil {
+		cleanup(nil)
+		services.NewValidationErr(err).Write(resp)
+		return
+	}
+
+	used := map[string]bool{}
+	result := &plugin.BundleResult{Created: []*plugin.Plugin{}, Updated: []*plugin.Plugin{}}
+	for _, bp := range bundle.Plugins {
+		raw := bp.Plugin
+		raw.Container.Archive = images[bp.ImagePath()]
+		obj, prev, err := mgr.Plugins.Import(raw)
+		if err != nil {
+			cleanup(used)
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+		used[raw.Container.Archive] = true
+		if prev == nil {
+			result.Created = append(result.Created, obj)
+			continue
+		}
+		result.Updated = append(result.Updated, obj)
+		s.removeArchive(mgr, prev)
+	}
+	cleanup(used)
+	logrus.Infof("Plugin bundle is imported: %d created, %d updated", len(result.Created), len(result.Updated))
+	resp.WriteEntity(result)
+}
+
+// removeArchive removes the image archive replaced by the import, if other plugins don't use it
+func (s *PluginService) removeArchive(mgr *manager.Manager, prev *plugin.Plugin) {
+	if prev.Container == nil || prev.Container.Archive == "" {
+		return
+	}
+	count, err := mgr.Plugins.CountByArchive(prev.Container.Archive)
+	if err != nil || count > 0 {
+		if err != nil {
+			logrus.Error(stackerr.Wrap(err))
+		}
+		return
+	}
+	if err := mgr.Files.Remove(&file.Meta{Id: prev.Container.Archive}); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+	}
+}
@@ -3,11 +3,13 @@ package plugin
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/emicklei/go-restful"
 	"github.com/facebookgo/stackerr"
 
+	"github.com/bearded-web/bearded/models/issue"
 	"github.com/bearded-web/bearded/models/plugin"
 	"github.com/bearded-web/bearded/pkg/filters"
 	"github.com/bearded-web/bearded/pkg/fltr"
@@ -93,6 +95,37 @@ func (s *PluginService) Register(container *restful.Container) {
 	r.Do(services.ReturnsE(http.StatusBadRequest))
 	ws.Route(r)
 
+	r = ws.GET(fmt.Sprintf("{%s}/severities", ParamId)).To(s.TakePlugin(s.severities))
+	addDefaults(r)
+	r.Doc("severities")
+	r.Operation("severities")
+	r.Notes("Severity mapping is shared between all versions of the plugin")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Writes(plugin.SeverityMap{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.PUT(fmt.Sprintf("{%s}/severities", ParamId)).To(s.TakePlugin(s.severitiesUpdate))
+	addDefaults(r)
+	r.Doc("severitiesUpdate")
+	r.Operation("severitiesUpdate")
+	r.Notes("Only admins can change severity mapping")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Reads(SeverityMapEntity{})
+	r.Writes(plugin.SeverityMap{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+
+	s.RegisterBundles(ws)
+
 	container.Add(ws)
 }
 
@@ -108,7 +141,7 @@ func (s *PluginService) create(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	obj, err := mgr.Plugins.Create(raw)
@@ -135,7 +168,7 @@ func (s *PluginService) list(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	results, count, err := mgr.Plugins.FilterByQuery(query)
@@ -166,7 +199,7 @@ func (s *PluginService) update(req *restful.Request, resp *restful.Response, pl
 		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
 		return
 	}
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	raw.Id = pl.Id
@@ -191,6 +224,57 @@ func (s *PluginService) update(req *restful.Request, resp *restful.Response, pl
 	resp.WriteEntity(raw)
 }
 
+func (s *PluginService) severities(req *restful.Request, resp *restful.Response, pl *plugin.Plugin) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	sevMap, err := mgr.Severities.GetByPlugin(pl.Name)
+	if err != nil {
+		if !mgr.IsNotFound(err) {
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+		sevMap = &plugin.SeverityMap{Plugin: pl.Name, Mapping: map[string]issue.Severity{}}
+	}
+	resp.WriteEntity(sevMap)
+}
+
+func (s *PluginService) severitiesUpdate(req *restful.Request, resp *restful.Response, pl *plugin.Plugin) {
+	raw := &SeverityMapEntity{}
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
+	}
+	for from, to := range raw.Mapping {
+		if strings.TrimSpace(from) == "" || strings.ContainsAny(from, ".$") {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("wrong plugin severity %q", from))
+			return
+		}
+		if !to.IsValid() {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("unknown severity %q for %q", to, from))
+			return
+		}
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if !mgr.Permission.IsAdmin(filters.GetUser(req)) {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+
+	sevMap, err := mgr.Severities.Set(pl.Name, raw.Mapping)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(sevMap)
+}
+
 func (s *PluginService) TakePlugin(fn func(*restful.Request,
 	*restful.Response, *plugin.Plugin)) restful.RouteFunction {
 	return func(req *restful.Request, resp *restful.Response) {
@@ -200,7 +284,7 @@ func (s *PluginService) TakePlugin(fn func(*restful.Request,
 			return
 		}
 
-		mgr := s.Manager()
+		mgr := s.RequestManager(req)
 		defer mgr.Close()
 
 		pl, err := mgr.Plugins.GetById(pluginId)
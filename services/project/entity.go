@@ -1,5 +1,113 @@
 package project
 
+import (
+	"time"
+
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/models/target"
+)
+
 type ProjectEntity struct {
-	Name string `json:"name"`
+	Name      string  `json:"name" description:"project name, 80 symbols max" create:"nonzero" validate:"max=80"`
+	IssueSort *string `json:"issueSort,omitempty" description:"default sort for project issues, like -severity,created"`
+
+	Escalation *project.Escalation `json:"escalation,omitempty" description:"escalation policy for unacknowledged issues"`
+	Gate       *project.Gate       `json:"gate,omitempty" description:"deploy policy checked by CI, send null to reset to the default"`
+	RateLimit  *target.RateLimit   `json:"rateLimit,omitempty" description:"default scan politeness for project targets, send null or empty object to reset"`
+	Proxy      *target.Proxy       `json:"proxy,omitempty" description:"default outbound proxy for plugin traffic, send null to remove"`
+	Delivery   *project.Delivery   `json:"delivery,omitempty" description:"weekly report of open issues sent by email, send null to disable"`
+	Engagement *project.Engagement `json:"engagement,omitempty" description:"rules of engagement checked for new scans, send null to remove"`
+	MsTeams    *project.MsTeams    `json:"msTeams,omitempty" description:"microsoft teams channel for project notifications, send null to remove"`
+	ServiceNow *project.ServiceNow `json:"serviceNow,omitempty" description:"servicenow integration for severe issues, send null to remove"`
+
+	RequireReview *bool `json:"requireReview,omitempty" description:"count issues of scans only after their review"`
+}
+
+type PageEntity struct {
+	Title    string `json:"title" description:"page title, 200 symbols max" validate:"nonzero,max=200"`
+	Text     string `json:"text" description:"markdown text, 1048576 symbols max" validate:"max=1048576"`
+	Revision int    `json:"revision,omitempty" description:"revision the change is based on, the page isn't updated if it's changed since"`
+}
+
+type RuleTestEntity struct {
+	Plugin string          `json:"plugin,omitempty" description:"plugin which found the issue, ex: barbudo/wpscan"`
+	Url    string          `json:"url,omitempty" description:"issue url"`
+	Rules  []*project.Rule `json:"rules,omitempty" description:"test these rules instead of the saved ones"`
+}
+
+type RuleTestResult struct {
+	Matched  []*project.Rule `json:"matched"`
+	Assignee bson.ObjectId   `json:"assignee,omitempty"`
+	Team     bson.ObjectId   `json:"team,omitempty"`
+	Labels   []string        `json:"labels"`
+}
+
+type Calendar struct {
+	Scans     []*CalendarScan     `json:"scans" description:"scans which aren't started yet"`
+	Blackouts []*CalendarBlackout `json:"blackouts"`
+}
+
+type CalendarScan struct {
+	Scan      bson.ObjectId `json:"scan"`
+	Target    bson.ObjectId `json:"target"`
+	Plan      bson.ObjectId `json:"plan"`
+	Start     time.Time     `json:"start" description:"estimated start time"`
+	Postponed bool          `json:"postponed" description:"the scan is postponed because of blackout"`
+}
+
+type CalendarBlackout struct {
+	Blackout       bson.ObjectId   `json:"blackout"`
+	Name           string          `json:"name"`
+	Targets        []bson.ObjectId `json:"targets,omitempty"`
+	project.Period `json:",inline"`
+}
+
+type DiscoveryEntity struct {
+	Target   bson.ObjectId `json:"target" description:"web target with the root domain"`
+	Plan     bson.ObjectId `json:"plan" description:"plan with enumeration plugins"`
+	Interval int           `json:"interval" description:"hours between runs"`
+	At       string        `json:"at,omitempty" description:"time of day in format 15:04 when runs are started, interval should be whole days then"`
+	Timezone string        `json:"timezone,omitempty" description:"IANA time zone of at like Europe/Moscow, UTC if empty"`
+	Enabled  bool          `json:"enabled"`
+}
+
+type Board struct {
+	Columns []*BoardColumn `json:"columns"`
+}
+
+type BoardColumn struct {
+	Column  issue.Column         `json:"column"`
+	Count   int                  `json:"count" description:"issues in the column"`
+	HasMore bool                 `json:"hasMore" description:"there are issues after the page"`
+	Results []*issue.TargetIssue `json:"results"`
+}
+
+type BoardMoveEntity struct {
+	Issue  string       `json:"issue"`
+	Column issue.Column `json:"column" description:"one of [open|confirmed|muted|false|resolved]"`
+}
+
+type UsageEntity struct {
+	*scan.UsageReport `json:",inline"`
+	Quota             project.Quota `json:"quota" description:"project quota or server default, zero values are unlimited"`
+	MonthAgentSeconds int           `json:"monthAgentSeconds" description:"agent time used in this month"`
+}
+
+type QuotaEntity struct {
+	Quota   project.Quota `json:"quota" description:"project quota or server default, zero values are unlimited"`
+	Default bool          `json:"default" description:"server default is used"`
+	Used    QuotaUsage    `json:"used"`
+}
+
+// QuotaUsage is current consumption of every quota value
+type QuotaUsage struct {
+	MaxScans     int `json:"maxScans" description:"running scans"`
+	AgentMinutes int `json:"agentMinutes" description:"agent minutes used in this month"`
+	Targets      int `json:"targets"`
+	ScansPerDay  int `json:"scansPerDay" description:"scans created today"`
+	Storage      int `json:"storage" description:"megabytes of uploaded files, rounded up"`
 }
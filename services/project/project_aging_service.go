@@ -0,0 +1,42 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/services"
+)
+
+func (s *ProjectService) RegisterAging(ws *restful.WebService) {
+	r := ws.GET(fmt.Sprintf("{%s}/aging", ParamId)).To(s.TakeProject(s.aging))
+	r.Doc("aging")
+	r.Operation("aging")
+	addDefaults(r)
+	r.Notes("Open issues by age in days and severity. Muted, false and issues of unreviewed scans aren't counted")
+	r.Writes(issue.Aging{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	ws.Route(r)
+}
+
+func (s *ProjectService) aging(req *restful.Request, resp *restful.Response, p *project.Project) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	result, err := mgr.Issues.Aging(p.Id, time.Now().UTC())
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(result)
+}
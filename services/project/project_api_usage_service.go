@@ -0,0 +1,69 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/apiusage"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/services"
+)
+
+func (s *ProjectService) RegisterApiUsage(ws *restful.WebService) {
+	r := ws.GET(fmt.Sprintf("{%s}/api-usage", ParamId)).To(s.TakeProject(s.apiUsage))
+	r.Doc("apiUsage")
+	r.Operation("apiUsage")
+	addDefaults(r)
+	r.Notes("Api calls to the project by token, route and hour. Calls of project routes, " +
+		"lists filtered by the project and ingest tokens are counted, calls by session have no token. " +
+		"Only the project owner and admins can see it, counters are written every minute")
+	r.Writes(apiusage.Report{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.QueryParameter("from", "RFC3339 time, 7 days ago by default"))
+	r.Param(ws.QueryParameter("to", "RFC3339 time, not limited by default"))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+}
+
+func (s *ProjectService) apiUsage(req *restful.Request, resp *restful.Response, p *project.Project) {
+	from, err := parseTimeParam(req, "from", time.Now().UTC().Add(-7*24*time.Hour).Truncate(time.Hour))
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
+	to, err := parseTimeParam(req, "to", time.Time{})
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
+	if !to.IsZero() && !to.After(from) {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("to should be after from"))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if u := filters.GetUser(req); p.Owner != u.Id && !mgr.Permission.IsAdmin(u) {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	report, err := mgr.ApiUsage.Report(p.Id, from, to)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(report)
+}
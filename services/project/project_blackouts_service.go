@@ -0,0 +1,327 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/pkg/validate"
+	"github.com/bearded-web/bearded/services"
+)
+
+const (
+	BlackoutParamId = "blackout-id"
+
+	// max range for calendar requests
+	calendarMaxRange = 62 * 24 * time.Hour
+)
+
+func (s *ProjectService) RegisterBlackouts(ws *restful.WebService) {
+	r := ws.GET(fmt.Sprintf("{%s}/blackouts", ParamId)).To(s.TakeProject(s.blackouts))
+	r.Doc("blackouts")
+	r.Operation("blackouts")
+	addDefaults(r)
+	r.Writes(project.BlackoutList{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/blackouts", ParamId)).To(s.TakeProject(s.blackoutsCreate))
+	r.Doc("blackoutsCreate")
+	r.Operation("blackoutsCreate")
+	addDefaults(r)
+	r.Notes("Authorization required. Only project owner can manage blackouts")
+	r.Reads(project.Blackout{})
+	r.Writes(project.Blackout{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusCreated,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+
+	r = ws.PUT(fmt.Sprintf("{%s}/blackouts/{%s}", ParamId, BlackoutParamId)).To(s.TakeProject(s.TakeBlackout(s.blackoutsUpdate)))
+	r.Doc("blackoutsUpdate")
+	r.Operation("blackoutsUpdate")
+	addDefaults(r)
+	r.Notes("Authorization required. Only project owner can manage blackouts")
+	r.Reads(project.Blackout{})
+	r.Writes(project.Blackout{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(BlackoutParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+
+	r = ws.DELETE(fmt.Sprintf("{%s}/blackouts/{%s}", ParamId, BlackoutParamId)).To(s.TakeProject(s.TakeBlackout(s.blackoutsDelete)))
+	r.Doc("blackoutsDelete")
+	r.Operation("blackoutsDelete")
+	addDefaults(r)
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(BlackoutParamId, ""))
+	r.Do(services.Returns(
+		http.StatusNoContent,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusForbidden))
+	ws.Route(r)
+
+	r = ws.GET(fmt.Sprintf("{%s}/calendar", ParamId)).To(s.TakeProject(s.calendar))
+	r.Doc("calendar")
+	r.Operation("calendar")
+	addDefaults(r)
+	r.Notes("Upcoming scans with estimated start and blocked periods")
+	r.Writes(Calendar{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.QueryParameter("from", "RFC3339 time, now by default"))
+	r.Param(ws.QueryParameter("to", "RFC3339 time, a week after from by default, 62 days max"))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+}
+
+func (s *ProjectService) blackouts(_ *restful.Request, resp *restful.Response, p *project.Project) {
+	results := p.Blackouts
+	if results == nil {
+		results = []*project.Blackout{}
+	}
+	result := &project.BlackoutList{
+		Meta:    pagination.Meta{Count: len(results)},
+		Results: results,
+	}
+	resp.WriteEntity(result)
+}
+
+func (s *ProjectService) blackoutsCreate(req *restful.Request, resp *restful.Response, p *project.Project) {
+	raw := &project.Blackout{}
+	if sErr := readBlackout(req, raw); sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	raw.Id = mgr.NewId()
+	p.Blackouts = append(p.Blackouts, raw)
+	if err := mgr.Projects.Update(p); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteHeader(http.StatusCreated)
+	resp.WriteEntity(raw)
+}
+
+func (s *ProjectService) blackoutsUpdate(req *restful.Request, resp *restful.Response, p *project.Project, b *project.Blackout) {
+	raw := &project.Blackout{}
+	if sErr := readBlackout(req, raw); sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	raw.Id = b.Id
+	*b = *raw
+	if err := mgr.Projects.Update(p); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteEntity(b)
+}
+
+func (s *ProjectService) blackoutsDelete(req *restful.Request, resp *restful.Response, p *project.Project, b *project.Blackout) {
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	blackouts := make([]*project.Blackout, 0, len(p.Blackouts)-1)
+	for _, blackout := range p.Blackouts {
+		if blackout.Id != b.Id {
+			blackouts = append(blackouts, blackout)
+		}
+	}
+	p.Blackouts = blackouts
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if err := mgr.Projects.Update(p); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.ResponseWriter.WriteHeader(http.StatusNoContent)
+}
+
+func (s *ProjectService) calendar(req *restful.Request, resp *restful.Response, p *project.Project) {
+	now := time.Now().UTC()
+	from, err := parseTimeParam(req, "from", now)
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
+	to, err := parseTimeParam(req, "to", from.AddDate(0, 0, 7))
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
+	if !to.After(from) || to.Sub(from) > calendarMaxRange {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("to should be after from and not later than 62 days"))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	pending, _, err := mgr.Scans.FilterByQuery(bson.M{"project": p.Id, "status": scan.StatusCreated})
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	result := &Calendar{
+		Scans:     []*CalendarScan{},
+		Blackouts: []*CalendarBlackout{},
+	}
+	for _, sc := range pending {
+		start := now
+		if sc.Scheduled != nil && sc.Scheduled.After(start) {
+			start = *sc.Scheduled
+		}
+		item := &CalendarScan{
+			Scan:   sc.Id,
+			Target: sc.Target,
+			Plan:   sc.Plan,
+			Start:  start,
+		}
+		if until := p.Blocked(sc.Target, start); !until.IsZero() {
+			item.Start = until
+			item.Postponed = true
+		}
+		if item.Start.Before(from) || !item.Start.Before(to) {
+			continue
+		}
+		result.Scans = append(result.Scans, item)
+	}
+	sort.Sort(calendarScans(result.Scans))
+
+	for _, b := range p.Blackouts {
+		for _, period := range b.Periods(from, to) {
+			result.Blackouts = append(result.Blackouts, &CalendarBlackout{
+				Blackout: b.Id,
+				Name:     b.Name,
+				Targets:  b.Targets,
+				Period:   period,
+			})
+		}
+	}
+	sort.Sort(calendarBlackouts(result.Blackouts))
+
+	resp.WriteEntity(result)
+}
+
+// Helpers
+
+func parseTimeParam(req *restful.Request, name string, def time.Time) (time.Time, error) {
+	val := req.QueryParameter(name)
+	if val == "" {
+		return def, nil
+	}
+	t, err := time.Parse(time.RFC3339, val)
+	if err != nil {
+		return t, fmt.Errorf("%s should be in RFC3339 format", name)
+	}
+	return t.UTC(), nil
+}
+
+func readBlackout(req *restful.Request, raw *project.Blackout) *services.ErrResp {
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.WrongEntityErr}
+	}
+	if err := validate.Entity(raw); err != nil {
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewValidationErr(err)}
+	}
+	return nil
+}
+
+type calendarScans []*CalendarScan
+
+func (c calendarScans) Len() int           { return len(c) }
+func (c calendarScans) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c calendarScans) Less(i, j int) bool { return c[i].Start.Before(c[j].Start) }
+
+type calendarBlackouts []*CalendarBlackout
+
+func (c calendarBlackouts) Len() int           { return len(c) }
+func (c calendarBlackouts) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
+func (c calendarBlackouts) Less(i, j int) bool { return c[i].Start.Before(c[j].Start) }
+
+type BlackoutFunction func(*restful.Request, *restful.Response, *project.Project, *project.Blackout)
+
+// Decorate ProjectFunction. Look for blackout in project by BlackoutParamId
+// and add blackout object in the end. If blackout is not found then return Not Found.
+func (s *ProjectService) TakeBlackout(fn BlackoutFunction) ProjectFunction {
+	return func(req *restful.Request, resp *restful.Response, p *project.Project) {
+		id := req.PathParameter(BlackoutParamId)
+		if !s.IsId(id) {
+			resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+			return
+		}
+
+		b := p.GetBlackout(manager.ToId(id))
+		if b == nil {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		fn(req, resp, p, b)
+	}
+}
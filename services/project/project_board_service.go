@@ -0,0 +1,174 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/services"
+)
+
+var boardDefaultSort = []string{"-severityRank", "-created"}
+
+func (s *ProjectService) RegisterBoard(ws *restful.WebService) {
+	r := ws.GET(fmt.Sprintf("{%s}/board", ParamId)).To(s.TakeProject(s.board))
+	r.Doc("board")
+	r.Operation("board")
+	addDefaults(r)
+	r.Notes("Project issues grouped by status columns. Skip and limit are applied to every column, " +
+		"use columns param to load the next page of one column")
+	r.Writes(Board{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.QueryParameter("columns", "comma separated columns from [open|confirmed|muted|false|resolved], all by default"))
+	r.Param(ws.QueryParameter("target", "target id"))
+	r.Param(ws.QueryParameter("assignee", "assignee id"))
+	r.Param(s.Paginator.SkipParam())
+	r.Param(s.Paginator.LimitParam())
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/board/move", ParamId)).To(s.TakeProject(s.boardMove))
+	r.Doc("boardMove")
+	r.Operation("boardMove")
+	addDefaults(r)
+	r.Notes("Move the issue to another column. False positives and resolved issues should be reopened " +
+		"before moving them further, confirmed issues can't be marked as false directly")
+	r.Reads(BoardMoveEntity{})
+	r.Writes(issue.TargetIssue{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusConflict))
+	ws.Route(r)
+}
+
+func (s *ProjectService) board(req *restful.Request, resp *restful.Response, p *project.Project) {
+	columns := issue.Columns
+	if param := req.QueryParameter("columns"); param != "" {
+		columns = []issue.Column{}
+		for _, name := range strings.Split(param, ",") {
+			col := issue.Column(strings.TrimSpace(name))
+			if !col.IsValid() {
+				resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Unknown column %s", col))
+				return
+			}
+			columns = append(columns, col)
+		}
+	}
+	query := bson.M{"project": p.Id}
+	for _, param := range []string{"target", "assignee"} {
+		if val := req.QueryParameter(param); val != "" {
+			if !s.IsId(val) {
+				resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("%s should be bson.ObjectId hex", param))
+				return
+			}
+			query[param] = bson.ObjectIdHex(val)
+		}
+	}
+	skip, limit := s.Paginator.Parse(req)
+	// project issue sort is used for columns
+	sort := manager.IssueSorter.ParseString(p.IssueSort)
+	if len(sort) == 0 {
+		sort = boardDefaultSort
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	result := &Board{Columns: []*BoardColumn{}}
+	for _, col := range columns {
+		colQuery := bson.M{"statusRank": col.Rank()}
+		for k, v := range query {
+			colQuery[k] = v
+		}
+		issues, count, err := mgr.Issues.FilterByQuery(colQuery, manager.Opts{
+			Sort:  sort,
+			Skip:  skip,
+			Limit: limit,
+			Count: manager.CountCached,
+		})
+		if err != nil {
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+		result.Columns = append(result.Columns, &BoardColumn{
+			Column:  col,
+			Count:   count,
+			HasMore: skip+len(issues) < count,
+			Results: issues,
+		})
+	}
+	resp.WriteEntity(result)
+}
+
+func (s *ProjectService) boardMove(req *restful.Request, resp *restful.Response, p *project.Project) {
+	raw := &BoardMoveEntity{}
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
+	}
+	if !s.IsId(raw.Issue) {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Issue is wrong"))
+		return
+	}
+	if !raw.Column.IsValid() {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Column should be one of [open|confirmed|muted|false|resolved]"))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	obj, err := mgr.Issues.GetById(mgr.ToId(raw.Issue))
+	if err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	if obj.Project != p.Id {
+		resp.WriteErrorString(http.StatusNotFound, "Not found")
+		return
+	}
+	from := obj.Column()
+	if from == raw.Column {
+		resp.WriteEntity(obj)
+		return
+	}
+	if !from.CanMove(raw.Column) {
+		resp.WriteServiceError(http.StatusConflict, services.NewBadReq("Issue can't be moved from %s to %s", from, raw.Column))
+		return
+	}
+
+	obj.Move(raw.Column, filters.GetUser(req).Id)
+	if err := mgr.Issues.Update(obj); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(obj)
+}
@@ -0,0 +1,479 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/discovery"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/target"
+	discoveryEngine "github.com/bearded-web/bearded/pkg/discovery"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/fltr"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/services"
+)
+
+const (
+	DiscoveryParamId = "discovery-id"
+	HostParamId      = "host-id"
+)
+
+func (s *ProjectService) RegisterDiscoveries(ws *restful.WebService) {
+	r := ws.GET(fmt.Sprintf("{%s}/discoveries", ParamId)).To(s.TakeProject(s.discoveries))
+	r.Doc("discoveries")
+	r.Operation("discoveries")
+	addDefaults(r)
+	r.Writes(discovery.DiscoveryList{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/discoveries", ParamId)).To(s.TakeProject(s.discoveriesCreate))
+	r.Doc("discoveriesCreate")
+	r.Operation("discoveriesCreate")
+	addDefaults(r)
+	r.Notes("Authorization required. Only project owner can manage discoveries")
+	r.Reads(DiscoveryEntity{})
+	r.Writes(discovery.Discovery{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusCreated,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+
+	r = ws.PUT(fmt.Sprintf("{%s}/discoveries/{%s}", ParamId, DiscoveryParamId)).To(s.TakeProject(s.TakeDiscovery(s.discoveriesUpdate)))
+	r.Doc("discoveriesUpdate")
+	r.Operation("discoveriesUpdate")
+	addDefaults(r)
+	r.Notes("Authorization required. Only project owner can manage discoveries")
+	r.Reads(DiscoveryEntity{})
+	r.Writes(discovery.Discovery{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(DiscoveryParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+
+	r = ws.DELETE(fmt.Sprintf("{%s}/discoveries/{%s}", ParamId, DiscoveryParamId)).To(s.TakeProject(s.TakeDiscovery(s.discoveriesDelete)))
+	r.Doc("discoveriesDelete")
+	r.Operation("discoveriesDelete")
+	addDefaults(r)
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(DiscoveryParamId, ""))
+	r.Do(services.Returns(
+		http.StatusNoContent,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusForbidden))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/discoveries/{%s}/run", ParamId, DiscoveryParamId)).To(s.TakeProject(s.TakeDiscovery(s.discoveriesRun)))
+	r.Doc("discoveriesRun")
+	r.Operation("discoveriesRun")
+	addDefaults(r)
+	r.Notes("Create discovery scan right now. Only project owner can run discoveries")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(DiscoveryParamId, ""))
+	r.Do(services.Returns(
+		http.StatusCreated,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusForbidden))
+	ws.Route(r)
+
+	r = ws.GET(fmt.Sprintf("{%s}/hosts", ParamId)).To(s.TakeProject(s.hosts))
+	r.Doc("hosts")
+	r.Operation("hosts")
+	addDefaults(r)
+	r.Notes("Hosts found by discoveries")
+	r.Writes(discovery.HostList{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	s.SetParams(r, fltr.GetParams(ws, manager.HostFltr{}))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/hosts/{%s}/approve", ParamId, HostParamId)).To(s.TakeProject(s.TakeHost(s.hostsApprove)))
+	r.Doc("hostsApprove")
+	r.Operation("hostsApprove")
+	addDefaults(r)
+	r.Notes("Create web target for the host. Only project owner can approve hosts")
+	r.Writes(discovery.Host{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(HostParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden,
+		http.StatusPaymentRequired))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/hosts/{%s}/ignore", ParamId, HostParamId)).To(s.TakeProject(s.TakeHost(s.hostsIgnore)))
+	r.Doc("hostsIgnore")
+	r.Operation("hostsIgnore")
+	addDefaults(r)
+	r.Notes("Ignored hosts aren't proposed again. Only project owner can ignore hosts")
+	r.Writes(discovery.Host{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(HostParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+}
+
+func (s *ProjectService) discoveries(req *restful.Request, resp *restful.Response, p *project.Project) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	results, count, err := mgr.Discovery.FilterBy(&manager.DiscoveryFltr{Project: p.Id})
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	result := &discovery.DiscoveryList{
+		Meta:    pagination.Meta{Count: count},
+		Results: results,
+	}
+	resp.WriteEntity(result)
+}
+
+func (s *ProjectService) discoveriesCreate(req *restful.Request, resp *restful.Response, p *project.Project) {
+	u := filters.GetUser(req)
+	if p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	raw := &discovery.Discovery{}
+	if sErr := readDiscovery(req, mgr, p, raw); sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+	raw.Project = p.Id
+	raw.Owner = u.Id
+
+	obj, err := mgr.Discovery.Create(raw)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteHeader(http.StatusCreated)
+	resp.WriteEntity(obj)
+}
+
+func (s *ProjectService) discoveriesUpdate(req *restful.Request, resp *restful.Response, p *project.Project, d *discovery.Discovery) {
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if sErr := readDiscovery(req, mgr, p, d); sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+	if err := mgr.Discovery.Update(d); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteEntity(d)
+}
+
+func (s *ProjectService) discoveriesDelete(req *restful.Request, resp *restful.Response, p *project.Project, d *discovery.Discovery) {
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if err := mgr.Discovery.Remove(d); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.ResponseWriter.WriteHeader(http.StatusNoContent)
+}
+
+func (s *ProjectService) discoveriesRun(req *restful.Request, resp *restful.Response, p *project.Project, d *discovery.Discovery) {
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	sc, err := discoveryEngine.Launch(mgr, s.Scheduler(), d)
+	if err != nil {
+		logrus.Error(err)
+		resp.WriteServiceError(http.StatusInternalServerError, services.AppErr)
+		return
+	}
+
+	resp.WriteHeader(http.StatusCreated)
+	resp.WriteEntity(sc)
+}
+
+func (s *ProjectService) hosts(req *restful.Request, resp *restful.Response, p *project.Project) {
+	query, err := fltr.FromRequest(req, manager.HostFltr{})
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
+	query["project"] = p.Id
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	skip, limit := s.Paginator.Parse(req)
+	opt := manager.Opts{
+		Sort:  []string{"-seen"},
+		Limit: limit,
+		Skip:  skip,
+	}
+	results, count, err := mgr.Hosts.FilterByQuery(query, opt)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	previous, next := s.Paginator.Urls(req, skip, limit, count)
+	result := &discovery.HostList{
+		Meta: pagination.Meta{
+			Count:    count,
+			Previous: previous,
+			Next:     next,
+		},
+		Results: results,
+	}
+	resp.WriteEntity(result)
+}
+
+func (s *ProjectService) hostsApprove(req *restful.Request, resp *restful.Response, p *project.Project, h *discovery.Host) {
+	u := filters.GetUser(req)
+	if p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	if h.Status == discovery.HostApproved {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("host is already approved"))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if err := mgr.Targets.CheckCount(p); err != nil {
+		if sErr := services.QuotaErr(err); sErr != nil {
+			sErr.Write(resp)
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	// new target uses the scheme of the root domain
+	scheme := "http"
+	if d, err := mgr.Discovery.GetById(h.Discovery); err == nil {
+		if root, err := mgr.Targets.GetById(d.Target); err == nil {
+			if addr, err := url.Parse(root.Addr()); err == nil && addr.Scheme != "" {
+				scheme = addr.Scheme
+			}
+		}
+	}
+	t, err := mgr.Targets.Create(&target.Target{
+		Type:    target.TypeWeb,
+		Project: p.Id,
+		Web:     &target.WebTarget{Domain: (&url.URL{Scheme: scheme, Host: h.Name}).String()},
+	})
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	h.Status = discovery.HostApproved
+	h.Target = t.Id
+	h.User = u.Id
+	if err := mgr.Hosts.Update(h); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteEntity(h)
+}
+
+func (s *ProjectService) hostsIgnore(req *restful.Request, resp *restful.Response, p *project.Project, h *discovery.Host) {
+	u := filters.GetUser(req)
+	if p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	if h.Status == discovery.HostApproved {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("host is approved, remove the target instead"))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	h.Status = discovery.HostIgnored
+	h.User = u.Id
+	if err := mgr.Hosts.Update(h); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteEntity(h)
+}
+
+// readDiscovery reads DiscoveryEntity to obj, the target and plan should be compatible
+func readDiscovery(req *restful.Request, mgr *manager.Manager, p *project.Project, obj *discovery.Discovery) *services.ErrResp {
+	raw := &DiscoveryEntity{}
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.WrongEntityErr}
+	}
+	if !raw.Target.Valid() || !raw.Plan.Valid() {
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewBadReq("target and plan are required")}
+	}
+	t, err := mgr.Targets.GetById(raw.Target)
+	if err != nil || t.Project != p.Id {
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewBadReq("target not found")}
+	}
+	if t.Type != target.TypeWeb {
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewBadReq("only web targets could be discovered")}
+	}
+	planObj, err := mgr.Plans.GetById(raw.Plan)
+	if err != nil {
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewBadReq("plan not found")}
+	}
+	if planObj.TargetType != t.Type {
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewBadReq("target.type and plan.targetType is not compatible")}
+	}
+	obj.Target = raw.Target
+	obj.Plan = raw.Plan
+	obj.Interval = raw.Interval
+	obj.At = raw.At
+	obj.Timezone = raw.Timezone
+	obj.Enabled = raw.Enabled
+	if err := obj.Validate(); err != nil {
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewValidationErr(err)}
+	}
+	return nil
+}
+
+type DiscoveryFunction func(*restful.Request, *restful.Response, *project.Project, *discovery.Discovery)
+
+// Decorate ProjectFunction. Look for discovery of the project by DiscoveryParamId
+// and add discovery object in the end. If discovery is not found then return Not Found.
+func (s *ProjectService) TakeDiscovery(fn DiscoveryFunction) ProjectFunction {
+	return func(req *restful.Request, resp *restful.Response, p *project.Project) {
+		id := req.PathParameter(DiscoveryParamId)
+		if !s.IsId(id) {
+			resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+			return
+		}
+		mgr := s.RequestManager(req)
+		defer mgr.Close()
+
+		d, err := mgr.Discovery.GetById(mgr.ToId(id))
+		if err != nil {
+			if mgr.IsNotFound(err) {
+				resp.WriteErrorString(http.StatusNotFound, "Not found")
+				return
+			}
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+		if d.Project != p.Id {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		mgr.Close()
+
+		fn(req, resp, p, d)
+	}
+}
+
+type HostFunction func(*restful.Request, *restful.Response, *project.Project, *discovery.Host)
+
+// Decorate ProjectFunction. Look for discovered host of the project by HostParamId
+// and add host object in the end. If host is not found then return Not Found.
+func (s *ProjectService) TakeHost(fn HostFunction) ProjectFunction {
+	return func(req *restful.Request, resp *restful.Response, p *project.Project) {
+		id := req.PathParameter(HostParamId)
+		if !s.IsId(id) {
+			resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+			return
+		}
+		mgr := s.RequestManager(req)
+		defer mgr.Close()
+
+		h, err := mgr.Hosts.GetById(mgr.ToId(id))
+		if err != nil {
+			if mgr.IsNotFound(err) {
+				resp.WriteErrorString(http.StatusNotFound, "Not found")
+				return
+			}
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+		if h.Project != p.Id {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		mgr.Close()
+
+		fn(req, resp, p, h)
+	}
+}
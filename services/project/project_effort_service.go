@@ -0,0 +1,60 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/worklog"
+	"github.com/bearded-web/bearded/services"
+)
+
+func (s *ProjectService) RegisterEffort(ws *restful.WebService) {
+	r := ws.GET(fmt.Sprintf("{%s}/effort", ParamId)).To(s.TakeProject(s.effort))
+	r.Doc("effort")
+	r.Operation("effort")
+	addDefaults(r)
+	r.Notes("Remediation effort from issue worklogs, total and grouped by users and issues")
+	r.Writes(worklog.Effort{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.QueryParameter("from", "RFC3339 time, worklogs from this date inclusive"))
+	r.Param(ws.QueryParameter("to", "RFC3339 time, worklogs before this date"))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+}
+
+func (s *ProjectService) effort(req *restful.Request, resp *restful.Response, p *project.Project) {
+	from, err := parseTimeParam(req, "from", time.Time{})
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
+	to, err := parseTimeParam(req, "to", time.Time{})
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
+	if !from.IsZero() && !to.IsZero() && !to.After(from) {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("to should be after from"))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	result, err := mgr.Worklogs.Effort(p.Id, from, to)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(result)
+}
@@ -0,0 +1,229 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/pkg/validate"
+	"github.com/bearded-web/bearded/services"
+)
+
+const (
+	FieldParamId = "field-id"
+)
+
+func (s *ProjectService) RegisterFields(ws *restful.WebService) {
+	r := ws.GET(fmt.Sprintf("{%s}/fields", ParamId)).To(s.TakeProject(s.fields))
+	r.Doc("fields")
+	r.Operation("fields")
+	addDefaults(r)
+	r.Writes(project.FieldList{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/fields", ParamId)).To(s.TakeProject(s.fieldsCreate))
+	r.Doc("fieldsCreate")
+	r.Operation("fieldsCreate")
+	addDefaults(r)
+	r.Notes("Authorization required. Only project owner can manage custom issue fields")
+	r.Reads(project.Field{})
+	r.Writes(project.Field{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusCreated,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden,
+		http.StatusConflict))
+	ws.Route(r)
+
+	r = ws.PUT(fmt.Sprintf("{%s}/fields/{%s}", ParamId, FieldParamId)).To(s.TakeProject(s.TakeField(s.fieldsUpdate)))
+	r.Doc("fieldsUpdate")
+	r.Operation("fieldsUpdate")
+	addDefaults(r)
+	r.Notes("Authorization required. Only project owner can manage custom issue fields. " +
+		"Name and type can't be changed, because issues already have values")
+	r.Reads(project.Field{})
+	r.Writes(project.Field{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(FieldParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+
+	r = ws.DELETE(fmt.Sprintf("{%s}/fields/{%s}", ParamId, FieldParamId)).To(s.TakeProject(s.TakeField(s.fieldsDelete)))
+	r.Doc("fieldsDelete")
+	r.Operation("fieldsDelete")
+	addDefaults(r)
+	r.Notes("Authorization required. Values of the field are removed from project issues")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(FieldParamId, ""))
+	r.Do(services.Returns(
+		http.StatusNoContent,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusForbidden))
+	ws.Route(r)
+}
+
+func (s *ProjectService) fields(_ *restful.Request, resp *restful.Response, p *project.Project) {
+	results := p.Fields
+	if results == nil {
+		results = []*project.Field{}
+	}
+	result := &project.FieldList{
+		Meta:    pagination.Meta{Count: len(results)},
+		Results: results,
+	}
+	resp.WriteEntity(result)
+}
+
+func (s *ProjectService) fieldsCreate(req *restful.Request, resp *restful.Response, p *project.Project) {
+	raw := &project.Field{}
+	if sErr := readField(req, raw); sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	if p.GetFieldByName(raw.Name) != nil {
+		resp.WriteServiceError(http.StatusConflict, services.DuplicateErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	raw.Id = mgr.NewId()
+	p.Fields = append(p.Fields, raw)
+	if err := mgr.Projects.Update(p); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteHeader(http.StatusCreated)
+	resp.WriteEntity(raw)
+}
+
+func (s *ProjectService) fieldsUpdate(req *restful.Request, resp *restful.Response, p *project.Project, f *project.Field) {
+	raw := &project.Field{}
+	if sErr := readField(req, raw); sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	if raw.Name != f.Name || raw.Type != f.Type {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Name and type can't be changed"))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	raw.Id = f.Id
+	*f = *raw
+	if err := mgr.Projects.Update(p); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteEntity(f)
+}
+
+func (s *ProjectService) fieldsDelete(req *restful.Request, resp *restful.Response, p *project.Project, f *project.Field) {
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	fields := make([]*project.Field, 0, len(p.Fields)-1)
+	for _, field := range p.Fields {
+		if field.Id != f.Id {
+			fields = append(fields, field)
+		}
+	}
+	p.Fields = fields
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if err := mgr.Projects.Update(p); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	if err := mgr.Issues.RemoveField(p.Id, f.Name); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.ResponseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// Helpers
+
+func readField(req *restful.Request, raw *project.Field) *services.ErrResp {
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.WrongEntityErr}
+	}
+	if err := validate.Entity(raw); err != nil {
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewValidationErr(err)}
+	}
+	return nil
+}
+
+type FieldFunction func(*restful.Request, *restful.Response, *project.Project, *project.Field)
+
+// Decorate ProjectFunction. Look for field in project by FieldParamId
+// and add field object in the end. If field is not found then return Not Found.
+func (s *ProjectService) TakeField(fn FieldFunction) ProjectFunction {
+	return func(req *restful.Request, resp *restful.Response, p *project.Project) {
+		id := req.PathParameter(FieldParamId)
+		if !s.IsId(id) {
+			resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+			return
+		}
+
+		f := p.GetField(manager.ToId(id))
+		if f == nil {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		fn(req, resp, p, f)
+	}
+}
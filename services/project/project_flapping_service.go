@@ -0,0 +1,42 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/services"
+)
+
+func (s *ProjectService) RegisterFlapping(ws *restful.WebService) {
+	r := ws.GET(fmt.Sprintf("{%s}/flapping", ParamId)).To(s.TakeProject(s.flapping))
+	r.Doc("flapping")
+	r.Operation("flapping")
+	addDefaults(r)
+	r.Notes(fmt.Sprintf("How many resolved issues were reopened, issues reopened %d times or more are flapping. "+
+		"Get them by GET /api/v1/issues?flapping=true", issue.FlappingReopens))
+	r.Writes(issue.Flappiness{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	ws.Route(r)
+}
+
+func (s *ProjectService) flapping(req *restful.Request, resp *restful.Response, p *project.Project) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	result, err := mgr.Issues.Flappiness(p.Id)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(result)
+}
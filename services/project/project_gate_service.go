@@ -0,0 +1,66 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/services"
+)
+
+// max number of blocking issue ids returned to CI
+const gateIssuesLimit = 100
+
+func (s *ProjectService) RegisterGate(ws *restful.WebService) {
+	r := ws.GET(fmt.Sprintf("{%s}/gate", ParamId)).To(s.TakeProject(s.gate))
+	r.Doc("gate")
+	r.Operation("gate")
+	addDefaults(r)
+	r.Notes("Check the deploy gate of the project. Open issues with the gate severity and confidence or higher " +
+		"of targets in the gate environments block deploys")
+	r.Writes(project.GateResult{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.QueryParameter("target", "check only issues of the target"))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+}
+
+func (s *ProjectService) gate(req *restful.Request, resp *restful.Response, p *project.Project) {
+	var tgt bson.ObjectId
+	if id := req.QueryParameter("target"); id != "" {
+		if !bson.IsObjectIdHex(id) {
+			resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+			return
+		}
+		tgt = bson.ObjectIdHex(id)
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	gate := p.GetGate()
+	issues, count, err := mgr.Issues.Blocking(p, gate, tgt, gateIssuesLimit)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	result := &project.GateResult{
+		Passed:   count == 0,
+		Blocking: count,
+		Issues:   []bson.ObjectId{},
+		Gate:     gate,
+	}
+	for _, obj := range issues {
+		result.Issues = append(result.Issues, obj.Id)
+	}
+	resp.WriteEntity(result)
+}
@@ -90,7 +90,7 @@ func (s *ProjectService) membersCreate(req *restful.Request, resp *restful.Respo
 		}
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	mUser, err := mgr.Users.GetById(raw.User)
@@ -122,15 +122,9 @@ func (s *ProjectService) membersCreate(req *restful.Request, resp *restful.Respo
 }
 
 func (s *ProjectService) membersDelete(req *restful.Request, resp *restful.Response, p *project.Project, m *project.Member) {
-	members := make([]*project.Member, 0, len(p.Members)-1)
-	for _, member := range p.Members {
-		if member.User != m.User {
-			members = append(members, member)
-		}
-	}
-	p.Members = members
+	p.RemoveMember(m.User)
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	err := mgr.Projects.Update(p)
@@ -0,0 +1,70 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/notify"
+	"github.com/bearded-web/bearded/pkg/validate"
+	"github.com/bearded-web/bearded/services"
+)
+
+func (s *ProjectService) RegisterMsTeams(ws *restful.WebService) {
+	r := ws.POST(fmt.Sprintf("{%s}/msteams/test", ParamId)).To(s.TakeProject(s.msTeamsTest))
+	r.Doc("msTeamsTest")
+	r.Operation("msTeamsTest")
+	addDefaults(r)
+	r.Notes("Authorization required. Only project owner can test the channel. " +
+		"Posts a test card to the webhook from the body or to the saved one if url is empty, " +
+		"the response of teams is returned if the card is rejected")
+	r.Reads(project.MsTeams{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusNoContent,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+}
+
+func (s *ProjectService) msTeamsTest(req *restful.Request, resp *restful.Response, p *project.Project) {
+	raw := &project.MsTeams{}
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
+	}
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	if raw.Url == "" {
+		if p.MsTeams == nil {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("url is required, the project has no teams channel"))
+			return
+		}
+		raw = p.MsTeams
+	}
+	if err := raw.Validate(); err != nil {
+		services.NewValidationErr(validate.Nested("msTeams", err)).Write(resp)
+		return
+	}
+
+	n := &notify.Notification{
+		Subject: "Test notification",
+		Text:    fmt.Sprintf("Notifications of the project %s will be posted to this channel", p.Name),
+		Link:    fmt.Sprintf("/#/project/%s", p.Id.Hex()),
+	}
+	if err := s.Notifier.PostMsTeams(raw.Url, n); err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Test card isn't posted: %s", err))
+		return
+	}
+	resp.WriteHeader(http.StatusNoContent)
+}
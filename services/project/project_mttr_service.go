@@ -0,0 +1,62 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/services"
+)
+
+func (s *ProjectService) RegisterMttr(ws *restful.WebService) {
+	r := ws.GET(fmt.Sprintf("{%s}/mttr", ParamId)).To(s.TakeProject(s.mttr))
+	r.Doc("mttr")
+	r.Operation("mttr")
+	addDefaults(r)
+	r.Notes("Mean time to remediate issues resolved in the period by severity, assignee and month of resolution. " +
+		"False issues aren't counted, reopened ones are counted till the last resolution")
+	r.Writes(issue.Mttr{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.QueryParameter("from", "RFC3339 time, 12 months ago by default"))
+	r.Param(ws.QueryParameter("to", "RFC3339 time, now by default"))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+}
+
+func (s *ProjectService) mttr(req *restful.Request, resp *restful.Response, p *project.Project) {
+	now := time.Now().UTC()
+	from, err := parseTimeParam(req, "from", now.AddDate(-1, 0, 0))
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
+	to, err := parseTimeParam(req, "to", now)
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
+	if !to.After(from) {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("to should be after from"))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	result, err := mgr.Issues.Mttr(p.Id, from, to)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(result)
+}
@@ -0,0 +1,270 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/page"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/pkg/validate"
+	"github.com/bearded-web/bearded/services"
+)
+
+const (
+	PageParamId = "page-id"
+)
+
+func (s *ProjectService) RegisterPages(ws *restful.WebService) {
+	r := ws.GET(fmt.Sprintf("{%s}/pages", ParamId)).To(s.TakeProject(s.pages))
+	r.Doc("pages")
+	r.Operation("pages")
+	addDefaults(r)
+	r.Notes("Markdown pages of the project, like scoping notes and rules of engagement")
+	r.Writes(page.PageList{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/pages", ParamId)).To(s.TakeProject(s.pagesCreate))
+	r.Doc("pagesCreate")
+	r.Operation("pagesCreate")
+	addDefaults(r)
+	r.Notes("Authorization required. Text is sanitized like comments")
+	r.Reads(PageEntity{})
+	r.Writes(page.Page{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusCreated,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.GET(fmt.Sprintf("{%s}/pages/{%s}", ParamId, PageParamId)).To(s.TakeProject(s.TakePage(s.pagesGet)))
+	r.Doc("pagesGet")
+	r.Operation("pagesGet")
+	addDefaults(r)
+	r.Writes(page.Page{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(PageParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	ws.Route(r)
+
+	r = ws.PUT(fmt.Sprintf("{%s}/pages/{%s}", ParamId, PageParamId)).To(s.TakeProject(s.TakePage(s.pagesUpdate)))
+	r.Doc("pagesUpdate")
+	r.Operation("pagesUpdate")
+	addDefaults(r)
+	r.Notes("Authorization required. Every change makes a new revision, the previous one is kept in history. " +
+		"Pass the revision which was edited to get 409 instead of overwriting changes of someone else")
+	r.Reads(PageEntity{})
+	r.Writes(page.Page{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(PageParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusConflict))
+	ws.Route(r)
+
+	r = ws.DELETE(fmt.Sprintf("{%s}/pages/{%s}", ParamId, PageParamId)).To(s.TakeProject(s.TakePage(s.pagesDelete)))
+	r.Doc("pagesDelete")
+	r.Operation("pagesDelete")
+	addDefaults(r)
+	r.Notes("Authorization required. Only project owner can delete pages, history is deleted too")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(PageParamId, ""))
+	r.Do(services.Returns(
+		http.StatusNoContent,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusForbidden))
+	ws.Route(r)
+
+	r = ws.GET(fmt.Sprintf("{%s}/pages/{%s}/revisions", ParamId, PageParamId)).To(s.TakeProject(s.TakePage(s.pagesRevisions)))
+	r.Doc("pagesRevisions")
+	r.Operation("pagesRevisions")
+	addDefaults(r)
+	r.Notes("History of the page, the latest revision is the first")
+	r.Writes(page.RevisionList{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(PageParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	ws.Route(r)
+}
+
+func (s *ProjectService) pages(req *restful.Request, resp *restful.Response, p *project.Project) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	results, count, err := mgr.Pages.FilterByQuery(bson.M{"project": p.Id}, manager.Opts{Sort: []string{"title"}})
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	result := &page.PageList{
+		Meta:    pagination.Meta{Count: count},
+		Results: results,
+	}
+	resp.WriteEntity(result)
+}
+
+func readPage(req *restful.Request) (*PageEntity, *services.ErrResp) {
+	raw := &PageEntity{}
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		return nil, &services.ErrResp{Code: http.StatusBadRequest, Err: services.WrongEntityErr}
+	}
+	if err := validate.Entity(raw); err != nil {
+		return nil, &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewValidationErr(err)}
+	}
+	return raw, nil
+}
+
+func (s *ProjectService) pagesCreate(req *restful.Request, resp *restful.Response, p *project.Project) {
+	raw, sErr := readPage(req)
+	if sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	obj, err := mgr.Pages.Create(&page.Page{
+		Project: p.Id,
+		Title:   raw.Title,
+		Text:    raw.Text,
+		Author:  filters.GetUser(req).Id,
+	})
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteHeader(http.StatusCreated)
+	resp.WriteEntity(obj)
+}
+
+func (s *ProjectService) pagesGet(_ *restful.Request, resp *restful.Response, _ *project.Project, obj *page.Page) {
+	resp.WriteEntity(obj)
+}
+
+func (s *ProjectService) pagesUpdate(req *restful.Request, resp *restful.Response, p *project.Project, obj *page.Page) {
+	raw, sErr := readPage(req)
+	if sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+	changedErr := services.NewError(services.CodeDuplicate, "page is changed since the revision, reload it")
+	if raw.Revision != 0 && raw.Revision != obj.Revision {
+		resp.WriteServiceError(http.StatusConflict, changedErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	obj.Title = raw.Title
+	obj.Text = raw.Text
+	obj.Author = filters.GetUser(req).Id
+	if err := mgr.Pages.Update(obj); err != nil {
+		if mgr.IsNotFound(err) {
+			// the page is changed or removed after it was taken
+			resp.WriteServiceError(http.StatusConflict, changedErr)
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(obj)
+}
+
+func (s *ProjectService) pagesDelete(req *restful.Request, resp *restful.Response, p *project.Project, obj *page.Page) {
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if err := mgr.Pages.Remove(obj); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.ResponseWriter.WriteHeader(http.StatusNoContent)
+}
+
+func (s *ProjectService) pagesRevisions(req *restful.Request, resp *restful.Response, _ *project.Project, obj *page.Page) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	results, count, err := mgr.Pages.Revisions(obj.Id)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	result := &page.RevisionList{
+		Meta:    pagination.Meta{Count: count},
+		Results: results,
+	}
+	resp.WriteEntity(result)
+}
+
+type PageFunction func(*restful.Request, *restful.Response, *project.Project, *page.Page)
+
+// Decorate ProjectFunction. Look for page of the project by PageParamId
+// and add page object in the end. If page is not found then return Not Found.
+func (s *ProjectService) TakePage(fn PageFunction) ProjectFunction {
+	return func(req *restful.Request, resp *restful.Response, p *project.Project) {
+		id := req.PathParameter(PageParamId)
+		if !s.IsId(id) {
+			resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+			return
+		}
+		mgr := s.RequestManager(req)
+		defer mgr.Close()
+
+		obj, err := mgr.Pages.GetById(mgr.ToId(id))
+		if err != nil {
+			if mgr.IsNotFound(err) {
+				resp.WriteErrorString(http.StatusNotFound, "Not found")
+				return
+			}
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+		if obj.Project != p.Id {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		mgr.Close()
+
+		fn(req, resp, p, obj)
+	}
+}
@@ -0,0 +1,266 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/pkg/validate"
+	"github.com/bearded-web/bearded/services"
+)
+
+const (
+	RuleParamId = "rule-id"
+)
+
+func (s *ProjectService) RegisterRules(ws *restful.WebService) {
+	r := ws.GET(fmt.Sprintf("{%s}/rules", ParamId)).To(s.TakeProject(s.rules))
+	r.Doc("rules")
+	r.Operation("rules")
+	addDefaults(r)
+	r.Writes(project.RuleList{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/rules", ParamId)).To(s.TakeProject(s.rulesCreate))
+	r.Doc("rulesCreate")
+	r.Operation("rulesCreate")
+	addDefaults(r)
+	r.Notes("Authorization required. Only project owner can manage rules")
+	r.Reads(project.Rule{})
+	r.Writes(project.Rule{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusCreated,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/rules/test", ParamId)).To(s.TakeProject(s.rulesTest))
+	r.Doc("rulesTest")
+	r.Operation("rulesTest")
+	addDefaults(r)
+	r.Notes("Dry run of the rules for the issue, nothing is saved")
+	r.Reads(RuleTestEntity{})
+	r.Writes(RuleTestResult{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.PUT(fmt.Sprintf("{%s}/rules/{%s}", ParamId, RuleParamId)).To(s.TakeProject(s.TakeRule(s.rulesUpdate)))
+	r.Doc("rulesUpdate")
+	r.Operation("rulesUpdate")
+	addDefaults(r)
+	r.Notes("Authorization required. Only project owner can manage rules")
+	r.Reads(project.Rule{})
+	r.Writes(project.Rule{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(RuleParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+
+	r = ws.DELETE(fmt.Sprintf("{%s}/rules/{%s}", ParamId, RuleParamId)).To(s.TakeProject(s.TakeRule(s.rulesDelete)))
+	r.Doc("rulesDelete")
+	r.Operation("rulesDelete")
+	addDefaults(r)
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(RuleParamId, ""))
+	r.Do(services.Returns(
+		http.StatusNoContent,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusForbidden))
+	ws.Route(r)
+}
+
+func (s *ProjectService) rules(_ *restful.Request, resp *restful.Response, p *project.Project) {
+	results := p.Rules
+	if results == nil {
+		results = []*project.Rule{}
+	}
+	result := &project.RuleList{
+		Meta:    pagination.Meta{Count: len(results)},
+		Results: results,
+	}
+	resp.WriteEntity(result)
+}
+
+func (s *ProjectService) rulesCreate(req *restful.Request, resp *restful.Response, p *project.Project) {
+	raw := &project.Rule{}
+	if sErr := readRule(req, p, raw); sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	raw.Id = mgr.NewId()
+	p.Rules = append(p.Rules, raw)
+	if err := mgr.Projects.Update(p); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteHeader(http.StatusCreated)
+	resp.WriteEntity(raw)
+}
+
+func (s *ProjectService) rulesUpdate(req *restful.Request, resp *restful.Response, p *project.Project, rule *project.Rule) {
+	raw := &project.Rule{}
+	if sErr := readRule(req, p, raw); sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	raw.Id = rule.Id
+	*rule = *raw
+	if err := mgr.Projects.Update(p); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteEntity(rule)
+}
+
+func (s *ProjectService) rulesDelete(req *restful.Request, resp *restful.Response, p *project.Project, rule *project.Rule) {
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	rules := make([]*project.Rule, 0, len(p.Rules)-1)
+	for _, r := range p.Rules {
+		if r.Id != rule.Id {
+			rules = append(rules, r)
+		}
+	}
+	p.Rules = rules
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if err := mgr.Projects.Update(p); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.ResponseWriter.WriteHeader(http.StatusNoContent)
+}
+
+func (s *ProjectService) rulesTest(req *restful.Request, resp *restful.Response, p *project.Project) {
+	raw := &RuleTestEntity{}
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
+	}
+	rules := p.Rules
+	if raw.Rules != nil {
+		for i, rule := range raw.Rules {
+			if err := validate.Entity(rule); err != nil {
+				services.NewValidationErr(validate.Nested(fmt.Sprintf("rules.%d", i), err)).Write(resp)
+				return
+			}
+		}
+		rules = raw.Rules
+	}
+
+	obj := &issue.TargetIssue{Project: p.Id}
+	if raw.Url != "" {
+		obj.Vector = &issue.Vector{Url: raw.Url}
+	}
+	matched := project.ApplyRules(rules, raw.Plugin, obj)
+	result := &RuleTestResult{
+		Matched:  matched,
+		Assignee: obj.Assignee,
+		Team:     obj.Team,
+		Labels:   obj.Labels,
+	}
+	resp.WriteEntity(result)
+}
+
+// Helpers
+
+func readRule(req *restful.Request, p *project.Project, raw *project.Rule) *services.ErrResp {
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.WrongEntityErr}
+	}
+	if err := validate.Entity(raw); err != nil {
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewValidationErr(err)}
+	}
+	if raw.Assignee != "" && p.GetMember(raw.Assignee) == nil {
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewBadReq("assignee should be a project member")}
+	}
+	if raw.Team != "" && p.GetTeam(raw.Team) == nil {
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewBadReq("team should be a project team")}
+	}
+	return nil
+}
+
+type RuleFunction func(*restful.Request, *restful.Response, *project.Project, *project.Rule)
+
+// Decorate ProjectFunction. Look for rule in project by RuleParamId
+// and add rule object in the end. If rule is not found then return Not Found.
+func (s *ProjectService) TakeRule(fn RuleFunction) ProjectFunction {
+	return func(req *restful.Request, resp *restful.Response, p *project.Project) {
+		id := req.PathParameter(RuleParamId)
+		if !s.IsId(id) {
+			resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+			return
+		}
+
+		rule := p.GetRule(manager.ToId(id))
+		if rule == nil {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		fn(req, resp, p, rule)
+	}
+}
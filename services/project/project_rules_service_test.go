@@ -0,0 +1,117 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/filters"
+)
+
+func TestRules(t *testing.T) {
+	env, ts, u := newTestServer(t)
+	defer env.Close()
+	defer ts.Close()
+	mgr, sess := env.Mgr, env.Session
+	member, err := mgr.Users.Create(&user.User{Email: "member@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Convey("Given project", t, func() {
+		p, err := mgr.Projects.Create(&project.Project{
+			Name:    bson.NewObjectId().Hex(),
+			Owner:   u.Id,
+			Members: []*project.Member{{User: u.Id}, {User: member.Id}},
+		})
+		c.So(err, c.ShouldBeNil)
+		url := fmt.Sprintf("%s/api/v1/projects/%s/rules", ts.URL, p.Id.Hex())
+
+		c.Convey("Rules are empty", func() {
+			result := &project.RuleList{}
+			res := doJson(t, "GET", url, nil, result)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+			c.So(result.Count, c.ShouldEqual, 0)
+			c.So(result.Results, c.ShouldNotBeNil)
+		})
+
+		c.Convey("Create, update and delete rule", func() {
+			rule := &project.Rule{}
+			res := doJson(t, "POST", url, &project.Rule{
+				Name:     "wordpress",
+				Enabled:  true,
+				Plugin:   "barbudo/wpscan",
+				Assignee: member.Id,
+				Labels:   []string{"cms"},
+			}, rule)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusCreated)
+			c.So(rule.Id, c.ShouldNotEqual, "")
+			c.So(rule.Assignee, c.ShouldEqual, member.Id)
+
+			result := &project.RuleList{}
+			res = doJson(t, "GET", url, nil, result)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+			c.So(result.Count, c.ShouldEqual, 1)
+			c.So(result.Results[0].Name, c.ShouldEqual, "wordpress")
+
+			test := &RuleTestResult{}
+			res = doJson(t, "POST", url+"/test", &RuleTestEntity{Plugin: "barbudo/wpscan:0.1"}, test)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+			c.So(len(test.Matched), c.ShouldEqual, 1)
+			c.So(test.Assignee, c.ShouldEqual, member.Id)
+			c.So(test.Labels, c.ShouldResemble, []string{"cms"})
+
+			updated := &project.Rule{}
+			res = doJson(t, "PUT", url+"/"+rule.Id.Hex(), &project.Rule{
+				Name:   "admin pages",
+				Path:   "^/admin",
+				Labels: []string{"admin"},
+			}, updated)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+			c.So(updated.Id, c.ShouldEqual, rule.Id)
+			c.So(updated.Path, c.ShouldEqual, "^/admin")
+			c.So(updated.Assignee, c.ShouldEqual, "")
+
+			obj, err := mgr.Projects.GetById(p.Id)
+			c.So(err, c.ShouldBeNil)
+			c.So(len(obj.Rules), c.ShouldEqual, 1)
+			c.So(obj.Rules[0].Name, c.ShouldEqual, "admin pages")
+
+			res = doJson(t, "DELETE", url+"/"+rule.Id.Hex(), nil, nil)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusNoContent)
+			res = doJson(t, "DELETE", url+"/"+rule.Id.Hex(), nil, nil)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusNotFound)
+
+			obj, err = mgr.Projects.GetById(p.Id)
+			c.So(err, c.ShouldBeNil)
+			c.So(len(obj.Rules), c.ShouldEqual, 0)
+		})
+
+		c.Convey("Wrong rules aren't created", func() {
+			for _, rule := range []*project.Rule{
+				{Name: "no conditions", Labels: []string{"a"}},
+				{Name: "no actions", Plugin: "barbudo/wpscan"},
+				{Name: "bad path", Path: "(", Labels: []string{"a"}},
+				{Name: "not a member", Plugin: "barbudo/wpscan", Assignee: bson.NewObjectId()},
+			} {
+				res := doJson(t, "POST", url, rule, nil)
+				c.So(res.StatusCode, c.ShouldEqual, http.StatusBadRequest)
+			}
+		})
+
+		c.Convey("Only owner manages rules", func() {
+			sess.Set(filters.SessionUserKey, member.Id.Hex())
+			defer sess.Set(filters.SessionUserKey, u.Id.Hex())
+
+			res := doJson(t, "GET", url, nil, nil)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+			res = doJson(t, "POST", url, &project.Rule{Name: "a", Plugin: "b", Labels: []string{"c"}}, nil)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusForbidden)
+		})
+	})
+}
@@ -7,12 +7,15 @@ import (
 	"github.com/Sirupsen/logrus"
 	"github.com/emicklei/go-restful"
 	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2/bson"
 
+	"github.com/bearded-web/bearded/models/approval"
 	"github.com/bearded-web/bearded/models/project"
 	"github.com/bearded-web/bearded/pkg/filters"
 	"github.com/bearded-web/bearded/pkg/fltr"
 	"github.com/bearded-web/bearded/pkg/manager"
 	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/pkg/validate"
 	"github.com/bearded-web/bearded/services"
 )
 
@@ -81,22 +84,56 @@ func (s *ProjectService) Register(container *restful.Container) {
 	addDefaults(r)
 	ws.Route(r)
 
-	r = ws.PUT(fmt.Sprintf("{%s}", ParamId)).To(s.TakeProject(s.update))
-	// docs
-	r.Doc("update")
-	r.Operation("update")
+	for _, r := range services.UpdateRoutes(ws, fmt.Sprintf("{%s}", ParamId), s.TakeProject(s.update)) {
+		r.Param(ws.PathParameter(ParamId, ""))
+		r.Reads(ProjectEntity{})
+		r.Writes(project.Project{})
+		r.Do(services.Returns(
+			http.StatusOK,
+			http.StatusNotFound))
+		r.Do(services.ReturnsE(
+			http.StatusBadRequest,
+			http.StatusInternalServerError))
+		ws.Route(r)
+	}
+
+	r = ws.DELETE(fmt.Sprintf("{%s}", ParamId)).To(s.TakeProject(s.delete))
+	r.Doc("delete")
+	r.Operation("delete")
+	addDefaults(r)
+	r.Notes("Authorization required. Only project owner can delete the project with its targets, issues and scans. " +
+		"If approvals are enabled, the project is deleted after confirmation by a second admin. " +
+		"Children are removed in background, see /api/v1/cascades for progress, " +
+		"or the deletion is rejected with 409 if the block cascade policy is configured")
 	r.Param(ws.PathParameter(ParamId, ""))
-	r.Reads(ProjectEntity{})
-	r.Writes(project.Project{})
+	r.Writes(approval.Approval{})
 	r.Do(services.Returns(
-		http.StatusOK,
+		http.StatusNoContent,
+		http.StatusAccepted,
 		http.StatusNotFound))
 	r.Do(services.ReturnsE(
-		http.StatusBadRequest,
-		http.StatusInternalServerError))
+		http.StatusForbidden,
+		http.StatusConflict))
 	ws.Route(r)
 
 	s.RegisterMembers(ws)
+	s.RegisterTeams(ws)
+	s.RegisterTemplates(ws)
+	s.RegisterRules(ws)
+	s.RegisterBlackouts(ws)
+	s.RegisterFields(ws)
+	s.RegisterBoard(ws)
+	s.RegisterEffort(ws)
+	s.RegisterFlapping(ws)
+	s.RegisterAging(ws)
+	s.RegisterMttr(ws)
+	s.RegisterTrend(ws)
+	s.RegisterGate(ws)
+	s.RegisterUsage(ws)
+	s.RegisterApiUsage(ws)
+	s.RegisterDiscoveries(ws)
+	s.RegisterPages(ws)
+	s.RegisterMsTeams(ws)
 
 	container.Add(ws)
 }
@@ -111,10 +148,14 @@ func (s *ProjectService) create(req *restful.Request, resp *restful.Response) {
 		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
 		return
 	}
+	if err := validate.Entity(raw, "create"); err != nil {
+		services.NewValidationErr(err).Write(resp)
+		return
+	}
 
 	user := filters.GetUser(req)
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	obj := &project.Project{
@@ -153,13 +194,18 @@ func (s *ProjectService) list(req *restful.Request, resp *restful.Response) {
 	if !admin {
 		query = manager.Or(fltr.GetQuery(&manager.ProjectFltr{Owner: u.Id, Member: u.Id}))
 	}
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	skip, limit := s.Paginator.Parse(req)
+	sort, err := s.sorter.ParseStrict(req)
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
 
 	opt := manager.Opts{
-		Sort:  s.sorter.Parse(req),
+		Sort:  sort,
 		Limit: limit,
 		Skip:  skip,
 	}
@@ -186,14 +232,41 @@ func (s *ProjectService) get(_ *restful.Request, resp *restful.Response, p *proj
 	resp.WriteEntity(p)
 }
 
+func (s *ProjectService) delete(req *restful.Request, resp *restful.Response, p *project.Project) {
+	u := filters.GetUser(req)
+	if p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	services.SubmitApproval(resp, mgr, &approval.Approval{
+		Action:    approval.ActionProjectDelete,
+		Project:   p.Id,
+		Requester: u.Id,
+	})
+}
+
 func (s *ProjectService) update(req *restful.Request, resp *restful.Response, p *project.Project) {
 	raw := &ProjectEntity{}
 
-	if err := req.ReadEntity(raw); err != nil {
+	mask, err := services.ReadMasked(req, raw)
+	if err != nil {
 		logrus.Error(stackerr.Wrap(err))
 		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
 		return
 	}
+	// name couldn't be reset
+	tags := []string{}
+	if mask.Has("name") {
+		tags = append(tags, "create")
+	}
+	if err := validate.Entity(raw, tags...); err != nil {
+		services.NewValidationErr(err).Write(resp)
+		return
+	}
 
 	user := filters.GetUser(req)
 
@@ -202,12 +275,112 @@ func (s *ProjectService) update(req *restful.Request, resp *restful.Response, p
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
-	if raw.Name != "" {
+	if mask.Has("name") {
 		p.Name = raw.Name
 	}
+	if mask.Has("issueSort") {
+		p.IssueSort = ""
+		if raw.IssueSort != nil {
+			if _, err := manager.IssueSorter.ParseStringStrict(*raw.IssueSort); err != nil {
+				services.NewValidationErr(validate.NewError("issueSort", validate.CodeInvalid, err.Error())).Write(resp)
+				return
+			}
+			p.IssueSort = *raw.IssueSort
+		}
+	}
+	if mask.Has("escalation") {
+		if raw.Escalation != nil {
+			if err := raw.Escalation.Validate(p); err != nil {
+				services.NewValidationErr(validate.Nested("escalation", err)).Write(resp)
+				return
+			}
+		}
+		p.Escalation = raw.Escalation
+	}
+	if mask.Has("gate") {
+		if raw.Gate != nil {
+			if err := raw.Gate.Validate(); err != nil {
+				services.NewValidationErr(validate.Nested("gate", err)).Write(resp)
+				return
+			}
+		}
+		p.Gate = raw.Gate
+	}
+	if mask.Has("delivery") {
+		if raw.Delivery != nil {
+			if err := raw.Delivery.Validate(); err != nil {
+				services.NewValidationErr(validate.Nested("delivery", err)).Write(resp)
+				return
+			}
+			// last sent time is kept, so the report isn't sent twice in the week
+			raw.Delivery.LastSent = nil
+			if p.Delivery != nil {
+				raw.Delivery.LastSent = p.Delivery.LastSent
+			}
+		}
+		p.Delivery = raw.Delivery
+	}
+	if mask.Has("engagement") {
+		if raw.Engagement != nil {
+			if err := raw.Engagement.Validate(); err != nil {
+				services.NewValidationErr(validate.Nested("engagement", err)).Write(resp)
+				return
+			}
+		}
+		p.Engagement = raw.Engagement
+	}
+	if mask.Has("msTeams") {
+		if raw.MsTeams != nil {
+			if err := raw.MsTeams.Validate(); err != nil {
+				services.NewValidationErr(validate.Nested("msTeams", err)).Write(resp)
+				return
+			}
+		}
+		p.MsTeams = raw.MsTeams
+	}
+	if mask.Has("serviceNow") {
+		if raw.ServiceNow != nil {
+			if err := raw.ServiceNow.Validate(); err != nil {
+				services.NewValidationErr(validate.Nested("serviceNow", err)).Write(resp)
+				return
+			}
+		}
+		p.ServiceNow = raw.ServiceNow
+	}
+	if mask.Has("proxy") {
+		if raw.Proxy != nil {
+			if err := raw.Proxy.Validate(); err != nil {
+				services.NewValidationErr(validate.Nested("proxy", err)).Write(resp)
+				return
+			}
+			if raw.Proxy.IsSocks() {
+				// targets without own proxy would record traffic through the socks proxy
+				_, count, err := mgr.Targets.FilterByQuery(bson.M{"project": p.Id, "record": true, "proxy": nil}, manager.Opts{Limit: 1})
+				if err != nil {
+					logrus.Error(stackerr.Wrap(err))
+					resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+					return
+				}
+				if count > 0 {
+					services.NewValidationErr(validate.NewError("proxy", validate.CodeUnsupported, "project targets record traffic, it can't be recorded through socks5 proxies")).Write(resp)
+					return
+				}
+			}
+		}
+		p.Proxy = raw.Proxy
+	}
+	if mask.Has("rateLimit") {
+		p.RateLimit = nil
+		if raw.RateLimit != nil {
+			p.RateLimit = raw.RateLimit.WithDefaults(nil)
+		}
+	}
+	if mask.Has("requireReview") {
+		p.RequireReview = raw.RequireReview != nil && *raw.RequireReview
+	}
 	if err := mgr.Projects.Update(p); err != nil {
 		if mgr.IsDup(err) {
 			resp.WriteServiceError(
@@ -234,7 +407,7 @@ func (s *ProjectService) TakeProject(fn ProjectFunction) restful.RouteFunction {
 			resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
 			return
 		}
-		mgr := s.Manager()
+		mgr := s.RequestManager(req)
 		defer mgr.Close()
 
 		p, err := mgr.Projects.GetById(mgr.ToId(id))
@@ -253,6 +426,7 @@ func (s *ProjectService) TakeProject(fn ProjectFunction) restful.RouteFunction {
 			sErr.Write(resp)
 			return
 		}
+		filters.SetProject(req, p.Id)
 
 		mgr.Close()
 
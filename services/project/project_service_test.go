@@ -0,0 +1,55 @@
+package project
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/managertest"
+)
+
+// newTestServer serves the project service for the logged in owner, change the env session user to act as another one
+func newTestServer(t *testing.T) (*managertest.Env, *httptest.Server, *user.User) {
+	env := managertest.Open(t)
+	u, err := env.Login(&user.User{Email: "owner@example.com"})
+	if err != nil {
+		env.Close()
+		t.Fatal(err)
+	}
+	ts, err := env.Server(New(env.Base))
+	if err != nil {
+		env.Close()
+		t.Fatal(err)
+	}
+	return env, ts, u
+}
+
+// doJson sends the entity and decodes the response to result if the request succeeded
+func doJson(t *testing.T, method, url string, entity, result interface{}) *http.Response {
+	buf := bytes.NewBuffer(nil)
+	if entity != nil {
+		if err := json.NewEncoder(buf).Encode(entity); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, url, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if result != nil && res.StatusCode >= 200 && res.StatusCode < 300 {
+		if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return res
+}
@@ -0,0 +1,349 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/pkg/validate"
+	"github.com/bearded-web/bearded/services"
+)
+
+const TeamParamId = "team-id"
+
+func (s *ProjectService) RegisterTeams(ws *restful.WebService) {
+	r := ws.GET(fmt.Sprintf("{%s}/teams", ParamId)).To(s.TakeProject(s.teams))
+	r.Doc("teams")
+	r.Operation("teams")
+	addDefaults(r)
+	r.Writes(project.TeamList{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/teams", ParamId)).To(s.TakeProject(s.teamsCreate))
+	r.Doc("teamsCreate")
+	r.Operation("teamsCreate")
+	addDefaults(r)
+	r.Notes("Authorization required. Only project owner can manage teams, members should be project members")
+	r.Reads(project.Team{})
+	r.Writes(project.Team{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusCreated,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+
+	r = ws.GET(fmt.Sprintf("{%s}/teams/{%s}", ParamId, TeamParamId)).To(s.TakeProject(s.TakeTeam(s.teamsGet)))
+	r.Doc("teamsGet")
+	r.Operation("teamsGet")
+	addDefaults(r)
+	r.Writes(project.Team{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(TeamParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	ws.Route(r)
+
+	r = ws.PUT(fmt.Sprintf("{%s}/teams/{%s}", ParamId, TeamParamId)).To(s.TakeProject(s.TakeTeam(s.teamsUpdate)))
+	r.Doc("teamsUpdate")
+	r.Operation("teamsUpdate")
+	addDefaults(r)
+	r.Notes("Authorization required. Only project owner can manage teams")
+	r.Reads(project.Team{})
+	r.Writes(project.Team{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(TeamParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+
+	r = ws.DELETE(fmt.Sprintf("{%s}/teams/{%s}", ParamId, TeamParamId)).To(s.TakeProject(s.TakeTeam(s.teamsDelete)))
+	r.Doc("teamsDelete")
+	r.Operation("teamsDelete")
+	addDefaults(r)
+	r.Notes("Authorization required. Issues of the team become unassigned. " +
+		"Teams used by rules or escalation steps can't be removed")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(TeamParamId, ""))
+	r.Do(services.Returns(
+		http.StatusNoContent,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusForbidden,
+		http.StatusConflict))
+	ws.Route(r)
+
+	r = ws.PUT(fmt.Sprintf("{%s}/teams/{%s}/members/{%s}", ParamId, TeamParamId, MemberParamId)).
+		To(s.TakeProject(s.TakeTeam(s.teamMembersAdd)))
+	r.Doc("teamMembersAdd")
+	r.Operation("teamMembersAdd")
+	addDefaults(r)
+	r.Notes("Authorization required. Only project members could be added")
+	r.Writes(project.Team{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(TeamParamId, ""))
+	r.Param(ws.PathParameter(MemberParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+
+	r = ws.DELETE(fmt.Sprintf("{%s}/teams/{%s}/members/{%s}", ParamId, TeamParamId, MemberParamId)).
+		To(s.TakeProject(s.TakeTeam(s.teamMembersDelete)))
+	r.Doc("teamMembersDelete")
+	r.Operation("teamMembersDelete")
+	addDefaults(r)
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(TeamParamId, ""))
+	r.Param(ws.PathParameter(MemberParamId, ""))
+	r.Do(services.Returns(
+		http.StatusNoContent,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+}
+
+func (s *ProjectService) teams(_ *restful.Request, resp *restful.Response, p *project.Project) {
+	results := p.Teams
+	if results == nil {
+		results = []*project.Team{}
+	}
+	result := &project.TeamList{
+		Meta:    pagination.Meta{Count: len(results)},
+		Results: results,
+	}
+	resp.WriteEntity(result)
+}
+
+func (s *ProjectService) teamsCreate(req *restful.Request, resp *restful.Response, p *project.Project) {
+	raw := &project.Team{}
+	if sErr := readTeam(req, p, raw); sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	raw.Id = mgr.NewId()
+	p.Teams = append(p.Teams, raw)
+	if err := mgr.Projects.Update(p); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteHeader(http.StatusCreated)
+	resp.WriteEntity(raw)
+}
+
+func (s *ProjectService) teamsGet(_ *restful.Request, resp *restful.Response, _ *project.Project, t *project.Team) {
+	resp.WriteEntity(t)
+}
+
+func (s *ProjectService) teamsUpdate(req *restful.Request, resp *restful.Response, p *project.Project, t *project.Team) {
+	raw := &project.Team{Id: t.Id}
+	if sErr := readTeam(req, p, raw); sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	raw.Id = t.Id
+	*t = *raw
+	if err := mgr.Projects.Update(p); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteEntity(t)
+}
+
+func (s *ProjectService) teamsDelete(req *restful.Request, resp *restful.Response, p *project.Project, t *project.Team) {
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	if p.TeamUsed(t.Id) {
+		resp.WriteServiceError(http.StatusConflict, services.NewBadReq("Team is used by rules or escalation"))
+		return
+	}
+	teams := make([]*project.Team, 0, len(p.Teams)-1)
+	for _, team := range p.Teams {
+		if team.Id != t.Id {
+			teams = append(teams, team)
+		}
+	}
+	p.Teams = teams
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if err := mgr.Projects.Update(p); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	if err := mgr.Issues.RemoveTeam(p.Id, t.Id); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.ResponseWriter.WriteHeader(http.StatusNoContent)
+}
+
+func (s *ProjectService) teamMembersAdd(req *restful.Request, resp *restful.Response, p *project.Project, t *project.Team) {
+	id := req.PathParameter(MemberParamId)
+	if !s.IsId(id) {
+		resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+		return
+	}
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	userId := manager.ToId(id)
+	if p.GetMember(userId) == nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("User should be a project member"))
+		return
+	}
+	if t.HasMember(userId) {
+		resp.WriteEntity(t)
+		return
+	}
+	t.Members = append(t.Members, userId)
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if err := mgr.Projects.Update(p); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteEntity(t)
+}
+
+func (s *ProjectService) teamMembersDelete(req *restful.Request, resp *restful.Response, p *project.Project, t *project.Team) {
+	id := req.PathParameter(MemberParamId)
+	if !s.IsId(id) {
+		resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+		return
+	}
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	if !t.RemoveMember(manager.ToId(id)) {
+		resp.WriteErrorString(http.StatusNotFound, "Not found")
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if err := mgr.Projects.Update(p); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.ResponseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// Helpers
+
+func readTeam(req *restful.Request, p *project.Project, raw *project.Team) *services.ErrResp {
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.WrongEntityErr}
+	}
+	if err := validate.Entity(raw); err != nil {
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewValidationErr(err)}
+	}
+	if err := raw.Validate(p); err != nil {
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewValidationErr(err)}
+	}
+	if raw.Members == nil {
+		raw.Members = []bson.ObjectId{}
+	}
+	return nil
+}
+
+type TeamFunction func(*restful.Request, *restful.Response, *project.Project, *project.Team)
+
+// Decorate ProjectFunction. Look for team in project by TeamParamId
+// and add team object in the end. If team is not found then return Not Found.
+func (s *ProjectService) TakeTeam(fn TeamFunction) ProjectFunction {
+	return func(req *restful.Request, resp *restful.Response, p *project.Project) {
+		id := req.PathParameter(TeamParamId)
+		if !s.IsId(id) {
+			resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+			return
+		}
+
+		t := p.GetTeam(manager.ToId(id))
+		if t == nil {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		fn(req, resp, p, t)
+	}
+}
@@ -0,0 +1,216 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/pkg/validate"
+	"github.com/bearded-web/bearded/services"
+)
+
+const (
+	TemplateParamId = "template-id"
+)
+
+func (s *ProjectService) RegisterTemplates(ws *restful.WebService) {
+	r := ws.GET(fmt.Sprintf("{%s}/templates", ParamId)).To(s.TakeProject(s.templates))
+	r.Doc("templates")
+	r.Operation("templates")
+	addDefaults(r)
+	r.Writes(project.IssueTemplateList{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/templates", ParamId)).To(s.TakeProject(s.templatesCreate))
+	r.Doc("templatesCreate")
+	r.Operation("templatesCreate")
+	addDefaults(r)
+	r.Notes("Authorization required. Only project owner can manage templates")
+	r.Reads(project.IssueTemplate{})
+	r.Writes(project.IssueTemplate{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusCreated,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+
+	r = ws.PUT(fmt.Sprintf("{%s}/templates/{%s}", ParamId, TemplateParamId)).To(s.TakeProject(s.TakeTemplate(s.templatesUpdate)))
+	r.Doc("templatesUpdate")
+	r.Operation("templatesUpdate")
+	addDefaults(r)
+	r.Notes("Authorization required. Only project owner can manage templates")
+	r.Reads(project.IssueTemplate{})
+	r.Writes(project.IssueTemplate{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(TemplateParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+
+	r = ws.DELETE(fmt.Sprintf("{%s}/templates/{%s}", ParamId, TemplateParamId)).To(s.TakeProject(s.TakeTemplate(s.templatesDelete)))
+	r.Doc("templatesDelete")
+	r.Operation("templatesDelete")
+	addDefaults(r)
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(TemplateParamId, ""))
+	r.Do(services.Returns(
+		http.StatusNoContent,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusForbidden))
+	ws.Route(r)
+}
+
+func (s *ProjectService) templates(_ *restful.Request, resp *restful.Response, p *project.Project) {
+	results := p.Templates
+	if results == nil {
+		results = []*project.IssueTemplate{}
+	}
+	result := &project.IssueTemplateList{
+		Meta:    pagination.Meta{Count: len(results)},
+		Results: results,
+	}
+	resp.WriteEntity(result)
+}
+
+func (s *ProjectService) templatesCreate(req *restful.Request, resp *restful.Response, p *project.Project) {
+	raw := &project.IssueTemplate{}
+	if sErr := readTemplate(req, raw); sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	raw.Id = mgr.NewId()
+	p.Templates = append(p.Templates, raw)
+	if err := mgr.Projects.Update(p); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteHeader(http.StatusCreated)
+	resp.WriteEntity(raw)
+}
+
+func (s *ProjectService) templatesUpdate(req *restful.Request, resp *restful.Response, p *project.Project, t *project.IssueTemplate) {
+	raw := &project.IssueTemplate{}
+	if sErr := readTemplate(req, raw); sErr != nil {
+		sErr.Write(resp)
+		return
+	}
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	raw.Id = t.Id
+	*t = *raw
+	if err := mgr.Projects.Update(p); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteEntity(t)
+}
+
+func (s *ProjectService) templatesDelete(req *restful.Request, resp *restful.Response, p *project.Project, t *project.IssueTemplate) {
+	if u := filters.GetUser(req); p.Owner != u.Id {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	templates := make([]*project.IssueTemplate, 0, len(p.Templates)-1)
+	for _, template := range p.Templates {
+		if template.Id != t.Id {
+			templates = append(templates, template)
+		}
+	}
+	p.Templates = templates
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if err := mgr.Projects.Update(p); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.ResponseWriter.WriteHeader(http.StatusNoContent)
+}
+
+// Helpers
+
+func readTemplate(req *restful.Request, raw *project.IssueTemplate) *services.ErrResp {
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.WrongEntityErr}
+	}
+	if err := validate.Entity(raw); err != nil {
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewValidationErr(err)}
+	}
+	if raw.Severity != "" && !raw.Severity.IsValid() {
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewBadReq("severity should be one of [high|medium|low|info]")}
+	}
+	return nil
+}
+
+type TemplateFunction func(*restful.Request, *restful.Response, *project.Project, *project.IssueTemplate)
+
+// Decorate ProjectFunction. Look for template in project by TemplateParamId
+// and add template object in the end. If template is not found then return Not Found.
+func (s *ProjectService) TakeTemplate(fn TemplateFunction) ProjectFunction {
+	return func(req *restful.Request, resp *restful.Response, p *project.Project) {
+		id := req.PathParameter(TemplateParamId)
+		if !s.IsId(id) {
+			resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+			return
+		}
+
+		t := p.GetTemplate(manager.ToId(id))
+		if t == nil {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		fn(req, resp, p, t)
+	}
+}
@@ -0,0 +1,109 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/filters"
+)
+
+func TestTemplates(t *testing.T) {
+	env, ts, u := newTestServer(t)
+	defer env.Close()
+	defer ts.Close()
+	mgr, sess := env.Mgr, env.Session
+	member, err := mgr.Users.Create(&user.User{Email: "member@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Convey("Given project", t, func() {
+		p, err := mgr.Projects.Create(&project.Project{
+			Name:    bson.NewObjectId().Hex(),
+			Owner:   u.Id,
+			Members: []*project.Member{{User: u.Id}, {User: member.Id}},
+		})
+		c.So(err, c.ShouldBeNil)
+		url := fmt.Sprintf("%s/api/v1/projects/%s/templates", ts.URL, p.Id.Hex())
+
+		c.Convey("Templates are empty", func() {
+			result := &project.IssueTemplateList{}
+			res := doJson(t, "GET", url, nil, result)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+			c.So(result.Count, c.ShouldEqual, 0)
+			c.So(result.Results, c.ShouldNotBeNil)
+		})
+
+		c.Convey("Create, update and delete template", func() {
+			tmpl := &project.IssueTemplate{}
+			res := doJson(t, "POST", url, &project.IssueTemplate{
+				Name:     "xss",
+				Summary:  "Cross site scripting",
+				Severity: issue.SeverityMedium,
+				Desc:     "## Steps",
+			}, tmpl)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusCreated)
+			c.So(tmpl.Id, c.ShouldNotEqual, "")
+			c.So(tmpl.Severity, c.ShouldEqual, issue.SeverityMedium)
+
+			result := &project.IssueTemplateList{}
+			res = doJson(t, "GET", url, nil, result)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+			c.So(result.Count, c.ShouldEqual, 1)
+			c.So(result.Results[0].Name, c.ShouldEqual, "xss")
+
+			updated := &project.IssueTemplate{}
+			res = doJson(t, "PUT", url+"/"+tmpl.Id.Hex(), &project.IssueTemplate{
+				Name:     "stored xss",
+				Severity: issue.SeverityHigh,
+			}, updated)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+			c.So(updated.Id, c.ShouldEqual, tmpl.Id)
+			c.So(updated.Severity, c.ShouldEqual, issue.SeverityHigh)
+			c.So(updated.Desc, c.ShouldEqual, "")
+
+			obj, err := mgr.Projects.GetById(p.Id)
+			c.So(err, c.ShouldBeNil)
+			c.So(len(obj.Templates), c.ShouldEqual, 1)
+			c.So(obj.Templates[0].Name, c.ShouldEqual, "stored xss")
+
+			res = doJson(t, "DELETE", url+"/"+tmpl.Id.Hex(), nil, nil)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusNoContent)
+			res = doJson(t, "PUT", url+"/"+tmpl.Id.Hex(), &project.IssueTemplate{Name: "gone"}, nil)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusNotFound)
+
+			obj, err = mgr.Projects.GetById(p.Id)
+			c.So(err, c.ShouldBeNil)
+			c.So(len(obj.Templates), c.ShouldEqual, 0)
+		})
+
+		c.Convey("Wrong templates aren't created", func() {
+			for _, tmpl := range []*project.IssueTemplate{
+				{Summary: "without name"},
+				{Name: "error severity", Severity: issue.SeverityError},
+			} {
+				res := doJson(t, "POST", url, tmpl, nil)
+				c.So(res.StatusCode, c.ShouldEqual, http.StatusBadRequest)
+			}
+			res := doJson(t, "PUT", url+"/bad", &project.IssueTemplate{Name: "a"}, nil)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusBadRequest)
+		})
+
+		c.Convey("Only owner manages templates", func() {
+			sess.Set(filters.SessionUserKey, member.Id.Hex())
+			defer sess.Set(filters.SessionUserKey, u.Id.Hex())
+
+			res := doJson(t, "GET", url, nil, nil)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+			res = doJson(t, "POST", url, &project.IssueTemplate{Name: "a"}, nil)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusForbidden)
+		})
+	})
+}
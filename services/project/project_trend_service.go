@@ -0,0 +1,63 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/stats"
+	"github.com/bearded-web/bearded/services"
+)
+
+const (
+	defaultTrendDays = 30
+	maxTrendDays     = 366
+)
+
+func (s *ProjectService) RegisterTrend(ws *restful.WebService) {
+	r := ws.GET(fmt.Sprintf("{%s}/trend", ParamId)).To(s.TakeProject(s.trend))
+	r.Doc("trend")
+	r.Operation("trend")
+	addDefaults(r)
+	r.Notes("Issues by status and severity at the end of every day, they are taken from nightly snapshots. " +
+		"Today isn't included, days before the first snapshot are skipped")
+	r.Writes(stats.Trend{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.QueryParameter("days", fmt.Sprintf("the last days, %d by default, max %d", defaultTrendDays, maxTrendDays)).DataType("integer"))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+}
+
+func (s *ProjectService) trend(req *restful.Request, resp *restful.Response, p *project.Project) {
+	days := defaultTrendDays
+	if param := req.QueryParameter("days"); param != "" {
+		val, err := strconv.Atoi(param)
+		if err != nil || val <= 0 || val > maxTrendDays {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("days should be from 1 to %d", maxTrendDays))
+			return
+		}
+		days = val
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	result := stats.NewTrend(p.Id, time.Now().UTC(), days)
+	snapshots, err := mgr.Snapshots.Range(p.Id, result.From, result.To)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	result.Results = snapshots
+	resp.WriteEntity(result)
+}
@@ -0,0 +1,212 @@
+package project
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/validate"
+	"github.com/bearded-web/bearded/services"
+)
+
+func (s *ProjectService) RegisterUsage(ws *restful.WebService) {
+	r := ws.GET(fmt.Sprintf("{%s}/usage", ParamId)).To(s.TakeProject(s.usage))
+	r.Doc("usage")
+	r.Operation("usage")
+	addDefaults(r)
+	r.Notes("Resources consumed by scans created in the period and the project quota")
+	r.Writes(UsageEntity{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.QueryParameter("from", "RFC3339 time, the beginning of this month by default"))
+	r.Param(ws.QueryParameter("to", "RFC3339 time, not limited by default"))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.GET(fmt.Sprintf("{%s}/quota", ParamId)).To(s.TakeProject(s.quotaGet))
+	r.Doc("quotaGet")
+	r.Operation("quotaGet")
+	addDefaults(r)
+	r.Notes("Effective project quota with current usage of every limit")
+	r.Writes(QuotaEntity{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	ws.Route(r)
+
+	r = ws.PUT(fmt.Sprintf("{%s}/quota", ParamId)).To(s.TakeProject(s.quotaUpdate))
+	r.Doc("quotaUpdate")
+	r.Operation("quotaUpdate")
+	addDefaults(r)
+	r.Notes("Authorization required. Only admins can change quotas")
+	r.Reads(project.Quota{})
+	r.Writes(project.Quota{})
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusForbidden))
+	ws.Route(r)
+
+	r = ws.DELETE(fmt.Sprintf("{%s}/quota", ParamId)).To(s.TakeProject(s.quotaDelete))
+	r.Doc("quotaDelete")
+	r.Operation("quotaDelete")
+	addDefaults(r)
+	r.Notes("Authorization required. Only admins can change quotas, server defaults are used after removal")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Do(services.Returns(
+		http.StatusNoContent,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusForbidden))
+	ws.Route(r)
+}
+
+func (s *ProjectService) usage(req *restful.Request, resp *restful.Response, p *project.Project) {
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	from, err := parseTimeParam(req, "from", month)
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
+	to, err := parseTimeParam(req, "to", time.Time{})
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
+	if !to.IsZero() && !to.After(from) {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("to should be after from"))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	report, err := mgr.Scans.Usage(p.Id, from, to)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	result := &UsageEntity{
+		UsageReport: report,
+		Quota:       p.GetQuota(mgr.Cfg.Quota),
+	}
+	if from.Equal(month) && to.IsZero() {
+		result.MonthAgentSeconds = report.AgentSeconds
+	} else {
+		monthReport, err := mgr.Scans.Usage(p.Id, month, time.Time{})
+		if err != nil {
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+		result.MonthAgentSeconds = monthReport.AgentSeconds
+	}
+	resp.WriteEntity(result)
+}
+
+func (s *ProjectService) quotaGet(req *restful.Request, resp *restful.Response, p *project.Project) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	result := &QuotaEntity{
+		Quota:   p.GetQuota(mgr.Cfg.Quota),
+		Default: p.Quota == nil,
+	}
+	now := time.Now().UTC()
+	month, err := mgr.Scans.Usage(p.Id, time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), time.Time{})
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	result.Used.AgentMinutes = month.AgentSeconds / 60
+	if result.Used.MaxScans, err = mgr.Scans.Running(p.Id); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	if result.Used.ScansPerDay, err = mgr.Scans.CreatedToday(p.Id); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	if result.Used.Targets, err = mgr.Targets.Count(p.Id); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	size, err := mgr.Files.ProjectSize(p.Id)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	result.Used.Storage = (size + 1<<20 - 1) >> 20
+	resp.WriteEntity(result)
+}
+
+func (s *ProjectService) quotaUpdate(req *restful.Request, resp *restful.Response, p *project.Project) {
+	raw := &project.Quota{}
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
+	}
+	if err := validate.Entity(raw); err != nil {
+		services.NewValidationErr(err).Write(resp)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if !mgr.Permission.IsAdmin(filters.GetUser(req)) {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	p.Quota = raw
+	if err := mgr.Projects.Update(p); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(p.Quota)
+}
+
+func (s *ProjectService) quotaDelete(req *restful.Request, resp *restful.Response, p *project.Project) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if !mgr.Permission.IsAdmin(filters.GetUser(req)) {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+	p.Quota = nil
+	if err := mgr.Projects.Update(p); err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.ResponseWriter.WriteHeader(http.StatusNoContent)
+}
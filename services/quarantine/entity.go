@@ -0,0 +1,5 @@
+package quarantine
+
+type ReleaseEntity struct {
+	Data string `json:"data,omitempty" description:"corrected report, the original data is used if empty"`
+}
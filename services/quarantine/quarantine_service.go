@@ -0,0 +1,262 @@
+package quarantine
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/report"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/fltr"
+	"github.com/bearded-web/bearded/pkg/ingest"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/services"
+)
+
+const ParamId = "quarantine-id"
+
+type QuarantineService struct {
+	*services.BaseService
+}
+
+func New(base *services.BaseService) *QuarantineService {
+	return &QuarantineService{
+		BaseService: base,
+	}
+}
+
+func addDefaults(r *restful.RouteBuilder) {
+	r.Notes("Authorization required, only for admins")
+	r.Do(services.ReturnsE(
+		http.StatusUnauthorized,
+		http.StatusForbidden,
+		http.StatusInternalServerError,
+	))
+}
+
+func (s *QuarantineService) Register(container *restful.Container) {
+	ws := &restful.WebService{}
+	ws.Path("/api/v1/quarantine")
+	ws.Doc("Review reports which failed validation")
+	ws.Consumes(restful.MIME_JSON)
+	ws.Produces(restful.MIME_JSON)
+	ws.Filter(filters.AuthTokenFilter(s.BaseManager()))
+	ws.Filter(filters.AuthRequiredFilter(s.BaseManager()))
+	ws.Filter(filters.AdminRequiredFilter(s.BaseManager()))
+
+	r := ws.GET("").To(s.list)
+	addDefaults(r)
+	r.Doc("list")
+	r.Operation("list")
+	r.Notes("Reports are listed without data, get a report by id to review its data")
+	s.SetParams(r, fltr.GetParams(ws, manager.QuarantineFltr{}))
+	r.Param(s.Paginator.SkipParam())
+	r.Param(s.Paginator.LimitParam())
+	r.Writes(report.QuarantineList{})
+	r.Do(services.Returns(http.StatusOK))
+	ws.Route(r)
+
+	r = ws.GET(fmt.Sprintf("{%s}", ParamId)).To(s.TakeQuarantine(s.get))
+	addDefaults(r)
+	r.Doc("get")
+	r.Operation("get")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Writes(report.Quarantine{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/release", ParamId)).To(s.TakeQuarantine(s.release))
+	addDefaults(r)
+	r.Doc("release")
+	r.Operation("release")
+	r.Notes("Ingest the report, corrected data could be passed to fix validation errors")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Reads(ReleaseEntity{})
+	r.Writes(report.Quarantine{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusConflict,
+		http.StatusUnprocessableEntity))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/discard", ParamId)).To(s.TakeQuarantine(s.discard))
+	addDefaults(r)
+	r.Doc("discard")
+	r.Operation("discard")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Writes(report.Quarantine{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	container.Add(ws)
+}
+
+// ====== service operations
+
+func (s *QuarantineService) list(req *restful.Request, resp *restful.Response) {
+	query, err := fltr.FromRequest(req, manager.QuarantineFltr{})
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	skip, limit := s.Paginator.Parse(req)
+	results, count, err := mgr.Quarantine.FilterByQuery(query, manager.Opts{
+		Sort:   []string{"-created"},
+		Skip:   skip,
+		Limit:  limit,
+		Select: bson.M{"data": 0},
+	})
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	previous, next := s.Paginator.Urls(req, skip, limit, count)
+	result := &report.QuarantineList{
+		Meta:    pagination.Meta{Count: count, Previous: previous, Next: next},
+		Results: results,
+	}
+	resp.WriteEntity(result)
+}
+
+func (s *QuarantineService) get(_ *restful.Request, resp *restful.Response, obj *report.Quarantine) {
+	resp.WriteEntity(obj)
+}
+
+func (s *QuarantineService) release(req *restful.Request, resp *restful.Response, obj *report.Quarantine) {
+	raw := &ReleaseEntity{}
+	if req.Request.ContentLength != 0 {
+		if err := req.ReadEntity(raw); err != nil {
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+			return
+		}
+	}
+	if obj.Status != report.QuarantinePending {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("only pending reports could be released"))
+		return
+	}
+
+	data := obj.Data
+	if raw.Data != "" {
+		data = raw.Data
+	}
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	sc, err := mgr.Scans.GetById(obj.Scan)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	sess := sc.GetSession(obj.ScanSession)
+	if sess == nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("scan session is not found"))
+		return
+	}
+	sevMap, err := ingest.SeverityMap(mgr, sess)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	rep, problems := ingest.Parse([]byte(data), sevMap)
+	if len(problems) > 0 {
+		resp.WriteServiceError(http.StatusUnprocessableEntity,
+			services.NewBadReq("report is still malformed: %s", strings.Join(problems, "; ")))
+		return
+	}
+
+	rep, err = ingest.Store(mgr, rep, sc, sess)
+	if err != nil {
+		if mgr.IsDup(err) {
+			resp.WriteServiceError(
+				http.StatusConflict,
+				services.NewError(services.CodeDuplicate, "report with this scan session is existed"))
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	obj.Data = data
+	obj.Report = rep.Id
+	s.review(req, resp, mgr, obj, report.QuarantineReleased)
+}
+
+func (s *QuarantineService) discard(req *restful.Request, resp *restful.Response, obj *report.Quarantine) {
+	if obj.Status != report.QuarantinePending {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("only pending reports could be discarded"))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	s.review(req, resp, mgr, obj, report.QuarantineDiscarded)
+}
+
+func (s *QuarantineService) review(req *restful.Request, resp *restful.Response,
+	mgr *manager.Manager, obj *report.Quarantine, status report.QuarantineStatus) {
+
+	obj.Status = status
+	obj.Reviewer = filters.GetUser(req).Id
+	if err := mgr.Quarantine.Update(obj); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(obj)
+}
+
+// Helpers
+
+func (s *QuarantineService) TakeQuarantine(fn func(*restful.Request,
+	*restful.Response, *report.Quarantine)) restful.RouteFunction {
+	return func(req *restful.Request, resp *restful.Response) {
+		id := req.PathParameter(ParamId)
+		if !s.IsId(id) {
+			resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+			return
+		}
+
+		mgr := s.RequestManager(req)
+		defer mgr.Close()
+
+		obj, err := mgr.Quarantine.GetById(mgr.ToId(id))
+		if err != nil {
+			if mgr.IsNotFound(err) {
+				resp.WriteErrorString(http.StatusNotFound, "Not found")
+				return
+			}
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+		mgr.Close()
+		fn(req, resp, obj)
+	}
+}
@@ -0,0 +1,102 @@
+package quarantine
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/report"
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/managertest"
+)
+
+func TestQuarantine(t *testing.T) {
+	env := managertest.Open(t)
+	defer env.Close()
+	mgr, sess := env.Mgr, env.Session
+
+	mgr.Permission.SetAdmins([]string{"admin@example.com"})
+	u, err := mgr.Users.Create(&user.User{Email: "user@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := env.Login(&user.User{Email: "admin@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts, err := env.Server(New(env.Base))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+
+	c.Convey("Given quarantined reports", t, func() {
+		scan := bson.NewObjectId()
+		for _, data := range []string{`{"version": 1}`, `{"version": 2}`} {
+			_, err := mgr.Quarantine.Create(&report.Quarantine{
+				Scan:        scan,
+				ScanSession: bson.NewObjectId(),
+				Errors:      []string{"malformed"},
+				Data:        data,
+			})
+			c.So(err, c.ShouldBeNil)
+		}
+
+		c.Convey("List is paginated and doesn't return data", func() {
+			res, err := http.Get(ts.URL + "/api/v1/quarantine?limit=1&scan=" + scan.Hex())
+			c.So(err, c.ShouldBeNil)
+			defer res.Body.Close()
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+			result := &report.QuarantineList{}
+			c.So(json.NewDecoder(res.Body).Decode(result), c.ShouldBeNil)
+			c.So(result.Count, c.ShouldEqual, 2)
+			c.So(result.Next, c.ShouldNotEqual, "")
+			c.So(len(result.Results), c.ShouldEqual, 1)
+			c.So(result.Results[0].Data, c.ShouldEqual, "")
+			c.So(len(result.Results[0].Errors), c.ShouldEqual, 1)
+
+			c.Convey("Get returns data", func() {
+				res, err := http.Get(ts.URL + "/api/v1/quarantine/" + result.Results[0].Id.Hex())
+				c.So(err, c.ShouldBeNil)
+				defer res.Body.Close()
+				c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+				obj := &report.Quarantine{}
+				c.So(json.NewDecoder(res.Body).Decode(obj), c.ShouldBeNil)
+				c.So(obj.Data, c.ShouldNotEqual, "")
+			})
+
+			c.Convey("Discard marks report as discarded once", func() {
+				url := ts.URL + "/api/v1/quarantine/" + result.Results[0].Id.Hex() + "/discard"
+				res, err := http.Post(url, "application/json", nil)
+				c.So(err, c.ShouldBeNil)
+				res.Body.Close()
+				c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+
+				obj, err := mgr.Quarantine.GetById(result.Results[0].Id)
+				c.So(err, c.ShouldBeNil)
+				c.So(obj.Status, c.ShouldEqual, report.QuarantineDiscarded)
+				c.So(obj.Reviewer, c.ShouldEqual, admin.Id)
+
+				res, err = http.Post(url, "application/json", nil)
+				c.So(err, c.ShouldBeNil)
+				res.Body.Close()
+				c.So(res.StatusCode, c.ShouldEqual, http.StatusBadRequest)
+			})
+		})
+
+		c.Convey("Non admins are forbidden", func() {
+			sess.Set(filters.SessionUserKey, u.Id.Hex())
+			defer sess.Set(filters.SessionUserKey, admin.Id.Hex())
+
+			res, err := http.Get(ts.URL + "/api/v1/quarantine")
+			c.So(err, c.ShouldBeNil)
+			res.Body.Close()
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusForbidden)
+		})
+	})
+}
@@ -45,6 +45,11 @@ func Returns(codes ...int) func(*restful.RouteBuilder) {
 func ReturnsE(codes ...int) func(*restful.RouteBuilder) {
 	return func(b *restful.RouteBuilder) {
 		for _, code := range codes {
+			if code == http.StatusBadRequest {
+				// fields are set only for validation errors
+				b.Returns(code, http.StatusText(code), ValidationErr{})
+				continue
+			}
 			b.Returns(code, http.StatusText(code), restful.ServiceError{})
 		}
 	}
@@ -1,7 +1,26 @@
 package scan
 
-import "github.com/bearded-web/bearded/models/scan"
+import (
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/scan"
+)
 
 type SessionUpdateEntity struct {
 	Status scan.ScanStatus `json:"status" description:"one of [working|finished|failed]"`
+	Agent  bson.ObjectId   `json:"agent,omitempty" description:"agent which the session was queued to, conflict is returned if the session is given to another agent"`
+}
+
+type ReviewEntity struct {
+	Notes string `json:"notes,omitempty" description:"reviewer notes, max 5000 symbols" validate:"max=5000"`
+}
+
+type ProgressEntity struct {
+	Percent int    `json:"percent" description:"from 0 to 100" validate:"min=0,max=100"`
+	Message string `json:"message,omitempty" description:"what plugin is doing now, max 200 symbols" validate:"max=200"`
+}
+
+type ScopeEntity struct {
+	Url     string `json:"url"`
+	InScope bool   `json:"inScope" description:"url host is the scan target host or its subdomain"`
 }
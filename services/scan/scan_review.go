@@ -0,0 +1,79 @@
+package scan
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/validate"
+	"github.com/bearded-web/bearded/services"
+)
+
+func (s *ScanService) RegisterReview(ws *restful.WebService) {
+	r := ws.POST(fmt.Sprintf("{%s}/review", ParamId)).To(s.TakeScan(s.review))
+	r.Doc("review")
+	r.Operation("review")
+	addDefaults(r)
+	r.Notes("Sign off finished scan results. Issues of the scan are counted in target summaries " +
+		"after the review, if the project requires it")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Reads(ReviewEntity{})
+	r.Writes(scan.Scan{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusConflict))
+	ws.Route(r)
+}
+
+func (s *ScanService) review(req *restful.Request, resp *restful.Response, sc *scan.Scan) {
+	raw := &ReviewEntity{}
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
+	}
+	if err := validate.Entity(raw); err != nil {
+		services.NewValidationErr(err).Write(resp)
+		return
+	}
+	if !sc.CanReview() {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("only finished or failed scans could be reviewed"))
+		return
+	}
+	if sc.IsReviewed() {
+		resp.WriteServiceError(http.StatusConflict, services.NewError(services.CodeDuplicate, "scan is already reviewed"))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	sc.Review = &scan.Review{
+		User:    filters.GetUser(req).Id,
+		Notes:   raw.Notes,
+		Created: time.Now().UTC(),
+	}
+	if err := mgr.Scans.Update(sc); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	if err := mgr.Feed.UpdateScan(sc); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+	}
+	if _, err := mgr.Issues.ReleasePending(sc.Id); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(sc)
+}
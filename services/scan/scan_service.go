@@ -1,17 +1,14 @@
 package scan
 
 import (
-	"bytes"
 	"fmt"
 	"net/http"
-	"text/template"
 	"time"
 
 	"github.com/Sirupsen/logrus"
 	"github.com/emicklei/go-restful"
 	"github.com/facebookgo/stackerr"
 
-	"github.com/bearded-web/bearded/models/plan"
 	"github.com/bearded-web/bearded/models/report"
 	"github.com/bearded-web/bearded/models/scan"
 	"github.com/bearded-web/bearded/pkg/filters"
@@ -66,6 +63,7 @@ func (s *ScanService) Register(container *restful.Container) {
 	r = ws.POST("").To(s.create)
 	r.Doc("create")
 	r.Operation("create")
+	r.Notes("Scans which break project rules of engagement are rejected with all violations explained")
 	addDefaults(r)
 	r.Writes(scan.Scan{})
 	r.Reads(scan.Scan{})
@@ -73,6 +71,8 @@ func (s *ScanService) Register(container *restful.Container) {
 	r.Do(services.ReturnsE(
 		http.StatusBadRequest,
 		http.StatusConflict,
+		http.StatusPaymentRequired,
+		http.StatusTooManyRequests,
 	))
 	ws.Route(r)
 
@@ -116,11 +116,16 @@ func (s *ScanService) Register(container *restful.Container) {
 	r.Operation("reports")
 	r.Param(ws.PathParameter(ParamId, ""))
 	addDefaults(r)
+	r.Notes("Authorization required. Raw reports are listed with sizes and archives only, " +
+		"get raw data from sessions/{session-id}/report/raw")
 	r.Writes(report.ReportList{})
 	r.Do(services.Returns(http.StatusOK))
 	ws.Route(r)
 
 	s.RegisterSessions(ws)
+	s.RegisterCallbacks(ws)
+	s.RegisterArtifacts(ws)
+	s.RegisterReview(ws)
 
 	container.Add(ws)
 }
@@ -138,7 +143,7 @@ func (s *ScanService) create(req *restful.Request, resp *restful.Response) {
 	}
 	u := filters.GetUser(req)
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	// TODO (m0sth8): check project and target permissions for this user
@@ -185,85 +190,53 @@ func (s *ScanService) create(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	sc := &scan.Scan{
-		Status:  scan.StatusCreated,
-		Owner:   u.Id,
-		Plan:    raw.Plan,
-		Project: project.Id,
-		Target:  target.Id,
-		Conf: scan.ScanConf{
-			Target: target.Addr(),
-		},
-		Sessions: []*scan.Session{},
+	err = mgr.Scans.CheckMinutes(project)
+	if err == nil {
+		err = mgr.Scans.CheckDaily(project)
 	}
-	now := time.Now().UTC()
+	if err != nil {
+		if sErr := services.QuotaErr(err); sErr != nil {
+			sErr.Write(resp)
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
 	// Add session from plans workflow steps
-	for _, step := range planObj.Workflow {
-		plugin, err := mgr.Plugins.GetByName(step.Plugin)
-		if err != nil {
-			if mgr.IsNotFound(err) {
+	sc, err := mgr.Scans.NewScan(u.Id, project, target, planObj)
+	if err != nil {
+		if stepErr, ok := err.(*manager.StepError); ok {
+			if mgr.IsNotFound(stepErr.Err) {
 				resp.WriteServiceError(http.StatusBadRequest,
-					services.NewBadReq(fmt.Sprintf("plugin %s is not found", step.Plugin)))
+					services.NewBadReq("plugin %s is not found", stepErr.Plugin))
 				return
 			}
-			logrus.Error(stackerr.Wrap(err))
-			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
-			return
-		}
-		// TODO (m0sth8): extract template execution
-		if step.Conf != nil {
-			if command := step.Conf.CommandArgs; command != "" {
-				t, err := template.New("").Parse(command)
-				if err != nil {
-					logrus.Error(stackerr.Wrap(err))
-					resp.WriteServiceError(http.StatusInternalServerError, services.NewAppErr("Wrong command args template"))
-					return
-				}
-				buf := bytes.NewBuffer(nil)
-				err = t.Execute(buf, sc.Conf)
-				if err != nil {
-					logrus.Error(stackerr.Wrap(err))
-					resp.WriteServiceError(http.StatusInternalServerError, services.NewAppErr("Wrong command args template"))
-					return
-				}
-				step.Conf.CommandArgs = buf.String()
-			}
-			if formData := step.Conf.FormData; formData != "" {
-				t, err := template.New("").Parse(formData)
-				if err != nil {
-					logrus.Error(stackerr.Wrap(err))
-					resp.WriteServiceError(http.StatusInternalServerError, services.NewAppErr("Wrong form data template"))
-					return
-				}
-				buf := bytes.NewBuffer(nil)
-				err = t.Execute(buf, sc.Conf)
-				if err != nil {
-					logrus.Error(stackerr.Wrap(err))
-					resp.WriteServiceError(http.StatusInternalServerError, services.NewAppErr("Wrong form data template"))
-					return
-				}
-				step.Conf.FormData = buf.String()
-			}
-			if target := step.Conf.Target; target == "" {
-				step.Conf.Target = sc.Conf.Target
-			}
-		} else {
-			step.Conf = &plan.Conf{
-				Target: sc.Conf.Target,
+			if stepErr.Msg != "" {
+				logrus.Error(stackerr.Wrap(err))
+				resp.WriteServiceError(http.StatusInternalServerError, services.NewAppErr(stepErr.Msg))
+				return
 			}
 		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	sc.Scheduled = raw.Scheduled
 
-		sess := scan.Session{
-			Id:     mgr.NewId(),
-			Step:   step,
-			Plugin: plugin.Id,
-			Status: scan.StatusCreated,
-			Dates: scan.Dates{
-				Created: &now,
-				Updated: &now,
-			},
+	at := time.Now().UTC()
+	if sc.Scheduled != nil && sc.Scheduled.After(at) {
+		at = *sc.Scheduled
+	}
+	if err := mgr.Scans.CheckEngagement(project, sc, at); err != nil {
+		if sErr := services.EngagementErr(err); sErr != nil {
+			sErr.Write(resp)
+			return
 		}
-		sc.Sessions = append(sc.Sessions, &sess)
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
 	}
 
 	obj, err := mgr.Scans.Create(sc)
@@ -289,7 +262,7 @@ func (s *ScanService) list(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	results, count, err := mgr.Scans.FilterByQuery(query)
@@ -322,7 +295,7 @@ func (s *ScanService) update(req *restful.Request, resp *restful.Response, pl *s
 		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
 		return
 	}
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	raw.Id = pl.Id
@@ -347,19 +320,19 @@ func (s *ScanService) update(req *restful.Request, resp *restful.Response, pl *s
 	resp.WriteEntity(raw)
 }
 
-func (s *ScanService) delete(_ *restful.Request, resp *restful.Response, obj *scan.Scan) {
+func (s *ScanService) delete(req *restful.Request, resp *restful.Response, obj *scan.Scan) {
 	// TODO (m0sth8): Forbid to remove scan after queued status
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	mgr.Scans.Remove(obj)
 	resp.WriteHeader(http.StatusNoContent)
 }
 
-func (s *ScanService) reports(_ *restful.Request, resp *restful.Response, sc *scan.Scan) {
+func (s *ScanService) reports(req *restful.Request, resp *restful.Response, sc *scan.Scan) {
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	results := []*report.Report{}
@@ -371,6 +344,10 @@ func (s *ScanService) reports(_ *restful.Request, resp *restful.Response, sc *sc
 		return
 	}
 
+	for _, rep := range results {
+		rep.Summarize()
+	}
+
 	reportList := report.ReportList{
 		Meta:    pagination.Meta{Count: count},
 		Results: results,
@@ -394,7 +371,7 @@ func (s *ScanService) TakeScan(fn ScanFunction) restful.RouteFunction {
 			return
 		}
 
-		mgr := s.Manager()
+		mgr := s.RequestManager(req)
 		defer mgr.Close()
 
 		obj, err := mgr.Scans.GetById(mgr.ToId(id))
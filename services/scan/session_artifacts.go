@@ -0,0 +1,171 @@
+package scan
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/file"
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/services"
+)
+
+const ArtifactParamId = "artifact-id"
+
+func (s *ScanService) RegisterArtifacts(ws *restful.WebService) {
+	r := ws.GET(fmt.Sprintf("{%s}/sessions/{%s}/artifacts", ParamId, SessionParamId)).
+		To(s.TakeScan(s.TakeSession(s.artifactList)))
+	r.Doc("artifactList")
+	r.Operation("artifactList")
+	addDefaults(r)
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(SessionParamId, ""))
+	r.Writes([]*file.Meta{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
+	r = ws.POST(fmt.Sprintf("{%s}/sessions/{%s}/artifacts", ParamId, SessionParamId)).
+		To(s.TakeScan(s.TakeSession(s.TakeSessionToken(s.artifactCreate))))
+	r.Doc("artifactCreate")
+	r.Operation("artifactCreate")
+	addDefaults(r)
+	r.Notes(fmt.Sprintf("Authorization and %s header with the token of the job are required. "+
+		"Artifact is uploaded as multipart form with file field, it counts toward the project storage quota", SessionTokenHeader))
+	r.Consumes("multipart/form-data")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(SessionParamId, ""))
+	r.Param(ws.HeaderParameter(SessionTokenHeader, "session token from the job"))
+	r.Param(ws.FormParameter("file", "artifact data").DataType("file"))
+	r.Writes(file.Meta{})
+	r.Do(services.Returns(
+		http.StatusCreated,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusConflict,
+		http.StatusPaymentRequired))
+	ws.Route(r)
+
+	r = ws.GET(fmt.Sprintf("{%s}/sessions/{%s}/artifacts/{%s}", ParamId, SessionParamId, ArtifactParamId)).
+		To(s.TakeScan(s.TakeSession(s.artifactDownload)))
+	r.Doc("artifactDownload")
+	r.Operation("artifactDownload")
+	addDefaults(r)
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(SessionParamId, ""))
+	r.Param(ws.PathParameter(ArtifactParamId, ""))
+	r.Produces("application/octet-stream")
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+}
+
+func (s *ScanService) artifactList(_ *restful.Request, resp *restful.Response, _ *scan.Scan, sess *scan.Session) {
+	artifacts := sess.Artifacts
+	if artifacts == nil {
+		artifacts = []*file.Meta{}
+	}
+	resp.WriteEntity(artifacts)
+}
+
+func (s *ScanService) artifactCreate(req *restful.Request, resp *restful.Response, sc *scan.Scan, sess *scan.Session) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	p, err := mgr.Projects.GetById(sc.Project)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	// the body is limited before parsing, because the form is spooled to disk
+	body, err := mgr.Files.StorageLimit(p, req.Request.Body)
+	if err != nil {
+		if sErr := services.QuotaErr(err); sErr != nil {
+			sErr.Write(resp)
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	req.Request.Body = ioutil.NopCloser(body)
+
+	f, header, err := req.Request.FormFile("file")
+	if err != nil {
+		if sErr := services.QuotaErr(body.Err()); sErr != nil {
+			sErr.Write(resp)
+			return
+		}
+		logrus.Warn(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Couldn't read file"))
+		return
+	}
+	defer f.Close()
+
+	meta, err := mgr.Files.Create(f, &file.Meta{
+		Name:        header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Project:     p.Id,
+	})
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	sess.Artifacts = append(sess.Artifacts, meta)
+	if err := mgr.Scans.UpdateSession(sc, sess); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	s.Scheduler().UpdateScan(sc)
+
+	resp.WriteHeader(http.StatusCreated)
+	resp.WriteEntity(meta)
+}
+
+func (s *ScanService) artifactDownload(req *restful.Request, resp *restful.Response, _ *scan.Scan, sess *scan.Session) {
+	// only artifacts of the session are served, so the scan permission is enough
+	meta := sess.GetArtifact(req.PathParameter(ArtifactParamId))
+	if meta == nil {
+		resp.WriteErrorString(http.StatusNotFound, "Not found")
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	obj, err := mgr.Files.GetById(meta.Id)
+	if err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	defer obj.Close()
+
+	resp.AddHeader("Content-Type", "application/octet-stream")
+	if filename := obj.Meta.Name; filename != "" {
+		resp.AddHeader("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", url.QueryEscape(filename)))
+	}
+	if _, err := io.Copy(resp.ResponseWriter, obj); err != nil {
+		// headers are already sent, so just stop streaming
+		logrus.Error(stackerr.Wrap(err))
+	}
+}
@@ -0,0 +1,134 @@
+package scan
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/discovery"
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/pkg/validate"
+	"github.com/bearded-web/bearded/services"
+)
+
+// header with the session token, agent passes it on behalf of the running plugin
+const SessionTokenHeader = "X-Session-Token"
+
+// RegisterCallbacks adds routes which are used by running plugins through the agent
+func (s *ScanService) RegisterCallbacks(ws *restful.WebService) {
+	r := ws.PUT(fmt.Sprintf("{%s}/sessions/{%s}/progress", ParamId, SessionParamId)).
+		To(s.TakeScan(s.TakeSession(s.TakeSessionToken(s.sessionProgress))))
+	r.Doc("sessionProgress")
+	r.Operation("sessionProgress")
+	addDefaults(r)
+	r.Notes(fmt.Sprintf("Authorization and %s header with the token of the job are required", SessionTokenHeader))
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(SessionParamId, ""))
+	r.Param(ws.HeaderParameter(SessionTokenHeader, "session token from the job"))
+	r.Reads(ProgressEntity{})
+	r.Writes(scan.Progress{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusConflict))
+	ws.Route(r)
+
+	r = ws.GET(fmt.Sprintf("{%s}/sessions/{%s}/scope", ParamId, SessionParamId)).
+		To(s.TakeScan(s.TakeSession(s.TakeSessionToken(s.sessionScope))))
+	r.Doc("sessionScope")
+	r.Operation("sessionScope")
+	addDefaults(r)
+	r.Notes(fmt.Sprintf("Authorization and %s header with the token of the job are required. "+
+		"Url is in scope if its host is the scan target host or its subdomain", SessionTokenHeader))
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(SessionParamId, ""))
+	r.Param(ws.HeaderParameter(SessionTokenHeader, "session token from the job"))
+	r.Param(ws.QueryParameter("url", "url which plugin is going to request"))
+	r.Writes(ScopeEntity{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusConflict))
+	ws.Route(r)
+}
+
+func (s *ScanService) sessionProgress(req *restful.Request, resp *restful.Response, sc *scan.Scan, sess *scan.Session) {
+	raw := &ProgressEntity{}
+	if err := req.ReadEntity(raw); err != nil {
+		logrus.Warn(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
+	}
+	if err := validate.Entity(raw); err != nil {
+		services.NewValidationErr(err).Write(resp)
+		return
+	}
+	if sess.Status != scan.StatusWorking {
+		resp.WriteServiceError(http.StatusConflict, services.NewBadReq("Progress is reported only by working session"))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	now := time.Now().UTC()
+	sess.Progress = &scan.Progress{
+		Percent: raw.Percent,
+		Message: raw.Message,
+		Updated: &now,
+	}
+	if err := mgr.Scans.UpdateSession(sc, sess); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	s.Scheduler().UpdateScan(sc)
+
+	resp.WriteEntity(sess.Progress)
+}
+
+func (s *ScanService) sessionScope(req *restful.Request, resp *restful.Response, sc *scan.Scan, _ *scan.Session) {
+	url := req.QueryParameter("url")
+	if url == "" {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("url is required"))
+		return
+	}
+	resp.WriteEntity(&ScopeEntity{
+		Url:     url,
+		InScope: InScope(url, sc.Conf.Target),
+	})
+}
+
+// InScope reports whether the url host is the target host or its subdomain
+func InScope(url, target string) bool {
+	host, root := discovery.NormalizeHost(url), discovery.NormalizeHost(target)
+	return host != "" && (host == root || discovery.InScope(host, root))
+}
+
+// Helpers
+
+// Decorate SessionFunction. Check that the session token from the header is the token of the session,
+// the token is changed when the session is queued to another agent.
+func (s *ScanService) TakeSessionToken(fn SessionFunction) SessionFunction {
+	return func(req *restful.Request, resp *restful.Response, sc *scan.Scan, sess *scan.Session) {
+		token := req.HeaderParameter(SessionTokenHeader)
+		if token == "" {
+			resp.WriteServiceError(http.StatusUnauthorized, services.AuthReqErr)
+			return
+		}
+		if sess.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(sess.Token)) != 1 {
+			resp.WriteServiceError(http.StatusConflict, services.NewBadReq("Session is queued to another agent"))
+			return
+		}
+		fn(req, resp, sc, sess)
+	}
+}
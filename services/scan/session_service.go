@@ -2,6 +2,8 @@ package scan
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"time"
 
@@ -10,10 +12,14 @@ import (
 	"github.com/facebookgo/stackerr"
 
 	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/notification"
+	"github.com/bearded-web/bearded/models/plan"
 	"github.com/bearded-web/bearded/models/report"
 	"github.com/bearded-web/bearded/models/scan"
-	"github.com/bearded-web/bearded/models/tech"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/pkg/ingest"
 	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/notify"
 	"github.com/bearded-web/bearded/services"
 )
 
@@ -55,7 +61,9 @@ func (s *ScanService) RegisterSessions(ws *restful.WebService) {
 	r.Do(services.Returns(
 		http.StatusOK,
 		http.StatusNotFound))
-	r.Do(services.ReturnsE(http.StatusBadRequest))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusConflict))
 	ws.Route(r)
 
 	// TODO (m0sth8): exclude reports to it's own service
@@ -73,6 +81,20 @@ func (s *ScanService) RegisterSessions(ws *restful.WebService) {
 	r.Do(services.ReturnsE(http.StatusBadRequest))
 	ws.Route(r)
 
+	r = ws.GET(fmt.Sprintf("{%s}/sessions/{%s}/report/raw", ParamId, SessionParamId)).To(s.TakeScan(s.TakeSession(s.sessionReportRaw)))
+	r.Doc("sessionReportRaw")
+	r.Operation("sessionReportRaw")
+	addDefaults(r)
+	r.Notes("Authorization required. Stream raw data of the report, raw parts of multi report are separated by new line")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.PathParameter(SessionParamId, ""))
+	r.Produces("text/plain")
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	ws.Route(r)
+
 	r = ws.POST(fmt.Sprintf("{%s}/sessions/{%s}/report", ParamId, SessionParamId)).To(s.TakeScan(s.TakeSession(s.sessionReportCreate)))
 	r.Doc("sessionReportCreate")
 	r.Operation("sessionReportCreate")
@@ -84,7 +106,8 @@ func (s *ScanService) RegisterSessions(ws *restful.WebService) {
 	r.Do(services.Returns(http.StatusCreated))
 	r.Do(services.ReturnsE(
 		http.StatusBadRequest,
-		http.StatusConflict))
+		http.StatusConflict,
+		http.StatusUnprocessableEntity))
 	ws.Route(r)
 }
 
@@ -136,7 +159,7 @@ func (s *ScanService) sessionCreate(req *restful.Request, resp *restful.Response
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	pl, err := mgr.Plugins.GetByName(raw.Step.Plugin)
@@ -150,6 +173,25 @@ func (s *ScanService) sessionCreate(req *restful.Request, resp *restful.Response
 		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
 		return
 	}
+	// rules of engagement could forbid plugins which the scan spawns
+	p, err := mgr.Projects.GetById(sc.Project)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	if err := mgr.Scans.CheckPlugin(p, pl); err != nil {
+		services.EngagementErr(err).Write(resp)
+		return
+	}
+
+	// children scan the same target, so limits are inherited from scan
+	if raw.Step.Conf == nil {
+		raw.Step.Conf = &plan.Conf{}
+	}
+	raw.Step.Conf.RateLimit = sc.Conf.RateLimit
+	raw.Step.Conf.Proxy = sc.Conf.Proxy
+	raw.Step.Conf.Record = sc.Conf.Record
 
 	now := time.Now().UTC()
 	sess := scan.Session{
@@ -198,12 +240,17 @@ func (s *ScanService) sessionUpdate(req *restful.Request, resp *restful.Response
 		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("status should be one of [working|finished|failed]"))
 		return
 	}
+	if raw.Agent != "" && raw.Agent != sess.Agent {
+		resp.WriteServiceError(http.StatusConflict, services.NewBadReq("Session is queued to another agent"))
+		return
+	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	logrus.Debugf("Update session %s status from %s to %s", mgr.FromId(sess.Id), sess.Status, raw.Status)
 
+	prevStatus := sc.Status
 	sess.Status = raw.Status
 	if err := mgr.Scans.UpdateSession(sc, sess); err != nil {
 		logrus.Error(stackerr.Wrap(err))
@@ -212,6 +259,14 @@ func (s *ScanService) sessionUpdate(req *restful.Request, resp *restful.Response
 	}
 	s.Scheduler().UpdateScan(sc)
 
+	if sc.Status == scan.StatusFailed && prevStatus != scan.StatusFailed {
+		s.notifyScanFailed(mgr, sc)
+	}
+	if sc.Retest != "" && sc.Status != prevStatus &&
+		(sc.Status == scan.StatusFinished || sc.Status == scan.StatusFailed) {
+		s.finishRetest(mgr, sc)
+	}
+
 	if err := mgr.Feed.UpdateScan(sc); err != nil {
 		logrus.Error(stackerr.Wrap(err))
 	}
@@ -219,9 +274,9 @@ func (s *ScanService) sessionUpdate(req *restful.Request, resp *restful.Response
 	resp.WriteEntity(sess)
 }
 
-func (s *ScanService) sessionReportGet(_ *restful.Request, resp *restful.Response, _ *scan.Scan, sess *scan.Session) {
+func (s *ScanService) sessionReportGet(req *restful.Request, resp *restful.Response, _ *scan.Scan, sess *scan.Session) {
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	rep, err := mgr.Reports.GetBySession(sess.Id)
@@ -239,164 +294,82 @@ func (s *ScanService) sessionReportGet(_ *restful.Request, resp *restful.Respons
 	resp.WriteEntity(rep)
 }
 
-func (s *ScanService) sessionReportCreate(req *restful.Request, resp *restful.Response, sc *scan.Scan, sess *scan.Session) {
-	// TODO (m0sth8): Check permissions
-	// TODO (m0sth8): Forbid creating report in session after finished|failed status
-
-	raw := &report.Report{}
-
-	if err := req.ReadEntity(raw); err != nil {
-		logrus.Error(stackerr.Wrap(err))
-		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
-		return
-	}
-
-	raw.SetScan(sc.Id)
-	raw.SetScanSession(sess.Id)
-
-	mgr := s.Manager()
+func (s *ScanService) sessionReportRaw(req *restful.Request, resp *restful.Response, _ *scan.Scan, sess *scan.Session) {
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
-	// TODO (m0sth8): for raw reports check metadata for files (check if file existed, set right md5, size etc)
-	rep, err := mgr.Reports.Create(raw)
-
+	rep, err := mgr.Reports.GetBySession(sess.Id)
 	if err != nil {
-		if mgr.IsDup(err) {
-			resp.WriteServiceError(
-				http.StatusConflict,
-				services.NewError(services.CodeDuplicate, "report with this scan session is existed"))
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
 			return
 		}
 		logrus.Error(stackerr.Wrap(err))
 		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
 		return
 	}
-
-	// create target issues from report
-	// TODO (m0sth8): exclude to another process (maybe push to queue)
-	err = s.createTargetIssues(rep, sc, sess)
-	if err != nil {
-		logrus.Error(stackerr.Wrap(err))
-		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
-		return
-	}
-
-	// create target techs from report
-	// TODO (m0sth8): exclude to another process (maybe push to queue)
-	err = s.createTargetTechs(rep, sc, sess)
-	if err != nil {
-		logrus.Error(stackerr.Wrap(err))
-		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+	raws := rep.GetAllRaws()
+	if len(raws) == 0 {
+		resp.WriteErrorString(http.StatusNotFound, "Report hasn't raw data")
 		return
 	}
 
-	// update feed item
-	err = mgr.Feed.UpdateScanReport(sc, rep)
-	if err != nil {
-		logrus.Error(stackerr.Wrap(err))
-	}
-
-	resp.WriteHeader(http.StatusCreated)
-	resp.WriteEntity(rep)
-}
-
-func (s *ScanService) createTargetIssues(rep *report.Report, sc *scan.Scan, sess *scan.Session) error {
-	issues := rep.GetAllIssues()
-	if len(issues) == 0 {
-		return nil
-	}
-
-	mgr := s.Manager()
-	defer mgr.Close()
-
-	isIssuesAdded := false
-
-	for _, issueObj := range issues {
-		if issueObj.Severity == issue.SeverityError {
-			continue
-		}
-		targetIssue := &issue.TargetIssue{
-			Target:  sc.Target,
-			Project: sc.Project,
-			Issue:   *issueObj,
+	resp.AddHeader("Content-Type", "text/plain; charset=utf-8")
+	resp.WriteHeader(http.StatusOK)
+	for i, raw := range raws {
+		if i > 0 {
+			resp.Write([]byte("\n"))
 		}
-		targetIssue.AddReportActivity(rep.Id, sc.Id, sess.Id)
-		_, err := mgr.Issues.Create(targetIssue)
+		r, err := mgr.Reports.OpenRaw(raw)
 		if err != nil {
-			if mgr.IsDup(err) {
-				if targetIssue.UniqId != "" {
-					targetIssue, err = mgr.Issues.GetByUniqId(sc.Target, targetIssue.UniqId)
-					if err != nil {
-						logrus.Error(stackerr.Wrap(err))
-					}
-					if targetIssue.False {
-						continue
-					}
-					updateSummary := false
-					targetIssue.AddReportActivity(rep.Id, sc.Id, sess.Id)
-					if targetIssue.Resolved {
-						targetIssue.Resolved = false
-						updateSummary = true
-					}
-					err := mgr.Issues.Update(targetIssue)
-					if err != nil {
-						logrus.Error(stackerr.Wrap(err))
-					}
-					if updateSummary {
-						err := mgr.Targets.UpdateSummaryById(sc.Target)
-						if err != nil {
-							logrus.Error(stackerr.Wrap(err))
-						}
-					}
-				}
-				continue
-			} else {
-				return stackerr.Wrap(err)
-			}
-		}
-		isIssuesAdded = true
-	}
-	if isIssuesAdded {
-		// TODO(m0sth8): exclude summary updating
-		targetObj, err := mgr.Targets.GetById(sc.Target)
-		if err != nil {
-			return stackerr.Wrap(err)
+			// headers are already sent, so just stop streaming
+			logrus.Error(stackerr.Wrap(err))
+			return
 		}
-		err = mgr.Targets.UpdateSummary(targetObj)
+		_, err = io.Copy(resp, r)
+		r.Close()
 		if err != nil {
-			return stackerr.Wrap(err)
+			logrus.Error(stackerr.Wrap(err))
+			return
 		}
 	}
-
-	return nil
 }
 
-func (s *ScanService) createTargetTechs(rep *report.Report, sc *scan.Scan, sess *scan.Session) error {
-	techs := rep.GetAllTechs()
-	if len(techs) == 0 {
-		return nil
+func (s *ScanService) sessionReportCreate(req *restful.Request, resp *restful.Response, sc *scan.Scan, sess *scan.Session) {
+	// TODO (m0sth8): Check permissions
+	// TODO (m0sth8): Forbid creating report in session after finished|failed status
+
+	data, err := ioutil.ReadAll(req.Request.Body)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
+		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
-	for _, techObj := range techs {
-		targetTech := &tech.TargetTech{
-			Target:  sc.Target,
-			Project: sc.Project,
-			Tech:    *techObj,
+	rep, err := ingest.Ingest(mgr, data, sc, sess)
+	if err != nil {
+		if mgr.IsDup(err) {
+			resp.WriteServiceError(
+				http.StatusConflict,
+				services.NewError(services.CodeDuplicate, "report with this scan session is existed"))
+			return
 		}
-		targetTech.AddReportActivity(rep.Id, sc.Id, sess.Id)
-		_, err := mgr.Techs.Create(targetTech)
-		if err != nil {
-			if mgr.IsDup(err) {
-				// TODO (m0sth8): should we make a report activity?
-			} else {
-				return stackerr.Wrap(err)
-			}
+		if qErr, ok := err.(*ingest.QuarantineError); ok {
+			resp.WriteServiceError(
+				http.StatusUnprocessableEntity,
+				services.NewBadReq("report is malformed and quarantined as %s", qErr.Quarantine.Id.Hex()))
+			return
 		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
 	}
-	return nil
+
+	resp.WriteHeader(http.StatusCreated)
+	resp.WriteEntity(rep)
 }
 
 // Helpers
@@ -423,3 +396,72 @@ func (s *ScanService) TakeSession(fn SessionFunction) ScanFunction {
 		fn(req, resp, sc, sess)
 	}
 }
+
+func (s *ScanService) notifyScanFailed(mgr *manager.Manager, sc *scan.Scan) {
+	var env target.Environment
+	if t, err := mgr.Targets.GetById(sc.Target); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+	} else {
+		env = t.Environment
+	}
+	newNotification := func() *notify.Notification {
+		return &notify.Notification{
+			Event:       notification.EventScanFailed,
+			Subject:     "Scan failed",
+			Text:        fmt.Sprintf("Scan %s for target %s is failed", sc.Id.Hex(), sc.Conf.Target),
+			Link:        fmt.Sprintf("/#/scan/%s", sc.Id.Hex()),
+			Environment: env,
+		}
+	}
+	if p, err := mgr.Projects.GetById(sc.Project); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+	} else {
+		go s.Notifier.NotifyProject(p, newNotification())
+	}
+	if sc.Owner == "" {
+		return
+	}
+	owner, err := mgr.Users.GetById(sc.Owner)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		return
+	}
+	n := newNotification()
+	n.User = owner
+	go s.Notifier.Notify(n)
+}
+
+// finishRetest checks if the retest scan reported the issue again and saves the result
+func (s *ScanService) finishRetest(mgr *manager.Manager, sc *scan.Scan) {
+	obj, err := mgr.Issues.GetById(sc.Retest)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		return
+	}
+	if obj.Retest == nil || obj.Retest.Scan != sc.Id {
+		// another retest was started
+		return
+	}
+	status := issue.RetestFailed
+	if sc.Status == scan.StatusFinished {
+		status = issue.RetestNotReproduced
+		reports, _, err := mgr.Reports.FilterBySessions(sc.GetAllSessions())
+		if err != nil {
+			logrus.Error(stackerr.Wrap(err))
+			return
+		}
+	loop:
+		for _, rep := range reports {
+			for _, found := range rep.GetAllIssues() {
+				if obj.Same(found) {
+					status = issue.RetestVulnerable
+					break loop
+				}
+			}
+		}
+	}
+	obj.FinishRetest(status)
+	if err := mgr.Issues.Update(obj); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+	}
+}
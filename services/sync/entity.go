@@ -0,0 +1,37 @@
+package sync
+
+import (
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/comment"
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/scan"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/models/tombstone"
+)
+
+// CommentEntity exposes the commented entity, it's hidden in the usual api
+type CommentEntity struct {
+	*comment.Comment `json:",inline"`
+	Type             comment.Type  `json:"type" description:"one of [issue|scan], scan comments are linked to targets"`
+	Link             bson.ObjectId `json:"link"`
+}
+
+type SyncEntity struct {
+	Token string `json:"token" description:"pass it as since parameter to get next changes"`
+	Full  bool   `json:"full" description:"all entities are returned, the local cache should be replaced"`
+	More  bool   `json:"more" description:"full sync has more entities, request the next page"`
+
+	Project  *project.Project       `json:"project,omitempty" description:"only if it's changed"`
+	Targets  []*target.Target       `json:"targets"`
+	Issues   []*issue.TargetIssue   `json:"issues"`
+	Comments []*CommentEntity       `json:"comments"`
+	Scans    []*scan.Scan           `json:"scans"`
+	Removed  []*tombstone.Tombstone `json:"removed"`
+}
+
+func (e *SyncEntity) Empty() bool {
+	return e.Project == nil && len(e.Targets) == 0 && len(e.Issues) == 0 &&
+		len(e.Comments) == 0 && len(e.Scans) == 0 && len(e.Removed) == 0
+}
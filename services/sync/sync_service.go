@@ -0,0 +1,341 @@
+package sync
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/comment"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/tombstone"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/services"
+)
+
+const (
+	// entities are saved a bit later than their updated field is set,
+	// so every sync returns the overlap again and clients should merge entities by id
+	overlap = 2 * time.Second
+
+	maxWait      = 60
+	pollInterval = time.Second
+
+	// full sync returns at most this count of entities of every kind per request
+	pageLimit = 500
+)
+
+type SyncService struct {
+	*services.BaseService
+}
+
+func New(base *services.BaseService) *SyncService {
+	return &SyncService{
+		BaseService: base,
+	}
+}
+
+func (s *SyncService) Register(container *restful.Container) {
+	ws := &restful.WebService{}
+	ws.Path("/api/v1/sync")
+	ws.Doc("Delta sync for clients with a local cache")
+	ws.Consumes(restful.MIME_JSON)
+	ws.Produces(restful.MIME_JSON)
+	ws.Filter(filters.AuthTokenFilter(s.BaseManager()))
+	ws.Filter(filters.AuthRequiredFilter(s.BaseManager()))
+
+	r := ws.GET("").To(s.sync)
+	r.Doc("sync")
+	r.Operation("sync")
+	r.Notes("Authorization required. Returns project entities changed since the token, " +
+		"all of them if there is no token. Entities changed right before the token could be returned again. " +
+		"Full sync is paginated: while more is true, request the next page with skip increased by limit " +
+		"and use the token of the first page for the next delta sync. Variables in issue descriptions are substituted.")
+	r.Param(ws.QueryParameter("project", "project id").Required(true))
+	r.Param(ws.QueryParameter("since", "token from the previous sync"))
+	r.Param(s.Paginator.SkipParam())
+	r.Param(ws.QueryParameter("limit", fmt.Sprintf("max entities of every kind in full sync, %d by default and max", pageLimit)).DataType("integer"))
+	r.Param(ws.QueryParameter("wait", fmt.Sprintf("wait up to n seconds for changes, %d max", maxWait)).DataType("integer"))
+	r.Writes(SyncEntity{})
+	r.Do(services.Returns(http.StatusOK))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusUnauthorized,
+		http.StatusForbidden,
+		http.StatusGone,
+		http.StatusInternalServerError,
+	))
+	ws.Route(r)
+
+	container.Add(ws)
+}
+
+// ====== service operations
+
+func (s *SyncService) sync(req *restful.Request, resp *restful.Response) {
+	projectId := req.QueryParameter("project")
+	if !s.IsId(projectId) {
+		resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+		return
+	}
+	var since time.Time
+	if token := req.QueryParameter("since"); token != "" {
+		var err error
+		if since, err = DecodeToken(token); err != nil {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("since: wrong token"))
+			return
+		}
+		// removals aren't known for such long periods
+		if time.Since(since) > manager.TombstoneTtl {
+			resp.WriteServiceError(http.StatusGone, services.NewBadReq("sync token is expired, full sync is required"))
+			return
+		}
+	}
+	wait := 0
+	if p := req.QueryParameter("wait"); p != "" {
+		val, err := strconv.Atoi(p)
+		if err != nil || val < 0 {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("wait should be a positive integer"))
+			return
+		}
+		if val > maxWait {
+			val = maxWait
+		}
+		wait = val
+	}
+	// delta syncs return all changes, they are small and tombstones aren't paginated
+	var opt manager.Opts
+	if since.IsZero() {
+		limit := pageLimit
+		if p := req.QueryParameter("limit"); p != "" {
+			val, err := strconv.Atoi(p)
+			if err != nil || val <= 0 {
+				resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("limit should be a positive integer"))
+				return
+			}
+			if val < limit {
+				limit = val
+			}
+		}
+		opt = manager.Opts{Skip: s.Paginator.ParseSkip(req), Limit: limit, Sort: []string{"_id"}, Count: manager.CountHasMore}
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	proj, err := mgr.Projects.GetById(mgr.ToId(projectId))
+	if err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	if !mgr.Permission.HasProjectAccess(proj, filters.GetUser(req)) {
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+
+	if !since.IsZero() && wait > 0 {
+		if proj, err = waitChanges(req, mgr, proj, since, time.Duration(wait)*time.Second); err != nil {
+			logrus.Error(stackerr.Wrap(err))
+			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+			return
+		}
+	}
+
+	now := time.Now().UTC()
+	from := since
+	if !from.IsZero() {
+		from = from.Add(-overlap)
+	}
+	result, err := changes(mgr, proj, from, opt)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	result.Token = EncodeToken(now)
+	resp.WriteEntity(result)
+}
+
+// waitChanges blocks until something is changed after the time, timeout is passed or client is gone
+// the project is reloaded on every check, so its last state is returned
+func waitChanges(req *restful.Request, mgr *manager.Manager, proj *project.Project, since time.Time, timeout time.Duration) (*project.Project, error) {
+	deadline := time.After(timeout)
+	// comments of new issues and targets aren't checked, but new issues and targets are changes themselves
+	links, err := commentLinks(mgr, proj)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		ok, err := changed(mgr, proj, since, links)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return proj, nil
+		}
+		select {
+		case <-deadline:
+			return proj, nil
+		case <-req.Request.Context().Done():
+			return proj, nil
+		case <-time.After(pollInterval):
+		}
+		if proj, err = mgr.Projects.GetById(proj.Id); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// changed reports whether something of the project is changed after the time,
+// only the first changed entity of every kind is fetched
+func changed(mgr *manager.Manager, proj *project.Project, since time.Time, links bson.M) (bool, error) {
+	if proj.Updated.After(since) {
+		return true, nil
+	}
+	first := manager.Opts{Limit: 1, Count: manager.CountHasMore}
+	updated := bson.M{"$gt": since}
+	checks := []func() (int, error){
+		func() (int, error) {
+			_, n, err := mgr.Targets.FilterByQuery(bson.M{"project": proj.Id, "updated": updated}, first)
+			return n, err
+		},
+		func() (int, error) {
+			_, n, err := mgr.Issues.FilterByQuery(bson.M{"project": proj.Id, "updated": updated}, first)
+			return n, err
+		},
+		func() (int, error) {
+			_, n, err := mgr.Scans.FilterByQuery(bson.M{"project": proj.Id, "dates.updated": updated}, first)
+			return n, err
+		},
+		func() (int, error) {
+			_, n, err := mgr.Comments.FilterByQuery(bson.M{"$or": links["$or"], "updated": updated}, first)
+			return n, err
+		},
+		func() (int, error) {
+			removed, err := mgr.Tombstones.Since(proj.Id, since)
+			return len(removed), err
+		},
+	}
+	for _, check := range checks {
+		n, err := check()
+		if err != nil || n > 0 {
+			return n > 0, err
+		}
+	}
+	return false, nil
+}
+
+// commentLinks returns the query of comments of the project,
+// comments are linked to issues and targets, not to projects
+func commentLinks(mgr *manager.Manager, proj *project.Project) (bson.M, error) {
+	issueIds, err := mgr.Issues.Ids(bson.M{"project": proj.Id})
+	if err != nil {
+		return nil, err
+	}
+	targetIds, err := mgr.Targets.Ids(bson.M{"project": proj.Id})
+	if err != nil {
+		return nil, err
+	}
+	return bson.M{"$or": []bson.M{
+		{"type": comment.Issue, "link": bson.M{"$in": issueIds}},
+		{"type": comment.Scan, "link": bson.M{"$in": targetIds}},
+	}}, nil
+}
+
+// changes collects project entities updated after the time, zero time means all entities.
+// Every kind is paginated with opt, More is set if any kind has more entities.
+func changes(mgr *manager.Manager, proj *project.Project, since time.Time, opt manager.Opts) (*SyncEntity, error) {
+	result := &SyncEntity{Full: since.IsZero()}
+	updated := func(field string) bson.M {
+		query := bson.M{"project": proj.Id}
+		if !since.IsZero() {
+			query[field] = bson.M{"$gt": since}
+		}
+		return query
+	}
+	// in CountHasMore mode the count is greater than the page end if there are more entities
+	more := func(count, n int) {
+		if count > opt.Skip+n {
+			result.More = true
+		}
+	}
+
+	if since.IsZero() || proj.Updated.After(since) {
+		result.Project = proj
+	}
+	var (
+		err   error
+		count int
+	)
+	if result.Targets, count, err = mgr.Targets.FilterByQuery(updated("updated"), opt); err != nil {
+		return nil, err
+	}
+	more(count, len(result.Targets))
+	if err = mgr.Summaries.Fill(result.Targets...); err != nil {
+		return nil, err
+	}
+	if result.Issues, count, err = mgr.Issues.FilterByQuery(updated("updated"), opt); err != nil {
+		return nil, err
+	}
+	more(count, len(result.Issues))
+	// issues are returned like the issue service does, with substituted variables
+	if err = mgr.Issues.RenderVars(result.Issues...); err != nil {
+		return nil, err
+	}
+	if result.Scans, count, err = mgr.Scans.FilterByQuery(updated("dates.updated"), opt); err != nil {
+		return nil, err
+	}
+	more(count, len(result.Scans))
+
+	query, err := commentLinks(mgr, proj)
+	if err != nil {
+		return nil, err
+	}
+	if !since.IsZero() {
+		query["updated"] = bson.M{"$gt": since}
+	}
+	comments, count, err := mgr.Comments.FilterByQuery(query, opt)
+	if err != nil {
+		return nil, err
+	}
+	more(count, len(comments))
+	result.Comments = make([]*CommentEntity, len(comments))
+	for i, c := range comments {
+		result.Comments[i] = &CommentEntity{Comment: c, Type: c.Type, Link: c.Link}
+	}
+
+	result.Removed = []*tombstone.Tombstone{}
+	if !since.IsZero() {
+		if result.Removed, err = mgr.Tombstones.Since(proj.Id, since); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// EncodeToken makes an opaque sync token from the time
+func EncodeToken(t time.Time) string {
+	return strconv.FormatInt(t.UnixNano(), 36)
+}
+
+func DecodeToken(token string) (time.Time, error) {
+	nano, err := strconv.ParseInt(token, 36, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if nano <= 0 {
+		return time.Time{}, fmt.Errorf("token should be positive")
+	}
+	return time.Unix(0, nano).UTC(), nil
+}
@@ -0,0 +1,171 @@
+package sync
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	c "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/issue"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/models/tombstone"
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/managertest"
+)
+
+func TestToken(t *testing.T) {
+	now := time.Now().UTC()
+	decoded, err := DecodeToken(EncodeToken(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(now) {
+		t.Fatalf("expected %s, got %s", now, decoded)
+	}
+	for _, token := range []string{"", "-1", "not a token!"} {
+		if _, err := DecodeToken(token); err == nil {
+			t.Fatalf("error expected for %q", token)
+		}
+	}
+}
+
+func TestSync(t *testing.T) {
+	env := managertest.Open(t)
+	defer env.Close()
+	mgr := env.Mgr
+	u, err := env.Login(&user.User{Email: "user@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts, err := env.Server(New(env.Base))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+
+	c.Convey("Given project with target and issue", t, func() {
+		// every convey leaf runs this again, names are unique for the owner
+		projectObj, err := mgr.Projects.Create(&project.Project{Name: bson.NewObjectId().Hex(), Owner: u.Id})
+		c.So(err, c.ShouldBeNil)
+		targetObj, err := mgr.Targets.Create(&target.Target{
+			Project: projectObj.Id,
+			Type:    target.TypeWeb,
+			Web:     &target.WebTarget{Domain: "http://example.com"},
+		})
+		c.So(err, c.ShouldBeNil)
+		issueObj, err := mgr.Issues.Create(&issue.TargetIssue{
+			Target:  targetObj.Id,
+			Project: projectObj.Id,
+			Issue:   issue.Issue{Summary: "xss", Severity: issue.SeverityHigh, Desc: "xss on {{target.host}}"},
+		})
+		c.So(err, c.ShouldBeNil)
+		params := fmt.Sprintf("project=%s", projectObj.Id.Hex())
+
+		c.Convey("Full sync returns everything", func() {
+			res, result := doSync(t, ts.URL, params)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+			c.So(result.Full, c.ShouldBeTrue)
+			c.So(result.Token, c.ShouldNotEqual, "")
+			c.So(result.Project, c.ShouldNotBeNil)
+			c.So(len(result.Targets), c.ShouldEqual, 1)
+			c.So(len(result.Issues), c.ShouldEqual, 1)
+			c.So(result.Issues[0].Desc, c.ShouldEqual, "xss on example.com")
+			c.So(result.More, c.ShouldBeFalse)
+		})
+
+		c.Convey("Full sync is paginated", func() {
+			_, err := mgr.Issues.Create(&issue.TargetIssue{
+				Target:  targetObj.Id,
+				Project: projectObj.Id,
+				Issue:   issue.Issue{Summary: "sqli", Severity: issue.SeverityHigh},
+			})
+			c.So(err, c.ShouldBeNil)
+			res, result := doSync(t, ts.URL, params+"&limit=1")
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+			c.So(result.More, c.ShouldBeTrue)
+			c.So(len(result.Issues), c.ShouldEqual, 1)
+			first := result.Issues[0].Id
+
+			res, result = doSync(t, ts.URL, params+"&limit=1&skip=1")
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+			c.So(result.More, c.ShouldBeFalse)
+			c.So(len(result.Targets), c.ShouldEqual, 0)
+			c.So(len(result.Issues), c.ShouldEqual, 1)
+			c.So(result.Issues[0].Id, c.ShouldNotEqual, first)
+		})
+
+		c.Convey("Delta sync returns only changes after the overlap", func() {
+			since := EncodeToken(time.Now().UTC().Add(time.Hour))
+			res, result := doSync(t, ts.URL, params+"&since="+since)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+			c.So(result.Full, c.ShouldBeFalse)
+			c.So(result.Project, c.ShouldBeNil)
+			c.So(len(result.Targets), c.ShouldEqual, 0)
+			c.So(len(result.Issues), c.ShouldEqual, 0)
+
+			since = EncodeToken(time.Now().UTC())
+			c.So(mgr.Issues.Update(issueObj), c.ShouldBeNil)
+			res, result = doSync(t, ts.URL, params+"&since="+since)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+			c.So(len(result.Issues), c.ShouldEqual, 1)
+			c.So(result.Issues[0].Id, c.ShouldEqual, issueObj.Id)
+		})
+
+		c.Convey("Removed entities are returned as tombstones", func() {
+			since := EncodeToken(time.Now().UTC())
+			c.So(mgr.Issues.Remove(issueObj), c.ShouldBeNil)
+			res, result := doSync(t, ts.URL, params+"&since="+since)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+			c.So(len(result.Removed), c.ShouldEqual, 1)
+			c.So(result.Removed[0].Kind, c.ShouldEqual, tombstone.Issue)
+			c.So(result.Removed[0].Entity, c.ShouldEqual, issueObj.Id)
+		})
+
+		c.Convey("Issues removed in bulk are returned as tombstones", func() {
+			since := EncodeToken(time.Now().UTC())
+			_, err := mgr.Issues.RemoveAll(bson.M{"target": targetObj.Id})
+			c.So(err, c.ShouldBeNil)
+			res, result := doSync(t, ts.URL, params+"&since="+since)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+			c.So(len(result.Removed), c.ShouldEqual, 1)
+			c.So(result.Removed[0].Entity, c.ShouldEqual, issueObj.Id)
+		})
+
+		c.Convey("Expired token requires full sync", func() {
+			since := EncodeToken(time.Now().UTC().Add(-manager.TombstoneTtl - time.Hour))
+			res, _ := doSync(t, ts.URL, params+"&since="+since)
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusGone)
+		})
+
+		c.Convey("Wrong parameters", func() {
+			res, _ := doSync(t, ts.URL, "project=bad")
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusBadRequest)
+			res, _ = doSync(t, ts.URL, params+"&since=bad!")
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusBadRequest)
+			res, _ = doSync(t, ts.URL, params+"&wait=-1")
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusBadRequest)
+		})
+	})
+}
+
+func doSync(t *testing.T, url, params string) (*http.Response, *SyncEntity) {
+	res, err := http.Get(url + "/api/v1/sync?" + params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	result := &SyncEntity{}
+	if res.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(res.Body).Decode(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return res, result
+}
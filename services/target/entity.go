@@ -1,7 +1,10 @@
 package target
 
 import (
+	"gopkg.in/mgo.v2/bson"
+
 	"github.com/bearded-web/bearded/models/file"
+	"github.com/bearded-web/bearded/models/issue"
 	"github.com/bearded-web/bearded/models/target"
 )
 
@@ -16,11 +19,35 @@ type WebTargetEntity struct {
 type AndroidTargetEntity struct {
 	Name string     `json:"name,omitempty" description:"target name, 80 symbols max" cmobile:"nonzero" validate:"max=80"`
 	File *file.Meta `json:"file,omitempty" description:"apk file metadata"`
+
+	AutoScan bson.ObjectId `json:"autoScan,omitempty" description:"plan which is run when a new apk version is uploaded"`
+}
+
+type ApiTargetEntity struct {
+	Name    string     `json:"name,omitempty" description:"target name, 80 symbols max" capi:"nonzero" validate:"max=80"`
+	SpecUrl string     `json:"specUrl,omitempty" description:"url of openapi or swagger json spec"`
+	File    *file.Meta `json:"file,omitempty" description:"uploaded spec, it's used instead of specUrl"`
+}
+
+type RepoTargetEntity struct {
+	Name        string `json:"name,omitempty" description:"target name, 80 symbols max" crepo:"nonzero" validate:"max=80"`
+	Url         string `json:"url,omitempty" description:"clone url, https, ssh or git@host:path" crepo:"nonzero"`
+	Branch      string `json:"branch,omitempty" description:"default branch is used if empty"`
+	Credentials string `json:"credentials,omitempty" description:"name of credentials configured on agents"`
 }
 
 type TargetEntity struct {
-	Type    target.TargetType    `json:"type,omitempty" description:"one of [web|android]" create:"nonzero"`
+	Type    target.TargetType    `json:"type,omitempty" description:"one of [web|android|api|repo]" create:"nonzero"`
 	Web     *WebTargetEntity     `json:"web,omitempty" description:"information about web target" cweb:"nonzero"`
 	Android *AndroidTargetEntity `json:"android,omitempty" description:"information about android target" cmobile:"nonzero"`
+	Api     *ApiTargetEntity     `json:"api,omitempty" description:"information about api target" capi:"nonzero"`
+	Repo    *RepoTargetEntity    `json:"repo,omitempty" description:"information about repository target" crepo:"nonzero"`
 	Project string               `json:"project,omitempty" create:"nonzero,bsonId"`
+
+	Criticality target.Criticality  `json:"criticality,omitempty" description:"one of [low|medium|high|critical]"`
+	Environment *target.Environment `json:"environment,omitempty" description:"one of [prod|staging|dev], send null to reset"`
+	Exposure    *issue.Exposure     `json:"exposure,omitempty" description:"exposure of issues found by scans and monitor, existed issues aren't changed"`
+	RateLimit   *target.RateLimit   `json:"rateLimit,omitempty" description:"scan politeness, project defaults are used for unset fields"`
+	Proxy       *target.Proxy       `json:"proxy,omitempty" description:"outbound proxy for plugin traffic, send null to use the project proxy"`
+	Record      bool                `json:"record,omitempty" description:"record plugin http traffic to har artifacts of scan sessions"`
 }
@@ -0,0 +1,66 @@
+package target
+
+import (
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/facebookgo/stackerr"
+
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/pkg/netguard"
+	"github.com/bearded-web/bearded/pkg/openapi"
+	"github.com/bearded-web/bearded/services"
+)
+
+// timeout for spec downloading
+const specTimeout = 10 * time.Second
+
+// loadApi takes the spec from the uploaded file or spec url and parses endpoints
+func loadApi(mgr *manager.Manager, raw *ApiTargetEntity) (*target.ApiTarget, *services.ErrResp) {
+	obj := &target.ApiTarget{
+		Name:    raw.Name,
+		SpecUrl: raw.SpecUrl,
+	}
+	var data []byte
+	switch {
+	case raw.File != nil:
+		f, err := mgr.Files.GetById(raw.File.Id)
+		if err != nil {
+			if mgr.IsNotFound(err) {
+				return nil, &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewBadReq("spec file is not found")}
+			}
+			logrus.Error(stackerr.Wrap(err))
+			return nil, &services.ErrResp{Code: http.StatusInternalServerError, Err: services.DbErr}
+		}
+		defer f.Close()
+		if data, err = ioutil.ReadAll(f); err != nil {
+			logrus.Error(stackerr.Wrap(err))
+			return nil, &services.ErrResp{Code: http.StatusInternalServerError, Err: services.DbErr}
+		}
+		obj.File = f.Meta
+	case raw.SpecUrl != "":
+		var err error
+		if data, err = openapi.Fetch(netguard.Client(specTimeout), raw.SpecUrl); err != nil {
+			return nil, &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewBadReq("Couldn't download spec: %s", err)}
+		}
+	default:
+		return nil, &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewBadReq("specUrl or file is required")}
+	}
+
+	spec, err := openapi.Parse(data, raw.SpecUrl)
+	if err != nil {
+		return nil, &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewBadReq("Wrong spec: %s", err)}
+	}
+	if spec.BaseUrl == "" {
+		return nil, &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewBadReq("Wrong spec: server url is unknown")}
+	}
+	obj.Title = spec.Title
+	obj.Version = spec.Version
+	obj.BaseUrl = spec.BaseUrl
+	obj.Endpoints = spec.Endpoints
+	obj.Parsed = time.Now().UTC()
+	return obj, nil
+}
@@ -0,0 +1,181 @@
+package target
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/emicklei/go-restful"
+	"github.com/facebookgo/stackerr"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/file"
+	"github.com/bearded-web/bearded/models/project"
+	"github.com/bearded-web/bearded/models/target"
+	"github.com/bearded-web/bearded/pkg/apk"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/manager"
+	"github.com/bearded-web/bearded/services"
+)
+
+// ApkMaxSize limits the size of uploaded apk
+const ApkMaxSize = 200 << 20
+
+func (s *TargetService) RegisterApk(ws *restful.WebService) {
+	r := ws.POST(fmt.Sprintf("{%s}/apk", ParamId)).To(s.TakeTarget(s.apkUpload))
+	r.Doc("apkUpload")
+	r.Operation("apkUpload")
+	r.Notes("Upload a new apk for android target. Version is extracted from the manifest, " +
+		"auto scan plan is run if the version is new")
+	r.Consumes("multipart/form-data")
+	r.Param(ws.PathParameter(ParamId, ""))
+	r.Param(ws.FormParameter("file", "apk file").DataType("File"))
+	r.Writes(target.Target{})
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusPaymentRequired))
+	addDefaults(r)
+	ws.Route(r)
+}
+
+func (s *TargetService) apkUpload(req *restful.Request, resp *restful.Response, obj *target.Target, p *project.Project) {
+	if obj.Type != target.TypeAndroid {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("only android targets have apk"))
+		return
+	}
+	req.Request.Body = http.MaxBytesReader(resp.ResponseWriter, req.Request.Body, ApkMaxSize)
+	f, header, err := req.Request.FormFile("file")
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Couldn't read file"))
+		return
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Couldn't read file"))
+		return
+	}
+	m, err := apk.ParseBytes(data)
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Couldn't read apk manifest: %s", err))
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	limited, err := mgr.Files.StorageLimit(p, bytes.NewReader(data))
+	if err != nil {
+		if sErr := services.QuotaErr(err); sErr != nil {
+			sErr.Write(resp)
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	meta, err := mgr.Files.Create(limited, &file.Meta{
+		Name:        header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Project:     p.Id,
+	})
+	if err != nil {
+		if sErr := services.QuotaErr(err); sErr != nil {
+			sErr.Write(resp)
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	v := newApkVersion(m, meta)
+	s.setApkVersion(mgr, filters.GetUser(req).Id, obj, p, v)
+
+	if err := mgr.Targets.Update(obj); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	resp.WriteEntity(obj)
+}
+
+// readApk extracts version from the apk in file storage
+func readApk(mgr *manager.Manager, meta *file.Meta) (*target.ApkVersion, error) {
+	f, err := mgr.Files.GetById(meta.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	m, err := apk.ParseBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	return newApkVersion(m, f.Meta), nil
+}
+
+func newApkVersion(m *apk.Manifest, meta *file.Meta) *target.ApkVersion {
+	return &target.ApkVersion{
+		Package:     m.Package,
+		VersionCode: m.VersionCode,
+		VersionName: m.VersionName,
+		MinSdk:      m.MinSdk,
+		TargetSdk:   m.TargetSdk,
+		File:        meta,
+		Uploaded:    time.Now().UTC(),
+	}
+}
+
+// setApkVersion makes the version current and runs auto scan for new versions.
+// Target should be saved after.
+func (s *TargetService) setApkVersion(mgr *manager.Manager, owner bson.ObjectId, obj *target.Target, p *project.Project, v *target.ApkVersion) {
+	if !obj.Android.SetVersion(v) || obj.Android.AutoScan == "" {
+		return
+	}
+	planObj, err := mgr.Plans.GetById(obj.Android.AutoScan)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		return
+	}
+	sc, err := mgr.Scans.NewScan(owner, p, obj, planObj)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		return
+	}
+	sc, err = mgr.Scans.Create(sc)
+	if err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		return
+	}
+	s.Scheduler().AddScan(sc)
+	if _, err := mgr.Feed.AddScan(sc); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+	}
+	v.Scan = sc.Id
+}
+
+// checkAutoScan returns an error if the plan can't be used for android targets
+func checkAutoScan(mgr *manager.Manager, planId bson.ObjectId) *services.ErrResp {
+	planObj, err := mgr.Plans.GetById(planId)
+	if err != nil {
+		if mgr.IsNotFound(err) {
+			return &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewBadReq("auto scan plan is not found")}
+		}
+		logrus.Error(stackerr.Wrap(err))
+		return &services.ErrResp{Code: http.StatusInternalServerError, Err: services.DbErr}
+	}
+	if planObj.TargetType != target.TypeAndroid {
+		return &services.ErrResp{Code: http.StatusBadRequest, Err: services.NewBadReq("auto scan plan should be for android targets")}
+	}
+	return nil
+}
@@ -9,15 +9,17 @@ import (
 	"github.com/emicklei/go-restful"
 	"github.com/facebookgo/stackerr"
 
+	"github.com/bearded-web/bearded/models/approval"
 	"github.com/bearded-web/bearded/models/comment"
+	"github.com/bearded-web/bearded/models/issue"
 	"github.com/bearded-web/bearded/models/project"
 	"github.com/bearded-web/bearded/models/target"
 	"github.com/bearded-web/bearded/pkg/filters"
 	"github.com/bearded-web/bearded/pkg/fltr"
 	"github.com/bearded-web/bearded/pkg/manager"
 	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/pkg/validate"
 	"github.com/bearded-web/bearded/services"
-	"gopkg.in/validator.v2"
 )
 
 const ParamId = "target-id"
@@ -71,7 +73,7 @@ func (s *TargetService) Register(container *restful.Container) {
 	r.Writes(target.Target{})
 	r.Reads(TargetEntity{})
 	r.Do(services.Returns(http.StatusCreated))
-	r.Do(services.ReturnsE(http.StatusConflict))
+	r.Do(services.ReturnsE(http.StatusConflict, http.StatusPaymentRequired))
 	addDefaults(r)
 	ws.Route(r)
 
@@ -89,8 +91,13 @@ func (s *TargetService) Register(container *restful.Container) {
 	r.Doc("delete")
 	r.Operation("delete")
 	r.Param(ws.PathParameter(ParamId, ""))
-	r.Do(services.Returns(http.StatusNoContent))
+	r.Writes(approval.Approval{})
+	r.Do(services.Returns(http.StatusNoContent, http.StatusAccepted))
+	r.Do(services.ReturnsE(http.StatusConflict))
 	addDefaults(r)
+	r.Notes("Authorization required. If approvals are enabled, the target is deleted after confirmation by a second admin. " +
+		"Issues and scans are removed in background, see /api/v1/cascades for progress, " +
+		"or the deletion is rejected with 409 if the block cascade policy is configured")
 	ws.Route(r)
 
 	r = ws.GET(fmt.Sprintf("{%s}/comments", ParamId)).To(s.TakeTarget(s.comments))
@@ -115,18 +122,18 @@ func (s *TargetService) Register(container *restful.Container) {
 		http.StatusNotFound))
 	ws.Route(r)
 
-	r = ws.PUT(fmt.Sprintf("{%s}", ParamId)).To(s.TakeTarget(s.update))
-	// docs
-	r.Doc("update")
-	r.Operation("update")
-	r.Param(ws.PathParameter(ParamId, ""))
-	r.Writes(target.Target{})
-	r.Reads(TargetEntity{})
-	r.Do(services.Returns(
-		http.StatusOK,
-		http.StatusNotFound))
-	r.Do(services.ReturnsE(http.StatusBadRequest))
-	ws.Route(r)
+	s.RegisterApk(ws)
+
+	for _, r := range services.UpdateRoutes(ws, fmt.Sprintf("{%s}", ParamId), s.TakeTarget(s.update)) {
+		r.Param(ws.PathParameter(ParamId, ""))
+		r.Writes(target.Target{})
+		r.Reads(TargetEntity{})
+		r.Do(services.Returns(
+			http.StatusOK,
+			http.StatusNotFound))
+		r.Do(services.ReturnsE(http.StatusBadRequest))
+		ws.Route(r)
+	}
 
 	container.Add(ws)
 }
@@ -142,20 +149,14 @@ func (s *TargetService) create(req *restful.Request, resp *restful.Response) {
 		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
 		return
 	}
-	if err := validator.WithTag("create").Validate(raw); err != nil {
-		resp.WriteServiceError(
-			http.StatusBadRequest,
-			services.NewBadReq("Validation error: %s", err.Error()),
-		)
+	if err := validate.Entity(raw, "create"); err != nil {
+		services.NewValidationErr(err).Write(resp)
 		return
 	}
 	switch raw.Type {
 	case target.TypeWeb:
-		if err := validator.WithTag("cweb").Validate(raw); err != nil {
-			resp.WriteServiceError(
-				http.StatusBadRequest,
-				services.NewBadReq("Validation error: %s", err.Error()),
-			)
+		if err := validate.Entity(raw, "cweb"); err != nil {
+			services.NewValidationErr(err).Write(resp)
 			return
 		}
 		addr, err := url.Parse(raw.Web.Domain)
@@ -171,11 +172,8 @@ func (s *TargetService) create(req *restful.Request, resp *restful.Response) {
 			Domain: addr.String(),
 		}
 	case target.TypeAndroid:
-		if err := validator.WithTag("cmobile").Validate(raw); err != nil {
-			resp.WriteServiceError(
-				http.StatusBadRequest,
-				services.NewBadReq("Validation error: %s", err.Error()),
-			)
+		if err := validate.Entity(raw, "cmobile"); err != nil {
+			services.NewValidationErr(err).Write(resp)
 			return
 		}
 
@@ -187,16 +185,64 @@ func (s *TargetService) create(req *restful.Request, resp *restful.Response) {
 			// TODO (m0sth8): check metadata for files (check if file existed, set true md5, size etc)
 			new.Android.File = raw.Android.File
 		}
+	case target.TypeApi:
+		if err := validate.Entity(raw, "capi"); err != nil {
+			services.NewValidationErr(err).Write(resp)
+			return
+		}
+	case target.TypeRepo:
+		if err := validate.Entity(raw, "crepo"); err != nil {
+			services.NewValidationErr(err).Write(resp)
+			return
+		}
+		new.Repo = &target.RepoTarget{
+			Name:        raw.Repo.Name,
+			Url:         raw.Repo.Url,
+			Branch:      raw.Repo.Branch,
+			Credentials: raw.Repo.Credentials,
+		}
+		if err := new.Repo.Validate(); err != nil {
+			services.NewValidationErr(validate.Nested("repo", err)).Write(resp)
+			return
+		}
 	default:
 		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Unknown target type"))
 		return
 	}
+	if raw.Criticality != "" {
+		if !raw.Criticality.IsValid() {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("criticality should be one of [low|medium|high|critical]"))
+			return
+		}
+		new.Criticality = raw.Criticality
+	}
+	if raw.Environment != nil {
+		if !raw.Environment.IsValid() {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("environment should be one of [prod|staging|dev]"))
+			return
+		}
+		new.Environment = *raw.Environment
+	}
+	if raw.Exposure != nil {
+		new.Exposure = *raw.Exposure
+	}
+	if raw.RateLimit != nil {
+		new.RateLimit = raw.RateLimit.WithDefaults(nil)
+	}
+	if raw.Proxy != nil {
+		if err := raw.Proxy.Validate(); err != nil {
+			services.NewValidationErr(validate.Nested("proxy", err)).Write(resp)
+			return
+		}
+		new.Proxy = raw.Proxy
+	}
+	new.Record = raw.Record
 	new.Type = raw.Type
 	// TODO (m0sth8): add validation and extract it to manager
 
 	user := filters.GetUser(req)
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	// TODO (m0sth8): check if the user has permission to add a target to the project
@@ -217,6 +263,37 @@ func (s *TargetService) create(req *restful.Request, resp *restful.Response) {
 		return
 	}
 	new.Project = proj.Id
+	if err := validateRecord(new, proj); err != nil {
+		services.NewValidationErr(err).Write(resp)
+		return
+	}
+
+	if err := mgr.Targets.CheckCount(proj); err != nil {
+		if sErr := services.QuotaErr(err); sErr != nil {
+			sErr.Write(resp)
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	if new.Type == target.TypeApi {
+		api, sErr := loadApi(mgr, raw.Api)
+		if sErr != nil {
+			sErr.Write(resp)
+			return
+		}
+		new.Api = api
+	}
+
+	if new.Type == target.TypeAndroid && raw.Android.AutoScan != "" {
+		if sErr := checkAutoScan(mgr, raw.Android.AutoScan); sErr != nil {
+			sErr.Write(resp)
+			return
+		}
+		new.Android.AutoScan = raw.Android.AutoScan
+	}
 
 	obj, err := mgr.Targets.Create(new)
 	if err != nil {
@@ -225,6 +302,17 @@ func (s *TargetService) create(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
+	if obj.Type == target.TypeAndroid && obj.Android.File != nil {
+		if v, err := readApk(mgr, obj.Android.File); err != nil {
+			logrus.Warnf("Couldn't read apk %s: %s", obj.Android.File.Id, err)
+		} else {
+			s.setApkVersion(mgr, user.Id, obj, proj, v)
+			if err := mgr.Targets.Update(obj); err != nil {
+				logrus.Error(stackerr.Wrap(err))
+			}
+		}
+	}
+
 	resp.WriteHeader(http.StatusCreated)
 	resp.WriteEntity(obj)
 }
@@ -237,13 +325,18 @@ func (s *TargetService) list(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	skip, limit := s.Paginator.Parse(req)
+	sort, err := s.sorter.ParseStrict(req)
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
 
 	opt := manager.Opts{
-		Sort:  s.sorter.Parse(req),
+		Sort:  sort,
 		Limit: limit,
 		Skip:  skip,
 	}
@@ -254,6 +347,11 @@ func (s *TargetService) list(req *restful.Request, resp *restful.Response) {
 		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
 		return
 	}
+	if err := mgr.Summaries.Fill(results...); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
 	previous, next := s.Paginator.Urls(req, skip, limit, count)
 	result := &target.TargetList{
 		Meta: pagination.Meta{
@@ -266,18 +364,29 @@ func (s *TargetService) list(req *restful.Request, resp *restful.Response) {
 	resp.WriteEntity(result)
 }
 
-func (s *TargetService) get(_ *restful.Request, resp *restful.Response, obj *target.Target, _ *project.Project) {
+func (s *TargetService) get(req *restful.Request, resp *restful.Response, obj *target.Target, _ *project.Project) {
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	if err := mgr.Summaries.Fill(obj); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
 	resp.WriteEntity(obj)
 }
 
-func (s *TargetService) delete(_ *restful.Request, resp *restful.Response, obj *target.Target, _ *project.Project) {
+func (s *TargetService) delete(req *restful.Request, resp *restful.Response, obj *target.Target, _ *project.Project) {
 	// TODO (m0sth8): do not remove target, just mark as deleted
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
-	mgr.Targets.Remove(obj)
-
-	resp.WriteHeader(http.StatusNoContent)
+	services.SubmitApproval(resp, mgr, &approval.Approval{
+		Action:    approval.ActionTargetDelete,
+		Project:   obj.Project,
+		Target:    obj.Id,
+		Requester: filters.GetUser(req).Id,
+	})
 }
 
 func (s *TargetService) update(req *restful.Request, resp *restful.Response, obj *target.Target, p *project.Project) {
@@ -285,31 +394,143 @@ func (s *TargetService) update(req *restful.Request, resp *restful.Response, obj
 	updated := false
 	raw := &TargetEntity{}
 
-	if err := req.ReadEntity(raw); err != nil {
+	mask, err := services.ReadMasked(req, raw)
+	if err != nil {
 		logrus.Error(stackerr.Wrap(err))
 		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
 		return
 	}
-	if err := validator.WithTag("update").Validate(raw); err != nil {
-		resp.WriteServiceError(
-			http.StatusBadRequest,
-			services.NewBadReq("Validation error: %s", err.Error()),
-		)
+	if err := validate.Entity(raw); err != nil {
+		services.NewValidationErr(err).Write(resp)
 		return
 	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
 	// update file for android target
-	if obj.Type == target.TypeAndroid {
-		if raw.Android != nil && raw.Android.File != nil && raw.Android.File.Id != obj.Android.File.Id {
+	if obj.Type == target.TypeAndroid && raw.Android != nil {
+		if mask.Has("android.autoScan") && raw.Android.AutoScan != obj.Android.AutoScan {
+			if raw.Android.AutoScan != "" {
+				if sErr := checkAutoScan(mgr, raw.Android.AutoScan); sErr != nil {
+					sErr.Write(resp)
+					return
+				}
+			}
+			obj.Android.AutoScan = raw.Android.AutoScan
+			updated = true
+		}
+		if raw.Android.File != nil && (obj.Android.File == nil || raw.Android.File.Id != obj.Android.File.Id) {
 			// TODO (m0sth8): HIGH! check file permissions
-			obj.Android.File = raw.Android.File
+			if v, err := readApk(mgr, raw.Android.File); err != nil {
+				logrus.Warnf("Couldn't read apk %s: %s", raw.Android.File.Id, err)
+				obj.Android.File = raw.Android.File
+			} else {
+				s.setApkVersion(mgr, filters.GetUser(req).Id, obj, p, v)
+			}
 			updated = true
 		}
 	}
 
-	if updated {
-		mgr := s.Manager()
-		defer mgr.Close()
+	// spec is downloaded and parsed again
+	if obj.Type == target.TypeApi && raw.Api != nil {
+		if raw.Api.Name == "" {
+			raw.Api.Name = obj.Api.Name
+		}
+		if raw.Api.SpecUrl == "" && raw.Api.File == nil {
+			raw.Api.SpecUrl, raw.Api.File = obj.Api.SpecUrl, obj.Api.File
+		}
+		api, sErr := loadApi(mgr, raw.Api)
+		if sErr != nil {
+			sErr.Write(resp)
+			return
+		}
+		obj.Api = api
+		updated = true
+	}
+
+	if obj.Type == target.TypeRepo && raw.Repo != nil {
+		repo := *obj.Repo
+		if raw.Repo.Name != "" {
+			repo.Name = raw.Repo.Name
+		}
+		if raw.Repo.Url != "" {
+			repo.Url = raw.Repo.Url
+		}
+		if mask.Has("repo.branch") {
+			repo.Branch = raw.Repo.Branch
+		}
+		if mask.Has("repo.credentials") {
+			repo.Credentials = raw.Repo.Credentials
+		}
+		if err := repo.Validate(); err != nil {
+			services.NewValidationErr(validate.Nested("repo", err)).Write(resp)
+			return
+		}
+		obj.Repo = &repo
+		updated = true
+	}
+
+	rescore := false
+	if raw.Criticality != "" && raw.Criticality != obj.Criticality {
+		if !raw.Criticality.IsValid() {
+			resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("criticality should be one of [low|medium|high|critical]"))
+			return
+		}
+		obj.Criticality = raw.Criticality
+		updated = true
+		rescore = true
+	}
+	relabel := false
+	if mask.Has("environment") {
+		env := target.Environment("")
+		if raw.Environment != nil {
+			if !raw.Environment.IsValid() {
+				resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("environment should be one of [prod|staging|dev]"))
+				return
+			}
+			env = *raw.Environment
+		}
+		relabel = env != obj.Environment
+		obj.Environment = env
+		updated = true
+	}
+	if mask.Has("exposure") {
+		obj.Exposure = issue.Exposure{}
+		if raw.Exposure != nil {
+			obj.Exposure = *raw.Exposure
+		}
+		updated = true
+	}
+	if mask.Has("rateLimit") {
+		obj.RateLimit = nil
+		if raw.RateLimit != nil {
+			obj.RateLimit = raw.RateLimit.WithDefaults(nil)
+		}
+		updated = true
+	}
+	if mask.Has("proxy") {
+		if raw.Proxy != nil {
+			if err := raw.Proxy.Validate(); err != nil {
+				services.NewValidationErr(validate.Nested("proxy", err)).Write(resp)
+				return
+			}
+		}
+		obj.Proxy = raw.Proxy
+		updated = true
+	}
+	if mask.Has("record") {
+		obj.Record = raw.Record
+		updated = true
+	}
+	if mask.Has("proxy") || mask.Has("record") {
+		if err := validateRecord(obj, p); err != nil {
+			services.NewValidationErr(err).Write(resp)
+			return
+		}
+	}
 
+	if updated {
 		err := mgr.Targets.Update(obj)
 		if err != nil {
 			if mgr.IsNotFound(err) {
@@ -320,14 +541,33 @@ func (s *TargetService) update(req *restful.Request, resp *restful.Response, obj
 			resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
 			return
 		}
+		if relabel {
+			if err := mgr.Issues.UpdateEnvironment(obj); err != nil {
+				logrus.Error(stackerr.Wrap(err))
+				resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+				return
+			}
+		}
+		if rescore {
+			if err := mgr.Issues.UpdateRisk(obj); err != nil {
+				logrus.Error(stackerr.Wrap(err))
+				resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+				return
+			}
+		}
+	}
+	if err := mgr.Summaries.Fill(obj); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
 	}
 
 	resp.WriteEntity(obj)
 
 }
 
-func (s *TargetService) comments(_ *restful.Request, resp *restful.Response, obj *target.Target, _ *project.Project) {
-	mgr := s.Manager()
+func (s *TargetService) comments(req *restful.Request, resp *restful.Response, obj *target.Target, _ *project.Project) {
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	results, count, err := mgr.Comments.FilterBy(&manager.CommentFltr{Type: comment.Scan, Link: obj.Id})
@@ -366,7 +606,7 @@ func (s *TargetService) commentsAdd(req *restful.Request, resp *restful.Response
 		Text:  ent.Text,
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	obj, err := mgr.Comments.Create(raw)
@@ -393,7 +633,7 @@ func (s *TargetService) TakeTarget(fn TargetFunction) restful.RouteFunction {
 			return
 		}
 
-		mgr := s.Manager()
+		mgr := s.RequestManager(req)
 		defer mgr.Close()
 
 		t, err := mgr.Targets.GetById(mgr.ToId(id))
@@ -428,3 +668,12 @@ func (s *TargetService) TakeTarget(fn TargetFunction) restful.RouteFunction {
 		fn(req, resp, t, p)
 	}
 }
+
+// validateRecord returns an error if the target records traffic through its own or the project socks proxy
+func validateRecord(t *target.Target, p *project.Project) error {
+	proxy := t.Proxy
+	if proxy == nil {
+		proxy = p.Proxy
+	}
+	return target.ValidateRecord(t.Record, proxy)
+}
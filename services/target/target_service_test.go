@@ -23,6 +23,7 @@ import (
 	"github.com/bearded-web/bearded/pkg/manager"
 	"github.com/bearded-web/bearded/pkg/scheduler"
 	"github.com/bearded-web/bearded/pkg/tests"
+	"github.com/bearded-web/bearded/pkg/validate"
 	"github.com/bearded-web/bearded/services"
 )
 
@@ -108,12 +109,12 @@ func TestTargetService(t *testing.T) {
 			c.Convey("Without web field", func() {
 				te.Web = nil
 				res, _, _ := createTarget(ts.URL, te)
-				shouldBeBadRequest(t, res, services.CodeWrongData, "Validation error: Web: zero value")
+				shouldBeBadRequest(t, res, services.CodeWrongData, "Validation error: web: shouldn't be empty")
 			})
 			c.Convey("Without domain", func() {
 				te.Web.Domain = ""
 				res, _, _ := createTarget(ts.URL, te)
-				shouldBeBadRequest(t, res, services.CodeWrongData, "Validation error: Web.Domain: zero value")
+				shouldBeBadRequest(t, res, services.CodeWrongData, "Validation error: web.domain: shouldn't be empty")
 			})
 			c.Convey("With bad domain", func() {
 				te.Web.Domain = "bad domain"
@@ -123,12 +124,22 @@ func TestTargetService(t *testing.T) {
 			c.Convey("Without project id", func() {
 				te.Project = ""
 				res, _, _ := createTarget(ts.URL, te)
-				shouldBeBadRequest(t, res, services.CodeWrongData, "Validation error: Project: zero value")
+				shouldBeBadRequest(t, res, services.CodeWrongData, "Validation error: project: shouldn't be empty")
 			})
 			c.Convey("With bad project id", func() {
 				te.Project = "1234234sdf"
 				res, _, _ := createTarget(ts.URL, te)
-				shouldBeBadRequest(t, res, services.CodeWrongData, "Validation error: Project: should be bson uuid in hex form")
+				shouldBeBadRequest(t, res, services.CodeWrongData, "Validation error: project: should be bson uuid in hex form")
+			})
+			c.Convey("With field errors", func() {
+				te.Project = "1234234sdf"
+				res, _, _ := createTarget(ts.URL, te)
+				c.So(res.StatusCode, c.ShouldEqual, http.StatusBadRequest)
+				e := &services.ValidationErr{}
+				c.So(json.NewDecoder(res.Body).Decode(e), c.ShouldBeNil)
+				c.So(e.Fields, c.ShouldResemble, validate.Errors{
+					{Field: "project", Code: validate.CodeBsonId, Message: "should be bson uuid in hex form"},
+				})
 			})
 			c.Convey("With non existed project id", func() {
 				te.Project = "553a8abcf18c0f18d3000004"
@@ -184,12 +195,12 @@ func TestTargetService(t *testing.T) {
 			c.Convey("Without name", func() {
 				te.Android.Name = ""
 				res, _, _ := createTarget(ts.URL, te)
-				shouldBeBadRequest(t, res, services.CodeWrongData, "Validation error: Android.Name: zero value")
+				shouldBeBadRequest(t, res, services.CodeWrongData, "Validation error: android.name: shouldn't be empty")
 			})
 			c.Convey("Without android field", func() {
 				te.Android = nil
 				res, _, _ := createTarget(ts.URL, te)
-				shouldBeBadRequest(t, res, services.CodeWrongData, "Validation error: Android: zero value")
+				shouldBeBadRequest(t, res, services.CodeWrongData, "Validation error: android: shouldn't be empty")
 			})
 			// TODO(m0sth8): add test for files permission violation
 		})
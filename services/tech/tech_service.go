@@ -8,7 +8,6 @@ import (
 	"github.com/emicklei/go-restful"
 	"github.com/facebookgo/stackerr"
 	"gopkg.in/mgo.v2/bson"
-	//	"gopkg.in/validator.v2"
 
 	"github.com/bearded-web/bearded/models/tech"
 	"github.com/bearded-web/bearded/pkg/filters"
@@ -146,15 +145,12 @@ func (s *TechService) Register(container *restful.Container) {
 //		return
 //	}
 //	// validate other fields
-//	if err := validator.WithTag("creating").Validate(raw); err != nil {
-//		resp.WriteServiceError(
-//			http.StatusBadRequest,
-//			services.NewBadReq("Validation error: %s", err.Error()),
-//		)
+//	if err := validate.Entity(raw, "creating"); err != nil {
+//		services.NewValidationErr(err).Write(resp)
 //		return
 //	}
 //
-//	mgr := s.Manager()
+//	mgr := s.RequestManager(req)
 //	defer mgr.Close()
 //
 //	// load target and project
@@ -208,7 +204,7 @@ func (s *TechService) Register(container *restful.Container) {
 //			logrus.Error(stackerr.Wrap(err))
 //			return
 //		}
-//	}(s.Manager())
+//	}(s.RequestManager(req))
 //	resp.WriteHeader(http.StatusCreated)
 //	resp.WriteEntity(obj)
 //}
@@ -221,7 +217,7 @@ func (s *TechService) list(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	if search := req.QueryParameter("search"); search != "" {
@@ -231,9 +227,14 @@ func (s *TechService) list(req *restful.Request, resp *restful.Response) {
 	}
 
 	skip, limit := s.Paginator.Parse(req)
+	sort, err := s.sorter.ParseStrict(req)
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
 
 	opt := manager.Opts{
-		Sort:  s.sorter.Parse(req),
+		Sort:  sort,
 		Limit: limit,
 		Skip:  skip,
 	}
@@ -267,7 +268,7 @@ func (s *TechService) update(req *restful.Request, resp *restful.Response, techO
 		resp.WriteServiceError(http.StatusBadRequest, services.WrongEntityErr)
 		return
 	}
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	// update tech object from entity
@@ -293,8 +294,8 @@ func (s *TechService) update(req *restful.Request, resp *restful.Response, techO
 	resp.WriteEntity(techObj)
 }
 
-func (s *TechService) delete(_ *restful.Request, resp *restful.Response, obj *tech.TargetTech) {
-	mgr := s.Manager()
+func (s *TechService) delete(req *restful.Request, resp *restful.Response, obj *tech.TargetTech) {
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	mgr.Techs.Remove(obj)
@@ -322,7 +323,7 @@ func (s *TechService) TakeTech(fn func(*restful.Request,
 			return
 		}
 
-		mgr := s.Manager()
+		mgr := s.RequestManager(req)
 		defer mgr.Close()
 
 		obj, err := mgr.Techs.GetById(mgr.ToId(id))
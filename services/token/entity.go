@@ -1,6 +1,11 @@
 package token
 
+import (
+	"gopkg.in/mgo.v2/bson"
+)
+
 type TokenEntity struct {
-	Name string `json:"name,omitempty" description:"token name" validate:"max=256"`
+	Name    string        `json:"name,omitempty" description:"token name" validate:"max=256"`
+	Project bson.ObjectId `json:"project,omitempty" description:"create write-only ingest token for the project, it's used to push issues"`
 	//	Scopes  []string      `json:"scopes,omitempty"`
 }
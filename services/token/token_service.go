@@ -7,13 +7,13 @@ import (
 	"github.com/Sirupsen/logrus"
 	"github.com/emicklei/go-restful"
 	"github.com/facebookgo/stackerr"
-	"gopkg.in/validator.v2"
 
 	"github.com/bearded-web/bearded/models/token"
 	"github.com/bearded-web/bearded/pkg/filters"
 	"github.com/bearded-web/bearded/pkg/fltr"
 	"github.com/bearded-web/bearded/pkg/manager"
 	"github.com/bearded-web/bearded/pkg/pagination"
+	"github.com/bearded-web/bearded/pkg/validate"
 	"github.com/bearded-web/bearded/services"
 )
 
@@ -64,6 +64,7 @@ func (s *TokenService) Register(container *restful.Container) {
 	r.Do(services.Returns(http.StatusCreated))
 	r.Do(services.ReturnsE(
 		http.StatusBadRequest,
+		http.StatusForbidden,
 	))
 	ws.Route(r)
 
@@ -121,15 +122,12 @@ func (s *TokenService) create(req *restful.Request, resp *restful.Response) {
 		return
 	}
 	// validate fields
-	if err := validator.Validate(raw); err != nil {
-		resp.WriteServiceError(
-			http.StatusBadRequest,
-			services.NewBadReq("Validation error: %s", err.Error()),
-		)
+	if err := validate.Entity(raw); err != nil {
+		services.NewValidationErr(err).Write(resp)
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	u := filters.GetUser(req)
@@ -138,6 +136,14 @@ func (s *TokenService) create(req *restful.Request, resp *restful.Response) {
 		User: u.Id,
 		Name: raw.Name,
 	}
+	if raw.Project != "" {
+		if sErr := services.Must(services.HasProjectIdPermission(mgr, u, raw.Project)); sErr != nil {
+			sErr.Write(resp)
+			return
+		}
+		newObj.Project = raw.Project
+		newObj.Scopes = []string{token.ScopeIngest}
+	}
 
 	obj, err := mgr.Tokens.Create(newObj)
 	if err != nil {
@@ -158,7 +164,7 @@ func (s *TokenService) list(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	u := filters.GetUser(req)
@@ -195,15 +201,12 @@ func (s *TokenService) update(req *restful.Request, resp *restful.Response, toke
 		return
 	}
 	// validate fields
-	if err := validator.Validate(raw); err != nil {
-		resp.WriteServiceError(
-			http.StatusBadRequest,
-			services.NewBadReq("Validation error: %s", err.Error()),
-		)
+	if err := validate.Entity(raw); err != nil {
+		services.NewValidationErr(err).Write(resp)
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	// update token object from entity
@@ -225,8 +228,8 @@ func (s *TokenService) update(req *restful.Request, resp *restful.Response, toke
 	resp.WriteEntity(tokenObj)
 }
 
-func (s *TokenService) delete(_ *restful.Request, resp *restful.Response, obj *token.Token) {
-	mgr := s.Manager()
+func (s *TokenService) delete(req *restful.Request, resp *restful.Response, obj *token.Token) {
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	err := mgr.Tokens.Remove(obj)
@@ -249,7 +252,7 @@ func (s *TokenService) TakeToken(fn func(*restful.Request,
 			return
 		}
 
-		mgr := s.Manager()
+		mgr := s.RequestManager(req)
 		defer mgr.Close()
 
 		obj, err := mgr.Tokens.GetById(mgr.ToId(id))
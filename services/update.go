@@ -0,0 +1,83 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/emicklei/go-restful"
+)
+
+// UpdateNotes describes semantics of updates, PUT and PATCH of all services are the same
+const UpdateNotes = "Only fields present in the body are updated, absent fields keep their values. " +
+	"null resets optional objects and values. PUT and PATCH have the same semantics"
+
+// Mask holds json paths of fields present in the request body, nested fields are dotted, e.g. repo.branch
+type Mask map[string]bool
+
+// Has returns true if the field is present in the body, even if it's null
+func (m Mask) Has(field string) bool {
+	return m[field]
+}
+
+// Apply copies fields of src to dst if they are present in the mask, only the listed fields could be updated.
+// dst and src should be pointers to structs of the same type, fields are identified by their json names.
+func (m Mask) Apply(dst, src interface{}, fields ...string) {
+	dv := reflect.ValueOf(dst).Elem()
+	sv := reflect.ValueOf(src).Elem()
+	t := dv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || !m.Has(name) {
+			continue
+		}
+		for _, f := range fields {
+			if f == name {
+				dv.Field(i).Set(sv.Field(i))
+				break
+			}
+		}
+	}
+}
+
+func (m Mask) add(path string, obj map[string]interface{}) {
+	for name, val := range obj {
+		field := name
+		if path != "" {
+			field = path + "." + name
+		}
+		m[field] = true
+		if nested, ok := val.(map[string]interface{}); ok {
+			m.add(field, nested)
+		}
+	}
+}
+
+// ReadMasked reads the entity from the request and returns the mask of fields which are set in the body
+func ReadMasked(req *restful.Request, entity interface{}) (Mask, error) {
+	if err := req.ReadEntity(entity); err != nil {
+		return nil, err
+	}
+	// the body is cached by restful, so it could be read again
+	body := map[string]interface{}{}
+	if err := req.ReadEntity(&body); err != nil {
+		return nil, err
+	}
+	mask := Mask{}
+	mask.add("", body)
+	return mask, nil
+}
+
+// UpdateRoutes returns PUT and PATCH routes to the same update function, docs are shared by them
+func UpdateRoutes(ws *restful.WebService, subPath string, fn restful.RouteFunction) []*restful.RouteBuilder {
+	put := ws.PUT(subPath).To(fn)
+	put.Doc("update")
+	put.Operation("update")
+	put.Notes(UpdateNotes)
+
+	patch := ws.PATCH(subPath).To(fn)
+	patch.Doc("patch")
+	patch.Operation("patch")
+	patch.Notes(UpdateNotes)
+
+	return []*restful.RouteBuilder{put, patch}
+}
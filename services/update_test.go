@@ -0,0 +1,52 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/emicklei/go-restful"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type repoEntity struct {
+	Url    string `json:"url,omitempty"`
+	Branch string `json:"branch,omitempty"`
+}
+
+type maskEntity struct {
+	Id   string      `json:"id,omitempty"`
+	Name string      `json:"name"`
+	Desc string      `json:"desc"`
+	Repo *repoEntity `json:"repo,omitempty"`
+}
+
+func readRequest(t *testing.T, body string) *restful.Request {
+	httpReq, err := http.NewRequest("PATCH", "/", strings.NewReader(body))
+	require.NoError(t, err)
+	httpReq.Header.Set("Content-Type", restful.MIME_JSON)
+	return restful.NewRequest(httpReq)
+}
+
+func TestReadMasked(t *testing.T) {
+	raw := &maskEntity{}
+	mask, err := ReadMasked(readRequest(t, `{"name": "new", "desc": null, "repo": {"branch": ""}}`), raw)
+	require.NoError(t, err)
+	assert.Equal(t, Mask{"name": true, "desc": true, "repo": true, "repo.branch": true}, mask)
+	assert.Equal(t, "new", raw.Name)
+	require.NotNil(t, raw.Repo)
+	assert.False(t, mask.Has("repo.url"))
+
+	_, err = ReadMasked(readRequest(t, `[]`), &maskEntity{})
+	assert.Error(t, err)
+}
+
+func TestMaskApply(t *testing.T) {
+	dst := &maskEntity{Id: "1", Name: "old", Desc: "desc", Repo: &repoEntity{Url: "url"}}
+	src := &maskEntity{Id: "2", Name: "new"}
+
+	// only listed fields present in the mask are copied
+	Mask{"id": true, "name": true, "repo": true}.Apply(dst, src, "name", "desc", "repo")
+	assert.Equal(t, &maskEntity{Id: "1", Name: "new", Desc: "desc"}, dst)
+}
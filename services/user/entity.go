@@ -6,7 +6,7 @@ type passwordEntity struct {
 
 type userEntity struct {
 	Nickname string `json:"nickname"`
-	Email    string `json:"email" valid:"email,required"`
-	Password string `json:"password" valid:",required"`
+	Email    string `json:"email" validate:"nonzero,email"`
+	Password string `json:"password" validate:"password"`
 	Admin    bool   `json:"admin" description:"isn't used now, set admin in config file"`
 }
@@ -4,7 +4,6 @@ import (
 	"net/http"
 
 	"github.com/Sirupsen/logrus"
-	"github.com/asaskevich/govalidator"
 	"github.com/emicklei/go-restful"
 	"github.com/facebookgo/stackerr"
 
@@ -82,6 +81,22 @@ func (s *UserService) Register(container *restful.Container) {
 	addDefaults(r)
 	ws.Route(r)
 
+	r = ws.GET("{user-id}/profile").To(s.profile)
+	r.Doc("profile")
+	r.Operation("profile")
+	r.Notes("Authorization required. Minimal public information, f.e for comment authors")
+	r.Param(ws.PathParameter("user-id", ""))
+	r.Writes(user.Profile{}) // on the response
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(
+		http.StatusBadRequest,
+		http.StatusUnauthorized,
+		http.StatusInternalServerError,
+	))
+	ws.Route(r)
+
 	r = ws.POST("{user-id}/password").To(s.setPassword)
 	r.Doc("setPassword")
 	r.Operation("setPassword")
@@ -96,6 +111,20 @@ func (s *UserService) Register(container *restful.Container) {
 
 	ws.Route(r)
 
+	r = ws.POST("{user-id}/approve").To(s.approve)
+	r.Doc("approve")
+	r.Operation("approve")
+	r.Param(ws.PathParameter("user-id", ""))
+	r.Writes(user.User{}) // on the response
+	r.Do(services.Returns(
+		http.StatusOK,
+		http.StatusNotFound))
+	r.Do(services.ReturnsE(http.StatusBadRequest))
+	addDefaults(r)
+	r.Notes("Authorization required. This method available only for administrator. " +
+		"Pending users are listed with status=pending filter")
+	ws.Route(r)
+
 	container.Add(ws)
 }
 
@@ -110,12 +139,18 @@ func (s *UserService) list(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	skip, limit := s.Paginator.Parse(req)
+	sort, err := s.sorter.ParseStrict(req)
+	if err != nil {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
+		return
+	}
+
 	opt := manager.Opts{
-		Sort:  s.sorter.Parse(req),
+		Sort:  sort,
 		Limit: limit,
 		Skip:  skip,
 	}
@@ -143,7 +178,7 @@ func (s *UserService) create(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	u := filters.GetUser(req)
@@ -153,14 +188,9 @@ func (s *UserService) create(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	// check email
-	if valid, err := govalidator.ValidateStruct(raw); !valid {
-		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq(err.Error()))
-		return
-	}
-	// check password
-	if valid, reason := validate.Password(raw.Password); !valid {
-		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("Password %s", reason))
+	// check email and password
+	if err := validate.Entity(raw); err != nil {
+		services.NewValidationErr(err).Write(resp)
 		return
 	}
 	// hash password
@@ -200,7 +230,7 @@ func (s *UserService) get(req *restful.Request, resp *restful.Response) {
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	u, err := mgr.Users.GetById(mgr.ToId(userId))
@@ -217,6 +247,30 @@ func (s *UserService) get(req *restful.Request, resp *restful.Response) {
 	resp.WriteEntity(u)
 }
 
+func (s *UserService) profile(req *restful.Request, resp *restful.Response) {
+	userId := req.PathParameter("user-id")
+	if !s.IsId(userId) {
+		resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	u, err := mgr.Users.GetById(mgr.ToId(userId))
+	if err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteEntity(u.Profile())
+}
+
 func (s *UserService) setPassword(req *restful.Request, resp *restful.Response) {
 	// TODO (m0sth8): Check permissions for admins
 	userId := req.PathParameter("user-id")
@@ -232,7 +286,7 @@ func (s *UserService) setPassword(req *restful.Request, resp *restful.Response)
 		return
 	}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	u, err := mgr.Users.GetById(mgr.ToId(userId))
@@ -274,3 +328,44 @@ func (s *UserService) setPassword(req *restful.Request, resp *restful.Response)
 	// resp.WriteHeader(http.StatusCreated) - this method doesn't work if body isn't written
 	resp.ResponseWriter.WriteHeader(http.StatusCreated)
 }
+
+func (s *UserService) approve(req *restful.Request, resp *restful.Response) {
+	userId := req.PathParameter("user-id")
+	if !s.IsId(userId) {
+		resp.WriteServiceError(http.StatusBadRequest, services.IdHexErr)
+		return
+	}
+
+	mgr := s.RequestManager(req)
+	defer mgr.Close()
+
+	currentUser := filters.GetUser(req)
+	if !mgr.Permission.IsAdmin(currentUser) {
+		logrus.Warnf("User %s try to approve user %s without admin permission", currentUser, userId)
+		resp.WriteServiceError(http.StatusForbidden, services.AuthForbidErr)
+		return
+	}
+
+	u, err := mgr.Users.GetById(mgr.ToId(userId))
+	if err != nil {
+		if mgr.IsNotFound(err) {
+			resp.WriteErrorString(http.StatusNotFound, "Not found")
+			return
+		}
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+	if u.Status != user.StatusPending {
+		resp.WriteServiceError(http.StatusBadRequest, services.NewBadReq("user isn't waiting for approval"))
+		return
+	}
+	u.Status = user.StatusActive
+	if err := mgr.Users.Update(u); err != nil {
+		logrus.Error(stackerr.Wrap(err))
+		resp.WriteServiceError(http.StatusInternalServerError, services.DbErr)
+		return
+	}
+
+	resp.WriteEntity(u)
+}
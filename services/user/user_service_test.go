@@ -0,0 +1,85 @@
+package user
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	c "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/mgo.v2/bson"
+
+	"github.com/bearded-web/bearded/models/user"
+	"github.com/bearded-web/bearded/pkg/filters"
+	"github.com/bearded-web/bearded/pkg/managertest"
+)
+
+func TestApprove(t *testing.T) {
+	env := managertest.Open(t)
+	defer env.Close()
+	mgr, sess := env.Mgr, env.Session
+
+	mgr.Permission.SetAdmins([]string{"admin@example.com"})
+	u, err := mgr.Users.Create(&user.User{Email: "user@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := env.Login(&user.User{Email: "admin@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts, err := env.Server(New(env.Base))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ts.Close()
+
+	c.Convey("Given pending user", t, func() {
+		pending, err := mgr.Users.Create(&user.User{
+			Email:  bson.NewObjectId().Hex() + "@example.com",
+			Status: user.StatusPending,
+		})
+		c.So(err, c.ShouldBeNil)
+		url := ts.URL + "/api/v1/users/" + pending.Id.Hex() + "/approve"
+
+		c.Convey("Admin approves user once", func() {
+			res, err := http.Post(url, "application/json", nil)
+			c.So(err, c.ShouldBeNil)
+			defer res.Body.Close()
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusOK)
+			obj := &user.User{}
+			c.So(json.NewDecoder(res.Body).Decode(obj), c.ShouldBeNil)
+			c.So(obj.Status, c.ShouldEqual, user.StatusActive)
+
+			obj, err = mgr.Users.GetById(pending.Id)
+			c.So(err, c.ShouldBeNil)
+			c.So(obj.IsActive(), c.ShouldBeTrue)
+
+			res, err = http.Post(url, "application/json", nil)
+			c.So(err, c.ShouldBeNil)
+			res.Body.Close()
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusBadRequest)
+		})
+
+		c.Convey("Unknown user isn't found", func() {
+			res, err := http.Post(ts.URL+"/api/v1/users/"+bson.NewObjectId().Hex()+"/approve", "application/json", nil)
+			c.So(err, c.ShouldBeNil)
+			res.Body.Close()
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusNotFound)
+		})
+
+		c.Convey("Non admins are forbidden", func() {
+			sess.Set(filters.SessionUserKey, u.Id.Hex())
+			defer sess.Set(filters.SessionUserKey, admin.Id.Hex())
+
+			res, err := http.Post(url, "application/json", nil)
+			c.So(err, c.ShouldBeNil)
+			res.Body.Close()
+			c.So(res.StatusCode, c.ShouldEqual, http.StatusForbidden)
+
+			obj, err := mgr.Users.GetById(pending.Id)
+			c.So(err, c.ShouldBeNil)
+			c.So(obj.Status, c.ShouldEqual, user.StatusPending)
+		})
+	})
+}
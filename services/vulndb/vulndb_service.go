@@ -75,7 +75,7 @@ func (s *VulndbService) Register(container *restful.Container) {
 }
 
 func (s *VulndbService) list(req *restful.Request, resp *restful.Response) {
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	results := mgr.Vulndb.GetVulns()
@@ -109,7 +109,7 @@ func (s *VulndbService) get(req *restful.Request, resp *restful.Response, v *vul
 func (s *VulndbService) compact(req *restful.Request, resp *restful.Response) {
 	results := []*vuln.CompactVuln{}
 
-	mgr := s.Manager()
+	mgr := s.RequestManager(req)
 	defer mgr.Close()
 
 	for _, vuln := range mgr.Vulndb.GetVulns() {
@@ -135,7 +135,7 @@ func (s *VulndbService) TakeVuln(fn func(*restful.Request,
 			return
 		}
 
-		mgr := s.Manager()
+		mgr := s.RequestManager(req)
 		defer mgr.Close()
 
 		obj := mgr.Vulndb.GetById(id)